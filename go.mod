@@ -3,6 +3,7 @@ module github.com/adamancini/obsidian-notion-sync
 go 1.24
 
 require (
+	github.com/forPelevin/gomoji v1.3.1
 	github.com/fsnotify/fsnotify v1.9.0
 	github.com/jomei/notionapi v1.13.2
 	github.com/mattn/go-sqlite3 v1.14.24
@@ -11,18 +12,18 @@ require (
 	github.com/yuin/goldmark v1.7.13
 	go.abhg.dev/goldmark/hashtag v0.4.0
 	go.abhg.dev/goldmark/wikilink v0.6.0
+	golang.org/x/sys v0.13.0
+	golang.org/x/text v0.21.0
 	golang.org/x/time v0.8.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/forPelevin/gomoji v1.3.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/litao91/goldmark-mathjax v0.0.0-20210217064022-a43cf739a50f // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/yuin/goldmark-meta v1.1.0 // indirect
 	go.abhg.dev/goldmark/mermaid v0.5.0 // indirect
-	golang.org/x/sys v0.13.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
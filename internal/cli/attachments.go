@@ -0,0 +1,652 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yuin/goldmark/ast"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/vault"
+)
+
+var attachmentsPrune bool
+
+// attachmentsCmd represents the attachments command.
+var attachmentsCmd = &cobra.Command{
+	Use:   "attachments",
+	Short: "Manage uploaded attachments",
+	Long: `Manage attachments (images) uploaded to Notion.
+
+Attachments are tracked by content hash, so a file embedded in many notes
+is uploaded once and reused.`,
+}
+
+// attachmentsGCCmd reports uploads no note references anymore.
+var attachmentsGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Report unused attachment uploads",
+	Long: `Report attachments that were uploaded to Notion but are no longer
+referenced by any synced note.
+
+Notion keeps uploaded files as long as a block uses them, so unused uploads
+cannot be deleted through the API. Use --prune to forget them locally so
+the content is uploaded again if it is embedded later.
+
+Examples:
+  obsidian-notion attachments gc           # List unused uploads
+  obsidian-notion attachments gc --prune   # Also drop them from the state database`,
+	RunE: runAttachmentsGC,
+}
+
+func init() {
+	attachmentsGCCmd.Flags().BoolVar(&attachmentsPrune, "prune", false, "remove unused uploads from the state database")
+	attachmentsCmd.AddCommand(attachmentsGCCmd)
+}
+
+func runAttachmentsGC(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	store := state.NewAttachmentStore(db)
+	unused, err := store.Unused()
+	if err != nil {
+		return fmt.Errorf("list unused attachments: %w", err)
+	}
+
+	if len(unused) == 0 {
+		fmt.Println("No unused attachments.")
+		return nil
+	}
+
+	var totalSize int64
+	fmt.Printf("Unused attachments (%d):\n", len(unused))
+	for _, a := range unused {
+		totalSize += a.Size
+		line := fmt.Sprintf("  %s (%s, uploaded %s)", a.OriginalPath, formatBytes(a.Size), a.UploadedAt.Format("2006-01-02"))
+		if a.Credential != "" {
+			line += fmt.Sprintf(" [%s]", a.Credential)
+		}
+		fmt.Println(line)
+		if verbose {
+			fmt.Printf("    hash: %s  upload: %s\n", a.ContentHash, a.FileUploadID)
+		}
+	}
+	fmt.Printf("\nTotal: %s\n", formatBytes(totalSize))
+
+	if !attachmentsPrune {
+		fmt.Println("Run with --prune to remove them from the state database.")
+		return nil
+	}
+
+	var pruned int
+	for _, a := range unused {
+		if err := store.Delete(a.ContentHash, a.Credential); err != nil {
+			printError(os.Stderr, "Error pruning", a.OriginalPath, err)
+			continue
+		}
+		pruned++
+	}
+	fmt.Printf("Pruned %d attachment record(s).\n", pruned)
+
+	return nil
+}
+
+// formatBytes renders a byte count in human-readable units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// attachmentUploader uploads local attachments and deduplicates them by
+// content hash. It is shared by all workers of a push, and queues their
+// uploads apart from page operations: at most attachments.concurrency
+// upload at once, and the clients pace them at attachments.max_rps.
+type attachmentUploader struct {
+	cfg     *config.Config
+	store   *state.AttachmentStore
+	clients *notion.Factory
+	scanner *vault.Scanner
+
+	// slots holds a token for each upload in progress.
+	slots chan struct{}
+
+	// mu guards inflight and skipped.
+	mu sync.Mutex
+
+	// inflight holds the uploads in progress by content hash and
+	// credential, so two notes embedding the same file at the same time
+	// don't upload it twice.
+	inflight map[string]*inflightUpload
+
+	// skipped holds attachments left out for being too large, by path.
+	skipped map[string]skippedFile
+}
+
+// inflightUpload is an upload in progress that other notes wait for.
+type inflightUpload struct {
+	done     chan struct{}
+	uploadID string
+	err      error
+}
+
+// newAttachmentUploader creates an uploader backed by the state database.
+func newAttachmentUploader(cfg *config.Config, db *state.DB, clients *notion.Factory, scanner *vault.Scanner) *attachmentUploader {
+	concurrency := cfg.Attachments.Concurrency
+	if concurrency < 1 {
+		concurrency = config.DefaultAttachmentConcurrency
+	}
+	return &attachmentUploader{
+		cfg:      cfg,
+		store:    state.NewAttachmentStore(db),
+		clients:  clients,
+		scanner:  scanner,
+		slots:    make(chan struct{}, concurrency),
+		inflight: make(map[string]*inflightUpload),
+	}
+}
+
+// noteAttachments holds the attachments resolved for a single note.
+// It implements transformer.AttachmentResolver.
+type noteAttachments struct {
+	notePath string
+	uploads  map[string]string // reference -> file upload ID
+	hashes   []string
+}
+
+// ResolveAttachment implements transformer.AttachmentResolver.
+func (n *noteAttachments) ResolveAttachment(ref string) (string, bool) {
+	id, ok := n.uploads[ref]
+	return id, ok
+}
+
+// prepare uploads the local images a note references, reusing earlier
+// uploads of identical content. The images are queued together, so they
+// upload side by side up to attachments.concurrency. Attachments that
+// cannot be found or read are skipped with a warning and render as
+// placeholders.
+func (u *attachmentUploader) prepare(ctx context.Context, notePath string, note *parser.ParsedNote) *noteAttachments {
+	na := &noteAttachments{
+		notePath: notePath,
+		uploads:  make(map[string]string),
+	}
+
+	var refs []string
+	queued := make(map[string]bool)
+	for _, ref := range attachmentRefs(note) {
+		if !queued[ref] {
+			queued[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	type uploaded struct {
+		hash, uploadID string
+		err            error
+	}
+	results := make([]uploaded, len(refs))
+	var wg sync.WaitGroup
+	for i, ref := range refs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i].hash, results[i].uploadID, results[i].err = u.upload(ctx, notePath, ref)
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for i, ref := range refs {
+		hash, uploadID, err := results[i].hash, results[i].uploadID, results[i].err
+		var skip *vault.SkipError
+		if errors.As(err, &skip) {
+			u.skip(skip)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: attachment %s in %s: %v\n", ref, notePath, err)
+			continue
+		}
+		if uploadID == "" {
+			continue
+		}
+
+		na.uploads[ref] = uploadID
+		if !seen[hash] {
+			seen[hash] = true
+			na.hashes = append(na.hashes, hash)
+		}
+	}
+
+	return na
+}
+
+// commit records which attachments a note references after a successful push.
+func (u *attachmentUploader) commit(na *noteAttachments) error {
+	return u.store.SetRefs(na.notePath, na.hashes)
+}
+
+// upload resolves one attachment reference and returns its content hash and
+// file upload ID. An empty upload ID means the file does not exist. It
+// waits for a free slot in the upload queue before reading the file.
+func (u *attachmentUploader) upload(ctx context.Context, notePath, ref string) (string, string, error) {
+	select {
+	case u.slots <- struct{}{}:
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	}
+	release := sync.OnceFunc(func() { <-u.slots })
+	defer release()
+
+	relPath, data, err := u.read(notePath, ref)
+	if err != nil || relPath == "" {
+		return "", "", err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	credential := u.cfg.CredentialForPath(notePath)
+	key := hash + "\x00" + credential
+
+	u.mu.Lock()
+	existing, err := u.store.Get(hash, credential)
+	if err != nil {
+		u.mu.Unlock()
+		return "", "", fmt.Errorf("look up upload: %w", err)
+	}
+	if existing != nil {
+		u.mu.Unlock()
+		return hash, existing.FileUploadID, nil
+	}
+	if call, ok := u.inflight[key]; ok {
+		// Another note is uploading the same content; wait for it
+		// without holding a slot.
+		u.mu.Unlock()
+		release()
+		<-call.done
+		return hash, call.uploadID, call.err
+	}
+	call := &inflightUpload{done: make(chan struct{})}
+	u.inflight[key] = call
+	u.mu.Unlock()
+
+	call.uploadID, call.err = u.send(ctx, notePath, relPath, hash, credential, data)
+
+	u.mu.Lock()
+	delete(u.inflight, key)
+	u.mu.Unlock()
+	close(call.done)
+
+	if call.err != nil {
+		return "", "", call.err
+	}
+	return hash, call.uploadID, nil
+}
+
+// send uploads an attachment and records the upload. Files too large for
+// a single request are sent in parts.
+func (u *attachmentUploader) send(ctx context.Context, notePath, relPath, hash, credential string, data []byte) (string, error) {
+	client := u.clients.ForPath(notePath)
+	var (
+		uploadID string
+		err      error
+	)
+	if len(data) > notion.MaxSinglePartUploadSize {
+		uploadID, err = u.sendParts(ctx, client, relPath, hash, credential, data)
+	} else {
+		uploadID, err = client.UploadFile(ctx, filepath.Base(relPath), data)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := u.store.Record(&state.Attachment{
+		ContentHash:  hash,
+		Credential:   credential,
+		FileUploadID: uploadID,
+		OriginalPath: relPath,
+		Size:         int64(len(data)),
+		UploadedAt:   time.Now(),
+	}); err != nil {
+		return "", fmt.Errorf("record upload: %w", err)
+	}
+	return uploadID, nil
+}
+
+// sendParts uploads a large attachment in parts, recording its progress
+// after each part so an upload interrupted by a failed or cancelled push
+// picks up where it stopped on the next one.
+func (u *attachmentUploader) sendParts(ctx context.Context, client *notion.Client, relPath, hash, credential string, data []byte) (string, error) {
+	var resume *notion.PartialUpload
+	started := time.Now()
+	if p, err := u.store.GetPartial(hash, credential); err == nil && p != nil {
+		resume = &notion.PartialUpload{ID: p.FileUploadID, Parts: p.Parts, Sent: p.Sent}
+		started = p.StartedAt
+	}
+
+	uploadID, err := client.UploadFileParts(ctx, filepath.Base(relPath), data, resume, func(p notion.PartialUpload) {
+		if p.Sent == 0 {
+			started = time.Now() // A new upload, not the one resumed.
+		} else {
+			fmt.Fprintf(os.Stderr, "  Uploading %s: part %d of %d\n", relPath, p.Sent, p.Parts)
+		}
+		if err := u.store.SavePartial(&state.PartialUpload{
+			ContentHash:  hash,
+			Credential:   credential,
+			FileUploadID: p.ID,
+			Parts:        p.Parts,
+			Sent:         p.Sent,
+			StartedAt:    started,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: failed to record upload progress for %s: %v\n", relPath, err)
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := u.store.ClearPartial(hash, credential); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to clear upload progress for %s: %v\n", relPath, err)
+	}
+	return uploadID, nil
+}
+
+// read finds and reads the file an attachment reference names. An empty
+// path means the file does not exist.
+func (u *attachmentUploader) read(notePath, ref string) (string, []byte, error) {
+	name := ref
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		name = unescaped
+	}
+
+	relPath, err := u.scanner.FindAttachment(name, notePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("find file: %w", err)
+	}
+	if relPath == "" {
+		return "", nil, nil
+	}
+
+	// Check the size before reading, so a huge file never stalls a push.
+	if err := u.scanner.CheckSize(relPath, u.cfg.Sync.AttachmentSizeLimit()); err != nil {
+		return "", nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(u.cfg.Vault, relPath))
+	if err != nil {
+		return "", nil, fmt.Errorf("read file: %w", err)
+	}
+	return relPath, data, nil
+}
+
+// pendingUploads counts the attachments of a note that prepare would
+// upload: files not uploaded before, each counted once.
+func (u *attachmentUploader) pendingUploads(notePath string, note *parser.ParsedNote) int {
+	credential := u.cfg.CredentialForPath(notePath)
+	seen := make(map[string]bool)
+	n := 0
+	for _, ref := range attachmentRefs(note) {
+		relPath, data, err := u.read(notePath, ref)
+		if err != nil || relPath == "" {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		if existing, err := u.store.Get(hash, credential); err == nil && existing == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// skip records an attachment left out for being too large.
+func (u *attachmentUploader) skip(e *vault.SkipError) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.skipped == nil {
+		u.skipped = make(map[string]skippedFile)
+	}
+	u.skipped[e.Path] = newSkippedFile(e)
+}
+
+// skippedFiles returns the attachments left out so far, sorted by path.
+func (u *attachmentUploader) skippedFiles() []skippedFile {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	files := make([]skippedFile, 0, len(u.skipped))
+	for _, f := range u.skipped {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files
+}
+
+// attachmentRefs returns the local image references in a note: image embeds
+// (![[image.png]]) and markdown images with relative paths.
+func attachmentRefs(note *parser.ParsedNote) []string {
+	var refs []string
+	for _, e := range note.Embeds {
+		if e.IsImage {
+			refs = append(refs, e.Target)
+		}
+	}
+
+	if note.AST == nil {
+		return refs
+	}
+	_ = ast.Walk(note.AST, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if img, ok := n.(*ast.Image); ok {
+			dest := string(img.Destination)
+			isLocal := strings.HasPrefix(dest, "file://") ||
+				(!strings.HasPrefix(dest, "data:") && !strings.Contains(dest, "://"))
+			if isLocal && dest != "" {
+				refs = append(refs, dest)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return refs
+}
+
+// attachmentDownloadMu serializes attachment writes, so parallel pulls
+// saving files with the same name pick distinct names.
+var attachmentDownloadMu sync.Mutex
+
+// attachmentDownloader saves the Notion-hosted files of a pulled note into
+// the vault's attachment folder. It implements transformer.AttachmentSaver.
+type attachmentDownloader struct {
+	ctx      context.Context
+	cfg      *config.Config
+	scanner  *vault.Scanner
+	notePath string
+}
+
+// newAttachmentDownloader creates a downloader for the note at notePath.
+func newAttachmentDownloader(ctx context.Context, cfg *config.Config, scanner *vault.Scanner, notePath string) *attachmentDownloader {
+	return &attachmentDownloader{ctx: ctx, cfg: cfg, scanner: scanner, notePath: notePath}
+}
+
+// SaveAttachment downloads a file and returns the embed target for it. A file
+// with the same name and content is reused; a different file with the same
+// name gets a numbered name, as Obsidian does.
+func (d *attachmentDownloader) SaveAttachment(fileURL, name string) (string, bool) {
+	data, err := d.download(fileURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: attachment %s in %s: %v\n", name, d.notePath, err)
+		return "", false
+	}
+
+	relPath, err := d.store(name, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: attachment %s in %s: %v\n", name, d.notePath, err)
+		return "", false
+	}
+
+	return embedTarget(d.scanner, d.notePath, relPath), true
+}
+
+// download fetches a file, refusing files over the attachment size limit.
+func (d *attachmentDownloader) download(fileURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	client := http.DefaultClient
+	if httpClient != nil {
+		client = httpClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download: %s", resp.Status)
+	}
+
+	var body io.Reader = resp.Body
+	limit := d.cfg.Sync.AttachmentSizeLimit()
+	if limit > 0 {
+		body = io.LimitReader(resp.Body, limit+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err)
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		return nil, fmt.Errorf("larger than the %s attachment limit", formatBytes(limit))
+	}
+	return data, nil
+}
+
+// store writes data into the note's attachment folder and returns its
+// vault-relative path.
+func (d *attachmentDownloader) store(name string, data []byte) (string, error) {
+	name = filepath.Base(filepath.FromSlash(name))
+	if name == "." || name == string(filepath.Separator) {
+		return "", fmt.Errorf("invalid file name")
+	}
+	dir := d.scanner.AttachmentDir(d.notePath)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	attachmentDownloadMu.Lock()
+	defer attachmentDownloadMu.Unlock()
+
+	for i := 0; ; i++ {
+		candidate := name
+		if i > 0 {
+			candidate = fmt.Sprintf("%s %d%s", stem, i, ext)
+		}
+		relPath := filepath.Join(dir, candidate)
+		fullPath := filepath.Join(d.cfg.Vault, relPath)
+
+		existing, err := os.ReadFile(fullPath)
+		if os.IsNotExist(err) {
+			if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+				return "", fmt.Errorf("create directory: %w", err)
+			}
+			if err := os.WriteFile(fullPath, data, 0644); err != nil {
+				return "", fmt.Errorf("write file: %w", err)
+			}
+			return relPath, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("read file: %w", err)
+		}
+		if bytes.Equal(existing, data) {
+			return relPath, nil
+		}
+	}
+}
+
+// embedTarget returns the file name when it resolves to relPath from the
+// note, as Obsidian prefers, and the vault-relative path otherwise.
+func embedTarget(scanner *vault.Scanner, notePath, relPath string) string {
+	name := filepath.Base(relPath)
+	if found, err := scanner.FindAttachment(name, notePath); err == nil && found == relPath {
+		return name
+	}
+	return filepath.ToSlash(relPath)
+}
+
+// attachmentMapper embeds the Notion-hosted files of a page fetched
+// without its attachments as the local files the note's attachments were
+// uploaded from, matched by name, so the page reads back as the note it
+// was pushed from. Files it has no record of keep their Notion URLs. It
+// implements transformer.AttachmentSaver.
+type attachmentMapper struct {
+	scanner  *vault.Scanner
+	notePath string
+	paths    map[string]string // Vault-relative paths by file name
+}
+
+// newAttachmentMapper creates a mapper for the note at notePath from the
+// attachments recorded for it. Failures to read them leave every file
+// unmapped.
+func newAttachmentMapper(db *state.DB, scanner *vault.Scanner, notePath string) *attachmentMapper {
+	m := &attachmentMapper{scanner: scanner, notePath: notePath, paths: make(map[string]string)}
+	attachments, err := state.NewAttachmentStore(db).ForNote(notePath)
+	if err != nil {
+		return m
+	}
+	for _, a := range attachments {
+		relPath := filepath.FromSlash(a.OriginalPath)
+		if _, ok := m.paths[filepath.Base(relPath)]; !ok {
+			m.paths[filepath.Base(relPath)] = relPath
+		}
+	}
+	return m
+}
+
+// SaveAttachment returns the embed target of the recorded file of a name.
+func (m *attachmentMapper) SaveAttachment(fileURL, name string) (string, bool) {
+	relPath, ok := m.paths[name]
+	if !ok {
+		return "", false
+	}
+	return embedTarget(m.scanner, m.notePath, relPath), true
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+// bookmarkPreviewLimit is how much of a bookmarked page is read looking
+// for its title and description, which belong in the head.
+const bookmarkPreviewLimit = 256 << 10
+
+var (
+	// htmlTitleRegex matches the title element of an HTML page.
+	htmlTitleRegex = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+	// htmlMetaRegex matches meta elements.
+	htmlMetaRegex = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+
+	// htmlAttrRegex matches the quoted attributes of an element.
+	htmlAttrRegex = regexp.MustCompile(`(?is)([a-z:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// bookmarkPreviewer fetches the title and description of bookmarked pages
+// for pull.bookmark_previews. It implements transformer.BookmarkPreviewer.
+type bookmarkPreviewer struct {
+	ctx      context.Context
+	client   *http.Client
+	notePath string
+}
+
+// newBookmarkPreviewer returns a previewer for the bookmarks of the note
+// at notePath, or nil if pull.bookmark_previews is off.
+func newBookmarkPreviewer(ctx context.Context, cfg *config.Config, notePath string) transformer.BookmarkPreviewer {
+	if !cfg.Pull.BookmarkPreviews {
+		return nil
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	if httpClient != nil {
+		client.Transport = httpClient.Transport
+	}
+	return &bookmarkPreviewer{
+		ctx:      ctx,
+		client:   client,
+		notePath: notePath,
+	}
+}
+
+// BookmarkPreview implements transformer.BookmarkPreviewer. Pages that
+// cannot be fetched are reported with a warning and get no preview.
+func (p *bookmarkPreviewer) BookmarkPreview(url string) (transformer.BookmarkPreview, bool) {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return transformer.BookmarkPreview{}, false
+	}
+	page, err := p.fetch(url)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: bookmark preview %s in %s: %v\n", url, p.notePath, err)
+		return transformer.BookmarkPreview{}, false
+	}
+	preview := parseBookmarkPreview(page)
+	return preview, preview.Title != "" || preview.Description != ""
+}
+
+// fetch reads the start of an HTML page.
+func (p *bookmarkPreviewer) fetch(url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(p.ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/html")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch: %s", resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("not an HTML page (%s)", contentType)
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, bookmarkPreviewLimit))
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+	return page, nil
+}
+
+// parseBookmarkPreview reads the title and description of an HTML page,
+// preferring its Open Graph ones.
+func parseBookmarkPreview(page []byte) transformer.BookmarkPreview {
+	var preview transformer.BookmarkPreview
+	var title, description string
+	for _, meta := range htmlMetaRegex.FindAll(page, -1) {
+		attrs := make(map[string]string)
+		for _, m := range htmlAttrRegex.FindAllSubmatch(meta, -1) {
+			attrs[strings.ToLower(string(m[1]))] = string(m[2]) + string(m[3])
+		}
+		key := strings.ToLower(attrs["property"])
+		if key == "" {
+			key = strings.ToLower(attrs["name"])
+		}
+		content := html.UnescapeString(attrs["content"])
+		switch key {
+		case "og:title":
+			preview.Title = content
+		case "og:description":
+			preview.Description = content
+		case "description":
+			description = content
+		}
+	}
+	if m := htmlTitleRegex.FindSubmatch(page); m != nil {
+		title = html.UnescapeString(string(m[1]))
+	}
+
+	if strings.TrimSpace(preview.Title) == "" {
+		preview.Title = title
+	}
+	if strings.TrimSpace(preview.Description) == "" {
+		preview.Description = description
+	}
+	return preview
+}
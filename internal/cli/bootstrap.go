@@ -0,0 +1,414 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	osync "github.com/adamancini/obsidian-notion-sync/internal/sync"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+var (
+	bootstrapMaxRequests int
+	bootstrapNoMatch     bool
+)
+
+// bootstrapCmd represents the bootstrap command.
+var bootstrapCmd = &cobra.Command{
+	Use:   "bootstrap",
+	Short: "Push a whole vault to Notion for the first time, resumably",
+	Long: `Push every note of a large vault to Notion for the first time, in
+phases that can be interrupted and resumed:
+
+  1. create  Create a page for each new note, with its properties only.
+  2. fill    Push the content of each created page. Wiki-links resolve
+             here, since every note already has a page.
+  3. links   Update notes filled by an earlier run before the pages
+             they link to existed.
+
+Progress is checkpointed in the state database: a page that has been
+created but not filled is recorded as pending. Stopping bootstrap with
+Ctrl-C, or when --max-requests runs out, loses no work; run it again to
+continue where it stopped. A plain push also fills pending pages.
+
+Notes that are already synced are left alone, and notes covered by a
+composition rule are left for the next push. Relation properties are not
+synced by this tool, so there is no phase for them.
+
+Examples:
+  obsidian-notion bootstrap                      # Push the whole vault
+  obsidian-notion bootstrap --max-requests 5000  # Stop after 5000 requests`,
+	Args: cobra.NoArgs,
+	RunE: runBootstrap,
+}
+
+func init() {
+	bootstrapCmd.Flags().IntVar(&bootstrapMaxRequests, "max-requests", 0, "stop before making more than this many API requests (0 for no limit)")
+	bootstrapCmd.Flags().BoolVar(&bootstrapNoMatch, "no-match", false, "create new pages without matching notes to pages already in Notion")
+	rootCmd.AddCommand(bootstrapCmd)
+}
+
+// bootstrapPending is the status of a note whose page bootstrap created
+// but has not filled yet.
+const bootstrapPending = "pending"
+
+// bootstrapTally counts the outcome of one bootstrap phase.
+type bootstrapTally struct {
+	done, failed int
+}
+
+// add counts the results of a phase, reporting failures. Notes the phase
+// did not reach, or that ran out of budget or were interrupted, are not
+// counted; they are left for the next run.
+func (t *bootstrapTally) add(results []osync.Task[pushFile, pushResult], prefix string) {
+	for _, r := range results {
+		switch {
+		case errors.Is(r.Err, errOverBudget), errors.Is(r.Err, context.Canceled), errors.Is(r.Err, notion.ErrBudgetExhausted):
+		case r.Err != nil:
+			printPushError(os.Stderr, prefix, r.Input.path, r.Err)
+			t.failed++
+		case r.Result.pageID != "":
+			t.done++
+		}
+	}
+}
+
+func runBootstrap(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	var budget *notion.Budget
+	var clientOpts []notion.ClientOption
+	if bootstrapMaxRequests > 0 {
+		budget = notion.NewBudget(int64(bootstrapMaxRequests))
+		clientOpts = append(clientOpts, notion.WithBudget(budget))
+	}
+	clients := newNotionClients(cfg, clientOpts...)
+	scanner := newScanner(cfg)
+	linkRegistry := state.NewLinkRegistry(db)
+	pc := &pushContext{
+		cfg:          cfg,
+		db:           db,
+		clients:      clients,
+		linkRegistry: linkRegistry,
+		attachments:  newAttachmentUploader(cfg, db, clients, scanner),
+		parser:       parser.New(),
+		scanner:      scanner,
+		undo:         newUndoRecorder(cfg, db, linkRegistry, "bootstrap", started),
+		budget:       budget,
+		templates:    &templateCache{},
+	}
+	workers := cfg.RateLimit.Workers
+	if workers < 1 {
+		workers = 4
+	}
+	pool := osync.NewWorkerPool(workers)
+
+	// 1. Create a page for each new note.
+	creates, skipped, composed, err := bootstrapCreates(ctx, pc)
+	if err != nil {
+		return err
+	}
+	var created bootstrapTally
+	var createdPaths map[string]bool
+	if len(creates) > 0 {
+		fmt.Printf("Phase 1/3: creating %d page(s)...\n", len(creates))
+		results := runPhase(ctx, pool, creates, pc.createEmptyPage)
+		created.add(results, "Error creating")
+		createdPaths = donePaths(results)
+	}
+
+	// 2. Fill every created page, including those of earlier runs.
+	var filled bootstrapTally
+	var filledPaths map[string]bool
+	if ctx.Err() == nil {
+		fills, err := bootstrapFills(pc)
+		if err != nil {
+			return err
+		}
+		if len(fills) > 0 {
+			fmt.Printf("Phase 2/3: filling %d page(s)...\n", len(fills))
+			results := runPhase(ctx, pool, fills, pc.processFile)
+			filled.add(results, "Error filling")
+			filledPaths = donePaths(results)
+		}
+	}
+
+	// 3. Update notes filled before their link targets had pages.
+	var linkUpdates int
+	if ctx.Err() == nil && (len(createdPaths) > 0 || len(filledPaths) > 0) {
+		fmt.Println("Phase 3/3: resolving links...")
+		linkUpdates = bootstrapLinks(ctx, pc, createdPaths, filledPaths)
+	}
+
+	recordRun(db, clients, "bootstrap", started, created.done+filled.done, 0, created.failed+filled.failed)
+
+	pending, err := bootstrapRemaining(pc)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	if pending > 0 {
+		fmt.Println("Bootstrap stopped:")
+	} else {
+		fmt.Println("Bootstrap complete:")
+	}
+	fmt.Printf("  Created: %d\n", created.done)
+	fmt.Printf("  Filled:  %d\n", filled.done)
+	if linkUpdates > 0 {
+		fmt.Printf("  Links updated: %d page(s)\n", linkUpdates)
+	}
+	if failed := created.failed + filled.failed; failed > 0 {
+		fmt.Printf("  Failed:  %d\n", failed)
+	}
+	if pending > 0 {
+		fmt.Printf("  Pending: %d (run 'obsidian-notion bootstrap' again to continue)\n", pending)
+	}
+	if len(composed) > 0 {
+		fmt.Printf("  Composed notes left for push: %d\n", len(composed))
+	}
+	usage := clients.Usage()
+	if budget != nil {
+		fmt.Printf("  API requests: %d of %d\n", usage.Requests, budget.Limit())
+	} else {
+		fmt.Printf("  API requests: %d\n", usage.Requests)
+	}
+	printSkipped(skipped)
+	return nil
+}
+
+// donePaths returns the paths of the notes a phase finished.
+func donePaths(results []osync.Task[pushFile, pushResult]) map[string]bool {
+	paths := make(map[string]bool, len(results))
+	for _, r := range results {
+		if r.Err == nil && r.Result.pageID != "" {
+			paths[r.Input.path] = true
+		}
+	}
+	return paths
+}
+
+// runPhase runs one bootstrap phase over files with a progress bar.
+func runPhase(ctx context.Context, pool *osync.WorkerPool, files []pushFile, fn func(context.Context, pushFile) (pushResult, error)) []osync.Task[pushFile, pushResult] {
+	progress := osync.NewProgress(len(files), os.Stdout)
+	progress.SetEnabled(!verbose)
+	results := osync.ProcessWithProgress(ctx, pool, files, fn, progress.SimpleCallback())
+	progress.Finish()
+	return results
+}
+
+// bootstrapCreates returns the notes that have no page yet, leaving out
+// notes that are too large, pull-only, or composed. On the first push,
+// notes matching pages already in Notion are linked to them instead.
+func bootstrapCreates(ctx context.Context, pc *pushContext) (creates []pushFile, skipped []skippedFile, composed []pushFile, err error) {
+	files, err := getFilesToPush(ctx, pc.cfg, pc.db)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("get files to push: %w", err)
+	}
+	for _, f := range files {
+		if f.changeType == state.ChangeCreated {
+			creates = append(creates, f)
+		}
+	}
+	creates, skipped = skipUnsyncable(pc.cfg, pc.scanner, creates)
+	creates, _ = blockPushFiles(pc.cfg, creates)
+	composed, creates = splitComposed(pc.cfg, creates)
+
+	if !bootstrapNoMatch && len(creates) > 0 {
+		first, err := isFirstPush(pc.db)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("list sync state: %w", err)
+		}
+		if first {
+			if creates, err = matchFirstPush(ctx, pc.cfg, pc.db, pc.clients, creates); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+	return creates, skipped, composed, nil
+}
+
+// bootstrapFills returns the notes whose pages bootstrap created but has
+// not filled.
+func bootstrapFills(pc *pushContext) ([]pushFile, error) {
+	states, err := pc.db.ListStates(bootstrapPending)
+	if err != nil {
+		return nil, fmt.Errorf("list sync state: %w", err)
+	}
+	var fills []pushFile
+	for _, s := range states {
+		if s.NotionPageID == "" {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(pc.cfg.Vault, s.ObsidianPath))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: cannot fill %s: %v\n", s.ObsidianPath, err)
+			continue
+		}
+		fills = append(fills, pushFile{
+			path:       s.ObsidianPath,
+			state:      s,
+			mtime:      info.ModTime(),
+			changeType: state.ChangeModified,
+		})
+	}
+	return fills, nil
+}
+
+// bootstrapRemaining counts the notes a later bootstrap still has to
+// create or fill.
+func bootstrapRemaining(pc *pushContext) (int, error) {
+	fills, err := bootstrapFills(pc)
+	if err != nil {
+		return 0, err
+	}
+	files, err := getFilesToPush(context.Background(), pc.cfg, pc.db)
+	if err != nil {
+		return 0, fmt.Errorf("get files to push: %w", err)
+	}
+	var creates []pushFile
+	for _, f := range files {
+		if f.changeType == state.ChangeCreated {
+			creates = append(creates, f)
+		}
+	}
+	creates, _ = skipUnsyncable(pc.cfg, pc.scanner, creates)
+	creates, _ = blockPushFiles(pc.cfg, creates)
+	_, creates = splitComposed(pc.cfg, creates)
+	return len(fills) + len(creates), nil
+}
+
+// createEmptyPage creates a note's page with its properties only, and its
+// template's content if it has one, as push creates pages, and records it
+// as pending, so the fill phase, or any push, pushes its content.
+// Registering the page lets links to the note resolve.
+func (pc *pushContext) createEmptyPage(ctx context.Context, f pushFile) (pushResult, error) {
+	if pc.budget != nil {
+		release, ok := pc.budget.Reserve(1)
+		if !ok {
+			return pushResult{}, errOverBudget
+		}
+		defer release()
+	}
+
+	content, err := os.ReadFile(filepath.Join(pc.cfg.Vault, f.path))
+	if err != nil {
+		return pushResult{}, fmt.Errorf("read file: %w", err)
+	}
+	note, err := pc.parser.Parse(f.path, content)
+	if err != nil {
+		return pushResult{}, fmt.Errorf("parse markdown: %w", err)
+	}
+	registerNoteLinks(pc.linkRegistry, f.path, note)
+
+	page, err := transformer.New(pc.linkRegistry, buildTransformerConfig(pc.cfg, f.path)).Transform(note)
+	if err != nil {
+		return pushResult{}, fmt.Errorf("transform to Notion: %w", err)
+	}
+	parentID := pc.newPageParent(f.path, note)
+	empty := &transformer.NotionPage{Properties: page.Properties}
+	before, after, err := pc.applyTemplate(ctx, f.path, empty)
+	if err != nil {
+		return pushResult{}, err
+	}
+	result, err := pc.createPage(ctx, pc.clients.ForPath(f.path), parentID, empty)
+	if err != nil {
+		return pushResult{}, fmt.Errorf("create page: %w", err)
+	}
+	pc.undo.created(f.path, result.PageID, f.state)
+
+	// The note gets the template's content its page was created with, which
+	// the fill phase pushes back with the note's own.
+	fullPath := filepath.Join(pc.cfg.Vault, f.path)
+	rt := transformer.NewReverse(pc.linkRegistry, buildTransformerConfig(pc.cfg, f.path))
+	if err := writeTemplated(fullPath, rt, before, after); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to add template content to %s: %v\n", f.path, err)
+	} else if info, err := os.Stat(fullPath); err == nil {
+		f.mtime = info.ModTime()
+	}
+
+	if err := pc.db.SetState(&state.SyncState{
+		ObsidianPath:   f.path,
+		NotionPageID:   result.PageID,
+		NotionParentID: parentID,
+		ObsidianMtime:  f.mtime,
+		NotionMtime:    time.Now(),
+		LastSync:       time.Now(),
+		SyncDirection:  "push",
+		Status:         bootstrapPending,
+	}); err != nil {
+		return pushResult{}, fmt.Errorf("update state: %w", err)
+	}
+	return pushResult{pageID: result.PageID, parentID: parentID, isNew: true}, nil
+}
+
+// bootstrapLinks marks the links that now resolve, and re-pushes notes
+// filled by an earlier run that link to pages this run created: their
+// targets had no page yet. Notes filled in this run already link to every
+// page. It returns how many notes were updated.
+func bootstrapLinks(ctx context.Context, pc *pushContext, created, filled map[string]bool) int {
+	if _, err := pc.linkRegistry.ResolveAll(); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: partial link resolution failure: %v\n", err)
+	}
+
+	sources := make(map[string]bool)
+	for path := range created {
+		backlinks, err := pc.linkRegistry.GetBacklinks(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: cannot check links to %s: %v\n", path, err)
+			continue
+		}
+		for _, link := range backlinks {
+			if !created[link.SourcePath] && !filled[link.SourcePath] {
+				sources[link.SourcePath] = true
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(sources))
+	for path := range sources {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	updates := 0
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		updated, err := repushLinkedPage(ctx, pc.cfg, pc.db, pc.clients, pc.linkRegistry, pc.attachments, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: %v\n", err)
+			continue
+		}
+		if updated {
+			updates++
+		}
+	}
+	return updates
+}
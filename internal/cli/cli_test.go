@@ -1,18 +1,39 @@
 package cli
 
 import (
+	"archive/zip"
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/jomei/notionapi"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 
 	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/notionexport"
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
 	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	osync "github.com/adamancini/obsidian-notion-sync/internal/sync"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+	"github.com/adamancini/obsidian-notion-sync/internal/vault"
 )
 
 // =============================================================================
@@ -76,6 +97,81 @@ func TestScoreToLabel(t *testing.T) {
 	}
 }
 
+func TestParseMinScore(t *testing.T) {
+	for _, label := range []string{"exact", "case-insensitive", "prefix", "fuzzy"} {
+		score, err := parseMinScore(label)
+		if err != nil || scoreToLabel(score) != label {
+			t.Errorf("parseMinScore(%q) = %d, %v", label, score, err)
+		}
+	}
+	if _, err := parseMinScore("none"); err == nil {
+		t.Error("parseMinScore(\"none\") should fail")
+	}
+}
+
+func TestReplaceWikiLinkTarget(t *testing.T) {
+	content := "See [[Meting Notes]], [[Meting Notes#Agenda|agenda]], and ![[Meting Notes.md^summary]].\n" +
+		"Not [[Meting Notes Archive]] or [[Other]].\n"
+	want := "See [[Meeting Notes]], [[Meeting Notes#Agenda|agenda]], and ![[Meeting Notes^summary]].\n" +
+		"Not [[Meting Notes Archive]] or [[Other]].\n"
+
+	got, n := replaceWikiLinkTarget(content, "Meting Notes", "Meeting Notes")
+	if got != want || n != 3 {
+		t.Errorf("replaceWikiLinkTarget() = %q, %d; want %q, 3", got, n, want)
+	}
+}
+
+func TestRunLinksRepair_ApplySuggestions(t *testing.T) {
+	dir := t.TempDir()
+	db, err := state.Open(filepath.Join(dir, ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.SetState(&state.SyncState{ObsidianPath: "Projects/Roadmap 2026.md", NotionPageID: "page-1", Status: "synced"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.md"), []byte("Plan: [[Roadmap#Q1]] and [[Budget]]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	registry := state.NewLinkRegistry(db)
+	if err := registry.RegisterLinks("index.md", []string{"Roadmap#Q1", "Budget"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runLinksRepair(dir, registry, state.MatchPrefix, true, false); err != nil {
+		t.Fatalf("runLinksRepair() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "Plan: [[Roadmap 2026#Q1]] and [[Budget]]\n" {
+		t.Errorf("index.md = %q", data)
+	}
+
+	links, err := registry.GetLinksFrom("index.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range links {
+		switch l.TargetName {
+		case "Roadmap 2026#Q1":
+			if !l.Resolved || l.NotionPageID != "page-1" {
+				t.Errorf("rewritten link = %+v; want resolved to page-1", l)
+			}
+		case "Budget":
+			if l.Resolved {
+				t.Errorf("Budget link resolved without a match")
+			}
+		default:
+			t.Errorf("unexpected link target %q", l.TargetName)
+		}
+	}
+}
+
 func TestPrintStatusLine(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -152,7 +248,7 @@ func TestSanitizeFilename(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got := sanitizeFilename(tc.input)
+			got := sanitizeFilename(tc.input, config.FilenamesConfig{})
 			if got != tc.want {
 				t.Errorf("sanitizeFilename(%q) = %q; want %q", tc.input, got, tc.want)
 			}
@@ -160,41 +256,89 @@ func TestSanitizeFilename(t *testing.T) {
 	}
 }
 
-func TestIsNotFoundError(t *testing.T) {
+func TestSanitizeFilename_Options(t *testing.T) {
+	nfd := "Cafe\u0301 notes"
 	tests := []struct {
-		name    string
-		errStr  string
-		want    bool
+		name  string
+		input string
+		opts  config.FilenamesConfig
+		want  string
 	}{
-		{"nil error", "", false},
-		{"not found lowercase", "page not found", true},
-		{"could not find", "Could not find page", true},
-		{"404 error", "request failed with status 404", true},
-		{"other error", "rate limit exceeded", false},
-		{"network error", "connection timeout", false},
+		{"nfc by default", nfd, config.FilenamesConfig{}, "Caf\u00e9 notes"},
+		{"nfd", "Caf\u00e9 notes", config.FilenamesConfig{Unicode: "nfd"}, nfd},
+		{"no normalization", nfd, config.FilenamesConfig{Unicode: "none"}, nfd},
+		{"emoji kept", "\U0001F680 Launch", config.FilenamesConfig{}, "\U0001F680 Launch"},
+		{"emoji stripped", "\U0001F680 Launch \u2728 plan", config.FilenamesConfig{Emoji: "strip"}, "Launch plan"},
+		{"emoji transliterated", "\U0001F680Launch", config.FilenamesConfig{Emoji: "transliterate"}, "rocket Launch"},
+		{"only emoji stripped", "\U0001F680", config.FilenamesConfig{Emoji: "strip"}, "Untitled"},
+		{"control characters", "Tab\there", config.FilenamesConfig{}, "Tabhere"},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			var err error
-			if tc.errStr != "" {
-				err = &testError{msg: tc.errStr}
-			}
-			got := isNotFoundError(err)
+			got := sanitizeFilename(tc.input, tc.opts)
 			if got != tc.want {
-				t.Errorf("isNotFoundError(%v) = %v; want %v", err, got, tc.want)
+				t.Errorf("sanitizeFilename(%q) = %q; want %q", tc.input, got, tc.want)
 			}
 		})
 	}
 }
 
-// testError is a simple error implementation for testing.
-type testError struct {
-	msg string
+func TestNoteNames(t *testing.T) {
+	vault := t.TempDir()
+	if err := os.WriteFile(filepath.Join(vault, "Plan.md"), []byte("# Plan"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vault, "Cafe\u0301.md"), []byte("# Cafe"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	orig := caseInsensitiveFS
+	t.Cleanup(func() { caseInsensitiveFS = orig })
+
+	caseInsensitiveFS = false
+	names := newNoteNames(vault)
+	for _, tc := range []struct{ path, want string }{
+		{"Plan.md", "Plan 1.md"},
+		{"Plan.md", "Plan 2.md"},
+		{"plan.md", "plan.md"},
+		{"Caf\u00e9.md", "Caf\u00e9 1.md"},
+		{"Other.md", "Other.md"},
+	} {
+		if got := names.claim(tc.path); got != tc.want {
+			t.Errorf("claim(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+
+	caseInsensitiveFS = true
+	names = newNoteNames(vault)
+	if got := names.claim("PLAN.md"); got != "PLAN 1.md" {
+		t.Errorf("claim(PLAN.md) on a case-insensitive filesystem = %q, want %q", got, "PLAN 1.md")
+	}
 }
 
-func (e *testError) Error() string {
-	return e.msg
+func TestNotionHint(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil error", nil, ""},
+		{"other error", errors.New("connection timeout"), ""},
+		{"not found", fmt.Errorf("get page: %w", &notion.Error{Kind: notion.ErrNotFound}), "Connections"},
+		{"unauthorized", &notion.Error{Kind: notion.ErrUnauthorized}, "obsidian-notion doctor"},
+		{"rate limited", &notion.Error{Kind: notion.ErrRateLimited}, "rate_limit.requests_per_second"},
+		{"validation", &notion.Error{Kind: notion.ErrValidation, BlockPath: "children[4].code.rich_text"}, "at children[4].code.rich_text"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := notionHint(tc.err)
+			if (tc.want == "") != (got == "") || !strings.Contains(got, tc.want) {
+				t.Errorf("notionHint(%v) = %q; want it to mention %q", tc.err, got, tc.want)
+			}
+		})
+	}
 }
 
 // =============================================================================
@@ -389,6 +533,287 @@ func TestFilterByPath(t *testing.T) {
 	}
 }
 
+func TestSkipUnsyncable(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "small.md"), []byte("# Small\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "large.md"), bytes.Repeat([]byte("x"), 2048), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Vault: dir, Sync: config.SyncConfig{MaxNoteSize: "1KB"}}
+	scanner := vault.NewScanner(dir, nil)
+	files := []pushFile{
+		{path: "small.md", changeType: state.ChangeModified},
+		{path: "large.md", changeType: state.ChangeCreated},
+		{path: "gone.md", changeType: state.ChangeDeleted},
+	}
+
+	kept, skipped := skipUnsyncable(cfg, scanner, files)
+	if len(kept) != 2 || kept[0].path != "small.md" || kept[1].path != "gone.md" {
+		t.Errorf("kept = %v; want small.md and gone.md", kept)
+	}
+	if len(skipped) != 1 || skipped[0].path != "large.md" {
+		t.Fatalf("skipped = %v; want large.md", skipped)
+	}
+	if skipped[0].reason != "2.0 KB, over the 1.0 KB limit" {
+		t.Errorf("reason = %q", skipped[0].reason)
+	}
+}
+
+func TestDirectionPolicies(t *testing.T) {
+	cfg := &config.Config{Sync: config.SyncConfig{Directions: []config.DirectionPolicy{
+		{Path: "templates/", Direction: config.DirectionPush},
+		{Path: "clippings/", Direction: config.DirectionPull},
+	}}}
+
+	files := []pushFile{
+		{path: "templates/daily.md", changeType: state.ChangeModified},
+		{path: "clippings/article.md", changeType: state.ChangeModified},
+		{path: "notes/moved.md", oldPath: "clippings/moved.md", changeType: state.ChangeRenamed},
+		{path: "notes/idea.md", changeType: state.ChangeCreated},
+	}
+	kept, blocked := blockPushFiles(cfg, files)
+	if len(kept) != 2 || kept[0].path != "templates/daily.md" || kept[1].path != "notes/idea.md" {
+		t.Errorf("kept push files = %v", kept)
+	}
+	if len(blocked) != 2 || blocked[0].path != "clippings/article.md" || blocked[1].policy != config.DirectionPull {
+		t.Errorf("blocked push files = %v", blocked)
+	}
+
+	pages := []pullPage{{localPath: "templates/daily.md"}, {localPath: "clippings/article.md"}}
+	keptPages, blocked := blockPullPages(cfg, pages)
+	if len(keptPages) != 1 || keptPages[0].localPath != "clippings/article.md" {
+		t.Errorf("kept pull pages = %v", keptPages)
+	}
+	if len(blocked) != 1 || blocked[0].path != "templates/daily.md" || blocked[0].policy != config.DirectionPush {
+		t.Errorf("blocked pull pages = %v", blocked)
+	}
+
+	changes := []state.Change{{Path: "templates/daily.md"}, {Path: "notes/idea.md"}}
+	keptChanges, blocked := blockChanges(cfg, changes, state.DirectionPull)
+	if len(keptChanges) != 1 || keptChanges[0].Path != "notes/idea.md" || len(blocked) != 1 {
+		t.Errorf("blockChanges(pull) = %v, %v", keptChanges, blocked)
+	}
+
+	var out bytes.Buffer
+	printBlocked(&out, blocked)
+	if want := "  Blocked: 1 (sync.directions)\n    templates/daily.md (push-only)\n"; out.String() != want {
+		t.Errorf("printBlocked() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPrintArchived(t *testing.T) {
+	var out bytes.Buffer
+	printArchived(&out, nil)
+	if out.Len() != 0 {
+		t.Errorf("printArchived(nil) = %q, want no output", out.String())
+	}
+
+	printArchived(&out, []pullPage{{localPath: "old/plan.md"}, {localPath: "retro.md"}})
+	want := "  Archived remotely: 2 (not pulled; use --include-archived)\n    old/plan.md\n    retro.md\n"
+	if out.String() != want {
+		t.Errorf("printArchived() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestAttachmentDownloader(t *testing.T) {
+	files := map[string]string{"/a.png": "image a", "/b.png": "image b"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cfg := &config.Config{Vault: dir}
+	scanner := vault.NewScanner(dir, nil)
+	scanner.SetAttachmentFolder("Assets")
+	d := newAttachmentDownloader(context.Background(), cfg, scanner, "Notes/note.md")
+
+	if target, ok := d.SaveAttachment(srv.URL+"/a.png", "photo.png"); !ok || target != "photo.png" {
+		t.Errorf("first save = %q, %v; want photo.png", target, ok)
+	}
+	// The same content reuses the file.
+	if target, ok := d.SaveAttachment(srv.URL+"/a.png", "photo.png"); !ok || target != "photo.png" {
+		t.Errorf("repeat save = %q, %v; want photo.png", target, ok)
+	}
+	// Different content with the same name gets a numbered name.
+	if target, ok := d.SaveAttachment(srv.URL+"/b.png", "photo.png"); !ok || target != "photo 1.png" {
+		t.Errorf("clashing save = %q, %v; want photo 1.png", target, ok)
+	}
+	if _, ok := d.SaveAttachment(srv.URL+"/missing.png", "missing.png"); ok {
+		t.Error("missing file was saved")
+	}
+
+	for name, want := range map[string]string{"photo.png": "image a", "photo 1.png": "image b"} {
+		data, err := os.ReadFile(filepath.Join(dir, "Assets", name))
+		if err != nil || string(data) != want {
+			t.Errorf("Assets/%s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+
+	// A file of the same name next to the note shadows the attachment
+	// folder, so the embed needs the full path.
+	if err := os.MkdirAll(filepath.Join(dir, "Notes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Notes", "other.png"), []byte("local"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if target, ok := d.SaveAttachment(srv.URL+"/a.png", "other.png"); !ok || target != "Assets/other.png" {
+		t.Errorf("shadowed save = %q, %v; want Assets/other.png", target, ok)
+	}
+}
+
+func TestSearchScope(t *testing.T) {
+	cfg := &config.Config{
+		Notion: config.NotionConfig{
+			DefaultDatabase: "11111111-2222-3333-4444-555555555555",
+			DefaultPage:     "aaaaaaaabbbbccccddddeeeeeeeeeeee",
+		},
+		Mappings: []config.FolderMapping{
+			{Path: "work/**", Database: "99999999999999999999999999999999"},
+			{Path: "other/**", Database: "11111111-2222-3333-4444-555555555555"},
+		},
+	}
+	scope := newSearchScope(cfg)
+	if len(scope.databases) != 2 {
+		t.Errorf("databases = %v; want 2 distinct IDs", scope.databases)
+	}
+
+	tests := []struct {
+		name   string
+		parent notionapi.Parent
+		want   bool
+	}{
+		{"default database", notionapi.Parent{DatabaseID: "11111111222233334444555555555555"}, true},
+		{"mapping database", notionapi.Parent{DatabaseID: "99999999-9999-9999-9999-999999999999"}, true},
+		{"default page", notionapi.Parent{PageID: "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"}, true},
+		{"other database", notionapi.Parent{DatabaseID: "00000000000000000000000000000000"}, false},
+		{"workspace", notionapi.Parent{Workspace: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scope.contains(&notionapi.Page{Parent: tt.parent}); got != tt.want {
+				t.Errorf("contains() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitNewPages(t *testing.T) {
+	files := []pushFile{
+		{path: "new.md", state: nil},
+		{path: "pending.md", state: &state.SyncState{Status: "pending"}},
+		{path: "synced.md", state: &state.SyncState{NotionPageID: "page-1", Status: "synced"}},
+	}
+
+	creates, modifies := splitNewPages(files)
+	if len(creates) != 2 || creates[0].path != "new.md" || creates[1].path != "pending.md" {
+		t.Errorf("creates = %v; want new.md and pending.md", creates)
+	}
+	if len(modifies) != 1 || modifies[0].path != "synced.md" {
+		t.Errorf("modifies = %v; want synced.md", modifies)
+	}
+}
+
+func TestSplitComposed(t *testing.T) {
+	cfg := &config.Config{
+		Compositions: []config.Composition{{Path: "meetings/*.md", Page: "page-1"}},
+	}
+	files := []pushFile{
+		{path: "meetings/2024-01-15.md"},
+		{path: "notes/idea.md"},
+		{path: "archive/old.md", oldPath: "meetings/old.md", changeType: state.ChangeRenamed},
+	}
+
+	composed, rest := splitComposed(cfg, files)
+	if len(composed) != 2 || composed[0].path != "meetings/2024-01-15.md" || composed[1].path != "archive/old.md" {
+		t.Errorf("composed = %v; want the meeting note and the note moved out", composed)
+	}
+	if len(rest) != 1 || rest[0].path != "notes/idea.md" {
+		t.Errorf("rest = %v; want notes/idea.md", rest)
+	}
+}
+
+func TestCompositeHeadingAndOrder(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+
+	if got := compositeDate("meetings/2024-01-15 review.md", nil); !got.Equal(day("2024-01-15")) {
+		t.Errorf("compositeDate() from filename = %v", got)
+	}
+	if got := compositeDate("meetings/review.md", map[string]any{"date": "2024-02-01"}); !got.Equal(day("2024-02-01")) {
+		t.Errorf("compositeDate() from frontmatter = %v", got)
+	}
+	if got := compositeHeading("2024-01-15 review", day("2024-01-15")); got != "2024-01-15 review" {
+		t.Errorf("compositeHeading() = %q; date should not repeat", got)
+	}
+	if got := compositeHeading("Review", day("2024-01-15")); got != "2024-01-15 Review" {
+		t.Errorf("compositeHeading() = %q; want date prefix", got)
+	}
+
+	notes := []*compositeNote{
+		{path: "c.md"},
+		{path: "b.md", date: day("2024-02-01")},
+		{path: "a.md", date: day("2024-01-01")},
+	}
+	sortCompositeNotes(notes, "date")
+	if notes[0].path != "a.md" || notes[1].path != "b.md" || notes[2].path != "c.md" {
+		t.Errorf("date order = %s, %s, %s; want a, b, c", notes[0].path, notes[1].path, notes[2].path)
+	}
+}
+
+func TestSplitComposedBlocks(t *testing.T) {
+	para := func(text string) notionapi.Block {
+		return &notionapi.ParagraphBlock{
+			BasicBlock: notionapi.BasicBlock{Type: notionapi.BlockTypeParagraph},
+			Paragraph: notionapi.Paragraph{
+				RichText: []notionapi.RichText{{Text: &notionapi.Text{Content: text}}},
+			},
+		}
+	}
+
+	blocks := []notionapi.Block{
+		para("intro"),
+		compositeHeadingBlock("2024-01-08 Kickoff"),
+		para("first"),
+		compositeHeadingBlock("Agenda"), // a note's own heading stays in its section
+		compositeHeadingBlock("2024-01-15 Review"),
+		para("second"),
+	}
+	headings := []string{"2024-01-08 Kickoff", "2024-01-15 Review", "2024-01-22 Retro"}
+
+	sections, missing := splitComposedBlocks(blocks, headings)
+	if len(sections[0]) != 3 {
+		t.Errorf("section 0 has %d blocks; want intro, paragraph, and heading", len(sections[0]))
+	}
+	if len(sections[1]) != 1 {
+		t.Errorf("section 1 has %d blocks; want 1", len(sections[1]))
+	}
+	if len(missing) != 1 || missing[0] != 2 {
+		t.Errorf("missing = %v; want [2]", missing)
+	}
+}
+
+func TestNoteFrontmatter(t *testing.T) {
+	content := []byte("---\ntitle: Kickoff\n---\n\nBody\n")
+	if got := string(noteFrontmatter(content)); got != "---\ntitle: Kickoff\n---\n\n" {
+		t.Errorf("noteFrontmatter() = %q", got)
+	}
+	if got := noteFrontmatter([]byte("Body\n")); got != nil {
+		t.Errorf("noteFrontmatter() = %q; want nil", got)
+	}
+}
+
 func TestCheckConflicts(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -540,6 +965,8 @@ func TestRootCommand_HasExpectedSubcommands(t *testing.T) {
 		"status",
 		"conflicts",
 		"links",
+		"state",
+		"search",
 	}
 
 	for _, cmdName := range expectedCommands {
@@ -570,6 +997,12 @@ func TestRootCommand_HasExpectedFlags(t *testing.T) {
 	if verboseFlag.Shorthand != "v" {
 		t.Errorf("--verbose shorthand = %q; want 'v'", verboseFlag.Shorthand)
 	}
+	if verboseFlag.Value.Type() != "count" {
+		t.Errorf("--verbose type = %q; want count, for -vv", verboseFlag.Value.Type())
+	}
+	if rootCmd.PersistentFlags().Lookup("trace") == nil {
+		t.Error("rootCmd missing --trace flag")
+	}
 }
 
 func TestInitCommand_HasRequiredFlags(t *testing.T) {
@@ -596,7 +1029,7 @@ func TestInitCommand_HasRequiredFlags(t *testing.T) {
 }
 
 func TestPushCommand_HasExpectedFlags(t *testing.T) {
-	flags := []string{"all", "path", "dry-run", "force"}
+	flags := []string{"all", "path", "dry-run", "force", "staged"}
 	for _, flagName := range flags {
 		flag := pushCmd.Flags().Lookup(flagName)
 		if flag == nil {
@@ -606,7 +1039,7 @@ func TestPushCommand_HasExpectedFlags(t *testing.T) {
 }
 
 func TestPullCommand_HasExpectedFlags(t *testing.T) {
-	flags := []string{"all", "path", "dry-run", "force"}
+	flags := []string{"all", "path", "dry-run", "force", "include-archived"}
 	for _, flagName := range flags {
 		flag := pullCmd.Flags().Lookup(flagName)
 		if flag == nil {
@@ -675,6 +1108,164 @@ func TestConflictsCommand_HasResolveSubcommand(t *testing.T) {
 	}
 }
 
+func TestConflictsCommand_HasDiffSubcommand(t *testing.T) {
+	for _, cmd := range conflictsCmd.Commands() {
+		if cmd.Name() == "diff" {
+			for _, name := range []string{"color", "context"} {
+				if cmd.Flags().Lookup(name) == nil {
+					t.Errorf("diff subcommand missing --%s flag", name)
+				}
+			}
+			return
+		}
+	}
+	t.Error("conflictsCmd missing 'diff' subcommand")
+}
+
+func TestColorizeDiff(t *testing.T) {
+	in := "--- a\n+++ b\n@@ -1 +1 @@\n same\n-old\n+new\n"
+	want := ansiBold + "--- a" + ansiReset + "\n" +
+		ansiBold + "+++ b" + ansiReset + "\n" +
+		ansiCyan + "@@ -1 +1 @@" + ansiReset + "\n" +
+		" same\n" +
+		ansiRed + "-old" + ansiReset + "\n" +
+		ansiGreen + "+new" + ansiReset + "\n"
+	if got := colorizeDiff(in); got != want {
+		t.Errorf("colorizeDiff() = %q; want %q", got, want)
+	}
+}
+
+func TestSyncCommand_HasUndoSubcommand(t *testing.T) {
+	for _, cmd := range syncCmd.Commands() {
+		if cmd.Name() == "undo" {
+			if cmd.Flags().Lookup("dry-run") == nil {
+				t.Error("undo subcommand missing --dry-run flag")
+			}
+			return
+		}
+	}
+	t.Error("syncCmd missing 'undo' subcommand")
+}
+
+func TestStateCommand_HasMigrateSubcommand(t *testing.T) {
+	for _, cmd := range stateCmd.Commands() {
+		if cmd.Name() == "migrate" {
+			if cmd.Flags().Lookup("dry-run") == nil {
+				t.Error("migrate subcommand missing --dry-run flag")
+			}
+			return
+		}
+	}
+	t.Error("stateCmd missing 'migrate' subcommand")
+}
+
+func TestRemoveRemoteSidecar(t *testing.T) {
+	vaultDir := t.TempDir()
+	cfg := &config.Config{Vault: vaultDir}
+
+	if got := remoteSidecarPath("notes/Note.md"); got != "notes/Note.md.remote" {
+		t.Errorf("remoteSidecarPath() = %q", got)
+	}
+
+	sidecar := filepath.Join(vaultDir, "Note.md.remote")
+	if err := os.WriteFile(sidecar, []byte("# Remote\n"), 0644); err != nil {
+		t.Fatalf("write sidecar: %v", err)
+	}
+	removeRemoteSidecar(cfg, "Note.md")
+	if _, err := os.Stat(sidecar); !os.IsNotExist(err) {
+		t.Errorf("sidecar still exists after removal: %v", err)
+	}
+
+	// Removing a missing sidecar is not an error.
+	removeRemoteSidecar(cfg, "Note.md")
+}
+
+func TestForgetAndMoveNote(t *testing.T) {
+	db, err := state.Open(filepath.Join(t.TempDir(), ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	registry := state.NewLinkRegistry(db)
+	for _, path := range []string{"a.md", "b.md"} {
+		if err := db.SetState(&state.SyncState{ObsidianPath: path, NotionPageID: "page-" + path, Status: "synced"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := registry.ReplaceLinks(path, []string{"Target"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := moveNote(db, "a.md", "renamed.md"); err != nil {
+		t.Fatalf("moveNote() error: %v", err)
+	}
+	if s, _ := db.GetState("renamed.md"); s == nil || s.NotionPageID != "page-a.md" {
+		t.Errorf("renamed state = %+v", s)
+	}
+	if links, _ := registry.GetLinksFrom("renamed.md"); len(links) != 1 {
+		t.Errorf("renamed links = %d; want 1", len(links))
+	}
+
+	if err := forgetNote(db, "b.md"); err != nil {
+		t.Fatalf("forgetNote() error: %v", err)
+	}
+	if s, _ := db.GetState("b.md"); s != nil {
+		t.Errorf("forgotten state = %+v; want nil", s)
+	}
+	if links, _ := registry.GetLinksFrom("b.md"); len(links) != 0 {
+		t.Errorf("forgotten links = %d; want 0", len(links))
+	}
+}
+
+func TestRestoreSnapshot_Pulled(t *testing.T) {
+	vaultDir := t.TempDir()
+	db, err := state.Open(filepath.Join(vaultDir, ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	cfg := &config.Config{Vault: vaultDir}
+
+	// A pull overwrote an existing note and created a new one.
+	prior := &state.SyncState{ObsidianPath: "note.md", NotionPageID: "page-1", ContentHash: "before", Status: "synced"}
+	if err := db.SetState(&state.SyncState{ObsidianPath: "note.md", NotionPageID: "page-1", ContentHash: "after", Status: "synced"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetState(&state.SyncState{ObsidianPath: "new.md", NotionPageID: "page-2", Status: "synced"}); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"note.md": "# Pulled\n", "new.md": "# New\n"} {
+		if err := os.WriteFile(filepath.Join(vaultDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshots := []*state.Snapshot{
+		{Path: "note.md", Action: state.SnapshotPulled, Content: []byte("# Local\n"), NotionPageID: "page-1", Prior: prior},
+		{Path: "new.md", Action: state.SnapshotPulled, NotionPageID: "page-2"},
+	}
+	for _, s := range snapshots {
+		if err := restoreSnapshot(context.Background(), cfg, db, nil, state.NewLinkRegistry(db), s); err != nil {
+			t.Fatalf("restoreSnapshot(%s) error: %v", s.Path, err)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(vaultDir, "note.md"))
+	if err != nil || string(content) != "# Local\n" {
+		t.Errorf("note.md = %q, %v; want the local version", content, err)
+	}
+	if s, _ := db.GetState("note.md"); s == nil || s.ContentHash != "before" {
+		t.Errorf("note.md state = %+v; want the prior state", s)
+	}
+	if _, err := os.Stat(filepath.Join(vaultDir, "new.md")); !os.IsNotExist(err) {
+		t.Errorf("new.md should be removed, stat error: %v", err)
+	}
+	if s, _ := db.GetState("new.md"); s != nil {
+		t.Errorf("new.md state = %+v; want none", s)
+	}
+}
+
 // =============================================================================
 // Error Message Tests
 // =============================================================================
@@ -832,8 +1423,8 @@ func TestWatcher_ShouldIgnore(t *testing.T) {
 	}
 
 	tests := []struct {
-		path   string
-		want   bool
+		path string
+		want bool
 	}{
 		{"notes.md", false},
 		{"work/project.md", false},
@@ -910,6 +1501,53 @@ func TestFilterByPath_NestedPatterns(t *testing.T) {
 	}
 }
 
+func TestResolveNotePaths(t *testing.T) {
+	vault := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(vault, "notes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vault, "notes", "today.md"), []byte("# Today\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(filepath.Join(vault, "notes"))
+
+	paths, err := resolveNotePaths(vault, []string{
+		"today.md",      // From the current directory
+		"notes/gone.md", // From the vault root
+		filepath.Join(vault, "notes", "today.md"), // Absolute
+	})
+	if err != nil {
+		t.Fatalf("resolveNotePaths() error: %v", err)
+	}
+	want := []string{"notes/today.md", "notes/gone.md", "notes/today.md"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("resolveNotePaths() = %v, want %v", paths, want)
+	}
+
+	for _, arg := range []string{"../../outside.md", filepath.Join(t.TempDir(), "other.md")} {
+		if _, err := resolveNotePaths(vault, []string{arg}); err == nil {
+			t.Errorf("resolveNotePaths(%q) accepted a path outside the vault", arg)
+		}
+	}
+}
+
+func TestFilterByPaths(t *testing.T) {
+	files := []pushFile{
+		{path: "a.md"},
+		{path: "b.md"},
+		{path: "new.md", oldPath: "old.md", changeType: state.ChangeRenamed},
+	}
+	got := filterByPaths(files, []string{"a.md", "old.md"})
+	if len(got) != 2 || got[0].path != "a.md" || got[1].path != "new.md" {
+		t.Errorf("filterByPaths() = %+v, want a.md and the rename of old.md", got)
+	}
+
+	pages := []pullPage{{localPath: "a.md"}, {localPath: "b.md"}}
+	if got := filterPullByPaths(pages, []string{"b.md"}); len(got) != 1 || got[0].localPath != "b.md" {
+		t.Errorf("filterPullByPaths() = %+v, want b.md", got)
+	}
+}
+
 func TestFilterPullByPath_NestedPatterns(t *testing.T) {
 	pages := []pullPage{
 		{localPath: "a/b/c/d.md"},
@@ -929,8 +1567,8 @@ func TestFilterPullByPath_NestedPatterns(t *testing.T) {
 
 func TestCommandDescriptions(t *testing.T) {
 	commands := []struct {
-		cmd   *cobra.Command
-		name  string
+		cmd  *cobra.Command
+		name string
 	}{
 		{initCmd, "init"},
 		{pushCmd, "push"},
@@ -1140,6 +1778,43 @@ func TestExtractTitle_NoTitleProperty(t *testing.T) {
 	}
 }
 
+func TestPagePullPath(t *testing.T) {
+	vault := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(vault, "work"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vault, "work", "Plan.md"), []byte("# Plan\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{Vault: vault}
+
+	tests := []struct {
+		to, title string
+		want      string
+	}{
+		{"", "Launch: Q3", "Launch- Q3.md"},
+		{"", "", "Untitled.md"},
+		{"work", "Plan", "work/Plan 1.md"},
+		{"shared/", "Plan", "shared/Plan.md"},
+		{"shared/Roadmap", "Plan", "shared/Roadmap.md"},
+		{"work/Plan.md", "Plan", "work/Plan.md"},
+	}
+	for _, tt := range tests {
+		got, err := pagePullPath(cfg, tt.to, tt.title)
+		if err != nil {
+			t.Errorf("pagePullPath(%q, %q) error: %v", tt.to, tt.title, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("pagePullPath(%q, %q) = %q, want %q", tt.to, tt.title, got, tt.want)
+		}
+	}
+
+	if _, err := pagePullPath(cfg, "../outside.md", "Plan"); err == nil {
+		t.Error("pagePullPath() accepted a path outside the vault")
+	}
+}
+
 // =============================================================================
 // extractDatabaseTitle Tests
 // =============================================================================
@@ -1231,6 +1906,288 @@ func TestWatcher_PendingChangesMap(t *testing.T) {
 	}
 }
 
+func TestWatcher_AttachmentJoinsPendingNote(t *testing.T) {
+	dir := t.TempDir()
+	note := "![[Pasted image 1.png]] and ![](assets/Diagram%20A.svg)\n"
+	if err := os.WriteFile(filepath.Join(dir, "note.md"), []byte(note), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &watcher{
+		cfg:               &config.Config{Vault: dir},
+		parser:            parser.New(),
+		pendingChanges:    make(map[string]time.Time),
+		noteAttachments:   make(map[string]map[string]bool),
+		attachmentChanged: make(map[string]bool),
+		debounce:          5 * time.Second,
+		out:               io.Discard,
+	}
+	w.pendingChanges["note.md"] = time.Now().Add(-time.Minute)
+	w.trackAttachments("note.md", w.attachmentNames("note.md"))
+
+	// An unrelated file leaves the note alone.
+	w.attachmentEvent("attachments/other.png")
+	if w.attachmentChanged["note.md"] {
+		t.Error("unrelated file marked the note's attachments changed")
+	}
+
+	// A referenced file restarts the note's debounce and forces a push.
+	for _, path := range []string{"attachments/Pasted image 1.png", "assets/diagram a.svg"} {
+		w.pendingChanges["note.md"] = time.Now().Add(-time.Minute)
+		delete(w.attachmentChanged, "note.md")
+		w.attachmentEvent(path)
+		if time.Since(w.pendingChanges["note.md"]) > time.Second {
+			t.Errorf("%s: note debounce not restarted", path)
+		}
+		if !w.attachmentChanged["note.md"] {
+			t.Errorf("%s: note not marked for push", path)
+		}
+	}
+
+	w.untrackAttachments("note.md")
+	if len(w.noteAttachments) != 0 {
+		t.Errorf("noteAttachments = %v after untrack; want empty", w.noteAttachments)
+	}
+}
+
+func TestWatcher_Settled(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "note.md")
+	if err := os.WriteFile(path, []byte("# Part"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &watcher{
+		cfg:       &config.Config{Vault: dir},
+		settle:    time.Second,
+		snapshots: make(map[string]fileSnapshot),
+	}
+	now := time.Now()
+
+	// A note is watched for the settle time, even with an old mtime.
+	old := now.Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if w.settled("note.md", now) {
+		t.Error("note settled when first seen")
+	}
+	if w.settled("note.md", now.Add(500*time.Millisecond)) {
+		t.Error("note settled before the settle time")
+	}
+
+	// Growing restarts the settle time.
+	if err := os.WriteFile(path, []byte("# Part two"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if w.settled("note.md", now.Add(1500*time.Millisecond)) {
+		t.Error("note settled right after it grew")
+	}
+	if !w.settled("note.md", now.Add(2600*time.Millisecond)) {
+		t.Error("note not settled after staying the same for the settle time")
+	}
+
+	// Deleted notes are settled, and without a settle time every note is.
+	if !w.settled("gone.md", now) {
+		t.Error("deleted note not settled")
+	}
+	w.settle = 0
+	w.snapshots = make(map[string]fileSnapshot)
+	if !w.settled("note.md", now) {
+		t.Error("note not settled with settle disabled")
+	}
+}
+
+func TestWatcher_RetryPartial(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "note.md")
+	if err := os.WriteFile(path, []byte("# Note\n\n```go\nfunc main() {\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &watcher{
+		cfg:               &config.Config{Vault: dir},
+		parser:            parser.New(),
+		pendingChanges:    make(map[string]time.Time),
+		noteAttachments:   make(map[string]map[string]bool),
+		attachmentChanged: make(map[string]bool),
+		partialRetries:    make(map[string]int),
+	}
+
+	for i := 0; i < maxPartialRetries; i++ {
+		if !w.retryPartial("note.md", true) {
+			t.Fatalf("retry %d: truncated note not put back", i+1)
+		}
+		if _, ok := w.pendingChanges["note.md"]; !ok || !w.attachmentChanged["note.md"] {
+			t.Fatalf("retry %d: note not pending with its forced push", i+1)
+		}
+		delete(w.pendingChanges, "note.md")
+	}
+	if w.retryPartial("note.md", false) {
+		t.Error("note put back after maxPartialRetries")
+	}
+	if _, ok := w.partialRetries["note.md"]; ok {
+		t.Error("retry count kept after giving up")
+	}
+
+	// Once complete, the note is synced straight away.
+	if err := os.WriteFile(path, []byte("# Note\n\n```go\nfunc main() {}\n```\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if w.retryPartial("note.md", false) {
+		t.Error("complete note put back")
+	}
+}
+
+func TestLooksTruncated(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"", true},
+		{"\n\n", true},
+		{"# Note\n\nText.\n", false},
+		{"```\ncode\n```\n", false},
+		{"Text\n\n  ```python\nprint(1)\n", true},
+	}
+	for _, tt := range tests {
+		if got := looksTruncated([]byte(tt.content)); got != tt.want {
+			t.Errorf("looksTruncated(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestIsTempFile(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"notes/Plan.md", false},
+		{"notes/Plan.md.tmp", true},
+		{"notes/Plan.md.icloud", true},
+		{"notes/Plan.md~", true},
+		{"attachments/photo.PART", true},
+		{"attachments/photo.png", false},
+	}
+	for _, tt := range tests {
+		if got := isTempFile(tt.path); got != tt.want {
+			t.Errorf("isTempFile(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWatcher_API(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"synced.md": "# Synced\n", "new.md": "# New\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, err := state.Open(filepath.Join(dir, ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	hashes, err := state.HashFileDetailed(filepath.Join(dir, "synced.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*state.SyncState{
+		{ObsidianPath: "synced.md", NotionPageID: "page-1", ContentHash: hashes.ContentHash, FrontmatterHash: hashes.FrontmatterHash, Status: "synced"},
+		{ObsidianPath: "clash.md", NotionPageID: "page-2", Status: "conflict"},
+	} {
+		if err := db.SetState(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{Vault: dir}
+	cfg.Watch.API.Token = "secret"
+	w := &watcher{
+		cfg:               cfg,
+		db:                db,
+		scanner:           vault.NewScanner(dir, nil),
+		pendingChanges:    map[string]time.Time{"new.md": time.Now()},
+		noteAttachments:   make(map[string]map[string]bool),
+		attachmentChanged: make(map[string]bool),
+		apiRequests:       make(chan apiSyncRequest),
+		out:               io.Discard,
+	}
+	srv := httptest.NewServer(w.apiHandler())
+	defer srv.Close()
+
+	// Stand in for the watch loop: record the synced path.
+	synced := make(chan string, 1)
+	go func() {
+		for req := range w.apiRequests {
+			synced <- req.path
+			req.done <- nil
+		}
+	}()
+	defer close(w.apiRequests)
+
+	call := func(method, path, token, body string) (int, map[string]any) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var decoded any
+		_ = json.NewDecoder(resp.Body).Decode(&decoded)
+		result, _ := decoded.(map[string]any)
+		if list, ok := decoded.([]any); ok {
+			result = map[string]any{"list": list}
+		}
+		return resp.StatusCode, result
+	}
+
+	if code, _ := call("GET", "/status", "", ""); code != http.StatusUnauthorized {
+		t.Errorf("no token: status %d, want 401", code)
+	}
+	if code, _ := call("GET", "/status", "wrong", ""); code != http.StatusUnauthorized {
+		t.Errorf("wrong token: status %d, want 401", code)
+	}
+
+	code, summary := call("GET", "/status", "secret", "")
+	if code != http.StatusOK || summary["synced"] != 1.0 || summary["conflicts"] != 1.0 {
+		t.Errorf("GET /status = %d %v", code, summary)
+	}
+
+	for path, want := range map[string]string{"synced.md": "synced", "new.md": "new", "gone.md": "missing"} {
+		_, status := call("GET", "/status?path="+path, "secret", "")
+		if status["status"] != want {
+			t.Errorf("status of %s = %v, want %s", path, status["status"], want)
+		}
+	}
+
+	_, conflicts := call("GET", "/conflicts", "secret", "")
+	if list, _ := conflicts["list"].([]any); len(list) != 1 || list[0].(map[string]any)["path"] != "clash.md" {
+		t.Errorf("GET /conflicts = %v", conflicts)
+	}
+
+	if code, _ := call("POST", "/sync/file", "secret", `{"path": "../outside.md"}`); code != http.StatusBadRequest {
+		t.Errorf("sync outside vault: status %d, want 400", code)
+	}
+	code, status := call("POST", "/sync/file", "secret", `{"path": "new.md"}`)
+	if code != http.StatusOK || status["path"] != "new.md" {
+		t.Errorf("POST /sync/file = %d %v", code, status)
+	}
+	if got := <-synced; got != "new.md" {
+		t.Errorf("synced %q, want new.md", got)
+	}
+}
+
 // =============================================================================
 // pushContext and pullContext Tests
 // =============================================================================
@@ -1551,3 +2508,2917 @@ func TestCheckForNewlyResolvedLinks_CircularReference(t *testing.T) {
 		}
 	}
 }
+
+// =============================================================================
+// Deletion Guard Tests
+// =============================================================================
+
+func TestGuardDeletions(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Sync.MaxDeletionsPerRun = 2
+
+	var out bytes.Buffer
+	if err := guardDeletions(&out, cfg, []string{"a.md", "b.md"}, false); err != nil {
+		t.Errorf("deletions at the limit: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("deletions at the limit printed %q", out.String())
+	}
+
+	paths := []string{"a.md", "b.md", "c.md"}
+	err := guardDeletions(&out, cfg, paths, false)
+	if err == nil || !strings.Contains(err.Error(), "--confirm-deletions") {
+		t.Errorf("deletions over the limit: err = %v, want a request to confirm", err)
+	}
+	if !strings.Contains(out.String(), "  D c.md\n") {
+		t.Errorf("deleted notes not listed: %q", out.String())
+	}
+	if err := guardDeletions(io.Discard, cfg, paths, true); err != nil {
+		t.Errorf("confirmed deletions: %v", err)
+	}
+
+	cfg.Sync.MaxDeletionsPerRun = -1
+	if err := guardDeletions(io.Discard, cfg, paths, false); err != nil {
+		t.Errorf("disabled guard: %v", err)
+	}
+}
+
+func TestWatcher_HoldDeletions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "kept.md"), []byte("# Kept\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := state.Open(filepath.Join(dir, ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, path := range []string{"kept.md", "gone-1.md", "gone-2.md"} {
+		if err := db.SetState(&state.SyncState{ObsidianPath: path, NotionPageID: "page-" + path, Status: "synced"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{Vault: dir}
+	cfg.Sync.MaxDeletionsPerRun = 1
+	w := &watcher{cfg: cfg, db: db, out: io.Discard}
+
+	got := w.holdDeletions([]string{"kept.md", "gone-1.md", "gone-2.md", "untracked.md"})
+	if want := []string{"kept.md", "untracked.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("holdDeletions() = %v, want %v", got, want)
+	}
+
+	cfg.Sync.MaxDeletionsPerRun = 2
+	got = w.holdDeletions([]string{"kept.md", "gone-1.md", "gone-2.md"})
+	if want := []string{"kept.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("holdDeletions() with deletions held = %v, want %v", got, want)
+	}
+
+	// The user confirms: a push with --confirm-deletions applies them.
+	for _, path := range []string{"gone-1.md", "gone-2.md"} {
+		if err := db.DeleteState(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Deletions spread over batches count together.
+	for _, path := range []string{"gone-3.md", "gone-4.md", "gone-5.md"} {
+		if err := db.SetState(&state.SyncState{ObsidianPath: path, NotionPageID: "page-" + path, Status: "synced"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range []string{"gone-3.md", "gone-4.md"} {
+		if got := w.holdDeletions([]string{path}); len(got) != 1 {
+			t.Errorf("holdDeletions(%s) within the limit = %v, want it applied", path, got)
+		}
+		if err := db.DeleteState(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := w.holdDeletions([]string{"gone-5.md"}); len(got) != 0 {
+		t.Errorf("holdDeletions() past the limit over batches = %v, want it held", got)
+	}
+	if err := db.DeleteState("gone-5.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetState(&state.SyncState{ObsidianPath: "gone-6.md", NotionPageID: "page-gone-6.md", Status: "synced"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.holdDeletions([]string{"gone-6.md"}); len(got) != 1 {
+		t.Errorf("holdDeletions() after confirmation = %v, want the count reset", got)
+	}
+}
+
+func TestFilterTrashed(t *testing.T) {
+	trashed := []*state.TrashedPage{
+		{ID: 3, Path: "a.md", NotionPageID: "page-a2"},
+		{ID: 2, Path: "b.md", NotionPageID: "page-b"},
+		{ID: 1, Path: "a.md", NotionPageID: "page-a1"},
+	}
+
+	all := filterTrashed(trashed, nil)
+	if len(all) != 2 || all[0].NotionPageID != "page-a2" || all[1].Path != "b.md" {
+		t.Errorf("filterTrashed(nil) = %+v, want the latest page of each path", all)
+	}
+	one := filterTrashed(trashed, []string{"./b.md"})
+	if len(one) != 1 || one[0].Path != "b.md" {
+		t.Errorf("filterTrashed(b.md) = %+v", one)
+	}
+}
+
+// =============================================================================
+// Push Estimate Tests
+// =============================================================================
+
+func TestPrintEstimate(t *testing.T) {
+	est := pushEstimate{Notes: 4, Requests: 30, Blocks: 120, Uploads: 2}
+
+	var out bytes.Buffer
+	printEstimate(&out, est, 3, 0, 1)
+	for _, want := range []string{"4 change(s)", "API requests:  30", "Blocks:        120", "~10s at 3 requests/s", "1 composed note(s)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("printEstimate() output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "max-requests") {
+		t.Errorf("printEstimate() without a budget mentions it:\n%s", out.String())
+	}
+
+	out.Reset()
+	printEstimate(&out, est, 3, 20, 0)
+	if !strings.Contains(out.String(), "Exceeds --max-requests 20") {
+		t.Errorf("printEstimate() over budget:\n%s", out.String())
+	}
+	out.Reset()
+	printEstimate(&out, est, 3, 30, 0)
+	if !strings.Contains(out.String(), "Fits within --max-requests 30") {
+		t.Errorf("printEstimate() within budget:\n%s", out.String())
+	}
+}
+
+func TestPushContextReserve(t *testing.T) {
+	pc := &pushContext{cfg: &config.Config{Sync: config.SyncConfig{DeletionStrategy: "archive"}}, budget: notion.NewBudget(1)}
+	deleted := pushFile{path: "gone.md", changeType: state.ChangeDeleted, state: &state.SyncState{NotionPageID: "page-1"}}
+
+	release, err := pc.reserve(deleted)
+	if err != nil {
+		t.Fatalf("reserve() error = %v", err)
+	}
+	if _, err := pc.reserve(deleted); !errors.Is(err, errOverBudget) {
+		t.Errorf("reserve() beyond the budget error = %v, want errOverBudget", err)
+	}
+	release()
+	if _, err := pc.reserve(deleted); err != nil {
+		t.Errorf("reserve() after release error = %v", err)
+	}
+}
+
+// =============================================================================
+// Note Target Tests
+// =============================================================================
+
+func TestNoteTargets(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  []string
+	}{
+		{"list", []any{"docs-db", "tasks-db"}, []string{"docs-db", "tasks-db"}},
+		{"string list", []string{"docs-db"}, []string{"docs-db"}},
+		{"comma-separated", "docs-db, tasks-db", []string{"docs-db", "tasks-db"}},
+		{"blank entries", []any{"", " docs-db "}, []string{"docs-db"}},
+		{"missing", nil, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := noteTargets(map[string]any{"notion-targets": tc.value})
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("noteTargets(%v) = %v, want %v", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewPageParent(t *testing.T) {
+	cfg := &config.Config{
+		Notion: config.NotionConfig{DefaultDatabase: "db-default"},
+		Targets: map[string]config.TargetConfig{
+			"docs":  {Database: "db-docs"},
+			"tasks": {Database: "db-tasks"},
+		},
+	}
+	note := func(targets ...any) *parser.ParsedNote {
+		return &parser.ParsedNote{Frontmatter: map[string]any{"notion-targets": targets}}
+	}
+
+	if got := newPageParent(cfg, "a.md", note("tasks", "docs")); got != "db-tasks" {
+		t.Errorf("newPageParent() = %q, want the primary target's database", got)
+	}
+	if got := newPageParent(cfg, "a.md", note("unknown")); got != "db-default" {
+		t.Errorf("newPageParent() with an unknown target = %q, want db-default", got)
+	}
+	if got := newPageParent(cfg, "a.md", note()); got != "db-default" {
+		t.Errorf("newPageParent() without targets = %q, want db-default", got)
+	}
+}
+
+func TestTargetPage(t *testing.T) {
+	cfg := &config.Config{Vault: t.TempDir()}
+	content := "---\ntitle: Launch\nstatus: active\nowner: Sam\nnotion-targets: [docs, tasks]\n---\n\n# Body\n\nText.\n"
+	note, err := parser.New().Parse("launch.md", []byte(content))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	target := config.TargetConfig{
+		Database: "db-tasks",
+		Properties: []config.PropertyMappingConfig{
+			{Obsidian: "title", Notion: "Task", Type: "title"},
+			{Obsidian: "status", Notion: "Status", Type: "select"},
+		},
+	}
+	page, err := targetPage(cfg, "launch.md", note, target)
+	if err != nil {
+		t.Fatalf("targetPage() error: %v", err)
+	}
+	if len(page.Children) != 0 || len(page.Sections) != 0 {
+		t.Errorf("target page has a body: %d blocks, %d sections", len(page.Children), len(page.Sections))
+	}
+	if len(page.Properties) != 2 || page.Properties["Task"] == nil || page.Properties["Status"] == nil {
+		t.Errorf("target page properties = %v, want Task and Status only", page.Properties)
+	}
+}
+
+// =============================================================================
+// First-Push Matching Tests
+// =============================================================================
+
+func TestFindMatches(t *testing.T) {
+	notes := []matchNote{
+		{path: "Meeting Notes.md", name: "Meeting Notes", database: "db1"},
+		{path: "roadmap.md", name: "roadmap", database: "db1"},
+		{path: "Project Plan.md", name: "Project Plan", database: "db1"},
+		{path: "Recipes.md", name: "Recipes", database: "db1"},
+		{path: "Journal.md", name: "Journal", database: "db1"},
+		{path: "Other.md", name: "Other", database: "db2"},
+	}
+	pages := []matchPage{
+		{id: "p-meeting", title: "Meeting Notes", database: "db1"},
+		{id: "p-roadmap", title: "Roadmap", database: "db1"},
+		{id: "p-plan", title: "Project Plan 2024", database: "db1"},
+		{id: "p-recipe", title: "Recipes (old)", database: "db1"},
+		{id: "p-journal-a", title: "Journal", database: "db1"},
+		{id: "p-journal-b", title: "Journal", database: "db1"},
+		{id: "p-other", title: "Other", database: "db1"},
+	}
+	similar := map[string]float64{
+		"Project Plan.md|p-plan": 0.8,
+		"Recipes.md|p-recipe":    0.1,
+		"Journal.md|p-journal-a": 0.2,
+		"Journal.md|p-journal-b": 0.9,
+	}
+	var compared []string
+	matches, err := findMatches(notes, pages, func(path, pageID string) (float64, error) {
+		compared = append(compared, path+"|"+pageID)
+		return similar[path+"|"+pageID], nil
+	})
+	if err != nil {
+		t.Fatalf("findMatches() error: %v", err)
+	}
+
+	got := make(map[string]existingMatch)
+	for _, m := range matches {
+		got[m.path] = m
+	}
+	want := map[string]struct {
+		pageID string
+		score  state.MatchScore
+	}{
+		"Meeting Notes.md": {"p-meeting", state.MatchExact},
+		"roadmap.md":       {"p-roadmap", state.MatchCaseInsensitive},
+		"Project Plan.md":  {"p-plan", state.MatchPrefix},
+		"Journal.md":       {"p-journal-b", state.MatchExact},
+	}
+	if len(got) != len(want) {
+		t.Errorf("findMatches() matched %d notes, want %d: %+v", len(got), len(want), matches)
+	}
+	for path, w := range want {
+		m, ok := got[path]
+		if !ok {
+			t.Errorf("%s not matched", path)
+			continue
+		}
+		if m.pageID != w.pageID || m.score != w.score {
+			t.Errorf("%s matched %s (%s), want %s (%s)", path, m.pageID, scoreToLabel(m.score), w.pageID, scoreToLabel(w.score))
+		}
+	}
+	if _, ok := got["Other.md"]; ok {
+		t.Error("Other.md matched a page of another database")
+	}
+	for _, c := range compared {
+		if strings.HasPrefix(c, "Meeting Notes.md|") || strings.HasPrefix(c, "roadmap.md|") {
+			t.Errorf("compared contents of an unambiguous title match: %s", c)
+		}
+	}
+}
+
+func TestFindMatches_PagesUsedOnce(t *testing.T) {
+	notes := []matchNote{
+		{path: "a/Todo.md", name: "Todo", database: "db1"},
+		{path: "b/todo.md", name: "todo", database: "db1"},
+	}
+	pages := []matchPage{{id: "p1", title: "Todo", database: "db1"}}
+	matches, err := findMatches(notes, pages, func(string, string) (float64, error) { return 0, nil })
+	if err != nil {
+		t.Fatalf("findMatches() error: %v", err)
+	}
+	if len(matches) != 1 || matches[0].path != "a/Todo.md" {
+		t.Errorf("findMatches() = %+v, want only the exact match a/Todo.md", matches)
+	}
+}
+
+func TestContentSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"The quick fox", "the QUICK fox!", 1},
+		{"alpha beta", "gamma delta", 0},
+		{"alpha beta gamma", "alpha beta delta", 0.5},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		if got := contentSimilarity([]byte(tt.a), []byte(tt.b)); got != tt.want {
+			t.Errorf("contentSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestBucketMatches(t *testing.T) {
+	matches := []existingMatch{
+		{path: "a.md", score: state.MatchFuzzy},
+		{path: "b.md", score: state.MatchExact},
+		{path: "c.md", score: state.MatchExact},
+		{path: "d.md", score: state.MatchPrefix},
+	}
+	buckets := bucketMatches(matches)
+	if len(buckets) != 3 {
+		t.Fatalf("bucketMatches() = %d buckets, want 3", len(buckets))
+	}
+	if buckets[0][0].score != state.MatchExact || len(buckets[0]) != 2 {
+		t.Errorf("first bucket = %+v, want the 2 exact matches", buckets[0])
+	}
+	if buckets[2][0].score != state.MatchFuzzy {
+		t.Errorf("last bucket = %+v, want the fuzzy match", buckets[2])
+	}
+
+	if kept := filterMatchScore(matches, state.MatchPrefix); len(kept) != 3 {
+		t.Errorf("filterMatchScore(prefix) kept %d, want 3", len(kept))
+	}
+}
+
+func TestConfirmMatches(t *testing.T) {
+	vault := t.TempDir()
+	for _, name := range []string{"a.md", "b.md"} {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte("# "+name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("state.Open() error: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{Vault: vault}
+	matches := []existingMatch{
+		{path: "a.md", pageID: "page-a", database: "db1", score: state.MatchExact, similarity: -1},
+		{path: "b.md", pageID: "page-b", database: "db1", score: state.MatchFuzzy, similarity: 0.7},
+	}
+	var out bytes.Buffer
+	linked, err := confirmMatches(bufio.NewReader(strings.NewReader("y\nn\n")), &out, cfg, db, matches)
+	if err != nil {
+		t.Fatalf("confirmMatches() error: %v", err)
+	}
+	if len(linked) != 1 || linked[0] != "a.md" {
+		t.Errorf("confirmMatches() linked %v, want [a.md]", linked)
+	}
+	s, err := db.GetState("a.md")
+	if err != nil || s == nil || s.NotionPageID != "page-a" || s.Status != "synced" {
+		t.Errorf("state of a.md = %+v, %v; want synced with page-a", s, err)
+	}
+	if s, _ := db.GetState("b.md"); s != nil {
+		t.Errorf("b.md linked after declining its bucket: %+v", s)
+	}
+	if !strings.Contains(out.String(), "b.md -> \"\" (70% similar)") {
+		t.Errorf("output does not list the fuzzy match with its similarity:\n%s", out.String())
+	}
+}
+
+// =============================================================================
+// Block Map Tests
+// =============================================================================
+
+func TestRecordBlockMap(t *testing.T) {
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("state.Open() error: %v", err)
+	}
+	defer db.Close()
+
+	paragraph := func(id, text string) notionapi.Block {
+		return &notionapi.ParagraphBlock{
+			BasicBlock: notionapi.BasicBlock{ID: notionapi.BlockID(id), Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeParagraph},
+			Paragraph: notionapi.Paragraph{
+				RichText: []notionapi.RichText{{Type: notionapi.ObjectTypeText, Text: &notionapi.Text{Content: text}, PlainText: text}},
+			},
+		}
+	}
+
+	// A pushed page carries the created IDs alongside its blocks.
+	pushed := &transformer.NotionPage{
+		Children: []notionapi.Block{paragraph("", "First"), paragraph("", "Second")},
+		BlockIDs: []string{"block-1", "block-2"},
+	}
+	if err := recordPushedPage(db, "note.md", pushed); err != nil {
+		t.Fatalf("recordPushedPage() error: %v", err)
+	}
+	blocks, err := db.GetBlockMap("note.md")
+	if err != nil {
+		t.Fatalf("GetBlockMap() error: %v", err)
+	}
+	if len(blocks) != 2 || blocks[0].NotionBlockID != "block-1" || blocks[1].NotionBlockID != "block-2" {
+		t.Fatalf("block map after push = %+v, want block-1 and block-2", blocks)
+	}
+	if blocks[0].Hash == blocks[1].Hash {
+		t.Errorf("blocks with different markdown have the same hash %q", blocks[0].Hash)
+	}
+
+	// A pulled page carries the IDs on its blocks. The same markdown hashes
+	// the same, whichever way it was synced.
+	pulled := &transformer.NotionPage{
+		Children: []notionapi.Block{paragraph("block-1", "First"), paragraph("block-3", "Third")},
+	}
+	if err := recordBlockMap(db, "note.md", pulled); err != nil {
+		t.Fatalf("recordBlockMap() error: %v", err)
+	}
+	after, _ := db.GetBlockMap("note.md")
+	if len(after) != 2 || after[1].NotionBlockID != "block-3" {
+		t.Fatalf("block map after pull = %+v, want block-1 and block-3", after)
+	}
+	if after[0].Hash != blocks[0].Hash {
+		t.Errorf("hash of unchanged block = %q after pull, want %q", after[0].Hash, blocks[0].Hash)
+	}
+
+	// Blocks without an ID, as after a failed push, are left out.
+	if err := recordBlockMap(db, "note.md", &transformer.NotionPage{Children: []notionapi.Block{paragraph("", "New")}}); err != nil {
+		t.Fatalf("recordBlockMap() error: %v", err)
+	}
+	if got, _ := db.GetBlockMap("note.md"); len(got) != 0 {
+		t.Errorf("block map = %+v, want none for blocks without IDs", got)
+	}
+}
+
+func TestPrintBlockMap(t *testing.T) {
+	var out bytes.Buffer
+	printBlockMap(&out, "note.md", nil)
+	if !strings.Contains(out.String(), "No blocks recorded for note.md") {
+		t.Errorf("empty block map output = %q", out.String())
+	}
+
+	out.Reset()
+	printBlockMap(&out, "note.md", []state.BlockMapping{
+		{Index: 0, Hash: "0123456789abcdef", NotionBlockID: "block-1"},
+	})
+	if !strings.Contains(out.String(), "0123456789ab  block-1") || strings.Contains(out.String(), "cdef") {
+		t.Errorf("block map output = %q, want the shortened hash and block ID", out.String())
+	}
+}
+
+// =============================================================================
+// Completion Tests
+// =============================================================================
+
+func TestWriteCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		var out bytes.Buffer
+		if err := writeCompletion(rootCmd, &out, shell); err != nil {
+			t.Errorf("writeCompletion(%s) error: %v", shell, err)
+			continue
+		}
+		if !strings.Contains(out.String(), "obsidian-notion") {
+			t.Errorf("writeCompletion(%s) output does not mention the command", shell)
+		}
+	}
+	if err := writeCompletion(rootCmd, io.Discard, "tcsh"); err == nil {
+		t.Error("writeCompletion(tcsh) expected an error")
+	}
+}
+
+func TestDatabaseChoices(t *testing.T) {
+	c := &config.Config{
+		Notion: config.NotionConfig{DefaultDatabase: "db-default", StagingDatabase: "db-staging"},
+		Mappings: []config.FolderMapping{
+			{Path: "work/**", Database: "db-work"},
+			{Path: "notes/**", Database: "db-default"},
+		},
+		Targets: map[string]config.TargetConfig{
+			"blog":  {Database: "db-blog"},
+			"alpha": {Database: "db-work"},
+		},
+	}
+	want := []string{
+		"db-default\tdefault database",
+		"db-work\tmapping work/**",
+		"db-blog\ttarget blog",
+		"db-staging\tstaging database",
+	}
+	if got := databaseChoices(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("databaseChoices() = %q, want %q", got, want)
+	}
+}
+
+func TestCompleteConflictPaths(t *testing.T) {
+	vault := t.TempDir()
+	db, err := state.Open(filepath.Join(vault, ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatalf("state.Open() error: %v", err)
+	}
+	for path, status := range map[string]string{"a.md": "conflict", "b.md": "synced", "work/c.md": "conflict"} {
+		if err := db.SetState(&state.SyncState{ObsidianPath: path, Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	originalCfg := cfg
+	defer func() { cfg = originalCfg }()
+	cfg = &config.Config{Vault: vault}
+
+	got, directive := completeConflictPaths(resolveCmd, nil, "")
+	sort.Strings(got)
+	if want := []string{"a.md", "work/c.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("completeConflictPaths() = %v, want %v", got, want)
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("directive = %v, want NoFileComp", directive)
+	}
+	if got, _ := completeConflictPaths(resolveCmd, nil, "work/"); !reflect.DeepEqual(got, []string{"work/c.md"}) {
+		t.Errorf("completeConflictPaths(work/) = %v", got)
+	}
+	if got, _ := completeConflictPaths(resolveCmd, []string{"a.md"}, ""); len(got) != 0 {
+		t.Errorf("completeConflictPaths() after the path = %v, want none", got)
+	}
+}
+
+// =============================================================================
+// Streaming Push Tests
+// =============================================================================
+
+// writeLargeNote writes a note of several stream chunks with a tag and a
+// reference link at the start and a tag and wiki-link at the end.
+func writeLargeNote(t *testing.T, vault, path string) {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("---\ntitle: Log\n---\nStart #early and [the docs][docs].\n\n")
+	for b.Len() < 2*streamChunkSize {
+		b.WriteString("A line of the log export that goes on for a while.\n\n")
+	}
+	b.WriteString("End #late and [[Other]].\n\n[docs]: https://example.com/docs\n")
+	if err := os.WriteFile(filepath.Join(vault, path), []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPushContext_Streams(t *testing.T) {
+	vault := t.TempDir()
+	writeLargeNote(t, vault, "log.md")
+	if err := os.WriteFile(filepath.Join(vault, "small.md"), []byte("# Small\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pc := &pushContext{cfg: &config.Config{Vault: vault, Sync: config.SyncConfig{StreamNoteSize: "256KB"}}}
+	if !pc.streams("log.md") || pc.streams("small.md") {
+		t.Errorf("streams(log.md) = %v, streams(small.md) = %v; want true, false", pc.streams("log.md"), pc.streams("small.md"))
+	}
+
+	pc.cfg.Sync.StreamNoteSize = "0"
+	if pc.streams("log.md") {
+		t.Error("streams() with stream_note_size 0 = true, want false")
+	}
+	pc.cfg.Sync.StreamNoteSize = "256KB"
+	pc.cfg.Transform.SplitOn = "h1"
+	if pc.streams("log.md") {
+		t.Error("streams() with split_on = true, want false")
+	}
+}
+
+func TestEachNoteChunk(t *testing.T) {
+	vault := t.TempDir()
+	writeLargeNote(t, vault, "log.md")
+	fullPath := filepath.Join(vault, "log.md")
+
+	var chunks int
+	var lastOffset int64
+	err := eachNoteChunk(parser.New(), fullPath, "log.md", func(i int, chunk *parser.ParsedNote, offset int64) error {
+		chunks++
+		lastOffset = offset
+		if i == 0 {
+			if chunk.Frontmatter["title"] != "Log" {
+				t.Errorf("first chunk frontmatter = %v", chunk.Frontmatter)
+			}
+			// The definition at the end of the note resolves in the first chunk.
+			page, err := transformer.New(nil, nil).Transform(chunk)
+			if err != nil {
+				t.Fatalf("Transform() error: %v", err)
+			}
+			para := page.Children[0].(*notionapi.ParagraphBlock)
+			var linked bool
+			for _, rt := range para.Paragraph.RichText {
+				linked = linked || (rt.Text.Link != nil && rt.Text.Link.Url == "https://example.com/docs")
+			}
+			if !linked {
+				t.Error("reference link in the first chunk is not linked")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("eachNoteChunk() error: %v", err)
+	}
+	info, _ := os.Stat(fullPath)
+	if chunks < 2 || lastOffset != info.Size() {
+		t.Errorf("read %d chunk(s) up to %d bytes, want several up to %d", chunks, lastOffset, info.Size())
+	}
+
+	summary, err := summarizeNote(parser.New(), fullPath, "log.md")
+	if err != nil {
+		t.Fatalf("summarizeNote() error: %v", err)
+	}
+	if !reflect.DeepEqual(summary.Tags, []string{"early", "late"}) {
+		t.Errorf("summary tags = %v, want early and late", summary.Tags)
+	}
+	if len(summary.WikiLinks) != 1 || summary.WikiLinks[0].Target != "Other" {
+		t.Errorf("summary links = %v, want [[Other]]", summary.WikiLinks)
+	}
+}
+
+// =============================================================================
+// Retitle Tests
+// =============================================================================
+
+func TestTitleProperty(t *testing.T) {
+	title := notionapi.TitleProperty{Title: []notionapi.RichText{{PlainText: "Plan"}}}
+	props := notionapi.Properties{
+		"Status": notionapi.SelectProperty{Select: notionapi.Option{Name: "draft"}},
+		"Name":   title,
+	}
+	if got := titleProperty(props); !reflect.DeepEqual(got, title) {
+		t.Errorf("titleProperty() = %#v, want the Name property", got)
+	}
+	if got := titleProperty(notionapi.Properties{"Status": props["Status"]}); got != nil {
+		t.Errorf("titleProperty() without a title = %#v, want nil", got)
+	}
+}
+
+func TestRetitlePage_NoTitleProperty(t *testing.T) {
+	// Without a title property there is nothing to retitle, and the caller
+	// replaces the page as usual.
+	ok, err := retitlePage(context.Background(), nil, nil, nil, "a.md", &state.SyncState{NotionPageID: "page-1"}, &transformer.NotionPage{})
+	if ok || err != nil {
+		t.Errorf("retitlePage() = %v, %v; want false, nil", ok, err)
+	}
+}
+
+// =============================================================================
+// Watch Pause Tests
+// =============================================================================
+
+func TestWatcher_PauseQueuesChanges(t *testing.T) {
+	queued := time.Now().Add(-time.Minute)
+	w := &watcher{
+		cfg:            &config.Config{Vault: t.TempDir()},
+		pendingChanges: map[string]time.Time{"note.md": queued},
+		snapshots:      map[string]fileSnapshot{"note.md": {}},
+		debounce:       5 * time.Second,
+		out:            io.Discard,
+	}
+
+	// Paused, a note past its debounce stays queued.
+	w.setPaused(true)
+	w.processDebounced()
+	if _, ok := w.pendingChanges["note.md"]; !ok || !w.isPaused() {
+		t.Fatal("paused watcher synced a queued note")
+	}
+
+	// On resume, the queued note waits out a fresh debounce.
+	w.setPaused(false)
+	if w.isPaused() {
+		t.Error("watcher still paused after resume")
+	}
+	if !w.pendingChanges["note.md"].After(queued) {
+		t.Error("queued note's debounce not restarted on resume")
+	}
+	if _, ok := w.snapshots["note.md"]; ok {
+		t.Error("queued note's snapshot kept on resume")
+	}
+	w.processDebounced()
+	if _, ok := w.pendingChanges["note.md"]; !ok {
+		t.Error("queued note synced before its fresh debounce")
+	}
+}
+
+func TestWatcher_PauseAPI(t *testing.T) {
+	cfg := &config.Config{Vault: t.TempDir()}
+	cfg.Watch.API.Token = "secret"
+	w := &watcher{
+		cfg:            cfg,
+		pendingChanges: make(map[string]time.Time),
+		pauseRequests:  make(chan bool),
+		out:            io.Discard,
+	}
+	srv := httptest.NewServer(w.apiHandler())
+	defer srv.Close()
+
+	// Stand in for the watch loop.
+	go func() {
+		for paused := range w.pauseRequests {
+			w.setPaused(paused)
+		}
+	}()
+	defer close(w.pauseRequests)
+
+	for _, tt := range []struct {
+		path string
+		want bool
+	}{{"/pause", true}, {"/resume", false}} {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+tt.path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body map[string]bool
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK || body["paused"] != tt.want {
+			t.Errorf("POST %s = %d %v (%v), want paused %v", tt.path, resp.StatusCode, body, err, tt.want)
+		}
+	}
+}
+
+// =============================================================================
+// Attachment Queue Tests
+// =============================================================================
+
+func TestAttachmentUploader_Queue(t *testing.T) {
+	cfg := &config.Config{Vault: t.TempDir()}
+	cfg.Attachments.Concurrency = 1
+	u := newAttachmentUploader(cfg, nil, nil, vault.NewScanner(cfg.Vault, nil))
+	if cap(u.slots) != 1 {
+		t.Fatalf("upload queue has %d slot(s), want 1", cap(u.slots))
+	}
+
+	// With every slot taken, an upload waits until it is cancelled.
+	u.slots <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := u.upload(ctx, "note.md", "image.png"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("upload() with no free slot = %v, want deadline exceeded", err)
+	}
+
+	// Once a slot is free, a missing file resolves to nothing.
+	<-u.slots
+	if hash, id, err := u.upload(context.Background(), "note.md", "image.png"); hash != "" || id != "" || err != nil {
+		t.Errorf("upload() of a missing file = %q, %q, %v; want nothing", hash, id, err)
+	}
+	if len(u.slots) != 0 {
+		t.Error("upload kept its slot")
+	}
+}
+
+// =============================================================================
+// Bookmark Preview Tests
+// =============================================================================
+
+func TestParseBookmarkPreview(t *testing.T) {
+	page := `<html><head><title>Page &amp; Title</title>
+<meta name="description" content="Plain description">
+<meta property='og:description' content='Open Graph &quot;description&quot;'>
+</head></html>`
+	got := parseBookmarkPreview([]byte(page))
+	want := transformer.BookmarkPreview{Title: "Page & Title", Description: `Open Graph "description"`}
+	if got != want {
+		t.Errorf("parseBookmarkPreview() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBookmarkPreviewer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = io.WriteString(w, `<title>Example</title>`)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	if p := newBookmarkPreviewer(context.Background(), cfg, "note.md"); p != nil {
+		t.Fatal("previewer created with pull.bookmark_previews off")
+	}
+	cfg.Pull.BookmarkPreviews = true
+	p := newBookmarkPreviewer(context.Background(), cfg, "note.md")
+
+	if preview, ok := p.BookmarkPreview(srv.URL + "/page"); !ok || preview.Title != "Example" {
+		t.Errorf("BookmarkPreview() = %+v, %v; want title Example", preview, ok)
+	}
+	if _, ok := p.BookmarkPreview(srv.URL + "/missing"); ok {
+		t.Error("BookmarkPreview() of a missing page reported a preview")
+	}
+	if _, ok := p.BookmarkPreview("mailto:someone@example.com"); ok {
+		t.Error("BookmarkPreview() of a non-HTTP URL reported a preview")
+	}
+}
+
+// =============================================================================
+// Push Verify Tests
+// =============================================================================
+
+func TestVerifyDigest(t *testing.T) {
+	pushed := "Intro\n\n- a\n- b\n\nLast paragraph.\n"
+	same := "---\ntitle: Note\n---\n\nIntro  \n\n- a\n- b\nLast paragraph.\n\n\n"
+	if verifyDigest(pushed) != verifyDigest(same) {
+		t.Error("verifyDigest() differs for the same content with other blank lines and frontmatter")
+	}
+	if verifyDigest(pushed) == verifyDigest("Intro\n\n- a\n- b\n\nLast para\n") {
+		t.Error("verifyDigest() matches truncated content")
+	}
+}
+
+func TestVerifyDiff(t *testing.T) {
+	pushed := "---\ntitle: Note\n---\n\nA long paragraph about the plan.\n\nLast paragraph.\n"
+	readBack := "A long paragraph about a plan.\n"
+	want := "    ~A long paragraph about [-the-]{+a+} plan.\n    -Last paragraph.\n"
+	if got := verifyDiff(pushed, readBack); got != want {
+		t.Errorf("verifyDiff() =\n%s\nwant:\n%s", got, want)
+	}
+
+	var long strings.Builder
+	for i := range verifyDiffLines + 3 {
+		fmt.Fprintf(&long, "line %d\n", i)
+	}
+	if got := verifyDiff(long.String(), ""); !strings.HasSuffix(got, "    ... 3 more line(s)\n") {
+		t.Errorf("verifyDiff() of a long change =\n%s", got)
+	}
+}
+
+func TestVerifyTransformer_Uploads(t *testing.T) {
+	pc := &pushContext{cfg: &config.Config{Pull: config.PullConfig{Wrap: "10"}}}
+	md := []byte("A paragraph long enough to wrap.\n\n![[photo.png]]\n")
+	p := parser.New()
+	note, err := p.Parse("note.md", md)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	tr := transformer.New(nil, buildTransformerConfig(pc.cfg, "note.md"))
+	tr.SetAttachmentResolver(&noteAttachments{uploads: map[string]string{"photo.png": "upload-1"}})
+	page, err := tr.Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	pushed, _ := pc.verifyTransformer("note.md").NotionToMarkdown(page)
+
+	// Read back, the upload is a Notion-hosted file.
+	fetched := &transformer.NotionPage{Children: []notionapi.Block{
+		page.Children[0],
+		&notionapi.ImageBlock{Image: notionapi.Image{File: &notionapi.FileObject{URL: "https://files.example.com/x/photo.png?X-Amz=1"}}},
+	}}
+	got, _ := pc.verifyTransformer("note.md").NotionToMarkdown(fetched)
+	if verifyDigest(string(pushed)) != verifyDigest(string(got)) {
+		t.Errorf("read-back page does not verify:\npushed:\n%s\nfetched:\n%s", pushed, got)
+	}
+}
+
+// =============================================================================
+// Config Command Tests
+// =============================================================================
+
+func TestPrintSettings_MasksSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	printSettings(&buf, []config.Setting{
+		{Key: "notion.token", Value: "secret_abc"},
+		{Key: "notion.credentials.work", Value: "${WORK_TOKEN}"},
+		{Key: "notify.smtp.password", Value: ""},
+		{Key: "sync.root", Value: "Areas/Public"},
+	})
+	want := "notion.token = ********\nnotion.credentials.work = ${WORK_TOKEN}\nnotify.smtp.password = \nsync.root = Areas/Public\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printSettings() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// =============================================================================
+// Property Error Tests
+// =============================================================================
+
+func TestPrintPushError_PropertyErrors(t *testing.T) {
+	err := fmt.Errorf("transform to Notion: %w", transformer.PropertyErrors{
+		{Key: "priority", Property: "Priority", Value: "high", Type: transformer.PropertyTypeNumber, Suggestion: "write a number"},
+	})
+	var buf bytes.Buffer
+	if !printPushError(&buf, "Error processing", "note.md", err) {
+		t.Error("printPushError() = false for property errors")
+	}
+	want := "  Error processing note.md: 1 frontmatter value(s) do not fit their Notion properties\n" +
+		"    - priority: \"high\" is not a valid number for property \"Priority\" (write a number)\n"
+	if buf.String() != want {
+		t.Errorf("printPushError() wrote:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if printPushError(&buf, "Error processing", "note.md", fmt.Errorf("create page: boom")) {
+		t.Error("printPushError() = true for another error")
+	}
+	if want := "  Error processing note.md: create page: boom\n"; buf.String() != want {
+		t.Errorf("printPushError() wrote %q, want %q", buf.String(), want)
+	}
+}
+
+// =============================================================================
+// Bootstrap Tests
+// =============================================================================
+
+func TestBootstrapCheckpoint(t *testing.T) {
+	vault := t.TempDir()
+	for _, name := range []string{"synced.md", "created.md", "new.md"} {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte("# "+name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, err := state.Open(filepath.Join(vault, ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	for _, s := range []*state.SyncState{
+		{ObsidianPath: "synced.md", NotionPageID: "page-1", Status: "synced"},
+		{ObsidianPath: "created.md", NotionPageID: "page-2", Status: bootstrapPending},
+	} {
+		if err := db.SetState(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Vault = vault
+	pc := &pushContext{cfg: cfg, db: db, scanner: newScanner(cfg)}
+
+	fills, err := bootstrapFills(pc)
+	if err != nil {
+		t.Fatalf("bootstrapFills() error: %v", err)
+	}
+	if len(fills) != 1 || fills[0].path != "created.md" || fills[0].changeType != state.ChangeModified {
+		t.Errorf("bootstrapFills() = %+v, want created.md as a modification", fills)
+	}
+
+	// The page of created.md is still to be filled, and new.md to be created.
+	remaining, err := bootstrapRemaining(pc)
+	if err != nil {
+		t.Fatalf("bootstrapRemaining() error: %v", err)
+	}
+	if remaining != 2 {
+		t.Errorf("bootstrapRemaining() = %d, want 2", remaining)
+	}
+}
+
+func TestCreateEmptyPage_Template(t *testing.T) {
+	var req struct {
+		Parent   notionapi.Parent
+		Children []json.RawMessage
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_, _ = w.Write([]byte(`{"object":"page","id":"new-page","properties":{}}`))
+	}))
+	defer server.Close()
+
+	vault := t.TempDir()
+	db, err := state.Open(filepath.Join(vault, ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := os.WriteFile(filepath.Join(vault, "note.md"), []byte("Body.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{Vault: vault}
+	cfg.Notion.Token = "test-token"
+	cfg.Notion.DefaultPage = "home-page"
+	cfg.Template.Page = "template-page"
+	httpClient := &http.Client{Transport: redirectTransport{strings.TrimPrefix(server.URL, "http://")}}
+	clients := notion.NewFactory(cfg, notion.WithRateLimit(1000), notion.WithHTTPClient(httpClient))
+	agenda := &notionapi.Heading2Block{
+		BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeHeading2},
+		Heading2:   notionapi.Heading{RichText: []notionapi.RichText{{Type: notionapi.ObjectTypeText, Text: &notionapi.Text{Content: "Agenda"}, PlainText: "Agenda"}}},
+	}
+	linkRegistry := state.NewLinkRegistry(db)
+	pc := &pushContext{
+		cfg:          cfg,
+		db:           db,
+		clients:      clients,
+		linkRegistry: linkRegistry,
+		parser:       parser.New(),
+		scanner:      newScanner(cfg),
+		undo:         newUndoRecorder(cfg, db, linkRegistry, "bootstrap", time.Now()),
+		templates:    &templateCache{templates: map[string]*notion.Template{"template-page": {Children: []notionapi.Block{agenda}}}},
+	}
+
+	if _, err := pc.createEmptyPage(context.Background(), pushFile{path: "note.md"}); err != nil {
+		t.Fatalf("createEmptyPage() error: %v", err)
+	}
+	if len(req.Children) != 1 {
+		t.Errorf("page created with %d block(s), want the template's heading", len(req.Children))
+	}
+	if got, _ := os.ReadFile(filepath.Join(vault, "note.md")); string(got) != "## Agenda\n\nBody.\n" {
+		t.Errorf("note = %q, want the template's content added", got)
+	}
+	s, err := db.GetState("note.md")
+	if err != nil || s == nil {
+		t.Fatalf("GetState() = %v, %v", s, err)
+	}
+	if s.NotionParentID != "home-page" || s.Status != bootstrapPending {
+		t.Errorf("state = parent %q, status %q; want home-page, %s", s.NotionParentID, s.Status, bootstrapPending)
+	}
+}
+
+// =============================================================================
+// Link Fixup Tests
+// =============================================================================
+
+// pageIDs resolves wiki-link targets from a map.
+type pageIDs map[string]string
+
+func (m pageIDs) Resolve(target string) (string, bool) {
+	id, ok := m[target]
+	return id, ok
+}
+
+func TestPlaceholderRecorder(t *testing.T) {
+	links := &placeholderRecorder{LinkResolver: pageIDs{"Known": "page-1"}}
+	for _, target := range []string{"Known", "Later", "Known", "Later", "Missing"} {
+		links.Resolve(target)
+	}
+	if want := []string{"Later", "Missing"}; !reflect.DeepEqual(links.missed, want) {
+		t.Errorf("missed = %v, want %v", links.missed, want)
+	}
+
+	// Hiding a target makes it a placeholder again, as it was when pushed.
+	hidden := &hiddenTargets{LinkResolver: pageIDs{"Known": "page-1", "Later": "page-2"}, hidden: []string{"Later"}}
+	if _, found := hidden.Resolve("Later"); found {
+		t.Error("hidden target resolved")
+	}
+	if id, found := hidden.Resolve("Known"); !found || id != "page-1" {
+		t.Errorf("Resolve(Known) = %q, %v; want page-1", id, found)
+	}
+}
+
+// =============================================================================
+// Status Detail Tests
+// =============================================================================
+
+func TestLoadFileStatus(t *testing.T) {
+	vault := t.TempDir()
+	if err := os.WriteFile(filepath.Join(vault, "Plan.md"), []byte("See [[Goals]] and [[Later]].\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := state.Open(filepath.Join(vault, ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	for _, s := range []*state.SyncState{
+		{ObsidianPath: "Plan.md", NotionPageID: "0123-abcd", ContentHash: "old", Status: "synced", SyncDirection: "push"},
+		{ObsidianPath: "Goals.md", NotionPageID: "page-goals", Status: "synced"},
+	} {
+		if err := db.SetState(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry := state.NewLinkRegistry(db)
+	if err := registry.ReplaceLinks("Plan.md", []string{"Goals", "Later"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.ReplaceLinks("Goals.md", []string{"Plan"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.ResolveAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetBlockMap("Plan.md", []state.BlockMapping{{Index: 0, Hash: "h", NotionBlockID: "block-1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := loadFileStatus(vault, state.Hasher{}, db, "Plan.md")
+	if err != nil {
+		t.Fatalf("loadFileStatus() error: %v", err)
+	}
+	if status.NotionURL != "https://www.notion.so/0123abcd" || !status.LocalChanges || status.Blocks != 1 {
+		t.Errorf("loadFileStatus() = %+v", status)
+	}
+	wantLinks := []fileLink{{Target: "Goals", Path: "Goals.md", Resolved: true}, {Target: "Later"}}
+	if !reflect.DeepEqual(status.LinksTo, wantLinks) {
+		t.Errorf("LinksTo = %+v, want %+v", status.LinksTo, wantLinks)
+	}
+	if !reflect.DeepEqual(status.LinkedFrom, []string{"Goals.md"}) {
+		t.Errorf("LinkedFrom = %v, want [Goals.md]", status.LinkedFrom)
+	}
+
+	var buf bytes.Buffer
+	printFileStatus(&buf, status)
+	for _, want := range []string{"synced (modified locally)", "[[Goals]] -> Goals.md", "[[Later]] (unresolved)", "Linked from (1):\n  Goals.md"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("printFileStatus() output missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	if _, err := loadFileStatus(vault, state.Hasher{}, db, "Nowhere.md"); err == nil {
+		t.Error("loadFileStatus() of an unknown note succeeded")
+	}
+}
+
+// =============================================================================
+// Poll Formatting Tests
+// =============================================================================
+
+func TestFormattingOnly(t *testing.T) {
+	synced := state.HashContent([]byte("---\ntags: [a]\n---\n# Plan\n\nFirst step.\n"))
+	s := &state.SyncState{ContentHash: synced.ContentHash, FrontmatterHash: synced.FrontmatterHash}
+
+	tests := []struct {
+		name     string
+		markdown string
+		want     bool
+	}{
+		{"same", "---\ntags: [a]\n---\n# Plan\n\nFirst step.\n", true},
+		{"whitespace rewritten", "---\ntags: [a]\n---\n# Plan  \n\n\n\nFirst step. \n\n", true},
+		{"text changed", "---\ntags: [a]\n---\n# Plan\n\nSecond step.\n", false},
+		{"property changed", "---\ntags: [b]\n---\n# Plan\n\nFirst step.\n", false},
+	}
+	for _, tt := range tests {
+		if got := formattingOnly(state.Hasher{}, s, []byte(tt.markdown)); got != tt.want {
+			t.Errorf("%s: formattingOnly() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// =============================================================================
+// Profile Tests
+// =============================================================================
+
+func TestStartProfiles(t *testing.T) {
+	dir := t.TempDir()
+	cpu, mem := filepath.Join(dir, "cpu.pprof"), filepath.Join(dir, "mem.pprof")
+
+	stop, err := startProfiles(cpu, mem)
+	if err != nil {
+		t.Fatalf("startProfiles() error: %v", err)
+	}
+	stop()
+
+	for _, path := range []string{cpu, mem} {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("profile %s was not written: %v", filepath.Base(path), err)
+		}
+	}
+
+	if _, err := startProfiles(filepath.Join(dir, "missing", "cpu.pprof"), ""); err == nil {
+		t.Error("startProfiles() to a missing directory succeeded")
+	}
+}
+
+// =============================================================================
+// Sync Schedule Tests
+// =============================================================================
+
+func TestJitterInterval(t *testing.T) {
+	every := 30 * time.Minute
+	for i := 0; i < 100; i++ {
+		if got := jitterInterval(every); got < 27*time.Minute || got > 33*time.Minute {
+			t.Fatalf("jitterInterval(%s) = %s, want within a tenth", every, got)
+		}
+	}
+}
+
+func TestRunSyncEveryRejectsShortIntervals(t *testing.T) {
+	if err := runSyncEvery(10 * time.Second); err == nil || !strings.Contains(err.Error(), "at least") {
+		t.Errorf("runSyncEvery(10s) error = %v, want interval error", err)
+	}
+}
+
+// =============================================================================
+// Note Conflict Strategy Tests
+// =============================================================================
+
+func TestNoteConflictStrategy(t *testing.T) {
+	vault := t.TempDir()
+	files := map[string]string{
+		"meeting.md": "---\nnotion-conflict: theirs\n---\n\nAgenda\n",
+		"plain.md":   "Body\n",
+		"typo.md":    "---\nnotion-conflict: remote\n---\n\nBody\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &config.Config{Vault: vault}
+
+	tests := []struct {
+		path string
+		want ConflictStrategy
+	}{
+		{"meeting.md", StrategyTheirs},
+		{"plain.md", StrategyManual},
+		{"typo.md", StrategyManual},
+		{"deleted.md", StrategyManual},
+	}
+	for _, tt := range tests {
+		if got := noteConflictStrategy(cfg, tt.path, StrategyManual); got != tt.want {
+			t.Errorf("noteConflictStrategy(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+// =============================================================================
+// Heading Repair Tests
+// =============================================================================
+
+func TestBlockMappingsRecordHeadings(t *testing.T) {
+	page := &transformer.NotionPage{
+		Children: []notionapi.Block{
+			&notionapi.Heading2Block{Heading2: notionapi.Heading{RichText: []notionapi.RichText{{Type: "text", Text: &notionapi.Text{Content: "Agenda"}, PlainText: "Agenda"}}}},
+			&notionapi.ParagraphBlock{Paragraph: notionapi.Paragraph{RichText: []notionapi.RichText{{Type: "text", Text: &notionapi.Text{Content: "Body"}, PlainText: "Body"}}}},
+		},
+		BlockIDs: []string{"block-0", "block-1"},
+	}
+	blocks := blockMappings(page, 0)
+	if len(blocks) != 2 || blocks[0].Heading != "Agenda" || blocks[1].Heading != "" {
+		t.Errorf("blockMappings() = %+v, want the heading text of the first block only", blocks)
+	}
+}
+
+func TestRunHeadingRepair(t *testing.T) {
+	vault := t.TempDir()
+	db, err := state.Open(filepath.Join(vault, ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	// The heading of Work/Meeting.md was retitled twice.
+	for _, heading := range []string{"Agenda", "Agenda for Monday", "Monday agenda"} {
+		if err := db.SetBlockMap("Work/Meeting.md", []state.BlockMapping{{Index: 0, Hash: heading, NotionBlockID: "block-0", Heading: heading}}); err != nil {
+			t.Fatalf("SetBlockMap() error: %v", err)
+		}
+	}
+
+	files := map[string]string{
+		"Work/Meeting.md": "## Monday agenda\n\nSee [[#Agenda]].\n",
+		"Notes.md":        "[[Meeting#Agenda|the agenda]], [[Work/Meeting.md#Agenda]], [[Other#Agenda]], ![[Meeting#Intro#Agenda]]\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(vault, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(vault, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := []string{"Notes.md", "Work/Meeting.md"}
+
+	// A dry run changes nothing.
+	var out bytes.Buffer
+	if err := runHeadingRepair(&out, vault, db, paths, true); err != nil {
+		t.Fatalf("runHeadingRepair() error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(vault, "Notes.md")); string(data) != files["Notes.md"] {
+		t.Errorf("dry run rewrote Notes.md:\n%s", data)
+	}
+
+	out.Reset()
+	if err := runHeadingRepair(&out, vault, db, paths, false); err != nil {
+		t.Fatalf("runHeadingRepair() error: %v", err)
+	}
+	want := map[string]string{
+		"Work/Meeting.md": "## Monday agenda\n\nSee [[#Monday agenda]].\n",
+		"Notes.md":        "[[Meeting#Monday agenda|the agenda]], [[Work/Meeting.md#Monday agenda]], [[Other#Agenda]], ![[Meeting#Intro#Monday agenda]]\n",
+	}
+	for name, content := range want {
+		if data, _ := os.ReadFile(filepath.Join(vault, name)); string(data) != content {
+			t.Errorf("%s =\n%s\nwant\n%s", name, data, content)
+		}
+	}
+	if renames, _ := db.HeadingRenames(); len(renames) != 0 {
+		t.Errorf("expected renames forgotten, got %+v", renames)
+	}
+	if !strings.Contains(out.String(), "Rewrote 4 link(s) in 2 note(s)") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+// =============================================================================
+// Nested Database Tests
+// =============================================================================
+
+func TestTakeNestedDatabases(t *testing.T) {
+	vault := t.TempDir()
+	db, err := state.Open(filepath.Join(vault, ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Vault = vault
+	pc := &pullContext{cfg: cfg, db: db}
+
+	tasks := &notionapi.ChildDatabaseBlock{}
+	tasks.ID = "db-1"
+	tasks.ChildDatabase.Title = "Tasks"
+	page := &transformer.NotionPage{
+		Children: []notionapi.Block{
+			&notionapi.ParagraphBlock{Paragraph: notionapi.Paragraph{RichText: []notionapi.RichText{{Type: "text", Text: &notionapi.Text{Content: "Plan"}, PlainText: "Plan"}}}},
+			tasks,
+		},
+	}
+	pc.takeNestedDatabases("Work/Project.md", page)
+	if len(page.Children) != 1 {
+		t.Errorf("page keeps %d block(s), want the paragraph only", len(page.Children))
+	}
+	want := state.NestedDatabase{DatabaseID: "db-1", Folder: "Work/Project/Tasks", ParentPath: "Work/Project.md", Title: "Tasks"}
+	if len(pc.nested) != 1 || pc.nested[0] != want {
+		t.Errorf("nested = %+v, want %+v", pc.nested, want)
+	}
+
+	// A database found again is not new.
+	pc.nested = nil
+	pc.takeNestedDatabases("Work/Project.md", &transformer.NotionPage{Children: []notionapi.Block{tasks}})
+	if len(pc.nested) != 0 {
+		t.Errorf("nested = %+v after finding the database again, want none", pc.nested)
+	}
+
+	// Notes created in the folder are pushed to the database, once mapped.
+	for range 2 {
+		if err := useNestedDatabases(cfg, db); err != nil {
+			t.Fatalf("useNestedDatabases() error: %v", err)
+		}
+	}
+	if len(cfg.Mappings) != 1 {
+		t.Errorf("Mappings = %+v, want one mapping", cfg.Mappings)
+	}
+	if got := cfg.GetDatabaseForPath("Work/Project/Tasks/Call.md"); got != "db-1" {
+		t.Errorf("GetDatabaseForPath() = %q, want db-1", got)
+	}
+	if got := cfg.GetDatabaseForPath("Work/Project.md"); got == "db-1" {
+		t.Errorf("GetDatabaseForPath() of the parent note = %q, want the default", got)
+	}
+}
+
+func TestAddNestedMappingEscapesFolder(t *testing.T) {
+	cfg := config.DefaultConfig()
+	addNestedMapping(cfg, state.NestedDatabase{DatabaseID: "db-1", Folder: "Plans [2024]/Tasks*"})
+	if got := cfg.GetDatabaseForPath("Plans [2024]/Tasks*/Call.md"); got != "db-1" {
+		t.Errorf("GetDatabaseForPath() = %q, want db-1", got)
+	}
+	if got := cfg.GetDatabaseForPath("Plans 2/Tasks/Call.md"); got == "db-1" {
+		t.Errorf("GetDatabaseForPath() matched a folder the pattern should not")
+	}
+}
+
+// =============================================================================
+// Push Tag Filter Tests
+// =============================================================================
+
+func TestFilterByTags(t *testing.T) {
+	vault := t.TempDir()
+	notes := map[string]string{
+		"blog.md":    "---\ntags: [publish]\n---\n\nPost.\n",
+		"draft.md":   "Not ready. #Publish #private\n",
+		"project.md": "---\ntags: [\"#project/alpha\"]\n---\n\nPlan.\n",
+		"diary.md":   "Dear diary.\n",
+	}
+	for name, content := range notes {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := []pushFile{
+		{path: "blog.md", changeType: state.ChangeModified},
+		{path: "draft.md", changeType: state.ChangeCreated},
+		{path: "project.md", changeType: state.ChangeCreated},
+		{path: "diary.md", changeType: state.ChangeModified},
+		{path: "gone.md", changeType: state.ChangeDeleted},
+	}
+
+	tests := []struct {
+		name             string
+		include, exclude []string
+		want             []string
+	}{
+		{"include", []string{"publish"}, nil, []string{"blog.md", "draft.md"}},
+		{"include and exclude", []string{"#publish"}, []string{"private"}, []string{"blog.md"}},
+		{"exclude only", nil, []string{"private"}, []string{"blog.md", "project.md", "diary.md", "gone.md"}},
+		{"nested tag matches its parent", []string{"project"}, nil, []string{"project.md"}},
+		{"several tags", []string{"publish", "project"}, nil, []string{"blog.md", "draft.md", "project.md"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, f := range filterByTags(vault, files, tc.include, tc.exclude) {
+				got = append(got, f.path)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("filterByTags(%v, %v) = %v; want %v", tc.include, tc.exclude, got, tc.want)
+			}
+		})
+	}
+}
+
+// =============================================================================
+// HTTP Client Tests
+// =============================================================================
+
+func TestNewHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	// The server's certificate is trusted once it is the configured CA.
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		http     config.HTTPConfig
+		insecure bool
+		wantErr  bool
+	}{
+		{"untrusted", config.HTTPConfig{}, false, true},
+		{"ca_cert", config.HTTPConfig{CACert: caFile, Timeout: "5s"}, false, false},
+		{"insecure", config.HTTPConfig{}, true, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := newHTTPClient(tc.http, tc.insecure)
+			if err != nil {
+				t.Fatalf("newHTTPClient() error: %v", err)
+			}
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Get() error = %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	client, err := newHTTPClient(config.HTTPConfig{Proxy: "http://proxy.example.com:3128", Timeout: "30s"}, false)
+	if err != nil {
+		t.Fatalf("newHTTPClient() error: %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.notion.com/v1/users/me", nil)
+	proxy, err := client.Transport.(*http.Transport).Proxy(req)
+	if err != nil || proxy == nil || proxy.Host != "proxy.example.com:3128" {
+		t.Errorf("Proxy() = %v, %v; want proxy.example.com:3128", proxy, err)
+	}
+	if client.Timeout != 30*time.Second {
+		t.Errorf("Timeout = %v, want 30s", client.Timeout)
+	}
+
+	if _, err := newHTTPClient(config.HTTPConfig{CACert: filepath.Join(t.TempDir(), "missing.pem")}, false); err == nil {
+		t.Error("newHTTPClient() with a missing ca_cert succeeded")
+	}
+}
+
+// =============================================================================
+// Publish Tests
+// =============================================================================
+
+func TestSetFrontmatterValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "adds the key",
+			content: "---\ntitle: Hello\n---\n\nPost.\n",
+			want:    "---\ntitle: Hello\nstatus: Published\n---\n\nPost.\n",
+		},
+		{
+			name:    "replaces the value",
+			content: "---\nstatus: Draft # not yet\ntitle: Hello\n---\nPost.\n",
+			want:    "---\nstatus: Published\ntitle: Hello\n---\nPost.\n",
+		},
+		{
+			name:    "replaces a list value",
+			content: "---\nstatus:\n  - Draft\n  - Review\ntags: [blog]\n---\n",
+			want:    "---\nstatus: Published\ntags: [blog]\n---\n",
+		},
+		{
+			name:    "adds frontmatter",
+			content: "Post.\n",
+			want:    "---\nstatus: Published\n---\nPost.\n",
+		},
+		{
+			name:    "leaves similar keys",
+			content: "---\nstatuses: [a]\n---\n",
+			want:    "---\nstatuses: [a]\nstatus: Published\n---\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setFrontmatterValue([]byte(tt.content), "status", "Published")
+			if err != nil {
+				t.Fatalf("setFrontmatterValue() error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("setFrontmatterValue() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	got, err := setFrontmatterValue([]byte("---\ntitle: Hello\n---\n"), "public_url", "https://acme.notion.site/Hello-0123: draft")
+	if err != nil {
+		t.Fatalf("setFrontmatterValue() error: %v", err)
+	}
+	fm, _, _ := strings.Cut(strings.TrimPrefix(string(got), "---\n"), "---\n")
+	var parsed map[string]string
+	if err := yaml.Unmarshal([]byte(fm), &parsed); err != nil || parsed["public_url"] != "https://acme.notion.site/Hello-0123: draft" {
+		t.Errorf("value not quoted for YAML: %q (%v)", got, err)
+	}
+
+	if _, err := setFrontmatterValue([]byte("---\ntitle: Hello\n"), "status", "Published"); err == nil {
+		t.Error("setFrontmatterValue() with unclosed frontmatter succeeded")
+	}
+}
+
+func TestPublishStatusKey(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Vault = t.TempDir()
+	if _, ok := publishStatusKey(cfg, "posts/hello.md"); ok {
+		t.Error("publishStatusKey() found a key with no mapping")
+	}
+
+	cfg.Transform.PropertyMappings = []config.PropertyMappingConfig{
+		{Obsidian: "title", Notion: "Name", Type: "title"},
+		{Obsidian: "state", Notion: "Status", Type: "status"},
+	}
+	if key, ok := publishStatusKey(cfg, "posts/hello.md"); !ok || key != "state" {
+		t.Errorf("publishStatusKey() = %q, %v, want state", key, ok)
+	}
+
+	cfg.Publish.StatusProperty = "Stage"
+	if _, ok := publishStatusKey(cfg, "posts/hello.md"); ok {
+		t.Error("publishStatusKey() found a key for an unmapped property")
+	}
+}
+
+func TestSendPublishEvent(t *testing.T) {
+	var got publishEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	event := publishEvent{Event: "published", Path: "posts/hello.md", PageID: "page-1", URL: "https://www.notion.so/page-1", PublicURL: "https://acme.notion.site/page-1"}
+	if err := sendPublishEvent(context.Background(), server.URL, event); err != nil {
+		t.Fatalf("sendPublishEvent() error: %v", err)
+	}
+	if got != event {
+		t.Errorf("webhook got %+v, want %+v", got, event)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	if err := sendPublishEvent(context.Background(), failing.URL, event); err == nil {
+		t.Error("sendPublishEvent() to a failing webhook succeeded")
+	}
+}
+
+// =============================================================================
+// Mirror Tests
+// =============================================================================
+
+func TestRefuseMirror(t *testing.T) {
+	cfg := &config.Config{}
+	if err := refuseMirror(cfg, "pull"); err != nil {
+		t.Errorf("refuseMirror() in sync mode = %v, want nil", err)
+	}
+	cfg.Mode = config.ModeMirror
+	err := refuseMirror(cfg, "pull")
+	if err == nil || !strings.Contains(err.Error(), "pull is disabled in mirror mode") {
+		t.Errorf("refuseMirror() in mirror mode = %v, want pull disabled", err)
+	}
+}
+
+// redirectTransport sends requests to a test server.
+type redirectTransport struct {
+	target string
+}
+
+func (rt redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = "http"
+	r.URL.Host = rt.target
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestCheckMirror(t *testing.T) {
+	dir := t.TempDir()
+	db, err := state.Open(filepath.Join(dir, ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	pushed := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	notes := map[string]string{
+		"intact.md":   "page-intact",
+		"archived.md": "page-archived",
+		"deleted.md":  "page-deleted",
+		"edited.md":   "page-edited",
+		"changed.md":  "page-changed",
+		"new.md":      "",
+	}
+	for path, pageID := range notes {
+		fullPath := filepath.Join(dir, path)
+		if err := os.WriteFile(fullPath, []byte("# "+path+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if pageID == "" {
+			continue
+		}
+		hashes, err := state.HashFileDetailed(fullPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.SetState(&state.SyncState{
+			ObsidianPath:    path,
+			NotionPageID:    pageID,
+			ContentHash:     hashes.ContentHash,
+			FrontmatterHash: hashes.FrontmatterHash,
+			LastSync:        pushed,
+			Status:          "synced",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "changed.md"), []byte("# changed locally\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/v1/pages/")
+		w.Header().Set("Content-Type", "application/json")
+		if id == "page-deleted" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"object":"error","status":404,"code":"object_not_found","message":"Could not find page"}`)
+			return
+		}
+		edited := pushed.Add(-time.Hour)
+		if id == "page-edited" {
+			edited = pushed.Add(time.Hour)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"object":           "page",
+			"id":               id,
+			"archived":         id == "page-archived",
+			"created_time":     pushed.Add(-24 * time.Hour).Format(time.RFC3339),
+			"last_edited_time": edited.Format(time.RFC3339),
+		})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Vault: dir, Mode: config.ModeMirror}
+	cfg.Notion.Token = "test-token"
+	httpClient := &http.Client{Transport: redirectTransport{strings.TrimPrefix(server.URL, "http://")}}
+	clients := notion.NewFactory(cfg, notion.WithRateLimit(1000), notion.WithHTTPClient(httpClient))
+
+	report, err := checkMirror(context.Background(), cfg, db, clients)
+	if err != nil {
+		t.Fatalf("checkMirror() error: %v", err)
+	}
+	if report.Notes != 6 || report.Mirrored != 5 || report.Pages != 3 {
+		t.Errorf("Notes, Mirrored, Pages = %d, %d, %d; want 6, 5, 3", report.Notes, report.Mirrored, report.Pages)
+	}
+	sort.Strings(report.Pending)
+	sort.Strings(report.Missing)
+	checks := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"Pending", report.Pending, []string{"changed.md", "new.md"}},
+		{"Missing", report.Missing, []string{"archived.md", "deleted.md"}},
+		{"Edited", report.Edited, []string{"edited.md"}},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if report.intact() {
+		t.Error("intact() = true with missing and edited pages")
+	}
+
+	var out strings.Builder
+	report.write(&out)
+	if !strings.Contains(out.String(), "Edited:   1") || !strings.Contains(out.String(), "    deleted.md") {
+		t.Errorf("write() output missing sections:\n%s", out.String())
+	}
+}
+
+// =============================================================================
+// Verbose Tests
+// =============================================================================
+
+func TestExplainRoute(t *testing.T) {
+	cfg := &config.Config{
+		Notion: config.NotionConfig{DefaultDatabase: "db-default"},
+		Mappings: []config.FolderMapping{
+			{Path: "work/*.md", Database: "db-work", Credential: "work"},
+		},
+	}
+	cfg.Sync.DeletionStrategy = "archive"
+
+	tests := []struct {
+		name string
+		file pushFile
+		want string
+	}{
+		{
+			name: "new note, mapped",
+			file: pushFile{path: "work/plan.md", changeType: state.ChangeCreated},
+			want: "created: creating a page in database db-work (mapping work/*.md), credential work",
+		},
+		{
+			name: "new note, default database",
+			file: pushFile{path: "idea.md", changeType: state.ChangeCreated},
+			want: "created: creating a page in database db-default (notion.default_database)",
+		},
+		{
+			name: "modified note",
+			file: pushFile{path: "idea.md", changeType: state.ChangeModified, state: &state.SyncState{NotionPageID: "page-1"}},
+			want: "modified: updating page page-1",
+		},
+		{
+			name: "deleted note",
+			file: pushFile{path: "idea.md", changeType: state.ChangeDeleted, state: &state.SyncState{NotionPageID: "page-1"}},
+			want: "deleted: page handled by sync.deletion_strategy archive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := explainRoute(cfg, tt.file); got != tt.want {
+				t.Errorf("explainRoute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplainFiltered(t *testing.T) {
+	files := []pushFile{{path: "a.md"}, {path: "b.md"}}
+	kept := files[:1]
+
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	verbose = true
+	got := explainFiltered(files, kept, "does not match --path a*")
+	verbose = false
+	w.Close()
+	os.Stdout = old
+	out, _ := io.ReadAll(r)
+
+	if len(got) != 1 || got[0].path != "a.md" {
+		t.Errorf("explainFiltered() = %+v, want a.md only", got)
+	}
+	if want := "  . b.md: left out: does not match --path a*\n"; string(out) != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
+
+// =============================================================================
+// Import Export Tests
+// =============================================================================
+
+func TestExportProperties(t *testing.T) {
+	tc := &transformer.Config{
+		PropertyMappings: []transformer.PropertyMapping{
+			{ObsidianKey: "title", NotionName: "Name", NotionType: transformer.PropertyTypeTitle},
+			{ObsidianKey: "tags", NotionName: "Tags", NotionType: transformer.PropertyTypeMultiSelect},
+			{ObsidianKey: "status", NotionName: "Status", NotionType: transformer.PropertyTypeStatus},
+			{ObsidianKey: "points", NotionName: "Points", NotionType: transformer.PropertyTypeNumber},
+		},
+	}
+	page := &notionexport.Page{
+		Title:    "Ship it",
+		Database: &notionexport.Database{Columns: []string{"Name", "Tags", "Status", "Points", "Done", "Due", "Link", "Notes"}},
+		Properties: []notionexport.Property{
+			{Name: "Tags", Value: "work, launch"},
+			{Name: "Status", Value: "In progress"},
+			{Name: "Points", Value: "about 3"},
+			{Name: "Done", Value: "No"},
+			{Name: "Due", Value: "May 1, 2024 → May 3, 2024"},
+			{Name: "Link", Value: "https://example.com"},
+			{Name: "Notes", Value: "1 of 2"},
+		},
+	}
+	props := exportProperties(tc, page)
+
+	if p, ok := props["Name"].(*notionapi.TitleProperty); !ok || p.Title[0].PlainText != "Ship it" {
+		t.Errorf("Name = %#v", props["Name"])
+	}
+	if p, ok := props["Tags"].(*notionapi.MultiSelectProperty); !ok || len(p.MultiSelect) != 2 || p.MultiSelect[1].Name != "launch" {
+		t.Errorf("Tags = %#v", props["Tags"])
+	}
+	if p, ok := props["Status"].(*notionapi.StatusProperty); !ok || p.Status.Name != "In progress" {
+		t.Errorf("Status = %#v", props["Status"])
+	}
+	// A mapped value that does not parse as its type is kept as text.
+	if _, ok := props["Points"].(*notionapi.RichTextProperty); !ok {
+		t.Errorf("Points = %#v, want rich text", props["Points"])
+	}
+	if p, ok := props["Done"].(*notionapi.CheckboxProperty); !ok || p.Checkbox {
+		t.Errorf("Done = %#v", props["Done"])
+	}
+	if p, ok := props["Due"].(*notionapi.DateProperty); !ok || time.Time(*p.Date.Start).Format("2006-01-02") != "2024-05-01" {
+		t.Errorf("Due = %#v", props["Due"])
+	}
+	if _, ok := props["Link"].(*notionapi.URLProperty); !ok {
+		t.Errorf("Link = %#v, want url", props["Link"])
+	}
+	if _, ok := props["Notes"].(*notionapi.RichTextProperty); !ok {
+		t.Errorf("Notes = %#v, want rich text", props["Notes"])
+	}
+}
+
+func TestImportArchive(t *testing.T) {
+	const pageID = "1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d"
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"Plan " + pageID + ".md":        "# Plan\n\n<aside>\n💡 Keep it short.\n</aside>\n\n![chart.png](Plan%20" + pageID + "/chart.png)\n",
+		"Plan " + pageID + "/chart.png": "PNG",
+	} {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipPath := filepath.Join(t.TempDir(), "Export.zip")
+	if err := os.WriteFile(zipPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	vaultDir := t.TempDir()
+	db, err := state.Open(filepath.Join(vaultDir, ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	archive, err := notionexport.Open(zipPath)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer archive.Close()
+
+	cfg := &config.Config{
+		Vault: vaultDir,
+		Transform: config.TransformConfig{
+			Callouts: map[string]string{"tip": "💡"},
+		},
+	}
+	opts := importOptions{folder: "Notion", seedState: true}
+	result, err := importArchive(cfg, db, archive, opts)
+	if err != nil {
+		t.Fatalf("importArchive() error: %v", err)
+	}
+	if result.notes != 1 || result.files != 1 || result.tracked != 1 {
+		t.Errorf("result = %+v", result)
+	}
+
+	got, err := os.ReadFile(filepath.Join(vaultDir, "Notion", "Plan.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "---\ntitle: Plan\n---\n\n> [!tip]\n> Keep it short.\n\n![[Notion/Plan/chart.png]]\n"
+	if string(got) != want {
+		t.Errorf("note =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(vaultDir, "Notion", "Plan", "chart.png")); err != nil {
+		t.Errorf("attachment not copied: %v", err)
+	}
+	s, _ := db.GetState("Notion/Plan.md")
+	if s == nil || s.NotionPageID != "1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d" || s.Status != "synced" {
+		t.Errorf("state = %+v", s)
+	}
+	// The tracked note, frontmatter and all, is not seen as changed.
+	changes, err := newChangeDetector(cfg, db).DetectChanges(context.Background())
+	if err != nil {
+		t.Fatalf("DetectChanges() error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("changes after import = %+v, want none", changes)
+	}
+
+	// Existing notes are skipped without --force.
+	result, err = importArchive(cfg, db, archive, opts)
+	if err != nil {
+		t.Fatalf("importArchive() again error: %v", err)
+	}
+	if result.notes != 0 || result.skipped != 2 {
+		t.Errorf("second result = %+v", result)
+	}
+}
+
+func TestImportArchive_UnsafePath(t *testing.T) {
+	// An export with an entry leading out of the archive is refused.
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("../../evil.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("# Evil\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipPath := filepath.Join(t.TempDir(), "Export.zip")
+	if err := os.WriteFile(zipPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := notionexport.Open(zipPath); !errors.Is(err, notionexport.ErrUnsafePath) {
+		t.Errorf("Open() error = %v, want ErrUnsafePath", err)
+	}
+
+	// Nor are notes or files outside the import folder written.
+	parent := t.TempDir()
+	vaultDir := filepath.Join(parent, "vault")
+	cfg := &config.Config{Vault: vaultDir}
+	for _, archive := range []*notionexport.Archive{
+		{Pages: []*notionexport.Page{{Path: "../../evil.md", Title: "Evil"}}},
+		{Files: []*notionexport.File{{Path: "../.bashrc"}}},
+	} {
+		if _, err := importArchive(cfg, nil, archive, importOptions{folder: "Notion"}); err == nil {
+			t.Errorf("importArchive() of %+v succeeded", archive)
+		}
+	}
+	for _, name := range []string{"evil.md", ".bashrc", filepath.Join("vault", ".bashrc")} {
+		if _, err := os.Stat(filepath.Join(parent, name)); err == nil {
+			t.Errorf("%s written outside the import folder", name)
+		}
+	}
+}
+
+func TestImportArchive_HTML(t *testing.T) {
+	const (
+		planID = "1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d"
+		noteID = "fedcba9876543210fedcba9876543210"
+	)
+	plan := "Plan%20" + planID
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"Plan " + planID + ".html": `<html><body><article class="page sans"><header><h1 class="page-title">Plan</h1></header><div class="page-body">` +
+			`<figure class="callout"><div><span class="icon">💡</span></div><div><p>Keep it <span class="highlight-red">short</span>.</p></div></figure>` +
+			`<p>See <a href="` + plan + `/Notes%20` + noteID + `.html">Notes</a>.</p>` +
+			`<ul class="toggle"><li><details open=""><summary>More</summary><p>Hidden</p></details></li></ul>` +
+			`<figure class="image"><a href="` + plan + `/chart.png"><img src="` + plan + `/chart.png"/></a></figure>` +
+			`</div></article></body></html>`,
+		"Plan " + planID + "/Notes " + noteID + ".html": `<html><body><article><header><h1 class="page-title">Notes</h1></header><div class="page-body"><p>Hi</p></div></article></body></html>`,
+		"Plan " + planID + "/chart.png":                 "PNG",
+	} {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipPath := filepath.Join(t.TempDir(), "Export.zip")
+	if err := os.WriteFile(zipPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := notionexport.Open(zipPath)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer archive.Close()
+
+	vaultDir := t.TempDir()
+	cfg := &config.Config{
+		Vault: vaultDir,
+		Transform: config.TransformConfig{
+			Callouts: map[string]string{"tip": "💡"},
+		},
+	}
+	result, err := importArchive(cfg, nil, archive, importOptions{folder: "Notion"})
+	if err != nil {
+		t.Fatalf("importArchive() error: %v", err)
+	}
+	if result.notes != 2 || result.files != 1 {
+		t.Errorf("result = %+v", result)
+	}
+
+	// The page is converted as a pull converts it: the highlight, the
+	// mention, and the toggle are kept, and the image is embedded from
+	// where the import copied it.
+	got, err := os.ReadFile(filepath.Join(vaultDir, "Notion", "Plan.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"title: Plan",
+		"> [!tip]\n> Keep it ",
+		"short",
+		"See [[Notion/Plan/Notes]].",
+		"- More\n",
+		"Hidden",
+		"![[Notion/Plan/chart.png]]",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("note missing %q:\n%s", want, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(vaultDir, "Notion", "Plan", "chart.png")); err != nil {
+		t.Errorf("attachment not copied: %v", err)
+	}
+}
+
+// =============================================================================
+// Stub Tests
+// =============================================================================
+
+func TestStubTargets(t *testing.T) {
+	vaultDir := t.TempDir()
+	for _, name := range []string{"Existing.md", "work/Plan.md", ".trash/Gone.md"} {
+		full := filepath.Join(vaultDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err := os.WriteFile(full, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &config.Config{Vault: vaultDir, Links: config.LinksConfig{StubFolder: "Inbox"}}
+	results := []osync.Task[pushFile, pushResult]{
+		{Result: pushResult{placeholders: []string{"New Idea", "existing", "Plan#Goals", "diagram.png", "Gone"}}},
+		{Result: pushResult{placeholders: []string{"people/Ada^bio", "New Idea"}}},
+		{Result: pushResult{placeholders: []string{"Failed Link"}}, Err: errors.New("push failed")},
+	}
+
+	got, err := stubTargets(cfg, results)
+	if err != nil {
+		t.Fatalf("stubTargets() error: %v", err)
+	}
+	want := map[string]string{
+		"Inbox/New Idea.md": "New Idea",
+		"Inbox/Gone.md":     "Gone",
+		"people/Ada.md":     "Ada",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stubTargets() = %v, want %v", got, want)
+	}
+}
+
+func TestWriteStub(t *testing.T) {
+	vaultDir := t.TempDir()
+	os.MkdirAll(filepath.Join(vaultDir, "Templates"), 0755)
+	if err := os.WriteFile(filepath.Join(vaultDir, "Templates", "stub.md"), []byte("# {{title}}\n\nCreated {{date}}.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{Vault: vaultDir, Links: config.LinksConfig{StubTemplate: "Templates/stub.md"}}
+	now := time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)
+
+	if err := writeStub(cfg, "Inbox/New Idea.md", "New Idea", now); err != nil {
+		t.Fatalf("writeStub() error: %v", err)
+	}
+	got, _ := os.ReadFile(filepath.Join(vaultDir, "Inbox", "New Idea.md"))
+	if want := "# New Idea\n\nCreated 2024-03-05.\n"; string(got) != want {
+		t.Errorf("stub = %q, want %q", got, want)
+	}
+
+	// An existing note is not overwritten.
+	if err := writeStub(cfg, "Inbox/New Idea.md", "New Idea", now); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("writeStub() over existing note = %v, want fs.ErrExist", err)
+	}
+}
+
+// =============================================================================
+// Own Edit Tests
+// =============================================================================
+
+func TestOwnEdit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"object":"user","id":"bot-1","type":"bot","bot":{}}`))
+	}))
+	defer server.Close()
+	cfg := &config.Config{}
+	cfg.Notion.Token = "test-token"
+	httpClient := &http.Client{Transport: redirectTransport{strings.TrimPrefix(server.URL, "http://")}}
+	client := notion.NewFactory(cfg, notion.WithRateLimit(1000), notion.WithHTTPClient(httpClient)).ForToken("test-token")
+
+	synced := time.Date(2026, 3, 1, 12, 0, 30, 0, time.UTC)
+	s := &state.SyncState{ObsidianPath: "note.md", NotionPageID: "page-1", LastSync: synced}
+	page := func(editor string, edited time.Time) *notionapi.Page {
+		return &notionapi.Page{LastEditedBy: notionapi.User{ID: notionapi.UserID(editor)}, LastEditedTime: edited}
+	}
+
+	tests := []struct {
+		name string
+		page *notionapi.Page
+		s    *state.SyncState
+		want bool
+	}{
+		{"push racing the check", page("bot-1", synced.Add(30*time.Second)), s, true},
+		{"edit by a person", page("user-1", synced.Add(30*time.Second)), s, false},
+		{"later edit by the integration", page("bot-1", synced.Add(time.Hour)), s, false},
+		{"untracked page", page("bot-1", synced), nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ownEdit(context.Background(), client, tt.page, tt.s); got != tt.want {
+				t.Errorf("ownEdit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// =============================================================================
+// Template Tests
+// =============================================================================
+
+func TestWriteTemplated(t *testing.T) {
+	heading := func(text string) notionapi.Block {
+		return &notionapi.Heading2Block{Heading2: notionapi.Heading{RichText: []notionapi.RichText{{PlainText: text}}}}
+	}
+	rt := transformer.NewReverse(nil, nil)
+
+	tests := []struct {
+		name          string
+		content       string
+		before, after []notionapi.Block
+		want          string
+	}{
+		{
+			name:    "around the body",
+			content: "---\ntitle: Sync\n---\n\nNotes *as written*.\n",
+			before:  []notionapi.Block{heading("Agenda")},
+			after:   []notionapi.Block{heading("Actions")},
+			want:    "---\ntitle: Sync\n---\n\n## Agenda\n\nNotes *as written*.\n\n## Actions\n",
+		},
+		{
+			name:    "empty note",
+			content: "",
+			after:   []notionapi.Block{heading("Actions")},
+			want:    "## Actions\n",
+		},
+		{
+			name:    "no template content",
+			content: "Body.\n",
+			want:    "Body.\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fullPath := filepath.Join(t.TempDir(), "note.md")
+			if err := os.WriteFile(fullPath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := writeTemplated(fullPath, rt, tt.before, tt.after); err != nil {
+				t.Fatalf("writeTemplated() error: %v", err)
+			}
+			got, _ := os.ReadFile(fullPath)
+			if string(got) != tt.want {
+				t.Errorf("note = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// =============================================================================
+// Push Parent Tests
+// =============================================================================
+
+func TestCreatePage_Parent(t *testing.T) {
+	var parents []notionapi.Parent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Parent notionapi.Parent }
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		parents = append(parents, req.Parent)
+		_, _ = w.Write([]byte(`{"object":"page","id":"new-page","properties":{}}`))
+	}))
+	defer server.Close()
+	cfg := &config.Config{}
+	cfg.Notion.Token = "test-token"
+	httpClient := &http.Client{Transport: redirectTransport{strings.TrimPrefix(server.URL, "http://")}}
+	client := notion.NewFactory(cfg, notion.WithRateLimit(1000), notion.WithHTTPClient(httpClient)).ForToken("test-token")
+
+	page := func() *transformer.NotionPage {
+		return &transformer.NotionPage{Properties: notionapi.Properties{
+			"Name": notionapi.TitleProperty{Title: []notionapi.RichText{{Text: &notionapi.Text{Content: "Standup"}}}},
+		}}
+	}
+	pc := &pushContext{cfg: cfg}
+	if _, err := pc.createPage(context.Background(), client, pc.newPageParent("a.md", &parser.ParsedNote{}), page()); err != nil {
+		t.Fatalf("createPage() error: %v", err)
+	}
+	pc.parentPage = "meeting-page"
+	parent := pc.newPageParent("a.md", &parser.ParsedNote{})
+	if parent != "meeting-page" {
+		t.Errorf("newPageParent() = %q, want the --parent page", parent)
+	}
+	if _, err := pc.createPage(context.Background(), client, parent, page()); err != nil {
+		t.Fatalf("createPage() error: %v", err)
+	}
+
+	if len(parents) != 2 {
+		t.Fatalf("made %d requests, want 2", len(parents))
+	}
+	if parents[0].Type != notionapi.ParentTypeDatabaseID {
+		t.Errorf("default parent = %+v, want a database", parents[0])
+	}
+	if parents[1].Type != notionapi.ParentTypePageID || parents[1].PageID != "meeting-page" {
+		t.Errorf("--parent parent = %+v, want page meeting-page", parents[1])
+	}
+
+	// New pages record the parent they were created in; updated ones keep
+	// the one recorded.
+	if got := recordedParent(&state.SyncState{NotionParentID: "db-1"}, ""); got != "db-1" {
+		t.Errorf("recordedParent() of an update = %q, want db-1", got)
+	}
+	if got := recordedParent(nil, "meeting-page"); got != "meeting-page" {
+		t.Errorf("recordedParent() of a new page = %q, want meeting-page", got)
+	}
+}
+
+func TestPushThenSync_KeepsParent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/pages"):
+			_, _ = w.Write([]byte(`{"object":"page","id":"page-1","properties":{}}`))
+		default:
+			_, _ = w.Write([]byte(`{"object":"list","results":[],"has_more":false}`))
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	db, err := state.Open(filepath.Join(dir, ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cfg := &config.Config{Vault: dir}
+	cfg.Notion.Token = "test-token"
+	httpClient := &http.Client{Transport: redirectTransport{strings.TrimPrefix(server.URL, "http://")}}
+	clients := notion.NewFactory(cfg, notion.WithRateLimit(1000), notion.WithHTTPClient(httpClient))
+	scanner := newScanner(cfg)
+	linkRegistry := state.NewLinkRegistry(db)
+	fullPath := filepath.Join(dir, "note.md")
+	if err := os.WriteFile(fullPath, []byte("First.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// push --parent meeting-page
+	pc := &pushContext{
+		cfg:          cfg,
+		db:           db,
+		clients:      clients,
+		linkRegistry: linkRegistry,
+		attachments:  newAttachmentUploader(cfg, db, clients, scanner),
+		parser:       parser.New(),
+		scanner:      scanner,
+		undo:         newUndoRecorder(cfg, db, linkRegistry, "push", time.Now()),
+		parentPage:   "meeting-page",
+	}
+	if _, err := pc.processFile(context.Background(), pushFile{path: "note.md"}); err != nil {
+		t.Fatalf("push error: %v", err)
+	}
+
+	// sync of an edit to the note.
+	if err := os.WriteFile(fullPath, []byte("Second.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pushed, err := db.GetState("note.md")
+	if err != nil || pushed == nil {
+		t.Fatalf("GetState() = %v, %v", pushed, err)
+	}
+	spc := &syncPushContext{
+		cfg:          cfg,
+		db:           db,
+		clients:      clients,
+		linkRegistry: linkRegistry,
+		attachments:  newAttachmentUploader(cfg, db, clients, scanner),
+		parser:       parser.New(),
+		scanner:      scanner,
+		undo:         newUndoRecorder(cfg, db, linkRegistry, "sync", time.Now()),
+	}
+	if _, err := spc.processChange(context.Background(), state.Change{Type: state.ChangeModified, Path: "note.md", State: pushed}); err != nil {
+		t.Fatalf("sync error: %v", err)
+	}
+
+	synced, err := db.GetState("note.md")
+	if err != nil || synced == nil {
+		t.Fatalf("GetState() = %v, %v", synced, err)
+	}
+	if synced.NotionParentID != "meeting-page" {
+		t.Errorf("NotionParentID after sync = %q, want meeting-page", synced.NotionParentID)
+	}
+}
+
+// =============================================================================
+// Missing Mention Tests
+// =============================================================================
+
+func TestMissingMentions(t *testing.T) {
+	db, err := state.Open(filepath.Join(t.TempDir(), ".obsidian-notion.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	registry := state.NewLinkRegistry(db)
+	if err := db.SetState(&state.SyncState{ObsidianPath: "synced.md", NotionPageID: "synced-page", Status: "synced"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mentions := &mentionRecorder{PathLookup: registry}
+	for _, id := range []string{"synced-page", "0123abcd-4567-89ab-cdef-0123456789ab", "0123abcd-4567-89ab-cdef-0123456789ab"} {
+		mentions.LookupPath(id)
+	}
+	if len(mentions.missed) != 1 || mentions.missed[0] != "0123abcd-4567-89ab-cdef-0123456789ab" {
+		t.Fatalf("missed = %v, want the unsynced page", mentions.missed)
+	}
+
+	pc := &pullContext{db: db, linkRegistry: registry}
+	pc.takeMissingMentions("note.md", mentions.missed)
+	var out bytes.Buffer
+	pc.reportMissingMentions(&out)
+	if !strings.Contains(out.String(), "1 mentioned page") {
+		t.Errorf("report = %q, want one missing page", out.String())
+	}
+
+	got, err := registry.MissingMentions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	printMissingMentions(&out, got)
+	if !strings.Contains(out.String(), "https://www.notion.so/0123abcd456789abcdef0123456789ab") || !strings.Contains(out.String(), "mentioned in: note.md") {
+		t.Errorf("listing = %q", out.String())
+	}
+
+	// Once the page is pulled, it is no longer reported.
+	if err := db.SetState(&state.SyncState{ObsidianPath: "other.md", NotionPageID: "0123abcd-4567-89ab-cdef-0123456789ab", Status: "synced"}); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	pc.reportMissingMentions(&out)
+	if out.Len() != 0 {
+		t.Errorf("report after pulling = %q, want none", out.String())
+	}
+}
+
+// =============================================================================
+// Interrupt Tests
+// =============================================================================
+
+func TestStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if stopped(ctx, context.Canceled) {
+		t.Error("stopped() before the run ended = true")
+	}
+	if got := summaryTitle(ctx, "Push"); got != "Push complete:" {
+		t.Errorf("summaryTitle() = %q", got)
+	}
+
+	cancel()
+	if !stopped(ctx, fmt.Errorf("update page: %w", context.Canceled)) {
+		t.Error("stopped() of a cancelled request = false")
+	}
+	if stopped(ctx, errors.New("validation failed")) {
+		t.Error("stopped() of a failure = true")
+	}
+	if got := summaryTitle(ctx, "Push"); got != "Push interrupted:" {
+		t.Errorf("summaryTitle() = %q", got)
+	}
+
+	expired, cancelExpired := context.WithTimeout(context.Background(), 0)
+	defer cancelExpired()
+	if got := summaryTitle(expired, "Sync"); got != "Sync timed out:" {
+		t.Errorf("summaryTitle() = %q", got)
+	}
+}
+
+// =============================================================================
+// Selftest Tests
+// =============================================================================
+
+func TestSelftest_CleansUp(t *testing.T) {
+	var archived []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/pages":
+			_, _ = w.Write([]byte(`{"object":"page","id":"test-page","properties":{}}`))
+		case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/v1/blocks/"):
+			_, _ = w.Write([]byte(`{"object":"list","results":[]}`))
+		case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/v1/pages/"):
+			archived = append(archived, strings.TrimPrefix(r.URL.Path, "/v1/pages/"))
+			_, _ = w.Write([]byte(`{"object":"page","id":"test-page","archived":true,"properties":{}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"object":"error","status":404,"code":"object_not_found","message":"not found"}`))
+		}
+	}))
+	defer server.Close()
+	cfg := &config.Config{Vault: t.TempDir()}
+	cfg.Notion.Token = "test-token"
+	httpClient := &http.Client{Transport: redirectTransport{strings.TrimPrefix(server.URL, "http://")}}
+	st := &selftest{
+		cfg:     cfg,
+		client:  notion.NewFactory(cfg, notion.WithRateLimit(1000), notion.WithHTTPClient(httpClient)).ForToken("test-token"),
+		sandbox: "sandbox-page",
+	}
+
+	// The page is created, but cannot be read back.
+	var out bytes.Buffer
+	if passed := st.run(context.Background(), &out); passed != 1 {
+		t.Errorf("run() passed %d check(s), want 1\n%s", passed, out.String())
+	}
+	if !strings.Contains(out.String(), "FAIL  read it back") {
+		t.Errorf("output = %q, want the read back to fail", out.String())
+	}
+
+	st.cleanup(context.Background(), &out)
+	if len(archived) != 1 || archived[0] != "test-page" {
+		t.Errorf("archived %v, want the created page", archived)
+	}
+	if len(st.created) != 0 {
+		t.Errorf("created = %v after cleanup", st.created)
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	if line, diff := firstDifference("a\nb  \n", "a\nb\n\n"); diff != "" {
+		t.Errorf("firstDifference() of matching text = %d, %q", line, diff)
+	}
+	line, diff := firstDifference("a\nb\nc\n", "a\nB\nc\n")
+	if line != 2 || diff != `want "b", got "B"` {
+		t.Errorf("firstDifference() = %d, %q", line, diff)
+	}
+	if line, _ := firstDifference("a\n", "a\nextra\n"); line != 2 {
+		t.Errorf("firstDifference() of longer text = line %d, want 2", line)
+	}
+}
+
+// =============================================================================
+// State Doctor Tests
+// =============================================================================
+
+func TestFindStateIssues(t *testing.T) {
+	vault := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("kept.md", "# Kept\n")
+	write("copy.md", "# Copy\n")
+	write("moved.md", "# Moved\n")
+
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("state.Open() error: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	records := []*state.SyncState{
+		{ObsidianPath: "kept.md", NotionPageID: "page-a", LastSync: now, Status: "synced"},
+		{ObsidianPath: "copy.md", NotionPageID: "page-a", LastSync: now.Add(-time.Hour), Status: "synced"},
+		{ObsidianPath: "old.md", NotionPageID: "page-b", ContentHash: state.HashContent([]byte("# Moved\n")).ContentHash, Status: "synced"},
+		{ObsidianPath: "gone.md", NotionPageID: "page-c", ContentHash: "none", Status: "synced"},
+	}
+	for _, s := range records {
+		if err := db.SetState(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{Vault: vault}
+	issues, err := findStateIssues(context.Background(), cfg, db, nil)
+	if err != nil {
+		t.Fatalf("findStateIssues() error: %v", err)
+	}
+	got := make(map[string][]string)
+	for _, issue := range issues {
+		got[issue.state.ObsidianPath] = issue.repairs
+	}
+	want := map[string][]string{
+		"copy.md": {repairDrop, repairSkip},
+		"old.md":  {repairRelink, repairDrop, repairSkip},
+		"gone.md": {repairDrop, repairSkip},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("findStateIssues() repairs = %v, want %v", got, want)
+	}
+
+	for _, issue := range issues {
+		if err := repairStateIssue(context.Background(), db, nil, issue, issue.repairs[0]); err != nil {
+			t.Fatalf("repairStateIssue(%s) error: %v", issue.state.ObsidianPath, err)
+		}
+	}
+	if s, _ := db.GetState("moved.md"); s == nil || s.NotionPageID != "page-b" {
+		t.Errorf("moved.md = %+v, want relinked to page-b", s)
+	}
+	for _, path := range []string{"copy.md", "old.md", "gone.md"} {
+		if s, _ := db.GetState(path); s != nil {
+			t.Errorf("%s still has a record after repair: %+v", path, s)
+		}
+	}
+	if issues, _ := findStateIssues(context.Background(), cfg, db, nil); len(issues) != 0 {
+		t.Errorf("problems left after repair: %+v", issues)
+	}
+}
+
+func TestAskRepair(t *testing.T) {
+	issue := stateIssue{repairs: []string{repairReadopt, repairDrop, repairSkip}}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"\n", repairReadopt},
+		{"d\n", repairDrop},
+		{"bogus\nskip\n", repairSkip},
+		{"", repairSkip},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		got, err := askRepair(bufio.NewReader(strings.NewReader(tt.input)), &out, issue)
+		if err != nil || got != tt.want {
+			t.Errorf("askRepair(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+// =============================================================================
+// Page Lock Tests
+// =============================================================================
+
+func TestCheckBlocked(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"object":"page","id":"page-1","is_locked":true}`))
+	}))
+	defer server.Close()
+	cfg := &config.Config{}
+	cfg.Notion.Token = "test-token"
+	httpClient := &http.Client{Transport: redirectTransport{strings.TrimPrefix(server.URL, "http://")}}
+	client := notion.NewFactory(cfg, notion.WithRateLimit(1000), notion.WithHTTPClient(httpClient)).ForToken("test-token")
+
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("state.Open() error: %v", err)
+	}
+	defer db.Close()
+
+	s := &state.SyncState{ObsidianPath: "a.md", NotionPageID: "page-1", Status: "synced"}
+	if err := db.SetState(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkBlocked(context.Background(), client, s); err != nil || requests != 0 {
+		t.Errorf("checkBlocked() of a synced note = %v after %d request(s), want nil without requests", err, requests)
+	}
+
+	markBlocked(db, s)
+	blocked, err := db.GetState("a.md")
+	if err != nil || blocked.Status != blockedStatus {
+		t.Fatalf("state after markBlocked = %+v, %v", blocked, err)
+	}
+	err = checkBlocked(context.Background(), client, blocked)
+	if !errors.Is(err, notion.ErrLocked) {
+		t.Errorf("checkBlocked() of a locked page = %v, want a locked error", err)
+	}
+	if hint := notionHint(err); !strings.Contains(hint, "locked") {
+		t.Errorf("notionHint() = %q, want the lock explained", hint)
+	}
+}
+
+// =============================================================================
+// Mid-Push Edit Tests
+// =============================================================================
+
+func TestPushedStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.md")
+	content := []byte("---\ntags: [a]\n---\nBody\n")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pushed := state.HashContent(content)
+
+	if got := pushedStatus(state.Hasher{}, path, pushed); got != "synced" {
+		t.Errorf("pushedStatus() of an unchanged note = %q, want synced", got)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"body edited", "---\ntags: [a]\n---\nBody, edited\n"},
+		{"frontmatter edited", "---\ntags: [a, b]\n---\nBody\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := pushedStatus(state.Hasher{}, path, pushed); got != "pending" {
+				t.Errorf("pushedStatus() = %q, want pending", got)
+			}
+		})
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := pushedStatus(state.Hasher{}, path, pushed); got != "synced" {
+		t.Errorf("pushedStatus() of a deleted note = %q, want synced", got)
+	}
+}
+
+// =============================================================================
+// Pull Layout Tests
+// =============================================================================
+
+func TestLayoutPath(t *testing.T) {
+	page := func(id, title string, props notionapi.Properties) *notionapi.Page {
+		if props == nil {
+			props = notionapi.Properties{}
+		}
+		props["Name"] = &notionapi.TitleProperty{Title: []notionapi.RichText{{PlainText: title}}}
+		return &notionapi.Page{ID: notionapi.ObjectID(id), CreatedTime: time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), Properties: props}
+	}
+	parentOf := func(id string) *notionapi.RelationProperty {
+		return &notionapi.RelationProperty{Relation: []notionapi.Relation{{ID: notionapi.PageID(id)}}}
+	}
+	projects := page("p-1", "Projects", nil)
+	launch := page("p-2", "Launch: v2", notionapi.Properties{parentItemProperty: parentOf("p-1")})
+	task := page("p-3", "Write notes", notionapi.Properties{
+		parentItemProperty: parentOf("p-2"),
+		"Area":             &notionapi.SelectProperty{Select: notionapi.Option{Name: "Work"}},
+	})
+	pages := map[string]*notionapi.Page{"p1": projects, "p2": launch, "p3": task}
+
+	tests := []struct {
+		name   string
+		layout string
+		page   *notionapi.Page
+		want   string
+	}{
+		{"flat", "", task, "Write notes.md"},
+		{"by select", "{{Area}}/{{title}}.md", task, "Work/Write notes.md"},
+		{"missing select", "{{Area}}/{{title}}.md", projects, "Projects.md"},
+		{"by creation date", "Journal/{{year}}/{{month}}/{{title}}.md", task, "Journal/2026/03/Write notes.md"},
+		{"by parent items", parentLayout, task, "Projects/Launch- v2/Write notes.md"},
+		{"top-level parent", parentLayout, projects, "Projects.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := layoutPath(tt.layout, tt.page, pages, config.FilenamesConfig{})
+			if got != filepath.FromSlash(tt.want) {
+				t.Errorf("layoutPath(%q) = %q, want %q", tt.layout, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParentItems_Cycle(t *testing.T) {
+	a := &notionapi.Page{ID: "a", Properties: notionapi.Properties{
+		parentItemProperty: &notionapi.RelationProperty{Relation: []notionapi.Relation{{ID: "b"}}},
+	}}
+	b := &notionapi.Page{ID: "b", Properties: notionapi.Properties{
+		parentItemProperty: &notionapi.RelationProperty{Relation: []notionapi.Relation{{ID: "a"}}},
+	}}
+	chain := parentItems(a, map[string]*notionapi.Page{"a": a, "b": b})
+	if len(chain) != 1 || chain[0] != b {
+		t.Errorf("parentItems() of a cycle = %v, want only b", chain)
+	}
+}
+
+// =============================================================================
+// Link Graph Tests
+// =============================================================================
+
+func TestBuildLinkGraph(t *testing.T) {
+	db, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("state.Open() error: %v", err)
+	}
+	defer db.Close()
+
+	for _, s := range []*state.SyncState{
+		{ObsidianPath: "a.md", NotionPageID: "page-a", Status: "synced"},
+		{ObsidianPath: "b.md", NotionPageID: "page-b", Status: "pending"},
+	} {
+		if err := db.SetState(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry := state.NewLinkRegistry(db)
+	if err := registry.RegisterLinks("a.md", []string{"b", "Nowhere"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.ResolveAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	graph, err := buildLinkGraph(db)
+	if err != nil {
+		t.Fatalf("buildLinkGraph() error: %v", err)
+	}
+	nodes := make(map[string]graphNode)
+	for _, n := range graph.Nodes {
+		nodes[n.ID] = n
+	}
+	if n := nodes["a.md"]; n.Status != "synced" || n.NotionPageID != "page-a" || n.Label != "a" {
+		t.Errorf("node a.md = %+v", n)
+	}
+	if n := nodes["b.md"]; n.Status != "pending" {
+		t.Errorf("node b.md = %+v, want its sync status", n)
+	}
+	if n := nodes["unresolved:Nowhere"]; n.Status != graphUnresolved {
+		t.Errorf("node for the unresolved target = %+v", n)
+	}
+	want := []graphEdge{
+		{Source: "a.md", Target: "unresolved:Nowhere", Link: "Nowhere"},
+		{Source: "a.md", Target: "b.md", Link: "b", Resolved: true},
+	}
+	if !reflect.DeepEqual(graph.Edges, want) {
+		t.Errorf("edges = %+v, want %+v", graph.Edges, want)
+	}
+
+	var out bytes.Buffer
+	writeDOT(&out, graph)
+	for _, line := range []string{
+		`"a.md" [label="a", status="synced", notion_page_id="page-a", URL="https://www.notion.so/pagea"];`,
+		`"unresolved:Nowhere" [label="Nowhere", status="unresolved", style=dashed];`,
+		`"a.md" -> "b.md";`,
+		`"a.md" -> "unresolved:Nowhere" [style=dashed];`,
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("DOT missing %s:\n%s", line, out.String())
+		}
+	}
+}
+
+// =============================================================================
+// Watch Poll Tests
+// =============================================================================
+
+func TestPollNotion_Downloads(t *testing.T) {
+	var mu sync.Mutex
+	var downloads, blockFetches int
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/files/"):
+			downloads++
+			_, _ = w.Write([]byte("image"))
+		case strings.HasPrefix(r.URL.Path, "/v1/pages/"):
+			_, _ = w.Write([]byte(`{"object":"page","id":"page-1","last_edited_time":"2026-03-02T00:00:00.000Z","parent":{"type":"workspace","workspace":true},"properties":{"title":{"id":"title","type":"title","title":[{"type":"text","text":{"content":"Note"},"plain_text":"Note"}]}}}`))
+		case strings.HasPrefix(r.URL.Path, "/v1/blocks/"):
+			blockFetches++
+			fmt.Fprintf(w, `{"object":"list","results":[{"object":"block","id":"block-1","type":"image","has_children":false,"image":{"type":"file","file":{"url":"%s/files/pic.png","expiry_time":"2026-03-03T00:00:00.000Z"}}}],"has_more":false}`, server.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		local      string
+		directions []config.DirectionPolicy
+		mirror     bool
+		synced     string // The note when last synced, if not "Synced.\n"
+		recorded   bool   // pic.png is recorded as uploaded from the note
+		downloads  int
+		sidecar    bool
+	}{
+		{name: "pull", local: "Synced.\n", downloads: 1},
+		{name: "manual conflict", local: "Edited.\n", sidecar: true},
+		// The page reads back as the synced note, with the image embedded
+		// as the file it was pushed from: the local edit is no conflict.
+		{name: "formatting only", local: "Edited.\n", synced: "---\ntitle: Note\n---\n\n![[pic.png]]\n", recorded: true},
+		{name: "push-only path", local: "Synced.\n", directions: []config.DirectionPolicy{{Path: "note.md", Direction: config.DirectionPush}}},
+		{name: "mirror", local: "Edited.\n", mirror: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			downloads, blockFetches = 0, 0
+			dir := t.TempDir()
+			db, err := state.Open(filepath.Join(dir, ".obsidian-notion.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if tt.synced == "" {
+				tt.synced = "Synced.\n"
+			}
+			synced := state.HashContent([]byte(tt.synced))
+			if err := os.WriteFile(filepath.Join(dir, "note.md"), []byte(tt.local), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.SetState(&state.SyncState{
+				ObsidianPath:    "note.md",
+				NotionPageID:    "page-1",
+				ContentHash:     synced.ContentHash,
+				FrontmatterHash: synced.FrontmatterHash,
+				NotionMtime:     time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+				Status:          "synced",
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.recorded {
+				store := state.NewAttachmentStore(db)
+				if err := store.Record(&state.Attachment{ContentHash: "pic", FileUploadID: "upload-1", OriginalPath: "pic.png"}); err != nil {
+					t.Fatal(err)
+				}
+				if err := store.SetRefs("note.md", []string{"pic"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg := &config.Config{Vault: dir}
+			cfg.Notion.Token = "test-token"
+			cfg.Sync.Directions = tt.directions
+			if tt.mirror {
+				cfg.Mode = config.ModeMirror
+			}
+			httpClient := &http.Client{Transport: redirectTransport{strings.TrimPrefix(server.URL, "http://")}}
+			w := &watcher{
+				cfg:          cfg,
+				db:           db,
+				clients:      notion.NewFactory(cfg, notion.WithRateLimit(1000), notion.WithHTTPClient(httpClient)),
+				linkRegistry: state.NewLinkRegistry(db),
+				scanner:      vault.NewScanner(dir, nil),
+				strategy:     StrategyManual,
+				blockedPulls: make(map[string]time.Time),
+				out:          io.Discard,
+			}
+			w.pollNotion()
+
+			if downloads != tt.downloads {
+				t.Errorf("downloaded %d attachments, want %d", downloads, tt.downloads)
+			}
+			// Pages are fetched once; a mirror is not polled at all.
+			wantFetches := 1
+			if tt.mirror {
+				wantFetches = 0
+			}
+			if blockFetches != wantFetches {
+				t.Errorf("fetched the page's blocks %d times, want %d", blockFetches, wantFetches)
+			}
+			_, err = os.Stat(filepath.Join(dir, remoteSidecarPath("note.md")))
+			if sidecar := err == nil; sidecar != tt.sidecar {
+				t.Errorf("sidecar written = %v, want %v", sidecar, tt.sidecar)
+			}
+		})
+	}
+}
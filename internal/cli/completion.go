@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+)
+
+// completionCmd represents the completion command.
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate shell completion scripts",
+	Long: `Generate a completion script for your shell.
+
+Besides commands and flags, the script completes note paths relative to
+the vault for push, pull, and conflicts, conflicted notes for conflicts
+resolve and diff, and database IDs from the config for init --database.
+These are looked up when you press Tab, using --config if given.
+
+Bash (requires bash-completion):
+  source <(obsidian-notion completion bash)
+  obsidian-notion completion bash > /etc/bash_completion.d/obsidian-notion
+
+Zsh:
+  obsidian-notion completion zsh > "${fpath[1]}/_obsidian-notion"
+
+Fish:
+  obsidian-notion completion fish > ~/.config/fish/completions/obsidian-notion.fish
+
+PowerShell:
+  obsidian-notion completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return writeCompletion(cmd.Root(), cmd.OutOrStdout(), args[0])
+	},
+}
+
+// writeCompletion writes the completion script of root for a shell.
+func writeCompletion(root *cobra.Command, out io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		return root.GenBashCompletionV2(out, true)
+	case "zsh":
+		return root.GenZshCompletion(out)
+	case "fish":
+		return root.GenFishCompletion(out, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(out)
+	default:
+		return fmt.Errorf("unsupported shell: %s (must be bash, zsh, fish, or powershell)", shell)
+	}
+}
+
+// completionConfig returns the config for a completion request. Cobra does
+// not run the root's pre-run hook while completing, so the config is
+// loaded here if no command has loaded it yet.
+func completionConfig() (*config.Config, error) {
+	if cfg != nil {
+		return cfg, nil
+	}
+	return config.Load(cfgFile)
+}
+
+// completeNotePaths completes note arguments with the vault's notes,
+// relative to the vault root.
+func completeNotePaths(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := completionConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	files, err := newScanner(cfg).Scan(context.Background())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	return filterCompletions(paths, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConflictPaths completes the path argument of conflicts resolve
+// and diff with the notes currently in conflict.
+func completeConflictPaths(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := completionConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	// Don't create a state database just to complete a word.
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer db.Close()
+
+	conflicts, err := state.NewConflictTracker(db).GetConflicts()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	paths := make([]string, 0, len(conflicts))
+	for _, c := range conflicts {
+		paths = append(paths, c.ObsidianPath)
+	}
+	return filterCompletions(paths, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeDatabases completes a database flag with the databases named in
+// the config, described by where they are configured.
+func completeDatabases(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := completionConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var out []string
+	for _, choice := range databaseChoices(cfg) {
+		if strings.HasPrefix(choice, toComplete) {
+			out = append(out, choice)
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// databaseChoices lists the databases in the config as completions in
+// cobra's "value\tdescription" form. A database configured more than once
+// is listed once, described by the first place it appears.
+func databaseChoices(cfg *config.Config) []string {
+	seen := make(map[string]bool)
+	var choices []string
+	add := func(db, desc string) {
+		if db == "" || seen[db] {
+			return
+		}
+		seen[db] = true
+		choices = append(choices, db+"\t"+desc)
+	}
+
+	add(cfg.Notion.DefaultDatabase, "default database")
+	for _, m := range cfg.Mappings {
+		add(m.Database, "mapping "+m.Path)
+	}
+	names := make([]string, 0, len(cfg.Targets))
+	for name := range cfg.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(cfg.Targets[name].Database, "target "+name)
+	}
+	add(cfg.Notion.StagingDatabase, "staging database")
+	return choices
+}
+
+// filterCompletions keeps the candidates that start with toComplete and
+// were not already given as arguments.
+func filterCompletions(candidates, args []string, toComplete string) []string {
+	given := make(map[string]bool, len(args))
+	for _, arg := range args {
+		given[filepath.Clean(arg)] = true
+	}
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, toComplete) && !given[c] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
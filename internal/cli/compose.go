@@ -0,0 +1,510 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+	"github.com/adamancini/obsidian-notion-sync/internal/vault"
+)
+
+// datePrefixPattern matches a YYYY-MM-DD prefix in a filename.
+var datePrefixPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+
+// composer syncs composition rules: groups of notes stored as Heading 1
+// sections of a single Notion page. The member list and section headings
+// are recorded in the state database so pull can split the page again.
+type composer struct {
+	cfg          *config.Config
+	db           *state.DB
+	clients      *notion.Factory
+	linkRegistry *state.LinkRegistry
+	attachments  *attachmentUploader
+	scanner      *vault.Scanner
+}
+
+// newComposer creates a composer sharing the caller's clients and registry.
+func newComposer(cfg *config.Config, db *state.DB, clients *notion.Factory, linkRegistry *state.LinkRegistry, attachments *attachmentUploader, scanner *vault.Scanner) *composer {
+	return &composer{
+		cfg:          cfg,
+		db:           db,
+		clients:      clients,
+		linkRegistry: linkRegistry,
+		attachments:  attachments,
+		scanner:      scanner,
+	}
+}
+
+// compositeNote is a member note prepared for composition.
+type compositeNote struct {
+	path    string
+	title   string
+	date    time.Time
+	heading string
+	mtime   time.Time
+	note    *parser.ParsedNote
+}
+
+// rulesFor returns the composition rules matching any of the given paths,
+// each at most once.
+func (c *composer) rulesFor(paths []string) []*config.Composition {
+	seen := make(map[*config.Composition]bool)
+	var rules []*config.Composition
+	for _, path := range paths {
+		rule := c.cfg.GetComposition(path)
+		if rule != nil && !seen[rule] {
+			seen[rule] = true
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// pulledRules returns the composition rules with recorded members, limited
+// to rules with a member matching pattern when one is given.
+func (c *composer) pulledRules(pattern string) ([]*config.Composition, error) {
+	var rules []*config.Composition
+	for i := range c.cfg.Compositions {
+		rule := &c.cfg.Compositions[i]
+		members, err := c.db.GetCompositeMembers(rule.Page)
+		if err != nil {
+			return nil, fmt.Errorf("get members: %w", err)
+		}
+		for _, m := range members {
+			matched := true
+			if pattern != "" {
+				matched, _ = filepath.Match(pattern, m.ObsidianPath)
+			}
+			if matched {
+				rules = append(rules, rule)
+				break
+			}
+		}
+	}
+	return rules, nil
+}
+
+// push rebuilds a composed page from the notes currently matching the rule
+// and returns the number of member notes written.
+func (c *composer) push(ctx context.Context, rule *config.Composition) (int, error) {
+	previous, err := c.db.GetCompositeMembers(rule.Page)
+	if err != nil {
+		return 0, fmt.Errorf("get members: %w", err)
+	}
+
+	// 1. Collect and order the member notes.
+	files, err := c.scanner.ScanGlob(ctx, rule.Path)
+	if err != nil {
+		return 0, fmt.Errorf("scan %s: %w", rule.Path, err)
+	}
+	if len(files) == 0 && len(previous) == 0 {
+		return 0, nil
+	}
+
+	p := parser.New()
+	notes := make([]*compositeNote, 0, len(files))
+	for _, f := range files {
+		content, err := os.ReadFile(f.AbsPath)
+		if err != nil {
+			return 0, fmt.Errorf("read %s: %w", f.Path, err)
+		}
+		note, err := p.Parse(f.Path, content)
+		if err != nil {
+			return 0, fmt.Errorf("parse %s: %w", f.Path, err)
+		}
+		cn := &compositeNote{
+			path:  f.Path,
+			title: compositeTitle(f.Path, note.Frontmatter),
+			date:  compositeDate(f.Path, note.Frontmatter),
+			mtime: f.Info.ModTime(),
+			note:  note,
+		}
+		cn.heading = compositeHeading(cn.title, cn.date)
+		notes = append(notes, cn)
+	}
+	sortCompositeNotes(notes, rule.Order)
+
+	// 2. Transform each note under its own Heading 1. Notes are never split
+	// into child pages here; the headings already delimit them.
+	var children []notionapi.Block
+	resolved := make([]*noteAttachments, len(notes))
+	for i, cn := range notes {
+		registerNoteLinks(c.linkRegistry, cn.path, cn.note)
+		warnUnexpandedTemplates(os.Stderr, cn.path, cn.note)
+		resolved[i] = c.attachments.prepare(ctx, cn.path, cn.note)
+
+		tcfg := buildTransformerConfig(c.cfg, cn.path)
+		tcfg.SplitOn = ""
+		t := transformer.New(c.linkRegistry, tcfg)
+		t.SetAttachmentResolver(resolved[i])
+
+		page, err := t.Transform(cn.note)
+		if err != nil {
+			return 0, fmt.Errorf("transform %s: %w", cn.path, err)
+		}
+		children = append(children, compositeHeadingBlock(cn.heading))
+		children = append(children, page.Children...)
+	}
+
+	// 3. Replace the page content. Properties belong to the page itself and
+	// are left alone.
+	client := c.clients.ForPath(memberPath(rule, notes, previous))
+	if err := client.UpdatePage(ctx, rule.Page, &transformer.NotionPage{Children: children}); err != nil {
+		return 0, fmt.Errorf("update page: %w", err)
+	}
+
+	// 4. Record the mapping so pull can split the page again.
+	members := make([]state.CompositeMember, len(notes))
+	current := make(map[string]bool, len(notes))
+	for i, cn := range notes {
+		members[i] = state.CompositeMember{ObsidianPath: cn.path, Heading: cn.heading}
+		current[cn.path] = true
+	}
+	if err := c.db.SetCompositeMembers(rule.Page, members); err != nil {
+		return 0, fmt.Errorf("record members: %w", err)
+	}
+
+	now := time.Now()
+	for i, cn := range notes {
+		hashes, err := noteHasher(c.cfg).HashFileDetailed(filepath.Join(c.cfg.Vault, cn.path))
+		if err != nil {
+			hashes = state.ContentHashes{}
+		}
+		if err := c.db.SetState(&state.SyncState{
+			ObsidianPath:    cn.path,
+			NotionPageID:    rule.Page,
+			ObsidianMtime:   cn.mtime,
+			NotionMtime:     now,
+			ContentHash:     hashes.ContentHash,
+			FrontmatterHash: hashes.FrontmatterHash,
+			LastSync:        now,
+			SyncDirection:   "push",
+			Status:          "synced",
+		}); err != nil {
+			return i, fmt.Errorf("update state for %s: %w", cn.path, err)
+		}
+		if err := c.attachments.commit(resolved[i]); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: failed to record attachments for %s: %v\n", cn.path, err)
+		}
+	}
+
+	// 5. Forget members whose notes were removed or moved out of the rule.
+	for _, m := range previous {
+		if current[m.ObsidianPath] {
+			continue
+		}
+		if err := c.db.DeleteState(m.ObsidianPath); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: failed to clear state for %s: %v\n", m.ObsidianPath, err)
+		}
+	}
+
+	return len(notes), nil
+}
+
+// pull splits a composed page back into its member notes and returns the
+// number of files written. Unless force is set, nothing is fetched when the
+// page has not been edited since the last sync. Members in keep are left
+// untouched, so local edits survive until they are pushed.
+func (c *composer) pull(ctx context.Context, rule *config.Composition, force bool, keep map[string]bool) (int, error) {
+	members, err := c.db.GetCompositeMembers(rule.Page)
+	if err != nil {
+		return 0, fmt.Errorf("get members: %w", err)
+	}
+	if len(members) == 0 {
+		return 0, nil
+	}
+
+	client := c.clients.ForPath(members[0].ObsidianPath)
+
+	// 1. Skip pages that have not changed since every member was synced.
+	if !force {
+		meta, err := client.GetPage(ctx, rule.Page)
+		if err != nil {
+			return 0, err
+		}
+		stale := false
+		for _, m := range members {
+			s, err := c.db.GetState(m.ObsidianPath)
+			if err != nil || s == nil || meta.LastEditedTime.After(s.NotionMtime) {
+				stale = true
+				break
+			}
+		}
+		if !stale {
+			return 0, nil
+		}
+	}
+
+	// 2. Fetch the page and split it at the recorded headings.
+	page, err := client.FetchPage(ctx, rule.Page)
+	if err != nil {
+		return 0, err
+	}
+	headings := make([]string, len(members))
+	for i, m := range members {
+		headings[i] = m.Heading
+	}
+	sections, missing := splitComposedBlocks(page.Children, headings)
+	for _, i := range missing {
+		fmt.Fprintf(os.Stderr, "  Warning: section %q for %s not found in composed page; leaving the note unchanged\n", headings[i], members[i].ObsidianPath)
+	}
+
+	// 3. Write each member whose section changed, keeping its frontmatter.
+	skip := make(map[int]bool, len(missing))
+	for _, i := range missing {
+		skip[i] = true
+	}
+	for i, m := range members {
+		if keep[m.ObsidianPath] {
+			skip[i] = true
+		}
+	}
+
+	var written int
+	now := time.Now()
+	for i, m := range members {
+		if skip[i] {
+			continue
+		}
+
+		rt := transformer.NewReverse(c.linkRegistry, buildTransformerConfig(c.cfg, m.ObsidianPath))
+		body, err := rt.Transform(sections[i])
+		if err != nil {
+			return written, fmt.Errorf("transform %s: %w", m.ObsidianPath, err)
+		}
+
+		fullPath := filepath.Join(c.cfg.Vault, m.ObsidianPath)
+		existing, err := os.ReadFile(fullPath)
+		if err != nil && !os.IsNotExist(err) {
+			return written, fmt.Errorf("read %s: %w", m.ObsidianPath, err)
+		}
+		content := append(noteFrontmatter(existing), body...)
+
+		if !bytes.Equal(content, existing) {
+			if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+				return written, fmt.Errorf("create directory: %w", err)
+			}
+			if err := os.WriteFile(fullPath, content, 0644); err != nil {
+				return written, fmt.Errorf("write %s: %w", m.ObsidianPath, err)
+			}
+			written++
+		}
+
+		hashes, _ := noteHasher(c.cfg).HashFileDetailed(fullPath)
+		var mtime time.Time
+		if info, err := os.Stat(fullPath); err == nil {
+			mtime = info.ModTime()
+		}
+		if err := c.db.SetState(&state.SyncState{
+			ObsidianPath:    m.ObsidianPath,
+			NotionPageID:    rule.Page,
+			ObsidianMtime:   mtime,
+			NotionMtime:     now,
+			ContentHash:     hashes.ContentHash,
+			FrontmatterHash: hashes.FrontmatterHash,
+			LastSync:        now,
+			SyncDirection:   "pull",
+			Status:          "synced",
+		}); err != nil {
+			return written, fmt.Errorf("update state for %s: %w", m.ObsidianPath, err)
+		}
+	}
+
+	return written, nil
+}
+
+// memberPath returns the path whose integration updates a composed page:
+// the first member, or the rule pattern when there are no members.
+func memberPath(rule *config.Composition, notes []*compositeNote, previous []state.CompositeMember) string {
+	if len(notes) > 0 {
+		return notes[0].path
+	}
+	if len(previous) > 0 {
+		return previous[0].ObsidianPath
+	}
+	return rule.Path
+}
+
+// compositeTitle returns the section title for a note: its frontmatter
+// title, or the filename without extension.
+func compositeTitle(path string, fm map[string]any) string {
+	if title, ok := fm["title"].(string); ok && title != "" {
+		return title
+	}
+	return strings.TrimSuffix(filepath.Base(path), ".md")
+}
+
+// compositeDate returns the date a note is ordered by: the "date"
+// frontmatter field, or a YYYY-MM-DD filename prefix. It returns the zero
+// time for undated notes.
+func compositeDate(path string, fm map[string]any) time.Time {
+	switch v := fm["date"].(type) {
+	case time.Time:
+		return v
+	case string:
+		if len(v) >= 10 {
+			if t, err := time.Parse("2006-01-02", v[:10]); err == nil {
+				return t
+			}
+		}
+	}
+
+	if prefix := datePrefixPattern.FindString(filepath.Base(path)); prefix != "" {
+		if t, err := time.Parse("2006-01-02", prefix); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// compositeHeading builds the Heading 1 text for a section, prefixing the
+// date unless the title already contains it.
+func compositeHeading(title string, date time.Time) string {
+	if date.IsZero() {
+		return title
+	}
+	day := date.Format("2006-01-02")
+	if strings.Contains(title, day) {
+		return title
+	}
+	return day + " " + title
+}
+
+// sortCompositeNotes orders member notes by date (dated notes first) or by
+// path, depending on the rule's order.
+func sortCompositeNotes(notes []*compositeNote, order string) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		a, b := notes[i], notes[j]
+		if order != "name" && !a.date.Equal(b.date) {
+			if a.date.IsZero() || b.date.IsZero() {
+				return b.date.IsZero()
+			}
+			return a.date.Before(b.date)
+		}
+		return a.path < b.path
+	})
+}
+
+// compositeHeadingBlock creates the Heading 1 block that starts a section.
+func compositeHeadingBlock(text string) notionapi.Block {
+	return &notionapi.Heading1Block{
+		BasicBlock: notionapi.BasicBlock{
+			Object: notionapi.ObjectTypeBlock,
+			Type:   notionapi.BlockTypeHeading1,
+		},
+		Heading1: notionapi.Heading{
+			RichText: []notionapi.RichText{{
+				Type:      notionapi.ObjectTypeText,
+				Text:      &notionapi.Text{Content: text},
+				PlainText: text,
+			}},
+		},
+	}
+}
+
+// splitComposedBlocks splits a composed page's blocks into one slice per
+// expected heading. Headings are matched in order, so a note's own Heading 1
+// with unrelated text stays inside its section. Blocks before the first
+// section are kept with it. It also returns the indexes of headings that
+// were not found.
+func splitComposedBlocks(blocks []notionapi.Block, headings []string) ([][]notionapi.Block, []int) {
+	sections := make([][]notionapi.Block, len(headings))
+	found := make([]bool, len(headings))
+
+	current, next := 0, 0
+	var leading []notionapi.Block
+	started := false
+	for _, block := range blocks {
+		if text, ok := heading1Text(block); ok {
+			if k := matchHeading(headings, next, text); k >= 0 {
+				current, next = k, k+1
+				found[k] = true
+				started = true
+				continue
+			}
+		}
+		if !started {
+			leading = append(leading, block)
+			continue
+		}
+		sections[current] = append(sections[current], block)
+	}
+
+	var missing []int
+	first := -1
+	for i, ok := range found {
+		if !ok {
+			missing = append(missing, i)
+		} else if first < 0 {
+			first = i
+		}
+	}
+	if first >= 0 && len(leading) > 0 {
+		sections[first] = append(leading, sections[first]...)
+	}
+
+	return sections, missing
+}
+
+// matchHeading returns the index of the first heading at or after from
+// that equals text, or -1.
+func matchHeading(headings []string, from int, text string) int {
+	text = strings.TrimSpace(text)
+	for k := from; k < len(headings); k++ {
+		if headings[k] == text {
+			return k
+		}
+	}
+	return -1
+}
+
+// heading1Text returns the plain text of a Heading 1 block.
+func heading1Text(block notionapi.Block) (string, bool) {
+	h, ok := block.(*notionapi.Heading1Block)
+	if !ok {
+		return "", false
+	}
+	var b strings.Builder
+	for _, rt := range h.Heading1.RichText {
+		if rt.Text != nil {
+			b.WriteString(rt.Text.Content)
+		} else {
+			b.WriteString(rt.PlainText)
+		}
+	}
+	return b.String(), true
+}
+
+// noteFrontmatter returns the raw frontmatter block of a note, including
+// the blank line after it, or nil if the note has none.
+func noteFrontmatter(content []byte) []byte {
+	if !bytes.HasPrefix(content, []byte("---\n")) {
+		return nil
+	}
+	end := bytes.Index(content[4:], []byte("\n---\n"))
+	if end < 0 {
+		return nil
+	}
+	n := 4 + end + 5
+	for n < len(content) && content[n] == '\n' {
+		n++
+	}
+	fm := append([]byte(nil), content[:n]...)
+	if !bytes.HasSuffix(fm, []byte("\n\n")) {
+		fm = append(fm, '\n')
+	}
+	return fm
+}
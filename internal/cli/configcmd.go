@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+)
+
+var configListAll bool
+
+// configCmd represents the config command.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show and edit the configuration",
+	Long: `Show and edit the configuration file without editing YAML by hand.
+
+Keys are dot paths into the file, such as sync.root, transform.users.Ada,
+or mappings.0.database. Values are written as YAML: true, 3, a string, or
+a list such as "[a, b]".`,
+}
+
+// configGetCmd prints one setting.
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a setting",
+	Long: `Print the value of a setting, or its default if the config file
+does not set it. ${ENV_VAR} references are printed as written.
+
+Examples:
+  obsidian-notion config get sync.root
+  obsidian-notion config get transform.callouts`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigGet,
+}
+
+// configSetCmd changes one setting.
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting",
+	Long: `Change a setting in the config file, keeping its comments.
+
+The changed configuration is validated before it is written, and the
+previous file is kept next to it with a .bak suffix.
+
+Examples:
+  obsidian-notion config set sync.root Areas/Public
+  obsidian-notion config set transform.users.Ada 9b2f0c1e-...
+  obsidian-notion config set sync.conflict_strategy ask`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSet,
+}
+
+// configListCmd prints every setting.
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List settings",
+	Long: `List the settings in the config file, one "key = value" per line.
+Tokens, passwords, and webhook and proxy URLs are masked unless they are
+${ENV_VAR} references.
+
+Examples:
+  obsidian-notion config list         # Settings in the file
+  obsidian-notion config list --all   # Including defaults`,
+	Args: cobra.NoArgs,
+	RunE: runConfigList,
+}
+
+func init() {
+	configListCmd.Flags().BoolVar(&configListAll, "all", false, "include settings left at their defaults")
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configListCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	path, err := config.Find(cfgFile)
+	if err != nil {
+		return err
+	}
+	value, err := config.Get(path, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	path, err := config.Find(cfgFile)
+	if err != nil {
+		return err
+	}
+	if err := config.Set(path, args[0], args[1]); err != nil {
+		return err
+	}
+	fmt.Printf("Set %s in %s (previous file: %s.bak)\n", args[0], path, path)
+	return nil
+}
+
+func runConfigList(cmd *cobra.Command, args []string) error {
+	path, err := config.Find(cfgFile)
+	if err != nil {
+		return err
+	}
+	settings, err := config.List(path, configListAll)
+	if err != nil {
+		return err
+	}
+	printSettings(os.Stdout, settings)
+	return nil
+}
+
+// secretKeys match the settings config list masks.
+var secretKeys = []string{
+	"notion.token",
+	"notion.credentials.*",
+	"watch.api.token",
+	"notify.slack.webhook_url",
+	"notify.smtp.password",
+	"http.proxy",
+	"publish.webhook",
+}
+
+// printSettings writes settings as "key = value" lines, masking secrets.
+func printSettings(w io.Writer, settings []config.Setting) {
+	for _, s := range settings {
+		value := s.Value
+		if isSecretKey(s.Key) && value != "" && !strings.HasPrefix(value, "${") {
+			value = "********"
+		}
+		fmt.Fprintf(w, "%s = %s\n", s.Key, value)
+	}
+}
+
+// isSecretKey reports whether a setting holds a secret.
+func isSecretKey(key string) bool {
+	for _, pattern := range secretKeys {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/diff"
 	"github.com/adamancini/obsidian-notion-sync/internal/notion"
 	"github.com/adamancini/obsidian-notion-sync/internal/parser"
 	"github.com/adamancini/obsidian-notion-sync/internal/state"
@@ -20,6 +21,9 @@ import (
 var (
 	resolveKeep   string
 	conflictsJson bool
+	diffColor     string
+	diffContext   int
+	diffWords     bool
 )
 
 // conflictsCmd represents the conflicts command.
@@ -29,13 +33,16 @@ var conflictsCmd = &cobra.Command{
 	Long: `List all current sync conflicts and optionally resolve them.
 
 A conflict occurs when both the local Obsidian file and the remote
-Notion page have been modified since the last sync.
+Notion page have been modified since the last sync. The remote version is
+saved next to the note as <note>.md.remote, so it can be compared and
+merged in Obsidian; resolving the conflict removes it.
 
 Examples:
   obsidian-notion conflicts                              # List all conflicts
   obsidian-notion conflicts resolve path/to/note.md --keep local
   obsidian-notion conflicts resolve path/to/note.md --keep remote
-  obsidian-notion conflicts resolve path/to/note.md --keep both`,
+  obsidian-notion conflicts resolve path/to/note.md --keep both
+  obsidian-notion conflicts diff path/to/note.md         # Show what differs`,
 	RunE: runConflicts,
 }
 
@@ -49,16 +56,48 @@ Options for --keep:
   local   - Keep the Obsidian version, overwrite Notion
   remote  - Keep the Notion version, overwrite Obsidian
   both    - Keep both versions (create .conflict file)`,
-	Args: cobra.ExactArgs(1),
-	RunE: runResolve,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConflictPaths,
+	RunE:              runResolve,
+}
+
+// diffCmd represents the diff subcommand.
+var diffCmd = &cobra.Command{
+	Use:   "diff <path>",
+	Short: "Show how a note differs from its Notion page",
+	Long: `Fetch the Notion page for a note, convert it to markdown, and print a
+unified diff from the local file to the remote version.
+
+Lines starting with - are only in the local file; lines starting with +
+are only in Notion. Nothing is modified, locally or in Notion.
+
+With --word-diff, a line changed on one side is shown once, starting with
+~, with the words only in the local file marked [-like this-] and the
+words only in Notion marked {+like this+} (in red and green when
+colorized), so a small wording change in a long paragraph stands out.
+
+Options for --color:
+  auto    - Colorize when writing to a terminal (default)
+  always  - Always colorize
+  never   - Never colorize`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConflictPaths,
+	RunE:              runConflictsDiff,
 }
 
 func init() {
+	diffCmd.Flags().StringVar(&diffColor, "color", "auto", "colorize the diff (auto|always|never)")
+	diffCmd.Flags().IntVarP(&diffContext, "context", "U", 3, "number of context lines")
+	diffCmd.Flags().BoolVar(&diffWords, "word-diff", false, "mark the words that changed within lines")
+	_ = diffCmd.RegisterFlagCompletionFunc("color", cobra.FixedCompletions([]string{"auto", "always", "never"}, cobra.ShellCompDirectiveNoFileComp))
+
 	resolveCmd.Flags().StringVar(&resolveKeep, "keep", "", "which version to keep (local|remote|both)")
 	_ = resolveCmd.MarkFlagRequired("keep")
+	_ = resolveCmd.RegisterFlagCompletionFunc("keep", cobra.FixedCompletions([]string{"local", "remote", "both"}, cobra.ShellCompDirectiveNoFileComp))
 
 	conflictsCmd.Flags().BoolVar(&conflictsJson, "json", false, "output in JSON format")
 	conflictsCmd.AddCommand(resolveCmd)
+	conflictsCmd.AddCommand(diffCmd)
 }
 
 func runConflicts(cmd *cobra.Command, args []string) error {
@@ -103,6 +142,9 @@ func runConflicts(cmd *cobra.Command, args []string) error {
 		} else {
 			fmt.Printf("    Last sync:       %s\n", c.LastSync.Format(time.RFC3339))
 		}
+		if _, err := os.Stat(filepath.Join(cfg.Vault, remoteSidecarPath(c.ObsidianPath))); err == nil {
+			fmt.Printf("    Remote copy:     %s\n", remoteSidecarPath(c.ObsidianPath))
+		}
 		fmt.Println()
 	}
 
@@ -110,6 +152,8 @@ func runConflicts(cmd *cobra.Command, args []string) error {
 	fmt.Println("  obsidian-notion conflicts resolve <path> --keep local   # Keep Obsidian version")
 	fmt.Println("  obsidian-notion conflicts resolve <path> --keep remote  # Keep Notion version")
 	fmt.Println("  obsidian-notion conflicts resolve <path> --keep both    # Keep both (creates .conflict file)")
+	fmt.Println("\nTo see what differs first:")
+	fmt.Println("  obsidian-notion conflicts diff <path>")
 
 	return nil
 }
@@ -119,6 +163,9 @@ func runResolve(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if err := refuseMirror(cfg, "conflicts resolve"); err != nil {
+		return err
+	}
 	path := args[0]
 
 	switch resolveKeep {
@@ -151,17 +198,30 @@ func runResolve(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("file is not in conflict state: %s (status: %s)", path, syncState.Status)
 	}
 
-	// Initialize Notion client.
-	client := notion.New(cfg.Notion.Token,
-		notion.WithRateLimit(cfg.RateLimit.RequestsPerSecond),
-		notion.WithBatchSize(cfg.RateLimit.BatchSize),
-	)
+	// Initialize Notion client for the integration bound to this path.
+	clients := newNotionClients(cfg)
+	client := clients.ForPath(path)
 
 	tracker := state.NewConflictTracker(db)
 	linkRegistry := state.NewLinkRegistry(db)
 
 	var newHash string
 
+	// Composed notes share their page with other members, so they are
+	// resolved by rebuilding the page or splitting out just this note.
+	if rule := cfg.GetComposition(path); rule != nil {
+		newHash, err = resolveComposed(ctx, cfg, db, clients, linkRegistry, rule, path)
+		if err != nil {
+			return fmt.Errorf("resolve composed note: %w", err)
+		}
+		fmt.Printf("Resolved conflict for %s: kept %s version\n", path, resolveKeep)
+		if err := tracker.ResolveConflict(path, resolveKeep, newHash); err != nil {
+			return err
+		}
+		removeRemoteSidecar(cfg, path)
+		return nil
+	}
+
 	switch resolveKeep {
 	case "local":
 		// Push local version to Notion.
@@ -195,10 +255,174 @@ func runResolve(cmd *cobra.Command, args []string) error {
 	if err := tracker.ResolveConflict(path, resolveKeep, newHash); err != nil {
 		return fmt.Errorf("mark resolved: %w", err)
 	}
+	removeRemoteSidecar(cfg, path)
+
+	return nil
+}
+
+func runConflictsDiff(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+	path := args[0]
+
+	var color bool
+	switch diffColor {
+	case "auto":
+		color = isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	case "always":
+		color = true
+	case "never":
+	default:
+		return fmt.Errorf("invalid --color value: %s (must be auto, always, or never)", diffColor)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// Open state database.
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	syncState, err := db.GetState(path)
+	if err != nil {
+		return fmt.Errorf("get state: %w", err)
+	}
+	if syncState == nil || syncState.NotionPageID == "" {
+		return fmt.Errorf("no Notion page for path: %s", path)
+	}
+	if cfg.GetComposition(path) != nil {
+		return fmt.Errorf("diff is not supported for composed notes: %s", path)
+	}
 
+	local, err := os.ReadFile(filepath.Join(cfg.Vault, path))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	// Fetch and convert the remote version.
+	client := newNotionClients(cfg).ForPath(path)
+	notionPage, err := fetchNotePage(ctx, client, db, path, syncState.NotionPageID)
+	if err != nil {
+		return fmt.Errorf("fetch page: %w", err)
+	}
+	rt := transformer.NewReverse(state.NewLinkRegistry(db), buildTransformerConfig(cfg, path))
+	remote, err := rt.NotionToMarkdown(notionPage)
+	if err != nil {
+		return fmt.Errorf("transform to markdown: %w", err)
+	}
+
+	var out string
+	if diffWords {
+		out = diff.UnifiedWords("local/"+path, "notion/"+path, string(local), string(remote), diffContext, wordMarks(color))
+	} else {
+		out = diff.Unified("local/"+path, "notion/"+path, string(local), string(remote), diffContext)
+	}
+	if out == "" {
+		fmt.Printf("No differences: %s\n", path)
+		return nil
+	}
+	if color {
+		out = colorizeDiff(out)
+	}
+	fmt.Print(out)
 	return nil
 }
 
+// wordMarks returns the marks of a word diff: colors, or brackets.
+func wordMarks(color bool) diff.Marks {
+	if !color {
+		return diff.PlainMarks
+	}
+	return diff.Marks{DeleteStart: ansiRed, DeleteEnd: ansiReset, InsertStart: ansiGreen, InsertEnd: ansiReset}
+}
+
+// ANSI escape sequences used to colorize diffs.
+const (
+	ansiReset = "\033[0m"
+	ansiBold  = "\033[1m"
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+	ansiCyan  = "\033[36m"
+)
+
+// colorizeDiff adds terminal colors to a unified diff.
+func colorizeDiff(unified string) string {
+	lines := strings.SplitAfter(unified, "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		var color string
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			color = ansiBold
+		case strings.HasPrefix(line, "@@"):
+			color = ansiCyan
+		case strings.HasPrefix(line, "-"):
+			color = ansiRed
+		case strings.HasPrefix(line, "+"):
+			color = ansiGreen
+		}
+		if color == "" {
+			b.WriteString(line)
+			continue
+		}
+		b.WriteString(color)
+		b.WriteString(strings.TrimSuffix(line, "\n"))
+		b.WriteString(ansiReset)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// isTerminal reports whether f is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// resolveComposed resolves a conflict on a note covered by a composition
+// rule. Keeping both versions is not supported, since the remote version is
+// a section of a shared page rather than a page of its own.
+func resolveComposed(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory, linkRegistry *state.LinkRegistry, rule *config.Composition, path string) (string, error) {
+	scanner := newScanner(cfg)
+	comp := newComposer(cfg, db, clients, linkRegistry, newAttachmentUploader(cfg, db, clients, scanner), scanner)
+
+	switch resolveKeep {
+	case "local":
+		if _, err := comp.push(ctx, rule); err != nil {
+			return "", err
+		}
+	case "remote":
+		members, err := db.GetCompositeMembers(rule.Page)
+		if err != nil {
+			return "", fmt.Errorf("get members: %w", err)
+		}
+		keep := make(map[string]bool, len(members))
+		for _, m := range members {
+			keep[m.ObsidianPath] = m.ObsidianPath != path
+		}
+		if _, err := comp.pull(ctx, rule, true, keep); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("--keep %s is not supported for composed notes (use local or remote)", resolveKeep)
+	}
+
+	hashes, err := noteHasher(cfg).HashFileDetailed(filepath.Join(cfg.Vault, path))
+	if err != nil {
+		return "", fmt.Errorf("hash file: %w", err)
+	}
+	return hashes.FullHash, nil
+}
+
 // resolveKeepLocal pushes the local version to Notion.
 func resolveKeepLocal(ctx context.Context, cfg *config.Config, db *state.DB, client *notion.Client, linkRegistry *state.LinkRegistry, path string, syncState *state.SyncState) (string, error) {
 	// Read local file.
@@ -226,9 +450,12 @@ func resolveKeepLocal(ctx context.Context, cfg *config.Config, db *state.DB, cli
 	if err := client.UpdatePage(ctx, syncState.NotionPageID, notionPage); err != nil {
 		return "", fmt.Errorf("update page: %w", err)
 	}
+	if err := recordPushedPage(db, path, notionPage); err != nil {
+		return "", fmt.Errorf("record page state: %w", err)
+	}
 
 	// Compute new hash.
-	hashes, err := state.HashFileDetailed(fullPath)
+	hashes, err := noteHasher(cfg).HashFileDetailed(fullPath)
 	if err != nil {
 		return "", fmt.Errorf("hash file: %w", err)
 	}
@@ -239,13 +466,16 @@ func resolveKeepLocal(ctx context.Context, cfg *config.Config, db *state.DB, cli
 // resolveKeepRemote pulls the remote version from Notion.
 func resolveKeepRemote(ctx context.Context, cfg *config.Config, db *state.DB, client *notion.Client, linkRegistry *state.LinkRegistry, path string, syncState *state.SyncState) (string, error) {
 	// Fetch page from Notion.
-	notionPage, err := client.FetchPage(ctx, syncState.NotionPageID)
+	notionPage, err := fetchNotePage(ctx, client, db, path, syncState.NotionPageID)
 	if err != nil {
 		return "", fmt.Errorf("fetch page: %w", err)
 	}
 
 	// Transform to markdown.
 	rt := transformer.NewReverse(linkRegistry, buildTransformerConfig(cfg, path))
+	rt.SetAttachmentSaver(newAttachmentDownloader(ctx, cfg, newScanner(cfg), path))
+	rt.SetBookmarkPreviewer(newBookmarkPreviewer(ctx, cfg, path))
+	rt.SetUserNamer(newUserNamer(ctx, client))
 
 	markdown, err := rt.NotionToMarkdown(notionPage)
 	if err != nil {
@@ -259,7 +489,7 @@ func resolveKeepRemote(ctx context.Context, cfg *config.Config, db *state.DB, cl
 	}
 
 	// Compute new hash.
-	hashes, err := state.HashFileDetailed(fullPath)
+	hashes, err := noteHasher(cfg).HashFileDetailed(fullPath)
 	if err != nil {
 		return "", fmt.Errorf("hash file: %w", err)
 	}
@@ -267,10 +497,65 @@ func resolveKeepRemote(ctx context.Context, cfg *config.Config, db *state.DB, cl
 	return hashes.FullHash, nil
 }
 
+// remoteSidecarSuffix is appended to a note's path to name the file that
+// holds the remote version of a conflicted note.
+const remoteSidecarSuffix = ".remote"
+
+// remoteSidecarPath returns the vault-relative path of a note's remote
+// version sidecar.
+func remoteSidecarPath(path string) string {
+	return path + remoteSidecarSuffix
+}
+
+// writeRemoteSidecar saves the Notion version of a conflicted note next to
+// it, so the two versions can be diffed and merged inside the vault.
+// Composed notes share a page with other notes and get no sidecar.
+func writeRemoteSidecar(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory, linkRegistry *state.LinkRegistry, path string) error {
+	if cfg.GetComposition(path) != nil {
+		return nil
+	}
+	syncState, err := db.GetState(path)
+	if err != nil {
+		return fmt.Errorf("get state: %w", err)
+	}
+	if syncState == nil || syncState.NotionPageID == "" {
+		return nil
+	}
+
+	notionPage, err := fetchNotePage(ctx, clients.ForPath(path), db, path, syncState.NotionPageID)
+	if err != nil {
+		return fmt.Errorf("fetch page: %w", err)
+	}
+	rt := transformer.NewReverse(linkRegistry, buildTransformerConfig(cfg, path))
+	markdown, err := rt.NotionToMarkdown(notionPage)
+	if err != nil {
+		return fmt.Errorf("transform to markdown: %w", err)
+	}
+	return saveRemoteSidecar(cfg, path, markdown)
+}
+
+// saveRemoteSidecar writes the markdown of a conflicted note's page, already
+// fetched, to its sidecar.
+func saveRemoteSidecar(cfg *config.Config, path string, markdown []byte) error {
+	if err := os.WriteFile(filepath.Join(cfg.Vault, remoteSidecarPath(path)), markdown, 0644); err != nil {
+		return fmt.Errorf("write sidecar: %w", err)
+	}
+	return nil
+}
+
+// removeRemoteSidecar deletes a note's remote version sidecar once its
+// conflict is resolved. Failures are reported as warnings.
+func removeRemoteSidecar(cfg *config.Config, path string) {
+	err := os.Remove(filepath.Join(cfg.Vault, remoteSidecarPath(path)))
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to remove %s: %v\n", remoteSidecarPath(path), err)
+	}
+}
+
 // resolveKeepBoth keeps local version and saves remote to a .conflict file.
 func resolveKeepBoth(ctx context.Context, cfg *config.Config, db *state.DB, client *notion.Client, linkRegistry *state.LinkRegistry, path string, syncState *state.SyncState) (string, error) {
 	// Fetch page from Notion.
-	notionPage, err := client.FetchPage(ctx, syncState.NotionPageID)
+	notionPage, err := fetchNotePage(ctx, client, db, path, syncState.NotionPageID)
 	if err != nil {
 		return "", fmt.Errorf("fetch page: %w", err)
 	}
@@ -301,7 +586,7 @@ func resolveKeepBoth(ctx context.Context, cfg *config.Config, db *state.DB, clie
 
 	// Keep local version, compute its hash.
 	fullPath := filepath.Join(cfg.Vault, path)
-	hashes, err := state.HashFileDetailed(fullPath)
+	hashes, err := noteHasher(cfg).HashFileDetailed(fullPath)
 	if err != nil {
 		return "", fmt.Errorf("hash file: %w", err)
 	}
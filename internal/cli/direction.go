@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+)
+
+// blockedChange is a change left out of a run because the sync.directions
+// policy for its path forbids that direction.
+type blockedChange struct {
+	path   string
+	policy string // The direction the path is limited to.
+}
+
+// errPullOnly is returned when a change to a pull-only path would be pushed.
+var errPullOnly = errors.New("blocked by sync.directions: path is pull-only")
+
+// refuseMirror returns an error if the vault is a mirror, for commands
+// that change the vault from Notion or trash and restore pages.
+func refuseMirror(cfg *config.Config, command string) error {
+	if cfg.Mirror() {
+		return fmt.Errorf("%s is disabled in mirror mode: the vault is never changed from Notion, and pages are never trashed or restored", command)
+	}
+	return nil
+}
+
+// pushAllowed reports whether a path may be pushed to Notion.
+func pushAllowed(cfg *config.Config, path string) bool {
+	return cfg.DirectionForPath(path) != config.DirectionPull
+}
+
+// pullAllowed reports whether a path may be written from Notion.
+func pullAllowed(cfg *config.Config, path string) bool {
+	return cfg.DirectionForPath(path) != config.DirectionPush
+}
+
+// blockPushFiles removes files that may not be pushed. A rename is blocked
+// when either its old or new path is pull-only.
+func blockPushFiles(cfg *config.Config, files []pushFile) (kept []pushFile, blocked []blockedChange) {
+	for _, f := range files {
+		if !pushAllowed(cfg, f.path) || (f.oldPath != "" && !pushAllowed(cfg, f.oldPath)) {
+			blocked = append(blocked, blockedChange{path: f.path, policy: config.DirectionPull})
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept, blocked
+}
+
+// blockPullPages removes pages that may not be written to the vault.
+func blockPullPages(cfg *config.Config, pages []pullPage) (kept []pullPage, blocked []blockedChange) {
+	for _, p := range pages {
+		if !pullAllowed(cfg, p.localPath) {
+			blocked = append(blocked, blockedChange{path: p.localPath, policy: config.DirectionPush})
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept, blocked
+}
+
+// blockChanges removes sync changes in a direction their path forbids.
+func blockChanges(cfg *config.Config, changes []state.Change, direction state.Direction) (kept []state.Change, blocked []blockedChange) {
+	for _, c := range changes {
+		allowed, policy := pushAllowed(cfg, c.Path), config.DirectionPull
+		if direction == state.DirectionPull {
+			allowed, policy = pullAllowed(cfg, c.Path), config.DirectionPush
+		}
+		if !allowed {
+			blocked = append(blocked, blockedChange{path: c.Path, policy: policy})
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept, blocked
+}
+
+// printBlocked lists the changes a run refused because of sync.directions.
+func printBlocked(w io.Writer, blocked []blockedChange) {
+	if len(blocked) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "  Blocked: %d (sync.directions)\n", len(blocked))
+	for _, b := range blocked {
+		fmt.Fprintf(w, "    %s (%s-only)\n", b.path, b.policy)
+	}
+}
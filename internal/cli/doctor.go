@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+)
+
+// doctorCmd represents the doctor command.
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the Notion API setup",
+	Long: `Check that each configured integration token works with the pinned
+Notion API version, and report which endpoints and block types it supports.
+Each token is also checked against the databases it is configured for, so
+a database that is not shared with its integration shows up before a sync
+fails on it.
+
+Requests are pinned to one Notion-Version (notion.api_version, or the
+version this build targets). Block types the version does not support are
+pushed as placeholder callouts instead of failing the sync. Without the
+file uploads endpoint, local attachments become placeholders too.
+
+Example output:
+  Notion API version: 2022-06-28
+
+  Integration (default):
+    workspace      Acme
+    users          ok
+    search         ok
+    file_uploads   ok
+    Unsupported block types: none
+    Databases:
+      0123abcd-...  ok (Notes)`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	clients := newNotionClients(cfg)
+	failed := 0
+	for i, name := range credentialNames(cfg) {
+		client := clients.ForToken(cfg.TokenForCredential(name))
+		if i == 0 {
+			fmt.Printf("Notion API version: %s\n", client.APIVersion())
+		}
+		label := name
+		if label == "" {
+			label = "default"
+		}
+		fmt.Printf("\nIntegration (%s):\n", label)
+
+		caps, err := client.Probe(ctx)
+		if err != nil {
+			fmt.Printf("  Error: %v\n", err)
+			failed++
+			continue
+		}
+		if workspace, err := client.Workspace(ctx); err == nil && workspace != "" {
+			fmt.Printf("  %-14s %s\n", "workspace", workspace)
+		}
+		printCapabilities(os.Stdout, caps)
+		if !checkDatabases(ctx, os.Stdout, client, credentialDatabases(cfg, name)) {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d integration(s) failed the check", failed)
+	}
+	return nil
+}
+
+// credentialNames returns the default credential ("") followed by the
+// named credentials, sorted.
+func credentialNames(cfg *config.Config) []string {
+	names := []string{""}
+	named := make([]string, 0, len(cfg.Notion.Credentials))
+	for name := range cfg.Notion.Credentials {
+		named = append(named, name)
+	}
+	sort.Strings(named)
+	return append(names, named...)
+}
+
+// credentialDatabases returns the databases a credential is configured
+// for: the default database for the default credential, and the
+// databases of the mappings naming it.
+func credentialDatabases(cfg *config.Config, name string) []string {
+	var ids []string
+	if name == "" && cfg.Notion.DefaultDatabase != "" {
+		ids = append(ids, cfg.Notion.DefaultDatabase)
+	}
+	for _, m := range cfg.Mappings {
+		if m.Credential == name && m.Database != "" && !slices.Contains(ids, m.Database) {
+			ids = append(ids, m.Database)
+		}
+	}
+	return ids
+}
+
+// checkDatabases reports whether an integration can read each of its
+// databases, with what to do about those it cannot. It reports whether
+// all of them could be read.
+func checkDatabases(ctx context.Context, out io.Writer, client *notion.Client, ids []string) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	fmt.Fprintln(out, "  Databases:")
+	ok := true
+	for _, id := range ids {
+		db, err := client.GetDatabase(ctx, id)
+		if err != nil {
+			fmt.Fprintf(out, "    %s  %v\n", id, err)
+			if hint := notionHint(err); hint != "" {
+				fmt.Fprintf(out, "      %s\n", hint)
+			}
+			ok = false
+			continue
+		}
+		fmt.Fprintf(out, "    %s  ok (%s)\n", id, extractDatabaseTitle(db))
+	}
+	return ok
+}
+
+// printCapabilities writes the endpoints and unsupported block types of an
+// integration.
+func printCapabilities(out io.Writer, caps *notion.Capabilities) {
+	endpoints := make([]string, 0, len(caps.Endpoints))
+	for name := range caps.Endpoints {
+		endpoints = append(endpoints, name)
+	}
+	sort.Strings(endpoints)
+	for _, name := range endpoints {
+		status := "ok"
+		if !caps.Endpoints[name] {
+			status = "unavailable"
+		}
+		fmt.Fprintf(out, "  %-14s %s\n", name, status)
+	}
+
+	unsupported := "none"
+	if len(caps.UnsupportedBlocks) > 0 {
+		types := make([]string, len(caps.UnsupportedBlocks))
+		for i, blockType := range caps.UnsupportedBlocks {
+			types[i] = string(blockType)
+		}
+		unsupported = strings.Join(types, ", ") + " (pushed as placeholders)"
+	}
+	fmt.Fprintf(out, "  Unsupported block types: %s\n", unsupported)
+}
+
+// checkCapabilities probes the default integration when a long-running
+// command starts, warning about what the API version cannot do. A failed
+// probe is only a warning; the sync reports its own errors.
+func checkCapabilities(ctx context.Context, out io.Writer, client *notion.Client) {
+	caps, err := client.Probe(ctx)
+	if err != nil {
+		fmt.Fprintf(out, "  Warning: Notion API check failed: %v\n", err)
+		return
+	}
+	if !caps.Endpoints["file_uploads"] {
+		fmt.Fprintf(out, "  Warning: Notion API version %s has no file uploads; attachments will be pushed as placeholders\n", caps.APIVersion)
+	}
+	if len(caps.UnsupportedBlocks) > 0 {
+		fmt.Fprintf(out, "  Warning: %d block type(s) are not supported by Notion API version %s and will be pushed as placeholders\n",
+			len(caps.UnsupportedBlocks), caps.APIVersion)
+	}
+}
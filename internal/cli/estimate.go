@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+// errOverBudget marks a change left out because the request budget
+// (push --max-requests) could not cover it.
+var errOverBudget = errors.New("over request budget")
+
+// pushEstimate is the predicted API traffic of pushing notes.
+type pushEstimate struct {
+	Notes    int
+	Requests int
+	Blocks   int
+	Uploads  int
+}
+
+// add adds another estimate to e.
+func (e *pushEstimate) add(other pushEstimate) {
+	e.Notes += other.Notes
+	e.Requests += other.Requests
+	e.Blocks += other.Blocks
+	e.Uploads += other.Uploads
+}
+
+// estimateFile predicts the API traffic of pushing one change. Notes are
+// read and converted offline; nothing is uploaded or sent.
+func (pc *pushContext) estimateFile(f pushFile) (pushEstimate, error) {
+	switch f.changeType {
+	case state.ChangeDeleted:
+		if !trashesPage(pc.cfg, f.state) {
+			return pushEstimate{Notes: 1}, nil
+		}
+		return pushEstimate{Notes: 1, Requests: 1}, nil
+	case state.ChangeRenamed:
+		return pushEstimate{Notes: 1, Requests: 1}, nil
+	}
+	if pc.streams(f.path) {
+		return pc.estimateStreamed(f)
+	}
+
+	content, err := os.ReadFile(filepath.Join(pc.cfg.Vault, f.path))
+	if err != nil {
+		return pushEstimate{}, fmt.Errorf("read file: %w", err)
+	}
+	note, err := pc.parser.Parse(f.path, content)
+	if err != nil {
+		return pushEstimate{}, fmt.Errorf("parse markdown: %w", err)
+	}
+	page, err := transformer.New(pc.linkRegistry, buildTransformerConfig(pc.cfg, f.path)).Transform(note)
+	if err != nil {
+		return pushEstimate{}, fmt.Errorf("transform to Notion: %w", err)
+	}
+
+	uploads := pc.attachments.pendingUploads(f.path, note)
+	targets := 0
+	if names := noteTargets(note.Frontmatter); len(names) > 1 {
+		targets = len(names) - 1 // One create or update per other target.
+	}
+	isNew := f.state == nil || f.state.NotionPageID == ""
+	blocks := notion.CountBlocks(page.Children)
+	for _, section := range page.Sections {
+		blocks += notion.CountBlocks(section.Children)
+	}
+	return pushEstimate{
+		Notes:    1,
+		Requests: pc.clients.ForPath(f.path).PageRequests(page, isNew) + uploads*notion.UploadRequests + targets,
+		Blocks:   blocks,
+		Uploads:  uploads,
+	}, nil
+}
+
+// reserve sets aside the estimated requests of a change from the request
+// budget before it is pushed. Without a budget it does nothing. A change
+// the budget cannot cover returns errOverBudget and is left for the next
+// push.
+func (pc *pushContext) reserve(f pushFile) (release func(), err error) {
+	if pc.budget == nil {
+		return func() {}, nil
+	}
+	est, err := pc.estimateFile(f)
+	if err != nil {
+		return nil, err
+	}
+	release, ok := pc.budget.Reserve(est.Requests)
+	if !ok {
+		return nil, errOverBudget
+	}
+	return release, nil
+}
+
+// printEstimate writes a push estimate, with the time it takes at the
+// configured rate limit and, if budget is set, whether it fits.
+func printEstimate(out io.Writer, est pushEstimate, requestsPerSecond float64, budget int, composed int) {
+	fmt.Fprintf(out, "Estimate for %d change(s):\n", est.Notes)
+	fmt.Fprintf(out, "  %-14s %d\n", "API requests:", est.Requests)
+	fmt.Fprintf(out, "  %-14s %d\n", "Blocks:", est.Blocks)
+	fmt.Fprintf(out, "  %-14s %d\n", "Uploads:", est.Uploads)
+	if requestsPerSecond > 0 {
+		d := time.Duration(float64(est.Requests) / requestsPerSecond * float64(time.Second))
+		fmt.Fprintf(out, "  %-14s ~%s at %g requests/s\n", "Time:", d.Round(time.Second), requestsPerSecond)
+	}
+	if composed > 0 {
+		fmt.Fprintf(out, "  (%d composed note(s) not included)\n", composed)
+	}
+	if budget > 0 {
+		if est.Requests > budget {
+			fmt.Fprintf(out, "  Exceeds --max-requests %d; the push will stop early and resume on the next run\n", budget)
+		} else {
+			fmt.Fprintf(out, "  Fits within --max-requests %d\n", budget)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/forPelevin/gomoji"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+)
+
+// caseInsensitiveFS reports whether the vault's filesystem treats names
+// differing only in case as the same file. The default filesystems of
+// macOS and Windows do.
+var caseInsensitiveFS = runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+
+// sanitizeFilename converts a title to a valid filename, handling emoji
+// and Unicode normalization according to sync.filenames.
+func sanitizeFilename(title string, opts config.FilenamesConfig) string {
+	result := title
+	switch opts.Emoji {
+	case "strip":
+		result = gomoji.RemoveEmojis(result)
+	case "transliterate":
+		result = gomoji.ReplaceEmojisWithFunc(result, func(em gomoji.Emoji) string {
+			return " " + em.Slug + " "
+		})
+	}
+
+	// Replace invalid characters, and drop control characters.
+	invalid := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
+	for _, char := range invalid {
+		result = strings.ReplaceAll(result, char, "-")
+	}
+	result = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, result)
+	if opts.Emoji == "strip" || opts.Emoji == "transliterate" {
+		result = strings.Join(strings.Fields(result), " ")
+	}
+
+	switch opts.Unicode {
+	case "", "nfc":
+		result = norm.NFC.String(result)
+	case "nfd":
+		result = norm.NFD.String(result)
+	}
+
+	// Trim spaces and dots from ends.
+	result = strings.TrimSpace(result)
+	result = strings.Trim(result, ".")
+	if result == "" {
+		result = "Untitled"
+	}
+	return result
+}
+
+// noteNames hands out note paths for new pages, so that no two pages and
+// no page and existing file share a name.
+type noteNames struct {
+	vault string
+	taken map[string]bool
+	dirs  map[string]bool
+}
+
+// newNoteNames returns a namer for new notes in the vault.
+func newNoteNames(vault string) *noteNames {
+	return &noteNames{vault: vault, taken: make(map[string]bool), dirs: make(map[string]bool)}
+}
+
+// claim returns path, or path with a number added before the extension
+// if a file of that name exists or was already claimed. Names are
+// compared by their NFC form, and without case on case-insensitive
+// filesystems.
+func (n *noteNames) claim(path string) string {
+	n.scan(filepath.Dir(path))
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	candidate := path
+	for i := 1; n.taken[nameKey(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s %d%s", base, i, ext)
+	}
+	n.taken[nameKey(candidate)] = true
+	return candidate
+}
+
+// scan records the files of a vault directory as taken, once.
+func (n *noteNames) scan(dir string) {
+	if n.dirs[dir] {
+		return
+	}
+	n.dirs[dir] = true
+	entries, err := os.ReadDir(filepath.Join(n.vault, dir))
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		n.taken[nameKey(filepath.Join(dir, entry.Name()))] = true
+	}
+}
+
+// nameKey returns the form of a path under which the filesystem finds it.
+func nameKey(path string) string {
+	key := norm.NFC.String(filepath.Clean(path))
+	if caseInsensitiveFS {
+		key = strings.ToLower(key)
+	}
+	return key
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+)
+
+var linksGraphFormat string
+
+// linksGraphCmd represents the links graph subcommand.
+var linksGraphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Export the wiki-link graph",
+	Long: `Write the vault's wiki-link graph to stdout, for Graphviz or other tools.
+
+Each note is a node, with its Notion page ID and sync status; each
+wiki-link is an edge. Links to targets no note matches point to
+unresolved nodes, and links resolved to pages with no note in the vault
+to nodes of those pages, both drawn dashed in DOT.
+
+Formats:
+  dot    Graphviz DOT (default)
+  json   {"nodes": [...], "edges": [...]}
+
+Examples:
+  obsidian-notion links graph | dot -Tsvg > links.svg
+  obsidian-notion links graph --format json > links.json`,
+	Args: cobra.NoArgs,
+	RunE: runLinksGraph,
+}
+
+func init() {
+	linksGraphCmd.Flags().StringVar(&linksGraphFormat, "format", "dot", "output format: dot or json")
+	_ = linksGraphCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"dot", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	linksCmd.AddCommand(linksGraphCmd)
+}
+
+// Statuses of graph nodes that are not synced notes.
+const (
+	graphUntracked  = "untracked"  // A note with links but no sync state
+	graphUnresolved = "unresolved" // A link target no note matches
+	graphRemote     = "remote"     // A page with no note in the vault
+)
+
+// linkGraph is the wiki-link graph of the vault.
+type linkGraph struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+// graphNode is a note, a page, or an unresolved link target.
+type graphNode struct {
+	ID           string `json:"id"` // The note's path, the page's ID, or "unresolved:" and the target
+	Label        string `json:"label"`
+	Path         string `json:"path,omitempty"`
+	NotionPageID string `json:"notion_page_id,omitempty"`
+	NotionURL    string `json:"notion_url,omitempty"`
+	Status       string `json:"status"` // The sync state's status, or untracked, unresolved, or remote
+}
+
+// graphEdge is a wiki-link.
+type graphEdge struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Link     string `json:"link"` // The [[target]] text
+	Resolved bool   `json:"resolved"`
+}
+
+func runLinksGraph(cmd *cobra.Command, args []string) error {
+	if linksGraphFormat != "dot" && linksGraphFormat != "json" {
+		return fmt.Errorf("invalid format: %s (must be dot or json)", linksGraphFormat)
+	}
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	graph, err := buildLinkGraph(db)
+	if err != nil {
+		return err
+	}
+	if linksGraphFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(graph)
+	}
+	writeDOT(os.Stdout, graph)
+	return nil
+}
+
+// buildLinkGraph returns the graph of the synced notes and the links
+// registered from notes.
+func buildLinkGraph(db *state.DB) (*linkGraph, error) {
+	states, err := db.ListStates("")
+	if err != nil {
+		return nil, fmt.Errorf("list states: %w", err)
+	}
+	registry := state.NewLinkRegistry(db)
+	links, err := registry.AllLinks()
+	if err != nil {
+		return nil, fmt.Errorf("get links: %w", err)
+	}
+
+	graph := &linkGraph{Nodes: []graphNode{}, Edges: []graphEdge{}}
+	seen := make(map[string]bool)
+	add := func(node graphNode) {
+		if !seen[node.ID] {
+			seen[node.ID] = true
+			graph.Nodes = append(graph.Nodes, node)
+		}
+	}
+	noteNode := func(path string) graphNode {
+		return graphNode{ID: path, Label: strings.TrimSuffix(filepath.Base(path), ".md"), Path: path, Status: graphUntracked}
+	}
+
+	for _, s := range states {
+		node := noteNode(s.ObsidianPath)
+		node.Status = s.Status
+		if s.NotionPageID != "" {
+			node.NotionPageID = s.NotionPageID
+			node.NotionURL = notionPageURL(s.NotionPageID)
+		}
+		add(node)
+	}
+
+	for _, l := range links {
+		add(noteNode(l.SourcePath))
+		edge := graphEdge{Source: l.SourcePath, Link: l.TargetName, Resolved: l.Resolved}
+		target := l.TargetPath
+		if target == "" && l.NotionPageID != "" {
+			target, _ = registry.LookupPath(l.NotionPageID)
+		}
+		switch {
+		case target != "":
+			add(noteNode(target))
+			edge.Target = target
+		case l.NotionPageID != "":
+			add(graphNode{ID: l.NotionPageID, Label: l.TargetName, NotionPageID: l.NotionPageID, NotionURL: notionPageURL(l.NotionPageID), Status: graphRemote})
+			edge.Target = l.NotionPageID
+		default:
+			edge.Target = graphUnresolved + ":" + l.TargetName
+			add(graphNode{ID: edge.Target, Label: l.TargetName, Status: graphUnresolved})
+		}
+		graph.Edges = append(graph.Edges, edge)
+	}
+	return graph, nil
+}
+
+// writeDOT writes a link graph in Graphviz DOT. Nodes carry their page ID
+// and status as attributes, and link to their pages.
+func writeDOT(w io.Writer, graph *linkGraph) {
+	fmt.Fprintln(w, "digraph links {")
+	fmt.Fprintln(w, "  node [shape=box];")
+	for _, n := range graph.Nodes {
+		attrs := []string{"label=" + dotQuote(n.Label), "status=" + dotQuote(n.Status)}
+		if n.NotionPageID != "" {
+			attrs = append(attrs, "notion_page_id="+dotQuote(n.NotionPageID), "URL="+dotQuote(n.NotionURL))
+		}
+		if n.Status == graphUnresolved || n.Status == graphRemote {
+			attrs = append(attrs, "style=dashed")
+		}
+		fmt.Fprintf(w, "  %s [%s];\n", dotQuote(n.ID), strings.Join(attrs, ", "))
+	}
+	for _, e := range graph.Edges {
+		fmt.Fprintf(w, "  %s -> %s", dotQuote(e.Source), dotQuote(e.Target))
+		if !e.Resolved {
+			fmt.Fprint(w, " [style=dashed]")
+		}
+		fmt.Fprintln(w, ";")
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// dotQuote quotes a DOT identifier.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+)
+
+// notionHint returns what to do about a request Notion refused, or "" for
+// errors outside the notion package's catalog.
+func notionHint(err error) string {
+	switch {
+	case errors.Is(err, notion.ErrLocked):
+		return "The page is locked in Notion: unlock it from the page's ••• menu, then push again. push --force-unlock-warning marks notes with locked pages as blocked."
+	case errors.Is(err, notion.ErrNotFound):
+		return "The page or database is missing or not shared with the integration: open it in Notion and add the integration under Connections."
+	case errors.Is(err, notion.ErrUnauthorized):
+		return "The integration token was refused or lacks access: check notion.token and notion.credentials, and the integration's capabilities at https://www.notion.so/my-integrations. 'obsidian-notion doctor' checks each token."
+	case errors.Is(err, notion.ErrRateLimited):
+		return "Notion is limiting requests: lower rate_limit.requests_per_second or rate_limit.workers, and try again later."
+	case errors.Is(err, notion.ErrValidation):
+		var e *notion.Error
+		if errors.As(err, &e) && e.BlockPath != "" {
+			return fmt.Sprintf("Notion rejected the content at %s: edit that block of the note, or see 'obsidian-notion doctor' for block types the API version does not support.", e.BlockPath)
+		}
+		return "Notion rejected the content of the request: check the note's frontmatter against the database's properties."
+	}
+	return ""
+}
+
+// printError reports a path that failed, followed by what to do about it
+// when Notion refused a request.
+func printError(w io.Writer, prefix, path string, err error) {
+	fmt.Fprintf(w, "  %s %s: %v\n", prefix, path, err)
+	if hint := notionHint(err); hint != "" {
+		fmt.Fprintf(w, "    %s\n", hint)
+	}
+}
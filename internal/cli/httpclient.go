@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+)
+
+// newHTTPClient returns the HTTP client for Notion requests and attachment
+// transfers: through http.proxy, or the proxy of the environment, trusting
+// http.ca_cert besides the system's certificate authorities. With
+// insecure, certificates are not verified at all, for debugging a proxy.
+func newHTTPClient(h config.HTTPConfig, insecure bool) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if h.Proxy != "" {
+		proxy, err := url.Parse(h.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid http.proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
+	if h.CACert != "" {
+		pem, err := os.ReadFile(h.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("read http.ca_cert: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("http.ca_cert %s holds no PEM certificates", h.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport, Timeout: h.RequestTimeout()}, nil
+}
@@ -0,0 +1,482 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notionexport"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+var (
+	importExportFolder    string
+	importExportSeedState bool
+	importExportDryRun    bool
+	importExportForce     bool
+)
+
+// importExportCmd represents the import-export command.
+var importExportCmd = &cobra.Command{
+	Use:   "import-export <zip>",
+	Short: "Import a Notion workspace export into the vault",
+	Long: `Import the ZIP archive of a Notion export, as "Markdown & CSV" or as HTML,
+into the vault, without the API. This is the fastest way to bring a large
+workspace over.
+
+Every exported page becomes a note, named after the page without the IDs
+Notion adds to exported names, in the folders of the export. Database rows
+get their properties as frontmatter, typed by transform.property_mappings
+or, for unmapped properties, by their values. Callouts become Obsidian
+callouts, links between exported pages become wiki-links, and images and
+other files are copied next to the notes that embed them.
+
+With --seed-state, the imported notes are tracked as synced with the pages
+they were exported from, so the next pull or push only carries what
+changed since the export. Pages already tracked are left as they are.
+
+Existing notes are not overwritten unless --force is given.
+
+Pages of an HTML export are converted as a pull converts pages, so their
+notes keep text colors, mentions, toggles, and the like. Pages of a
+"Markdown & CSV" export are Notion's exported markdown with its links,
+embeds, and callouts rewritten, and lack what that export leaves out. With
+--seed-state, 'obsidian-notion pull --all' afterwards rewrites them as a
+pull would. Export with subpages included either way.
+
+Examples:
+  obsidian-notion import-export Export-1a2b3c.zip
+  obsidian-notion import-export Export-1a2b3c.zip --folder Notion
+  obsidian-notion import-export Export-1a2b3c.zip --seed-state
+  obsidian-notion import-export Export-1a2b3c.zip --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImportExport,
+}
+
+func init() {
+	rootCmd.AddCommand(importExportCmd)
+	importExportCmd.Flags().StringVar(&importExportFolder, "folder", "", "vault folder to import into (default: the top of the vault)")
+	importExportCmd.Flags().BoolVar(&importExportSeedState, "seed-state", false, "track imported notes as synced with their pages")
+	importExportCmd.Flags().BoolVar(&importExportDryRun, "dry-run", false, "show what would be imported without making changes")
+	importExportCmd.Flags().BoolVar(&importExportForce, "force", false, "overwrite existing notes and files")
+}
+
+// importOptions configure an import of a Notion export.
+type importOptions struct {
+	folder    string
+	seedState bool
+	dryRun    bool
+	force     bool
+}
+
+// importResult counts what an import did.
+type importResult struct {
+	notes, files, skipped, tracked int
+}
+
+func runImportExport(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+	if err := refuseMirror(cfg, "import-export"); err != nil {
+		return err
+	}
+
+	folder := filepath.ToSlash(filepath.Clean(importExportFolder))
+	if folder == "." {
+		folder = ""
+	}
+	if filepath.IsAbs(importExportFolder) || strings.HasPrefix(folder, "../") || folder == ".." {
+		return fmt.Errorf("--folder must be a folder inside the vault: %s", importExportFolder)
+	}
+
+	archive, err := notionexport.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("read export: %w", err)
+	}
+	defer archive.Close()
+
+	var db *state.DB
+	if importExportSeedState && !importExportDryRun {
+		db, err = state.Open(filepath.Join(cfg.Vault, ".obsidian-notion.db"))
+		if err != nil {
+			return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+		}
+		defer db.Close()
+	}
+
+	fmt.Printf("Importing %d page(s) and %d file(s) from %s...\n", len(archive.Pages), len(archive.Files), filepath.Base(args[0]))
+	if importExportDryRun {
+		fmt.Println("(dry-run mode - no changes will be made)")
+	}
+
+	result, err := importArchive(cfg, db, archive, importOptions{
+		folder:    folder,
+		seedState: importExportSeedState,
+		dryRun:    importExportDryRun,
+		force:     importExportForce,
+	})
+	if err != nil {
+		return err
+	}
+	if importExportDryRun {
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Printf("Import complete:\n")
+	fmt.Printf("  Notes:   %d\n", result.notes)
+	fmt.Printf("  Files:   %d\n", result.files)
+	if result.skipped > 0 {
+		fmt.Printf("  Skipped: %d (already exist; use --force to overwrite)\n", result.skipped)
+	}
+	if importExportSeedState {
+		fmt.Printf("  Tracked: %d\n", result.tracked)
+	}
+	return nil
+}
+
+// importArchive writes the pages and files of a Notion export to the vault
+// and, with opts.seedState, tracks the notes in db as synced with their
+// pages.
+func importArchive(cfg *config.Config, db *state.DB, archive *notionexport.Archive, opts importOptions) (importResult, error) {
+	var result importResult
+	lookup := exportLookup(archive, opts.folder)
+
+	for _, page := range archive.Pages {
+		notePath := path.Join(opts.folder, page.Path)
+		fullPath, err := importTarget(cfg, opts.folder, notePath)
+		if err != nil {
+			return result, err
+		}
+		if _, err := os.Stat(fullPath); err == nil && !opts.force {
+			result.skipped++
+			if verbose || opts.dryRun {
+				fmt.Printf("  - exists, skipped: %s\n", notePath)
+			}
+			continue
+		}
+		if opts.dryRun {
+			fmt.Printf("  + would import: %s\n", notePath)
+			continue
+		}
+
+		content, err := exportNote(cfg, archive, page, notePath, lookup, opts)
+		if err != nil {
+			return result, err
+		}
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return result, fmt.Errorf("create directory: %w", err)
+		}
+		if err := os.WriteFile(fullPath, content, 0644); err != nil {
+			return result, fmt.Errorf("write %s: %w", notePath, err)
+		}
+		result.notes++
+		if verbose {
+			fmt.Printf("  + %s\n", notePath)
+		}
+
+		if opts.seedState && page.ID != "" {
+			tracked, err := seedImportState(db, noteHasher(cfg), page, notePath, fullPath, archive.ExportedAt)
+			if err != nil {
+				return result, err
+			}
+			if tracked {
+				result.tracked++
+			}
+		}
+	}
+
+	for _, file := range archive.Files {
+		filePath := path.Join(opts.folder, file.Path)
+		fullPath, err := importTarget(cfg, opts.folder, filePath)
+		if err != nil {
+			return result, err
+		}
+		if _, err := os.Stat(fullPath); err == nil && !opts.force {
+			result.skipped++
+			if verbose || opts.dryRun {
+				fmt.Printf("  - exists, skipped: %s\n", filePath)
+			}
+			continue
+		}
+		if opts.dryRun {
+			fmt.Printf("  + would copy: %s\n", filePath)
+			continue
+		}
+		if err := copyExportFile(file, fullPath); err != nil {
+			return result, fmt.Errorf("copy %s: %w", filePath, err)
+		}
+		result.files++
+		if verbose {
+			fmt.Printf("  + %s\n", filePath)
+		}
+	}
+	return result, nil
+}
+
+// exportNote returns the note of an exported page. Pages of an HTML export
+// are converted from their blocks, as pulled pages are; those of a markdown
+// export keep Notion's markdown, with links and callouts rewritten.
+func exportNote(cfg *config.Config, archive *notionexport.Archive, page *notionexport.Page, notePath string, lookup transformer.PathLookup, opts importOptions) ([]byte, error) {
+	tc := buildTransformerConfig(cfg, notePath)
+	rt := transformer.NewReverse(lookup, tc)
+	rt.SetAttachmentSaver(exportAttachments{})
+	exportOpts := notionexport.Options{Folder: opts.folder, CalloutType: rt.CalloutType}
+	notionPage := &transformer.NotionPage{Properties: exportProperties(tc, page)}
+	if page.HTML {
+		notionPage.Children = archive.Blocks(page, exportOpts)
+	}
+	content, err := rt.NotionToMarkdown(notionPage)
+	if err != nil {
+		return nil, fmt.Errorf("convert %s: %w", page.ExportPath, err)
+	}
+	if !page.HTML {
+		content = append(content, archive.Markdown(page, exportOpts)...)
+	}
+	return content, nil
+}
+
+// exportPaths finds the notes of exported pages by their Notion IDs, for
+// the mentions of an HTML export.
+type exportPaths map[string]string
+
+// exportLookup returns the notes an import writes, by page ID.
+func exportLookup(archive *notionexport.Archive, folder string) exportPaths {
+	paths := make(exportPaths, len(archive.Pages))
+	for _, page := range archive.Pages {
+		if page.ID != "" {
+			paths[page.ID] = strings.TrimSuffix(path.Join(folder, page.Path), ".md")
+		}
+	}
+	return paths
+}
+
+// LookupPath implements transformer.PathLookup.
+func (p exportPaths) LookupPath(notionPageID string) (string, bool) {
+	notePath, ok := p[notionPageID]
+	return notePath, ok
+}
+
+// exportAttachments embeds the images and files of an HTML export where
+// the import copies them: Archive.Blocks gives their paths in the vault in
+// place of Notion's URLs.
+type exportAttachments struct{}
+
+// SaveAttachment implements transformer.AttachmentSaver.
+func (exportAttachments) SaveAttachment(url, name string) (string, bool) {
+	return url, true
+}
+
+// importTarget returns the full path of a note or file of an import,
+// refusing paths that lead out of the folder imported into.
+func importTarget(cfg *config.Config, folder, relPath string) (string, error) {
+	root := filepath.Join(cfg.Vault, filepath.FromSlash(folder))
+	fullPath := filepath.Join(cfg.Vault, filepath.FromSlash(relPath))
+	rel, err := filepath.Rel(root, fullPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to import %s: outside %s", relPath, root)
+	}
+	return fullPath, nil
+}
+
+// seedImportState tracks an imported note as synced with the page it was
+// exported from, as a pull would. Pages and notes tracked already are
+// left as they are, with a warning.
+func seedImportState(db *state.DB, h state.Hasher, page *notionexport.Page, notePath, fullPath string, exportedAt time.Time) (bool, error) {
+	existing, err := db.GetStateByNotionID(page.ID)
+	if err != nil {
+		return false, fmt.Errorf("get state: %w", err)
+	}
+	if existing != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: page of %s is already synced with %s; not tracking the import\n", notePath, existing.ObsidianPath)
+		return false, nil
+	}
+	existing, err = db.GetState(notePath)
+	if err != nil {
+		return false, fmt.Errorf("get state: %w", err)
+	}
+	if existing != nil && existing.NotionPageID != "" {
+		fmt.Fprintf(os.Stderr, "  Warning: %s is already synced with another page; not tracking the import\n", notePath)
+		return false, nil
+	}
+
+	hashes, err := h.HashFileDetailed(fullPath)
+	if err != nil {
+		return false, fmt.Errorf("hash %s: %w", notePath, err)
+	}
+	var mtime time.Time
+	if info, err := os.Stat(fullPath); err == nil {
+		mtime = info.ModTime()
+	}
+	syncState := &state.SyncState{
+		ObsidianPath:    notePath,
+		NotionPageID:    page.ID,
+		ContentHash:     hashes.ContentHash,
+		FrontmatterHash: hashes.FrontmatterHash,
+		ObsidianMtime:   mtime,
+		NotionMtime:     exportedAt,
+		LastSync:        time.Now(),
+		Status:          "synced",
+		SyncDirection:   "pull",
+	}
+	if page.Database != nil {
+		syncState.NotionParentID = page.Database.ID
+	}
+	if err := db.SetState(syncState); err != nil {
+		return false, fmt.Errorf("update state: %w", err)
+	}
+	return true, nil
+}
+
+// copyExportFile copies a file of an export to the vault.
+func copyExportFile(file *notionexport.File, fullPath string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	dst, err := os.Create(fullPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// exportProperties returns the properties of an exported page as Notion
+// properties, for the frontmatter of its note. Properties are typed by
+// their mapping, if they have one, or else by their values: Notion
+// exports every property as text.
+func exportProperties(tc *transformer.Config, page *notionexport.Page) notionapi.Properties {
+	titleName := "title"
+	if page.Database != nil && len(page.Database.Columns) > 0 {
+		titleName = page.Database.Columns[0]
+	}
+	props := notionapi.Properties{
+		titleName: &notionapi.TitleProperty{Type: notionapi.PropertyTypeTitle, Title: exportRichText(page.Title)},
+	}
+
+	mappings := tc.PropertyMappings
+	if len(mappings) == 0 {
+		mappings = transformer.DefaultMappings
+	}
+	types := make(map[string]transformer.PropertyType)
+	for _, m := range append(tc.PropertyRenames, mappings...) {
+		if _, ok := types[m.NotionName]; !ok {
+			types[m.NotionName] = m.NotionType
+		}
+	}
+
+	for _, p := range page.Properties {
+		if p.Name == titleName || strings.TrimSpace(p.Value) == "" {
+			continue
+		}
+		propType, ok := types[p.Name]
+		if !ok {
+			propType = inferExportType(p.Value)
+		}
+		props[p.Name] = exportProperty(propType, p.Value)
+	}
+	return props
+}
+
+// exportDateLayouts are the layouts of exported dates, without and with a
+// time.
+var exportDateLayouts = []string{"January 2, 2006", "January 2, 2006 3:04 PM"}
+
+// parseExportDate parses an exported date. Of a range, such as
+// "May 1, 2024 → May 3, 2024", the start is parsed.
+func parseExportDate(value string) (time.Time, bool) {
+	start, _, _ := strings.Cut(value, " → ")
+	for _, layout := range exportDateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(start)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// inferExportType guesses the type of an unmapped exported property from
+// its value.
+func inferExportType(value string) transformer.PropertyType {
+	switch {
+	case value == "Yes" || value == "No":
+		return transformer.PropertyTypeCheckbox
+	case strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://"):
+		return transformer.PropertyTypeURL
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return transformer.PropertyTypeNumber
+	}
+	if _, ok := parseExportDate(value); ok {
+		return transformer.PropertyTypeDate
+	}
+	return transformer.PropertyTypeRichText
+}
+
+// exportProperty converts an exported property value to a property of the
+// given type. Values that do not parse as the type are kept as text.
+func exportProperty(propType transformer.PropertyType, value string) notionapi.Property {
+	switch propType {
+	case transformer.PropertyTypeTitle:
+		return &notionapi.TitleProperty{Type: notionapi.PropertyTypeTitle, Title: exportRichText(value)}
+	case transformer.PropertyTypeNumber:
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return &notionapi.NumberProperty{Type: notionapi.PropertyTypeNumber, Number: n}
+		}
+	case transformer.PropertyTypeSelect:
+		return &notionapi.SelectProperty{Type: notionapi.PropertyTypeSelect, Select: notionapi.Option{Name: value}}
+	case transformer.PropertyTypeStatus:
+		return &notionapi.StatusProperty{Type: notionapi.PropertyTypeStatus, Status: notionapi.Option{Name: value}}
+	case transformer.PropertyTypeMultiSelect:
+		var options []notionapi.Option
+		for _, name := range strings.Split(value, ", ") {
+			options = append(options, notionapi.Option{Name: strings.TrimSpace(name)})
+		}
+		return &notionapi.MultiSelectProperty{Type: notionapi.PropertyTypeMultiSelect, MultiSelect: options}
+	case transformer.PropertyTypeDate:
+		if t, ok := parseExportDate(value); ok {
+			start := notionapi.Date(t)
+			return &notionapi.DateProperty{Type: notionapi.PropertyTypeDate, Date: &notionapi.DateObject{Start: &start}}
+		}
+	case transformer.PropertyTypeCheckbox:
+		if value == "Yes" || value == "No" {
+			return &notionapi.CheckboxProperty{Type: notionapi.PropertyTypeCheckbox, Checkbox: value == "Yes"}
+		}
+	case transformer.PropertyTypeURL:
+		return &notionapi.URLProperty{Type: notionapi.PropertyTypeURL, URL: value}
+	case transformer.PropertyTypeEmail:
+		return &notionapi.EmailProperty{Type: notionapi.PropertyTypeEmail, Email: value}
+	case transformer.PropertyTypePhone:
+		return &notionapi.PhoneNumberProperty{Type: notionapi.PropertyTypePhoneNumber, PhoneNumber: value}
+	}
+	return &notionapi.RichTextProperty{Type: notionapi.PropertyTypeRichText, RichText: exportRichText(value)}
+}
+
+// exportRichText returns text as plain rich text.
+func exportRichText(text string) []notionapi.RichText {
+	return []notionapi.RichText{{
+		Type:      notionapi.ObjectTypeText,
+		Text:      &notionapi.Text{Content: text},
+		PlainText: text,
+	}}
+}
@@ -52,6 +52,7 @@ func init() {
 
 	_ = initCmd.MarkFlagRequired("vault")
 	_ = initCmd.MarkFlagRequired("notion-token")
+	_ = initCmd.RegisterFlagCompletionFunc("database", completeDatabases)
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
@@ -212,7 +213,7 @@ func runInit(cmd *cobra.Command, args []string) error {
 		}
 
 		// Create initial state entry (pending sync).
-		contentHashStr, err := state.HashFile(file.AbsPath)
+		contentHashStr, err := noteHasher(newCfg).HashFile(file.AbsPath)
 		if err != nil {
 			if verbose {
 				fmt.Fprintf(os.Stderr, "  Warning: could not hash %s: %v\n", file.Path, err)
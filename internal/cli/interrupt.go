@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// runContext returns the context a push, pull, or sync runs in. It ends
+// after timeout, or at the first Ctrl+C or SIGTERM: work in progress stops
+// at its next API call, and the command reports what it finished. A second
+// Ctrl+C quits at once. The returned function releases the signals.
+func runContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		defer signal.Stop(sigCh)
+		select {
+		case <-sigCh:
+			fmt.Fprintln(os.Stderr, "\nInterrupted: stopping after the requests in progress (Ctrl+C again to quit now)")
+			cancel()
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() { close(done) })
+		cancel()
+	}
+}
+
+// stopped reports whether err is a run's context ending, by interrupt or
+// timeout, rather than the operation failing. Operations stopped this way
+// are left for the next run.
+func stopped(ctx context.Context, err error) bool {
+	return ctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
+}
+
+// summaryTitle returns the heading of a command's summary, which says
+// when the run did not finish.
+func summaryTitle(ctx context.Context, command string) string {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return command + " timed out:"
+	case ctx.Err() != nil:
+		return command + " interrupted:"
+	}
+	return command + " complete:"
+}
+
+// printNotReached reports the changes a stopped run left for the next.
+func printNotReached(n int, command string) {
+	if n > 0 {
+		fmt.Printf("  Not reached: %d (run 'obsidian-notion %s' again to continue)\n", n, command)
+	}
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+)
+
+// parentLayout lays out pulled pages by their Notion hierarchy, each page
+// in a folder named after its parent item.
+const parentLayout = "{{parent}}/{{title}}.md"
+
+// parentItemProperty is the relation Notion's sub-items link a database
+// page to its parent with.
+const parentItemProperty = "Parent item"
+
+// layoutPlaceholder matches a {{name}} placeholder of a layout.
+var layoutPlaceholder = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// pullLayout returns the layout of the notes of new database pages: flat
+// with --flatten, by parent item with --preserve-structure, or else
+// sync.pull_layout.
+func pullLayout(cfg *config.Config) string {
+	switch {
+	case pullFlatten:
+		return ""
+	case pullPreserveStructure:
+		return parentLayout
+	}
+	return cfg.Sync.PullLayout
+}
+
+// layoutPath returns the note path of a database page by a layout such as
+// "{{Area}}/{{title}}.md", or the page's title at the top if the layout is
+// empty. The layout's placeholders are:
+//
+//	{{title}}    the page's title
+//	{{year}}     the year the page was created
+//	{{month}}    the month the page was created, as 01 to 12
+//	{{parent}}   the folders of the page's parent items, outermost first
+//	{{Name}}     the page's Name property: a select, status, text, or the
+//	             first of a multi-select
+//
+// A folder whose placeholders are all empty is left out, so pages without
+// an Area are pulled to the top. pages are the database's pages by
+// normalized ID, for following parent items.
+func layoutPath(layout string, page *notionapi.Page, pages map[string]*notionapi.Page, names config.FilenamesConfig) string {
+	title := extractTitle(page.Properties)
+	if layout == "" {
+		return sanitizeFilename(title, names) + ".md"
+	}
+
+	segments := strings.Split(strings.TrimSuffix(filepath.ToSlash(layout), ".md"), "/")
+	var folders []string
+	for i, segment := range segments {
+		if i < len(segments)-1 && strings.TrimSpace(segment) == "{{parent}}" {
+			for _, parent := range parentItems(page, pages) {
+				folders = append(folders, sanitizeFilename(extractTitle(parent.Properties), names))
+			}
+			continue
+		}
+		value := layoutPlaceholder.ReplaceAllStringFunc(segment, func(placeholder string) string {
+			return layoutValue(layoutPlaceholder.FindStringSubmatch(placeholder)[1], page, title)
+		})
+		if i == len(segments)-1 {
+			folders = append(folders, sanitizeFilename(value, names)+".md")
+		} else if strings.TrimSpace(value) != "" {
+			folders = append(folders, sanitizeFilename(value, names))
+		}
+	}
+	return filepath.Join(folders...)
+}
+
+// layoutValue returns the value of a layout placeholder for a page.
+func layoutValue(name string, page *notionapi.Page, title string) string {
+	switch name {
+	case "title":
+		return title
+	case "year":
+		return fmt.Sprintf("%04d", page.CreatedTime.Year())
+	case "month":
+		return fmt.Sprintf("%02d", int(page.CreatedTime.Month()))
+	}
+	switch p := page.Properties[name].(type) {
+	case *notionapi.SelectProperty:
+		return p.Select.Name
+	case *notionapi.StatusProperty:
+		return p.Status.Name
+	case *notionapi.MultiSelectProperty:
+		if len(p.MultiSelect) > 0 {
+			return p.MultiSelect[0].Name
+		}
+	case *notionapi.RichTextProperty:
+		var text strings.Builder
+		for _, rt := range p.RichText {
+			text.WriteString(rt.PlainText)
+		}
+		return text.String()
+	}
+	return ""
+}
+
+// parentItems returns the chain of a page's parent items in its database,
+// outermost first.
+func parentItems(page *notionapi.Page, pages map[string]*notionapi.Page) []*notionapi.Page {
+	var chain []*notionapi.Page
+	seen := map[string]bool{normalizeNotionID(string(page.ID)): true}
+	for {
+		relation, ok := page.Properties[parentItemProperty].(*notionapi.RelationProperty)
+		if !ok || len(relation.Relation) == 0 {
+			break
+		}
+		id := normalizeNotionID(string(relation.Relation[0].ID))
+		parent := pages[id]
+		if parent == nil || seen[id] {
+			break
+		}
+		seen[id] = true
+		chain = append([]*notionapi.Page{parent}, chain...)
+		page = parent
+	}
+	return chain
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+// placeholderRecorder resolves wiki-links through another resolver and
+// remembers the targets that did not resolve, which the note's page shows
+// as placeholders.
+type placeholderRecorder struct {
+	transformer.LinkResolver
+	missed []string
+}
+
+// Resolve implements transformer.LinkResolver.
+func (r *placeholderRecorder) Resolve(target string) (string, bool) {
+	pageID, found := r.LinkResolver.Resolve(target)
+	if !found && !slices.Contains(r.missed, target) {
+		r.missed = append(r.missed, target)
+	}
+	return pageID, found
+}
+
+// hiddenTargets resolves wiki-links like another resolver, except for the
+// hidden targets, so a note can be transformed the way it was before they
+// resolved.
+type hiddenTargets struct {
+	transformer.LinkResolver
+	hidden []string
+}
+
+// Resolve implements transformer.LinkResolver.
+func (r *hiddenTargets) Resolve(target string) (string, bool) {
+	if slices.Contains(r.hidden, target) {
+		return "", false
+	}
+	return r.LinkResolver.Resolve(target)
+}
+
+// fixLinks updates the page of a note pushed this run whose placeholders,
+// the wiki-links that did not resolve when it was pushed, now resolve to
+// pages pushed after it. Only the top-level blocks holding those links are
+// updated; the page is re-pushed in full if the note is composed, split
+// into sections, has no recorded blocks, or changed in a way a block update
+// cannot patch. It reports whether the page was updated.
+func (pc *pushContext) fixLinks(ctx context.Context, path string, placeholders []string) (bool, error) {
+	var resolved []string
+	for _, target := range placeholders {
+		if _, found := pc.linkRegistry.Resolve(target); found {
+			resolved = append(resolved, target)
+		}
+	}
+	if len(resolved) == 0 {
+		return false, nil
+	}
+
+	repush := func() (bool, error) {
+		return repushLinkedPage(ctx, pc.cfg, pc.db, pc.clients, pc.linkRegistry, pc.attachments, path)
+	}
+	if pc.cfg.GetComposition(path) != nil {
+		return repush()
+	}
+
+	blockMap, err := pc.db.GetBlockMap(path)
+	if err != nil {
+		return false, fmt.Errorf("cannot get blocks of %s: %w", path, err)
+	}
+	if len(blockMap) == 0 {
+		return repush()
+	}
+
+	content, err := os.ReadFile(filepath.Join(pc.cfg.Vault, path))
+	if err != nil {
+		return false, fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	note, err := pc.parser.Parse(path, content)
+	if err != nil {
+		return false, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+
+	// Transform the note as it was pushed, with the newly resolved links as
+	// placeholders, and as it is now, to find the blocks that hold them.
+	attachments := pc.attachments.prepare(ctx, path, note)
+	transform := func(resolver transformer.LinkResolver) (*transformer.NotionPage, error) {
+		t := transformer.New(resolver, buildTransformerConfig(pc.cfg, path))
+		t.SetAttachmentResolver(attachments)
+		page, err := t.Transform(note)
+		if err != nil {
+			return nil, fmt.Errorf("cannot transform %s: %w", path, err)
+		}
+		return page, nil
+	}
+	before, err := transform(&hiddenTargets{LinkResolver: pc.linkRegistry, hidden: resolved})
+	if err != nil {
+		return false, err
+	}
+	after, err := transform(pc.linkRegistry)
+	if err != nil {
+		return false, err
+	}
+	if len(after.Sections) > 0 {
+		return repush()
+	}
+
+	// The recorded blocks must be the ones the note was pushed as; a note
+	// edited since is re-pushed.
+	rt := transformer.NewReverse(nil, nil)
+	ids := make([]string, len(before.Children))
+	for _, b := range blockMap {
+		if b.Index >= len(ids) || state.HashContentRaw([]byte(rt.BlockMarkdown(before.Children[b.Index]))) != b.Hash {
+			return repush()
+		}
+		ids[b.Index] = b.NotionBlockID
+	}
+
+	n, err := pc.clients.ForPath(path).PatchBlocks(ctx, ids, before.Children, after.Children)
+	if errors.Is(err, notion.ErrNotPatchable) {
+		return repush()
+	}
+	if err != nil {
+		return n > 0, fmt.Errorf("failed to update links in %s: %w", path, err)
+	}
+
+	after.BlockIDs = ids
+	if err := recordBlockMap(pc.db, path, after); err != nil {
+		return true, fmt.Errorf("failed to record page state for %s: %w", path, err)
+	}
+	return n > 0, nil
+}
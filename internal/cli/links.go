@@ -1,8 +1,15 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -10,9 +17,13 @@ import (
 )
 
 var (
-	linksRepair     bool
-	linksDryRun     bool
+	linksRepair      bool
+	linksDryRun      bool
 	linksSuggestions bool
+	linksMinScore    string
+	linksApply       bool
+	linksHeadings    bool
+	linksTo          string
 )
 
 // linksCmd represents the links command.
@@ -35,14 +46,74 @@ Examples:
   obsidian-notion links --repair --dry-run
 
   # Repair unresolved links using fuzzy matching
-  obsidian-notion links --repair`,
+  obsidian-notion links --repair
+
+  # Rewrite unresolved links in notes to their best prefix or better match
+  obsidian-notion links repair --apply-suggestions --min-score prefix
+
+  # Pull the pages pulled notes mention that are not in the vault
+  obsidian-notion links fetch-missing --to "inbox/"
+
+  # Draw the link graph with Graphviz
+  obsidian-notion links graph | dot -Tsvg > links.svg`,
 	RunE: runLinks,
 }
 
+// linksRepairCmd represents the links repair subcommand.
+var linksRepairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Repair unresolved wiki-links",
+	Long: `Resolve unresolved wiki-links to their best suggested target.
+
+By default only the link registry is updated, so the links resolve on the
+next push. With --apply-suggestions the links are also rewritten in the
+notes containing them, keeping any heading, block reference, or alias:
+
+  [[Meting Notes#Agenda|agenda]] -> [[Meeting Notes#Agenda|agenda]]
+
+--min-score sets the weakest match that is applied:
+  exact, case-insensitive, prefix, or fuzzy (default)
+
+With --headings, links to headings renamed since they were synced, such
+as a heading retitled in Notion and pulled, are rewritten across the
+synced notes to the new heading text instead:
+
+  [[Meeting Notes#Agenda]] -> [[Meeting Notes#Agenda for Monday]]`,
+	Args: cobra.NoArgs,
+	RunE: runLinksRepairCmd,
+}
+
+// linksFetchMissingCmd represents the links fetch-missing subcommand.
+var linksFetchMissingCmd = &cobra.Command{
+	Use:   "fetch-missing",
+	Short: "Pull pages mentioned in pulled notes that are not in the vault",
+	Long: `Pull the Notion pages that pulled notes mention but that are not synced
+to the vault, so the links the mentions were pulled as resolve.
+
+'obsidian-notion links' lists these pages with their Notion URLs and the
+notes mentioning them. Each is pulled into a new note named after the page,
+in --to if given or else at the top of the vault, and tracked from then on
+like any other note.`,
+	Args: cobra.NoArgs,
+	RunE: runLinksFetchMissingCmd,
+}
+
 func init() {
 	linksCmd.Flags().BoolVarP(&linksRepair, "repair", "r", false, "repair unresolved links using fuzzy matching")
 	linksCmd.Flags().BoolVarP(&linksDryRun, "dry-run", "n", false, "show what would be repaired without making changes")
 	linksCmd.Flags().BoolVarP(&linksSuggestions, "suggestions", "s", false, "show fuzzy match suggestions for unresolved links")
+	linksCmd.Flags().StringVar(&linksMinScore, "min-score", "fuzzy", "weakest match to suggest or repair: exact, case-insensitive, prefix, fuzzy")
+
+	linksRepairCmd.Flags().BoolVarP(&linksDryRun, "dry-run", "n", false, "show what would be repaired without making changes")
+	linksRepairCmd.Flags().BoolVar(&linksApply, "apply-suggestions", false, "rewrite the links in the source notes")
+	linksRepairCmd.Flags().StringVar(&linksMinScore, "min-score", "fuzzy", "weakest match to repair: exact, case-insensitive, prefix, fuzzy")
+	linksRepairCmd.Flags().BoolVar(&linksHeadings, "headings", false, "rewrite links to renamed headings")
+	_ = linksCmd.RegisterFlagCompletionFunc("min-score", completeMinScores)
+	_ = linksRepairCmd.RegisterFlagCompletionFunc("min-score", completeMinScores)
+	linksFetchMissingCmd.Flags().BoolVarP(&linksDryRun, "dry-run", "n", false, "show what would be pulled without making changes")
+	linksFetchMissingCmd.Flags().StringVar(&linksTo, "to", "", "folder to pull the pages to")
+	linksCmd.AddCommand(linksRepairCmd)
+	linksCmd.AddCommand(linksFetchMissingCmd)
 }
 
 func runLinks(cmd *cobra.Command, args []string) error {
@@ -61,14 +132,19 @@ func runLinks(cmd *cobra.Command, args []string) error {
 
 	registry := state.NewLinkRegistry(db)
 
+	minScore, err := parseMinScore(linksMinScore)
+	if err != nil {
+		return err
+	}
+
 	// Handle repair mode.
 	if linksRepair {
-		return runLinksRepair(registry, linksDryRun)
+		return runLinksRepair(cfg.Vault, registry, minScore, false, linksDryRun)
 	}
 
 	// Handle suggestions mode.
 	if linksSuggestions {
-		return runLinksSuggestions(registry)
+		return runLinksSuggestions(registry, minScore)
 	}
 
 	// Default: show statistics.
@@ -99,12 +175,203 @@ func runLinksStats(registry *state.LinkRegistry) error {
 		fmt.Println("Use --repair to fix with fuzzy matching")
 	}
 
+	mentions, err := registry.MissingMentions()
+	if err != nil {
+		return fmt.Errorf("get missing mentions: %w", err)
+	}
+	printMissingMentions(os.Stdout, mentions)
+
+	return nil
+}
+
+func runLinksFetchMissingCmd(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+	if err := refuseMirror(cfg, "pull"); err != nil {
+		return err
+	}
+	if linksTo != "" && !strings.HasSuffix(linksTo, "/") {
+		linksTo += "/"
+	}
+
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	pc := newPullContext(cfg, db, newNotionClients(cfg), started)
+	return runLinksFetchMissing(ctx, pc, linksTo, linksDryRun, started)
+}
+
+func runLinksRepairCmd(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+
+	minScore, err := parseMinScore(linksMinScore)
+	if err != nil {
+		return err
+	}
+
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	if linksHeadings {
+		files, err := newScanner(cfg).Scan(context.Background())
+		if err != nil {
+			return fmt.Errorf("scan vault: %w", err)
+		}
+		paths := make([]string, len(files))
+		for i, f := range files {
+			paths[i] = f.Path
+		}
+		return runHeadingRepair(os.Stdout, cfg.Vault, db, paths, linksDryRun)
+	}
+
+	return runLinksRepair(cfg.Vault, state.NewLinkRegistry(db), minScore, linksApply, linksDryRun)
+}
+
+// runHeadingRepair rewrites links to renamed headings in the notes at
+// paths, [[Note#Old]] to [[Note#New]], then forgets the renames.
+func runHeadingRepair(out io.Writer, vaultPath string, db *state.DB, paths []string, dryRun bool) error {
+	renames, err := db.HeadingRenames()
+	if err != nil {
+		return err
+	}
+	if len(renames) == 0 {
+		fmt.Fprintln(out, "No renamed headings")
+		return nil
+	}
+
+	// A heading renamed more than once is rewritten straight to its latest
+	// text; one renamed back needs nothing.
+	type headingChange struct{ path, old, new string }
+	var changes []*headingChange
+	for _, r := range renames {
+		for _, c := range changes {
+			if c.path == r.Path && c.new == r.Old {
+				c.new = r.New
+			}
+		}
+		changes = append(changes, &headingChange{path: r.Path, old: r.Old, new: r.New})
+	}
+
+	contents := make(map[string]string)
+	changed := make(map[string]bool)
+	total := 0
+	for _, c := range changes {
+		if c.old == c.new {
+			continue
+		}
+		count := 0
+		for _, notePath := range paths {
+			content, ok := contents[notePath]
+			if !ok {
+				data, err := os.ReadFile(filepath.Join(vaultPath, notePath))
+				if err != nil {
+					continue
+				}
+				content = string(data)
+				contents[notePath] = content
+			}
+
+			isNote := func(page string) bool {
+				if page == "" {
+					return notePath == c.path
+				}
+				return linksToNote(page, c.path)
+			}
+			rewritten, n := replaceHeadingLinks(content, isNote, c.old, c.new)
+			if n > 0 {
+				contents[notePath] = rewritten
+				changed[notePath] = true
+				count += n
+			}
+		}
+		fmt.Fprintf(out, "  %s: #%s -> #%s (%d link(s))\n", c.path, c.old, c.new, count)
+		total += count
+	}
+
+	if dryRun {
+		fmt.Fprintf(out, "\nWould rewrite %d link(s). Run without --dry-run to apply.\n", total)
+		return nil
+	}
+
+	for notePath := range changed {
+		if err := os.WriteFile(filepath.Join(vaultPath, notePath), []byte(contents[notePath]), 0644); err != nil {
+			return fmt.Errorf("write %s: %w", notePath, err)
+		}
+	}
+	for _, r := range renames {
+		if err := db.DeleteHeadingRename(r.ID); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "\nRewrote %d link(s) in %d note(s).\n", total, len(changed))
 	return nil
 }
 
+// linksToNote reports whether a wiki-link page, such as "Meeting Notes" or
+// "Work/Meeting Notes.md", names the note at path.
+func linksToNote(page, notePath string) bool {
+	page = strings.TrimSuffix(strings.TrimSpace(page), ".md")
+	name := strings.TrimSuffix(filepath.ToSlash(notePath), ".md")
+	return page == name || page == path.Base(name)
+}
+
+// replaceHeadingLinks points wiki-links to oldHeading of a note at
+// newHeading, keeping aliases. isNote reports whether a link's page names the
+// note; the page of a link within the same note is "". It returns the new
+// content and the number of links rewritten.
+func replaceHeadingLinks(content string, isNote func(page string) bool, oldHeading, newHeading string) (string, int) {
+	count := 0
+	result := wikiLinkPattern.ReplaceAllStringFunc(content, func(m string) string {
+		parts := wikiLinkPattern.FindStringSubmatch(m)
+		if !strings.HasPrefix(parts[3], "#") || !isNote(parts[2]) {
+			return m
+		}
+
+		// The anchor runs to the alias or the closing brackets, and may
+		// name nested headings: #Parent#Child.
+		rest := strings.TrimSuffix(parts[3], "]]")
+		anchor, alias, hasAlias := strings.Cut(rest[1:], "|")
+		headings := strings.Split(anchor, "#")
+		found := false
+		for i, h := range headings {
+			if strings.TrimSpace(h) == oldHeading {
+				headings[i] = newHeading
+				found = true
+			}
+		}
+		if !found {
+			return m
+		}
+		count++
+		rest = "#" + strings.Join(headings, "#")
+		if hasAlias {
+			rest += "|" + alias
+		}
+		return parts[1] + parts[2] + rest + "]]"
+	})
+	return result, count
+}
+
 // runLinksSuggestions shows fuzzy match suggestions for unresolved links.
-func runLinksSuggestions(registry *state.LinkRegistry) error {
-	suggestions, err := registry.GetSuggestionsForUnresolved(3)
+func runLinksSuggestions(registry *state.LinkRegistry, minScore state.MatchScore) error {
+	suggestions, err := registry.SuggestTargets(minScore, 3)
 	if err != nil {
 		return fmt.Errorf("get suggestions: %w", err)
 	}
@@ -118,11 +385,14 @@ func runLinksSuggestions(registry *state.LinkRegistry) error {
 	fmt.Println()
 
 	for _, s := range suggestions {
-		fmt.Printf("  [[%s]]:\n", s.Target)
+		fmt.Printf("  [[%s]] in %s:\n", s.Target, strings.Join(s.Sources, ", "))
 		for i, match := range s.Suggestions {
 			scoreLabel := scoreToLabel(match.Score)
-			fmt.Printf("    %d. %s (%s, distance: %d)\n",
-				i+1, match.Name, scoreLabel, match.Distance)
+			fmt.Printf("    %d. %s (%s, distance: %d", i+1, match.Name, scoreLabel, match.Distance)
+			if match.Modified.Unix() > 0 {
+				fmt.Printf(", modified %s", match.Modified.Format("2006-01-02"))
+			}
+			fmt.Println(")")
 		}
 		fmt.Println()
 	}
@@ -130,12 +400,20 @@ func runLinksSuggestions(registry *state.LinkRegistry) error {
 	return nil
 }
 
-// runLinksRepair repairs unresolved links using fuzzy matching.
-func runLinksRepair(registry *state.LinkRegistry, dryRun bool) error {
-	results, err := registry.RepairLinks(dryRun)
+// runLinksRepair repairs unresolved links using fuzzy matching. With apply,
+// the links are also rewritten in their source notes.
+func runLinksRepair(vaultPath string, registry *state.LinkRegistry, minScore state.MatchScore, apply, dryRun bool) error {
+	results, err := registry.RepairLinksMinScore(minScore, dryRun)
 	if err != nil {
 		return fmt.Errorf("repair links: %w", err)
 	}
+	if apply && !dryRun {
+		for i := range results {
+			if results[i].WasRepaired {
+				applySuggestion(vaultPath, registry, &results[i])
+			}
+		}
+	}
 
 	if len(results) == 0 {
 		fmt.Println("No links to repair (no unresolved links with fuzzy matches)")
@@ -149,13 +427,18 @@ func runLinksRepair(registry *state.LinkRegistry, dryRun bool) error {
 	}
 	fmt.Println()
 
+	repaired, wouldRepair := "repaired", "would repair"
+	if apply {
+		repaired, wouldRepair = "rewritten", "would rewrite"
+	}
+
 	repairedCount := 0
 	for _, r := range results {
 		scoreLabel := scoreToLabel(r.Score)
-		status := "would repair"
+		status := wouldRepair
 		if !dryRun {
 			if r.WasRepaired {
-				status = "repaired"
+				status = repaired
 				repairedCount++
 			} else {
 				status = "failed: " + r.Error
@@ -176,6 +459,80 @@ func runLinksRepair(registry *state.LinkRegistry, dryRun bool) error {
 	return nil
 }
 
+// applySuggestion rewrites a repaired link in its source note to point at
+// the matched note. Failures are recorded on the result; the link stays
+// resolved in the registry either way.
+func applySuggestion(vaultPath string, registry *state.LinkRegistry, r *state.RepairResult) {
+	fullPath := filepath.Join(vaultPath, r.SourcePath)
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		r.WasRepaired = false
+		r.Error = err.Error()
+		return
+	}
+
+	newPage := strings.TrimSuffix(filepath.Base(r.MatchedPath), ".md")
+	rewritten, n := replaceWikiLinkTarget(string(content), r.TargetPage, newPage)
+	if n == 0 {
+		r.WasRepaired = false
+		r.Error = "link not found in note"
+		return
+	}
+	if err := os.WriteFile(fullPath, []byte(rewritten), 0644); err != nil {
+		r.WasRepaired = false
+		r.Error = err.Error()
+		return
+	}
+
+	newTarget := newPage + strings.TrimPrefix(r.TargetName, r.TargetPage)
+	if err := registry.RetargetLink(r.LinkID, newTarget, r.MatchedPath, r.MatchedID); err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// wikiLinkPattern matches wiki-links and embeds, capturing the opening
+// brackets, the page, and the rest (heading, block reference, alias).
+var wikiLinkPattern = regexp.MustCompile(`(!?\[\[)([^\[\]|#^]*)([^\[\]]*\]\])`)
+
+// replaceWikiLinkTarget points wiki-links to oldPage at newPage, keeping
+// headings, block references, and aliases. It returns the new content and
+// the number of links rewritten.
+func replaceWikiLinkTarget(content, oldPage, newPage string) (string, int) {
+	count := 0
+	result := wikiLinkPattern.ReplaceAllStringFunc(content, func(m string) string {
+		parts := wikiLinkPattern.FindStringSubmatch(m)
+		page := strings.TrimSuffix(strings.TrimSpace(parts[2]), ".md")
+		if page != oldPage {
+			return m
+		}
+		count++
+		return parts[1] + newPage + parts[3]
+	})
+	return result, count
+}
+
+// minScores are the match scores --min-score accepts, strongest first.
+var minScores = []state.MatchScore{state.MatchExact, state.MatchCaseInsensitive, state.MatchPrefix, state.MatchFuzzy}
+
+// parseMinScore parses a match score label, as printed by scoreToLabel.
+func parseMinScore(s string) (state.MatchScore, error) {
+	for _, score := range minScores {
+		if scoreToLabel(score) == s {
+			return score, nil
+		}
+	}
+	return state.MatchNone, fmt.Errorf("invalid --min-score: %s (use exact, case-insensitive, prefix, or fuzzy)", s)
+}
+
+// completeMinScores completes --min-score with the score labels.
+func completeMinScores(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	labels := make([]string, 0, len(minScores))
+	for _, score := range minScores {
+		labels = append(labels, scoreToLabel(score))
+	}
+	return labels, cobra.ShellCompDirectiveNoFileComp
+}
+
 // scoreToLabel converts a MatchScore to a human-readable label.
 func scoreToLabel(score state.MatchScore) string {
 	switch score {
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+)
+
+// blockedStatus marks a note whose page is locked in Notion (push
+// --force-unlock-warning). Later pushes check the lock before sending the
+// note, and push it once the page is unlocked.
+const blockedStatus = "blocked"
+
+// lockedPage is a note a run left alone because its page is locked.
+type lockedPage struct {
+	path string
+	err  error
+}
+
+// printLocked reports a note whose page is locked, with who locked it if
+// known.
+func printLocked(w io.Writer, path string, err error) {
+	var locked *notion.LockedError
+	if errors.As(err, &locked) {
+		fmt.Fprintf(w, "  Locked %s: %v\n", path, locked)
+		return
+	}
+	fmt.Fprintf(w, "  Locked %s: %v\n", path, err)
+}
+
+// markBlocked records in the sync state that a note's page is locked.
+func markBlocked(db *state.DB, s *state.SyncState) {
+	if s == nil || s.Status == blockedStatus {
+		return
+	}
+	blocked := *s
+	blocked.Status = blockedStatus
+	if err := db.SetState(&blocked); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: could not mark %s blocked: %v\n", s.ObsidianPath, err)
+	}
+}
+
+// checkBlocked returns a locked error, without writing anything, for a
+// note marked blocked whose page is still locked.
+func checkBlocked(ctx context.Context, client *notion.Client, s *state.SyncState) error {
+	if s == nil || s.Status != blockedStatus || s.NotionPageID == "" {
+		return nil
+	}
+	return client.CheckLock(ctx, s.NotionPageID)
+}
+
+// printLockedSummary reports the notes a push left alone because their
+// pages are locked.
+func printLockedSummary(w io.Writer, locked []lockedPage, marked bool) {
+	if len(locked) == 0 {
+		return
+	}
+	if marked {
+		fmt.Fprintf(w, "  Locked: %d (marked blocked; unlock the pages in Notion and push again)\n", len(locked))
+	} else {
+		fmt.Fprintf(w, "  Locked: %d (unlock the pages in Notion and push again)\n", len(locked))
+	}
+	for _, l := range locked {
+		fmt.Fprintf(w, "    %s\n", l.path)
+	}
+}
@@ -0,0 +1,529 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+var (
+	matchYes      bool
+	matchDryRun   bool
+	matchMinScore string
+)
+
+// matchCmd represents the match command.
+var matchCmd = &cobra.Command{
+	Use:   "match",
+	Short: "Link untracked notes to existing Notion pages",
+	Long: `Link notes that have no Notion page yet to pages already in their
+database, so that pushing them updates those pages instead of creating
+duplicates.
+
+Notes are matched to pages by title: the note's title frontmatter, or its
+filename. Matches are grouped by how closely the titles agree (exact,
+case-insensitive, prefix, or fuzzy), and each group is confirmed on its
+own. Prefix and fuzzy matches are only kept if the note and the page also
+share most of their words, and a title matching several pages goes to the
+one with the most similar content.
+
+A linked note is tracked as in sync with its page; neither is changed
+until one of them is edited next.
+
+The first push into databases that already hold pages runs the same
+matching before creating anything; use push --no-match to skip it.
+
+Examples:
+  obsidian-notion match --dry-run            # List matches without linking
+  obsidian-notion match                      # Confirm each group of matches
+  obsidian-notion match --yes --min-score case-insensitive`,
+	RunE: runMatch,
+}
+
+func init() {
+	matchCmd.Flags().BoolVarP(&matchYes, "yes", "y", false, "link all matches without asking")
+	matchCmd.Flags().BoolVar(&matchDryRun, "dry-run", false, "list matches without linking them")
+	matchCmd.Flags().StringVar(&matchMinScore, "min-score", "fuzzy", "lowest title match to consider: exact, case-insensitive, prefix, or fuzzy")
+	_ = matchCmd.RegisterFlagCompletionFunc("min-score", completeMinScores)
+}
+
+func runMatch(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+	minScore, err := parseMinScore(matchMinScore)
+	if err != nil {
+		return err
+	}
+	if !matchYes && !matchDryRun && !isTerminal(os.Stdin) {
+		return fmt.Errorf("confirming matches needs a terminal: use --yes or --dry-run")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	files, err := newScanner(cfg).Scan(ctx)
+	if err != nil {
+		return fmt.Errorf("scan vault: %w", err)
+	}
+	var paths []string
+	for _, f := range files {
+		if s, _ := db.GetState(f.Path); s == nil || s.NotionPageID == "" {
+			paths = append(paths, f.Path)
+		}
+	}
+	if len(paths) == 0 {
+		fmt.Println("Every note already has a Notion page.")
+		return nil
+	}
+
+	matches, err := findExistingMatches(ctx, cfg, db, newNotionClients(cfg), paths)
+	if err != nil {
+		return err
+	}
+	matches = filterMatchScore(matches, minScore)
+	if len(matches) == 0 {
+		fmt.Printf("No existing pages match the %d untracked note(s).\n", len(paths))
+		return nil
+	}
+
+	if matchDryRun {
+		for _, bucket := range bucketMatches(matches) {
+			printMatchBucket(os.Stdout, bucket, len(bucket))
+		}
+		return nil
+	}
+
+	var linked []string
+	if matchYes {
+		linked, err = linkMatches(cfg, db, matches)
+	} else {
+		linked, err = confirmMatches(bufio.NewReader(os.Stdin), os.Stdout, cfg, db, matches)
+	}
+	fmt.Printf("\nLinked %d note(s) to existing pages.\n", len(linked))
+	return err
+}
+
+// minContentSimilarity is the share of words a prefix or fuzzy title match
+// needs in common with the page to be kept, since near titles alone often
+// belong to different notes.
+const minContentSimilarity = 0.5
+
+// existingMatch pairs a note without a Notion page with a page already in
+// its database.
+type existingMatch struct {
+	path       string
+	pageID     string
+	database   string
+	title      string // The page title
+	score      state.MatchScore
+	distance   int
+	similarity float64 // Word overlap of the contents, or -1 if not compared
+	lastEdited time.Time
+}
+
+// matchNote is a note considered for matching.
+type matchNote struct {
+	path     string
+	name     string
+	database string
+}
+
+// matchPage is an untracked page considered for matching.
+type matchPage struct {
+	id         string
+	title      string
+	database   string
+	lastEdited time.Time
+}
+
+// findMatches pairs notes with the pages of their database by title. Each
+// note and page is paired at most once, best matches first. similarity
+// compares the contents of a note and a page; it is called for prefix and
+// fuzzy matches, which are dropped below minContentSimilarity, and to pick
+// between pages whose titles match a note equally well.
+func findMatches(notes []matchNote, pages []matchPage, similarity func(path, pageID string) (float64, error)) ([]existingMatch, error) {
+	matcher := state.NewFuzzyMatcher()
+	var candidates []existingMatch
+	for _, n := range notes {
+		var found []existingMatch
+		for _, p := range pages {
+			if p.database != n.database || p.title == "" {
+				continue
+			}
+			score, dist := matcher.Match(n.name, p.title)
+			if score == state.MatchNone {
+				continue
+			}
+			found = append(found, existingMatch{
+				path:       n.path,
+				pageID:     p.id,
+				database:   p.database,
+				title:      p.title,
+				score:      score,
+				distance:   dist,
+				similarity: -1,
+				lastEdited: p.lastEdited,
+			})
+		}
+
+		// Titles matching several pages equally well are told apart by
+		// content.
+		top, tied := state.MatchNone, 0
+		for _, m := range found {
+			switch {
+			case m.score > top:
+				top, tied = m.score, 1
+			case m.score == top:
+				tied++
+			}
+		}
+		for i := range found {
+			m := &found[i]
+			if m.score >= state.MatchCaseInsensitive && (m.score != top || tied < 2) {
+				continue
+			}
+			sim, err := similarity(m.path, m.pageID)
+			if err != nil {
+				return nil, fmt.Errorf("compare %s with %q: %w", m.path, m.title, err)
+			}
+			m.similarity = sim
+		}
+		for _, m := range found {
+			if m.score < state.MatchCaseInsensitive && m.similarity < minContentSimilarity {
+				continue
+			}
+			candidates = append(candidates, m)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.similarity != b.similarity {
+			return a.similarity > b.similarity
+		}
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if a.path != b.path {
+			return a.path < b.path
+		}
+		return a.pageID < b.pageID
+	})
+
+	usedNotes := make(map[string]bool)
+	usedPages := make(map[string]bool)
+	var matches []existingMatch
+	for _, m := range candidates {
+		if usedNotes[m.path] || usedPages[m.pageID] {
+			continue
+		}
+		usedNotes[m.path] = true
+		usedPages[m.pageID] = true
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// findExistingMatches matches notes without a Notion page to the untracked
+// pages of their databases. Notes created under the default page are not
+// matched. Pages are fetched to compare contents only where titles do not
+// settle a match.
+func findExistingMatches(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory, paths []string) ([]existingMatch, error) {
+	p := parser.New()
+	var notes []matchNote
+	bodies := make(map[string][]byte)
+	for _, path := range paths {
+		content, err := os.ReadFile(filepath.Join(cfg.Vault, path))
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		note, err := p.Parse(path, content)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: %s: %v\n", path, err)
+			continue
+		}
+		database := noteDatabase(cfg, path, note)
+		if database == "" {
+			continue
+		}
+		notes = append(notes, matchNote{path: path, name: noteMatchName(path, note), database: database})
+		bodies[path] = content[len(noteFrontmatter(content)):]
+	}
+
+	var pages []matchPage
+	listed := make(map[string]bool)
+	for _, n := range notes {
+		if listed[n.database] {
+			continue
+		}
+		listed[n.database] = true
+		results, err := clients.ForDatabase(n.database).QueryAllPages(ctx, n.database, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("list pages of database %s: %w", n.database, err)
+		}
+		for _, page := range results {
+			id := string(page.ID)
+			if s, _ := db.GetStateByNotionID(id); s != nil {
+				continue
+			}
+			if isTarget, _ := db.IsTargetPage(id); isTarget {
+				continue
+			}
+			pages = append(pages, matchPage{
+				id:         id,
+				title:      extractTitle(page.Properties),
+				database:   n.database,
+				lastEdited: page.LastEditedTime,
+			})
+		}
+	}
+	if len(notes) == 0 || len(pages) == 0 {
+		return nil, nil
+	}
+
+	linkRegistry := state.NewLinkRegistry(db)
+	return findMatches(notes, pages, func(path, pageID string) (float64, error) {
+		page, err := clients.ForPath(path).FetchPage(ctx, pageID)
+		if err != nil {
+			return 0, err
+		}
+		markdown, err := transformer.NewReverse(linkRegistry, buildTransformerConfig(cfg, path)).NotionToMarkdown(page)
+		if err != nil {
+			return 0, err
+		}
+		return contentSimilarity(bodies[path], markdown[len(noteFrontmatter(markdown)):]), nil
+	})
+}
+
+// noteMatchName returns the name a note is matched by: its title
+// frontmatter, or else its filename.
+func noteMatchName(path string, note *parser.ParsedNote) string {
+	if title, ok := note.Frontmatter["title"].(string); ok && title != "" {
+		return title
+	}
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// contentSimilarity returns the share of distinct words two texts have in
+// common, from 0 for none to 1 for the same words. Case and punctuation
+// are ignored.
+func contentSimilarity(a, b []byte) float64 {
+	wordsA, wordsB := wordSet(a), wordSet(b)
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 0
+	}
+	common := 0
+	for w := range wordsA {
+		if wordsB[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(wordsA)+len(wordsB)-common)
+}
+
+// wordSet returns the distinct lowercase words of a text.
+func wordSet(text []byte) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(string(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[w] = true
+	}
+	return words
+}
+
+// filterMatchScore keeps the matches scoring at least min.
+func filterMatchScore(matches []existingMatch, min state.MatchScore) []existingMatch {
+	var kept []existingMatch
+	for _, m := range matches {
+		if m.score >= min {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// bucketMatches groups matches by score, best first.
+func bucketMatches(matches []existingMatch) [][]existingMatch {
+	var buckets [][]existingMatch
+	for _, score := range []state.MatchScore{state.MatchExact, state.MatchCaseInsensitive, state.MatchPrefix, state.MatchFuzzy} {
+		var bucket []existingMatch
+		for _, m := range matches {
+			if m.score == score {
+				bucket = append(bucket, m)
+			}
+		}
+		if len(bucket) > 0 {
+			buckets = append(buckets, bucket)
+		}
+	}
+	return buckets
+}
+
+// printMatchBucket lists up to limit matches of a bucket.
+func printMatchBucket(out io.Writer, bucket []existingMatch, limit int) {
+	fmt.Fprintf(out, "%d %s title match(es):\n", len(bucket), scoreToLabel(bucket[0].score))
+	for i, m := range bucket {
+		if i == limit {
+			fmt.Fprintf(out, "  ... and %d more\n", len(bucket)-limit)
+			break
+		}
+		if m.similarity >= 0 {
+			fmt.Fprintf(out, "  %s -> %q (%.0f%% similar)\n", m.path, m.title, m.similarity*100)
+		} else {
+			fmt.Fprintf(out, "  %s -> %q\n", m.path, m.title)
+		}
+	}
+}
+
+// confirmMatches asks, bucket by bucket, whether to link the matches, and
+// links those confirmed. Answering "l" lists the whole bucket. It returns
+// the linked paths.
+func confirmMatches(in *bufio.Reader, out io.Writer, cfg *config.Config, db *state.DB, matches []existingMatch) ([]string, error) {
+	const shown = 10
+	var linked []string
+	for _, bucket := range bucketMatches(matches) {
+		printMatchBucket(out, bucket, shown)
+		for {
+			fmt.Fprintf(out, "Link them to the existing pages? [y/N/l] ")
+			answer, err := in.ReadString('\n')
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			if answer == "l" {
+				printMatchBucket(out, bucket, len(bucket))
+				continue
+			}
+			if answer == "y" || answer == "yes" {
+				paths, linkErr := linkMatches(cfg, db, bucket)
+				linked = append(linked, paths...)
+				if linkErr != nil {
+					return linked, linkErr
+				}
+			}
+			if err != nil && err != io.EOF {
+				return linked, err
+			}
+			break
+		}
+	}
+	return linked, nil
+}
+
+// linkMatches tracks each matched note as synced with its page, so the
+// next push updates the page rather than creating another. It returns the
+// linked paths.
+func linkMatches(cfg *config.Config, db *state.DB, matches []existingMatch) ([]string, error) {
+	var linked []string
+	for _, m := range matches {
+		fullPath := filepath.Join(cfg.Vault, m.path)
+		hashes, err := noteHasher(cfg).HashFileDetailed(fullPath)
+		if err != nil {
+			return linked, fmt.Errorf("hash %s: %w", m.path, err)
+		}
+		var mtime time.Time
+		if info, err := os.Stat(fullPath); err == nil {
+			mtime = info.ModTime()
+		}
+		if err := db.SetState(&state.SyncState{
+			ObsidianPath:    m.path,
+			NotionPageID:    m.pageID,
+			NotionParentID:  m.database,
+			ContentHash:     hashes.ContentHash,
+			FrontmatterHash: hashes.FrontmatterHash,
+			ObsidianMtime:   mtime,
+			NotionMtime:     m.lastEdited,
+			LastSync:        time.Now(),
+			SyncDirection:   "push",
+			Status:          "synced",
+		}); err != nil {
+			return linked, fmt.Errorf("link %s: %w", m.path, err)
+		}
+		linked = append(linked, m.path)
+	}
+	return linked, nil
+}
+
+// isFirstPush reports whether no note has been synced to Notion yet.
+func isFirstPush(db *state.DB) (bool, error) {
+	states, err := db.ListStates("")
+	if err != nil {
+		return false, err
+	}
+	for _, s := range states {
+		if s.NotionPageID != "" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchFirstPush runs before the first push, matching its new notes to
+// pages already in their databases. Confirmed matches are linked and left
+// out of the returned files. Without a terminal to confirm on, a push that
+// found matches stops instead.
+func matchFirstPush(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory, files []pushFile) ([]pushFile, error) {
+	var paths []string
+	for _, f := range files {
+		if f.changeType == state.ChangeCreated {
+			paths = append(paths, f.path)
+		}
+	}
+	if len(paths) == 0 {
+		return files, nil
+	}
+
+	matches, err := findExistingMatches(ctx, cfg, db, clients, paths)
+	if err != nil {
+		return nil, fmt.Errorf("match existing pages: %w", err)
+	}
+	if len(matches) == 0 {
+		return files, nil
+	}
+	if !isTerminal(os.Stdin) {
+		return nil, fmt.Errorf("%d new note(s) match pages already in Notion: link them with 'obsidian-notion match', or push --no-match to create new pages", len(matches))
+	}
+
+	fmt.Printf("Found %d existing Notion page(s) matching new notes.\n", len(matches))
+	linked, err := confirmMatches(bufio.NewReader(os.Stdin), os.Stdout, cfg, db, matches)
+	if err != nil {
+		return nil, err
+	}
+	isLinked := make(map[string]bool, len(linked))
+	for _, path := range linked {
+		isLinked[path] = true
+	}
+	var remaining []pushFile
+	for _, f := range files {
+		if !isLinked[f.path] {
+			remaining = append(remaining, f)
+		}
+	}
+	fmt.Printf("Linked %d note(s) to existing pages.\n\n", len(linked))
+	return remaining, nil
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+// mentionRecorder looks up pages like another lookup and records the
+// pages it finds no note for, so a pull can track the mentions it wrote
+// as links to notes that do not exist.
+type mentionRecorder struct {
+	transformer.PathLookup
+	missed []string
+}
+
+// LookupPath implements transformer.PathLookup.
+func (r *mentionRecorder) LookupPath(notionPageID string) (string, bool) {
+	path, found := r.PathLookup.LookupPath(notionPageID)
+	if !found && !slices.Contains(r.missed, notionPageID) {
+		r.missed = append(r.missed, notionPageID)
+	}
+	return path, found
+}
+
+// recordMissingMentions stores the pages a pulled note mentions that have
+// no note in the vault, for links fetch-missing. Failures are reported as
+// warnings, since the note was pulled.
+func recordMissingMentions(registry *state.LinkRegistry, path string, missed []string) {
+	if err := registry.SetMissingMentions(path, missed); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to record mentions of %s: %v\n", path, err)
+	}
+}
+
+// takeMissingMentions records the pages a note pulled by the context
+// mentions that have no note, and remembers them for the pull's summary.
+func (pc *pullContext) takeMissingMentions(path string, missed []string) {
+	recordMissingMentions(pc.linkRegistry, path, missed)
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for _, id := range missed {
+		if !slices.Contains(pc.missing, id) {
+			pc.missing = append(pc.missing, id)
+		}
+	}
+}
+
+// reportMissingMentions tells how many pages the pulled notes mention
+// that are not in the vault, if any.
+func (pc *pullContext) reportMissingMentions(w io.Writer) {
+	var missing int
+	for _, id := range pc.missing {
+		if _, found := pc.linkRegistry.LookupPath(id); !found {
+			missing++
+		}
+	}
+	if missing > 0 {
+		fmt.Fprintf(w, "  Missing: %d mentioned page(s) not in the vault (see 'obsidian-notion links')\n", missing)
+	}
+}
+
+// notionPageURL returns the URL of a Notion page.
+func notionPageURL(pageID string) string {
+	return "https://www.notion.so/" + strings.ReplaceAll(pageID, "-", "")
+}
+
+// printMissingMentions lists the pages pulled notes mention that are not
+// in the vault, with the notes mentioning them.
+func printMissingMentions(w io.Writer, mentions []*state.MissingMention) {
+	if len(mentions) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Pages mentioned in pulled notes but not in the vault:")
+	for _, m := range mentions {
+		fmt.Fprintf(w, "  %s\n", notionPageURL(m.NotionPageID))
+		fmt.Fprintf(w, "    mentioned in: %s\n", strings.Join(m.Sources, ", "))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Use 'links fetch-missing' to pull them")
+}
+
+// fetchMissingMentions pulls the pages pulled notes mention that are not
+// in the vault into new notes in folder, and returns how many it pulled
+// and how many failed.
+func fetchMissingMentions(ctx context.Context, w io.Writer, pc *pullContext, folder string, dryRun bool) (pulled, failed int, err error) {
+	mentions, err := pc.linkRegistry.MissingMentions()
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(mentions) == 0 {
+		fmt.Fprintln(w, "No mentioned pages are missing from the vault.")
+		return 0, 0, nil
+	}
+
+	for _, m := range mentions {
+		localPath, err := pc.pullNewPage(ctx, m.NotionPageID, folder, dryRun, false, false)
+		if err != nil {
+			printError(os.Stderr, "Error pulling", notionPageURL(m.NotionPageID), err)
+			failed++
+			continue
+		}
+		if dryRun {
+			fmt.Fprintf(w, "  + would create: %s\n", localPath)
+			continue
+		}
+		fmt.Fprintf(w, "  + %s\n", localPath)
+		pulled++
+	}
+	return pulled, failed, nil
+}
+
+// runLinksFetchMissing pulls the pages pulled notes mention that are not
+// in the vault.
+func runLinksFetchMissing(ctx context.Context, pc *pullContext, folder string, dryRun bool, started time.Time) error {
+	pulled, failed, err := fetchMissingMentions(ctx, os.Stdout, pc, folder, dryRun)
+	if err != nil {
+		return err
+	}
+	if dryRun || pulled+failed == 0 {
+		return nil
+	}
+	recordRun(pc.db, pc.clients, "pull", started, 0, pulled, failed)
+	fmt.Printf("\nPulled %d page(s)", pulled)
+	if failed > 0 {
+		fmt.Printf(", %d failed", failed)
+	}
+	fmt.Println()
+	pc.reportMissingMentions(os.Stdout)
+	return nil
+}
@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notify"
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+)
+
+const (
+	// mirrorReportInterval is how often push and sync report on the
+	// integrity of a mirror.
+	mirrorReportInterval = 7 * 24 * time.Hour
+
+	// mirrorReportKey is the state database setting holding when the last
+	// integrity report was made.
+	mirrorReportKey = "mirror_report_at"
+)
+
+// mirrorCmd represents the mirror command.
+var mirrorCmd = &cobra.Command{
+	Use:   "mirror",
+	Short: "Check a vault mirrored to Notion",
+	Long: `With mode: mirror in the config file, the vault is kept as an
+append-only copy in Notion:
+
+  - Nothing is pulled. pull, restore, conflicts resolve, sync undo, and
+    publish refuse to run, sync only pushes, and watch does not poll
+    Notion. Pages edited in Notion are overwritten by the next push of
+    their note.
+  - Deleted notes keep their pages, as with sync.deletion_strategy ignore.
+  - Once a week, push and sync print an integrity report comparing the
+    vault with Notion, and send it through notify if it is configured.`,
+}
+
+// mirrorReportCmd reports on the integrity of a mirror.
+var mirrorReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Compare the vault with its mirror in Notion",
+	Long: `Compare the notes in the vault with their pages in Notion, and report:
+
+  - notes not pushed yet, or changed since they were pushed
+  - notes whose pages are archived or missing in Notion
+  - notes whose pages were edited in Notion since they were pushed
+
+Only page metadata is read: a page counts as edited when its last edit
+time is after the note's last push, and its content is not compared with
+the note. An edit that restores the pushed content is still reported, and
+content that differs without a later edit, such as a push that failed
+partway, is not. Each page is read once, so the report takes about as
+many API requests as the vault has notes. It exits non-zero if a page is
+missing or was edited.
+
+Examples:
+  obsidian-notion mirror report`,
+	Args: cobra.NoArgs,
+	RunE: runMirrorReport,
+}
+
+func init() {
+	mirrorCmd.AddCommand(mirrorReportCmd)
+	rootCmd.AddCommand(mirrorCmd)
+}
+
+func runMirrorReport(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	report, err := checkMirror(ctx, cfg, db, newNotionClients(cfg))
+	if err != nil {
+		return err
+	}
+	report.write(os.Stdout)
+	if err := db.SetConfig(mirrorReportKey, time.Now().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("record report: %w", err)
+	}
+	if !report.intact() {
+		return fmt.Errorf("mirror has %d missing and %d edited page(s)", len(report.Missing), len(report.Edited))
+	}
+	return nil
+}
+
+// integrityReport compares the notes in a vault with their pages in
+// Notion.
+type integrityReport struct {
+	Notes    int      // Notes in the vault.
+	Mirrored int      // Notes with a page.
+	Pages    int      // Pages of mirrored notes found in Notion.
+	Pending  []string // Notes not pushed yet, or changed since.
+	Missing  []string // Notes whose pages are archived or missing.
+	Edited   []string // Notes whose pages were edited in Notion since they were pushed.
+}
+
+// intact reports whether every pushed note has its page in Notion, as it
+// was pushed.
+func (r *integrityReport) intact() bool {
+	return len(r.Missing) == 0 && len(r.Edited) == 0
+}
+
+// write prints the report.
+func (r *integrityReport) write(w io.Writer) {
+	fmt.Fprintln(w, "Mirror integrity report:")
+	fmt.Fprintf(w, "  Notes:    %d\n", r.Notes)
+	fmt.Fprintf(w, "  Mirrored: %d (%d page(s) found in Notion)\n", r.Mirrored, r.Pages)
+	list := func(label, note string, paths []string) {
+		if len(paths) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "  %-9s %d (%s)\n", label+":", len(paths), note)
+		for i, path := range paths {
+			if i == 20 {
+				fmt.Fprintf(w, "    ... and %d more\n", len(paths)-i)
+				break
+			}
+			fmt.Fprintf(w, "    %s\n", path)
+		}
+	}
+	list("Pending", "not pushed yet, or changed since", r.Pending)
+	list("Missing", "archived or missing in Notion", r.Missing)
+	list("Edited", "edited in Notion; the next push of the note overwrites it", r.Edited)
+}
+
+// checkMirror compares the notes in the vault with their sync state and
+// their pages in Notion. Pages are checked by metadata alone: whether they
+// exist and when they were last edited, not what they contain.
+func checkMirror(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory) (*integrityReport, error) {
+	scanner := newScanner(cfg)
+	files, err := scanner.Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scan vault: %w", err)
+	}
+
+	report := &integrityReport{}
+	for _, f := range files {
+		if scanner.IsTemplate(f.Path) {
+			continue
+		}
+		report.Notes++
+
+		s, err := db.GetState(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("get state of %s: %w", f.Path, err)
+		}
+		if s == nil || s.NotionPageID == "" {
+			report.Pending = append(report.Pending, f.Path)
+			continue
+		}
+		report.Mirrored++
+		hashes, err := noteHasher(cfg).HashFileDetailed(f.AbsPath)
+		if err != nil || state.HasContentChanged(state.HashesFromState(s), hashes) {
+			report.Pending = append(report.Pending, f.Path)
+		}
+
+		meta, err := clients.ForPath(f.Path).GetPageMetadata(ctx, s.NotionPageID)
+		switch {
+		case errors.Is(err, notion.ErrNotFound):
+			report.Missing = append(report.Missing, f.Path)
+		case err != nil:
+			return nil, fmt.Errorf("check page of %s: %w", f.Path, err)
+		case meta.Archived:
+			report.Missing = append(report.Missing, f.Path)
+		default:
+			report.Pages++
+			// Notion rounds edit times down to the minute, so a page
+			// edited since its push has a later edit time.
+			if meta.LastEditedTime.After(s.LastSync) {
+				report.Edited = append(report.Edited, f.Path)
+			}
+		}
+	}
+	return report, nil
+}
+
+// reportMirror prints the integrity report of a mirror and sends it
+// through notify, if a week has passed since the last one.
+func reportMirror(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory) {
+	if !cfg.Mirror() {
+		return
+	}
+	last, err := db.GetConfig(mirrorReportKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: could not read when the mirror was last checked: %v\n", err)
+		return
+	}
+	if at, err := time.Parse(time.RFC3339, last); err == nil && time.Since(at) < mirrorReportInterval {
+		return
+	}
+
+	started := time.Now()
+	report, err := checkMirror(ctx, cfg, db, clients)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: could not check the mirror: %v\n", err)
+		return
+	}
+	var text strings.Builder
+	report.write(&text)
+	fmt.Printf("\n%s", text.String())
+	if err := db.SetConfig(mirrorReportKey, started.Format(time.RFC3339)); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: could not record the mirror report: %v\n", err)
+	}
+
+	sent := &notify.Report{
+		Command:   "mirror report",
+		Vault:     cfg.Vault,
+		StartedAt: started,
+		Duration:  time.Since(started),
+		Details:   text.String(),
+	}
+	for _, path := range report.Missing {
+		sent.Failures = append(sent.Failures, notify.Failure{Path: path, Err: "page archived or missing in Notion"})
+	}
+	for _, path := range report.Edited {
+		sent.Failures = append(sent.Failures, notify.Failure{Path: path, Err: "page edited in Notion"})
+	}
+	sendReport(cfg, sent)
+}
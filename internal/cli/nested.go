@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+// nestedFolder returns the vault folder the rows of a database nested in
+// a note's page are pulled to: a folder named after the database, in a
+// folder named after the note.
+func nestedFolder(cfg *config.Config, notePath, title string) string {
+	if title == "" {
+		title = "Untitled"
+	}
+	parent := strings.TrimSuffix(notePath, filepath.Ext(notePath))
+	return filepath.Join(parent, sanitizeFilename(title, cfg.Sync.Filenames))
+}
+
+// takeNestedDatabases removes the databases nested at the top of a pulled
+// page, which are not part of its note, and records them with their
+// folders. Databases found for the first time are kept for nestedRows.
+func (pc *pullContext) takeNestedDatabases(notePath string, page *transformer.NotionPage) {
+	children := page.Children[:0]
+	for _, block := range page.Children {
+		child, ok := block.(*notionapi.ChildDatabaseBlock)
+		if !ok {
+			children = append(children, block)
+			continue
+		}
+		nested := state.NestedDatabase{
+			DatabaseID: string(child.ID),
+			Folder:     nestedFolder(pc.cfg, notePath, child.ChildDatabase.Title),
+			ParentPath: notePath,
+			Title:      child.ChildDatabase.Title,
+		}
+		added, err := pc.db.AddNestedDatabase(nested)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: could not record database %q in %s: %v\n", nested.Title, notePath, err)
+			continue
+		}
+		if added {
+			pc.mu.Lock()
+			pc.nested = append(pc.nested, nested)
+			pc.mu.Unlock()
+		}
+	}
+	page.Children = children
+}
+
+// nestedRows returns the rows of the databases found nested in pages
+// pulled so far, to be pulled into their folders, and forgets those
+// databases.
+func (pc *pullContext) nestedRows(ctx context.Context) []pullPage {
+	pc.mu.Lock()
+	found := pc.nested
+	pc.nested = nil
+	pc.mu.Unlock()
+
+	var pages []pullPage
+	names := newNoteNames(pc.cfg.Vault)
+	for _, n := range found {
+		addNestedMapping(pc.cfg, n)
+		rows, err := discoverNewPages(ctx, pc.cfg, pc.db, pc.clients, names, n.DatabaseID, n.Folder, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: could not list rows of database %q: %v\n", n.Title, err)
+			continue
+		}
+		pages = append(pages, rows...)
+	}
+	return pages
+}
+
+// useNestedDatabases maps the folder of each recorded nested database to
+// its database, so notes created in the folder are pushed as its rows.
+// Mappings in the config file take precedence.
+func useNestedDatabases(cfg *config.Config, db *state.DB) error {
+	nested, err := db.NestedDatabases()
+	if err != nil {
+		return fmt.Errorf("get nested databases: %w", err)
+	}
+	for _, n := range nested {
+		addNestedMapping(cfg, n)
+	}
+	return nil
+}
+
+// addNestedMapping appends a mapping of a nested database's folder to the
+// database, using the credential of the note the database is nested in.
+func addNestedMapping(cfg *config.Config, n state.NestedDatabase) {
+	pattern := filepath.Join(escapeGlob(n.Folder), "*.md")
+	for _, m := range cfg.Mappings {
+		if m.Path == pattern && m.Database == n.DatabaseID {
+			return
+		}
+	}
+	cfg.Mappings = append(cfg.Mappings, config.FolderMapping{
+		Path:       pattern,
+		Database:   n.DatabaseID,
+		Credential: cfg.CredentialForPath(n.ParentPath),
+	})
+}
+
+// escapeGlob quotes the characters of a path that filepath.Match treats
+// as a pattern.
+func escapeGlob(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
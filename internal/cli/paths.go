@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// resolveNotePaths turns note arguments into vault-relative paths. An
+// argument naming a vault file from the current directory is taken
+// relative to it; any other relative argument is taken relative to the
+// vault root, so deleted notes can be named too. Paths outside the vault
+// are rejected.
+func resolveNotePaths(vault string, args []string) ([]string, error) {
+	root, err := filepath.Abs(vault)
+	if err != nil {
+		return nil, fmt.Errorf("resolve vault: %w", err)
+	}
+	cwd, _ := os.Getwd()
+
+	paths := make([]string, 0, len(args))
+	for _, arg := range args {
+		var path string
+		var ok bool
+		if filepath.IsAbs(arg) {
+			path, ok = vaultRel(root, arg)
+		} else if _, err := os.Stat(filepath.Join(cwd, arg)); cwd != "" && err == nil {
+			path, ok = vaultRel(root, filepath.Join(cwd, arg))
+		}
+		if !ok && !filepath.IsAbs(arg) {
+			path = filepath.Clean(arg)
+			ok = filepath.IsLocal(path)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s is not in the vault (%s)", arg, vault)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// vaultRel returns an absolute path relative to the vault root, reporting
+// false if it lies outside the vault. Symlinks are resolved if the paths
+// do not compare directly, as when the vault is reached through one.
+func vaultRel(root, path string) (string, bool) {
+	if rel, err := filepath.Rel(root, path); err == nil && filepath.IsLocal(rel) {
+		return rel, true
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", false
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(realRoot, realPath)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", false
+	}
+	return rel, true
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package cli
+
+import (
+	"os"
+	"syscall"
+)
+
+// pauseSignals returns the signals that pause and resume the watcher:
+// SIGUSR1 and SIGUSR2.
+func pauseSignals() (pause, resume os.Signal) {
+	return syscall.SIGUSR1, syscall.SIGUSR2
+}
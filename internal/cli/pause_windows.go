@@ -0,0 +1,11 @@
+//go:build windows
+
+package cli
+
+import "os"
+
+// pauseSignals returns no signals, as Windows has none to spare; the
+// watcher is paused and resumed through the local HTTP API instead.
+func pauseSignals() (pause, resume os.Signal) {
+	return nil, nil
+}
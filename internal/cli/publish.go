@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+)
+
+// publishCmd represents the publish command.
+var publishCmd = &cobra.Command{
+	Use:   "publish <path>",
+	Short: "Push a note and mark its page published",
+	Long: `Publish a note, for example a blog post kept in a Notion database.
+
+The note's status is set to publish.status_value (default "Published") in
+its frontmatter, under the key mapped to the publish.status_property
+Notion property (default "Status"), and the note is pushed. Map the
+property in transform.property_mappings, with type status or select:
+
+  transform:
+    property_mappings:
+      - obsidian: status
+        notion: Status
+        type: status
+
+If the page is shared to the web, its public URL is recorded in the
+note's frontmatter under publish.url_key (default "public_url"). Share
+the page in Notion and publish again to record the URL later.
+
+With publish.webhook set, the webhook is sent a JSON POST describing the
+published note: its path, page ID, page URL, and public URL.
+
+Examples:
+  obsidian-notion publish posts/hello-world.md`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeNotePaths,
+	RunE:              runPublish,
+}
+
+func init() {
+	rootCmd.AddCommand(publishCmd)
+}
+
+// publishEvent is the body of the webhook request sent for a published
+// note.
+type publishEvent struct {
+	Event     string `json:"event"`
+	Path      string `json:"path"`
+	PageID    string `json:"page_id"`
+	URL       string `json:"url"`
+	PublicURL string `json:"public_url,omitempty"`
+}
+
+func runPublish(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+	if err := refuseMirror(cfg, "publish"); err != nil {
+		return err
+	}
+	paths, err := resolveNotePaths(cfg.Vault, args)
+	if err != nil {
+		return err
+	}
+	path := paths[0]
+
+	statusKey, ok := publishStatusKey(cfg, path)
+	if !ok {
+		return fmt.Errorf("the %s property is not mapped to a frontmatter key for %s; map it in transform.property_mappings (see 'obsidian-notion publish --help')", cfg.Publish.StatusProperty, path)
+	}
+
+	// 1. Mark the note published and push it.
+	fullPath := filepath.Join(cfg.Vault, path)
+	if err := setNoteFrontmatter(fullPath, statusKey, cfg.Publish.StatusValue); err != nil {
+		return err
+	}
+	if err := runPush(cmd, []string{path}); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	syncState, err := db.GetState(path)
+	if err != nil {
+		return fmt.Errorf("get state: %w", err)
+	}
+	if syncState == nil || syncState.NotionPageID == "" || syncState.Status != "synced" {
+		return fmt.Errorf("%s was not pushed, so it was not published", path)
+	}
+
+	// 2. Record the public URL of a page shared to the web.
+	page, err := newNotionClients(cfg).ForPath(path).GetPage(ctx, syncState.NotionPageID)
+	if err != nil {
+		return fmt.Errorf("get page: %w", err)
+	}
+	event := publishEvent{
+		Event:     "published",
+		Path:      path,
+		PageID:    syncState.NotionPageID,
+		URL:       page.URL,
+		PublicURL: page.PublicURL,
+	}
+	if event.PublicURL != "" {
+		if err := recordPublicURL(cfg, db, syncState, event.PublicURL); err != nil {
+			return err
+		}
+	}
+
+	// 3. Tell the webhook.
+	if cfg.Publish.Webhook != "" {
+		if err := sendPublishEvent(ctx, cfg.Publish.Webhook, event); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: %v\n", err)
+		}
+	}
+
+	fmt.Printf("\nPublished %s\n", path)
+	if event.PublicURL != "" {
+		fmt.Printf("  Public URL: %s\n", event.PublicURL)
+	} else {
+		fmt.Println("  The page is not shared to the web; share it in Notion and publish again to record its public URL.")
+	}
+	return nil
+}
+
+// publishStatusKey returns the frontmatter key mapped to the status
+// property of a note's page.
+func publishStatusKey(cfg *config.Config, path string) (string, bool) {
+	tc := buildTransformerConfig(cfg, path)
+	// Renames apply on top of the mappings.
+	for _, m := range append(tc.PropertyRenames, tc.PropertyMappings...) {
+		if m.NotionName == cfg.Publish.StatusProperty {
+			return m.ObsidianKey, true
+		}
+	}
+	return "", false
+}
+
+// recordPublicURL writes the public URL of a published note's page to its
+// frontmatter, and records the edit in the sync state so the note is not
+// pushed again for it.
+func recordPublicURL(cfg *config.Config, db *state.DB, syncState *state.SyncState, publicURL string) error {
+	fullPath := filepath.Join(cfg.Vault, syncState.ObsidianPath)
+	if err := setNoteFrontmatter(fullPath, cfg.Publish.URLKey, publicURL); err != nil {
+		return err
+	}
+	hashes, err := noteHasher(cfg).HashFileDetailed(fullPath)
+	if err != nil {
+		return fmt.Errorf("hash %s: %w", syncState.ObsidianPath, err)
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", syncState.ObsidianPath, err)
+	}
+	syncState.ContentHash = hashes.ContentHash
+	syncState.FrontmatterHash = hashes.FrontmatterHash
+	syncState.ObsidianMtime = info.ModTime()
+	if err := db.SetState(syncState); err != nil {
+		return fmt.Errorf("update state: %w", err)
+	}
+	return nil
+}
+
+// setNoteFrontmatter sets a frontmatter key of a note, leaving the file
+// untouched if it already has the value.
+func setNoteFrontmatter(fullPath, key, value string) error {
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return fmt.Errorf("read note: %w", err)
+	}
+	updated, err := setFrontmatterValue(content, key, value)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(updated, content) {
+		return nil
+	}
+	if err := os.WriteFile(fullPath, updated, 0o644); err != nil {
+		return fmt.Errorf("write note: %w", err)
+	}
+	return nil
+}
+
+// setFrontmatterValue sets a top-level frontmatter key of a note to a
+// string, editing only the key's lines so the rest of the frontmatter
+// keeps its formatting. A note without frontmatter gets some.
+func setFrontmatterValue(content []byte, key, value string) ([]byte, error) {
+	encoded, err := yaml.Marshal(map[string]string{key: value})
+	if err != nil {
+		return nil, fmt.Errorf("encode frontmatter: %w", err)
+	}
+	entry := strings.TrimSuffix(string(encoded), "\n")
+
+	text := string(content)
+	if !strings.HasPrefix(text, "---\n") && !strings.HasPrefix(text, "---\r\n") {
+		return []byte("---\n" + entry + "\n---\n" + text), nil
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], "\r\n") == "---" {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, fmt.Errorf("frontmatter is not closed with ---")
+	}
+
+	var out []string
+	out = append(out, lines[0])
+	replaced := false
+	for i := 1; i < end; i++ {
+		line := lines[i]
+		if !strings.HasPrefix(line, key+":") {
+			out = append(out, line)
+			continue
+		}
+		// Drop the key's old value, including indented continuation lines.
+		for i+1 < end && (strings.HasPrefix(lines[i+1], " ") || strings.HasPrefix(lines[i+1], "\t") || strings.HasPrefix(lines[i+1], "- ")) {
+			i++
+		}
+		if !replaced {
+			out = append(out, entry+"\n")
+			replaced = true
+		}
+	}
+	if !replaced {
+		out = append(out, entry+"\n")
+	}
+	out = append(out, lines[end:]...)
+	return []byte(strings.Join(out, "")), nil
+}
+
+// sendPublishEvent posts a published note's event to a webhook.
+func sendPublishEvent(ctx context.Context, webhook string, event publishEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode webhook request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("publish webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("publish webhook returned %s", resp.Status)
+	}
+	return nil
+}
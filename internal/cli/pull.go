@@ -2,10 +2,13 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -17,6 +20,7 @@ import (
 	"github.com/adamancini/obsidian-notion-sync/internal/state"
 	osync "github.com/adamancini/obsidian-notion-sync/internal/sync"
 	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+	"github.com/adamancini/obsidian-notion-sync/internal/vault"
 )
 
 var (
@@ -24,23 +28,57 @@ var (
 	pullPath   string
 	pullDryRun bool
 	pullForce  bool
+
+	pullIncludeArchived   bool
+	pullPageRef           string
+	pullTo                string
+	pullFlatten           bool
+	pullPreserveStructure bool
 )
 
 // pullCmd represents the pull command.
 var pullCmd = &cobra.Command{
-	Use:   "pull",
+	Use:   "pull [files...]",
 	Short: "Pull changes from Notion to local vault",
 	Long: `Pull changes from Notion to the local Obsidian vault.
 
 By default, only pulls pages that have changed since the last sync.
-Use --all to pull all tracked pages regardless of change detection.
+Use --all to pull all tracked pages regardless of change detection. Files
+named as arguments, relative to the current directory or the vault root,
+limit the pull to those notes.
+
+Pages archived or trashed in Notion are not pulled. Their notes are left
+as they are and listed as archived remotely here and in 'status'. Use
+--include-archived to pull them anyway.
+
+Databases nested in a pulled page are synced as folders: each row is a
+note in a folder named after the database, inside a folder named after
+the page's note, and notes created in that folder are pushed as new rows.
 
 Examples:
   obsidian-notion pull                    # Pull all changed pages
   obsidian-notion pull --all              # Pull all tracked pages
+  obsidian-notion pull notes/today.md     # Pull one note if its page changed
   obsidian-notion pull --path "work/**"   # Pull pages matching pattern
-  obsidian-notion pull --dry-run          # Show what would be pulled`,
-	RunE: runPull,
+  obsidian-notion pull --dry-run          # Show what would be pulled
+  obsidian-notion pull --include-archived # Also pull archived pages
+
+New pages of the default database are pulled as notes named after them
+at the top of the vault, or into the folders of sync.pull_layout, a note
+path template such as "{{Area}}/{{title}}.md" (by select property) or
+"{{year}}/{{month}}/{{title}}.md" (by creation date). Use
+--preserve-structure to pull them into folders named after their parent
+items instead, or --flatten to pull them to the top.
+
+A page that is not synced yet, such as one a colleague shared, can be
+pulled on its own with --page, by ID or URL. It is written to --to, a note
+path or a folder, or else to a note named after the page at the top of
+the vault, and is tracked from then on like any other note:
+
+  obsidian-notion pull --page https://www.notion.so/team/Plan-0123abcd456789abcdef0123456789ab
+  obsidian-notion pull --page 0123abcd456789abcdef0123456789ab --to "work/"`,
+	ValidArgsFunction: completeNotePaths,
+	RunE:              runPull,
 }
 
 func init() {
@@ -48,6 +86,11 @@ func init() {
 	pullCmd.Flags().StringVar(&pullPath, "path", "", "glob pattern to filter files")
 	pullCmd.Flags().BoolVar(&pullDryRun, "dry-run", false, "show what would be pulled without making changes")
 	pullCmd.Flags().BoolVar(&pullForce, "force", false, "force pull even if there are conflicts")
+	pullCmd.Flags().BoolVar(&pullIncludeArchived, "include-archived", false, "also pull pages archived or trashed in Notion")
+	pullCmd.Flags().StringVar(&pullPageRef, "page", "", "pull one page not yet synced, by ID or URL")
+	pullCmd.Flags().StringVar(&pullTo, "to", "", "with --page, the note path or folder to pull the page to")
+	pullCmd.Flags().BoolVar(&pullFlatten, "flatten", false, "pull new database pages to the top of the vault, ignoring sync.pull_layout")
+	pullCmd.Flags().BoolVar(&pullPreserveStructure, "preserve-structure", false, "pull new database pages into folders named after their parent items")
 }
 
 func runPull(cmd *cobra.Command, args []string) error {
@@ -55,8 +98,12 @@ func runPull(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if err := refuseMirror(cfg, "pull"); err != nil {
+		return err
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	started := time.Now()
+	ctx, cancel := runContext(30 * time.Minute)
 	defer cancel()
 
 	// 1. Open state database.
@@ -66,31 +113,75 @@ func runPull(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
 	}
 	defer db.Close()
+	if err := useNestedDatabases(cfg, db); err != nil {
+		return err
+	}
 
-	// 2. Initialize Notion client.
-	client := notion.New(cfg.Notion.Token,
-		notion.WithRateLimit(cfg.RateLimit.RequestsPerSecond),
-		notion.WithBatchSize(cfg.RateLimit.BatchSize),
-	)
+	// 2. Initialize Notion clients.
+	clients := newNotionClients(cfg)
+
+	if pullPageRef != "" {
+		if len(args) > 0 {
+			return fmt.Errorf("--page cannot be combined with files")
+		}
+		return pullSinglePage(ctx, cfg, db, clients, pullPageRef, pullTo, started)
+	}
+	if pullTo != "" {
+		return fmt.Errorf("--to requires --page")
+	}
+	if pullFlatten && pullPreserveStructure {
+		return fmt.Errorf("--flatten cannot be used with --preserve-structure")
+	}
 
 	// 3. Get pages to pull.
-	pagesToPull, err := getPagesToPull(ctx, cfg, db, client)
+	pagesToPull, archived, err := getPagesToPull(ctx, cfg, db, clients)
 	if err != nil {
 		return fmt.Errorf("get pages to pull: %w", err)
 	}
 
-	// Filter by path pattern if specified.
-	if pullPath != "" {
-		pagesToPull = filterPullByPath(pagesToPull, pullPath)
+	// Filter by path pattern and named files if specified.
+	var notePaths []string
+	if len(args) > 0 {
+		notePaths, err = resolveNotePaths(cfg.Vault, args)
+		if err != nil {
+			return err
+		}
+	}
+	filter := func(pages []pullPage) []pullPage {
+		if pullPath != "" {
+			pages = filterPullByPath(pages, pullPath)
+		}
+		if len(args) > 0 {
+			pages = filterPullByPaths(pages, notePaths)
+		}
+		return pages
+	}
+	pagesToPull = filter(pagesToPull)
+	archived = filter(archived)
+
+	// Leave out push-only paths, reporting them.
+	pagesToPull, blocked := blockPullPages(cfg, pagesToPull)
+
+	// Composed pages are split back into their member notes separately.
+	linkRegistry := state.NewLinkRegistry(db)
+	scanner := newScanner(cfg)
+	comp := newComposer(cfg, db, clients, linkRegistry, newAttachmentUploader(cfg, db, clients, scanner), scanner)
+	composedRules, err := comp.pulledRules(pullPath)
+	if err != nil {
+		return fmt.Errorf("get composed pages: %w", err)
+	}
+	if len(notePaths) > 0 {
+		composedRules = filterComposedRules(cfg, composedRules, notePaths)
 	}
 
-	if len(pagesToPull) == 0 {
+	if len(pagesToPull) == 0 && len(composedRules) == 0 {
 		fmt.Println("No pages to pull.")
+		printBlocked(os.Stdout, blocked)
+		printArchived(os.Stdout, archived)
 		return nil
 	}
 
 	// 4. Check for conflicts.
-	linkRegistry := state.NewLinkRegistry(db)
 	conflicts := checkPullConflicts(pagesToPull)
 	if len(conflicts) > 0 && !pullForce {
 		fmt.Printf("Found %d conflict(s). Use --force to pull anyway, or resolve with 'obsidian-notion conflicts'.\n", len(conflicts))
@@ -113,6 +204,15 @@ func runPull(cmd *cobra.Command, args []string) error {
 				fmt.Printf("  D would %s: %s\n", cfg.Sync.DeletionStrategy, p.localPath)
 			}
 		}
+		for _, rule := range composedRules {
+			fmt.Printf("  C would split if changed: %s -> %s\n", rule.Page, rule.Path)
+		}
+		for _, b := range blocked {
+			fmt.Printf("  ! would block: %s (%s-only)\n", b.path, b.policy)
+		}
+		for _, p := range archived {
+			fmt.Printf("  A archived remotely, would skip: %s\n", p.localPath)
+		}
 		return nil
 	}
 
@@ -128,11 +228,15 @@ func runPull(cmd *cobra.Command, args []string) error {
 	}
 
 	// 6. Process deletions sequentially (state-dependent).
-	var deleted int
+	var deleted, notReached int
 	var failed int32
 	for _, p := range deletions {
+		if ctx.Err() != nil {
+			notReached++
+			continue
+		}
 		if err := handlePullDeletion(cfg, db, linkRegistry, p); err != nil {
-			fmt.Fprintf(os.Stderr, "  Error deleting %s: %v\n", p.localPath, err)
+			printError(os.Stderr, "Error deleting", p.localPath, err)
 			atomic.AddInt32(&failed, 1)
 			continue
 		}
@@ -144,7 +248,15 @@ func runPull(cmd *cobra.Command, args []string) error {
 
 	// 7. Process new/modified pages in parallel.
 	var created, updated int32
-	if len(fetchModify) > 0 {
+	procCtx := &pullContext{
+		cfg:          cfg,
+		db:           db,
+		clients:      clients,
+		linkRegistry: linkRegistry,
+		scanner:      scanner,
+		undo:         newUndoRecorder(cfg, db, linkRegistry, "pull", started),
+	}
+	for len(fetchModify) > 0 {
 		// Initialize worker pool.
 		workers := cfg.RateLimit.Workers
 		if workers < 1 {
@@ -156,22 +268,17 @@ func runPull(cmd *cobra.Command, args []string) error {
 		progress := osync.NewProgress(len(fetchModify), os.Stdout)
 		progress.SetEnabled(!verbose) // Use progress bar only when not verbose
 
-		// Create processing context.
-		procCtx := &pullContext{
-			cfg:          cfg,
-			db:           db,
-			client:       client,
-			linkRegistry: linkRegistry,
-		}
-
-		// Process pages in parallel.
-		results := osync.ProcessWithProgress(ctx, pool, fetchModify, procCtx.processPage, progress.SimpleCallback())
+		// Process pages in parallel, each within the file timeout.
+		processPage := osync.WithTimeout(cfg.Sync.FileTimeoutPeriod(), procCtx.processPage)
+		results := osync.ProcessWithProgress(ctx, pool, fetchModify, processPage, progress.SimpleCallback())
 		progress.Finish()
 
 		// Collect results.
 		for _, result := range results {
-			if result.Err != nil {
-				fmt.Fprintf(os.Stderr, "  Error processing %s: %v\n", result.Input.localPath, result.Err)
+			if stopped(ctx, result.Err) {
+				notReached++
+			} else if result.Err != nil {
+				printError(os.Stderr, "Error processing", result.Input.localPath, result.Err)
 				atomic.AddInt32(&failed, 1)
 			} else if result.Result.isNew {
 				atomic.AddInt32(&created, 1)
@@ -185,11 +292,44 @@ func runPull(cmd *cobra.Command, args []string) error {
 				}
 			}
 		}
+
+		// Databases found nested in the pulled pages have their rows
+		// pulled in turn, into the databases' folders.
+		if ctx.Err() != nil {
+			break
+		}
+		var nestedBlocked []blockedChange
+		fetchModify, nestedBlocked = blockPullPages(cfg, filter(procCtx.nestedRows(ctx)))
+		blocked = append(blocked, nestedBlocked...)
+	}
+
+	// 8. Split changed composed pages back into their member notes.
+	for _, rule := range composedRules {
+		if ctx.Err() != nil {
+			notReached++
+			continue
+		}
+		n, err := comp.pull(ctx, rule, pullAll, nil)
+		if stopped(ctx, err) {
+			notReached++
+			continue
+		}
+		if err != nil {
+			printError(os.Stderr, "Error splitting", rule.Path, err)
+			atomic.AddInt32(&failed, 1)
+			continue
+		}
+		atomic.AddInt32(&updated, int32(n))
+		if verbose && n > 0 {
+			fmt.Printf("  C %s (%d note(s))\n", rule.Path, n)
+		}
 	}
 
+	recordRun(db, clients, "pull", started, 0, int(created+updated)+deleted, int(failed))
+
 	// Print summary.
 	fmt.Println()
-	fmt.Printf("Pull complete:\n")
+	fmt.Println(summaryTitle(ctx, "Pull"))
 	fmt.Printf("  Created: %d\n", created)
 	fmt.Printf("  Updated: %d\n", updated)
 	if deleted > 0 {
@@ -198,10 +338,127 @@ func runPull(cmd *cobra.Command, args []string) error {
 	if failed > 0 {
 		fmt.Printf("  Failed:  %d\n", failed)
 	}
+	printNotReached(notReached, "pull")
+	printBlocked(os.Stdout, blocked)
+	printArchived(os.Stdout, archived)
+	procCtx.reportMissingMentions(os.Stdout)
 
 	return nil
 }
 
+// pullSinglePage pulls a page not yet synced, given by ID or URL, into a
+// new note and tracks it.
+func pullSinglePage(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory, ref, to string, started time.Time) error {
+	pageID, ok := notion.ParsePageID(ref)
+	if !ok {
+		return fmt.Errorf("invalid page: %s (use a page ID or URL)", ref)
+	}
+
+	pc := newPullContext(cfg, db, clients, started)
+	localPath, err := pc.pullNewPage(ctx, pageID, to, pullDryRun, pullForce, pullIncludeArchived)
+	if err != nil {
+		if localPath != "" {
+			recordRun(db, clients, "pull", started, 0, 0, 1)
+			return fmt.Errorf("pull %s: %w", localPath, err)
+		}
+		return err
+	}
+	if pullDryRun {
+		fmt.Printf("  + would create: %s\n", localPath)
+		return nil
+	}
+	recordRun(db, clients, "pull", started, 0, 1, 0)
+	fmt.Printf("  + %s\n", localPath)
+	pc.reportMissingMentions(os.Stdout)
+	return nil
+}
+
+// newPullContext returns the context pages are pulled in, outside a full
+// pull.
+func newPullContext(cfg *config.Config, db *state.DB, clients *notion.Factory, started time.Time) *pullContext {
+	linkRegistry := state.NewLinkRegistry(db)
+	return &pullContext{
+		cfg:          cfg,
+		db:           db,
+		clients:      clients,
+		linkRegistry: linkRegistry,
+		scanner:      newScanner(cfg),
+		undo:         newUndoRecorder(cfg, db, linkRegistry, "pull", started),
+	}
+}
+
+// pullNewPage pulls a page not yet synced into a new note at the path
+// pagePullPath picks for to, and returns the note's path. With dryRun,
+// only the path is returned. An error pulling the page, rather than
+// checking it, comes with the path.
+func (pc *pullContext) pullNewPage(ctx context.Context, pageID, to string, dryRun, force, archived bool) (string, error) {
+	if existing, err := pc.db.GetStateByNotionID(pageID); err != nil {
+		return "", fmt.Errorf("get state: %w", err)
+	} else if existing != nil {
+		return "", fmt.Errorf("page is already synced to %s; run 'obsidian-notion pull' to update it", existing.ObsidianPath)
+	}
+
+	page, err := pc.clients.ForPath(to).GetPage(ctx, pageID)
+	if err != nil {
+		return "", fmt.Errorf("get page: %w", err)
+	}
+	if page.Archived && !archived {
+		return "", fmt.Errorf("page is archived in Notion; use --include-archived to pull it anyway")
+	}
+
+	localPath, err := pagePullPath(pc.cfg, to, extractTitle(page.Properties))
+	if err != nil {
+		return "", err
+	}
+	if !force {
+		if _, err := os.Stat(filepath.Join(pc.cfg.Vault, localPath)); err == nil {
+			return "", fmt.Errorf("%s already exists; use --force to overwrite it", localPath)
+		}
+	}
+	if !pullAllowed(pc.cfg, localPath) {
+		return "", fmt.Errorf("%s is push-only (sync.direction)", localPath)
+	}
+	if dryRun {
+		return localPath, nil
+	}
+
+	_, err = pc.processPage(ctx, pullPage{
+		notionPageID: pageID,
+		localPath:    localPath,
+		notionMtime:  page.LastEditedTime,
+		changeType:   pullChangeNew,
+	})
+	return localPath, err
+}
+
+// pagePullPath returns the note pull --page writes a page to: to, as a
+// markdown note, or a note named after the page's title in to when it is
+// a folder, ending in a slash or existing in the vault. Without to, the
+// note goes at the top of the vault. Notes named after the title never
+// replace an existing note.
+func pagePullPath(cfg *config.Config, to, title string) (string, error) {
+	if title == "" {
+		title = "Untitled"
+	}
+	name := sanitizeFilename(title, cfg.Sync.Filenames) + ".md"
+	if to == "" {
+		return newNoteNames(cfg.Vault).claim(name), nil
+	}
+
+	path := filepath.Clean(to)
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("--to must be a path inside the vault: %s", to)
+	}
+	info, err := os.Stat(filepath.Join(cfg.Vault, path))
+	if strings.HasSuffix(to, "/") || (err == nil && info.IsDir()) {
+		return newNoteNames(cfg.Vault).claim(filepath.Join(path, name)), nil
+	}
+	if filepath.Ext(path) != ".md" {
+		path += ".md"
+	}
+	return path, nil
+}
+
 // pullChangeType represents the type of change for a pull operation.
 type pullChangeType int
 
@@ -218,29 +475,36 @@ type pullPage struct {
 	state        *state.SyncState
 	notionMtime  time.Time
 	changeType   pullChangeType
+	database     string // For new pages, the database they were found in
 }
 
-// getPagesToPull returns the list of pages that need to be pulled.
-func getPagesToPull(ctx context.Context, cfg *config.Config, db *state.DB, client *notion.Client) ([]pullPage, error) {
-	var pages []pullPage
-
-	// Get all synced states.
+// getPagesToPull returns the list of pages that need to be pulled, and the
+// tracked pages left out because they are archived in Notion. Archived
+// pages are marked in the sync state so status can list them; a page
+// restored in Notion is pulled again.
+func getPagesToPull(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory) (pages, archived []pullPage, err error) {
+	// Get all synced states, and those archived by an earlier pull.
 	states, err := db.ListStates("synced")
 	if err != nil {
-		return nil, fmt.Errorf("list synced states: %w", err)
+		return nil, nil, fmt.Errorf("list synced states: %w", err)
 	}
+	archivedStates, err := db.ListStates("archived")
+	if err != nil {
+		return nil, nil, fmt.Errorf("list archived states: %w", err)
+	}
+	states = append(states, archivedStates...)
 
 	// Check each tracked page for changes.
 	for _, s := range states {
-		if s.NotionPageID == "" {
+		if s.NotionPageID == "" || cfg.GetComposition(s.ObsidianPath) != nil {
 			continue
 		}
 
 		// Get current page metadata from Notion.
-		notionPage, err := client.GetPage(ctx, s.NotionPageID)
+		notionPage, err := clients.ForPath(s.ObsidianPath).GetPage(ctx, s.NotionPageID)
 		if err != nil {
 			// Page may have been deleted in Notion.
-			if isNotFoundError(err) {
+			if errors.Is(err, notion.ErrNotFound) {
 				pages = append(pages, pullPage{
 					notionPageID: s.NotionPageID,
 					localPath:    s.ObsidianPath,
@@ -254,9 +518,29 @@ func getPagesToPull(ctx context.Context, cfg *config.Config, db *state.DB, clien
 			continue
 		}
 
-		// Check if page was modified.
+		// Leave archived pages alone unless asked to pull them.
+		if notionPage.Archived && !pullIncludeArchived {
+			if s.Status != "archived" && !pullDryRun {
+				s.Status = "archived"
+				if err := db.SetState(s); err != nil {
+					fmt.Fprintf(os.Stderr, "  Warning: could not mark %s archived: %v\n", s.ObsidianPath, err)
+				}
+			}
+			archived = append(archived, pullPage{
+				notionPageID: s.NotionPageID,
+				localPath:    s.ObsidianPath,
+				state:        s,
+				notionMtime:  notionPage.LastEditedTime,
+				changeType:   pullChangeModified,
+			})
+			continue
+		}
+
+		// Check if page was modified, other than by our own write. A page
+		// archived by an earlier pull is pulled once it is restored or
+		// included.
 		notionMtime := notionPage.LastEditedTime
-		if pullAll || notionMtime.After(s.NotionMtime) {
+		if pullAll || s.Status == "archived" || notionMtime.After(s.NotionMtime) && !ownEdit(ctx, clients.ForPath(s.ObsidianPath), notionPage, s) {
 			pages = append(pages, pullPage{
 				notionPageID: s.NotionPageID,
 				localPath:    s.ObsidianPath,
@@ -267,58 +551,131 @@ func getPagesToPull(ctx context.Context, cfg *config.Config, db *state.DB, clien
 		}
 	}
 
-	// Also check for new pages in the database.
+	// Also check for new pages in the database, and in the databases
+	// nested in pulled pages.
+	names := newNoteNames(cfg.Vault)
 	if cfg.Notion.DefaultDatabase != "" {
-		newPages, err := discoverNewPages(ctx, cfg, db, client)
+		newPages, err := discoverNewPages(ctx, cfg, db, clients, names, cfg.Notion.DefaultDatabase, "", pullLayout(cfg))
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "  Warning: could not discover new pages: %v\n", err)
 		} else {
 			pages = append(pages, newPages...)
 		}
 	}
+	nested, err := db.NestedDatabases()
+	if err != nil {
+		return nil, nil, fmt.Errorf("get nested databases: %w", err)
+	}
+	for _, n := range nested {
+		newPages, err := discoverNewPages(ctx, cfg, db, clients, names, n.DatabaseID, n.Folder, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: could not discover new pages in %s: %v\n", n.Folder, err)
+			continue
+		}
+		pages = append(pages, newPages...)
+	}
 
-	return pages, nil
+	return pages, archived, nil
 }
 
-// discoverNewPages finds pages in Notion that don't exist locally.
-func discoverNewPages(ctx context.Context, cfg *config.Config, db *state.DB, client *notion.Client) ([]pullPage, error) {
+// discoverNewPages finds pages of a database that don't exist locally,
+// naming their notes in folder by a layout (see layoutPath).
+func discoverNewPages(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory, names *noteNames, database, folder, layout string) ([]pullPage, error) {
 	var pages []pullPage
 
-	// Query the default database.
-	resp, err := client.QueryDatabase(ctx, cfg.Notion.DefaultDatabase, nil)
+	// Query the database, following pagination.
+	results, err := clients.ForDatabase(database).QueryAllPages(ctx, database, nil, listingProgress(os.Stderr, cfg.RateLimit.PageSize))
 	if err != nil {
 		return nil, err
 	}
+	byID := make(map[string]*notionapi.Page, len(results))
+	for i := range results {
+		byID[normalizeNotionID(string(results[i].ID))] = &results[i]
+	}
 
-	for _, result := range resp.Results {
+	for _, result := range results {
 		pageID := string(result.ID)
+		if result.Archived && !pullIncludeArchived {
+			continue
+		}
 
 		// Check if we already track this page.
 		existing, _ := db.GetStateByNotionID(pageID)
 		if existing != nil {
 			continue // Already tracked.
 		}
-
-		// Extract title from properties.
-		title := extractTitle(result.Properties)
-		if title == "" {
-			title = "Untitled"
+		if isTarget, _ := db.IsTargetPage(pageID); isTarget {
+			continue // Published from a note's notion-targets.
 		}
 
-		// Generate local path.
-		localPath := sanitizeFilename(title) + ".md"
+		// Generate a local path that no existing note or other new page
+		// uses.
+		localPath := names.claim(filepath.Join(folder, layoutPath(layout, &result, byID, cfg.Sync.Filenames)))
 
 		pages = append(pages, pullPage{
 			notionPageID: pageID,
 			localPath:    localPath,
 			notionMtime:  result.LastEditedTime,
 			changeType:   pullChangeNew,
+			database:     database,
 		})
 	}
 
 	return pages, nil
 }
 
+// listingProgress reports how many results a paginated listing has
+// fetched. Listings that fit in one page print nothing.
+func listingProgress(w io.Writer, pageSize int) notion.ProgressFunc {
+	return func(fetched int) {
+		if fetched > pageSize {
+			fmt.Fprintf(w, "  Listed %d page(s) from Notion...\n", fetched)
+		}
+	}
+}
+
+// printArchived lists the tracked pages a pull left out because they are
+// archived in Notion.
+func printArchived(w io.Writer, archived []pullPage) {
+	if len(archived) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "  Archived remotely: %d (not pulled; use --include-archived)\n", len(archived))
+	for _, p := range archived {
+		fmt.Fprintf(w, "    %s\n", p.localPath)
+	}
+}
+
+// filterPullByPaths keeps the pages of the notes at the given paths.
+func filterPullByPaths(pages []pullPage, paths []string) []pullPage {
+	wanted := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		wanted[path] = true
+	}
+	var filtered []pullPage
+	for _, p := range pages {
+		if wanted[p.localPath] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// filterComposedRules keeps the composition rules covering any of the
+// given paths.
+func filterComposedRules(cfg *config.Config, rules []*config.Composition, paths []string) []*config.Composition {
+	var filtered []*config.Composition
+	for _, rule := range rules {
+		for _, path := range paths {
+			if c := cfg.GetComposition(path); c != nil && c.Page == rule.Page {
+				filtered = append(filtered, rule)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
 // extractTitle extracts the title from Notion page properties.
 func extractTitle(props notionapi.Properties) string {
 	for _, prop := range props {
@@ -331,23 +688,6 @@ func extractTitle(props notionapi.Properties) string {
 	return ""
 }
 
-// sanitizeFilename converts a title to a valid filename.
-func sanitizeFilename(title string) string {
-	// Replace invalid characters.
-	invalid := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
-	result := title
-	for _, char := range invalid {
-		result = strings.ReplaceAll(result, char, "-")
-	}
-	// Trim spaces and dots from ends.
-	result = strings.TrimSpace(result)
-	result = strings.Trim(result, ".")
-	if result == "" {
-		result = "Untitled"
-	}
-	return result
-}
-
 // filterPullByPath filters pages by a glob pattern.
 func filterPullByPath(pages []pullPage, pattern string) []pullPage {
 	var filtered []pullPage
@@ -405,34 +745,25 @@ func handlePullDeletion(cfg *config.Config, db *state.DB, linkRegistry *state.Li
 		return fmt.Errorf("unknown deletion strategy: %s", strategy)
 	}
 
-	// Remove from sync state.
-	if err := db.DeleteState(p.localPath); err != nil {
-		return fmt.Errorf("delete state: %w", err)
-	}
-
-	// Clear links from this file.
-	_ = linkRegistry.ClearLinksFrom(p.localPath)
-
-	return nil
-}
-
-// isNotFoundError checks if an error is a "not found" error from Notion.
-func isNotFoundError(err error) bool {
-	if err == nil {
-		return false
-	}
-	errStr := err.Error()
-	return strings.Contains(errStr, "not found") ||
-		strings.Contains(errStr, "Could not find") ||
-		strings.Contains(errStr, "404")
+	// Remove sync state, links, and attachment references.
+	return forgetNote(db, p.localPath)
 }
 
 // pullContext holds shared dependencies for parallel page processing.
 type pullContext struct {
 	cfg          *config.Config
 	db           *state.DB
-	client       *notion.Client
+	clients      *notion.Factory
 	linkRegistry *state.LinkRegistry
+	scanner      *vault.Scanner
+	undo         *undoRecorder
+
+	// mu guards nested, the databases found nested in pulled pages for
+	// the first time, and missing, the pages pulled pages mention that
+	// are not in the vault.
+	mu      sync.Mutex
+	nested  []state.NestedDatabase
+	missing []string
 }
 
 // pullResult holds the result of processing a single page.
@@ -442,14 +773,24 @@ type pullResult struct {
 
 // processPage processes a single page for pull (fetch, transform, write).
 func (pc *pullContext) processPage(ctx context.Context, p pullPage) (pullResult, error) {
-	// Fetch full page content from Notion.
-	notionPage, err := pc.client.FetchPage(ctx, p.notionPageID)
+	// Fetch full page content from Notion. Newly discovered pages come from
+	// a database and are read with that database's integration.
+	client := pc.clients.ForPath(p.localPath)
+	if p.database != "" {
+		client = pc.clients.ForDatabase(p.database)
+	}
+	notionPage, err := fetchNotePage(ctx, client, pc.db, p.localPath, p.notionPageID)
 	if err != nil {
 		return pullResult{}, fmt.Errorf("fetch page: %w", err)
 	}
+	pc.takeNestedDatabases(p.localPath, notionPage)
 
 	// Create reverse transformer with path-specific property mappings.
-	rt := transformer.NewReverse(pc.linkRegistry, buildTransformerConfig(pc.cfg, p.localPath))
+	mentions := &mentionRecorder{PathLookup: pc.linkRegistry}
+	rt := transformer.NewReverse(mentions, buildTransformerConfig(pc.cfg, p.localPath))
+	rt.SetAttachmentSaver(newAttachmentDownloader(ctx, pc.cfg, pc.scanner, p.localPath))
+	rt.SetBookmarkPreviewer(newBookmarkPreviewer(ctx, pc.cfg, p.localPath))
+	rt.SetUserNamer(newUserNamer(ctx, client))
 
 	// Transform to markdown.
 	markdown, err := rt.NotionToMarkdown(notionPage)
@@ -457,6 +798,8 @@ func (pc *pullContext) processPage(ctx context.Context, p pullPage) (pullResult,
 		return pullResult{}, fmt.Errorf("transform to markdown: %w", err)
 	}
 
+	pc.undo.pulled(p.localPath, p.notionPageID, p.state)
+
 	// Ensure directory exists.
 	fullPath := filepath.Join(pc.cfg.Vault, p.localPath)
 	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
@@ -469,7 +812,7 @@ func (pc *pullContext) processPage(ctx context.Context, p pullPage) (pullResult,
 	}
 
 	// Update sync state.
-	contentHash, _ := state.HashFile(fullPath)
+	contentHash, _ := noteHasher(pc.cfg).HashFile(fullPath)
 	fileInfo, _ := os.Stat(fullPath)
 	var mtime time.Time
 	if fileInfo != nil {
@@ -493,6 +836,10 @@ func (pc *pullContext) processPage(ctx context.Context, p pullPage) (pullResult,
 	if err := pc.db.SetState(syncState); err != nil {
 		return pullResult{}, fmt.Errorf("update state: %w", err)
 	}
+	if err := recordBlockMap(pc.db, p.localPath, notionPage); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to record blocks for %s: %v\n", p.localPath, err)
+	}
+	pc.takeMissingMentions(p.localPath, mentions.missed)
 
 	return pullResult{isNew: p.changeType == pullChangeNew}, nil
 }
@@ -2,13 +2,17 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/jomei/notionapi"
 	"github.com/spf13/cobra"
 
 	"github.com/adamancini/obsidian-notion-sync/internal/config"
@@ -25,23 +29,94 @@ var (
 	pushPath   string
 	pushDryRun bool
 	pushForce  bool
+	pushStaged bool
+
+	pushConfirmDeletions bool
+	pushShowEstimate     bool
+	pushMaxRequests      int
+	pushNoMatch          bool
+	pushVerify           bool
+	pushTags             []string
+	pushExcludeTags      []string
+	pushParent           string
+	pushMarkLocked       bool
+
+	pushProfileCPU string
+	pushProfileMem string
 )
 
 // pushCmd represents the push command.
 var pushCmd = &cobra.Command{
-	Use:   "push",
+	Use:   "push [files...]",
 	Short: "Push local changes to Notion",
 	Long: `Push local Obsidian changes to Notion.
 
 By default, only pushes files that have changed since the last sync.
-Use --all to push all files regardless of change detection.
+Use --all to push all files regardless of change detection. Files named
+as arguments, relative to the current directory or the vault root, limit
+the push to those notes.
 
 Examples:
   obsidian-notion push                    # Push all changed files
+  obsidian-notion push notes/today.md     # Push one note if it changed
   obsidian-notion push --all              # Push all files
   obsidian-notion push --path "work/**"   # Push files matching pattern
-  obsidian-notion push --dry-run          # Show what would be pushed`,
-	RunE: runPush,
+  obsidian-notion push --tag publish      # Push notes tagged #publish
+  obsidian-notion push --exclude-tag private
+  obsidian-notion push --dry-run          # Show what would be pushed
+  obsidian-notion push --staged           # Publish new pages only if all succeed
+  obsidian-notion push --estimate         # Estimate API requests and time
+  obsidian-notion push --max-requests 500 # Stop after 500 API requests
+  obsidian-notion push --verify           # Read pages back to check them
+  obsidian-notion push notes/standup.md --parent https://www.notion.so/Meetings-1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d
+
+--tag keeps the notes carrying any of the given tags, in their text or
+their frontmatter tags, and --exclude-tag leaves out those carrying any
+of them. Both can be repeated or given a comma-separated list. Nested
+tags match their parents: --tag project keeps notes tagged
+#project/alpha. Deleted notes have no tags left to match, so their pages
+are only archived by a push without --tag.
+
+With --staged, new pages are first built in notion.staging_database and
+moved to their target database only after every page has been built. If
+any page fails, the staged pages are archived and nothing is published.
+Updates to existing pages, renames, and deletions run after publishing.
+
+--parent creates the pages of new notes under the given page, by ID or
+URL, instead of their database or notion.default_page, for one-off
+destinations such as a meeting's page. Only the title is kept as a
+property there. The parent is recorded in the sync state; notes that
+already have pages keep them where they are.
+
+If more notes were deleted than sync.max_deletions_per_run (default 25),
+the push stops before changing anything and lists them. Rerun with
+--confirm-deletions to archive their pages, or use 'obsidian-notion
+restore' to bring back pages archived by mistake.
+
+--estimate converts the changed notes offline and reports the API
+requests, blocks, and uploads the push would make, and how long that
+takes at rate_limit.requests_per_second. With --max-requests, a change is
+only started if its estimated requests fit in what is left of the budget;
+the rest stay pending for the next push, which picks up where this one
+stopped.
+
+The first push into databases that already hold pages matches new notes
+to those pages by title and content, and asks before linking each group
+of matches, so the push updates them instead of creating duplicates. See
+'obsidian-notion match'; --no-match skips this.
+
+--verify reads each pushed page back from Notion, converts it to
+markdown, and compares it with what was pushed, ignoring blank lines and
+frontmatter. A page that differs, for example because Notion truncated
+or changed its content, is reported and its note marked degraded in the
+sync state; the next push sends it again.
+
+Notes whose pages are locked in Notion are skipped and listed with who
+locked them, rather than failing. With --force-unlock-warning they are
+also marked blocked in the sync state: later pushes check the lock before
+sending them, and push them once the pages are unlocked.`,
+	ValidArgsFunction: completeNotePaths,
+	RunE:              runPush,
 }
 
 func init() {
@@ -49,6 +124,20 @@ func init() {
 	pushCmd.Flags().StringVar(&pushPath, "path", "", "glob pattern to filter files")
 	pushCmd.Flags().BoolVar(&pushDryRun, "dry-run", false, "show what would be pushed without making changes")
 	pushCmd.Flags().BoolVar(&pushForce, "force", false, "force push even if there are conflicts")
+	pushCmd.Flags().StringSliceVar(&pushTags, "tag", nil, "push only notes with any of these tags")
+	pushCmd.Flags().StringSliceVar(&pushExcludeTags, "exclude-tag", nil, "leave out notes with any of these tags")
+	pushCmd.Flags().BoolVar(&pushStaged, "staged", false, "build new pages in the staging database and publish them only if all succeed")
+	pushCmd.Flags().BoolVar(&pushConfirmDeletions, "confirm-deletions", false, "archive pages even if more notes were deleted than sync.max_deletions_per_run")
+	pushCmd.Flags().BoolVar(&pushShowEstimate, "estimate", false, "estimate the API requests and time of the push without making changes")
+	pushCmd.Flags().IntVar(&pushMaxRequests, "max-requests", 0, "stop the push before it makes more than this many API requests (0 for no limit)")
+	pushCmd.Flags().BoolVar(&pushNoMatch, "no-match", false, "on the first push, create new pages without matching notes to existing ones")
+	pushCmd.Flags().BoolVar(&pushVerify, "verify", false, "read each pushed page back and mark notes whose content differs as degraded")
+	pushCmd.Flags().BoolVar(&pushMarkLocked, "force-unlock-warning", false, "mark notes whose pages are locked in Notion as blocked in the sync state")
+	pushCmd.Flags().StringVar(&pushParent, "parent", "", "create new pages under this page (ID or URL) instead of their configured parent")
+	pushCmd.Flags().StringVar(&pushProfileCPU, "profile-cpu", "", "write a CPU profile of the push to this file")
+	pushCmd.Flags().StringVar(&pushProfileMem, "profile-mem", "", "write a memory profile at the end of the push to this file")
+	_ = pushCmd.Flags().MarkHidden("profile-cpu")
+	_ = pushCmd.Flags().MarkHidden("profile-mem")
 }
 
 func runPush(cmd *cobra.Command, args []string) error {
@@ -57,7 +146,29 @@ func runPush(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	if pushStaged && cfg.Notion.StagingDatabase == "" {
+		return fmt.Errorf("--staged requires notion.staging_database to be set")
+	}
+	var parentPage string
+	if pushParent != "" {
+		id, ok := notion.ParsePageID(pushParent)
+		if !ok {
+			return fmt.Errorf("invalid --parent: %s (use a page ID or URL)", pushParent)
+		}
+		if pushStaged {
+			return fmt.Errorf("--parent cannot be used with --staged")
+		}
+		parentPage = id
+	}
+
+	stopProfiles, err := startProfiles(pushProfileCPU, pushProfileMem)
+	if err != nil {
+		return err
+	}
+	defer stopProfiles()
+
+	started := time.Now()
+	ctx, cancel := runContext(30 * time.Minute)
 	defer cancel()
 
 	// 1. Open state database.
@@ -67,12 +178,23 @@ func runPush(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
 	}
 	defer db.Close()
+	if err := useNestedDatabases(cfg, db); err != nil {
+		return err
+	}
 
-	// 2. Initialize Notion client.
-	client := notion.New(cfg.Notion.Token,
-		notion.WithRateLimit(cfg.RateLimit.RequestsPerSecond),
-		notion.WithBatchSize(cfg.RateLimit.BatchSize),
-	)
+	// 2. Initialize Notion clients, sharing the request budget if set.
+	var budget *notion.Budget
+	if pushMaxRequests > 0 {
+		budget = notion.NewBudget(int64(pushMaxRequests))
+	}
+	var clientOpts []notion.ClientOption
+	if budget != nil {
+		clientOpts = append(clientOpts, notion.WithBudget(budget))
+	}
+	clients := newNotionClients(cfg, clientOpts...)
+	if !pushDryRun && !pushShowEstimate {
+		defer reportMirror(ctx, cfg, db, clients)
+	}
 
 	// 3. Get files to push.
 	filesToPush, err := getFilesToPush(ctx, cfg, db)
@@ -80,16 +202,60 @@ func runPush(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("get files to push: %w", err)
 	}
 
-	// Filter by path pattern if specified.
+	// Filter by path pattern and named files if specified.
 	if pushPath != "" {
-		filesToPush = filterByPath(filesToPush, pushPath)
+		filesToPush = explainFiltered(filesToPush, filterByPath(filesToPush, pushPath), "does not match --path "+pushPath)
+	}
+	if len(args) > 0 {
+		paths, err := resolveNotePaths(cfg.Vault, args)
+		if err != nil {
+			return err
+		}
+		filesToPush = explainFiltered(filesToPush, filterByPaths(filesToPush, paths), "not named on the command line")
+	}
+	if len(pushTags) > 0 || len(pushExcludeTags) > 0 {
+		filesToPush = explainFiltered(filesToPush, filterByTags(cfg.Vault, filesToPush, pushTags, pushExcludeTags), "left out by --tag or --exclude-tag")
+	}
+
+	// Leave out notes that are too large or binary.
+	scanner := newScanner(cfg)
+	filesToPush, skipped := skipUnsyncable(cfg, scanner, filesToPush)
+
+	// Leave out pull-only paths, reporting them.
+	filesToPush, blocked := blockPushFiles(cfg, filesToPush)
+
+	// Notes covered by a composition rule are pushed as part of their
+	// composed page rather than individually.
+	composed, filesToPush := splitComposed(cfg, filesToPush)
+	for _, f := range composed {
+		explainFile(f.path, fmt.Sprintf("composed: pushed as part of %s", cfg.GetComposition(composedPath(cfg, f)).Page))
 	}
 
-	if len(filesToPush) == 0 {
+	if len(filesToPush) == 0 && len(composed) == 0 {
 		fmt.Println("No files to push.")
+		printSkipped(skipped)
+		printBlocked(os.Stdout, blocked)
 		return nil
 	}
 
+	// On the first push, link new notes to the pages they were copied from
+	// rather than duplicating them.
+	if !pushNoMatch && !pushDryRun && !pushShowEstimate {
+		first, err := isFirstPush(db)
+		if err != nil {
+			return fmt.Errorf("list sync state: %w", err)
+		}
+		if first {
+			if filesToPush, err = matchFirstPush(ctx, cfg, db, clients, filesToPush); err != nil {
+				return err
+			}
+			if len(filesToPush) == 0 && len(composed) == 0 {
+				fmt.Println("No files to push.")
+				return nil
+			}
+		}
+	}
+
 	// 4. Check for conflicts.
 	linkRegistry := state.NewLinkRegistry(db)
 	conflicts := checkConflicts(filesToPush)
@@ -101,27 +267,84 @@ func runPush(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("aborting due to conflicts")
 	}
 
-	fmt.Printf("Pushing %d change(s) to Notion...\n", len(filesToPush))
+	// Stop a mass deletion before anything changes.
+	var trashPaths []string
+	for _, f := range filesToPush {
+		if f.changeType == state.ChangeDeleted && trashesPage(cfg, f.state) {
+			trashPaths = append(trashPaths, f.path)
+		}
+	}
+	if !pushDryRun {
+		if err := guardDeletions(os.Stdout, cfg, trashPaths, pushConfirmDeletions); err != nil {
+			return err
+		}
+	}
+
+	if pushShowEstimate {
+		estimator := &pushContext{
+			cfg:          cfg,
+			db:           db,
+			clients:      clients,
+			linkRegistry: linkRegistry,
+			attachments:  newAttachmentUploader(cfg, db, clients, scanner),
+			parser:       parser.New(),
+			scanner:      scanner,
+		}
+		var total pushEstimate
+		for _, f := range filesToPush {
+			est, err := estimator.estimateFile(f)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: cannot estimate %s: %v\n", f.path, err)
+				continue
+			}
+			total.add(est)
+		}
+		printEstimate(os.Stdout, total, cfg.RateLimit.RequestsPerSecond, pushMaxRequests, len(composed))
+		return nil
+	}
+
+	fmt.Printf("Pushing %d change(s) to Notion...\n", len(filesToPush)+len(composed))
 	if pushDryRun {
 		fmt.Println("(dry-run mode - no changes will be made)")
 		for _, f := range filesToPush {
 			switch f.changeType {
 			case state.ChangeCreated:
-				fmt.Printf("  + would create: %s\n", f.path)
+				if pushStaged {
+					fmt.Printf("  + would stage and publish: %s\n", f.path)
+				} else {
+					fmt.Printf("  + would create: %s\n", f.path)
+				}
 			case state.ChangeModified:
-				fmt.Printf("  M would update: %s\n", f.path)
+				if f.retitled {
+					fmt.Printf("  T would retitle: %s\n", f.path)
+				} else {
+					fmt.Printf("  M would update: %s\n", f.path)
+				}
 			case state.ChangeRenamed:
 				fmt.Printf("  R would rename: %s -> %s\n", f.oldPath, f.path)
 			case state.ChangeDeleted:
 				fmt.Printf("  D would %s: %s\n", cfg.Sync.DeletionStrategy, f.path)
 			}
 		}
+		for _, f := range composed {
+			fmt.Printf("  C would compose: %s -> %s\n", f.path, cfg.GetComposition(composedPath(cfg, f)).Page)
+		}
+		for _, f := range skipped {
+			fmt.Printf("  - would skip: %s (%s)\n", f.path, f.reason)
+		}
+		for _, b := range blocked {
+			fmt.Printf("  ! would block: %s (%s-only)\n", b.path, b.policy)
+		}
+		if err := guardDeletions(io.Discard, cfg, trashPaths, pushConfirmDeletions); err != nil {
+			fmt.Printf("  ! %v\n", err)
+		}
 		return nil
 	}
 
 	// 5. Separate files by change type for processing.
 	var deletions, renames, createModify []pushFile
 	for _, f := range filesToPush {
+		explainFile(f.path, explainRoute(cfg, f))
 		switch f.changeType {
 		case state.ChangeDeleted:
 			deletions = append(deletions, f)
@@ -132,12 +355,83 @@ func runPush(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// 6. Process deletions and renames sequentially (state-dependent).
-	var renamed, deleted int
+	attachments := newAttachmentUploader(cfg, db, clients, scanner)
+	workers := cfg.RateLimit.Workers
+	if workers < 1 {
+		workers = 4
+	}
+	procCtx := &pushContext{
+		cfg:          cfg,
+		db:           db,
+		clients:      clients,
+		linkRegistry: linkRegistry,
+		attachments:  attachments,
+		parser:       parser.New(),
+		scanner:      scanner,
+		undo:         newUndoRecorder(cfg, db, linkRegistry, "push", started),
+		budget:       budget,
+		verify:       pushVerify,
+		templates:    &templateCache{},
+		parentPage:   parentPage,
+	}
+
+	// 6. In staged mode, build and publish new pages before touching
+	// anything readers can already see.
+	var created, updated, degraded int32
+	var results []osync.Task[pushFile, pushResult] // Store results for second pass
+	if pushStaged {
+		var creates []pushFile
+		creates, createModify = splitNewPages(createModify)
+		if len(creates) > 0 {
+			staged, err := pushStagedPages(ctx, procCtx, workers, creates)
+			if err != nil {
+				return err
+			}
+			results = append(results, staged...)
+			created = int32(len(staged))
+			for _, r := range staged {
+				if r.Result.degraded {
+					degraded++
+				}
+			}
+			if verbose {
+				for _, r := range staged {
+					fmt.Printf("  + %s (page: %s)\n", r.Input.path, r.Result.pageID)
+				}
+			}
+		}
+	}
+
+	// 7. Process deletions and renames sequentially (state-dependent).
+	pushPaths := make([]string, len(createModify))
+	for i, f := range createModify {
+		pushPaths[i] = f.path
+	}
+	linkRepair := newLinkRepairer(cfg, db, clients, linkRegistry, attachments, pushPaths)
+
+	var renamed, deleted, overBudget, badProperties, notReached int
 	var failed int32
+	var locked []lockedPage
 	for _, f := range deletions {
-		if err := handleDeletion(ctx, cfg, db, client, linkRegistry, f); err != nil {
-			fmt.Fprintf(os.Stderr, "  Error deleting %s: %v\n", f.path, err)
+		if ctx.Err() != nil {
+			notReached++
+			continue
+		}
+		release, err := procCtx.reserve(f)
+		if errors.Is(err, errOverBudget) {
+			overBudget++
+			continue
+		}
+		if err == nil {
+			err = handleDeletion(ctx, cfg, db, clients, linkRegistry, f)
+			release()
+		}
+		if stopped(ctx, err) {
+			notReached++
+			continue
+		}
+		if err != nil {
+			printError(os.Stderr, "Error deleting", f.path, err)
 			atomic.AddInt32(&failed, 1)
 			continue
 		}
@@ -148,8 +442,25 @@ func runPush(cmd *cobra.Command, args []string) error {
 	}
 
 	for _, f := range renames {
-		if err := handleRename(ctx, cfg, db, client, linkRegistry, f); err != nil {
-			fmt.Fprintf(os.Stderr, "  Error renaming %s: %v\n", f.oldPath, err)
+		if ctx.Err() != nil {
+			notReached++
+			continue
+		}
+		release, err := procCtx.reserve(f)
+		if errors.Is(err, errOverBudget) {
+			overBudget++
+			continue
+		}
+		if err == nil {
+			err = handleRename(ctx, cfg, db, clients, linkRegistry, f)
+			release()
+		}
+		if stopped(ctx, err) {
+			notReached++
+			continue
+		}
+		if err != nil {
+			printError(os.Stderr, "Error renaming", f.oldPath, err)
 			atomic.AddInt32(&failed, 1)
 			continue
 		}
@@ -157,42 +468,43 @@ func runPush(cmd *cobra.Command, args []string) error {
 		if verbose {
 			fmt.Printf("  R %s -> %s\n", f.oldPath, f.path)
 		}
+		linkRepair.renamed(ctx, f.oldPath, f.path)
 	}
 
-	// 7. Process creates/modifies in parallel.
-	var created, updated int32
-	var results []osync.Task[pushFile, pushResult] // Store results for second pass
-
+	// 8. Process creates/modifies in parallel.
 	if len(createModify) > 0 {
 		// Initialize worker pool.
-		workers := cfg.RateLimit.Workers
-		if workers < 1 {
-			workers = 4
-		}
 		pool := osync.NewWorkerPool(workers)
 
 		// Initialize progress reporter.
 		progress := osync.NewProgress(len(createModify), os.Stdout)
 		progress.SetEnabled(!verbose) // Use progress bar only when not verbose
 
-		// Create processing context.
-		procCtx := &pushContext{
-			cfg:          cfg,
-			db:           db,
-			client:       client,
-			linkRegistry: linkRegistry,
-			parser:       parser.New(),
-			scanner:      vault.NewScanner(cfg.Vault, cfg.Sync.Ignore),
-		}
-
-		// Process files in parallel.
-		results = osync.ProcessWithProgress(ctx, pool, createModify, procCtx.processFile, progress.SimpleCallback())
+		// Process files in parallel, each within the file timeout.
+		processFile := osync.WithTimeout(cfg.Sync.FileTimeoutPeriod(), procCtx.processFile)
+		batch := osync.ProcessWithProgress(ctx, pool, createModify, processFile, progress.SimpleCallback())
 		progress.Finish()
+		results = append(results, batch...)
 
 		// Collect results.
-		for _, result := range results {
-			if result.Err != nil {
-				fmt.Fprintf(os.Stderr, "  Error processing %s: %v\n", result.Input.path, result.Err)
+		for _, result := range batch {
+			if result.Err == nil && result.Result.degraded {
+				atomic.AddInt32(&degraded, 1)
+			}
+			if errors.Is(result.Err, errOverBudget) {
+				overBudget++
+			} else if stopped(ctx, result.Err) {
+				notReached++
+			} else if errors.Is(result.Err, notion.ErrLocked) {
+				printLocked(os.Stderr, result.Input.path, result.Err)
+				locked = append(locked, lockedPage{path: result.Input.path, err: result.Err})
+				if pushMarkLocked {
+					markBlocked(db, result.Input.state)
+				}
+			} else if result.Err != nil {
+				if printPushError(os.Stderr, "Error processing", result.Input.path, result.Err) {
+					badProperties++
+				}
 				atomic.AddInt32(&failed, 1)
 			} else if result.Result.isNew {
 				atomic.AddInt32(&created, 1)
@@ -201,14 +513,56 @@ func runPush(cmd *cobra.Command, args []string) error {
 				}
 			} else {
 				atomic.AddInt32(&updated, 1)
-				if verbose {
+				if verbose && result.Result.retitled {
+					fmt.Printf("  T %s\n", result.Input.path)
+				} else if verbose {
 					fmt.Printf("  M %s\n", result.Input.path)
 				}
 			}
 		}
 	}
 
-	// 8. Second pass: resolve wiki-links and update pages.
+	// Rebuild composed pages whose member notes changed.
+	var composedCount int
+	if len(composed) > 0 {
+		comp := newComposer(cfg, db, clients, linkRegistry, attachments, scanner)
+		paths := make([]string, len(composed))
+		for i, f := range composed {
+			paths[i] = composedPath(cfg, f)
+		}
+		for _, rule := range comp.rulesFor(paths) {
+			if ctx.Err() != nil {
+				notReached++
+				continue
+			}
+			n, err := comp.push(ctx, rule)
+			if stopped(ctx, err) {
+				notReached++
+				continue
+			}
+			if err != nil {
+				printError(os.Stderr, "Error composing", rule.Path, err)
+				atomic.AddInt32(&failed, 1)
+				continue
+			}
+			composedCount += n
+			if verbose {
+				fmt.Printf("  C %s (%d note(s), page: %s)\n", rule.Path, n, rule.Page)
+			}
+		}
+	}
+
+	// Create stubs for the links to notes that do not exist, so they
+	// resolve in the second pass.
+	var stubs []string
+	if cfg.Links.AutoCreateStubs && ctx.Err() == nil {
+		var stubErrors int
+		stubs, stubErrors = procCtx.createStubs(ctx, results)
+		created += int32(len(stubs))
+		failed += int32(stubErrors)
+	}
+
+	// 9. Second pass: resolve wiki-links and update pages.
 	// First resolve all links in the database. This may resolve forward references
 	// where A links to B, but B was processed after A.
 	resolvedCount, resolveErr := linkRegistry.ResolveAll()
@@ -216,93 +570,48 @@ func runPush(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "  Warning: partial link resolution failure: %v\n", resolveErr)
 	}
 
-	// Collect ALL pages with wiki-links for second pass update (not just new ones).
+	// Collect the pages pushed with placeholders for the link fixup pass.
 	// Modified files may have wiki-links to newly created pages that need resolution.
-	var pagesNeedingLinkUpdate []pushFile
+	var pagesNeedingLinkUpdate []osync.Task[pushFile, pushResult]
 	for _, result := range results {
-		if result.Err == nil && result.Result.hasWikiLinks {
-			pagesNeedingLinkUpdate = append(pagesNeedingLinkUpdate, result.Input)
+		if result.Err == nil && len(result.Result.placeholders) > 0 {
+			pagesNeedingLinkUpdate = append(pagesNeedingLinkUpdate, result)
 		}
 	}
 
-	// Update pages with resolved wiki-links if any links were newly resolved.
+	// Patch the mentions of placeholders that resolve now.
 	var linkUpdates int32
 	var linkUpdateErrors int32
-	if len(pagesNeedingLinkUpdate) > 0 && resolvedCount > 0 {
+	if len(pagesNeedingLinkUpdate) > 0 && resolvedCount > 0 && ctx.Err() == nil {
 		if verbose {
 			fmt.Printf("  Updating %d page(s) with resolved wiki-links...\n", len(pagesNeedingLinkUpdate))
 		}
 
-		// Re-process files to update with resolved links.
-		for _, f := range pagesNeedingLinkUpdate {
-			syncState, err := db.GetState(f.path)
-			if err != nil {
-				if verbose {
-					fmt.Fprintf(os.Stderr, "  Warning: cannot get state for %s: %v\n", f.path, err)
-				}
-				atomic.AddInt32(&linkUpdateErrors, 1)
-				continue
-			}
-			if syncState == nil {
-				continue
-			}
-
-			// Check if this file has any links that were just resolved.
-			// Skip update if all links from this file were already resolved before.
-			hasNewlyResolvedLinks, err := checkForNewlyResolvedLinks(linkRegistry, f.path)
-			if err != nil {
-				if verbose {
-					fmt.Fprintf(os.Stderr, "  Warning: cannot check links for %s: %v\n", f.path, err)
-				}
-			}
-			if !hasNewlyResolvedLinks {
-				continue // No need to update this page
-			}
-
-			// Re-read and re-transform with links now resolvable.
-			fullPath := filepath.Join(cfg.Vault, f.path)
-			content, err := os.ReadFile(fullPath)
-			if err != nil {
-				if verbose {
-					fmt.Fprintf(os.Stderr, "  Warning: cannot read %s: %v\n", f.path, err)
-				}
-				atomic.AddInt32(&linkUpdateErrors, 1)
-				continue
-			}
-
-			p := parser.New()
-			note, err := p.Parse(f.path, content)
-			if err != nil {
-				if verbose {
-					fmt.Fprintf(os.Stderr, "  Warning: cannot parse %s: %v\n", f.path, err)
-				}
-				atomic.AddInt32(&linkUpdateErrors, 1)
-				continue
+		for _, result := range pagesNeedingLinkUpdate {
+			updated, err := procCtx.fixLinks(ctx, result.Input.path, result.Result.placeholders)
+			if stopped(ctx, err) {
+				break
 			}
-
-			t := transformer.New(linkRegistry, buildTransformerConfig(cfg, f.path))
-
-			notionPage, err := t.Transform(note)
 			if err != nil {
 				if verbose {
-					fmt.Fprintf(os.Stderr, "  Warning: cannot transform %s: %v\n", f.path, err)
+					fmt.Fprintf(os.Stderr, "  Warning: %v\n", err)
 				}
 				atomic.AddInt32(&linkUpdateErrors, 1)
 				continue
 			}
-
-			// Update the page with resolved wiki-links.
-			if err := client.UpdatePage(ctx, syncState.NotionPageID, notionPage); err != nil {
-				if verbose {
-					fmt.Fprintf(os.Stderr, "  Warning: failed to update links in %s: %v\n", f.path, err)
-				}
-				atomic.AddInt32(&linkUpdateErrors, 1)
-				continue
+			if updated {
+				atomic.AddInt32(&linkUpdates, 1)
 			}
-			atomic.AddInt32(&linkUpdates, 1)
 		}
 	}
 
+	// 10. Re-push notes whose links pointed at a renamed note.
+	if ctx.Err() == nil {
+		repaired, repairErrors := linkRepair.flush(ctx)
+		linkUpdates += int32(repaired)
+		linkUpdateErrors += int32(repairErrors)
+	}
+
 	if verbose && (resolvedCount > 0 || linkUpdates > 0) {
 		fmt.Printf("  Resolved %d wiki-links, updated %d page(s)\n", resolvedCount, linkUpdates)
 		if linkUpdateErrors > 0 {
@@ -310,9 +619,11 @@ func runPush(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	recordRun(db, clients, "push", started, int(created+updated)+renamed+deleted+composedCount, 0, int(failed))
+
 	// Print summary.
 	fmt.Println()
-	fmt.Printf("Push complete:\n")
+	fmt.Println(summaryTitle(ctx, "Push"))
 	fmt.Printf("  Created: %d\n", created)
 	fmt.Printf("  Updated: %d\n", updated)
 	if renamed > 0 {
@@ -321,55 +632,107 @@ func runPush(cmd *cobra.Command, args []string) error {
 	if deleted > 0 {
 		fmt.Printf("  Deleted: %d\n", deleted)
 	}
+	if composedCount > 0 {
+		fmt.Printf("  Composed: %d\n", composedCount)
+	}
+	if len(stubs) > 0 {
+		fmt.Printf("  Stubs: %d (created for links to missing notes)\n", len(stubs))
+		for _, path := range stubs {
+			fmt.Printf("    + %s\n", path)
+		}
+	}
 	if failed > 0 {
 		fmt.Printf("  Failed:  %d\n", failed)
 	}
+	if degraded > 0 {
+		fmt.Printf("  Degraded: %d (did not read back as pushed; push again to retry)\n", degraded)
+	}
+	if overBudget > 0 {
+		fmt.Printf("  Pending: %d (--max-requests %d reached; push again to continue)\n", overBudget, pushMaxRequests)
+	}
+	printLockedSummary(os.Stdout, locked, pushMarkLocked)
+	printNotReached(notReached, "push")
+	printSkipped(append(skipped, attachments.skippedFiles()...))
+	printBlocked(os.Stdout, blocked)
 
+	if badProperties > 0 {
+		return propertyValuesError(badProperties)
+	}
 	return nil
 }
 
+// printPushError reports a note that failed to push, listing property
+// errors one per line. It reports whether err holds property errors.
+func printPushError(w io.Writer, prefix, path string, err error) bool {
+	var propErrs transformer.PropertyErrors
+	if !errors.As(err, &propErrs) {
+		printError(w, prefix, path, err)
+		return false
+	}
+	fmt.Fprintf(w, "  %s %s: %d frontmatter value(s) do not fit their Notion properties\n", prefix, path, len(propErrs))
+	for _, e := range propErrs {
+		fmt.Fprintf(w, "    - %v\n", e)
+	}
+	return true
+}
+
+// propertyValuesError is the error a push ends with when notes were not
+// pushed for their property values, so it exits non-zero.
+func propertyValuesError(notes int) error {
+	return fmt.Errorf("%d note(s) have frontmatter values that do not fit their Notion properties; fix them and push again", notes)
+}
+
 // handleDeletion processes a file deletion based on the configured strategy.
-func handleDeletion(ctx context.Context, cfg *config.Config, db *state.DB, client *notion.Client, linkRegistry *state.LinkRegistry, f pushFile) error {
+func handleDeletion(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory, linkRegistry *state.LinkRegistry, f pushFile) error {
 	if f.state == nil || f.state.NotionPageID == "" {
 		// No Notion page to delete, just clean up state.
 		return db.DeleteState(f.path)
 	}
 
-	strategy := cfg.Sync.DeletionStrategy
-	if strategy == "" {
-		strategy = "archive" // Default to archive.
+	if err := trashNotePage(ctx, cfg, db, clients, f.path, f.state); err != nil {
+		return err
 	}
 
-	switch strategy {
-	case "archive":
-		// Archive the Notion page.
-		if err := client.ArchivePage(ctx, f.state.NotionPageID); err != nil {
-			return fmt.Errorf("archive page: %w", err)
+	// Remove sync state, links, and attachment references.
+	return forgetNote(db, f.path)
+}
+
+// forgetNote removes the sync state, links, and attachment references of a
+// deleted note in one transaction.
+func forgetNote(db *state.DB, path string) error {
+	return db.InTx(func(tx *state.DB) error {
+		if err := tx.DeleteState(path); err != nil {
+			return fmt.Errorf("delete state: %w", err)
 		}
-	case "delete":
-		// Delete the Notion page (actually archives, as Notion API doesn't support permanent delete).
-		if err := client.DeletePage(ctx, f.state.NotionPageID); err != nil {
-			return fmt.Errorf("delete page: %w", err)
+		if err := state.NewLinkRegistry(tx).ClearLinksFrom(path); err != nil {
+			return fmt.Errorf("clear links: %w", err)
 		}
-	case "ignore":
-		// Do nothing to Notion, just remove from local tracking.
-	default:
-		return fmt.Errorf("unknown deletion strategy: %s", strategy)
-	}
-
-	// Remove from sync state.
-	if err := db.DeleteState(f.path); err != nil {
-		return fmt.Errorf("delete state: %w", err)
-	}
-
-	// Clear links from this file.
-	_ = linkRegistry.ClearLinksFrom(f.path)
+		if err := state.NewAttachmentStore(tx).ClearRefs(path); err != nil {
+			return fmt.Errorf("clear attachment refs: %w", err)
+		}
+		return nil
+	})
+}
 
-	return nil
+// moveNote moves the sync state, links, and attachment references of a
+// renamed note in one transaction.
+func moveNote(db *state.DB, oldPath, newPath string) error {
+	return db.InTx(func(tx *state.DB) error {
+		if err := tx.UpdatePath(oldPath, newPath); err != nil {
+			return fmt.Errorf("update state path: %w", err)
+		}
+		if err := state.NewLinkRegistry(tx).UpdateSourcePath(oldPath, newPath); err != nil {
+			return fmt.Errorf("update link source path: %w", err)
+		}
+		if err := state.NewAttachmentStore(tx).UpdateRefPath(oldPath, newPath); err != nil {
+			return fmt.Errorf("update attachment refs: %w", err)
+		}
+		return nil
+	})
 }
 
 // handleRename processes a file rename.
-func handleRename(ctx context.Context, cfg *config.Config, db *state.DB, client *notion.Client, linkRegistry *state.LinkRegistry, f pushFile) error {
+func handleRename(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory, linkRegistry *state.LinkRegistry, f pushFile) error {
 	if f.state == nil || f.state.NotionPageID == "" {
 		return fmt.Errorf("no sync state for renamed file")
 	}
@@ -379,21 +742,17 @@ func handleRename(ctx context.Context, cfg *config.Config, db *state.DB, client
 	basename := filepath.Base(f.path)
 	newTitle := strings.TrimSuffix(basename, filepath.Ext(basename))
 
-	if err := client.UpdatePageTitle(ctx, f.state.NotionPageID, newTitle); err != nil {
+	// The page still lives where it was created, so use the old path's integration.
+	if err := clients.ForPath(f.oldPath).UpdatePageTitle(ctx, f.state.NotionPageID, newTitle); err != nil {
 		return fmt.Errorf("update page title: %w", err)
 	}
 
-	// 2. Update sync state with new path.
-	if err := db.UpdatePath(f.oldPath, f.path); err != nil {
-		return fmt.Errorf("update state path: %w", err)
-	}
-
-	// 3. Update link registry with new path.
-	if err := linkRegistry.UpdateSourcePath(f.oldPath, f.path); err != nil {
-		return fmt.Errorf("update link source path: %w", err)
+	// 2. Move sync state, links, and attachment references to the new path.
+	if err := moveNote(db, f.oldPath, f.path); err != nil {
+		return err
 	}
 
-	// 4. Update last sync time.
+	// 3. Update last sync time.
 	syncState, err := db.GetState(f.path)
 	if err != nil {
 		return fmt.Errorf("get updated state: %w", err)
@@ -416,6 +775,7 @@ type pushFile struct {
 	state      *state.SyncState
 	mtime      time.Time
 	changeType state.ChangeType
+	retitled   bool // Only the title frontmatter changed
 }
 
 // getFilesToPush returns the list of files that need to be pushed.
@@ -424,7 +784,7 @@ func getFilesToPush(ctx context.Context, cfg *config.Config, db *state.DB) ([]pu
 
 	if pushAll {
 		// Push all files.
-		scanner := vault.NewScanner(cfg.Vault, cfg.Sync.Ignore)
+		scanner := newScanner(cfg)
 		vaultFiles, err := scanner.Scan(ctx)
 		if err != nil {
 			return nil, err
@@ -445,7 +805,7 @@ func getFilesToPush(ctx context.Context, cfg *config.Config, db *state.DB) ([]pu
 		}
 	} else {
 		// Push only changed files.
-		detector := state.NewChangeDetector(db, cfg.Vault)
+		detector := newChangeDetector(cfg, db)
 		changes, err := detector.DetectChanges(ctx)
 		if err != nil {
 			return nil, err
@@ -461,12 +821,16 @@ func getFilesToPush(ctx context.Context, cfg *config.Config, db *state.DB) ([]pu
 				state:      c.State,
 				mtime:      c.LocalMtime,
 				changeType: c.Type,
+				retitled:   c.Retitled,
 			})
 		}
 
-		// Also include pending files (never synced).
+		// Also include pending files (never synced), degraded pages, which
+		// did not read back as pushed, and notes blocked by locked pages.
 		pendingStates, _ := db.ListStates("pending")
-		for _, s := range pendingStates {
+		degradedStates, _ := db.ListStates(degradedStatus)
+		blockedStates, _ := db.ListStates(blockedStatus)
+		for _, s := range slices.Concat(pendingStates, degradedStates, blockedStates) {
 			// Check if already in list.
 			found := false
 			for _, f := range files {
@@ -476,11 +840,15 @@ func getFilesToPush(ctx context.Context, cfg *config.Config, db *state.DB) ([]pu
 				}
 			}
 			if !found {
+				changeType := state.ChangeCreated
+				if s.NotionPageID != "" {
+					changeType = state.ChangeModified
+				}
 				files = append(files, pushFile{
 					path:       s.ObsidianPath,
 					state:      s,
 					mtime:      s.ObsidianMtime,
-					changeType: state.ChangeCreated,
+					changeType: changeType,
 				})
 			}
 		}
@@ -489,6 +857,113 @@ func getFilesToPush(ctx context.Context, cfg *config.Config, db *state.DB) ([]pu
 	return files, nil
 }
 
+// skippedFile is a note or attachment left out of a run because it is too
+// large or binary.
+type skippedFile struct {
+	path   string
+	reason string
+}
+
+// newSkippedFile describes a file the vault scanner rejected.
+func newSkippedFile(e *vault.SkipError) skippedFile {
+	if e.Binary {
+		return skippedFile{path: e.Path, reason: "binary file"}
+	}
+	return skippedFile{
+		path:   e.Path,
+		reason: fmt.Sprintf("%s, over the %s limit", formatBytes(e.Size), formatBytes(e.Limit)),
+	}
+}
+
+// checkNote reports whether a note can be pushed. Notes that are too
+// large or binary are returned as skipped; other errors are left for the
+// push itself to report.
+func checkNote(cfg *config.Config, scanner *vault.Scanner, path string) (skippedFile, bool) {
+	var skip *vault.SkipError
+	if err := scanner.CheckNote(path, cfg.Sync.NoteSizeLimit()); errors.As(err, &skip) {
+		return newSkippedFile(skip), false
+	}
+	return skippedFile{}, true
+}
+
+// warnUnexpandedTemplates warns when a note about to be pushed still
+// contains template placeholders, usually a template copied by hand or a
+// template folder that is not excluded.
+func warnUnexpandedTemplates(out io.Writer, path string, note *parser.ParsedNote) {
+	found := transformer.UnexpandedTemplates(note)
+	if len(found) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "  Warning: %s contains unexpanded template syntax: %s\n", path, strings.Join(found, ", "))
+}
+
+// skipUnsyncable removes created and modified notes that are too large or
+// binary. Deletions and renames do not read the note and are kept.
+func skipUnsyncable(cfg *config.Config, scanner *vault.Scanner, files []pushFile) (kept []pushFile, skipped []skippedFile) {
+	for _, f := range files {
+		if f.changeType == state.ChangeCreated || f.changeType == state.ChangeModified {
+			if s, ok := checkNote(cfg, scanner, f.path); !ok {
+				skipped = append(skipped, s)
+				continue
+			}
+		}
+		kept = append(kept, f)
+	}
+	return kept, skipped
+}
+
+// printSkipped lists the files a run left out.
+func printSkipped(skipped []skippedFile) {
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Printf("  Skipped: %d\n", len(skipped))
+	for _, f := range skipped {
+		fmt.Printf("    %s (%s)\n", f.path, f.reason)
+	}
+}
+
+// explainFiltered explains, with -v, why the files not kept by a filter
+// are left out, and returns the kept files.
+func explainFiltered(files, kept []pushFile, reason string) []pushFile {
+	if !verbose {
+		return kept
+	}
+	keep := make(map[string]bool, len(kept))
+	for _, f := range kept {
+		keep[f.path] = true
+	}
+	for _, f := range files {
+		if !keep[f.path] {
+			explainFile(f.path, "left out: "+reason)
+		}
+	}
+	return kept
+}
+
+// explainRoute describes where a change is pushed: the database a new
+// page is created in and the mapping that chose it, or the page updated.
+func explainRoute(cfg *config.Config, f pushFile) string {
+	var route string
+	switch {
+	case f.changeType == state.ChangeDeleted:
+		return fmt.Sprintf("deleted: page handled by sync.deletion_strategy %s", cfg.Sync.DeletionStrategy)
+	case f.state != nil && f.state.NotionPageID != "":
+		route = fmt.Sprintf("%s: updating page %s", f.changeType, f.state.NotionPageID)
+	default:
+		route = fmt.Sprintf("%s: creating a page in database %s", f.changeType, cfg.GetDatabaseForPath(f.path))
+		if m := cfg.GetMapping(f.path); m != nil {
+			route += fmt.Sprintf(" (mapping %s)", m.Path)
+		} else {
+			route += " (notion.default_database)"
+		}
+	}
+	if credential := cfg.CredentialForPath(f.path); credential != "" {
+		route += fmt.Sprintf(", credential %s", credential)
+	}
+	return route
+}
+
 // filterByPath filters files by a glob pattern.
 func filterByPath(files []pushFile, pattern string) []pushFile {
 	var filtered []pushFile
@@ -501,6 +976,73 @@ func filterByPath(files []pushFile, pattern string) []pushFile {
 	return filtered
 }
 
+// filterByPaths keeps the files at the given paths. A rename is kept if
+// either its new or old path is given.
+func filterByPaths(files []pushFile, paths []string) []pushFile {
+	wanted := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		wanted[path] = true
+	}
+	var filtered []pushFile
+	for _, f := range files {
+		if wanted[f.path] || (f.oldPath != "" && wanted[f.oldPath]) {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered
+}
+
+// filterByTags keeps the files of notes carrying any of the include tags,
+// if there are any, and none of the exclude tags. A nested tag also counts
+// as each of its parents, and tags compare without case, as in Obsidian.
+// Deleted notes have no tags to read and are kept only without include
+// tags.
+func filterByTags(vaultPath string, files []pushFile, include, exclude []string) []pushFile {
+	normalize := func(tags []string) map[string]bool {
+		set := make(map[string]bool, len(tags))
+		for _, tag := range tags {
+			if tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#")); tag != "" {
+				set[tag] = true
+			}
+		}
+		return set
+	}
+	wanted, unwanted := normalize(include), normalize(exclude)
+
+	p := parser.New()
+	var filtered []pushFile
+	for _, f := range files {
+		if f.changeType == state.ChangeDeleted {
+			if len(wanted) == 0 {
+				filtered = append(filtered, f)
+			}
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(vaultPath, f.path))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: cannot read tags of %s: %v\n", f.path, err)
+			continue
+		}
+		note, err := p.Parse(f.path, content)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: cannot read tags of %s: %v\n", f.path, err)
+			continue
+		}
+
+		included, excluded := len(wanted) == 0, false
+		for _, tag := range note.Tags {
+			for _, t := range parser.TagHierarchy(strings.ToLower(tag)) {
+				included = included || wanted[t]
+				excluded = excluded || unwanted[t]
+			}
+		}
+		if included && !excluded {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered
+}
+
 // checkConflicts returns paths with conflict status.
 func checkConflicts(files []pushFile) []string {
 	var conflicts []string
@@ -516,21 +1058,58 @@ func checkConflicts(files []pushFile) []string {
 type pushContext struct {
 	cfg          *config.Config
 	db           *state.DB
-	client       *notion.Client
+	clients      *notion.Factory
 	linkRegistry *state.LinkRegistry
+	attachments  *attachmentUploader
 	parser       *parser.Parser
 	scanner      *vault.Scanner
+	undo         *undoRecorder
+
+	// stagingDatabase, if set, receives new pages instead of their
+	// target database (push --staged).
+	stagingDatabase string
+
+	// budget, if set, caps the API requests of the push
+	// (push --max-requests).
+	budget *notion.Budget
+
+	// verify reads each pushed page back to check it (push --verify).
+	verify bool
+
+	// templates are the template pages new pages are created from.
+	templates *templateCache
+
+	// parentPage, if set, is the page new pages are created under instead
+	// of their configured parent (push --parent).
+	parentPage string
 }
 
 // pushResult holds the result of processing a single file.
 type pushResult struct {
 	pageID       string
+	parentID     string // Target database for new pages
 	isNew        bool
+	retitled     bool // Only the page title was updated
+	degraded     bool // The page did not read back as pushed
 	hasWikiLinks bool // Track if file has wiki-links for second pass
+
+	// placeholders are the wiki-link targets that did not resolve when the
+	// page was pushed, for the link fixup pass.
+	placeholders []string
 }
 
 // processFile processes a single file for push (create or update).
 func (pc *pushContext) processFile(ctx context.Context, f pushFile) (pushResult, error) {
+	release, err := pc.reserve(f)
+	if err != nil {
+		return pushResult{}, err
+	}
+	defer release()
+
+	if pc.streams(f.path) {
+		return pc.streamFile(ctx, f)
+	}
+
 	// Read file content.
 	fullPath := filepath.Join(pc.cfg.Vault, f.path)
 	content, err := os.ReadFile(fullPath)
@@ -544,13 +1123,226 @@ func (pc *pushContext) processFile(ctx context.Context, f pushFile) (pushResult,
 		return pushResult{}, fmt.Errorf("parse markdown: %w", err)
 	}
 
+	registerNoteLinks(pc.linkRegistry, f.path, note)
+	warnUnexpandedTemplates(os.Stderr, f.path, note)
+
+	// Upload referenced attachments, reusing earlier uploads of identical files.
+	attachments := pc.attachments.prepare(ctx, f.path, note)
+
+	// Create transformer with path-specific property mappings.
+	links := &placeholderRecorder{LinkResolver: pc.linkRegistry}
+	t := transformer.New(links, buildTransformerConfig(pc.cfg, f.path))
+	t.SetAttachmentResolver(attachments)
+
+	// Transform to Notion page structure.
+	notionPage, err := t.Transform(note)
+	if err != nil {
+		return pushResult{}, fmt.Errorf("transform to Notion: %w", err)
+	}
+
+	// The hashes of the content pushed, checked against the note once the
+	// page is written.
+	hashes := noteHasher(pc.cfg).HashContent(content)
+
+	var pageID, parentID string
+	var isNew, retitled bool
+
+	if f.state == nil || f.state.NotionPageID == "" {
+		// Create new page.
+		parentID = pc.newPageParent(f.path, note)
+
+		before, after, err := pc.applyTemplate(ctx, f.path, notionPage)
+		if err != nil {
+			return pushResult{}, err
+		}
+
+		result, err := pc.createPage(ctx, pc.clients.ForPath(f.path), parentID, notionPage)
+		if err != nil {
+			// Keep the ID of a partially created page so it can be cleaned up.
+			failed := pushResult{parentID: parentID, isNew: true}
+			if result != nil {
+				failed.pageID = result.PageID
+			}
+			return failed, fmt.Errorf("create page: %w", err)
+		}
+		pageID = result.PageID
+		isNew = true
+		pc.undo.created(f.path, pageID, f.state)
+
+		// The note gets the template's content its page was created with.
+		rt := transformer.NewReverse(pc.linkRegistry, buildTransformerConfig(pc.cfg, f.path))
+		if err := writeTemplated(fullPath, rt, before, after); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: failed to add template content to %s: %v\n", f.path, err)
+		} else if info, err := os.Stat(fullPath); err == nil {
+			f.mtime = info.ModTime()
+			if written, err := noteHasher(pc.cfg).HashFileDetailed(fullPath); err == nil {
+				hashes = written
+			}
+		}
+	} else {
+		// Update existing page, unless it is still locked.
+		pageID = f.state.NotionPageID
+		if err := checkBlocked(ctx, pc.clients.ForPath(f.path), f.state); err != nil {
+			return pushResult{}, err
+		}
+
+		if f.retitled {
+			retitled, err = retitlePage(ctx, pc.clients.ForPath(f.path), pc.db, pc.undo, f.path, f.state, notionPage)
+			if err != nil {
+				return pushResult{}, fmt.Errorf("update title: %w", err)
+			}
+		}
+		if !retitled {
+			previous, err := pc.clients.ForPath(f.path).ReplacePage(ctx, pageID, notionPage)
+			if err != nil {
+				return pushResult{}, fmt.Errorf("update page: %w", err)
+			}
+			pc.undo.updated(f.path, pageID, f.state, previous)
+		}
+	}
+
+	// Update sync state with both content and frontmatter hashes.
+	// ContentHash stores the body hash (for detecting content-only changes).
+	// FrontmatterHash stores the metadata hash (for property-only updates).
+	// A note edited during the push is left pending for the next one.
+	status := pushedStatus(noteHasher(pc.cfg), fullPath, hashes)
+	if status == "pending" && verbose {
+		fmt.Fprintf(os.Stderr, "  %s changed during the push, leaving it pending\n", f.path)
+	}
+	syncState := &state.SyncState{
+		ObsidianPath:    f.path,
+		NotionPageID:    pageID,
+		NotionParentID:  recordedParent(f.state, parentID),
+		ObsidianMtime:   f.mtime,
+		NotionMtime:     time.Now(),
+		ContentHash:     hashes.ContentHash,
+		FrontmatterHash: hashes.FrontmatterHash,
+		LastSync:        time.Now(),
+		SyncDirection:   "push",
+		Status:          status,
+	}
+	if err := pc.db.SetState(syncState); err != nil {
+		return pushResult{}, fmt.Errorf("update state: %w", err)
+	}
+	// A retitled page keeps its blocks and sections, and their records.
+	if !retitled {
+		if err := recordPushedPage(pc.db, f.path, notionPage); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: failed to record page state for %s: %v\n", f.path, err)
+		}
+	}
+
+	if err := pc.attachments.commit(attachments); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to record attachments for %s: %v\n", f.path, err)
+	}
+	pushNoteTargets(ctx, os.Stderr, pc.cfg, pc.db, pc.clients, f.path, note)
+
+	// A retitled page keeps the blocks already checked when they were pushed.
+	var degraded bool
+	if pc.verify && !retitled {
+		pushed, _ := pc.verifyTransformer(f.path).NotionToMarkdown(notionPage)
+		degraded = pc.checkPushed(ctx, f.path, pageID, string(pushed))
+	}
+
+	result := pushResult{pageID: pageID, parentID: parentID, isNew: isNew, retitled: retitled, degraded: degraded, hasWikiLinks: len(note.WikiLinks) > 0}
+	// A retitled page keeps the blocks it had, placeholders and all.
+	if !retitled {
+		result.placeholders = links.missed
+	}
+	return result, nil
+}
+
+// newPageParent returns where the page of a new note is created: the page
+// given with push --parent, or else its configured parent.
+func (pc *pushContext) newPageParent(path string, note *parser.ParsedNote) string {
+	if pc.parentPage != "" {
+		return pc.parentPage
+	}
+	return newPageParent(pc.cfg, path, note)
+}
+
+// createPage creates the page of a new note under parent, a database or,
+// with push --parent, a page. With push --staged it is created in the
+// staging database instead, to be moved to parent later.
+func (pc *pushContext) createPage(ctx context.Context, client *notion.Client, parent string, page *transformer.NotionPage) (*notion.PageResult, error) {
+	switch {
+	case pc.parentPage != "":
+		return client.CreatePageUnderPage(ctx, parent, page)
+	case pc.stagingDatabase != "":
+		return client.CreatePage(ctx, pc.stagingDatabase, page)
+	}
+	return client.CreatePage(ctx, parent, page)
+}
+
+// recordedParent returns the parent the sync state records for a pushed
+// note: the one its page was just created in, or else the one recorded in
+// its existing state.
+func recordedParent(existing *state.SyncState, created string) string {
+	if created == "" && existing != nil {
+		return existing.NotionParentID
+	}
+	return created
+}
+
+// pushedStatus returns the status to record for a note pushed with the
+// given hashes: synced, or pending if the note was edited while its page
+// was being written, so the next push picks up the edit. A note gone from
+// the vault is left to deletion detection.
+func pushedStatus(h state.Hasher, fullPath string, pushed state.ContentHashes) string {
+	current, err := h.HashFileDetailed(fullPath)
+	if err != nil || (current.ContentHash == pushed.ContentHash && current.FrontmatterHash == pushed.FrontmatterHash) {
+		return "synced"
+	}
+	return "pending"
+}
+
+// retitlePage updates only the title of a note's page, for a change that
+// edits nothing but the title frontmatter, leaving its blocks alone. It
+// reports false if the page has no title property to update. When undo is
+// recorded, the page is fetched first so the old title can be restored.
+func retitlePage(ctx context.Context, client *notion.Client, db *state.DB, undo *undoRecorder, path string, prior *state.SyncState, page *transformer.NotionPage) (bool, error) {
+	title := titleProperty(page.Properties)
+	if title == nil {
+		return false, nil
+	}
+
+	var previous *transformer.NotionPage
+	if undo != nil {
+		var err error
+		previous, err = fetchNotePage(ctx, client, db, path, prior.NotionPageID)
+		if err != nil {
+			return false, fmt.Errorf("fetch page: %w", err)
+		}
+	}
+	// "title" is the ID of the title property of every page, whatever
+	// its database calls it.
+	if err := client.UpdatePageProperties(ctx, prior.NotionPageID, notionapi.Properties{"title": title}); err != nil {
+		return false, err
+	}
+	undo.updated(path, prior.NotionPageID, prior, previous)
+	return true, nil
+}
+
+// titleProperty returns the title property among props, or nil.
+func titleProperty(props notionapi.Properties) notionapi.Property {
+	for _, prop := range props {
+		switch prop.(type) {
+		case notionapi.TitleProperty, *notionapi.TitleProperty:
+			return prop
+		}
+	}
+	return nil
+}
+
+// registerNoteLinks records a note's title, aliases, and outgoing
+// wiki-links in the link registry. Failures are reported as warnings.
+func registerNoteLinks(linkRegistry *state.LinkRegistry, path string, note *parser.ParsedNote) {
 	// Register title and aliases for wiki-link resolution.
 	// This allows [[Title]] to resolve to filename.md when title differs from filename.
-	_ = pc.linkRegistry.ClearAliases(f.path) // Clear old aliases first
+	_ = linkRegistry.ClearAliases(path) // Clear old aliases first
 	if title, ok := note.Frontmatter["title"].(string); ok && title != "" {
-		if err := pc.linkRegistry.RegisterAlias(f.path, title, "title"); err != nil {
+		if err := linkRegistry.RegisterAlias(path, title, "title"); err != nil {
 			// Non-fatal: log but continue
-			fmt.Fprintf(os.Stderr, "  Warning: failed to register title alias for %s: %v\n", f.path, err)
+			fmt.Fprintf(os.Stderr, "  Warning: failed to register title alias for %s: %v\n", path, err)
 		}
 	}
 	// Also register aliases from frontmatter.
@@ -567,85 +1359,262 @@ func (pc *pushContext) processFile(ctx context.Context, f pushFile) (pushResult,
 			aliasStrings = v
 		}
 		if len(aliasStrings) > 0 {
-			if err := pc.linkRegistry.RegisterAliases(f.path, aliasStrings, "alias"); err != nil {
-				fmt.Fprintf(os.Stderr, "  Warning: failed to register aliases for %s: %v\n", f.path, err)
+			if err := linkRegistry.RegisterAliases(path, aliasStrings, "alias"); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: failed to register aliases for %s: %v\n", path, err)
 			}
 		}
 	}
 
-	// Register wiki-links for two-pass resolution.
-	// Clear existing links first (in case file was modified and links changed).
-	_ = pc.linkRegistry.ClearLinksFrom(f.path)
-	if len(note.WikiLinks) > 0 {
-		targets := make([]string, len(note.WikiLinks))
-		for i, link := range note.WikiLinks {
-			targets[i] = link.Target
+	// Register wiki-links for two-pass resolution, replacing the links from
+	// the previous version of the note.
+	targets := make([]string, len(note.WikiLinks))
+	for i, link := range note.WikiLinks {
+		targets[i] = link.Target
+	}
+	if err := linkRegistry.ReplaceLinks(path, targets); err != nil {
+		// Non-fatal: log but continue processing
+		fmt.Fprintf(os.Stderr, "  Warning: failed to register links from %s: %v\n", path, err)
+	}
+}
+
+// splitComposed separates files covered by a composition rule from files
+// that sync to their own page. A rename counts as composed when either side
+// of it matches a rule.
+func splitComposed(cfg *config.Config, files []pushFile) (composed, rest []pushFile) {
+	for _, f := range files {
+		if cfg.GetComposition(composedPath(cfg, f)) != nil {
+			composed = append(composed, f)
+		} else {
+			rest = append(rest, f)
+		}
+	}
+	return composed, rest
+}
+
+// composedPath returns the path of a file change that matches a
+// composition rule, preferring the new path of a rename.
+func composedPath(cfg *config.Config, f pushFile) string {
+	if f.oldPath != "" && cfg.GetComposition(f.path) == nil {
+		return f.oldPath
+	}
+	return f.path
+}
+
+// splitNewPages separates files without a Notion page from files that
+// update an existing page.
+func splitNewPages(files []pushFile) (creates, modifies []pushFile) {
+	for _, f := range files {
+		if f.state == nil || f.state.NotionPageID == "" {
+			creates = append(creates, f)
+		} else {
+			modifies = append(modifies, f)
 		}
-		if err := pc.linkRegistry.RegisterLinks(f.path, targets); err != nil {
-			// Non-fatal: log but continue processing
-			fmt.Fprintf(os.Stderr, "  Warning: failed to register links from %s: %v\n", f.path, err)
+	}
+	return creates, modifies
+}
+
+// pushStagedPages builds new pages in the staging database and then moves
+// them to their target databases. If any page fails to build or publish,
+// every staged page is archived and its sync state restored, so readers
+// never see part of the batch.
+func pushStagedPages(ctx context.Context, pc *pushContext, workers int, files []pushFile) ([]osync.Task[pushFile, pushResult], error) {
+	stagingCtx := *pc
+	stagingCtx.stagingDatabase = pc.cfg.Notion.StagingDatabase
+
+	fmt.Printf("Staging %d new page(s)...\n", len(files))
+	progress := osync.NewProgress(len(files), os.Stdout)
+	progress.SetEnabled(!verbose)
+	processFile := osync.WithTimeout(pc.cfg.Sync.FileTimeoutPeriod(), stagingCtx.processFile)
+	results := osync.ProcessWithProgress(ctx, osync.NewWorkerPool(workers), files, processFile, progress.SimpleCallback())
+	progress.Finish()
+
+	// Staged pages are discarded even when the push was interrupted.
+	cleanupCtx := context.WithoutCancel(ctx)
+	if ctx.Err() != nil {
+		discardStagedPages(cleanupCtx, pc, results)
+		return nil, fmt.Errorf("staged push interrupted, nothing was published: %w", ctx.Err())
+	}
+	var failures int
+	for _, r := range results {
+		if r.Err != nil {
+			printError(os.Stderr, "Error staging", r.Input.path, r.Err)
+			failures++
 		}
 	}
+	if failures > 0 {
+		discardStagedPages(cleanupCtx, pc, results)
+		return nil, fmt.Errorf("staged push aborted: %d page(s) failed, nothing was published", failures)
+	}
 
-	// Create transformer with path-specific property mappings.
-	t := transformer.New(pc.linkRegistry, buildTransformerConfig(pc.cfg, f.path))
+	// Publish: move every staged page to its target database.
+	for i, r := range results {
+		err := pc.clients.ForPath(r.Input.path).MovePage(ctx, r.Result.pageID, r.Result.parentID)
+		if err == nil {
+			continue
+		}
 
-	// Transform to Notion page structure.
-	notionPage, err := t.Transform(note)
-	if err != nil {
-		return pushResult{}, fmt.Errorf("transform to Notion: %w", err)
+		printError(os.Stderr, "Error publishing", r.Input.path, err)
+		// Take back the pages already published before discarding the batch.
+		for _, published := range results[:i] {
+			if err := pc.clients.ForPath(published.Input.path).MovePage(cleanupCtx, published.Result.pageID, pc.cfg.Notion.StagingDatabase); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: cannot unpublish %s: %v\n", published.Input.path, err)
+			}
+		}
+		discardStagedPages(cleanupCtx, pc, results)
+		return nil, fmt.Errorf("staged push aborted: publish %s: %w", r.Input.path, err)
 	}
 
-	var pageID string
-	var isNew bool
+	fmt.Printf("Published %d staged page(s).\n", len(results))
+	return results, nil
+}
 
-	if f.state == nil || f.state.NotionPageID == "" {
-		// Create new page.
-		parentID := pc.cfg.GetDatabaseForPath(f.path)
-		if parentID == "" {
-			parentID = pc.cfg.Notion.DefaultPage
+// discardStagedPages archives the pages of a failed staged push and
+// restores the sync state each file had before the push.
+func discardStagedPages(ctx context.Context, pc *pushContext, results []osync.Task[pushFile, pushResult]) {
+	for _, r := range results {
+		if r.Result.pageID != "" {
+			if err := pc.clients.ForPath(r.Input.path).ArchivePage(ctx, r.Result.pageID); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: cannot archive staged page for %s: %v\n", r.Input.path, err)
+			}
 		}
 
-		result, err := pc.client.CreatePage(ctx, parentID, notionPage)
-		if err != nil {
-			return pushResult{}, fmt.Errorf("create page: %w", err)
+		if r.Input.state != nil {
+			_ = pc.db.SetState(r.Input.state)
+		} else {
+			_ = pc.db.DeleteState(r.Input.path)
 		}
-		pageID = result.PageID
-		isNew = true
-	} else {
-		// Update existing page.
-		pageID = f.state.NotionPageID
+	}
+}
+
+// repushLinkedPage re-reads, re-transforms, and updates the Notion page for
+// a note so that its wiki-links reflect the current link registry. It
+// returns false if the note has no Notion page yet.
+func repushLinkedPage(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory, linkRegistry *state.LinkRegistry, attachments *attachmentUploader, path string) (bool, error) {
+	syncState, err := db.GetState(path)
+	if err != nil {
+		return false, fmt.Errorf("cannot get state for %s: %w", path, err)
+	}
+	if syncState == nil || syncState.NotionPageID == "" {
+		return false, nil
+	}
 
-		if err := pc.client.UpdatePage(ctx, pageID, notionPage); err != nil {
-			return pushResult{}, fmt.Errorf("update page: %w", err)
+	// A composed note shares its page with the other members, so the whole
+	// page is rebuilt.
+	if rule := cfg.GetComposition(path); rule != nil {
+		if _, err := newComposer(cfg, db, clients, linkRegistry, attachments, attachments.scanner).push(ctx, rule); err != nil {
+			return false, fmt.Errorf("failed to update links in %s: %w", path, err)
 		}
+		return true, nil
 	}
 
-	// Compute content hashes (normalized, with separate frontmatter hash).
-	hashes, err := state.HashFileDetailed(fullPath)
+	content, err := os.ReadFile(filepath.Join(cfg.Vault, path))
 	if err != nil {
-		hashes = state.ContentHashes{} // Non-fatal, continue without hashes
+		return false, fmt.Errorf("cannot read %s: %w", path, err)
 	}
 
-	// Update sync state with both content and frontmatter hashes.
-	// ContentHash stores the body hash (for detecting content-only changes).
-	// FrontmatterHash stores the metadata hash (for property-only updates).
-	syncState := &state.SyncState{
-		ObsidianPath:    f.path,
-		NotionPageID:    pageID,
-		ObsidianMtime:   f.mtime,
-		NotionMtime:     time.Now(),
-		ContentHash:     hashes.ContentHash,
-		FrontmatterHash: hashes.FrontmatterHash,
-		LastSync:        time.Now(),
-		SyncDirection:   "push",
-		Status:          "synced",
+	note, err := parser.New().Parse(path, content)
+	if err != nil {
+		return false, fmt.Errorf("cannot parse %s: %w", path, err)
 	}
-	if err := pc.db.SetState(syncState); err != nil {
-		return pushResult{}, fmt.Errorf("update state: %w", err)
+
+	t := transformer.New(linkRegistry, buildTransformerConfig(cfg, path))
+	t.SetAttachmentResolver(attachments.prepare(ctx, path, note))
+
+	notionPage, err := t.Transform(note)
+	if err != nil {
+		return false, fmt.Errorf("cannot transform %s: %w", path, err)
+	}
+
+	if err := clients.ForPath(path).UpdatePage(ctx, syncState.NotionPageID, notionPage); err != nil {
+		return false, fmt.Errorf("failed to update links in %s: %w", path, err)
+	}
+	if err := recordPushedPage(db, path, notionPage); err != nil {
+		return true, fmt.Errorf("failed to record page state for %s: %w", path, err)
+	}
+
+	return true, nil
+}
+
+// linkRepairer re-pushes notes that link to a renamed note, so their
+// mentions and placeholders follow the rename without waiting for the
+// referrer itself to be edited.
+type linkRepairer struct {
+	cfg          *config.Config
+	db           *state.DB
+	clients      *notion.Factory
+	linkRegistry *state.LinkRegistry
+	attachments  *attachmentUploader
+
+	// done holds paths that are (or will be) pushed anyway in this run.
+	done    map[string]bool
+	pending []string
+}
+
+// newLinkRepairer creates a linkRepairer that skips the given files,
+// since they are pushed with fresh link resolution regardless.
+func newLinkRepairer(cfg *config.Config, db *state.DB, clients *notion.Factory, linkRegistry *state.LinkRegistry, attachments *attachmentUploader, skip []string) *linkRepairer {
+	done := make(map[string]bool, len(skip))
+	for _, path := range skip {
+		done[path] = true
+	}
+	return &linkRepairer{
+		cfg:          cfg,
+		db:           db,
+		clients:      clients,
+		linkRegistry: linkRegistry,
+		attachments:  attachments,
+		done:         done,
+	}
+}
+
+// renamed re-resolves links affected by a rename. Depending on
+// sync.link_repair, referrers are re-pushed right away or queued for flush.
+func (lr *linkRepairer) renamed(ctx context.Context, oldPath, newPath string) {
+	mode := lr.cfg.Sync.LinkRepair
+	if mode == "off" {
+		return
+	}
+
+	referrers, err := lr.linkRegistry.RefreshBacklinks(oldPath, newPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to refresh links to %s: %v\n", newPath, err)
+	}
+
+	for _, path := range referrers {
+		if lr.done[path] {
+			continue
+		}
+		lr.done[path] = true
+		lr.pending = append(lr.pending, path)
+	}
+
+	if mode == "immediate" {
+		lr.flush(ctx)
+	}
+}
+
+// flush re-pushes all queued referrers and returns the number of pages
+// updated and the number of failures.
+func (lr *linkRepairer) flush(ctx context.Context) (updated, failed int) {
+	pending := lr.pending
+	lr.pending = nil
+
+	for _, path := range pending {
+		ok, err := repushLinkedPage(ctx, lr.cfg, lr.db, lr.clients, lr.linkRegistry, lr.attachments, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: link repair: %v\n", err)
+			failed++
+			continue
+		}
+		if ok {
+			updated++
+			if verbose {
+				fmt.Printf("  L %s (links updated)\n", path)
+			}
+		}
 	}
 
-	return pushResult{pageID: pageID, isNew: isNew, hasWikiLinks: len(note.WikiLinks) > 0}, nil
+	return updated, failed
 }
 
 // checkForNewlyResolvedLinks checks if a file has any links that can now be resolved
@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+var (
+	restoreSince  time.Duration
+	restoreDryRun bool
+)
+
+// restoreCmd represents the restore command.
+var restoreCmd = &cobra.Command{
+	Use:   "restore [paths...]",
+	Short: "Restore Notion pages archived for deleted notes",
+	Long: `Restore Notion pages that were archived because their notes were
+deleted from the vault.
+
+Each page is moved out of Notion's trash, pulled back to its original
+path, and tracked again. Without paths, every page archived within
+--since is restored. Notes that exist locally again are skipped.
+
+A run that would archive more pages than sync.max_deletions_per_run
+(default 25) stops before changing anything until it is rerun with
+--confirm-deletions, so a mass deletion in the vault is caught first.
+
+Examples:
+  obsidian-notion restore --dry-run          # List recently archived pages
+  obsidian-notion restore                    # Restore pages archived in the last 7 days
+  obsidian-notion restore --since 2h         # Restore pages archived in the last 2 hours
+  obsidian-notion restore "notes/Plan.md"    # Restore one note`,
+	RunE: runRestore,
+}
+
+func init() {
+	restoreCmd.Flags().DurationVar(&restoreSince, "since", 7*24*time.Hour, "restore pages archived within this duration")
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "show what would be restored without making changes")
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+	if err := refuseMirror(cfg, "restore"); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	trashed, err := db.ListTrashed(time.Now().Add(-restoreSince))
+	if err != nil {
+		return fmt.Errorf("list archived pages: %w", err)
+	}
+	trashed = filterTrashed(trashed, args)
+	if len(trashed) == 0 {
+		fmt.Println("Nothing to restore.")
+		return nil
+	}
+
+	if restoreDryRun {
+		fmt.Printf("Would restore %d page(s):\n", len(trashed))
+		for _, p := range trashed {
+			fmt.Printf("  %s (archived %s)\n", p.Path, p.TrashedAt.Format("2006-01-02 15:04"))
+		}
+		return nil
+	}
+
+	clients := newNotionClients(cfg)
+	linkRegistry := state.NewLinkRegistry(db)
+	var restored, failed int
+	for _, p := range trashed {
+		if _, err := os.Stat(filepath.Join(cfg.Vault, p.Path)); err == nil {
+			fmt.Printf("  - %s exists locally, skipped\n", p.Path)
+			continue
+		}
+		if err := restorePage(ctx, cfg, db, clients.ForPath(p.Path), linkRegistry, p); err != nil {
+			printError(os.Stderr, "Error restoring", p.Path, err)
+			failed++
+			continue
+		}
+		restored++
+		fmt.Printf("  + %s\n", p.Path)
+	}
+
+	fmt.Printf("\nRestored %d page(s)", restored)
+	if failed > 0 {
+		fmt.Printf(", %d failed", failed)
+	}
+	fmt.Println()
+	if failed > 0 {
+		return fmt.Errorf("%d page(s) failed to restore", failed)
+	}
+	return nil
+}
+
+// filterTrashed keeps the trashed pages of the given paths, or all of them
+// when no paths are given. Only the most recent deletion of a path is kept.
+func filterTrashed(trashed []*state.TrashedPage, paths []string) []*state.TrashedPage {
+	wanted := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		wanted[filepath.Clean(path)] = true
+	}
+	seen := make(map[string]bool)
+	var out []*state.TrashedPage
+	for _, p := range trashed {
+		if seen[p.Path] || (len(wanted) > 0 && !wanted[p.Path]) {
+			continue
+		}
+		seen[p.Path] = true
+		out = append(out, p)
+	}
+	return out
+}
+
+// restorePage moves a trashed page out of Notion's trash, writes it back to
+// its note, and tracks the note again.
+func restorePage(ctx context.Context, cfg *config.Config, db *state.DB, client *notion.Client, linkRegistry *state.LinkRegistry, p *state.TrashedPage) error {
+	if err := client.RestorePage(ctx, p.NotionPageID); err != nil {
+		return err
+	}
+	page, err := client.GetPage(ctx, p.NotionPageID)
+	if err != nil {
+		return fmt.Errorf("get page: %w", err)
+	}
+	notionPage, err := fetchNotePage(ctx, client, db, p.Path, p.NotionPageID)
+	if err != nil {
+		return fmt.Errorf("fetch page: %w", err)
+	}
+
+	rt := transformer.NewReverse(linkRegistry, buildTransformerConfig(cfg, p.Path))
+	rt.SetAttachmentSaver(newAttachmentDownloader(ctx, cfg, newScanner(cfg), p.Path))
+	rt.SetBookmarkPreviewer(newBookmarkPreviewer(ctx, cfg, p.Path))
+	rt.SetUserNamer(newUserNamer(ctx, client))
+	markdown, err := rt.NotionToMarkdown(notionPage)
+	if err != nil {
+		return fmt.Errorf("transform to markdown: %w", err)
+	}
+
+	fullPath := filepath.Join(cfg.Vault, p.Path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	if err := os.WriteFile(fullPath, markdown, 0644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	hashes, err := noteHasher(cfg).HashFileDetailed(fullPath)
+	if err != nil {
+		return fmt.Errorf("hash file: %w", err)
+	}
+	var mtime time.Time
+	if info, err := os.Stat(fullPath); err == nil {
+		mtime = info.ModTime()
+	}
+
+	return db.InTx(func(tx *state.DB) error {
+		if err := tx.SetState(&state.SyncState{
+			ObsidianPath:    p.Path,
+			NotionPageID:    p.NotionPageID,
+			NotionParentID:  p.NotionParentID,
+			ContentHash:     hashes.ContentHash,
+			FrontmatterHash: hashes.FrontmatterHash,
+			ObsidianMtime:   mtime,
+			NotionMtime:     page.LastEditedTime,
+			LastSync:        time.Now(),
+			SyncDirection:   "pull",
+			Status:          "synced",
+		}); err != nil {
+			return fmt.Errorf("update state: %w", err)
+		}
+		if err := recordBlockMap(tx, p.Path, notionPage); err != nil {
+			return fmt.Errorf("record blocks: %w", err)
+		}
+		return tx.RecordRestored(p.Path, p.NotionPageID)
+	})
+}
+
+// trashesPage reports whether deleting a note archives its Notion page.
+func trashesPage(cfg *config.Config, s *state.SyncState) bool {
+	return s != nil && s.NotionPageID != "" && cfg.Sync.DeletionStrategy != "ignore"
+}
+
+// trashNotePage archives the Notion page of a deleted note according to
+// sync.deletion_strategy and records it for restore. The note's pages in
+// its notion-targets are archived too.
+func trashNotePage(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory, path string, s *state.SyncState) error {
+	client := clients.ForPath(path)
+	strategy := cfg.Sync.DeletionStrategy
+	if strategy == "" {
+		strategy = "archive" // Default to archive.
+	}
+
+	switch strategy {
+	case "archive":
+		if err := client.ArchivePage(ctx, s.NotionPageID); err != nil {
+			return fmt.Errorf("archive page: %w", err)
+		}
+	case "delete":
+		// Delete the Notion page (actually archives, as Notion API doesn't support permanent delete).
+		if err := client.DeletePage(ctx, s.NotionPageID); err != nil {
+			return fmt.Errorf("delete page: %w", err)
+		}
+	case "ignore":
+		// Do nothing to Notion, just remove from local tracking.
+		return nil
+	default:
+		return fmt.Errorf("unknown deletion strategy: %s", strategy)
+	}
+
+	if err := db.RecordTrashed(path, s, strategy); err != nil {
+		return fmt.Errorf("record deletion: %w", err)
+	}
+	return archiveNoteTargets(ctx, clients, db, path)
+}
+
+// guardDeletions stops a run that would archive more Notion pages than
+// sync.max_deletions_per_run allows, listing the deleted notes, unless the
+// deletions were confirmed.
+func guardDeletions(out io.Writer, cfg *config.Config, paths []string, confirmed bool) error {
+	limit := cfg.Sync.DeletionLimit()
+	if confirmed || limit == 0 || len(paths) <= limit {
+		return nil
+	}
+
+	fmt.Fprintf(out, "%d deleted note(s) would archive their Notion pages:\n", len(paths))
+	const shown = 20
+	for i, path := range paths {
+		if i == shown {
+			fmt.Fprintf(out, "  ... and %d more\n", len(paths)-shown)
+			break
+		}
+		fmt.Fprintf(out, "  D %s\n", path)
+	}
+	return fmt.Errorf("%d deletions exceed sync.max_deletions_per_run (%d): check the vault, then rerun with --confirm-deletions", len(paths), limit)
+}
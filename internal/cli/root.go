@@ -6,13 +6,21 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/jomei/notionapi"
 	"github.com/spf13/cobra"
 
 	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
 	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+	"github.com/adamancini/obsidian-notion-sync/internal/vault"
 )
 
 var (
@@ -22,11 +30,22 @@ var (
 	date    = "unknown"
 
 	// Global flags.
-	cfgFile string
+	cfgFile            string
+	verbosity          int
+	traceAPI           bool
+	syncRoot           string
+	insecureSkipVerify bool
+
+	// verbose is set by -v, and by -vv and --trace, which also log every
+	// Notion API call.
 	verbose bool
 
 	// Loaded configuration.
 	cfg *config.Config
+
+	// httpClient carries Notion requests and attachment transfers, with
+	// the proxy and certificate settings of the configuration.
+	httpClient *http.Client
 )
 
 // SetVersion sets the version information for the CLI.
@@ -53,6 +72,11 @@ Use 'obsidian-notion init' to set up a new sync configuration,
 then 'obsidian-notion push' to export your notes to Notion.`,
 	Version: version,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if verbosity >= 2 {
+			traceAPI = true
+		}
+		verbose = verbosity > 0 || traceAPI
+
 		// Skip config loading for init command.
 		if cmd.Name() == "init" {
 			return nil
@@ -65,6 +89,16 @@ then 'obsidian-notion push' to export your notes to Notion.`,
 			if verbose {
 				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 			}
+			return nil
+		}
+		if httpClient, err = newHTTPClient(cfg.HTTP, insecureSkipVerify); err != nil {
+			return err
+		}
+		if insecureSkipVerify {
+			fmt.Fprintln(os.Stderr, "Warning: TLS certificates are not verified (--insecure-skip-verify)")
+		}
+		if syncRoot != "" {
+			return cfg.SetRoot(syncRoot)
 		}
 		return nil
 	},
@@ -73,25 +107,42 @@ then 'obsidian-notion push' to export your notes to Notion.`,
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() error {
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	if hint := notionHint(err); hint != "" {
+		fmt.Fprintln(os.Stderr, hint)
+	}
+	return err
 }
 
 func init() {
 	// Persistent flags available to all subcommands.
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/obsidian-notion/config.yaml)")
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose output")
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "explain why each file is or is not synced; -vv also logs every Notion API call")
+	rootCmd.PersistentFlags().BoolVar(&traceAPI, "trace", false, "log every Notion API call: method, path, status, latency, and retries (same as -vv)")
+	rootCmd.PersistentFlags().StringVar(&syncRoot, "root", "", "sync only this folder of the vault, e.g. Areas/Public (default: sync.root)")
+	rootCmd.PersistentFlags().BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "do not verify TLS certificates, for debugging a proxy")
 
 	// Set version template.
 	rootCmd.SetVersionTemplate(fmt.Sprintf("obsidian-notion %s (commit: %s, built: %s)\n", version, commit, date))
 
+	// The completion command is our own, with setup instructions.
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+
 	// Add subcommands.
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(pushCmd)
 	rootCmd.AddCommand(pullCmd)
 	rootCmd.AddCommand(syncCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(statsCmd)
 	rootCmd.AddCommand(conflictsCmd)
 	rootCmd.AddCommand(linksCmd)
+	rootCmd.AddCommand(attachmentsCmd)
+	rootCmd.AddCommand(stateCmd)
+	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(matchCmd)
+	rootCmd.AddCommand(completionCmd)
 }
 
 // ErrNoConfig is returned when no configuration is available.
@@ -105,14 +156,126 @@ func getConfig() (*config.Config, error) {
 	return cfg, nil
 }
 
+// newNotionClients creates the Notion client factory for the configured
+// integrations. Clients are resolved per path or database at call time.
+func newNotionClients(cfg *config.Config, extra ...notion.ClientOption) *notion.Factory {
+	opts := []notion.ClientOption{
+		notion.WithRateLimit(cfg.RateLimit.RequestsPerSecond),
+		notion.WithBatchSize(cfg.RateLimit.BatchSize),
+		notion.WithPageSize(cfg.RateLimit.PageSize),
+	}
+	if cfg.Notion.APIVersion != "" {
+		opts = append(opts, notion.WithAPIVersion(cfg.Notion.APIVersion))
+	}
+	if cfg.Attachments.MaxRPS > 0 {
+		opts = append(opts, notion.WithUploadRateLimit(cfg.Attachments.MaxRPS))
+	}
+	if httpClient != nil {
+		opts = append(opts, notion.WithHTTPClient(httpClient))
+	}
+	if icon := cfg.Transform.Icons[transformer.IconUnsupported]; icon != "" {
+		opts = append(opts, notion.WithPlaceholderIcon(icon))
+	}
+	if traceAPI {
+		opts = append(opts, notion.WithTrace(os.Stderr))
+	}
+	return notion.NewFactory(cfg, append(opts, extra...)...)
+}
+
+// newScanner creates the vault scanner, applying the attachment folder
+// override from the config.
+func newScanner(cfg *config.Config) *vault.Scanner {
+	scanner := vault.NewScanner(cfg.Vault, cfg.Sync.Ignore)
+	scanner.SetVaultRoot(cfg.VaultRoot())
+	if cfg.Sync.AttachmentFolder != "" {
+		scanner.SetAttachmentFolder(cfg.Sync.AttachmentFolder)
+	}
+	if cfg.Sync.IncludeTemplates {
+		scanner.SetTemplateFolders(nil)
+	}
+	return scanner
+}
+
+// newChangeDetector creates a change detector that, like the scanner,
+// leaves out template folders.
+func newChangeDetector(cfg *config.Config, db *state.DB) *state.ChangeDetector {
+	detector := state.NewChangeDetector(db, cfg.Vault)
+	detector.SetExclude(newScanner(cfg).IsTemplate)
+	detector.SetHasher(noteHasher(cfg))
+	if verbose {
+		detector.SetExplain(explainFile)
+	}
+	return detector
+}
+
+// noteHasher returns the hasher of the vault's notes, which with
+// sync.ignore_trivial_changes ignores the order of frontmatter keys.
+func noteHasher(cfg *config.Config) state.Hasher {
+	return state.Hasher{CanonicalFrontmatter: cfg.Sync.IgnoreTrivialChanges}
+}
+
+// explainFile prints, with -v, why a file is or is not synced.
+func explainFile(path, reason string) {
+	if verbose {
+		fmt.Printf("  . %s: %s\n", path, reason)
+	}
+}
+
 // buildTransformerConfig creates a transformer.Config from the app config.
 // If path is provided, it merges global and path-specific property mappings.
 func buildTransformerConfig(cfg *config.Config, path string) *transformer.Config {
 	transformerCfg := &transformer.Config{
 		UnresolvedLinkStyle: cfg.Transform.UnresolvedLinks,
 		CalloutIcons:        cfg.Transform.Callouts,
+		Icons:               cfg.Transform.Icons,
+		PluginBlocks:        cfg.Transform.PluginBlocks,
 		DataviewHandling:    cfg.Transform.Dataview,
 		FlattenHeadings:     true,
+		SplitOn:             cfg.Transform.SplitOn,
+		EmojiShortcodes:     cfg.Transform.EmojiShortcodes,
+		EmojiReverse:        cfg.Transform.EmojiReverse,
+		UserMentions:        cfg.Transform.Users,
+		DateMentions:        cfg.Transform.DateMentions,
+		HTMLHandling:        cfg.Transform.HTML,
+		TextColors:          cfg.Transform.TextColors,
+		NestedTags:          cfg.Transform.NestedTags,
+		DividerMarker:       cfg.Transform.Dividers,
+		EmptyParagraphs:     cfg.Transform.EmptyParagraphs,
+		TaskStates:          cfg.Transform.TaskStates,
+		CreatedProperty:     cfg.Transform.Dates.Created,
+		ModifiedProperty:    cfg.Transform.Dates.Modified,
+		DateLayout:          cfg.Transform.Dates.Format,
+		DateTimeLayout:      cfg.Transform.Dates.DateTimeFormat,
+		DateLocation:        cfg.Transform.Dates.Location(),
+	}
+	transformerCfg.TagRules = transformer.TagRules{
+		Case:   cfg.Transform.Tags.Case,
+		Spaces: cfg.Transform.Tags.Spaces,
+		Inline: cfg.Transform.Tags.Inline,
+	}
+
+	// Dates not in the frontmatter come from the file, if it exists yet.
+	if transformerCfg.CreatedProperty != "" || transformerCfg.ModifiedProperty != "" {
+		created, modified, err := vault.FileTimes(filepath.Join(cfg.Vault, path))
+		if err == nil {
+			transformerCfg.FileCreated = created
+			transformerCfg.FileModified = modified
+		}
+	}
+
+	// pull.wrap formats pulled paragraphs.
+	transformerCfg.WrapWidth = cfg.Pull.WrapWidth()
+	// The local note's frontmatter keeps its layout.
+	if local, err := os.ReadFile(filepath.Join(cfg.Vault, path)); err == nil {
+		transformerCfg.FrontmatterSource = local
+		if cfg.Pull.Wrap == "preserve" {
+			transformerCfg.WrapSource = local
+		}
+	}
+
+	// Notion does not store a note's targets, so pulls keep them.
+	if len(cfg.Targets) > 0 {
+		transformerCfg.NoteTargets = localNoteTargets(cfg, path)
 	}
 
 	// Convert config property mappings to transformer property mappings.
@@ -124,5 +287,165 @@ func buildTransformerConfig(cfg *config.Config, path string) *transformer.Config
 		}
 	}
 
+	// properties.map renames apply on top of the mappings.
+	for _, r := range cfg.Properties.Renames() {
+		rename := transformer.PropertyMapping{ObsidianKey: r.Obsidian, NotionName: r.Notion}
+		if r.Type != "" {
+			rename.NotionType = transformer.PropertyTypeFromString(r.Type)
+		}
+		transformerCfg.PropertyRenames = append(transformerCfg.PropertyRenames, rename)
+	}
+	transformerCfg.UnmappedProperties = cfg.Properties.Unmapped
+
 	return transformerCfg
 }
+
+// recordPushedPage stores the child pages a pushed note was split into,
+// the tags it was pushed as, and the blocks it was pushed as, so a later
+// pull can reassemble the note.
+func recordPushedPage(db *state.DB, path string, page *transformer.NotionPage) error {
+	if err := recordPageMeta(db, path, page); err != nil {
+		return err
+	}
+	return recordBlockMap(db, path, page)
+}
+
+// recordPageMeta stores the sections, tags, and task states a note was
+// pushed with.
+func recordPageMeta(db *state.DB, path string, page *transformer.NotionPage) error {
+	sections := make([]state.PageSection, len(page.Sections))
+	for i, s := range page.Sections {
+		sections[i] = state.PageSection{Title: s.Title, NotionPageID: s.PageID}
+	}
+	if err := db.SetSections(path, sections); err != nil {
+		return err
+	}
+
+	tags := make([]state.NoteTag, len(page.Tags))
+	for i, t := range page.Tags {
+		tags[i] = state.NoteTag{Tag: t.Tag, Values: t.Values}
+	}
+	if err := db.SetNoteTags(path, tags); err != nil {
+		return err
+	}
+
+	tasks := make([]state.NoteTask, len(page.Tasks))
+	for i, t := range page.Tasks {
+		tasks[i] = state.NoteTask{Text: t.Text, State: t.State}
+	}
+	return db.SetNoteTasks(path, tasks)
+}
+
+// recordBlockMap stores the Notion block each top-level block of a note was
+// pushed as or pulled from, with a hash of the block's markdown. Blocks
+// without an ID, such as those of a failed push, are left out.
+func recordBlockMap(db *state.DB, path string, page *transformer.NotionPage) error {
+	return db.SetBlockMap(path, blockMappings(page, 0))
+}
+
+// blockMappings returns the block map entries of a page's blocks, numbered
+// from first, the index of the page's first block in the note.
+func blockMappings(page *transformer.NotionPage, first int) []state.BlockMapping {
+	rt := transformer.NewReverse(nil, nil)
+	blocks := make([]state.BlockMapping, 0, len(page.Children))
+	for i, block := range page.Children {
+		id := string(block.GetID())
+		if i < len(page.BlockIDs) {
+			id = page.BlockIDs[i]
+		}
+		if id == "" {
+			continue
+		}
+		blocks = append(blocks, state.BlockMapping{
+			Index:         first + i,
+			Hash:          state.HashContentRaw([]byte(rt.BlockMarkdown(block))),
+			NotionBlockID: id,
+			Heading:       headingText(rt, block),
+			Marker:        page.Dividers[block],
+		})
+	}
+	return blocks
+}
+
+// dividerMarkers returns the markers recorded in a block map for the
+// dividers of a fetched page.
+func dividerMarkers(page *transformer.NotionPage, blocks []state.BlockMapping) map[notionapi.Block]string {
+	markers := make(map[string]string)
+	for _, b := range blocks {
+		if b.Marker != "" {
+			markers[b.NotionBlockID] = b.Marker
+		}
+	}
+	if len(markers) == 0 {
+		return nil
+	}
+	dividers := make(map[notionapi.Block]string)
+	for _, block := range page.Children {
+		if marker := markers[string(block.GetID())]; marker != "" {
+			dividers[block] = marker
+		}
+	}
+	return dividers
+}
+
+// headingText returns the text of a heading block as links to it name it,
+// or "" for other blocks.
+func headingText(rt *transformer.ReverseTransformer, block notionapi.Block) string {
+	switch b := block.(type) {
+	case *notionapi.Heading1Block:
+		return strings.TrimSpace(rt.TransformRichText(b.Heading1.RichText))
+	case *notionapi.Heading2Block:
+		return strings.TrimSpace(rt.TransformRichText(b.Heading2.RichText))
+	case *notionapi.Heading3Block:
+		return strings.TrimSpace(rt.TransformRichText(b.Heading3.RichText))
+	}
+	return ""
+}
+
+// fetchNotePage fetches a note's Notion page, including the sections it was
+// split into and the tags and task states it was pushed as.
+func fetchNotePage(ctx context.Context, client *notion.Client, db *state.DB, path, pageID string) (*transformer.NotionPage, error) {
+	page, err := client.FetchPage(ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := db.GetNoteTags(path)
+	if err != nil {
+		return nil, fmt.Errorf("get tags: %w", err)
+	}
+	for _, t := range tags {
+		page.Tags = append(page.Tags, transformer.TagSource{Tag: t.Tag, Values: t.Values})
+	}
+
+	tasks, err := db.GetNoteTasks(path)
+	if err != nil {
+		return nil, fmt.Errorf("get tasks: %w", err)
+	}
+	for _, t := range tasks {
+		page.Tasks = append(page.Tasks, transformer.TaskState{Text: t.Text, State: t.State})
+	}
+
+	blocks, err := db.GetBlockMap(path)
+	if err != nil {
+		return nil, fmt.Errorf("get block map: %w", err)
+	}
+	page.Dividers = dividerMarkers(page, blocks)
+
+	sections, err := db.GetSections(path)
+	if err != nil {
+		return nil, fmt.Errorf("get sections: %w", err)
+	}
+	if len(sections) == 0 {
+		return page, nil
+	}
+
+	known := make([]*transformer.PageSection, len(sections))
+	for i, s := range sections {
+		known[i] = &transformer.PageSection{Title: s.Title, PageID: s.NotionPageID}
+	}
+	if err := client.FetchSections(ctx, page, known); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
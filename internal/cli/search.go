@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+)
+
+var (
+	searchLimit int
+	searchAll   bool
+)
+
+// searchCmd represents the search command.
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search synced pages in Notion",
+	Long: `Search Notion for pages matching a query, using Notion's full-text search.
+
+Results are limited to pages in the configured databases (notion.default_database
+and mapping databases), pages under notion.default_page, and pages already
+tracked in the state database. Use --all to show every page the integration
+can see.
+
+Each result shows the page title, the local note it syncs with, the page
+URL, and when the page was last edited, most recent first.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSearch,
+}
+
+func init() {
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 20, "maximum number of results (0 for no limit)")
+	searchCmd.Flags().BoolVar(&searchAll, "all", false, "include pages outside the configured databases")
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// 1. Open state database.
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	// 2. Search with every integration that serves a configured database.
+	scope := newSearchScope(cfg)
+	keep := func(page *notionapi.Page) bool {
+		if searchAll || scope.contains(page) {
+			return true
+		}
+		s, err := db.GetStateByNotionID(string(page.ID))
+		return err == nil && s != nil
+	}
+
+	clients := newNotionClients(cfg)
+	seen := make(map[string]bool)
+	var pages []*notionapi.Page
+	for _, client := range scope.clients(cfg, clients) {
+		found, err := client.SearchAllPages(ctx, args[0], searchLimit, keep, listingProgress(os.Stderr, cfg.RateLimit.PageSize))
+		if err != nil {
+			return fmt.Errorf("search: %w", err)
+		}
+		for _, page := range found {
+			if !seen[string(page.ID)] {
+				seen[string(page.ID)] = true
+				pages = append(pages, page)
+			}
+		}
+	}
+
+	sort.SliceStable(pages, func(i, j int) bool {
+		return pages[i].LastEditedTime.After(pages[j].LastEditedTime)
+	})
+	if searchLimit > 0 && len(pages) > searchLimit {
+		pages = pages[:searchLimit]
+	}
+
+	// 3. Print results with their local paths.
+	if len(pages) == 0 {
+		fmt.Printf("No pages match %q.\n", args[0])
+		return nil
+	}
+
+	fmt.Printf("Found %d page(s) matching %q:\n\n", len(pages), args[0])
+	for _, page := range pages {
+		title := notion.PageTitle(page)
+		if title == "" {
+			title = "(untitled)"
+		}
+		local := "(not synced)"
+		if s, err := db.GetStateByNotionID(string(page.ID)); err == nil && s != nil {
+			local = s.ObsidianPath
+		}
+
+		fmt.Println(title)
+		fmt.Printf("  Local:  %s\n", local)
+		fmt.Printf("  URL:    %s\n", page.URL)
+		fmt.Printf("  Edited: %s\n", page.LastEditedTime.Local().Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// searchScope is the set of databases and parent pages search results are
+// limited to.
+type searchScope struct {
+	databases []string
+	pages     map[string]bool
+}
+
+// newSearchScope builds the search scope from the configured default
+// database, default page, and mapping databases.
+func newSearchScope(cfg *config.Config) *searchScope {
+	scope := &searchScope{pages: make(map[string]bool)}
+	add := func(id string) {
+		if id == "" {
+			return
+		}
+		for _, db := range scope.databases {
+			if db == id {
+				return
+			}
+		}
+		scope.databases = append(scope.databases, id)
+	}
+
+	add(cfg.Notion.DefaultDatabase)
+	for _, m := range cfg.Mappings {
+		add(m.Database)
+	}
+	if cfg.Notion.DefaultPage != "" {
+		scope.pages[normalizeNotionID(cfg.Notion.DefaultPage)] = true
+	}
+	return scope
+}
+
+// contains reports whether a page lives in one of the scope's databases or
+// directly under its parent page.
+func (s *searchScope) contains(page *notionapi.Page) bool {
+	if id := string(page.Parent.DatabaseID); id != "" {
+		for _, db := range s.databases {
+			if normalizeNotionID(db) == normalizeNotionID(id) {
+				return true
+			}
+		}
+	}
+	if id := string(page.Parent.PageID); id != "" {
+		return s.pages[normalizeNotionID(id)]
+	}
+	return false
+}
+
+// clients returns one client per integration serving the scope, so each
+// integration's workspace is searched once.
+func (s *searchScope) clients(cfg *config.Config, factory *notion.Factory) []*notion.Client {
+	if len(s.databases) == 0 {
+		return []*notion.Client{factory.ForPath("")}
+	}
+	var clients []*notion.Client
+	seen := make(map[string]bool)
+	for _, db := range s.databases {
+		token := cfg.TokenForDatabase(db)
+		if !seen[token] {
+			seen[token] = true
+			clients = append(clients, factory.ForToken(token))
+		}
+	}
+	return clients
+}
+
+// normalizeNotionID strips the dashes from a Notion ID so IDs copied from
+// URLs and IDs returned by the API compare equal.
+func normalizeNotionID(id string) string {
+	return strings.ToLower(strings.ReplaceAll(id, "-", ""))
+}
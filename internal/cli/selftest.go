@@ -0,0 +1,307 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+var selftestSandbox string
+
+// selftestCmd represents the selftest command.
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Check round trips against a sandbox Notion page",
+	Long: `Run round-trip checks against a sandbox page in Notion, to validate the
+token and configuration before syncing a real vault.
+
+Sample notes are pushed as pages under the sandbox page, with the
+configured transform settings, then read back, updated, retitled, and
+archived. Each page read back must convert to the markdown that was
+pushed. Nothing in the vault or the state database is touched.
+
+Every page the checks create is archived when they finish, even if a
+check fails or the run is interrupted; pages that cannot be archived are
+listed so they can be removed by hand. Share the sandbox page with the
+integration first.
+
+Examples:
+  obsidian-notion selftest --sandbox https://www.notion.so/team/Sandbox-0123abcd456789abcdef0123456789ab
+  obsidian-notion selftest --sandbox 0123abcd456789abcdef0123456789ab`,
+	Args: cobra.NoArgs,
+	RunE: runSelftest,
+}
+
+func init() {
+	selftestCmd.Flags().StringVar(&selftestSandbox, "sandbox", "", "the page to create test pages under, by ID or URL (required)")
+	_ = selftestCmd.MarkFlagRequired("sandbox")
+	rootCmd.AddCommand(selftestCmd)
+}
+
+// selftestNote is the note the checks push, covering the common block
+// types.
+const selftestNote = `# Heading
+
+A paragraph with **bold**, *italic*, ` + "`code`" + `, and a [link](https://example.com).
+
+- First item
+  - Nested item
+- Second item
+
+1. One
+2. Two
+
+- [ ] Open task
+- [x] Done task
+
+> A quote
+
+> [!note] Callout
+> Callout text
+
+` + "```go\nfmt.Println(\"hello\")\n```" + `
+
+| Column | Value |
+| --- | --- |
+| a | 1 |
+
+---
+
+Last paragraph.
+`
+
+// selftestUpdate is the note the update check pushes over selftestNote.
+const selftestUpdate = `# Heading
+
+An updated paragraph.
+
+- Only item
+
+Last paragraph, changed.
+`
+
+// selftestPath is the path the sample notes are transformed as. It does
+// not need to exist.
+const selftestPath = "obsidian-notion selftest.md"
+
+// selftest holds the state of a selftest run: the pages it created, which
+// are archived when it finishes.
+type selftest struct {
+	cfg     *config.Config
+	client  *notion.Client
+	sandbox string
+
+	pageID  string   // The page the checks work on.
+	created []string // Pages not yet archived.
+}
+
+// selftestCheck is one check of a selftest run.
+type selftestCheck struct {
+	name string
+	run  func(st *selftest, ctx context.Context) error
+}
+
+// selftestChecks are the checks of a run, in order. Each needs the ones
+// before it to have passed.
+var selftestChecks = []selftestCheck{
+	{"create a page", (*selftest).create},
+	{"read it back", (*selftest).readBack},
+	{"update it", (*selftest).update},
+	{"retitle it", (*selftest).retitle},
+	{"archive it", (*selftest).archive},
+}
+
+func runSelftest(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+	sandbox, ok := notion.ParsePageID(selftestSandbox)
+	if !ok {
+		return fmt.Errorf("invalid sandbox page: %s (use a page ID or URL)", selftestSandbox)
+	}
+
+	ctx, cancel := runContext(5 * time.Minute)
+	defer cancel()
+
+	st := &selftest{
+		cfg:     cfg,
+		client:  newNotionClients(cfg).ForPath(""),
+		sandbox: sandbox,
+	}
+	fmt.Printf("Running self-test under sandbox page %s...\n", sandbox)
+	passed := st.run(ctx, os.Stdout)
+	st.cleanup(context.WithoutCancel(ctx), os.Stdout)
+
+	fmt.Println()
+	if passed < len(selftestChecks) {
+		return fmt.Errorf("self-test failed: %d of %d check(s) passed", passed, len(selftestChecks))
+	}
+	fmt.Printf("Self-test passed: %d check(s)\n", passed)
+	return nil
+}
+
+// run runs the checks until one fails, writing the result of each, and
+// returns how many passed.
+func (st *selftest) run(ctx context.Context, w io.Writer) int {
+	for i, check := range selftestChecks {
+		if err := check.run(st, ctx); err != nil {
+			fmt.Fprintf(w, "  FAIL  %s: %v\n", check.name, err)
+			if hint := notionHint(err); hint != "" {
+				fmt.Fprintf(w, "        %s\n", hint)
+			}
+			return i
+		}
+		fmt.Fprintf(w, "  ok    %s\n", check.name)
+	}
+	return len(selftestChecks)
+}
+
+// cleanup archives the pages the run created and did not archive, listing
+// those it cannot.
+func (st *selftest) cleanup(ctx context.Context, w io.Writer) {
+	for _, id := range st.created {
+		if err := st.client.ArchivePage(ctx, id); err != nil {
+			fmt.Fprintf(w, "  Warning: cannot archive test page %s (remove it by hand): %v\n", notionPageURL(id), err)
+		}
+	}
+	st.created = nil
+}
+
+// transform returns the page a sample note pushes as, and the markdown
+// body it pulls back as.
+func (st *selftest) transform(content string) (*transformer.NotionPage, string, error) {
+	note, err := parser.New().Parse(selftestPath, []byte(content))
+	if err != nil {
+		return nil, "", fmt.Errorf("parse sample: %w", err)
+	}
+	page, err := transformer.New(nil, buildTransformerConfig(st.cfg, selftestPath)).Transform(note)
+	if err != nil {
+		return nil, "", fmt.Errorf("transform sample: %w", err)
+	}
+	want, err := st.markdown(page)
+	if err != nil {
+		return nil, "", err
+	}
+	return page, want, nil
+}
+
+// markdown returns the body a page pulls as.
+func (st *selftest) markdown(page *transformer.NotionPage) (string, error) {
+	md, err := transformer.NewReverse(nil, buildTransformerConfig(st.cfg, selftestPath)).NotionToMarkdown(page)
+	if err != nil {
+		return "", fmt.Errorf("transform to markdown: %w", err)
+	}
+	return string(md[len(noteFrontmatter(md)):]), nil
+}
+
+// matches reads the page back and checks that it pulls as want.
+func (st *selftest) matches(ctx context.Context, want string) error {
+	page, err := st.client.FetchPage(ctx, st.pageID)
+	if err != nil {
+		return fmt.Errorf("fetch page: %w", err)
+	}
+	got, err := st.markdown(page)
+	if err != nil {
+		return err
+	}
+	if line, diff := firstDifference(want, got); diff != "" {
+		return fmt.Errorf("page read back differs at line %d: %s", line, diff)
+	}
+	return nil
+}
+
+func (st *selftest) create(ctx context.Context) error {
+	page, _, err := st.transform(selftestNote)
+	if err != nil {
+		return err
+	}
+	// A page whose blocks failed to append is still cleaned up.
+	result, err := st.client.CreatePageUnderPage(ctx, st.sandbox, page)
+	if result != nil {
+		st.pageID = result.PageID
+		st.created = append(st.created, result.PageID)
+	}
+	return err
+}
+
+func (st *selftest) readBack(ctx context.Context) error {
+	_, want, err := st.transform(selftestNote)
+	if err != nil {
+		return err
+	}
+	return st.matches(ctx, want)
+}
+
+func (st *selftest) update(ctx context.Context) error {
+	page, want, err := st.transform(selftestUpdate)
+	if err != nil {
+		return err
+	}
+	if _, err := st.client.ReplacePage(ctx, st.pageID, page); err != nil {
+		return err
+	}
+	return st.matches(ctx, want)
+}
+
+func (st *selftest) retitle(ctx context.Context) error {
+	const title = "obsidian-notion selftest (retitled)"
+	if err := st.client.UpdatePageTitle(ctx, st.pageID, title); err != nil {
+		return err
+	}
+	page, err := st.client.GetPage(ctx, st.pageID)
+	if err != nil {
+		return fmt.Errorf("get page: %w", err)
+	}
+	if got := extractTitle(page.Properties); got != title {
+		return fmt.Errorf("title read back as %q", got)
+	}
+	return nil
+}
+
+func (st *selftest) archive(ctx context.Context) error {
+	if err := st.client.ArchivePage(ctx, st.pageID); err != nil {
+		return err
+	}
+	st.created = slices.DeleteFunc(st.created, func(id string) bool { return id == st.pageID })
+	page, err := st.client.GetPage(ctx, st.pageID)
+	if err != nil {
+		return fmt.Errorf("get page: %w", err)
+	}
+	if !page.Archived {
+		return fmt.Errorf("page is not archived")
+	}
+	return nil
+}
+
+// firstDifference returns the first line where got differs from want,
+// ignoring trailing whitespace, as the line number and both versions of
+// the line. It returns an empty diff if they match.
+func firstDifference(want, got string) (int, string) {
+	wantLines := strings.Split(strings.TrimRight(want, "\n"), "\n")
+	gotLines := strings.Split(strings.TrimRight(got, "\n"), "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = strings.TrimRight(wantLines[i], " \t")
+		}
+		if i < len(gotLines) {
+			g = strings.TrimRight(gotLines[i], " \t")
+		}
+		if w != g {
+			return i + 1, fmt.Sprintf("want %q, got %q", w, g)
+		}
+	}
+	return 0, ""
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+)
+
+var stateMigrateDryRun bool
+
+// stateCmd represents the state command.
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Maintain the sync state database",
+	Long: `Maintain the sync state database (.obsidian-notion.db in the vault).
+
+The database records which notes are synced to which Notion pages, the
+wiki-link registry, uploaded attachments, and conflict history.`,
+}
+
+// stateMigrateCmd upgrades the state database schema.
+var stateMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the state database schema",
+	Long: `Upgrade the state database to the schema version of this build.
+
+Every command upgrades the database automatically when it opens it; this
+command shows the current version and pending migrations, and applies them
+on request. Before migrating a database with data, a copy is written next
+to it as .obsidian-notion.db.v<version>.bak.
+
+Examples:
+  obsidian-notion state migrate --dry-run   # Show pending migrations
+  obsidian-notion state migrate             # Back up and migrate`,
+	Args: cobra.NoArgs,
+	RunE: runStateMigrate,
+}
+
+// stateBlocksCmd shows the block map of a note.
+var stateBlocksCmd = &cobra.Command{
+	Use:   "blocks <path>",
+	Short: "Show the Notion blocks recorded for a note",
+	Long: `Show the block map of a note: the Notion block each top-level
+markdown block was last pushed as or pulled from, with the hash of the
+block's markdown. The map is refreshed on every push and pull of the note.
+
+Examples:
+  obsidian-notion state blocks "notes/Plan.md"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runStateBlocks,
+}
+
+func init() {
+	stateMigrateCmd.Flags().BoolVarP(&stateMigrateDryRun, "dry-run", "n", false, "show pending migrations without applying them")
+	stateCmd.AddCommand(stateMigrateCmd)
+	stateCmd.AddCommand(stateBlocksCmd)
+}
+
+func runStateBlocks(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	path := filepath.Clean(args[0])
+	blocks, err := db.GetBlockMap(path)
+	if err != nil {
+		return err
+	}
+	printBlockMap(os.Stdout, path, blocks)
+	return nil
+}
+
+// printBlockMap writes the block map of a note as a table.
+func printBlockMap(out io.Writer, path string, blocks []state.BlockMapping) {
+	if len(blocks) == 0 {
+		fmt.Fprintf(out, "No blocks recorded for %s.\n", path)
+		return
+	}
+	fmt.Fprintf(out, "%d block(s) recorded for %s:\n", len(blocks), path)
+	fmt.Fprintf(out, "  %5s  %-12s  %s\n", "INDEX", "HASH", "NOTION BLOCK")
+	for _, b := range blocks {
+		hash := b.Hash
+		if len(hash) > 12 {
+			hash = hash[:12]
+		}
+		fmt.Fprintf(out, "  %5d  %-12s  %s\n", b.Index, hash, b.NotionBlockID)
+	}
+}
+
+func runStateMigrate(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+
+	// 1. Open the database without migrating it.
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	db, err := state.OpenUnmigrated(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	// 2. Report the schema version and pending migrations.
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Schema version: %d (latest: %d)\n", version, state.LatestSchemaVersion())
+
+	pending, err := db.PendingMigrations()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Println("Database is up to date.")
+		return nil
+	}
+
+	fmt.Printf("\nPending migrations:\n")
+	for _, m := range pending {
+		fmt.Printf("  %d: %s\n", m.Version, m.Description)
+	}
+
+	if stateMigrateDryRun {
+		return nil
+	}
+
+	// 3. Back up and migrate.
+	backup, err := db.Migrate()
+	if backup != "" {
+		fmt.Printf("\nBacked up to %s\n", backup)
+	}
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	fmt.Printf("Migrated to schema version %d.\n", state.LatestSchemaVersion())
+	return nil
+}
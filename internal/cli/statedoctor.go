@@ -0,0 +1,329 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+)
+
+var (
+	stateDoctorYes     bool
+	stateDoctorDryRun  bool
+	stateDoctorOffline bool
+)
+
+// stateDoctorCmd finds and repairs inconsistent sync records.
+var stateDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Find and repair inconsistent sync records",
+	Long: `Check the sync records for inconsistencies and offer to repair each:
+
+  missing file     the note no longer exists at its path. If an untracked
+                   note has the same content, the record can be relinked
+                   to it; otherwise it can be dropped.
+  duplicate page   two notes are synced to the same page. The note synced
+                   most recently keeps the page; the others can be
+                   dropped, so each is pushed to a page of its own.
+  archived page    the page is archived in Notion. It can be re-adopted,
+                   taking it out of Notion's trash, or dropped, so the
+                   note is pushed to a new page.
+  deleted page     the page no longer exists in Notion. The record can be
+                   dropped, so the note is pushed to a new page.
+
+Each repair is asked for in turn, suggesting the first. Dropping a record
+changes nothing in the vault or in Notion. Use --offline to skip the
+checks that read pages from Notion.
+
+Examples:
+  obsidian-notion state doctor --dry-run    # List the problems found
+  obsidian-notion state doctor              # Choose a repair for each
+  obsidian-notion state doctor --yes        # Apply the suggested repairs`,
+	Args: cobra.NoArgs,
+	RunE: runStateDoctor,
+}
+
+func init() {
+	stateDoctorCmd.Flags().BoolVarP(&stateDoctorYes, "yes", "y", false, "apply the suggested repair for each problem without asking")
+	stateDoctorCmd.Flags().BoolVarP(&stateDoctorDryRun, "dry-run", "n", false, "list problems without repairing them")
+	stateDoctorCmd.Flags().BoolVar(&stateDoctorOffline, "offline", false, "skip the checks that read pages from Notion")
+	stateCmd.AddCommand(stateDoctorCmd)
+}
+
+// State record repairs.
+const (
+	repairRelink  = "relink"
+	repairDrop    = "drop"
+	repairReadopt = "re-adopt"
+	repairSkip    = "skip"
+)
+
+// stateIssue is an inconsistent sync record and the repairs it allows,
+// the suggested one first.
+type stateIssue struct {
+	state    *state.SyncState
+	problem  string
+	relinkTo string // The note relink moves the record to
+	repairs  []string
+}
+
+func runStateDoctor(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+	if !stateDoctorYes && !stateDoctorDryRun && !isTerminal(os.Stdin) {
+		return fmt.Errorf("choosing repairs needs a terminal: use --yes or --dry-run")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	var clients *notion.Factory
+	if !stateDoctorOffline {
+		clients = newNotionClients(cfg)
+	}
+	issues, err := findStateIssues(ctx, cfg, db, clients)
+	if err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		fmt.Println("No problems found in the sync records.")
+		return nil
+	}
+
+	fmt.Printf("Found %d problem(s):\n", len(issues))
+	if stateDoctorDryRun {
+		for _, issue := range issues {
+			printStateIssue(os.Stdout, issue)
+		}
+		return nil
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	repaired, failed := 0, 0
+	for _, issue := range issues {
+		printStateIssue(os.Stdout, issue)
+		repair := issue.repairs[0]
+		if !stateDoctorYes {
+			if repair, err = askRepair(in, os.Stdout, issue); err != nil {
+				return err
+			}
+		}
+		if repair == repairSkip {
+			continue
+		}
+		if err := repairStateIssue(ctx, db, clients, issue, repair); err != nil {
+			printError(os.Stderr, "Error repairing", issue.state.ObsidianPath, err)
+			failed++
+			continue
+		}
+		fmt.Printf("    %s: done\n", repair)
+		repaired++
+	}
+
+	fmt.Printf("\nRepaired %d of %d problem(s)\n", repaired, len(issues))
+	if failed > 0 {
+		return fmt.Errorf("%d repair(s) failed", failed)
+	}
+	return nil
+}
+
+// findStateIssues checks the sync records for notes that no longer exist,
+// pages synced to several notes, and, given clients, pages archived or
+// deleted in Notion.
+func findStateIssues(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory) ([]stateIssue, error) {
+	states, err := db.ListStates("")
+	if err != nil {
+		return nil, fmt.Errorf("list states: %w", err)
+	}
+	exists := func(path string) bool {
+		_, err := os.Stat(filepath.Join(cfg.Vault, path))
+		return err == nil
+	}
+
+	var issues []stateIssue
+	flagged := make(map[string]bool)
+
+	// 1. Pages synced to several notes: the note synced most recently, and
+	// still in the vault, keeps the page.
+	byPage := make(map[string][]*state.SyncState)
+	for _, s := range states {
+		if s.NotionPageID != "" {
+			byPage[s.NotionPageID] = append(byPage[s.NotionPageID], s)
+		}
+	}
+	for _, s := range states {
+		group := byPage[s.NotionPageID]
+		if len(group) < 2 {
+			continue
+		}
+		delete(byPage, s.NotionPageID)
+		sort.SliceStable(group, func(i, j int) bool {
+			if a, b := exists(group[i].ObsidianPath), exists(group[j].ObsidianPath); a != b {
+				return a
+			}
+			return group[i].LastSync.After(group[j].LastSync)
+		})
+		for _, dup := range group[1:] {
+			issues = append(issues, stateIssue{
+				state:   dup,
+				problem: fmt.Sprintf("duplicate page: %s is also synced to %s", notionPageURL(dup.NotionPageID), group[0].ObsidianPath),
+				repairs: []string{repairDrop, repairSkip},
+			})
+			flagged[dup.ObsidianPath] = true
+		}
+	}
+
+	// 2. Notes no longer in the vault, relinked to an untracked note with
+	// the same content if there is one.
+	var missing []*state.SyncState
+	for _, s := range states {
+		if !flagged[s.ObsidianPath] && cfg.GetComposition(s.ObsidianPath) == nil && !exists(s.ObsidianPath) {
+			missing = append(missing, s)
+		}
+	}
+	if len(missing) > 0 {
+		moved, err := untrackedByContent(ctx, cfg, db)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range missing {
+			issue := stateIssue{state: s, problem: "missing file: the note is not in the vault"}
+			if to := moved[s.ContentHash]; to != "" {
+				issue.problem += fmt.Sprintf(", but %s has its content", to)
+				issue.relinkTo = to
+				issue.repairs = append(issue.repairs, repairRelink)
+				delete(moved, s.ContentHash)
+			}
+			issue.repairs = append(issue.repairs, repairDrop, repairSkip)
+			issues = append(issues, issue)
+			flagged[s.ObsidianPath] = true
+		}
+	}
+
+	// 3. Pages archived or deleted in Notion.
+	if clients == nil {
+		return issues, nil
+	}
+	for _, s := range states {
+		if flagged[s.ObsidianPath] || s.NotionPageID == "" || cfg.GetComposition(s.ObsidianPath) != nil {
+			continue
+		}
+		page, err := clients.ForPath(s.ObsidianPath).GetPage(ctx, s.NotionPageID)
+		switch {
+		case errors.Is(err, notion.ErrNotFound):
+			issues = append(issues, stateIssue{
+				state:   s,
+				problem: fmt.Sprintf("deleted page: %s no longer exists in Notion", notionPageURL(s.NotionPageID)),
+				repairs: []string{repairDrop, repairSkip},
+			})
+		case err != nil:
+			if stopped(ctx, err) {
+				return nil, err
+			}
+			fmt.Fprintf(os.Stderr, "  Warning: could not check %s: %v\n", s.ObsidianPath, err)
+		case page.Archived:
+			issues = append(issues, stateIssue{
+				state:   s,
+				problem: fmt.Sprintf("archived page: %s is archived in Notion", notionPageURL(s.NotionPageID)),
+				repairs: []string{repairReadopt, repairDrop, repairSkip},
+			})
+		}
+	}
+	return issues, nil
+}
+
+// untrackedByContent returns the notes in the vault without a sync record
+// by their content hash, for relinking records whose notes were moved.
+func untrackedByContent(ctx context.Context, cfg *config.Config, db *state.DB) (map[string]string, error) {
+	files, err := newScanner(cfg).Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scan vault: %w", err)
+	}
+	byHash := make(map[string]string)
+	for _, f := range files {
+		if s, _ := db.GetState(f.Path); s != nil {
+			continue
+		}
+		hashes, err := noteHasher(cfg).HashFileDetailed(f.AbsPath)
+		if err != nil {
+			continue
+		}
+		if _, dup := byHash[hashes.ContentHash]; !dup {
+			byHash[hashes.ContentHash] = f.Path
+		}
+	}
+	return byHash, nil
+}
+
+// printStateIssue writes a problem and the repairs it allows.
+func printStateIssue(out io.Writer, issue stateIssue) {
+	fmt.Fprintf(out, "  %s\n", issue.state.ObsidianPath)
+	fmt.Fprintf(out, "    %s\n", issue.problem)
+	fmt.Fprintf(out, "    repairs: %s\n", strings.Join(issue.repairs, ", "))
+}
+
+// askRepair asks which repair to apply to a problem, by name or first
+// letter. An empty answer applies the suggested repair.
+func askRepair(in *bufio.Reader, out io.Writer, issue stateIssue) (string, error) {
+	for {
+		fmt.Fprintf(out, "    Repair? [%s] ", strings.Join(issue.repairs, "/"))
+		answer, err := in.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer == "" {
+			if err != nil {
+				// Input ended: leave the rest alone.
+				return repairSkip, nil
+			}
+			return issue.repairs[0], nil
+		}
+		for _, repair := range issue.repairs {
+			if answer == repair || answer == repair[:1] {
+				return repair, nil
+			}
+		}
+		if err != nil {
+			return repairSkip, nil
+		}
+		fmt.Fprintf(out, "    Unknown repair %q\n", answer)
+	}
+}
+
+// repairStateIssue applies a repair to a problem record.
+func repairStateIssue(ctx context.Context, db *state.DB, clients *notion.Factory, issue stateIssue, repair string) error {
+	s := issue.state
+	switch repair {
+	case repairRelink:
+		return moveNote(db, s.ObsidianPath, issue.relinkTo)
+	case repairDrop:
+		return forgetNote(db, s.ObsidianPath)
+	case repairReadopt:
+		// A record a pull marked archived is pulled again by the next
+		// pull, bringing back edits made while the page was archived.
+		if err := clients.ForPath(s.ObsidianPath).RestorePage(ctx, s.NotionPageID); err != nil {
+			return fmt.Errorf("restore page: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown repair: %s", repair)
+}
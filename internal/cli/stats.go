@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+)
+
+var (
+	statsTop int
+)
+
+// statsCmd represents the stats command.
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show vault and sync statistics",
+	Long: `Summarize the size of the vault and the scope of syncing it to Notion.
+
+Reports:
+  - Vault size, note count, and average note size
+  - Synced and unsynced note counts
+  - Wiki-link totals, unresolved links, and the most-linked notes
+  - Blocks pushed and API calls, for the last run and all runs
+
+Stats are read from the vault and the local state database only; no
+Notion API calls are made. Useful for understanding sync scope before
+enabling watch mode.`,
+	RunE: runStats,
+}
+
+func init() {
+	statsCmd.Flags().IntVar(&statsTop, "top", 5, "number of most-linked notes to show")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// 1. Open state database.
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	// 2. Scan the vault.
+	scanner := newScanner(cfg)
+	files, err := scanner.Scan(ctx)
+	if err != nil {
+		return fmt.Errorf("scan vault: %w", err)
+	}
+
+	// 3. Compare notes with sync state.
+	states, err := db.ListStates("")
+	if err != nil {
+		return fmt.Errorf("list states: %w", err)
+	}
+	statusByPath := make(map[string]string, len(states))
+	for _, s := range states {
+		statusByPath[s.ObsidianPath] = s.Status
+	}
+
+	var totalSize int64
+	var synced, unsynced, conflicts int
+	for _, f := range files {
+		totalSize += f.Info.Size()
+		switch statusByPath[f.Path] {
+		case "synced":
+			synced++
+		case "conflict":
+			conflicts++
+		default:
+			unsynced++
+		}
+	}
+
+	// 4. Gather link and run statistics.
+	linkRegistry := state.NewLinkRegistry(db)
+	linkStats, err := linkRegistry.GetStats()
+	if err != nil {
+		return fmt.Errorf("get link stats: %w", err)
+	}
+	mostLinked, err := linkRegistry.MostLinked(statsTop)
+	if err != nil {
+		return fmt.Errorf("get most linked: %w", err)
+	}
+
+	lastRun, err := db.LastRun()
+	if err != nil {
+		return fmt.Errorf("get last run: %w", err)
+	}
+	runs, blocksPushed, apiCalls, err := db.RunTotals()
+	if err != nil {
+		return fmt.Errorf("get run totals: %w", err)
+	}
+
+	// Print summary.
+	fmt.Printf("Stats for: %s\n\n", cfg.Vault)
+
+	fmt.Println("Vault:")
+	fmt.Printf("  %-18s %6d\n", "Notes:", len(files))
+	fmt.Printf("  %-18s %6s\n", "Total size:", formatBytes(totalSize))
+	fmt.Printf("  %-18s %6s\n", "Average note:", formatBytes(averageSize(totalSize, len(files))))
+
+	fmt.Println()
+	fmt.Println("Sync:")
+	fmt.Printf("  %-18s %6d\n", "Synced:", synced)
+	fmt.Printf("  %-18s %6d\n", "Unsynced:", unsynced)
+	if conflicts > 0 {
+		fmt.Printf("  %-18s %6d\n", "Conflicts:", conflicts)
+	}
+
+	fmt.Println()
+	fmt.Println("Wiki-links:")
+	fmt.Printf("  %-18s %6d\n", "Total:", linkStats.Total)
+	fmt.Printf("  %-18s %6d\n", "Unresolved:", linkStats.Unresolved)
+	if len(mostLinked) > 0 {
+		fmt.Println("  Most linked:")
+		for _, l := range mostLinked {
+			fmt.Printf("    %4d  %s\n", l.Count, l.Path)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("Notion:")
+	fmt.Printf("  %-18s %6d (%d run(s))\n", "Blocks pushed:", blocksPushed, runs)
+	fmt.Printf("  %-18s %6d\n", "API calls:", apiCalls)
+	if lastRun != nil {
+		fmt.Printf("\nLast run: %s at %s (%s)\n", lastRun.Command,
+			lastRun.StartedAt.Format("2006-01-02 15:04"), lastRun.Duration.Round(time.Second))
+		fmt.Printf("  %-18s %6d\n", "Pushed:", lastRun.Pushed)
+		fmt.Printf("  %-18s %6d\n", "Pulled:", lastRun.Pulled)
+		if lastRun.Failed > 0 {
+			fmt.Printf("  %-18s %6d\n", "Failed:", lastRun.Failed)
+		}
+		fmt.Printf("  %-18s %6d\n", "Blocks pushed:", lastRun.BlocksPushed)
+		fmt.Printf("  %-18s %6d\n", "API calls:", lastRun.APICalls)
+	}
+
+	return nil
+}
+
+// averageSize returns the mean of total over n items, or 0 for none.
+func averageSize(total int64, n int) int64 {
+	if n == 0 {
+		return 0
+	}
+	return total / int64(n)
+}
+
+// recordRun stores the outcome and API usage of a run for the stats
+// command. Failures are reported as warnings.
+func recordRun(db *state.DB, clients *notion.Factory, command string, started time.Time, pushed, pulled, failed int) {
+	usage := clients.Usage()
+	run := &state.Run{
+		Command:      command,
+		StartedAt:    started,
+		Duration:     time.Since(started),
+		Pushed:       pushed,
+		Pulled:       pulled,
+		Failed:       failed,
+		APICalls:     usage.Requests,
+		BlocksPushed: usage.Blocks,
+	}
+	if err := db.RecordRun(run); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to record run: %v\n", err)
+	}
+	warnDegraded(os.Stderr, clients)
+}
+
+// warnDegraded reports blocks pushed as placeholders because the
+// configured Notion API version does not support their type.
+func warnDegraded(out io.Writer, clients *notion.Factory) {
+	degraded := clients.Degraded()
+	types := make([]string, 0, len(degraded))
+	for blockType := range degraded {
+		types = append(types, string(blockType))
+	}
+	sort.Strings(types)
+	for _, blockType := range types {
+		fmt.Fprintf(out, "  Warning: %d %s block(s) pushed as placeholders (not supported by the Notion API version; see 'obsidian-notion doctor')\n",
+			degraded[notionapi.BlockType(blockType)], blockType)
+	}
+}
@@ -2,8 +2,13 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -13,11 +18,12 @@ import (
 
 var (
 	statusShowAll bool
+	statusJSON    bool
 )
 
 // statusCmd represents the status command.
 var statusCmd = &cobra.Command{
-	Use:   "status",
+	Use:   "status [path]",
 	Short: "Show sync status",
 	Long: `Show the current sync status between Obsidian and Notion.
 
@@ -25,7 +31,10 @@ Displays counts of:
   - New files (to push)
   - Modified files (local changes to push)
   - Modified files (remote changes to pull)
+  - Retitled files (only the title frontmatter changed; push updates
+    just the page title)
   - Conflicts (both sides modified)
+  - Archived remotely (pages archived in Notion, not pulled)
   - Synced files (up to date)
 
 Example output:
@@ -33,12 +42,25 @@ Example output:
   Modified (push):  5 notes
   Modified (pull):  2 notes
   Conflicts:        1 note
-  Synced:         152 notes`,
-	RunE: runStatus,
+  Synced:         152 notes
+
+With a path, shows everything recorded for one note instead: its Notion
+page, hashes, modification times, last sync, wiki-links to and from it,
+any pending conflict, and its recorded blocks. --json prints the same as
+JSON for scripts.
+
+Examples:
+  obsidian-notion status
+  obsidian-notion status notes/Plan.md
+  obsidian-notion status notes/Plan.md --json`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeNotePaths,
+	RunE:              runStatus,
 }
 
 func init() {
 	statusCmd.Flags().BoolVarP(&statusShowAll, "all", "a", false, "show all files, not just summary")
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "with a path, print the note's status as JSON")
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
@@ -47,6 +69,10 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if statusJSON && len(args) == 0 {
+		return fmt.Errorf("--json needs a path")
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
@@ -58,8 +84,22 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	}
 	defer db.Close()
 
+	if len(args) == 1 {
+		status, err := loadFileStatus(cfg.Vault, noteHasher(cfg), db, filepath.Clean(args[0]))
+		if err != nil {
+			return err
+		}
+		if statusJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(status)
+		}
+		printFileStatus(os.Stdout, status)
+		return nil
+	}
+
 	// Get change detector.
-	detector := state.NewChangeDetector(db, cfg.Vault)
+	detector := newChangeDetector(cfg, db)
 
 	// Detect local changes.
 	changes, err := detector.DetectChanges(ctx)
@@ -68,14 +108,16 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	}
 
 	// Categorize changes.
-	var newFiles, modifiedPush, modifiedPull, renamedFiles, deletedFiles, conflicts []state.Change
+	var newFiles, modifiedPush, modifiedPull, retitled, renamedFiles, deletedFiles, conflicts []state.Change
 
 	for _, c := range changes {
 		switch c.Type {
 		case state.ChangeCreated:
 			newFiles = append(newFiles, c)
 		case state.ChangeModified:
-			if c.Direction == state.DirectionPush {
+			if c.Direction == state.DirectionPush && c.Retitled {
+				retitled = append(retitled, c)
+			} else if c.Direction == state.DirectionPush {
 				modifiedPush = append(modifiedPush, c)
 			} else {
 				modifiedPull = append(modifiedPull, c)
@@ -101,6 +143,24 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("list pending: %w", err)
 	}
 
+	// Pages a pull found archived in Notion.
+	archivedStates, err := db.ListStates("archived")
+	if err != nil {
+		return fmt.Errorf("list archived: %w", err)
+	}
+
+	// Pages that did not read back as pushed (push --verify).
+	degradedStates, err := db.ListStates(degradedStatus)
+	if err != nil {
+		return fmt.Errorf("list degraded: %w", err)
+	}
+
+	// Notes whose pages are locked in Notion (push --force-unlock-warning).
+	blockedStates, err := db.ListStates(blockedStatus)
+	if err != nil {
+		return fmt.Errorf("list blocked: %w", err)
+	}
+
 	// Get link registry stats.
 	linkRegistry := state.NewLinkRegistry(db)
 	linkStats, err := linkRegistry.GetStats()
@@ -114,9 +174,13 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	printStatusLine("New (push)", len(newFiles)+len(pendingStates))
 	printStatusLine("Modified (push)", len(modifiedPush))
 	printStatusLine("Modified (pull)", len(modifiedPull))
+	printStatusLine("Retitled", len(retitled))
 	printStatusLine("Renamed", len(renamedFiles))
 	printStatusLine("Deleted", len(deletedFiles))
 	printStatusLine("Conflicts", len(conflicts))
+	printStatusLine("Archived remotely", len(archivedStates))
+	printStatusLine("Degraded", len(degradedStates))
+	printStatusLine("Blocked (locked)", len(blockedStates))
 	printStatusLine("Synced", len(syncedStates))
 
 	// Print wiki-link statistics.
@@ -152,6 +216,13 @@ func runStatus(cmd *cobra.Command, args []string) error {
 			}
 		}
 
+		if len(retitled) > 0 {
+			fmt.Println("\nRetitled (to push):")
+			for _, c := range retitled {
+				fmt.Printf("  T %s\n", c.Path)
+			}
+		}
+
 		if len(renamedFiles) > 0 {
 			fmt.Println("\nRenamed files:")
 			for _, c := range renamedFiles {
@@ -173,6 +244,27 @@ func runStatus(cmd *cobra.Command, args []string) error {
 			}
 		}
 
+		if len(archivedStates) > 0 {
+			fmt.Println("\nArchived remotely (not pulled):")
+			for _, s := range archivedStates {
+				fmt.Printf("  A %s\n", s.ObsidianPath)
+			}
+		}
+
+		if len(degradedStates) > 0 {
+			fmt.Println("\nDegraded (did not read back as pushed; push to retry):")
+			for _, s := range degradedStates {
+				fmt.Printf("  ~ %s\n", s.ObsidianPath)
+			}
+		}
+
+		if len(blockedStates) > 0 {
+			fmt.Println("\nBlocked (page locked in Notion; unlock it and push):")
+			for _, s := range blockedStates {
+				fmt.Printf("  ! %s\n", s.ObsidianPath)
+			}
+		}
+
 		if linkStats.Unresolved > 0 && verbose {
 			fmt.Println("\nUnresolved wiki-links by source:")
 			for sourcePath, count := range linkStats.BySource {
@@ -201,3 +293,180 @@ func printLinkStatusLine(label string, count int) {
 	}
 	fmt.Printf("  %-18s %4d %s\n", label+":", count, link)
 }
+
+// fileStatus is everything recorded about one note, as status <path>
+// shows it.
+type fileStatus struct {
+	Path   string `json:"path"`
+	Status string `json:"status"` // The sync state's status, or "new" if never pushed
+
+	NotionPageID   string `json:"notion_page_id,omitempty"`
+	NotionURL      string `json:"notion_url,omitempty"`
+	NotionParentID string `json:"notion_parent_id,omitempty"`
+
+	ContentHash     string `json:"content_hash,omitempty"`
+	FrontmatterHash string `json:"frontmatter_hash,omitempty"`
+	LocalChanges    bool   `json:"local_changes"` // The file no longer has the recorded hashes
+	Missing         bool   `json:"missing,omitempty"`
+
+	ObsidianMtime time.Time `json:"obsidian_mtime,omitzero"`
+	NotionMtime   time.Time `json:"notion_mtime,omitzero"`
+	LastSync      time.Time `json:"last_sync,omitzero"`
+	SyncDirection string    `json:"sync_direction,omitempty"`
+
+	LinksTo    []fileLink `json:"links_to"`
+	LinkedFrom []string   `json:"linked_from"`
+
+	Conflict *state.ConflictInfo `json:"conflict,omitempty"`
+	Blocks   int                 `json:"blocks"` // Top-level blocks in the block map
+}
+
+// fileLink is a wiki-link from a note.
+type fileLink struct {
+	Target   string `json:"target"`
+	Path     string `json:"path,omitempty"` // The note it resolves to, if synced
+	Resolved bool   `json:"resolved"`
+}
+
+// loadFileStatus gathers the status of the note at path, relative to the
+// vault. A note with no sync state is "new" if the file exists.
+func loadFileStatus(vault string, h state.Hasher, db *state.DB, path string) (*fileStatus, error) {
+	syncState, err := db.GetState(path)
+	if err != nil {
+		return nil, fmt.Errorf("get state: %w", err)
+	}
+	hashes, hashErr := h.HashFileDetailed(filepath.Join(vault, path))
+	if syncState == nil && hashErr != nil {
+		return nil, fmt.Errorf("no sync state for path: %s", path)
+	}
+
+	status := &fileStatus{Path: path, Status: "new", LinksTo: []fileLink{}, LinkedFrom: []string{}}
+	if syncState != nil {
+		status.Status = syncState.Status
+		status.NotionPageID = syncState.NotionPageID
+		status.NotionParentID = syncState.NotionParentID
+		status.ContentHash = syncState.ContentHash
+		status.FrontmatterHash = syncState.FrontmatterHash
+		status.ObsidianMtime = syncState.ObsidianMtime
+		status.NotionMtime = syncState.NotionMtime
+		status.LastSync = syncState.LastSync
+		status.SyncDirection = syncState.SyncDirection
+		if syncState.NotionPageID != "" {
+			status.NotionURL = "https://www.notion.so/" + strings.ReplaceAll(syncState.NotionPageID, "-", "")
+		}
+	}
+	if hashErr != nil {
+		status.Missing = true
+	} else if syncState != nil {
+		status.LocalChanges = hashes.ContentHash != syncState.ContentHash || hashes.FrontmatterHash != syncState.FrontmatterHash
+	}
+
+	linkRegistry := state.NewLinkRegistry(db)
+	links, err := linkRegistry.GetLinksFrom(path)
+	if err != nil {
+		return nil, fmt.Errorf("get links: %w", err)
+	}
+	for _, l := range links {
+		link := fileLink{Target: l.TargetName, Path: l.TargetPath, Resolved: l.Resolved}
+		if link.Path == "" && l.NotionPageID != "" {
+			link.Path, _ = linkRegistry.LookupPath(l.NotionPageID)
+		}
+		status.LinksTo = append(status.LinksTo, link)
+	}
+	backlinks, err := linkRegistry.GetBacklinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("get backlinks: %w", err)
+	}
+	for _, l := range backlinks {
+		if !slices.Contains(status.LinkedFrom, l.SourcePath) {
+			status.LinkedFrom = append(status.LinkedFrom, l.SourcePath)
+		}
+	}
+
+	if status.Status == "conflict" {
+		info, err := state.NewConflictTracker(db).GetConflictInfo(path)
+		if err != nil {
+			return nil, fmt.Errorf("get conflict: %w", err)
+		}
+		if info != nil {
+			// The snapshots are the whole note; conflicts diff shows them.
+			info.LocalContent, info.RemoteContent = "", ""
+			status.Conflict = info
+		}
+	}
+
+	blocks, err := db.GetBlockMap(path)
+	if err != nil {
+		return nil, err
+	}
+	status.Blocks = len(blocks)
+
+	return status, nil
+}
+
+// printFileStatus writes the status of a note for humans.
+func printFileStatus(out io.Writer, s *fileStatus) {
+	timestamp := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	}
+	orDash := func(v string) string {
+		if v == "" {
+			return "-"
+		}
+		return v
+	}
+
+	fmt.Fprintf(out, "%s\n", s.Path)
+	status := s.Status
+	switch {
+	case s.Missing:
+		status += " (file missing)"
+	case s.LocalChanges:
+		status += " (modified locally)"
+	}
+	fmt.Fprintf(out, "  Status:           %s\n", status)
+	fmt.Fprintf(out, "  Notion page:      %s\n", orDash(s.NotionPageID))
+	if s.NotionURL != "" {
+		fmt.Fprintf(out, "  URL:              %s\n", s.NotionURL)
+	}
+	if s.NotionParentID != "" {
+		fmt.Fprintf(out, "  Parent:           %s\n", s.NotionParentID)
+	}
+	fmt.Fprintf(out, "  Content hash:     %s\n", orDash(s.ContentHash))
+	fmt.Fprintf(out, "  Frontmatter hash: %s\n", orDash(s.FrontmatterHash))
+	fmt.Fprintf(out, "  Local modified:   %s\n", timestamp(s.ObsidianMtime))
+	fmt.Fprintf(out, "  Remote modified:  %s\n", timestamp(s.NotionMtime))
+	fmt.Fprintf(out, "  Last sync:        %s (%s)\n", timestamp(s.LastSync), orDash(s.SyncDirection))
+
+	if s.Conflict != nil {
+		fmt.Fprintf(out, "\nConflict detected %s:\n", timestamp(s.Conflict.DetectedAt))
+		fmt.Fprintf(out, "  Local modified:   %s\n", timestamp(s.Conflict.LocalMtime))
+		fmt.Fprintf(out, "  Remote modified:  %s\n", timestamp(s.Conflict.RemoteMtime))
+		fmt.Fprintf(out, "  Run 'obsidian-notion conflicts diff %s' to compare.\n", s.Path)
+	}
+
+	fmt.Fprintf(out, "\nLinks to (%d):\n", len(s.LinksTo))
+	for _, l := range s.LinksTo {
+		switch {
+		case l.Path != "":
+			fmt.Fprintf(out, "  [[%s]] -> %s\n", l.Target, l.Path)
+		case l.Resolved:
+			fmt.Fprintf(out, "  [[%s]]\n", l.Target)
+		default:
+			fmt.Fprintf(out, "  [[%s]] (unresolved)\n", l.Target)
+		}
+	}
+	fmt.Fprintf(out, "\nLinked from (%d):\n", len(s.LinkedFrom))
+	for _, source := range s.LinkedFrom {
+		fmt.Fprintf(out, "  %s\n", source)
+	}
+
+	fmt.Fprintf(out, "\nBlocks: %d recorded", s.Blocks)
+	if s.Blocks > 0 {
+		fmt.Fprintf(out, " (see 'obsidian-notion state blocks %s')", s.Path)
+	}
+	fmt.Fprintln(out)
+}
@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+// streamChunkSize is how much of a streamed note is parsed, transformed,
+// and appended at a time.
+const streamChunkSize = 256 << 10
+
+// streams reports whether a note is pushed in chunks: it is larger than
+// sync.stream_note_size and not split into sections, which need the
+// whole note.
+func (pc *pushContext) streams(path string) bool {
+	threshold := pc.cfg.Sync.StreamThreshold()
+	if threshold == 0 || pc.cfg.Transform.SplitOn != "" {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(pc.cfg.Vault, path))
+	return err == nil && info.Size() > threshold
+}
+
+// eachNoteChunk parses a note one chunk at a time, calling fn with each
+// chunk's parsed note and the number of bytes read so far. The first
+// chunk carries the note's frontmatter. Only one chunk is held at a time.
+func eachNoteChunk(p *parser.Parser, fullPath, path string, fn func(i int, chunk *parser.ParsedNote, offset int64) error) error {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+	defer file.Close()
+
+	definitions, err := parser.LinkDefinitions(file)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	chunker := parser.NewChunker(file, streamChunkSize, definitions)
+	for i := 0; ; i++ {
+		content, err := chunker.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read file: %w", err)
+		}
+
+		var chunk *parser.ParsedNote
+		if i == 0 {
+			chunk, err = p.Parse(path, content)
+		} else {
+			chunk, err = p.ParseChunk(path, content)
+		}
+		if err != nil {
+			return fmt.Errorf("parse markdown: %w", err)
+		}
+		if err := fn(i, chunk, chunker.Offset()); err != nil {
+			return err
+		}
+	}
+}
+
+// summarizeNote reads a note in chunks and returns its first chunk with
+// the tags, links, embeds, and dataview queries of the whole note, enough
+// for its properties and the link registry.
+func summarizeNote(p *parser.Parser, fullPath, path string) (*parser.ParsedNote, error) {
+	var summary *parser.ParsedNote
+	err := eachNoteChunk(p, fullPath, path, func(i int, chunk *parser.ParsedNote, _ int64) error {
+		if summary == nil {
+			summary = chunk
+			return nil
+		}
+		summary.Tags = append(summary.Tags, chunk.Tags...)
+		summary.WikiLinks = append(summary.WikiLinks, chunk.WikiLinks...)
+		summary.Embeds = append(summary.Embeds, chunk.Embeds...)
+		summary.DataviewQueries = append(summary.DataviewQueries, chunk.DataviewQueries...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if summary == nil {
+		return nil, fmt.Errorf("parse markdown: empty note")
+	}
+	return summary, nil
+}
+
+// streamFile pushes a large note in chunks. The page is created or
+// replaced with the first chunk's blocks, and the blocks of each later
+// chunk are appended as it is read, reporting progress on stderr.
+//
+// The note is read twice: once for its properties and links, then for its
+// blocks. If the push fails partway, a new page is cleaned up like any
+// failed create, and an existing page is replaced in full on the next push.
+func (pc *pushContext) streamFile(ctx context.Context, f pushFile) (pushResult, error) {
+	fullPath := filepath.Join(pc.cfg.Vault, f.path)
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return pushResult{}, fmt.Errorf("read file: %w", err)
+	}
+
+	// The note is hashed before it is read, so an edit made while it
+	// streams leaves it pending.
+	hashes, err := noteHasher(pc.cfg).HashFileDetailed(fullPath)
+	if err != nil {
+		hashes = state.ContentHashes{} // Non-fatal, continue without hashes
+	}
+
+	summary, err := summarizeNote(pc.parser, fullPath, f.path)
+	if err != nil {
+		return pushResult{}, err
+	}
+	registerNoteLinks(pc.linkRegistry, f.path, summary)
+
+	client := pc.clients.ForPath(f.path)
+	attachments := &noteAttachments{notePath: f.path}
+	seenHashes := make(map[string]bool)
+	links := &placeholderRecorder{LinkResolver: pc.linkRegistry}
+	var pushed strings.Builder // Markdown of the pushed blocks, for --verify.
+	var (
+		first           *transformer.NotionPage
+		blocks          []state.BlockMapping
+		pageID, parent  string
+		isNew           bool
+		appended, total int
+	)
+
+	err = eachNoteChunk(pc.parser, fullPath, f.path, func(i int, chunk *parser.ParsedNote, offset int64) error {
+		warnUnexpandedTemplates(os.Stderr, f.path, chunk)
+
+		chunkAttachments := pc.attachments.prepare(ctx, f.path, chunk)
+		for _, hash := range chunkAttachments.hashes {
+			if !seenHashes[hash] {
+				seenHashes[hash] = true
+				attachments.hashes = append(attachments.hashes, hash)
+			}
+		}
+
+		t := transformer.New(links, buildTransformerConfig(pc.cfg, f.path))
+		t.SetAttachmentResolver(chunkAttachments)
+		if i == 0 {
+			// The page's properties come from the whole note.
+			chunk.Tags = summary.Tags
+		}
+		page, err := t.Transform(chunk)
+		if err != nil {
+			return fmt.Errorf("transform to Notion: %w", err)
+		}
+
+		if i == 0 {
+			first = page
+			pageID, parent, isNew, err = pc.writeFirstChunk(ctx, f, summary, page)
+			if err != nil {
+				return err
+			}
+		} else {
+			page.BlockIDs, err = client.AppendBlocks(ctx, pageID, page.Children)
+			if err != nil {
+				return fmt.Errorf("append blocks: %w", err)
+			}
+			first.Tasks = append(first.Tasks, page.Tasks...)
+		}
+
+		if pc.verify {
+			body, _ := pc.verifyTransformer(f.path).NotionToMarkdown(&transformer.NotionPage{Children: page.Children, Tasks: page.Tasks})
+			pushed.Write(body)
+		}
+
+		blocks = append(blocks, blockMappings(page, total)...)
+		total += len(page.Children)
+		appended += notion.CountBlocks(page.Children)
+		fmt.Fprintf(os.Stderr, "  Streaming %s: %d%% (%d blocks)\n", f.path, offset*100/max(info.Size(), 1), appended)
+		return nil
+	})
+	if err != nil {
+		if isNew {
+			// Keep the ID of a partially written page so it can be cleaned up.
+			return pushResult{pageID: pageID, parentID: parent, isNew: true}, err
+		}
+		return pushResult{}, err
+	}
+
+	if err := pc.db.SetState(&state.SyncState{
+		ObsidianPath:    f.path,
+		NotionPageID:    pageID,
+		NotionParentID:  recordedParent(f.state, parent),
+		ObsidianMtime:   f.mtime,
+		NotionMtime:     time.Now(),
+		ContentHash:     hashes.ContentHash,
+		FrontmatterHash: hashes.FrontmatterHash,
+		LastSync:        time.Now(),
+		SyncDirection:   "push",
+		Status:          pushedStatus(noteHasher(pc.cfg), fullPath, hashes),
+	}); err != nil {
+		return pushResult{}, fmt.Errorf("update state: %w", err)
+	}
+	if err := recordPageMeta(pc.db, f.path, first); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to record page state for %s: %v\n", f.path, err)
+	} else if err := pc.db.SetBlockMap(f.path, blocks); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to record page state for %s: %v\n", f.path, err)
+	}
+
+	if err := pc.attachments.commit(attachments); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to record attachments for %s: %v\n", f.path, err)
+	}
+	pushNoteTargets(ctx, os.Stderr, pc.cfg, pc.db, pc.clients, f.path, summary)
+
+	var degraded bool
+	if pc.verify {
+		degraded = pc.checkPushed(ctx, f.path, pageID, pushed.String())
+	}
+
+	return pushResult{pageID: pageID, parentID: parent, isNew: isNew, degraded: degraded, hasWikiLinks: len(summary.WikiLinks) > 0, placeholders: links.missed}, nil
+}
+
+// writeFirstChunk creates the page of a streamed note, or replaces the
+// page it already has, with the blocks of the note's first chunk.
+func (pc *pushContext) writeFirstChunk(ctx context.Context, f pushFile, summary *parser.ParsedNote, page *transformer.NotionPage) (pageID, parent string, isNew bool, err error) {
+	client := pc.clients.ForPath(f.path)
+	if f.state != nil && f.state.NotionPageID != "" {
+		previous, err := client.ReplacePage(ctx, f.state.NotionPageID, page)
+		if err != nil {
+			return "", "", false, fmt.Errorf("update page: %w", err)
+		}
+		pc.undo.updated(f.path, f.state.NotionPageID, f.state, previous)
+		return f.state.NotionPageID, "", false, nil
+	}
+
+	parent = pc.newPageParent(f.path, summary)
+	result, err := pc.createPage(ctx, client, parent, page)
+	if err != nil {
+		// Keep the ID of a partially created page so it can be cleaned up.
+		if result != nil {
+			pageID = result.PageID
+		}
+		return pageID, parent, true, fmt.Errorf("create page: %w", err)
+	}
+	pc.undo.created(f.path, result.PageID, f.state)
+	return result.PageID, parent, true, nil
+}
+
+// estimateStreamed predicts the API traffic of pushing a note in chunks,
+// reading it the way streamFile does.
+func (pc *pushContext) estimateStreamed(f pushFile) (pushEstimate, error) {
+	fullPath := filepath.Join(pc.cfg.Vault, f.path)
+	client := pc.clients.ForPath(f.path)
+	isNew := f.state == nil || f.state.NotionPageID == ""
+	est := pushEstimate{Notes: 1}
+
+	err := eachNoteChunk(pc.parser, fullPath, f.path, func(i int, chunk *parser.ParsedNote, _ int64) error {
+		page, err := transformer.New(pc.linkRegistry, buildTransformerConfig(pc.cfg, f.path)).Transform(chunk)
+		if err != nil {
+			return fmt.Errorf("transform to Notion: %w", err)
+		}
+		uploads := pc.attachments.pendingUploads(f.path, chunk)
+		requests := client.PageRequests(page, isNew)
+		if i == 0 {
+			if names := noteTargets(chunk.Frontmatter); len(names) > 1 {
+				requests += len(names) - 1 // One create or update per other target.
+			}
+		} else {
+			// Later chunks only add their blocks to the page's requests.
+			requests -= client.PageRequests(&transformer.NotionPage{}, isNew)
+		}
+		est.Requests += requests + uploads*notion.UploadRequests
+		est.Blocks += notion.CountBlocks(page.Children)
+		est.Uploads += uploads
+		return nil
+	})
+	return est, err
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	osync "github.com/adamancini/obsidian-notion-sync/internal/sync"
+)
+
+// stubTargets returns the wiki-link targets the pushed notes left as
+// placeholders that name no note in the vault, as the vault paths of the
+// stubs to create for them, by path. Links to headings and blocks name
+// their note; links to other files are left alone.
+func stubTargets(cfg *config.Config, results []osync.Task[pushFile, pushResult]) (map[string]string, error) {
+	var targets []string
+	for _, result := range results {
+		if result.Err == nil {
+			targets = append(targets, result.Result.placeholders...)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	// Notes are found by path and by name, without case, like Obsidian
+	// finds them; ignored notes exist too.
+	notes := make(map[string]bool)
+	err := filepath.WalkDir(cfg.Vault, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") && p != cfg.Vault {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.EqualFold(filepath.Ext(p), ".md") {
+			return nil
+		}
+		rel, err := filepath.Rel(cfg.Vault, p)
+		if err != nil {
+			return err
+		}
+		rel = strings.ToLower(strings.TrimSuffix(filepath.ToSlash(rel), filepath.Ext(rel)))
+		notes[rel] = true
+		notes[path.Base(rel)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan vault: %w", err)
+	}
+
+	stubs := make(map[string]string)
+	for _, target := range targets {
+		name, _, _ := strings.Cut(target, "#")
+		name, _, _ = strings.Cut(name, "^")
+		name = strings.TrimSpace(strings.TrimSuffix(name, ".md"))
+		if ext := path.Ext(name); name == "" || ext != "" && !strings.Contains(ext, " ") {
+			// A file name, such as "diagram.png", rather than a note's.
+			continue
+		}
+		if notes[strings.ToLower(name)] {
+			continue
+		}
+
+		stubPath := name + ".md"
+		if !strings.Contains(name, "/") {
+			stubPath = path.Join(filepath.ToSlash(cfg.Links.StubFolder), stubPath)
+		}
+		stubPath = path.Clean(stubPath)
+		if path.IsAbs(stubPath) || stubPath == ".." || strings.HasPrefix(stubPath, "../") {
+			continue
+		}
+		if _, ok := stubs[stubPath]; !ok {
+			stubs[stubPath] = path.Base(name)
+		}
+	}
+	return stubs, nil
+}
+
+// writeStub creates a stub note with the content of links.stub_template,
+// or empty. An existing file is not overwritten.
+func writeStub(cfg *config.Config, stubPath, title string, now time.Time) error {
+	var content []byte
+	if cfg.Links.StubTemplate != "" {
+		template, err := os.ReadFile(filepath.Join(cfg.Vault, filepath.FromSlash(cfg.Links.StubTemplate)))
+		if err != nil {
+			return fmt.Errorf("read links.stub_template: %w", err)
+		}
+		content = []byte(strings.NewReplacer(
+			"{{title}}", title,
+			"{{date}}", now.Format("2006-01-02"),
+			"{{time}}", now.Format("15:04"),
+		).Replace(string(template)))
+	}
+
+	fullPath := filepath.Join(cfg.Vault, filepath.FromSlash(stubPath))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	f, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// createStubs creates a stub note and page for each link of the pushed
+// notes to a note that does not exist (links.auto_create_stubs), so the
+// link fixup pass can turn the links into page mentions. It returns the
+// paths of the stubs created and how many failed.
+func (pc *pushContext) createStubs(ctx context.Context, results []osync.Task[pushFile, pushResult]) (created []string, failed int) {
+	stubs, err := stubTargets(pc.cfg, results)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: cannot create stubs: %v\n", err)
+		return nil, 0
+	}
+
+	now := time.Now()
+	for _, stubPath := range slices.Sorted(maps.Keys(stubs)) {
+		if err := writeStub(pc.cfg, stubPath, stubs[stubPath], now); err != nil {
+			if !errors.Is(err, fs.ErrExist) {
+				printError(os.Stderr, "Error creating stub", stubPath, err)
+				failed++
+			}
+			continue
+		}
+		if _, err := pc.processFile(ctx, pushFile{path: stubPath, changeType: state.ChangeCreated}); err != nil {
+			printError(os.Stderr, "Error pushing stub", stubPath, err)
+			failed++
+			continue
+		}
+		created = append(created, stubPath)
+		if verbose {
+			fmt.Printf("  S %s\n", stubPath)
+		}
+	}
+	return created, failed
+}
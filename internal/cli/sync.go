@@ -3,14 +3,20 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
+	"math/rand/v2"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/jomei/notionapi"
 	"github.com/spf13/cobra"
 
 	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notify"
 	"github.com/adamancini/obsidian-notion-sync/internal/notion"
 	"github.com/adamancini/obsidian-notion-sync/internal/parser"
 	"github.com/adamancini/obsidian-notion-sync/internal/state"
@@ -36,6 +42,10 @@ const (
 var (
 	syncStrategy string
 	syncDryRun   bool
+	syncNoNotify bool
+
+	syncConfirmDeletions bool
+	syncEvery            time.Duration
 )
 
 // syncCmd represents the sync command.
@@ -53,16 +63,39 @@ Conflict strategies:
   manual  - Stop and require manual resolution
   newer   - Keep whichever version is newer
 
+A note can override the strategy for itself with a notion-conflict
+frontmatter key, e.g. "notion-conflict: theirs" for meeting notes edited
+in Notion.
+
+If notify.slack or notify.smtp is configured, a summary of each sync is
+sent there, so unattended (cron) syncs report their results.
+
+If more notes were deleted than sync.max_deletions_per_run (default 25),
+the sync stops before changing anything. Rerun with --confirm-deletions
+to archive their pages.
+
+With --every, sync runs again after each interval, give or take a tenth
+so several vaults do not sync in step, until interrupted. Runs are
+skipped while the watch daemon is running, and an interrupt during a run
+stops it as it would a single sync, then stops the loop. A failed run is reported and
+retried at the next interval; only a failed first run stops the loop.
+
 Examples:
   obsidian-notion sync                     # Sync with manual conflict resolution
   obsidian-notion sync --strategy ours     # Always keep local version
-  obsidian-notion sync --strategy newer    # Keep newer version`,
+  obsidian-notion sync --strategy newer    # Keep newer version
+  obsidian-notion sync --every 30m         # Sync every 30 minutes
+  obsidian-notion sync undo                # Undo the last push, pull, or sync`,
 	RunE: runSync,
 }
 
 func init() {
 	syncCmd.Flags().StringVar(&syncStrategy, "strategy", "manual", "conflict resolution strategy (ours|theirs|manual|newer)")
 	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "show what would be synced without making changes")
+	syncCmd.Flags().BoolVar(&syncNoNotify, "no-notify", false, "don't send a sync report notification")
+	syncCmd.Flags().BoolVar(&syncConfirmDeletions, "confirm-deletions", false, "archive pages even if more notes were deleted than sync.max_deletions_per_run")
+	syncCmd.Flags().DurationVar(&syncEvery, "every", 0, "sync again after this interval (e.g. 30m) until interrupted")
+	_ = syncCmd.RegisterFlagCompletionFunc("strategy", cobra.FixedCompletions([]string{"ours", "theirs", "manual", "newer"}, cobra.ShellCompDirectiveNoFileComp))
 }
 
 // syncResult holds the results of a sync operation.
@@ -75,6 +108,65 @@ type syncResult struct {
 }
 
 func runSync(cmd *cobra.Command, args []string) error {
+	if syncEvery != 0 {
+		return runSyncEvery(syncEvery)
+	}
+	return syncOnce()
+}
+
+// minSyncInterval is the shortest interval sync --every accepts.
+const minSyncInterval = time.Minute
+
+// runSyncEvery syncs, then syncs again after each interval until
+// interrupted. An interrupt during a run stops the run, then the loop.
+func runSyncEvery(every time.Duration) error {
+	if every < minSyncInterval {
+		return fmt.Errorf("--every must be at least %s", minSyncInterval)
+	}
+	if syncDryRun {
+		return fmt.Errorf("--every cannot be combined with --dry-run")
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	for run := 1; ; run++ {
+		cfg, err := getConfig()
+		if err != nil {
+			return err
+		}
+		if pid, running := checkPIDFile(daemonPIDFile(cfg)); running {
+			fmt.Printf("Skipping sync: the watch daemon (PID: %d) is syncing the vault\n", pid)
+		} else if err := syncOnce(); err != nil {
+			if run == 1 {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "  Warning: sync failed: %v\n", err)
+		}
+
+		wait := jitterInterval(every)
+		fmt.Printf("\nNext sync at %s (Ctrl+C to stop)\n", time.Now().Add(wait).Format("15:04:05"))
+		select {
+		case <-sigCh:
+			fmt.Println("Stopped")
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// jitterInterval returns every give or take up to a tenth of it.
+func jitterInterval(every time.Duration) time.Duration {
+	spread := every / 10
+	if spread <= 0 {
+		return every
+	}
+	return every - spread + rand.N(2*spread+1)
+}
+
+// syncOnce runs one sync.
+func syncOnce() (retErr error) {
 	cfg, err := getConfig()
 	if err != nil {
 		return err
@@ -88,9 +180,22 @@ func runSync(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid conflict strategy: %s", syncStrategy)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	ctx, cancel := runContext(30 * time.Minute)
 	defer cancel()
 
+	report := &notify.Report{
+		Command:   "sync",
+		Vault:     cfg.Vault,
+		StartedAt: time.Now(),
+	}
+	if !syncDryRun && !syncNoNotify {
+		defer func() {
+			report.Err = retErr
+			report.Duration = time.Since(report.StartedAt)
+			sendReport(cfg, report)
+		}()
+	}
+
 	// 1. Open state database.
 	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
 	db, err := state.Open(dbPath)
@@ -98,12 +203,15 @@ func runSync(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
 	}
 	defer db.Close()
+	if err := useNestedDatabases(cfg, db); err != nil {
+		return err
+	}
 
-	// 2. Initialize Notion client.
-	client := notion.New(cfg.Notion.Token,
-		notion.WithRateLimit(cfg.RateLimit.RequestsPerSecond),
-		notion.WithBatchSize(cfg.RateLimit.BatchSize),
-	)
+	// 2. Initialize Notion clients.
+	clients := newNotionClients(cfg)
+	if !syncDryRun {
+		defer reportMirror(ctx, cfg, db, clients)
+	}
 
 	linkRegistry := state.NewLinkRegistry(db)
 	conflictTracker := state.NewConflictTracker(db)
@@ -117,24 +225,38 @@ func runSync(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	// 3. Detect local changes.
-	detector := state.NewChangeDetector(db, cfg.Vault)
+	detector := newChangeDetector(cfg, db)
 	localChanges, err := detector.DetectChanges(ctx)
 	if err != nil {
 		return fmt.Errorf("detect local changes: %w", err)
 	}
 
-	// 4. Detect remote changes.
-	remoteChanges, err := detector.DetectRemoteChanges(ctx, func(pageID string) (string, time.Time, error) {
-		page, err := client.GetPage(ctx, pageID)
+	// 4. Detect remote changes. A mirror never pulls, so edits made in
+	// Notion are overwritten by the next push of their note.
+	var remoteChanges []state.Change
+	if !cfg.Mirror() {
+		remoteChanges, err = detector.DetectRemoteChanges(ctx, func(pageID string) (string, time.Time, error) {
+			// Look up the local path so the page is read with its folder's integration.
+			client := clients.ForToken(cfg.Notion.Token)
+			s, _ := db.GetStateByNotionID(pageID)
+			if s != nil {
+				client = clients.ForPath(s.ObsidianPath)
+			}
+			page, err := client.GetPage(ctx, pageID)
+			if err != nil {
+				return "", time.Time{}, err
+			}
+			// An edit of our own is not a remote change.
+			if ownEdit(ctx, client, page, s) {
+				return "", time.Time{}, nil
+			}
+			// Use last_edited_time as a proxy for remote change detection.
+			// A proper implementation would compare content hashes.
+			return "", page.LastEditedTime, nil
+		})
 		if err != nil {
-			return "", time.Time{}, err
+			return fmt.Errorf("detect remote changes: %w", err)
 		}
-		// Use last_edited_time as a proxy for remote change detection.
-		// A proper implementation would compare content hashes.
-		return "", page.LastEditedTime, nil
-	})
-	if err != nil {
-		return fmt.Errorf("detect remote changes: %w", err)
 	}
 
 	// 5. Categorize changes.
@@ -172,77 +294,140 @@ func runSync(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// 6. Handle conflicts based on strategy.
-	if len(conflicts) > 0 {
-		switch strategy {
+	// 6. Handle conflicts based on strategy, or the note's own.
+	var manual []state.Change
+	resolved := make(map[ConflictStrategy]int)
+	for _, c := range conflicts {
+		noteStrategy := noteConflictStrategy(cfg, c.Path, strategy)
+		switch noteStrategy {
 		case StrategyManual:
-			// Record conflicts and stop.
-			fmt.Printf("Found %d conflict(s). Resolve manually with 'obsidian-notion conflicts'.\n\n", len(conflicts))
-			for _, c := range conflicts {
-				fmt.Printf("  ! %s\n", c.Path)
-				// Record conflict in database.
-				info := &state.ConflictInfo{
-					Path:        c.Path,
-					LocalHash:   c.LocalHash,
-					RemoteHash:  c.RemoteHash,
-					LocalMtime:  c.LocalMtime,
-					RemoteMtime: c.RemoteMtime,
-					DetectedAt:  time.Now(),
-				}
-				_ = conflictTracker.RecordConflict(info)
-			}
-			return fmt.Errorf("sync aborted: %d unresolved conflict(s)", len(conflicts))
-
+			manual = append(manual, c)
+			continue
 		case StrategyOurs:
-			// Convert conflicts to push operations.
-			for _, c := range conflicts {
-				c.Direction = state.DirectionPush
-				c.Type = state.ChangeModified
-				pushChanges = append(pushChanges, c)
-			}
-			fmt.Printf("Resolving %d conflict(s) with 'ours' strategy (keeping local)\n\n", len(conflicts))
-
+			c.Direction = state.DirectionPush
 		case StrategyTheirs:
-			// Convert conflicts to pull operations.
-			for _, c := range conflicts {
-				c.Direction = state.DirectionPull
-				c.Type = state.ChangeModified
-				pullChanges = append(pullChanges, c)
-			}
-			fmt.Printf("Resolving %d conflict(s) with 'theirs' strategy (keeping remote)\n\n", len(conflicts))
-
+			c.Direction = state.DirectionPull
 		case StrategyNewer:
 			// Compare timestamps and choose newer.
-			for _, c := range conflicts {
-				if c.LocalMtime.After(c.RemoteMtime) {
-					c.Direction = state.DirectionPush
-					c.Type = state.ChangeModified
-					pushChanges = append(pushChanges, c)
-				} else {
-					c.Direction = state.DirectionPull
-					c.Type = state.ChangeModified
-					pullChanges = append(pullChanges, c)
+			c.Direction = state.DirectionPull
+			if c.LocalMtime.After(c.RemoteMtime) {
+				c.Direction = state.DirectionPush
+			}
+		}
+		c.Type = state.ChangeModified
+		if c.Direction == state.DirectionPush {
+			pushChanges = append(pushChanges, c)
+		} else {
+			pullChanges = append(pullChanges, c)
+		}
+		resolved[noteStrategy]++
+	}
+	if n := resolved[StrategyOurs]; n > 0 {
+		fmt.Printf("Resolving %d conflict(s) with 'ours' strategy (keeping local)\n\n", n)
+	}
+	if n := resolved[StrategyTheirs]; n > 0 {
+		fmt.Printf("Resolving %d conflict(s) with 'theirs' strategy (keeping remote)\n\n", n)
+	}
+	if n := resolved[StrategyNewer]; n > 0 {
+		fmt.Printf("Resolving %d conflict(s) with 'newer' strategy\n\n", n)
+	}
+	if len(manual) > 0 {
+		// Record conflicts and stop.
+		fmt.Printf("Found %d conflict(s). Resolve manually with 'obsidian-notion conflicts'.\n\n", len(manual))
+		for _, c := range manual {
+			fmt.Printf("  ! %s\n", c.Path)
+			report.Conflicts = append(report.Conflicts, c.Path)
+			// Record conflict in database.
+			info := &state.ConflictInfo{
+				Path:        c.Path,
+				LocalHash:   c.LocalHash,
+				RemoteHash:  c.RemoteHash,
+				LocalMtime:  c.LocalMtime,
+				RemoteMtime: c.RemoteMtime,
+				DetectedAt:  time.Now(),
+			}
+			_ = conflictTracker.RecordConflict(info)
+			if !syncDryRun {
+				if err := writeRemoteSidecar(ctx, cfg, db, clients, linkRegistry, c.Path); err != nil {
+					fmt.Fprintf(os.Stderr, "  Warning: failed to save remote version of %s: %v\n", c.Path, err)
 				}
 			}
-			fmt.Printf("Resolving %d conflict(s) with 'newer' strategy\n\n", len(conflicts))
+		}
+		return fmt.Errorf("sync aborted: %d unresolved conflict(s)", len(manual))
+	}
+
+	// Leave out notes that are too large or binary.
+	scanner := newScanner(cfg)
+	var skipped []skippedFile
+	pushable := pushChanges[:0]
+	for _, c := range pushChanges {
+		if c.Type == state.ChangeCreated || c.Type == state.ChangeModified {
+			if f, ok := checkNote(cfg, scanner, c.Path); !ok {
+				skipped = append(skipped, f)
+				continue
+			}
+		}
+		pushable = append(pushable, c)
+	}
+	pushChanges = pushable
+
+	// Leave out changes in a direction their path's policy forbids,
+	// including conflicts the strategy resolved that way.
+	pushChanges, blocked := blockChanges(cfg, pushChanges, state.DirectionPush)
+	pullChanges, blockedPull := blockChanges(cfg, pullChanges, state.DirectionPull)
+	blocked = append(blocked, blockedPull...)
+
+	// Notes covered by a composition rule sync through their composed page.
+	var composedPush, composedPull []state.Change
+	composedPush, pushChanges = splitComposedChanges(cfg, pushChanges)
+	composedPull, pullChanges = splitComposedChanges(cfg, pullChanges)
+
+	// Stop a mass deletion before anything changes.
+	var trashPaths []string
+	for _, c := range pushChanges {
+		if c.Type == state.ChangeDeleted && trashesPage(cfg, c.State) {
+			trashPaths = append(trashPaths, c.Path)
+		}
+	}
+	if !syncDryRun {
+		if err := guardDeletions(os.Stdout, cfg, trashPaths, syncConfirmDeletions); err != nil {
+			return err
 		}
 	}
 
 	// 7. Show dry-run summary and exit if dry-run.
 	if syncDryRun {
-		fmt.Printf("Would push: %d change(s)\n", len(pushChanges))
+		fmt.Printf("Would push: %d change(s)\n", len(pushChanges)+len(composedPush))
 		for _, c := range pushChanges {
 			fmt.Printf("  -> %s (%s)\n", c.Path, c.Type)
 		}
-		fmt.Printf("\nWould pull: %d change(s)\n", len(pullChanges))
+		for _, c := range composedPush {
+			fmt.Printf("  -> %s (composed)\n", c.Path)
+		}
+		fmt.Printf("\nWould pull: %d change(s)\n", len(pullChanges)+len(composedPull))
 		for _, c := range pullChanges {
 			fmt.Printf("  <- %s (%s)\n", c.Path, c.Type)
 		}
+		for _, c := range composedPull {
+			fmt.Printf("  <- %s (composed)\n", c.Path)
+		}
+		for _, f := range skipped {
+			fmt.Printf("\nWould skip: %s (%s)\n", f.path, f.reason)
+		}
+		for _, b := range blocked {
+			fmt.Printf("\nWould block: %s (%s-only)\n", b.path, b.policy)
+		}
+		if err := guardDeletions(io.Discard, cfg, trashPaths, syncConfirmDeletions); err != nil {
+			fmt.Printf("\n%v\n", err)
+		}
 		return nil
 	}
 
 	// 8. Execute push operations.
+	attachments := newAttachmentUploader(cfg, db, clients, scanner)
+	undo := newUndoRecorder(cfg, db, linkRegistry, "sync", report.StartedAt)
 	var pushed, failed int32
+	var badProperties, notReached int
 	if len(pushChanges) > 0 {
 		fmt.Printf("Pushing %d change(s)...\n", len(pushChanges))
 
@@ -257,26 +442,50 @@ func runSync(cmd *cobra.Command, args []string) error {
 		pushCtx := &syncPushContext{
 			cfg:          cfg,
 			db:           db,
-			client:       client,
+			clients:      clients,
 			linkRegistry: linkRegistry,
+			attachments:  attachments,
 			parser:       parser.New(),
-			scanner:      vault.NewScanner(cfg.Vault, cfg.Sync.Ignore),
+			scanner:      scanner,
+			undo:         undo,
 		}
 
-		results := osync.ProcessWithProgress(ctx, pool, pushChanges, pushCtx.processChange, progress.SimpleCallback())
+		processChange := osync.WithTimeout(cfg.Sync.FileTimeoutPeriod(), pushCtx.processChange)
+		results := osync.ProcessWithProgress(ctx, pool, pushChanges, processChange, progress.SimpleCallback())
 		progress.Finish()
 
+		var pushedPaths []string
+		var renames []state.Change
 		for _, result := range results {
-			if result.Err != nil {
-				fmt.Fprintf(os.Stderr, "  Error pushing %s: %v\n", result.Input.Path, result.Err)
+			if stopped(ctx, result.Err) {
+				notReached++
+			} else if result.Err != nil {
+				if printPushError(os.Stderr, "Error pushing", result.Input.Path, result.Err) {
+					badProperties++
+				}
 				atomic.AddInt32(&failed, 1)
+				report.Failures = append(report.Failures, notify.Failure{Path: result.Input.Path, Err: result.Err.Error()})
 			} else {
 				atomic.AddInt32(&pushed, 1)
+				pushedPaths = append(pushedPaths, result.Input.Path)
+				if result.Input.Type == state.ChangeRenamed {
+					renames = append(renames, result.Input)
+				}
 				if verbose {
 					fmt.Printf("  -> %s\n", result.Input.Path)
 				}
 			}
 		}
+
+		// Renames run alongside other pushes, so referrers of renamed notes
+		// are repaired once all pushes have finished.
+		if len(renames) > 0 && ctx.Err() == nil {
+			linkRepair := newLinkRepairer(cfg, db, clients, linkRegistry, attachments, pushedPaths)
+			for _, c := range renames {
+				linkRepair.renamed(ctx, c.OldPath, c.Path)
+			}
+			linkRepair.flush(ctx)
+		}
 	}
 
 	// 9. Execute pull operations.
@@ -295,17 +504,23 @@ func runSync(cmd *cobra.Command, args []string) error {
 		pullCtx := &syncPullContext{
 			cfg:          cfg,
 			db:           db,
-			client:       client,
+			clients:      clients,
 			linkRegistry: linkRegistry,
+			scanner:      scanner,
+			undo:         undo,
 		}
 
-		results := osync.ProcessWithProgress(ctx, pool, pullChanges, pullCtx.processChange, progress.SimpleCallback())
+		processChange := osync.WithTimeout(cfg.Sync.FileTimeoutPeriod(), pullCtx.processChange)
+		results := osync.ProcessWithProgress(ctx, pool, pullChanges, processChange, progress.SimpleCallback())
 		progress.Finish()
 
 		for _, result := range results {
-			if result.Err != nil {
-				fmt.Fprintf(os.Stderr, "  Error pulling %s: %v\n", result.Input.Path, result.Err)
+			if stopped(ctx, result.Err) {
+				notReached++
+			} else if result.Err != nil {
+				printError(os.Stderr, "Error pulling", result.Input.Path, result.Err)
 				atomic.AddInt32(&failed, 1)
+				report.Failures = append(report.Failures, notify.Failure{Path: result.Input.Path, Err: result.Err.Error()})
 			} else {
 				atomic.AddInt32(&pulled, 1)
 				if verbose {
@@ -315,29 +530,165 @@ func runSync(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// 10. Print summary.
+	// 10. Sync composed pages. Remote edits are split out first, keeping
+	// locally changed members, and the page is then rebuilt from the vault.
+	if len(composedPush) > 0 || len(composedPull) > 0 {
+		comp := newComposer(cfg, db, clients, linkRegistry, attachments, scanner)
+
+		local := make(map[string]bool, len(composedPush))
+		var paths []string
+		for _, c := range composedPush {
+			local[c.Path] = true
+			local[c.OldPath] = true
+			paths = append(paths, composedChangePath(cfg, c))
+		}
+		for _, c := range composedPull {
+			paths = append(paths, c.Path)
+		}
+
+		for _, rule := range comp.rulesFor(paths) {
+			if ctx.Err() != nil {
+				notReached++
+				continue
+			}
+			n, err := comp.pull(ctx, rule, false, local)
+			if stopped(ctx, err) {
+				notReached++
+				continue
+			}
+			if err != nil {
+				printError(os.Stderr, "Error pulling", rule.Path, err)
+				atomic.AddInt32(&failed, 1)
+				report.Failures = append(report.Failures, notify.Failure{Path: rule.Path, Err: err.Error()})
+				continue
+			}
+			atomic.AddInt32(&pulled, int32(n))
+
+			var changed int32
+			for _, c := range composedPush {
+				if cfg.GetComposition(composedChangePath(cfg, c)) == rule {
+					changed++
+				}
+			}
+			if changed == 0 {
+				continue
+			}
+			if _, err := comp.push(ctx, rule); stopped(ctx, err) {
+				notReached++
+				continue
+			} else if err != nil {
+				printError(os.Stderr, "Error pushing", rule.Path, err)
+				atomic.AddInt32(&failed, 1)
+				report.Failures = append(report.Failures, notify.Failure{Path: rule.Path, Err: err.Error()})
+				continue
+			}
+			atomic.AddInt32(&pushed, changed)
+			if verbose {
+				fmt.Printf("  -> %s (composed)\n", rule.Path)
+			}
+		}
+	}
+
+	// 11. Print summary.
+	report.Pushed = int(pushed)
+	report.Pulled = int(pulled)
+	recordRun(db, clients, "sync", report.StartedAt, int(pushed), int(pulled), int(failed))
+	if notReached > 0 {
+		report.Details = fmt.Sprintf("%s: %d change(s) not reached, left for the next sync.", ctx.Err(), notReached)
+	}
 	fmt.Println()
-	fmt.Println("Sync complete:")
+	fmt.Println(summaryTitle(ctx, "Sync"))
 	fmt.Printf("  Pushed:    %d\n", pushed)
 	fmt.Printf("  Pulled:    %d\n", pulled)
-	if strategy == StrategyManual && len(conflicts) > 0 {
-		fmt.Printf("  Conflicts: %d (manual resolution required)\n", len(conflicts))
+	if len(manual) > 0 {
+		fmt.Printf("  Conflicts: %d (manual resolution required)\n", len(manual))
 	}
 	if failed > 0 {
 		fmt.Printf("  Failed:    %d\n", failed)
 	}
+	printNotReached(notReached, "sync")
+	printSkipped(append(skipped, attachments.skippedFiles()...))
+	printBlocked(os.Stdout, blocked)
 
+	if badProperties > 0 {
+		return propertyValuesError(badProperties)
+	}
 	return nil
 }
 
+// noteConflictStrategy returns the conflict strategy of a note: the one its
+// notion-conflict frontmatter names, or else fallback. An unknown strategy
+// is reported and ignored.
+func noteConflictStrategy(cfg *config.Config, path string, fallback ConflictStrategy) ConflictStrategy {
+	content, err := os.ReadFile(filepath.Join(cfg.Vault, path))
+	if err != nil {
+		return fallback
+	}
+	note, err := parser.New().Parse(path, content)
+	if err != nil {
+		return fallback
+	}
+	value, ok := note.Frontmatter[transformer.ConflictKey].(string)
+	if !ok || value == "" {
+		return fallback
+	}
+	switch s := ConflictStrategy(value); s {
+	case StrategyOurs, StrategyTheirs, StrategyManual, StrategyNewer:
+		return s
+	}
+	fmt.Fprintf(os.Stderr, "  Warning: %s: invalid %s %q (must be ours, theirs, newer, or manual)\n", path, transformer.ConflictKey, value)
+	return fallback
+}
+
+// ownEditWindow is how long after a note's last sync an edit to its page by
+// the integration itself is taken for that sync's own write. Notion rounds
+// edit times to the minute.
+const ownEditWindow = 2 * time.Minute
+
+// ownEdit reports whether the last edit of a synced note's page was the
+// sync's own write racing the check, rather than a remote change: it was
+// made by the integration's bot user, close to the note's last sync.
+// Later edits by the integration, such as pushes from another vault that
+// shares its token, are remote changes.
+func ownEdit(ctx context.Context, client *notion.Client, page *notionapi.Page, s *state.SyncState) bool {
+	if s == nil || s.LastSync.IsZero() || page.LastEditedTime.After(s.LastSync.Add(ownEditWindow)) {
+		return false
+	}
+	return client.EditedBySelf(ctx, page)
+}
+
+// splitComposedChanges separates changes to notes covered by a composition
+// rule from changes that sync to their own page.
+func splitComposedChanges(cfg *config.Config, changes []state.Change) (composed, rest []state.Change) {
+	for _, c := range changes {
+		if cfg.GetComposition(composedChangePath(cfg, c)) != nil {
+			composed = append(composed, c)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+	return composed, rest
+}
+
+// composedChangePath returns the path of a change that matches a
+// composition rule, preferring the new path of a rename.
+func composedChangePath(cfg *config.Config, c state.Change) string {
+	if c.OldPath != "" && cfg.GetComposition(c.Path) == nil {
+		return c.OldPath
+	}
+	return c.Path
+}
+
 // syncPushContext holds shared dependencies for push operations.
 type syncPushContext struct {
 	cfg          *config.Config
 	db           *state.DB
-	client       *notion.Client
+	clients      *notion.Factory
 	linkRegistry *state.LinkRegistry
+	attachments  *attachmentUploader
 	parser       *parser.Parser
 	scanner      *vault.Scanner
+	undo         *undoRecorder
 }
 
 // processChange processes a single change for push.
@@ -345,23 +696,11 @@ func (pc *syncPushContext) processChange(ctx context.Context, c state.Change) (s
 	// Handle deletions.
 	if c.Type == state.ChangeDeleted {
 		if c.State != nil && c.State.NotionPageID != "" {
-			strategy := pc.cfg.Sync.DeletionStrategy
-			if strategy == "" {
-				strategy = "archive"
-			}
-			switch strategy {
-			case "archive":
-				if err := pc.client.ArchivePage(ctx, c.State.NotionPageID); err != nil {
-					return struct{}{}, fmt.Errorf("archive page: %w", err)
-				}
-			case "delete":
-				if err := pc.client.DeletePage(ctx, c.State.NotionPageID); err != nil {
-					return struct{}{}, fmt.Errorf("delete page: %w", err)
-				}
+			if err := trashNotePage(ctx, pc.cfg, pc.db, pc.clients, c.Path, c.State); err != nil {
+				return struct{}{}, err
 			}
 		}
-		_ = pc.db.DeleteState(c.Path)
-		_ = pc.linkRegistry.ClearLinksFrom(c.Path)
+		_ = forgetNote(pc.db, c.Path)
 		return struct{}{}, nil
 	}
 
@@ -370,17 +709,20 @@ func (pc *syncPushContext) processChange(ctx context.Context, c state.Change) (s
 		if c.State != nil && c.State.NotionPageID != "" {
 			basename := filepath.Base(c.Path)
 			newTitle := basename[:len(basename)-len(filepath.Ext(basename))]
-			if err := pc.client.UpdatePageTitle(ctx, c.State.NotionPageID, newTitle); err != nil {
+			if err := pc.clients.ForPath(c.OldPath).UpdatePageTitle(ctx, c.State.NotionPageID, newTitle); err != nil {
 				return struct{}{}, fmt.Errorf("update page title: %w", err)
 			}
 		}
-		_ = pc.db.UpdatePath(c.OldPath, c.Path)
-		_ = pc.linkRegistry.UpdateSourcePath(c.OldPath, c.Path)
+		_ = moveNote(pc.db, c.OldPath, c.Path)
 		return struct{}{}, nil
 	}
 
 	// Handle creates and modifications.
 	fullPath := filepath.Join(pc.cfg.Vault, c.Path)
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return struct{}{}, fmt.Errorf("read file: %w", err)
+	}
 	content, err := os.ReadFile(fullPath)
 	if err != nil {
 		return struct{}{}, fmt.Errorf("read file: %w", err)
@@ -391,65 +733,73 @@ func (pc *syncPushContext) processChange(ctx context.Context, c state.Change) (s
 		return struct{}{}, fmt.Errorf("parse markdown: %w", err)
 	}
 
-	// Register wiki-links.
-	_ = pc.linkRegistry.ClearLinksFrom(c.Path)
-	if len(note.WikiLinks) > 0 {
-		targets := make([]string, len(note.WikiLinks))
-		for i, link := range note.WikiLinks {
-			targets[i] = link.Target
-		}
-		_ = pc.linkRegistry.RegisterLinks(c.Path, targets)
-	}
+	// Register the title, aliases, and wiki-links.
+	registerNoteLinks(pc.linkRegistry, c.Path, note)
+	warnUnexpandedTemplates(os.Stderr, c.Path, note)
+
+	// Upload referenced attachments, reusing earlier uploads of identical files.
+	attachments := pc.attachments.prepare(ctx, c.Path, note)
 
 	// Create transformer with path-specific property mappings.
 	t := transformer.New(pc.linkRegistry, buildTransformerConfig(pc.cfg, c.Path))
+	t.SetAttachmentResolver(attachments)
 	notionPage, err := t.Transform(note)
 	if err != nil {
 		return struct{}{}, fmt.Errorf("transform to Notion: %w", err)
 	}
 
-	var pageID string
+	var pageID, parentID string
+	var retitled bool
 	if c.State == nil || c.State.NotionPageID == "" {
 		// Create new page.
-		parentID := pc.cfg.GetDatabaseForPath(c.Path)
-		if parentID == "" {
-			parentID = pc.cfg.Notion.DefaultPage
-		}
-		result, err := pc.client.CreatePage(ctx, parentID, notionPage)
+		parentID = newPageParent(pc.cfg, c.Path, note)
+		result, err := pc.clients.ForPath(c.Path).CreatePage(ctx, parentID, notionPage)
 		if err != nil {
 			return struct{}{}, fmt.Errorf("create page: %w", err)
 		}
 		pageID = result.PageID
+		pc.undo.created(c.Path, pageID, c.State)
 	} else {
 		// Update existing page.
 		pageID = c.State.NotionPageID
-		if err := pc.client.UpdatePage(ctx, pageID, notionPage); err != nil {
-			return struct{}{}, fmt.Errorf("update page: %w", err)
+		if c.Retitled {
+			retitled, err = retitlePage(ctx, pc.clients.ForPath(c.Path), pc.db, pc.undo, c.Path, c.State, notionPage)
+			if err != nil {
+				return struct{}{}, fmt.Errorf("update title: %w", err)
+			}
+		}
+		if !retitled {
+			previous, err := pc.clients.ForPath(c.Path).ReplacePage(ctx, pageID, notionPage)
+			if err != nil {
+				return struct{}{}, fmt.Errorf("update page: %w", err)
+			}
+			pc.undo.updated(c.Path, pageID, c.State, previous)
 		}
 	}
 
-	// Update sync state.
-	hashes, _ := state.HashFileDetailed(fullPath)
-	fileInfo, _ := os.Stat(fullPath)
-	var mtime time.Time
-	if fileInfo != nil {
-		mtime = fileInfo.ModTime()
-	}
-
+	// Update sync state with the content pushed; a note edited during the
+	// push is left pending for the next sync.
+	hashes := noteHasher(pc.cfg).HashContent(content)
 	syncState := &state.SyncState{
 		ObsidianPath:    c.Path,
 		NotionPageID:    pageID,
-		ObsidianMtime:   mtime,
+		NotionParentID:  recordedParent(c.State, parentID),
+		ObsidianMtime:   info.ModTime(),
 		NotionMtime:     time.Now(),
 		ContentHash:     hashes.ContentHash,
 		FrontmatterHash: hashes.FrontmatterHash,
 		LastSync:        time.Now(),
 		SyncDirection:   "push",
-		Status:          "synced",
+		Status:          pushedStatus(noteHasher(pc.cfg), fullPath, hashes),
 	}
 	if err := pc.db.SetState(syncState); err != nil {
 		return struct{}{}, fmt.Errorf("update state: %w", err)
 	}
+	if !retitled {
+		_ = recordPushedPage(pc.db, c.Path, notionPage)
+	}
+	_ = pc.attachments.commit(attachments)
+	pushNoteTargets(ctx, os.Stderr, pc.cfg, pc.db, pc.clients, c.Path, note)
 
 	return struct{}{}, nil
 }
@@ -458,8 +808,10 @@ func (pc *syncPushContext) processChange(ctx context.Context, c state.Change) (s
 type syncPullContext struct {
 	cfg          *config.Config
 	db           *state.DB
-	client       *notion.Client
+	clients      *notion.Factory
 	linkRegistry *state.LinkRegistry
+	scanner      *vault.Scanner
+	undo         *undoRecorder
 }
 
 // processChange processes a single change for pull.
@@ -469,13 +821,17 @@ func (pc *syncPullContext) processChange(ctx context.Context, c state.Change) (s
 	}
 
 	// Fetch page from Notion.
-	notionPage, err := pc.client.FetchPage(ctx, c.State.NotionPageID)
+	notionPage, err := fetchNotePage(ctx, pc.clients.ForPath(c.Path), pc.db, c.Path, c.State.NotionPageID)
 	if err != nil {
 		return struct{}{}, fmt.Errorf("fetch page: %w", err)
 	}
 
 	// Create reverse transformer with path-specific property mappings.
-	rt := transformer.NewReverse(pc.linkRegistry, buildTransformerConfig(pc.cfg, c.Path))
+	mentions := &mentionRecorder{PathLookup: pc.linkRegistry}
+	rt := transformer.NewReverse(mentions, buildTransformerConfig(pc.cfg, c.Path))
+	rt.SetAttachmentSaver(newAttachmentDownloader(ctx, pc.cfg, pc.scanner, c.Path))
+	rt.SetBookmarkPreviewer(newBookmarkPreviewer(ctx, pc.cfg, c.Path))
+	rt.SetUserNamer(newUserNamer(ctx, pc.clients.ForPath(c.Path)))
 
 	// Transform to markdown.
 	markdown, err := rt.NotionToMarkdown(notionPage)
@@ -483,6 +839,8 @@ func (pc *syncPullContext) processChange(ctx context.Context, c state.Change) (s
 		return struct{}{}, fmt.Errorf("transform to markdown: %w", err)
 	}
 
+	pc.undo.pulled(c.Path, c.State.NotionPageID, c.State)
+
 	// Ensure directory exists.
 	fullPath := filepath.Join(pc.cfg.Vault, c.Path)
 	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
@@ -495,7 +853,7 @@ func (pc *syncPullContext) processChange(ctx context.Context, c state.Change) (s
 	}
 
 	// Update sync state.
-	hashes, _ := state.HashFileDetailed(fullPath)
+	hashes, _ := noteHasher(pc.cfg).HashFileDetailed(fullPath)
 	fileInfo, _ := os.Stat(fullPath)
 	var mtime time.Time
 	if fileInfo != nil {
@@ -514,6 +872,48 @@ func (pc *syncPullContext) processChange(ctx context.Context, c state.Change) (s
 	if err := pc.db.SetState(syncState); err != nil {
 		return struct{}{}, fmt.Errorf("update state: %w", err)
 	}
+	_ = recordBlockMap(pc.db, c.Path, notionPage)
+	recordMissingMentions(pc.linkRegistry, c.Path, mentions.missed)
 
 	return struct{}{}, nil
 }
+
+// newNotifier builds a notifier from the notify configuration, or returns
+// nil if no channel is configured.
+func newNotifier(cfg *config.Config) notify.Notifier {
+	var notifiers notify.Multi
+	if cfg.Notify.Slack.WebhookURL != "" {
+		notifiers = append(notifiers, notify.NewSlack(cfg.Notify.Slack.WebhookURL))
+	}
+	if smtpCfg := cfg.Notify.SMTP; smtpCfg.Host != "" {
+		notifiers = append(notifiers, notify.NewEmail(notify.SMTPSettings{
+			Host:     smtpCfg.Host,
+			Port:     smtpCfg.Port,
+			Username: smtpCfg.Username,
+			Password: smtpCfg.Password,
+			From:     smtpCfg.From,
+			To:       smtpCfg.To,
+		}))
+	}
+	if len(notifiers) == 0 {
+		return nil
+	}
+	return notifiers
+}
+
+// sendReport delivers a sync report if notifications are configured and
+// notify.when selects it. Delivery failures are reported but never fail
+// the sync itself.
+func sendReport(cfg *config.Config, report *notify.Report) {
+	notifier := newNotifier(cfg)
+	if notifier == nil || !notify.ShouldSend(cfg.Notify.When, report) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := notifier.Notify(ctx, report); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to send sync report: %v\n", err)
+	}
+}
@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+// noteTargets returns the target names a note lists in its notion-targets
+// frontmatter, as a list or a comma-separated string.
+func noteTargets(frontmatter map[string]any) []string {
+	var names []string
+	switch v := frontmatter[transformer.TargetsKey].(type) {
+	case string:
+		names = strings.Split(v, ",")
+	case []string:
+		names = v
+	case []any:
+		for _, item := range v {
+			names = append(names, fmt.Sprint(item))
+		}
+	}
+
+	var targets []string
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			targets = append(targets, name)
+		}
+	}
+	return targets
+}
+
+// localNoteTargets returns the notion-targets of a note in the vault, or
+// nil if it does not exist or lists none.
+func localNoteTargets(cfg *config.Config, path string) []string {
+	content, err := os.ReadFile(filepath.Join(cfg.Vault, path))
+	if err != nil {
+		return nil
+	}
+	note, err := parser.New().Parse(path, content)
+	if err != nil {
+		return nil
+	}
+	return noteTargets(note.Frontmatter)
+}
+
+// newPageParent returns where the page of a new note is created: its
+// database, or else the default page.
+func newPageParent(cfg *config.Config, path string, note *parser.ParsedNote) string {
+	if database := noteDatabase(cfg, path, note); database != "" {
+		return database
+	}
+	return cfg.Notion.DefaultPage
+}
+
+// noteDatabase returns the database of a note's page: that of its primary
+// target, the first it lists, or else of its folder mapping. It returns ""
+// if the page belongs under the default page.
+func noteDatabase(cfg *config.Config, path string, note *parser.ParsedNote) string {
+	if targets := noteTargets(note.Frontmatter); len(targets) > 0 {
+		if target, ok := cfg.Targets[targets[0]]; ok {
+			return target.Database
+		}
+	}
+	return cfg.GetDatabaseForPath(path)
+}
+
+// pushNoteTargets creates or updates the property-only pages of a note in
+// its targets other than the primary, and archives its pages in targets it
+// no longer lists. Failures are written to out as warnings, since the
+// note's own page was pushed.
+func pushNoteTargets(ctx context.Context, out io.Writer, cfg *config.Config, db *state.DB, clients *notion.Factory, path string, note *parser.ParsedNote) {
+	existing, err := db.GetNoteTargets(path)
+	if err != nil {
+		fmt.Fprintf(out, "  Warning: %s: %v\n", path, err)
+		return
+	}
+	pages := make(map[string]*state.NoteTarget, len(existing))
+	for _, t := range existing {
+		pages[t.DatabaseID] = t
+	}
+
+	targets := noteTargets(note.Frontmatter)
+	published := make(map[string]bool)
+	for i, name := range targets {
+		target, ok := cfg.Targets[name]
+		if !ok {
+			fmt.Fprintf(out, "  Warning: %s: unknown notion target %q (define it under targets)\n", path, name)
+			continue
+		}
+		if i == 0 {
+			// The primary target holds the note's own page.
+			published[target.Database] = true
+			delete(pages, target.Database)
+			continue
+		}
+		if published[target.Database] {
+			continue
+		}
+		published[target.Database] = true
+
+		if err := pushNoteTarget(ctx, cfg, db, clients, path, note, name, target, pages[target.Database]); err != nil {
+			fmt.Fprintf(out, "  Warning: %s: target %s: %v\n", path, name, err)
+		}
+	}
+
+	for _, t := range existing {
+		if published[t.DatabaseID] && pages[t.DatabaseID] == t {
+			continue
+		}
+		if err := archiveNoteTarget(ctx, clients, db, t); err != nil {
+			fmt.Fprintf(out, "  Warning: %s: target %s: %v\n", path, t.Target, err)
+		}
+	}
+}
+
+// pushNoteTarget creates the page of a note in a target database, or
+// updates the properties of the page it already has there.
+func pushNoteTarget(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory, path string, note *parser.ParsedNote, name string, target config.TargetConfig, existing *state.NoteTarget) error {
+	page, err := targetPage(cfg, path, note, target)
+	if err != nil {
+		return err
+	}
+
+	client := clients.ForDatabase(target.Database)
+	var pageID string
+	if existing != nil {
+		pageID = existing.NotionPageID
+		if err := client.UpdatePageProperties(ctx, pageID, page.Properties); err != nil {
+			return err
+		}
+	} else {
+		result, err := client.CreatePage(ctx, target.Database, page)
+		if err != nil {
+			return err
+		}
+		pageID = result.PageID
+	}
+
+	return db.SetNoteTarget(&state.NoteTarget{
+		ObsidianPath: path,
+		DatabaseID:   target.Database,
+		Target:       name,
+		NotionPageID: pageID,
+		LastSync:     time.Now(),
+	})
+}
+
+// targetPage builds the property-only page of a note for a target, with
+// the properties the target's mappings select.
+func targetPage(cfg *config.Config, path string, note *parser.ParsedNote, target config.TargetConfig) (*transformer.NotionPage, error) {
+	tcfg := buildTransformerConfig(cfg, path)
+	tcfg.PropertyMappings = nil
+	tcfg.PropertyRenames = nil
+	tcfg.UnmappedProperties = transformer.UnmappedIgnore
+	tcfg.SplitOn = ""
+	for _, m := range target.Properties {
+		tcfg.PropertyMappings = append(tcfg.PropertyMappings, transformer.PropertyMappingFromConfig(m.Obsidian, m.Notion, m.Type))
+	}
+
+	page, err := transformer.New(nil, tcfg).Transform(note)
+	if err != nil {
+		return nil, fmt.Errorf("transform to Notion: %w", err)
+	}
+	return &transformer.NotionPage{Properties: page.Properties}, nil
+}
+
+// archiveNoteTarget archives the page of a note in a target database and
+// forgets it.
+func archiveNoteTarget(ctx context.Context, clients *notion.Factory, db *state.DB, t *state.NoteTarget) error {
+	if err := clients.ForDatabase(t.DatabaseID).ArchivePage(ctx, t.NotionPageID); err != nil && !errors.Is(err, notion.ErrNotFound) {
+		return fmt.Errorf("archive page: %w", err)
+	}
+	return db.DeleteNoteTarget(t.ObsidianPath, t.DatabaseID)
+}
+
+// archiveNoteTargets archives the target pages of a deleted note.
+func archiveNoteTargets(ctx context.Context, clients *notion.Factory, db *state.DB, path string) error {
+	targets, err := db.GetNoteTargets(path)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		if err := archiveNoteTarget(ctx, clients, db, t); err != nil {
+			return fmt.Errorf("target %s: %w", t.Target, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+// templateCache holds the template pages a push has read, so each is
+// read once however many pages are created from it.
+type templateCache struct {
+	mu        sync.Mutex
+	templates map[string]*notion.Template
+	errs      map[string]error
+}
+
+// get returns a template page, reading it the first time. The blocks it
+// cannot copy are reported then. A nil cache reads the page every time.
+func (tc *templateCache) get(ctx context.Context, client *notion.Client, ref string) (*notion.Template, error) {
+	if tc == nil {
+		return fetchTemplate(ctx, client, ref)
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if t, ok := tc.templates[ref]; ok {
+		return t, nil
+	}
+	if err, ok := tc.errs[ref]; ok {
+		return nil, err
+	}
+	if tc.templates == nil {
+		tc.templates = make(map[string]*notion.Template)
+		tc.errs = make(map[string]error)
+	}
+
+	t, err := fetchTemplate(ctx, client, ref)
+	if err != nil {
+		tc.errs[ref] = err
+		return nil, err
+	}
+	if len(t.Skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "  Warning: template %s has blocks the API cannot copy, left out of new pages: %s\n", ref, strings.Join(t.Skipped, ", "))
+	}
+	tc.templates[ref] = t
+	return t, nil
+}
+
+// fetchTemplate reads the template page a reference names.
+func fetchTemplate(ctx context.Context, client *notion.Client, ref string) (*notion.Template, error) {
+	pageID, ok := notion.ParsePageID(ref)
+	if !ok {
+		return nil, fmt.Errorf("invalid template page: %s (use a page ID or URL)", ref)
+	}
+	t, err := client.FetchTemplate(ctx, pageID)
+	if err != nil {
+		return nil, fmt.Errorf("read template %s: %w", ref, err)
+	}
+	return t, nil
+}
+
+// applyTemplate puts a new note's page in the template configured for its
+// path (template.page or its mapping's template). It returns the
+// template's blocks before and after the note's, or none if the path has
+// no template.
+func (pc *pushContext) applyTemplate(ctx context.Context, path string, page *transformer.NotionPage) (before, after []notionapi.Block, err error) {
+	ref := pc.cfg.TemplateForPath(path)
+	if ref == "" {
+		return nil, nil, nil
+	}
+	t, err := pc.templates.get(ctx, pc.clients.ForPath(path), ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t.Apply(page, pc.cfg.Template.Marker)
+}
+
+// writeTemplated adds the markdown of the template blocks a note's page
+// was created with around the note's body, so the note matches its page
+// and later pushes keep the template's content. The note's own content is
+// left as it is.
+func writeTemplated(fullPath string, rt *transformer.ReverseTransformer, before, after []notionapi.Block) error {
+	if len(before) == 0 && len(after) == 0 {
+		return nil
+	}
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return fmt.Errorf("read note: %w", err)
+	}
+	fm := noteFrontmatter(content)
+	body := bytes.TrimRight(content[len(fm):], "\n")
+
+	var buf bytes.Buffer
+	buf.Write(fm)
+	if md := blocksMarkdown(rt, before); md != "" {
+		buf.WriteString(md + "\n\n")
+	}
+	if len(body) > 0 {
+		buf.Write(body)
+		buf.WriteString("\n")
+	}
+	if md := blocksMarkdown(rt, after); md != "" {
+		if len(body) > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(md + "\n")
+	}
+	if err := os.WriteFile(fullPath, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write note: %w", err)
+	}
+	return nil
+}
+
+// blocksMarkdown returns the markdown of top-level blocks, without
+// trailing blank lines.
+func blocksMarkdown(rt *transformer.ReverseTransformer, blocks []notionapi.Block) string {
+	var b strings.Builder
+	for _, block := range blocks {
+		b.WriteString(rt.BlockMarkdown(block))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
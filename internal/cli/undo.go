@@ -0,0 +1,294 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/config"
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+var (
+	undoDryRun bool
+)
+
+// undoCmd represents the sync undo subcommand.
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Undo the last push, pull, or sync",
+	Long: `Restore the notes changed by the last push, pull, or sync to the state
+they were in before it ran.
+
+Before each run, the affected notes are snapshotted:
+  - Pulled notes: the local file and its sync state
+  - Updated pages: the Notion page content, converted to markdown
+  - Created pages: the page ID, so the page can be archived
+
+Undo writes pulled notes back to disk, re-pushes the previous content of
+updated pages, and archives created pages. Local edits that were pushed
+are kept and show up as pending changes again. Deletions and renames are
+not undone.
+
+Snapshots are kept for sync.undo_retention (default 168h). Running undo
+again undoes the run before that.`,
+	Args: cobra.NoArgs,
+	RunE: runUndo,
+}
+
+func init() {
+	undoCmd.Flags().BoolVar(&undoDryRun, "dry-run", false, "show what would be restored without making changes")
+	syncCmd.AddCommand(undoCmd)
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+	if err := refuseMirror(cfg, "sync undo"); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	// 1. Open state database.
+	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
+	db, err := state.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
+	}
+	defer db.Close()
+
+	// 2. Find the last run with snapshots.
+	run, err := db.LastUndoRun()
+	if err != nil {
+		return fmt.Errorf("get last run: %w", err)
+	}
+	if run == nil {
+		fmt.Println("Nothing to undo.")
+		return nil
+	}
+
+	fmt.Printf("Undoing %s from %s (%d note(s))\n", run.Command,
+		run.StartedAt.Format("2006-01-02 15:04"), len(run.Snapshots))
+
+	if undoDryRun {
+		for _, s := range run.Snapshots {
+			fmt.Printf("  - %s: %s\n", s.Path, undoDescription(s))
+		}
+		return nil
+	}
+
+	// 3. Restore each note.
+	clients := newNotionClients(cfg)
+	linkRegistry := state.NewLinkRegistry(db)
+	var restored, failed int
+	for _, s := range run.Snapshots {
+		if err := restoreSnapshot(ctx, cfg, db, clients, linkRegistry, s); err != nil {
+			printError(os.Stderr, "Error restoring", s.Path, err)
+			failed++
+			continue
+		}
+		restored++
+		if verbose {
+			fmt.Printf("  %s: %s\n", s.Path, undoDescription(s))
+		}
+	}
+
+	// 4. Drop the run once fully undone, so the next undo goes further back.
+	fmt.Println()
+	fmt.Println("Undo complete:")
+	fmt.Printf("  Restored: %d\n", restored)
+	if failed > 0 {
+		fmt.Printf("  Failed:   %d\n", failed)
+		return fmt.Errorf("%d note(s) could not be restored; run 'obsidian-notion sync undo' again to retry", failed)
+	}
+	if err := db.DeleteUndoRun(run.ID); err != nil {
+		return fmt.Errorf("delete undo run: %w", err)
+	}
+	return nil
+}
+
+// undoDescription describes what undoing a snapshot does.
+func undoDescription(s *state.Snapshot) string {
+	switch s.Action {
+	case state.SnapshotPulled:
+		if s.Content == nil {
+			return "remove pulled file"
+		}
+		return "restore local file"
+	case state.SnapshotUpdated:
+		return "restore Notion page"
+	case state.SnapshotCreated:
+		return "archive created Notion page"
+	}
+	return s.Action
+}
+
+// restoreSnapshot reverses what a run did to a single note.
+func restoreSnapshot(ctx context.Context, cfg *config.Config, db *state.DB, clients *notion.Factory, linkRegistry *state.LinkRegistry, s *state.Snapshot) error {
+	prior := s.Prior
+
+	switch s.Action {
+	case state.SnapshotPulled:
+		fullPath := filepath.Join(cfg.Vault, s.Path)
+		if s.Content == nil {
+			if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("remove file: %w", err)
+			}
+		} else {
+			if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+				return fmt.Errorf("create directory: %w", err)
+			}
+			if err := os.WriteFile(fullPath, s.Content, 0644); err != nil {
+				return fmt.Errorf("write file: %w", err)
+			}
+		}
+
+	case state.SnapshotUpdated:
+		note, err := parser.New().Parse(s.Path, s.Remote)
+		if err != nil {
+			return fmt.Errorf("parse snapshot: %w", err)
+		}
+		t := transformer.New(linkRegistry, buildTransformerConfig(cfg, s.Path))
+		notionPage, err := t.Transform(note)
+		if err != nil {
+			return fmt.Errorf("transform snapshot: %w", err)
+		}
+		if err := clients.ForPath(s.Path).UpdatePage(ctx, s.NotionPageID, notionPage); err != nil {
+			return fmt.Errorf("update page: %w", err)
+		}
+		if err := recordPushedPage(db, s.Path, notionPage); err != nil {
+			return fmt.Errorf("record page state: %w", err)
+		}
+		// The page now matches the previous sync, not a remote edit.
+		if prior != nil {
+			prior.NotionMtime = time.Now()
+		}
+
+	case state.SnapshotCreated:
+		if err := clients.ForPath(s.Path).ArchivePage(ctx, s.NotionPageID); err != nil && !errors.Is(err, notion.ErrNotFound) {
+			return fmt.Errorf("archive page: %w", err)
+		}
+
+	default:
+		return fmt.Errorf("unknown snapshot action: %s", s.Action)
+	}
+
+	if prior == nil {
+		return db.DeleteState(s.Path)
+	}
+	return db.SetState(prior)
+}
+
+// undoRecorder snapshots notes before a push, pull, or sync changes them,
+// so "sync undo" can restore them. A nil recorder records nothing.
+type undoRecorder struct {
+	cfg          *config.Config
+	db           *state.DB
+	linkRegistry *state.LinkRegistry
+	runID        int64
+}
+
+// newUndoRecorder starts recording a run and prunes snapshots older than
+// the retention window. It returns nil when snapshots are disabled or
+// cannot be recorded.
+func newUndoRecorder(cfg *config.Config, db *state.DB, linkRegistry *state.LinkRegistry, command string, started time.Time) *undoRecorder {
+	retention := cfg.Sync.UndoRetentionPeriod()
+	if retention <= 0 {
+		return nil
+	}
+	if err := db.PruneUndoRuns(started.Add(-retention)); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to prune undo snapshots: %v\n", err)
+	}
+	runID, err := db.BeginUndoRun(command, started)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to start undo snapshots, this run cannot be undone: %v\n", err)
+		return nil
+	}
+	return &undoRecorder{cfg: cfg, db: db, linkRegistry: linkRegistry, runID: runID}
+}
+
+// pulled snapshots a local file and its sync state before a pull
+// overwrites them.
+func (u *undoRecorder) pulled(path, pageID string, prior *state.SyncState) {
+	if u == nil {
+		return
+	}
+	content, err := os.ReadFile(filepath.Join(u.cfg.Vault, path))
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to snapshot %s: %v\n", path, err)
+		return
+	}
+	u.save(&state.Snapshot{
+		Path:         path,
+		Action:       state.SnapshotPulled,
+		Content:      content,
+		NotionPageID: pageID,
+		Prior:        prior,
+	})
+}
+
+// updated snapshots the content a push replaced on a Notion page. It must
+// run before the pushed page is recorded, so the previous tags and task
+// states are used.
+func (u *undoRecorder) updated(path, pageID string, prior *state.SyncState, previous *transformer.NotionPage) {
+	if u == nil || previous == nil {
+		return
+	}
+	tags, err := u.db.GetNoteTags(path)
+	if err == nil {
+		for _, t := range tags {
+			previous.Tags = append(previous.Tags, transformer.TagSource{Tag: t.Tag, Values: t.Values})
+		}
+	}
+	tasks, err := u.db.GetNoteTasks(path)
+	if err == nil {
+		for _, t := range tasks {
+			previous.Tasks = append(previous.Tasks, transformer.TaskState{Text: t.Text, State: t.State})
+		}
+	}
+	rt := transformer.NewReverse(u.linkRegistry, buildTransformerConfig(u.cfg, path))
+	markdown, err := rt.NotionToMarkdown(previous)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to snapshot %s: %v\n", path, err)
+		return
+	}
+	u.save(&state.Snapshot{
+		Path:         path,
+		Action:       state.SnapshotUpdated,
+		Remote:       markdown,
+		NotionPageID: pageID,
+		Prior:        prior,
+	})
+}
+
+// created records a page created by the run.
+func (u *undoRecorder) created(path, pageID string, prior *state.SyncState) {
+	if u == nil {
+		return
+	}
+	u.save(&state.Snapshot{
+		Path:         path,
+		Action:       state.SnapshotCreated,
+		NotionPageID: pageID,
+		Prior:        prior,
+	})
+}
+
+// save stores a snapshot, reporting failures as warnings.
+func (u *undoRecorder) save(s *state.Snapshot) {
+	if err := u.db.SaveSnapshot(u.runID, s); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to snapshot %s: %v\n", s.Path, err)
+	}
+}
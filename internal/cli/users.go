@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+)
+
+// userNamer names pulled user mentions with the Notion users API. Users
+// the integration cannot read, because it lacks the user information
+// capability, keep the name Notion sent with the mention.
+type userNamer struct {
+	ctx    context.Context
+	client *notion.Client
+}
+
+// newUserNamer returns a userNamer that looks users up with client, which
+// caches them for the rest of the run.
+func newUserNamer(ctx context.Context, client *notion.Client) *userNamer {
+	return &userNamer{ctx: ctx, client: client}
+}
+
+// UserName implements transformer.UserNamer.
+func (n *userNamer) UserName(userID string) (string, bool) {
+	name, err := n.client.UserName(n.ctx, userID)
+	return name, err == nil && name != ""
+}
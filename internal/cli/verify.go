@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/diff"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+// degradedStatus marks a note whose page did not read back as it was
+// pushed (push --verify). The next push sends it again.
+const degradedStatus = "degraded"
+
+// verifyTransformer returns the reverse transformer push --verify renders
+// pages with. Paragraphs are not rewrapped, and every Notion-hosted file
+// gets the same name, so a page read back renders like the blocks pushed
+// for it, whose uploads have no URL yet.
+func (pc *pushContext) verifyTransformer(path string) *transformer.ReverseTransformer {
+	cfg := buildTransformerConfig(pc.cfg, path)
+	cfg.WrapWidth = 0
+	cfg.WrapSource = nil
+	rt := transformer.NewReverse(pc.linkRegistry, cfg)
+	rt.SetAttachmentSaver(verifySaver{})
+	return rt
+}
+
+// verifySaver names every Notion-hosted file "attachment" without
+// downloading it.
+type verifySaver struct{}
+
+// SaveAttachment implements transformer.AttachmentSaver.
+func (verifySaver) SaveAttachment(url, name string) (string, bool) {
+	return "attachment", true
+}
+
+// verifyDigest returns the hash push --verify compares, of verifyBody.
+func verifyDigest(markdown string) string {
+	return state.HashContentRaw([]byte(verifyBody(markdown)))
+}
+
+// verifyBody returns what push --verify compares: the note body without
+// frontmatter, blank lines, or trailing whitespace, which pages read back
+// in one piece and notes pushed in chunks render differently.
+func verifyBody(markdown string) string {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	if len(lines) > 0 && lines[0] == "---" {
+		for i := 1; i < len(lines); i++ {
+			if lines[i] == "---" {
+				lines = lines[i+1:]
+				break
+			}
+		}
+	}
+
+	var kept []string
+	for _, line := range lines {
+		if line = strings.TrimRight(line, " \t"); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n") + "\n"
+}
+
+// verifyDiffLines is how many lines of a word diff push --verify shows for
+// a page that did not read back as pushed.
+const verifyDiffLines = 10
+
+// verifyDiff returns the lines that differ between the markdown pushed for
+// a page and the page read back, as a word diff indented under a warning.
+func verifyDiff(pushed, readBack string) string {
+	out := diff.UnifiedWords("pushed", "read back", verifyBody(pushed), verifyBody(readBack), 0, diff.PlainMarks)
+	var b strings.Builder
+	var shown, more int
+	for _, line := range diff.SplitLines(out) {
+		if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "@@") {
+			continue
+		}
+		if shown == verifyDiffLines {
+			more++
+			continue
+		}
+		b.WriteString("    " + line + "\n")
+		shown++
+	}
+	if more > 0 {
+		fmt.Fprintf(&b, "    ... %d more line(s)\n", more)
+	}
+	return b.String()
+}
+
+// checkPushed reads a pushed page back from Notion and compares it with
+// pushed, the markdown of the blocks pushed for it. A page that differs,
+// such as one Notion truncated, marks the note degraded and reports true,
+// showing the words that differ. Failing to read the page back is only a
+// warning.
+func (pc *pushContext) checkPushed(ctx context.Context, path, pageID, pushed string) bool {
+	page, err := fetchNotePage(ctx, pc.clients.ForPath(path), pc.db, path, pageID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: cannot verify %s: %v\n", path, err)
+		return false
+	}
+	markdown, err := pc.verifyTransformer(path).NotionToMarkdown(page)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: cannot verify %s: %v\n", path, err)
+		return false
+	}
+	if verifyDigest(string(markdown)) == verifyDigest(pushed) {
+		return false
+	}
+
+	fmt.Fprintf(os.Stderr, "  Warning: %s did not read back as pushed; marked degraded\n%s", path, verifyDiff(pushed, string(markdown)))
+	syncState, err := pc.db.GetState(path)
+	if err == nil && syncState != nil {
+		syncState.Status = degradedStatus
+		err = pc.db.SetState(syncState)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: failed to mark %s degraded: %v\n", path, err)
+	}
+	return true
+}
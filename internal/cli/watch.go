@@ -1,12 +1,15 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,6 +34,7 @@ var (
 	watchPIDFile      string
 	watchLogFile      string
 	watchStrategy     string
+	watchAPIListen    string
 )
 
 // watchCmd represents the watch command.
@@ -41,6 +45,9 @@ var watchCmd = &cobra.Command{
 
 This command runs continuously, monitoring your vault for changes and pushing
 them to Notion. It can also optionally poll Notion for remote changes.
+Pages Notion only reformatted, whose markdown reads back the same as when
+they were last synced, are not pulled. A note's notion-conflict
+frontmatter overrides --strategy for that note.
 
 Examples:
   obsidian-notion watch                       # Watch with default settings
@@ -48,6 +55,27 @@ Examples:
   obsidian-notion watch --poll-interval 1m    # Check Notion every minute
   obsidian-notion watch --daemon              # Run as background process
   obsidian-notion watch --strategy ours       # Auto-resolve conflicts with local version
+  obsidian-notion watch --api-listen 127.0.0.1:27125  # Serve the local HTTP API
+
+The local HTTP API (watch.api in the config) lets an Obsidian plugin or
+scripts sync a note immediately and show its sync status. Requests must
+send "Authorization: Bearer <watch.api.token>":
+  POST /sync/file   {"path": "Notes/Today.md"}
+  GET  /status      vault summary, or ?path=Notes/Today.md for one note
+  GET  /conflicts   notes in conflict
+  POST /pause       queue changes without syncing them
+  POST /resume      sync the queued changes again
+
+During bulk edits, 'obsidian-notion watch pause' holds back syncing and
+Notion polling in the daemon, queueing changed notes, until 'obsidian-notion
+watch resume'. With watch.coalesce_on_resume, the queue is synced in one
+batch on resume.
+
+Notes synced in by iCloud or Obsidian Sync can arrive in pieces. A changed
+note is only synced once its size and modification time have stayed the
+same for watch.settle (default 1s), temporary files such as *.tmp and
+*.icloud are ignored, and a note that is empty, fails to parse, or ends
+inside a code block is retried a few times before it is pushed.
 
 Press Ctrl+C to stop watching.`,
 	RunE: runWatch,
@@ -60,6 +88,8 @@ func init() {
 	watchCmd.Flags().StringVar(&watchPIDFile, "pid-file", "", "PID file for daemon mode")
 	watchCmd.Flags().StringVar(&watchLogFile, "log-file", "", "log file for daemon mode")
 	watchCmd.Flags().StringVar(&watchStrategy, "strategy", "manual", "conflict resolution strategy (ours|theirs|manual|newer)")
+	watchCmd.Flags().StringVar(&watchAPIListen, "api-listen", "", "loopback address for the local HTTP API (default: watch.api.listen)")
+	_ = watchCmd.RegisterFlagCompletionFunc("strategy", cobra.FixedCompletions([]string{"ours", "theirs", "manual", "newer"}, cobra.ShellCompDirectiveNoFileComp))
 
 	rootCmd.AddCommand(watchCmd)
 }
@@ -68,12 +98,14 @@ func init() {
 type watcher struct {
 	cfg          *config.Config
 	db           *state.DB
-	client       *notion.Client
+	clients      *notion.Factory
 	linkRegistry *state.LinkRegistry
+	attachments  *attachmentUploader
 	parser       *parser.Parser
 	scanner      *vault.Scanner
 
 	debounce     time.Duration
+	settle       time.Duration
 	pollInterval time.Duration
 	strategy     ConflictStrategy
 
@@ -82,6 +114,45 @@ type watcher struct {
 	pendingMu      sync.Mutex
 	debounceTicker *time.Ticker
 
+	// snapshots records the size and modification time last seen of notes
+	// past their debounce, to tell when they stop changing. partialRetries
+	// counts how often a note that looked partially written was put back.
+	// Both are guarded by pendingMu.
+	snapshots      map[string]fileSnapshot
+	partialRetries map[string]int
+
+	// noteAttachments maps the attachment file names referenced by pending
+	// notes to those notes, so a file written after its note (a pasted
+	// screenshot) joins the note's batch. attachmentChanged holds pending
+	// notes whose attachments changed; they are pushed even if the note
+	// itself did not. Both are guarded by pendingMu.
+	noteAttachments   map[string]map[string]bool
+	attachmentChanged map[string]bool
+
+	// blockedPulls records the remote edit time of push-only pages already
+	// reported as blocked, so each edit is reported once.
+	blockedPulls map[string]time.Time
+
+	// apiRequests carries syncs requested through the local HTTP API to
+	// the watch loop, so they never run alongside a debounced sync.
+	apiRequests chan apiSyncRequest
+
+	// paused holds back debounced syncs and Notion polling while changes
+	// keep queueing, from 'watch pause' until 'watch resume'. Guarded by
+	// pendingMu; only the watch loop changes it.
+	paused bool
+
+	// pauseRequests carries pause (true) and resume (false) requests from
+	// the local HTTP API to the watch loop.
+	pauseRequests chan bool
+
+	// deletions counts the deletions applied since watch started or since
+	// held deletions were last confirmed. heldDeletions holds deleted notes
+	// left for a push with --confirm-deletions. Only the watch loop uses
+	// them.
+	deletions     int
+	heldDeletions map[string]bool
+
 	// Output
 	out io.Writer
 }
@@ -100,6 +171,12 @@ func runWatch(cmd *cobra.Command, args []string) error {
 	default:
 		return fmt.Errorf("invalid conflict strategy: %s", watchStrategy)
 	}
+	if watchAPIListen != "" {
+		cfg.Watch.API.Listen = watchAPIListen
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+	}
 
 	// Handle daemon mode.
 	if watchDaemon {
@@ -124,6 +201,19 @@ func runWatchForeground(cfg *config.Config, strategy ConflictStrategy, out io.Wr
 		return fmt.Errorf("invalid debounce duration: %w", err)
 	}
 
+	// Parse settle time.
+	settleStr := cfg.Watch.Settle
+	if settleStr == "" {
+		settleStr = "1s"
+	}
+	var settle time.Duration
+	if settleStr != "0" {
+		settle, err = time.ParseDuration(settleStr)
+		if err != nil {
+			return fmt.Errorf("invalid settle time: %w", err)
+		}
+	}
+
 	// Parse poll interval.
 	pollStr := watchPollInterval
 	if pollStr == "" {
@@ -139,6 +229,10 @@ func runWatchForeground(cfg *config.Config, strategy ConflictStrategy, out io.Wr
 			return fmt.Errorf("invalid poll interval: %w", err)
 		}
 	}
+	// A mirror is never changed from Notion, so there is nothing to poll.
+	if cfg.Mirror() {
+		pollInterval = 0
+	}
 
 	// Open state database.
 	dbPath := filepath.Join(cfg.Vault, ".obsidian-notion.db")
@@ -147,27 +241,41 @@ func runWatchForeground(cfg *config.Config, strategy ConflictStrategy, out io.Wr
 		return fmt.Errorf("open database: %w (run 'obsidian-notion init' first)", err)
 	}
 	defer db.Close()
+	if err := useNestedDatabases(cfg, db); err != nil {
+		return err
+	}
 
 	// Initialize components.
-	client := notion.New(cfg.Notion.Token,
-		notion.WithRateLimit(cfg.RateLimit.RequestsPerSecond),
-		notion.WithBatchSize(cfg.RateLimit.BatchSize),
-	)
+	clients := newNotionClients(cfg)
+	probeCtx, cancelProbe := context.WithTimeout(context.Background(), 30*time.Second)
+	checkCapabilities(probeCtx, out, clients.ForToken(cfg.Notion.Token))
+	cancelProbe()
 
 	linkRegistry := state.NewLinkRegistry(db)
+	scanner := newScanner(cfg)
 
 	w := &watcher{
-		cfg:            cfg,
-		db:             db,
-		client:         client,
-		linkRegistry:   linkRegistry,
-		parser:         parser.New(),
-		scanner:        vault.NewScanner(cfg.Vault, cfg.Sync.Ignore),
-		debounce:       debounce,
-		pollInterval:   pollInterval,
-		strategy:       strategy,
-		pendingChanges: make(map[string]time.Time),
-		out:            out,
+		cfg:               cfg,
+		db:                db,
+		clients:           clients,
+		linkRegistry:      linkRegistry,
+		attachments:       newAttachmentUploader(cfg, db, clients, scanner),
+		parser:            parser.New(),
+		scanner:           scanner,
+		debounce:          debounce,
+		settle:            settle,
+		pollInterval:      pollInterval,
+		strategy:          strategy,
+		pendingChanges:    make(map[string]time.Time),
+		snapshots:         make(map[string]fileSnapshot),
+		partialRetries:    make(map[string]int),
+		noteAttachments:   make(map[string]map[string]bool),
+		attachmentChanged: make(map[string]bool),
+		blockedPulls:      make(map[string]time.Time),
+		heldDeletions:     make(map[string]bool),
+		apiRequests:       make(chan apiSyncRequest),
+		pauseRequests:     make(chan bool),
+		out:               out,
 	}
 
 	return w.run()
@@ -191,15 +299,30 @@ func (w *watcher) run() error {
 	fmt.Fprintf(w.out, "Debounce: %s\n", w.debounce)
 	if w.pollInterval > 0 {
 		fmt.Fprintf(w.out, "Poll interval: %s\n", w.pollInterval)
+	} else if w.cfg.Mirror() {
+		fmt.Fprintf(w.out, "Notion polling: disabled (mirror mode)\n")
 	} else {
 		fmt.Fprintf(w.out, "Notion polling: disabled\n")
 	}
 	fmt.Fprintf(w.out, "Conflict strategy: %s\n", w.strategy)
+	if w.cfg.Watch.API.Listen != "" {
+		stopAPI, err := w.startAPI()
+		if err != nil {
+			return err
+		}
+		defer stopAPI()
+	}
 	fmt.Fprintf(w.out, "\nPress Ctrl+C to stop...\n\n")
 
 	// Setup signal handling.
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	pauseSignal, resumeSignal := pauseSignals()
+	pauseCh := make(chan os.Signal, 1)
+	if pauseSignal != nil {
+		signal.Notify(pauseCh, pauseSignal, resumeSignal)
+		defer signal.Stop(pauseCh)
+	}
 
 	// Setup debounce ticker.
 	w.debounceTicker = time.NewTicker(500 * time.Millisecond)
@@ -237,7 +360,18 @@ func (w *watcher) run() error {
 			w.processDebounced()
 
 		case <-pollCh:
-			w.pollNotion()
+			if !w.isPaused() {
+				w.pollNotion()
+			}
+
+		case req := <-w.apiRequests:
+			w.syncNow(req)
+
+		case sig := <-pauseCh:
+			w.setPaused(sig == pauseSignal)
+
+		case paused := <-w.pauseRequests:
+			w.setPaused(paused)
 		}
 	}
 }
@@ -270,31 +404,37 @@ func (w *watcher) handleFsEvent(fsWatcher *fsnotify.Watcher, event fsnotify.Even
 		return
 	}
 
-	// Skip hidden files and directories.
-	if strings.HasPrefix(filepath.Base(relPath), ".") {
+	// Skip hidden files and directories, and files still being written.
+	if strings.HasPrefix(filepath.Base(relPath), ".") || isTempFile(relPath) {
 		return
 	}
 
-	// Skip non-markdown files.
+	// Non-markdown files only matter as attachments of pending notes.
 	if !strings.HasSuffix(relPath, ".md") {
 		// But handle directory creation.
 		if event.Has(fsnotify.Create) {
 			info, err := os.Stat(path)
 			if err == nil && info.IsDir() {
 				_ = fsWatcher.Add(path)
+				return
 			}
 		}
+		if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+			w.attachmentEvent(relPath)
+		}
 		return
 	}
 
-	// Check if file matches ignore patterns.
-	if w.shouldIgnore(relPath) {
+	// Check if file matches ignore patterns or is a template.
+	if w.shouldIgnore(relPath) || w.scanner.IsTemplate(relPath) {
 		return
 	}
 
-	// Record the change for debouncing.
+	// Record the change for debouncing, along with the note's attachments.
+	refs := w.attachmentNames(relPath)
 	w.pendingMu.Lock()
 	w.pendingChanges[relPath] = time.Now()
+	w.trackAttachments(relPath, refs)
 	w.pendingMu.Unlock()
 
 	if verbose {
@@ -310,6 +450,73 @@ func (w *watcher) handleFsEvent(fsWatcher *fsnotify.Watcher, event fsnotify.Even
 	}
 }
 
+// attachmentNames returns the names of the attachments a note references,
+// keyed as by attachmentKey. Unreadable notes reference none.
+func (w *watcher) attachmentNames(relPath string) []string {
+	content, err := os.ReadFile(filepath.Join(w.cfg.Vault, relPath))
+	if err != nil {
+		return nil
+	}
+	note, err := w.parser.Parse(relPath, content)
+	if err != nil {
+		return nil
+	}
+	refs := attachmentRefs(note)
+	names := make([]string, len(refs))
+	for i, ref := range refs {
+		names[i] = attachmentKey(ref)
+	}
+	return names
+}
+
+// trackAttachments replaces the attachments recorded for a pending note.
+// The caller must hold pendingMu.
+func (w *watcher) trackAttachments(notePath string, names []string) {
+	w.untrackAttachments(notePath)
+	for _, name := range names {
+		if w.noteAttachments[name] == nil {
+			w.noteAttachments[name] = make(map[string]bool)
+		}
+		w.noteAttachments[name][notePath] = true
+	}
+}
+
+// untrackAttachments forgets the attachments of a note that is no longer
+// pending. The caller must hold pendingMu.
+func (w *watcher) untrackAttachments(notePath string) {
+	for name, notes := range w.noteAttachments {
+		delete(notes, notePath)
+		if len(notes) == 0 {
+			delete(w.noteAttachments, name)
+		}
+	}
+}
+
+// attachmentEvent handles a change to a non-markdown file. Pending notes
+// referencing it wait for the file to settle and push it with them.
+func (w *watcher) attachmentEvent(relPath string) {
+	w.pendingMu.Lock()
+	defer w.pendingMu.Unlock()
+
+	notes := w.noteAttachments[attachmentKey(relPath)]
+	for notePath := range notes {
+		w.pendingChanges[notePath] = time.Now()
+		w.attachmentChanged[notePath] = true
+	}
+	if verbose && len(notes) > 0 {
+		fmt.Fprintf(w.out, "[%s] attachment %s changed (%d pending note(s))\n", time.Now().Format("15:04:05"), relPath, len(notes))
+	}
+}
+
+// attachmentKey identifies an attachment by its lowercased file name, so
+// a reference matches the file wherever Obsidian saved it.
+func attachmentKey(ref string) string {
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+	return strings.ToLower(filepath.Base(filepath.FromSlash(ref)))
+}
+
 // shouldIgnore checks if a file should be ignored based on patterns.
 func (w *watcher) shouldIgnore(relPath string) bool {
 	for _, pattern := range w.cfg.Sync.Ignore {
@@ -331,7 +538,7 @@ func (w *watcher) processDebounced() {
 	w.pendingMu.Lock()
 	defer w.pendingMu.Unlock()
 
-	if len(w.pendingChanges) == 0 {
+	if w.paused || len(w.pendingChanges) == 0 {
 		return
 	}
 
@@ -339,7 +546,7 @@ func (w *watcher) processDebounced() {
 	var toProcess []string
 
 	for path, changedAt := range w.pendingChanges {
-		if now.Sub(changedAt) >= w.debounce {
+		if now.Sub(changedAt) >= w.debounce && w.settled(path, now) {
 			toProcess = append(toProcess, path)
 		}
 	}
@@ -347,10 +554,25 @@ func (w *watcher) processDebounced() {
 	if len(toProcess) == 0 {
 		return
 	}
+	w.syncPending(toProcess)
+}
 
+// syncPending syncs pending notes as one batch, taking them out of the
+// queue. The caller must hold pendingMu.
+func (w *watcher) syncPending(toProcess []string) {
 	// Remove from pending.
+	force := make(map[string]bool, len(toProcess))
 	for _, path := range toProcess {
 		delete(w.pendingChanges, path)
+		force[path] = w.attachmentChanged[path]
+		delete(w.attachmentChanged, path)
+		delete(w.snapshots, path)
+		w.untrackAttachments(path)
+	}
+
+	toProcess = w.holdDeletions(toProcess)
+	if len(toProcess) == 0 {
+		return
 	}
 
 	// Process changes.
@@ -360,22 +582,234 @@ func (w *watcher) processDebounced() {
 	defer cancel()
 
 	for _, relPath := range toProcess {
-		if err := w.syncFile(ctx, relPath); err != nil {
-			fmt.Fprintf(w.out, "  Error syncing %s: %v\n", relPath, err)
+		if w.retryPartial(relPath, force[relPath]) {
+			fmt.Fprintf(w.out, "  Waiting: %s looks partially written, retrying\n", relPath)
+			continue
+		}
+		if err := w.syncFile(ctx, relPath, force[relPath]); err != nil {
+			printError(w.out, "Error syncing", relPath, err)
 		} else {
 			fmt.Fprintf(w.out, "  Synced: %s\n", relPath)
 		}
 	}
 }
 
-// syncFile synchronizes a single file to Notion.
-func (w *watcher) syncFile(ctx context.Context, relPath string) error {
+// isPaused reports whether the watcher is paused.
+func (w *watcher) isPaused() bool {
+	w.pendingMu.Lock()
+	defer w.pendingMu.Unlock()
+	return w.paused
+}
+
+// setPaused pauses or resumes the watcher. On resume, queued notes are
+// synced at once with watch.coalesce_on_resume, and otherwise wait out a
+// fresh debounce.
+func (w *watcher) setPaused(paused bool) {
+	w.pendingMu.Lock()
+	defer w.pendingMu.Unlock()
+
+	if w.paused == paused {
+		return
+	}
+	w.paused = paused
+	now := time.Now()
+	if paused {
+		fmt.Fprintf(w.out, "[%s] Paused, queueing changes until resumed\n", now.Format("15:04:05"))
+		return
+	}
+
+	fmt.Fprintf(w.out, "[%s] Resumed with %d queued change(s)\n", now.Format("15:04:05"), len(w.pendingChanges))
+	if w.cfg.Watch.CoalesceOnResume {
+		queued := make([]string, 0, len(w.pendingChanges))
+		for path := range w.pendingChanges {
+			queued = append(queued, path)
+		}
+		sort.Strings(queued)
+		if len(queued) > 0 {
+			w.syncPending(queued)
+		}
+		return
+	}
+	for path := range w.pendingChanges {
+		w.pendingChanges[path] = now
+		delete(w.snapshots, path)
+	}
+}
+
+// fileSnapshot is the size and modification time of a note, and when they
+// were first seen.
+type fileSnapshot struct {
+	size  int64
+	mtime time.Time
+	since time.Time
+}
+
+// settled reports whether a note's size and modification time have stayed
+// the same for the settle time, recording what it sees. A note is watched
+// for the whole settle time even if its modification time is older, since
+// sync clients often keep the original one. Deleted notes are settled. The
+// caller must hold pendingMu.
+func (w *watcher) settled(relPath string, now time.Time) bool {
+	if w.settle <= 0 {
+		return true
+	}
+	info, err := os.Stat(filepath.Join(w.cfg.Vault, relPath))
+	if err != nil {
+		delete(w.snapshots, relPath)
+		return true
+	}
+
+	last, ok := w.snapshots[relPath]
+	if !ok || last.size != info.Size() || !last.mtime.Equal(info.ModTime()) {
+		w.snapshots[relPath] = fileSnapshot{size: info.Size(), mtime: info.ModTime(), since: now}
+		return false
+	}
+	return now.Sub(last.since) >= w.settle
+}
+
+// maxPartialRetries is how many times a note that looks partially written
+// is put back before it is synced as it is.
+const maxPartialRetries = 3
+
+// retryPartial puts a note that looks partially written back in the
+// debounce queue, reporting whether it did. After maxPartialRetries the
+// note is synced as it is. The caller must hold pendingMu.
+func (w *watcher) retryPartial(relPath string, force bool) bool {
+	content, err := os.ReadFile(filepath.Join(w.cfg.Vault, relPath))
+	if err != nil {
+		delete(w.partialRetries, relPath)
+		return false // Deleted, or an error syncFile reports.
+	}
+	partial := looksTruncated(content)
+	if !partial {
+		_, err := w.parser.Parse(relPath, content)
+		partial = err != nil
+	}
+	if !partial || w.partialRetries[relPath] >= maxPartialRetries {
+		delete(w.partialRetries, relPath)
+		return false
+	}
+
+	w.partialRetries[relPath]++
+	w.pendingChanges[relPath] = time.Now()
+	if force {
+		w.attachmentChanged[relPath] = true
+	}
+	w.trackAttachments(relPath, w.attachmentNames(relPath))
+	return true
+}
+
+// looksTruncated reports whether note content looks cut off mid-write: it
+// is empty, or it ends inside a fenced code block.
+func looksTruncated(content []byte) bool {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return true
+	}
+	inFence := false
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if bytes.HasPrefix(bytes.TrimLeft(line, " \t"), []byte("```")) {
+			inFence = !inFence
+		}
+	}
+	return inFence
+}
+
+// isTempFile reports whether a file is a temporary or placeholder file of
+// a sync client or editor, written before the real file is in place.
+func isTempFile(relPath string) bool {
+	name := strings.ToLower(filepath.Base(relPath))
+	for _, suffix := range []string{".tmp", ".temp", ".icloud", ".part", ".partial", ".crdownload", ".swp", "~"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// holdDeletions leaves out deleted notes once the deletions applied since
+// the daemon started, with those already held, would archive more Notion
+// pages than sync.max_deletions_per_run allows; a vault emptied a few files
+// per batch is caught as surely as one emptied at once. Held notes stay
+// tracked, so a push with --confirm-deletions applies them later. The count
+// starts over once such a push has applied them, the user's confirmation;
+// held notes restored in the vault are simply dropped from the hold.
+func (w *watcher) holdDeletions(paths []string) []string {
+	confirmed := false
+	for path := range w.heldDeletions {
+		if _, err := os.Stat(filepath.Join(w.cfg.Vault, path)); !os.IsNotExist(err) {
+			delete(w.heldDeletions, path)
+			continue
+		}
+		if s, _ := w.db.GetState(path); !trashesPage(w.cfg, s) {
+			delete(w.heldDeletions, path)
+			confirmed = true
+		}
+	}
+	if confirmed {
+		w.deletions = 0
+	}
+
+	var deleted []string
+	for _, path := range paths {
+		if _, err := os.Stat(filepath.Join(w.cfg.Vault, path)); !os.IsNotExist(err) || w.cfg.GetComposition(path) != nil {
+			continue
+		}
+		if s, _ := w.db.GetState(path); trashesPage(w.cfg, s) {
+			deleted = append(deleted, path)
+		}
+	}
+	if len(deleted) == 0 {
+		return paths
+	}
+
+	limit := w.cfg.Sync.DeletionLimit()
+	if limit == 0 || w.deletions+len(w.heldDeletions)+len(deleted) <= limit {
+		w.deletions += len(deleted)
+		return paths
+	}
+
+	if w.heldDeletions == nil {
+		w.heldDeletions = make(map[string]bool)
+	}
+	for _, path := range deleted {
+		w.heldDeletions[path] = true
+		fmt.Fprintf(w.out, "  D %s\n", path)
+	}
+	fmt.Fprintf(w.out, "  Warning: held back %d deletion(s): with %d applied since watch started, more than sync.max_deletions_per_run (%d); run 'obsidian-notion push --confirm-deletions' to apply them\n",
+		len(w.heldDeletions), w.deletions, limit)
+	kept := paths[:0]
+	for _, path := range paths {
+		if !w.heldDeletions[path] {
+			kept = append(kept, path)
+		}
+	}
+	return kept
+}
+
+// syncFile synchronizes a single file to Notion. With force, the note is
+// pushed even if its content is unchanged, as when an attachment changed.
+func (w *watcher) syncFile(ctx context.Context, relPath string, force bool) error {
 	fullPath := filepath.Join(w.cfg.Vault, relPath)
 
+	// Composed notes rebuild their shared page, deletions included.
+	if rule := w.cfg.GetComposition(relPath); rule != nil {
+		if s, _ := w.db.GetState(relPath); s != nil {
+			hashes, err := noteHasher(w.cfg).HashFileDetailed(fullPath)
+			if err == nil && !force && hashes.ContentHash == s.ContentHash && hashes.FrontmatterHash == s.FrontmatterHash {
+				return nil
+			}
+		}
+		_, err := w.composer().push(ctx, rule)
+		return err
+	}
+
 	// Check if file exists (might have been deleted).
 	info, err := os.Stat(fullPath)
 	if os.IsNotExist(err) {
 		// File was deleted - handle deletion.
+		if !pushAllowed(w.cfg, relPath) {
+			return errPullOnly
+		}
 		return w.handleDeletion(ctx, relPath)
 	}
 	if err != nil {
@@ -384,6 +818,10 @@ func (w *watcher) syncFile(ctx context.Context, relPath string) error {
 	if info.IsDir() {
 		return nil
 	}
+	if skipped, ok := checkNote(w.cfg, w.scanner, relPath); !ok {
+		fmt.Fprintf(w.out, "  Skipped: %s (%s)\n", skipped.path, skipped.reason)
+		return nil
+	}
 
 	// Read file content.
 	content, err := os.ReadFile(fullPath)
@@ -392,16 +830,16 @@ func (w *watcher) syncFile(ctx context.Context, relPath string) error {
 	}
 
 	// Check if content has actually changed.
-	hashes, err := state.HashFileDetailed(fullPath)
-	if err != nil {
-		return fmt.Errorf("hash file: %w", err)
-	}
+	hashes := noteHasher(w.cfg).HashContent(content)
 
 	existingState, _ := w.db.GetState(relPath)
-	if existingState != nil && existingState.ContentHash == hashes.ContentHash {
+	if !force && existingState != nil && existingState.ContentHash == hashes.ContentHash {
 		// Content hasn't changed, skip.
 		return nil
 	}
+	if !pushAllowed(w.cfg, relPath) {
+		return errPullOnly
+	}
 
 	// Parse markdown.
 	note, err := w.parser.Parse(relPath, content)
@@ -410,31 +848,30 @@ func (w *watcher) syncFile(ctx context.Context, relPath string) error {
 	}
 
 	// Register wiki-links.
-	_ = w.linkRegistry.ClearLinksFrom(relPath)
-	if len(note.WikiLinks) > 0 {
-		targets := make([]string, len(note.WikiLinks))
-		for i, link := range note.WikiLinks {
-			targets[i] = link.Target
-		}
-		_ = w.linkRegistry.RegisterLinks(relPath, targets)
+	targets := make([]string, len(note.WikiLinks))
+	for i, link := range note.WikiLinks {
+		targets[i] = link.Target
 	}
+	_ = w.linkRegistry.ReplaceLinks(relPath, targets)
+	warnUnexpandedTemplates(w.out, relPath, note)
+
+	// Upload referenced attachments, reusing earlier uploads of identical files.
+	attachments := w.attachments.prepare(ctx, relPath, note)
 
 	// Transform to Notion with path-specific property mappings.
 	t := transformer.New(w.linkRegistry, buildTransformerConfig(w.cfg, relPath))
+	t.SetAttachmentResolver(attachments)
 	notionPage, err := t.Transform(note)
 	if err != nil {
 		return fmt.Errorf("transform: %w", err)
 	}
 
 	// Create or update page.
-	var pageID string
+	var pageID, parentID string
 	if existingState == nil || existingState.NotionPageID == "" {
 		// Create new page.
-		parentID := w.cfg.GetDatabaseForPath(relPath)
-		if parentID == "" {
-			parentID = w.cfg.Notion.DefaultPage
-		}
-		result, err := w.client.CreatePage(ctx, parentID, notionPage)
+		parentID = newPageParent(w.cfg, relPath, note)
+		result, err := w.clients.ForPath(relPath).CreatePage(ctx, parentID, notionPage)
 		if err != nil {
 			return fmt.Errorf("create page: %w", err)
 		}
@@ -442,7 +879,7 @@ func (w *watcher) syncFile(ctx context.Context, relPath string) error {
 	} else {
 		// Update existing page.
 		pageID = existingState.NotionPageID
-		if err := w.client.UpdatePage(ctx, pageID, notionPage); err != nil {
+		if err := w.clients.ForPath(relPath).UpdatePage(ctx, pageID, notionPage); err != nil {
 			return fmt.Errorf("update page: %w", err)
 		}
 	}
@@ -451,15 +888,23 @@ func (w *watcher) syncFile(ctx context.Context, relPath string) error {
 	syncState := &state.SyncState{
 		ObsidianPath:    relPath,
 		NotionPageID:    pageID,
+		NotionParentID:  recordedParent(existingState, parentID),
 		ObsidianMtime:   info.ModTime(),
 		NotionMtime:     time.Now(),
 		ContentHash:     hashes.ContentHash,
 		FrontmatterHash: hashes.FrontmatterHash,
 		LastSync:        time.Now(),
 		SyncDirection:   "push",
-		Status:          "synced",
+		Status:          pushedStatus(noteHasher(w.cfg), fullPath, hashes),
 	}
-	return w.db.SetState(syncState)
+	if err := w.db.SetState(syncState); err != nil {
+		return err
+	}
+	if err := recordPushedPage(w.db, relPath, notionPage); err != nil {
+		return err
+	}
+	pushNoteTargets(ctx, w.out, w.cfg, w.db, w.clients, relPath, note)
+	return w.attachments.commit(attachments)
 }
 
 // handleDeletion handles a deleted file.
@@ -471,29 +916,20 @@ func (w *watcher) handleDeletion(ctx context.Context, relPath string) error {
 	}
 
 	if existingState.NotionPageID != "" {
-		strategy := w.cfg.Sync.DeletionStrategy
-		if strategy == "" {
-			strategy = "archive"
-		}
-		switch strategy {
-		case "archive":
-			if err := w.client.ArchivePage(ctx, existingState.NotionPageID); err != nil {
-				return fmt.Errorf("archive page: %w", err)
-			}
-		case "delete":
-			if err := w.client.DeletePage(ctx, existingState.NotionPageID); err != nil {
-				return fmt.Errorf("delete page: %w", err)
-			}
+		if err := trashNotePage(ctx, w.cfg, w.db, w.clients, relPath, existingState); err != nil {
+			return err
 		}
 	}
 
-	_ = w.db.DeleteState(relPath)
-	_ = w.linkRegistry.ClearLinksFrom(relPath)
+	_ = forgetNote(w.db, relPath)
 	return nil
 }
 
 // pollNotion checks Notion for remote changes.
 func (w *watcher) pollNotion() {
+	if w.cfg.Mirror() {
+		return
+	}
 	if verbose {
 		fmt.Fprintf(w.out, "[%s] Polling Notion for changes...\n", time.Now().Format("15:04:05"))
 	}
@@ -510,33 +946,81 @@ func (w *watcher) pollNotion() {
 
 	conflictTracker := state.NewConflictTracker(w.db)
 	var remoteChanges []string
+	composedRules := make(map[*config.Composition]bool)
+	localEdits := make(map[string]bool)
 
 	for _, s := range states {
 		if s.NotionPageID == "" {
 			continue
 		}
 
+		// Composed notes are pulled per page below. Members edited locally
+		// keep their content until the pending push rebuilds the page.
+		if rule := w.cfg.GetComposition(s.ObsidianPath); rule != nil {
+			composedRules[rule] = true
+			hashes, err := noteHasher(w.cfg).HashFileDetailed(filepath.Join(w.cfg.Vault, s.ObsidianPath))
+			if err != nil || hashes.ContentHash != s.ContentHash {
+				localEdits[s.ObsidianPath] = true
+			}
+			continue
+		}
+
 		// Fetch page metadata from Notion.
-		page, err := w.client.GetPage(ctx, s.NotionPageID)
+		page, err := w.clients.ForPath(s.ObsidianPath).GetPage(ctx, s.NotionPageID)
 		if err != nil {
 			if verbose {
-				fmt.Fprintf(w.out, "  Error fetching %s: %v\n", s.ObsidianPath, err)
+				printError(w.out, "Error fetching", s.ObsidianPath, err)
 			}
 			continue
 		}
 
-		// Check if remote has changed since last sync.
+		// Check if remote has changed since last sync. An edit of our own,
+		// such as a push racing this poll, is recorded as synced.
+		if page.LastEditedTime.After(s.NotionMtime) && ownEdit(ctx, w.clients.ForPath(s.ObsidianPath), page, s) {
+			if verbose {
+				fmt.Fprintf(w.out, "  Ignoring own edit to %s\n", s.ObsidianPath)
+			}
+			s.NotionMtime = page.LastEditedTime
+			if err := w.db.SetState(s); err != nil {
+				printError(w.out, "Error updating state for", s.ObsidianPath, err)
+			}
+			continue
+		}
 		if page.LastEditedTime.After(s.NotionMtime) {
-			// Remote has changed - check for conflict.
 			fullPath := filepath.Join(w.cfg.Vault, s.ObsidianPath)
-			currentHashes, err := state.HashFileDetailed(fullPath)
+			currentHashes, err := noteHasher(w.cfg).HashFileDetailed(fullPath)
+			if err != nil {
+				continue
+			}
+			localEdited := currentHashes.ContentHash != s.ContentHash
+			pull := pullAllowed(w.cfg, s.ObsidianPath)
+
+			// Attachments are downloaded only for a page about to be
+			// pulled; a conflict or push-only path leaves the vault as is.
+			remote, err := w.fetchRemote(ctx, s.ObsidianPath, s.NotionPageID, pull && !localEdited)
 			if err != nil {
+				printError(w.out, "Error fetching", s.ObsidianPath, err)
 				continue
 			}
 
-			if currentHashes.ContentHash != s.ContentHash {
+			// Notion rewrites some content when it saves a page, such as
+			// whitespace; an edit that reads back as the synced note is not
+			// a change.
+			if formattingOnly(noteHasher(w.cfg), s, remote.markdown) {
+				if verbose {
+					fmt.Fprintf(w.out, "  Ignoring formatting-only change to %s\n", s.ObsidianPath)
+				}
+				s.NotionMtime = page.LastEditedTime
+				if err := w.db.SetState(s); err != nil {
+					printError(w.out, "Error updating state for", s.ObsidianPath, err)
+				}
+				continue
+			}
+
+			if localEdited {
 				// Local also changed - conflict!
-				if w.strategy == StrategyManual {
+				strategy := noteConflictStrategy(w.cfg, s.ObsidianPath, w.strategy)
+				if strategy == StrategyManual {
 					fmt.Fprintf(w.out, "[%s] Conflict detected: %s\n", time.Now().Format("15:04:05"), s.ObsidianPath)
 					info := &state.ConflictInfo{
 						Path:        s.ObsidianPath,
@@ -547,10 +1031,13 @@ func (w *watcher) pollNotion() {
 						DetectedAt:  time.Now(),
 					}
 					_ = conflictTracker.RecordConflict(info)
+					if err := saveRemoteSidecar(w.cfg, s.ObsidianPath, remote.markdown); err != nil {
+						fmt.Fprintf(w.out, "  Warning: failed to save remote version of %s: %v\n", s.ObsidianPath, err)
+					}
 					continue
 				}
 				// Auto-resolve based on strategy.
-				switch w.strategy {
+				switch strategy {
 				case StrategyOurs:
 					// Local wins - push.
 					remoteChanges = append(remoteChanges, s.ObsidianPath)
@@ -567,50 +1054,116 @@ func (w *watcher) pollNotion() {
 				}
 			}
 
-			// Pull remote change.
-			if err := w.pullFile(ctx, s.ObsidianPath, s.NotionPageID); err != nil {
-				fmt.Fprintf(w.out, "  Error pulling %s: %v\n", s.ObsidianPath, err)
+			// Pull remote change, unless the path is push-only.
+			if !pull {
+				if !w.blockedPulls[s.ObsidianPath].Equal(page.LastEditedTime) {
+					w.blockedPulls[s.ObsidianPath] = page.LastEditedTime
+					printBlocked(w.out, []blockedChange{{path: s.ObsidianPath, policy: config.DirectionPush}})
+				}
+				continue
+			}
+			if localEdited {
+				// Converted without attachments for the conflict check.
+				if remote, err = w.convertRemote(ctx, s.ObsidianPath, remote.page, true); err != nil {
+					printError(w.out, "Error fetching", s.ObsidianPath, err)
+					continue
+				}
+			}
+			if err := w.pullFile(s, remote); err != nil {
+				printError(w.out, "Error pulling", s.ObsidianPath, err)
 			} else {
 				fmt.Fprintf(w.out, "[%s] Pulled: %s\n", time.Now().Format("15:04:05"), s.ObsidianPath)
 			}
 		}
 	}
 
+	for rule := range composedRules {
+		n, err := w.composer().pull(ctx, rule, false, localEdits)
+		if err != nil {
+			printError(w.out, "Error pulling", rule.Path, err)
+		} else if n > 0 {
+			fmt.Fprintf(w.out, "[%s] Pulled: %d note(s) from %s\n", time.Now().Format("15:04:05"), n, rule.Path)
+		}
+	}
+
 	// Process any files that need pushing due to conflict resolution.
 	for _, path := range remoteChanges {
-		if err := w.syncFile(ctx, path); err != nil {
-			fmt.Fprintf(w.out, "  Error syncing %s: %v\n", path, err)
+		if err := w.syncFile(ctx, path, false); err != nil {
+			printError(w.out, "Error syncing", path, err)
 		}
 	}
 }
 
-// pullFile pulls a file from Notion.
-func (w *watcher) pullFile(ctx context.Context, relPath, pageID string) error {
-	rt := transformer.NewReverse(w.linkRegistry, buildTransformerConfig(w.cfg, relPath))
+// composer returns a composer sharing the watcher's clients and registry.
+func (w *watcher) composer() *composer {
+	return newComposer(w.cfg, w.db, w.clients, w.linkRegistry, w.attachments, w.scanner)
+}
+
+// remoteNote is a note's Notion page and the markdown a pull writes for
+// it, with the pages it mentions that are not in the vault.
+type remoteNote struct {
+	page     *transformer.NotionPage
+	markdown []byte
+	missed   []string
+}
 
-	// Fetch page from Notion.
-	notionPage, err := w.client.FetchPage(ctx, pageID)
+// fetchRemote fetches the Notion page of a note and converts it to
+// markdown. Only with download are the page's attachments downloaded to the
+// vault and its bookmarks previewed, as a pull does; without, the markdown
+// is for comparing and embeds files by their Notion URLs.
+func (w *watcher) fetchRemote(ctx context.Context, relPath, pageID string, download bool) (*remoteNote, error) {
+	notionPage, err := fetchNotePage(ctx, w.clients.ForPath(relPath), w.db, relPath, pageID)
 	if err != nil {
-		return fmt.Errorf("fetch page: %w", err)
+		return nil, fmt.Errorf("fetch page: %w", err)
+	}
+	return w.convertRemote(ctx, relPath, notionPage, download)
+}
+
+// convertRemote converts a note's fetched Notion page to markdown, as
+// fetchRemote does.
+func (w *watcher) convertRemote(ctx context.Context, relPath string, notionPage *transformer.NotionPage, download bool) (*remoteNote, error) {
+	mentions := &mentionRecorder{PathLookup: w.linkRegistry}
+	rt := transformer.NewReverse(mentions, buildTransformerConfig(w.cfg, relPath))
+	if download {
+		rt.SetAttachmentSaver(newAttachmentDownloader(ctx, w.cfg, w.scanner, relPath))
+		rt.SetBookmarkPreviewer(newBookmarkPreviewer(ctx, w.cfg, relPath))
+	} else {
+		// Attachments are embedded as the files they were pushed from,
+		// so a page can still read back as its synced note.
+		rt.SetAttachmentSaver(newAttachmentMapper(w.db, w.scanner, relPath))
 	}
+	rt.SetUserNamer(newUserNamer(ctx, w.clients.ForPath(relPath)))
 
-	// Transform to markdown.
 	markdown, err := rt.NotionToMarkdown(notionPage)
 	if err != nil {
-		return fmt.Errorf("transform to markdown: %w", err)
+		return nil, fmt.Errorf("transform to markdown: %w", err)
 	}
+	return &remoteNote{page: notionPage, markdown: markdown, missed: mentions.missed}, nil
+}
+
+// formattingOnly reports whether the markdown of a changed page hashes the
+// same as the note did when it was last synced, so the change is only
+// Notion's normalization of the page and there is nothing to pull.
+func formattingOnly(h state.Hasher, s *state.SyncState, markdown []byte) bool {
+	hashes := h.HashContent(markdown)
+	return hashes.ContentHash == s.ContentHash && hashes.FrontmatterHash == s.FrontmatterHash
+}
 
+// pullFile writes a note's fetched page to the vault, updating its sync
+// state s.
+func (w *watcher) pullFile(s *state.SyncState, remote *remoteNote) error {
+	relPath := s.ObsidianPath
 	// Write file.
 	fullPath := filepath.Join(w.cfg.Vault, relPath)
 	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 		return fmt.Errorf("create directory: %w", err)
 	}
-	if err := os.WriteFile(fullPath, markdown, 0644); err != nil {
+	if err := os.WriteFile(fullPath, remote.markdown, 0644); err != nil {
 		return fmt.Errorf("write file: %w", err)
 	}
 
 	// Update sync state.
-	hashes, _ := state.HashFileDetailed(fullPath)
+	hashes, _ := noteHasher(w.cfg).HashFileDetailed(fullPath)
 	fileInfo, _ := os.Stat(fullPath)
 	var mtime time.Time
 	if fileInfo != nil {
@@ -619,7 +1172,8 @@ func (w *watcher) pullFile(ctx context.Context, relPath, pageID string) error {
 
 	syncState := &state.SyncState{
 		ObsidianPath:    relPath,
-		NotionPageID:    pageID,
+		NotionPageID:    s.NotionPageID,
+		NotionParentID:  s.NotionParentID,
 		ObsidianMtime:   mtime,
 		NotionMtime:     time.Now(),
 		ContentHash:     hashes.ContentHash,
@@ -628,24 +1182,16 @@ func (w *watcher) pullFile(ctx context.Context, relPath, pageID string) error {
 		SyncDirection:   "pull",
 		Status:          "synced",
 	}
-	return w.db.SetState(syncState)
+	if err := w.db.SetState(syncState); err != nil {
+		return err
+	}
+	recordMissingMentions(w.linkRegistry, relPath, remote.missed)
+	return recordBlockMap(w.db, relPath, remote.page)
 }
 
 // runDaemon runs the watcher as a background daemon.
 func runDaemon(cfg *config.Config) error {
-	// Determine PID file location.
-	pidFile := watchPIDFile
-	if pidFile == "" {
-		pidFile = cfg.Watch.PIDFile
-	}
-	if pidFile == "" {
-		// Default location.
-		if xdgRuntime := os.Getenv("XDG_RUNTIME_DIR"); xdgRuntime != "" {
-			pidFile = filepath.Join(xdgRuntime, "obsidian-notion.pid")
-		} else {
-			pidFile = "/tmp/obsidian-notion.pid"
-		}
-	}
+	pidFile := daemonPIDFile(cfg)
 
 	// Check for existing daemon.
 	if pid, running := checkPIDFile(pidFile); running {
@@ -690,6 +1236,21 @@ func runDaemon(cfg *config.Config) error {
 	return runWatchForeground(cfg, strategy, logWriter)
 }
 
+// daemonPIDFile returns the daemon's PID file: --pid-file, watch.pid_file,
+// or obsidian-notion.pid in $XDG_RUNTIME_DIR or /tmp.
+func daemonPIDFile(cfg *config.Config) string {
+	if watchPIDFile != "" {
+		return watchPIDFile
+	}
+	if cfg.Watch.PIDFile != "" {
+		return cfg.Watch.PIDFile
+	}
+	if xdgRuntime := os.Getenv("XDG_RUNTIME_DIR"); xdgRuntime != "" {
+		return filepath.Join(xdgRuntime, "obsidian-notion.pid")
+	}
+	return "/tmp/obsidian-notion.pid"
+}
+
 // checkPIDFile checks if a daemon is already running.
 func checkPIDFile(pidFile string) (int, bool) {
 	data, err := os.ReadFile(pidFile)
@@ -730,18 +1291,7 @@ func runStop(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Determine PID file location.
-	pidFile := watchPIDFile
-	if pidFile == "" {
-		pidFile = cfg.Watch.PIDFile
-	}
-	if pidFile == "" {
-		if xdgRuntime := os.Getenv("XDG_RUNTIME_DIR"); xdgRuntime != "" {
-			pidFile = filepath.Join(xdgRuntime, "obsidian-notion.pid")
-		} else {
-			pidFile = "/tmp/obsidian-notion.pid"
-		}
-	}
+	pidFile := daemonPIDFile(cfg)
 
 	pid, running := checkPIDFile(pidFile)
 	if !running {
@@ -780,18 +1330,7 @@ func runWatchStatus(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Determine PID file location.
-	pidFile := watchPIDFile
-	if pidFile == "" {
-		pidFile = cfg.Watch.PIDFile
-	}
-	if pidFile == "" {
-		if xdgRuntime := os.Getenv("XDG_RUNTIME_DIR"); xdgRuntime != "" {
-			pidFile = filepath.Join(xdgRuntime, "obsidian-notion.pid")
-		} else {
-			pidFile = "/tmp/obsidian-notion.pid"
-		}
-	}
+	pidFile := daemonPIDFile(cfg)
 
 	pid, running := checkPIDFile(pidFile)
 	if running {
@@ -801,3 +1340,65 @@ func runWatchStatus(cmd *cobra.Command, args []string) error {
 	}
 	return nil
 }
+
+// pauseCmd represents the pause subcommand for pausing the daemon.
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause syncing in the watch daemon",
+	Long: `Pause the watch daemon during bulk edits of the vault.
+
+While paused, the daemon keeps recording changed notes but syncs none of
+them and stops polling Notion. 'obsidian-notion watch resume' syncs what
+was queued: at once in one batch with watch.coalesce_on_resume, or else
+after a fresh debounce.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return signalDaemon(true)
+	},
+}
+
+// resumeCmd represents the resume subcommand for resuming the daemon.
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume syncing in a paused watch daemon",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return signalDaemon(false)
+	},
+}
+
+func init() {
+	watchCmd.AddCommand(pauseCmd)
+	watchCmd.AddCommand(resumeCmd)
+}
+
+// signalDaemon pauses or resumes the running daemon.
+func signalDaemon(pause bool) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+
+	pauseSignal, resumeSignal := pauseSignals()
+	if pauseSignal == nil {
+		return fmt.Errorf("pausing by signal is not supported on this platform; use POST /pause and /resume on the local HTTP API")
+	}
+	sig, action := resumeSignal, "resume"
+	if pause {
+		sig, action = pauseSignal, "pause"
+	}
+
+	pid, running := checkPIDFile(daemonPIDFile(cfg))
+	if !running {
+		fmt.Println("No daemon running")
+		return nil
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("find process: %w", err)
+	}
+	if err := process.Signal(sig); err != nil {
+		return fmt.Errorf("send signal: %w", err)
+	}
+
+	fmt.Printf("Sent %s signal to daemon (PID: %d)\n", action, pid)
+	return nil
+}
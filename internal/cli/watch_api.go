@@ -0,0 +1,339 @@
+package cli
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+)
+
+// obsidianOrigin is the origin of requests made from Obsidian plugins,
+// which the API allows cross-origin.
+const obsidianOrigin = "app://obsidian.md"
+
+// apiSyncRequest asks the watch loop to sync a note right away. The loop
+// sends the result on done.
+type apiSyncRequest struct {
+	path string
+	done chan error
+}
+
+// noteStatus is the sync status of one note as reported by the API.
+type noteStatus struct {
+	Path         string    `json:"path"`
+	Status       string    `json:"status"`
+	Pending      bool      `json:"pending"`
+	NotionPageID string    `json:"notion_page_id,omitempty"`
+	LastSync     time.Time `json:"last_sync,omitzero"`
+}
+
+// vaultStatus summarizes the watcher's state for the API.
+type vaultStatus struct {
+	Vault     string   `json:"vault"`
+	Paused    bool     `json:"paused"`
+	Pending   []string `json:"pending"`
+	Synced    int      `json:"synced"`
+	Conflicts int      `json:"conflicts"`
+}
+
+// apiConflict is a conflicted note as reported by the API.
+type apiConflict struct {
+	Path         string    `json:"path"`
+	NotionPageID string    `json:"notion_page_id,omitempty"`
+	LocalMtime   time.Time `json:"local_mtime"`
+	RemoteMtime  time.Time `json:"remote_mtime"`
+	DetectedAt   time.Time `json:"detected_at,omitzero"`
+}
+
+// startAPI serves the local HTTP API on the configured address. The
+// returned function shuts the server down.
+func (w *watcher) startAPI() (func(), error) {
+	listener, err := net.Listen("tcp", w.cfg.Watch.API.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("listen for API: %w", err)
+	}
+	server := &http.Server{
+		Handler:           w.apiHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(w.out, "API error: %v\n", err)
+		}
+	}()
+	fmt.Fprintf(w.out, "API: http://%s\n", listener.Addr())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}, nil
+}
+
+// apiHandler returns the API's routes, all behind token authentication:
+//
+//	POST /sync/file   sync the note named by {"path": ...} now
+//	GET  /status      summary, or one note's status with ?path=
+//	GET  /conflicts   notes in conflict
+//	POST /pause       queue changes without syncing them
+//	POST /resume      sync the queued changes again
+func (w *watcher) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /sync/file", w.handleAPISync)
+	mux.HandleFunc("GET /status", w.handleAPIStatus)
+	mux.HandleFunc("GET /conflicts", w.handleAPIConflicts)
+	mux.HandleFunc("POST /pause", w.handleAPIPause(true))
+	mux.HandleFunc("POST /resume", w.handleAPIPause(false))
+
+	token := []byte(w.cfg.Watch.API.Token)
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") == obsidianOrigin {
+			rw.Header().Set("Access-Control-Allow-Origin", obsidianOrigin)
+			rw.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			rw.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		}
+		if r.Method == http.MethodOptions {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), token) != 1 {
+			writeAPIError(rw, http.StatusUnauthorized, errors.New("invalid or missing token"))
+			return
+		}
+		mux.ServeHTTP(rw, r)
+	})
+}
+
+// handleAPISync syncs a note through the watch loop and waits for it.
+func (w *watcher) handleAPISync(rw http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeAPIError(rw, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	relPath, err := w.apiNotePath(body.Path)
+	if err != nil {
+		writeAPIError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	req := apiSyncRequest{path: relPath, done: make(chan error, 1)}
+	select {
+	case w.apiRequests <- req:
+	case <-r.Context().Done():
+		return
+	}
+	select {
+	case err := <-req.done:
+		if errors.Is(err, errPullOnly) {
+			writeAPIError(rw, http.StatusConflict, err)
+			return
+		}
+		if err != nil {
+			writeAPIError(rw, http.StatusInternalServerError, err)
+			return
+		}
+	case <-r.Context().Done():
+		return
+	}
+	w.writeNoteStatus(rw, relPath)
+}
+
+// handleAPIStatus reports the status of one note, or a vault summary.
+func (w *watcher) handleAPIStatus(rw http.ResponseWriter, r *http.Request) {
+	if path := r.URL.Query().Get("path"); path != "" {
+		relPath, err := w.apiNotePath(path)
+		if err != nil {
+			writeAPIError(rw, http.StatusBadRequest, err)
+			return
+		}
+		w.writeNoteStatus(rw, relPath)
+		return
+	}
+
+	states, err := w.db.ListStates("")
+	if err != nil {
+		writeAPIError(rw, http.StatusInternalServerError, err)
+		return
+	}
+	summary := vaultStatus{Vault: w.cfg.Vault, Paused: w.isPaused(), Pending: w.pendingPaths()}
+	for _, s := range states {
+		switch s.Status {
+		case "synced":
+			summary.Synced++
+		case "conflict":
+			summary.Conflicts++
+		}
+	}
+	writeAPIJSON(rw, summary)
+}
+
+// handleAPIConflicts lists notes in conflict.
+func (w *watcher) handleAPIConflicts(rw http.ResponseWriter, r *http.Request) {
+	tracker := state.NewConflictTracker(w.db)
+	states, err := tracker.GetConflicts()
+	if err != nil {
+		writeAPIError(rw, http.StatusInternalServerError, err)
+		return
+	}
+	conflicts := make([]apiConflict, 0, len(states))
+	for _, s := range states {
+		c := apiConflict{
+			Path:         s.ObsidianPath,
+			NotionPageID: s.NotionPageID,
+			LocalMtime:   s.ObsidianMtime,
+			RemoteMtime:  s.NotionMtime,
+		}
+		if info, err := tracker.GetConflictInfo(s.ObsidianPath); err == nil && info != nil {
+			c.RemoteMtime = info.RemoteMtime
+			c.DetectedAt = info.DetectedAt
+		}
+		conflicts = append(conflicts, c)
+	}
+	writeAPIJSON(rw, conflicts)
+}
+
+// handleAPIPause returns a handler that pauses or resumes the watch loop.
+// The loop takes the request before the handler replies.
+func (w *watcher) handleAPIPause(paused bool) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		select {
+		case w.pauseRequests <- paused:
+		case <-r.Context().Done():
+			return
+		}
+		writeAPIJSON(rw, map[string]bool{"paused": paused})
+	}
+}
+
+// apiNotePath checks that a path from a request names a note inside the
+// vault and returns it relative to the vault. Absolute paths are accepted
+// if they are inside the vault.
+func (w *watcher) apiNotePath(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("path is required")
+	}
+	path = filepath.FromSlash(path)
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(w.cfg.Vault, path)
+		if err != nil {
+			return "", fmt.Errorf("path %s is outside the vault", path)
+		}
+		path = rel
+	}
+	path = filepath.Clean(path)
+	if path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside the vault", path)
+	}
+	if !strings.HasSuffix(path, ".md") {
+		return "", fmt.Errorf("path %s is not a markdown note", path)
+	}
+	return path, nil
+}
+
+// writeNoteStatus writes the sync status of a note.
+func (w *watcher) writeNoteStatus(rw http.ResponseWriter, relPath string) {
+	status := noteStatus{Path: relPath}
+
+	w.pendingMu.Lock()
+	_, status.Pending = w.pendingChanges[relPath]
+	w.pendingMu.Unlock()
+
+	s, err := w.db.GetState(relPath)
+	if err != nil {
+		writeAPIError(rw, http.StatusInternalServerError, err)
+		return
+	}
+	_, statErr := os.Stat(filepath.Join(w.cfg.Vault, relPath))
+	switch {
+	case s == nil && statErr != nil:
+		status.Status = "missing"
+	case s == nil && (w.shouldIgnore(relPath) || w.scanner.IsTemplate(relPath)):
+		status.Status = "ignored"
+	case s == nil:
+		status.Status = "new"
+	default:
+		status.NotionPageID = s.NotionPageID
+		status.LastSync = s.LastSync
+		status.Status = s.Status
+		if s.Status == "synced" {
+			hashes, err := noteHasher(w.cfg).HashFileDetailed(filepath.Join(w.cfg.Vault, relPath))
+			if err != nil {
+				status.Status = "deleted"
+			} else if hashes.ContentHash != s.ContentHash || hashes.FrontmatterHash != s.FrontmatterHash {
+				status.Status = "modified"
+			}
+		}
+	}
+	writeAPIJSON(rw, status)
+}
+
+// pendingPaths returns the notes waiting for their debounce, sorted.
+func (w *watcher) pendingPaths() []string {
+	w.pendingMu.Lock()
+	defer w.pendingMu.Unlock()
+
+	paths := make([]string, 0, len(w.pendingChanges))
+	for path := range w.pendingChanges {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// syncNow handles an API sync request on the watch loop. The note leaves
+// the debounce queue, since it is synced now.
+func (w *watcher) syncNow(req apiSyncRequest) {
+	w.pendingMu.Lock()
+	force := w.attachmentChanged[req.path]
+	delete(w.pendingChanges, req.path)
+	delete(w.attachmentChanged, req.path)
+	w.untrackAttachments(req.path)
+	w.pendingMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	err := w.syncFile(ctx, req.path, force)
+	if err != nil {
+		printError(w.out, "Error syncing", req.path, err)
+	} else {
+		fmt.Fprintf(w.out, "[%s] Synced via API: %s\n", time.Now().Format("15:04:05"), req.path)
+	}
+	req.done <- err
+}
+
+// writeAPIJSON writes v as a JSON response.
+func writeAPIJSON(rw http.ResponseWriter, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(v)
+}
+
+// writeAPIError writes an error as a JSON response with the given status.
+// Requests Notion refused also carry the error's category, such as
+// "not_found", and what to do about it.
+func writeAPIError(rw http.ResponseWriter, code int, err error) {
+	body := map[string]string{"error": err.Error()}
+	if category := notion.Category(err); category != "" {
+		body["category"] = category
+		body["hint"] = notionHint(err)
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	_ = json.NewEncoder(rw).Encode(body)
+}
@@ -3,11 +3,20 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
 )
 
 const (
@@ -17,8 +26,43 @@ const (
 	// MaxBatchSize is the maximum allowed batch size (Notion API limit).
 	MaxBatchSize = 100
 
+	// DefaultPageSize is the default number of results per page when
+	// listing database pages and search results.
+	DefaultPageSize = 100
+
+	// MaxPageSize is the maximum allowed page size (Notion API limit).
+	MaxPageSize = 100
+
 	// DefaultRequestsPerSecond is the default API rate limit.
 	DefaultRequestsPerSecond = 3.0
+
+	// DefaultMaxNoteSize is the default size limit for notes.
+	DefaultMaxNoteSize = 5 << 20
+
+	// DefaultMaxAttachmentSize is the default size limit for attachments,
+	// Notion's limit for single-part uploads.
+	DefaultMaxAttachmentSize = 20 << 20
+
+	// DefaultAttachmentConcurrency is how many attachments upload at once.
+	DefaultAttachmentConcurrency = 2
+
+	// DefaultAttachmentRequestsPerSecond is the default rate limit of
+	// attachment uploads, a third of the default API rate limit.
+	DefaultAttachmentRequestsPerSecond = 1.0
+
+	// DefaultStreamNoteSize is the default size above which notes are
+	// pushed in chunks.
+	DefaultStreamNoteSize = 1 << 20
+
+	// DefaultUndoRetention is how long snapshots for sync undo are kept.
+	DefaultUndoRetention = 7 * 24 * time.Hour
+
+	// DefaultFileTimeout is how long pushing or pulling one note may take.
+	DefaultFileTimeout = 10 * time.Minute
+
+	// DefaultMaxDeletionsPerRun is how many Notion pages a run may archive
+	// for deleted notes without --confirm-deletions.
+	DefaultMaxDeletionsPerRun = 25
 )
 
 // Config represents the complete configuration for obsidian-notion.
@@ -26,23 +70,65 @@ type Config struct {
 	// Vault is the path to the Obsidian vault directory.
 	Vault string `yaml:"vault"`
 
+	// Mode is "sync" (default), which syncs both ways, or "mirror", which
+	// keeps an append-only copy of the vault in Notion: nothing is pulled,
+	// deleted notes keep their pages, commands that would change the vault
+	// from Notion refuse to run, and push and sync report on the mirror's
+	// integrity once a week.
+	Mode string `yaml:"mode"`
+
 	// Notion contains Notion API configuration.
 	Notion NotionConfig `yaml:"notion"`
 
 	// Mappings define folder-to-database mappings.
 	Mappings []FolderMapping `yaml:"mappings"`
 
+	// Compositions merge groups of notes into a single Notion page.
+	Compositions []Composition `yaml:"compositions"`
+
+	// Targets are databases notes publish to by naming them in their
+	// notion-targets frontmatter.
+	Targets map[string]TargetConfig `yaml:"targets"`
+
 	// Transform contains content transformation rules.
 	Transform TransformConfig `yaml:"transform"`
 
+	// Properties renames frontmatter keys to Notion properties.
+	Properties PropertiesConfig `yaml:"properties"`
+
 	// Sync contains synchronization behavior settings.
 	Sync SyncConfig `yaml:"sync"`
 
+	// Pull contains formatting settings for pulled notes.
+	Pull PullConfig `yaml:"pull"`
+
 	// RateLimit configures API rate limiting.
 	RateLimit RateLimitConfig `yaml:"rate_limit"`
 
+	// Attachments configures the attachment upload queue.
+	Attachments AttachmentsConfig `yaml:"attachments"`
+
 	// Watch contains watch mode configuration.
 	Watch WatchConfig `yaml:"watch"`
+
+	// Notify configures sync report notifications.
+	Notify NotifyConfig `yaml:"notify"`
+
+	// HTTP configures the connection to Notion, for networks that
+	// require a proxy or their own certificate authority.
+	HTTP HTTPConfig `yaml:"http"`
+
+	// Publish configures the publish command.
+	Publish PublishConfig `yaml:"publish"`
+
+	// Links configures the handling of wiki-links on push.
+	Links LinksConfig `yaml:"links"`
+
+	// Template configures the Notion page new pages are created from.
+	Template TemplateConfig `yaml:"template"`
+
+	// vaultRoot is the whole vault when Vault is scoped to sync.root.
+	vaultRoot string
 }
 
 // NotionConfig holds Notion API credentials and defaults.
@@ -56,6 +142,20 @@ type NotionConfig struct {
 
 	// DefaultPage is the default parent page ID (alternative to database).
 	DefaultPage string `yaml:"default_page"`
+
+	// StagingDatabase is where push --staged builds new pages before moving
+	// them to their target database. It must have the same properties as
+	// the target databases and should not be shared with readers.
+	StagingDatabase string `yaml:"staging_database"`
+
+	// Credentials maps credential names to additional integration tokens.
+	// Folder mappings refer to these by name; values support ${ENV_VAR}.
+	Credentials map[string]string `yaml:"credentials"`
+
+	// APIVersion pins the Notion-Version sent with every request, e.g.
+	// "2022-06-28". Empty uses the version the tool was built against.
+	// Block types newer than the version are pushed as placeholders.
+	APIVersion string `yaml:"api_version"`
 }
 
 // FolderMapping maps an Obsidian folder pattern to a Notion database.
@@ -68,6 +168,51 @@ type FolderMapping struct {
 
 	// Properties defines property mappings for this folder.
 	Properties []PropertyMappingConfig `yaml:"properties"`
+
+	// Credential names the entry in notion.credentials used for this
+	// folder and its database. Empty means notion.token.
+	Credential string `yaml:"credential"`
+
+	// Template is the ID or URL of the Notion page this folder's new
+	// pages are created from, instead of template.page.
+	Template string `yaml:"template"`
+
+	// RequestsPerSecond is the rate of this folder's database, with
+	// rate_limit.buckets set to database: its share of its integration's
+	// rate_limit.requests_per_second. Zero shares what the integration's
+	// other databases leave equally.
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+}
+
+// Composition syncs every note matching a pattern into one Notion page,
+// one Heading 1 section per note. Pull splits the page back into the
+// constituent files.
+type Composition struct {
+	// Path is a glob pattern for matching Obsidian paths, e.g.
+	// "project/meeting-notes/*.md".
+	Path string `yaml:"path"`
+
+	// Page is the Notion page ID the notes are composed into.
+	Page string `yaml:"page"`
+
+	// Order sorts the sections: "date" (default) or "name".
+	// Dates come from the "date" frontmatter field or a YYYY-MM-DD
+	// filename prefix; undated notes sort by name after dated ones.
+	Order string `yaml:"order"`
+}
+
+// TargetConfig is a database notes can publish to by listing the target's
+// name in their notion-targets frontmatter. The first target listed is the
+// note's primary: its page, with the note's body, is created there. The
+// other targets get a page with only the properties their mappings select.
+type TargetConfig struct {
+	// Database is the Notion database ID.
+	Database string `yaml:"database"`
+
+	// Properties select and map the frontmatter published to the
+	// database's pages when it is not the note's primary target.
+	// Default: title and tags.
+	Properties []PropertyMappingConfig `yaml:"properties"`
 }
 
 // PropertyMappingConfig defines how a frontmatter field maps to Notion.
@@ -82,6 +227,79 @@ type PropertyMappingConfig struct {
 	Type string `yaml:"type"`
 }
 
+// Unmapped property handling for PropertiesConfig.
+const (
+	// UnmappedPull adds unmapped Notion properties to the frontmatter on
+	// pull, under their lowercase names. Unmapped frontmatter keys are not
+	// pushed.
+	UnmappedPull = "pull"
+	// UnmappedPassthrough also pushes unmapped frontmatter keys as Notion
+	// properties of the same name, with a type inferred from the value.
+	UnmappedPassthrough = "passthrough"
+	// UnmappedIgnore syncs only mapped keys and properties.
+	UnmappedIgnore = "ignore"
+)
+
+// PropertiesConfig renames frontmatter keys to Notion property names.
+// Renames apply in both directions, on top of transform.property_mappings
+// (or the default title and tags mappings).
+type PropertiesConfig struct {
+	// Map renames frontmatter keys to Notion properties, e.g.
+	// {status: "Workflow State"}. A value can also be {name, type} to
+	// coerce the value to a Notion property type.
+	Map map[string]PropertyRename `yaml:"map"`
+
+	// Unmapped controls keys and properties without a mapping: "pull"
+	// (default), "passthrough", or "ignore".
+	Unmapped string `yaml:"unmapped"`
+}
+
+// PropertyRename is the Notion side of a properties.map entry.
+type PropertyRename struct {
+	// Name is the Notion property name.
+	Name string `yaml:"name"`
+
+	// Type is the Notion property type values are coerced to. Empty keeps
+	// the type of the mapping it renames, or infers one from the value.
+	Type string `yaml:"type,omitempty"`
+}
+
+// UnmarshalYAML accepts a property name or a {name, type} mapping.
+func (r *PropertyRename) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		r.Name = value.Value
+		r.Type = ""
+		return nil
+	}
+	type plain PropertyRename
+	return value.Decode((*plain)(r))
+}
+
+// MarshalYAML writes renames without a type as a plain property name.
+func (r PropertyRename) MarshalYAML() (any, error) {
+	if r.Type == "" {
+		return r.Name, nil
+	}
+	type plain PropertyRename
+	return plain(r), nil
+}
+
+// Renames returns the properties.map entries as property mappings, sorted
+// by frontmatter key.
+func (p PropertiesConfig) Renames() []PropertyMappingConfig {
+	keys := make([]string, 0, len(p.Map))
+	for key := range p.Map {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	renames := make([]PropertyMappingConfig, len(keys))
+	for i, key := range keys {
+		renames[i] = PropertyMappingConfig{Obsidian: key, Notion: p.Map[key].Name, Type: p.Map[key].Type}
+	}
+	return renames
+}
+
 // TransformConfig holds content transformation settings.
 type TransformConfig struct {
 	// Dataview handling: "snapshot" or "placeholder".
@@ -90,16 +308,170 @@ type TransformConfig struct {
 	// Callouts maps Obsidian callout types to emoji icons.
 	Callouts map[string]string `yaml:"callouts"`
 
+	// Icons overrides the emoji icons of generated blocks: "callout"
+	// (callouts of types without an icon), "dataview" (query placeholders),
+	// "image" (images that could not be embedded), "map" (Leaflet maps),
+	// and "unsupported" (blocks the API version does not support).
+	Icons map[string]string `yaml:"icons"`
+
+	// PluginBlocks turns the conversion of the fenced blocks of Obsidian
+	// plugins on or off: "admonition" (```ad-note and the like become
+	// callouts), "kanban" (boards become tables), and "leaflet" (maps
+	// become callouts linking to OpenStreetMap). Plugins not listed are
+	// converted; blocks of plugins turned off are pushed as code blocks.
+	PluginBlocks map[string]bool `yaml:"plugin_blocks"`
+
 	// UnresolvedLinks handling: "placeholder", "text", or "skip".
 	UnresolvedLinks string `yaml:"unresolved_links"`
 
 	// PropertyMappings defines how frontmatter fields map to Notion properties.
 	// If empty, uses default mappings (title->Name, tags->Tags).
 	PropertyMappings []PropertyMappingConfig `yaml:"property_mappings"`
+
+	// SplitOn splits notes into one child page per top-level heading.
+	// Options: "" (disabled) or "h1". Split notes are reassembled on pull.
+	SplitOn string `yaml:"split_on"`
+
+	// EmojiShortcodes converts :shortcode: text (as rendered by Obsidian
+	// emoji plugins) to emoji on push.
+	EmojiShortcodes bool `yaml:"emoji_shortcodes"`
+
+	// EmojiReverse converts emoji back to :shortcode: text on pull.
+	// Off by default, since most notes contain emoji typed directly.
+	EmojiReverse bool `yaml:"emoji_reverse"`
+
+	// Users maps display names to Notion user IDs. With "Ada Lovelace"
+	// mapped, "@Ada Lovelace" in a note becomes a mention of the user on
+	// push, and mentions of the user are pulled as "@Ada Lovelace". Other
+	// pulled mentions are named from the workspace's users.
+	Users map[string]string `yaml:"users"`
+
+	// DateMentions controls how Notion date mentions are pulled: "text"
+	// (default, as Notion shows them), "marker" ({{date:2024-03-03}},
+	// pushed back as date mentions), or "iso" (plain ISO dates). Reminders
+	// on dates are not exposed by the API and are not synced.
+	DateMentions string `yaml:"date_mentions"`
+
+	// HTML handling for raw HTML in notes: "convert" (default), "preserve",
+	// or "strip". Convert maps common tags to Notion blocks and annotations
+	// and keeps the rest as marked code blocks; preserve keeps all HTML.
+	HTML string `yaml:"html"`
+
+	// TextColors controls how Notion text colors are written on pull:
+	// "html" (default, <span style="color: red">), "highlight" (background
+	// colors become ==highlights==), or "strip". Colored spans become
+	// Notion colors on push.
+	TextColors string `yaml:"text_colors"`
+
+	// NestedTags maps nested tags like #project/alpha/backend to
+	// multi-select values: "keep" (default, as-is), "expand" (the tag and
+	// each ancestor), "flatten" (project-alpha-backend), or "top" (project).
+	// Rewritten tags are restored exactly on pull.
+	NestedTags string `yaml:"nested_tags"`
+
+	// Tags sets how tags are normalized before they are pushed to the
+	// multi-select property.
+	Tags TagsConfig `yaml:"tags"`
+
+	// Dividers is the markdown dividers are pulled as: "---" (default),
+	// "***", or "___". Dividers pushed from a note are pulled back as they
+	// were written, and a note's first line is never pulled as ---, which
+	// would open frontmatter.
+	Dividers string `yaml:"dividers"`
+
+	// EmptyParagraphs controls blank lines between blocks: "collapse"
+	// (default) drops extra blank lines on push, and, when set explicitly,
+	// empty paragraphs on pull, which are otherwise pulled as one blank
+	// line each; "keep" also pushes each blank line beyond the first
+	// between blocks as an empty paragraph.
+	EmptyParagraphs string `yaml:"empty_paragraphs"`
+
+	// TaskStates maps alternative task states used by themes, such as
+	// "- [-] cancelled" or "- [>] deferred", to Notion to-dos: "checked",
+	// "unchecked", or any other text, which is put before the task as a
+	// badge in an unchecked to-do (e.g. "/": "🚧"). States are restored on
+	// pull. Unmapped states stay plain list items.
+	TaskStates map[string]string `yaml:"task_states"`
+
+	// Dates maps note creation and modification dates to Notion date
+	// properties.
+	Dates DatesConfig `yaml:"dates"`
+}
+
+// TagsConfig sets how tags are normalized on push. Tags are read from the
+// frontmatter as a list or as a comma- or space-separated string, with
+// any leading # removed, and tags differing only in case are pushed once.
+// Rewritten tags are restored as written on pull.
+type TagsConfig struct {
+	// Case is "keep" (default) or "lower".
+	Case string `yaml:"case"`
+
+	// Spaces sets how spaces in tags are written: "keep" (default),
+	// "hyphen", "underscore", or "remove".
+	Spaces string `yaml:"spaces"`
+
+	// Inline adds the #tags in the body to the frontmatter tags. By
+	// default body tags are only pushed for notes without frontmatter tags.
+	Inline bool `yaml:"inline"`
+}
+
+// DatesConfig names the Notion date properties that hold a note's creation
+// and modification dates, and sets the format of frontmatter dates. On push
+// the properties are set from the frontmatter "created" and "updated" keys,
+// or from the file's timestamps. On pull the page's creation and last edit
+// times are written back to those keys.
+type DatesConfig struct {
+	// Created is the date property for the creation date, e.g. "Created".
+	Created string `yaml:"created"`
+
+	// Modified is the date property for the modification date.
+	Modified string `yaml:"modified"`
+
+	// Format is the Go time layout of frontmatter dates, e.g. "02.01.2006"
+	// for DD.MM.YYYY. Dates in this layout are read on push, and pulled
+	// dates are written in it. Default: ISO, "2006-01-02".
+	Format string `yaml:"format"`
+
+	// DateTimeFormat is the layout of frontmatter dates with a time, e.g.
+	// "02.01.2006 15:04". Default: "2006-01-02T15:04:05".
+	DateTimeFormat string `yaml:"datetime_format"`
+
+	// Timezone is the IANA zone of frontmatter times without an offset,
+	// e.g. "Europe/Berlin". Pulled times are converted to it. Default: the
+	// system's local time.
+	Timezone string `yaml:"timezone"`
+}
+
+// Location returns the zone of Timezone, or nil for local time.
+// Validate rejects unknown zones, so they are treated as unset.
+func (d DatesConfig) Location() *time.Location {
+	if d.Timezone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// validDateLayout reports whether a Go time layout writes a date that
+// parses back to the same day.
+func validDateLayout(layout string) bool {
+	ref := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+	parsed, err := time.Parse(layout, ref.Format(layout))
+	return err == nil && parsed.Year() == ref.Year() && parsed.YearDay() == ref.YearDay()
 }
 
 // SyncConfig holds synchronization behavior settings.
 type SyncConfig struct {
+	// Root scopes syncing to a folder of the vault, e.g. "Areas/Public".
+	// Notes outside it are never scanned, watched, pushed, or pulled.
+	// Paths in the state database, mappings, and ignore patterns are
+	// relative to the folder, and the state database is kept in it, so the
+	// folder can move within or between vaults. Default: the whole vault.
+	Root string `yaml:"root"`
+
 	// ConflictStrategy: "local", "remote", "manual", or "newer".
 	ConflictStrategy string `yaml:"conflict_strategy"`
 
@@ -109,8 +481,249 @@ type SyncConfig struct {
 	// - ignore: Keep in Notion, just remove from sync_state.
 	DeletionStrategy string `yaml:"deletion_strategy"`
 
+	// MaxDeletionsPerRun is how many Notion pages a push, sync, or watch
+	// batch may archive for deleted notes before it stops and asks for
+	// --confirm-deletions. Default: 25. Set to -1 to disable the guard.
+	MaxDeletionsPerRun int `yaml:"max_deletions_per_run"`
+
 	// Ignore patterns for files to skip.
 	Ignore []string `yaml:"ignore"`
+
+	// LinkRepair controls how notes linking to a renamed note are updated:
+	// "batch", "immediate", or "off".
+	// - batch: Re-push affected referrers once, after all renames are handled.
+	// - immediate: Re-push affected referrers right after each rename.
+	// - off: Leave referrers alone until they are edited.
+	LinkRepair string `yaml:"link_repair"`
+
+	// MaxNoteSize skips notes larger than this, e.g. "5MB". Default: 5MB.
+	// Set to "0" to disable the limit.
+	MaxNoteSize string `yaml:"max_note_size"`
+
+	// MaxAttachmentSize skips attachments larger than this. Default: 20MB,
+	// the largest file Notion accepts in a single-part upload. Larger
+	// files are uploaded in parts, resuming where an interrupted upload
+	// stopped. Set to "0" to disable the limit.
+	MaxAttachmentSize string `yaml:"max_attachment_size"`
+
+	// StreamNoteSize pushes notes larger than this in chunks, parsing and
+	// appending a part of the note at a time so memory stays bounded.
+	// Default: 1MB. Set to "0" to push every note whole. Notes are still
+	// skipped above max_note_size, so raise it to push larger notes.
+	StreamNoteSize string `yaml:"stream_note_size"`
+
+	// AttachmentFolder is where pulled attachments are saved and where
+	// embeds are looked up first. It uses the syntax of Obsidian's
+	// attachmentFolderPath ("/", "./", "./assets", or "Files/Attachments")
+	// and overrides the setting in .obsidian/app.json. Default: the vault's
+	// setting.
+	AttachmentFolder string `yaml:"attachment_folder"`
+
+	// IncludeTemplates syncs the template folders of Obsidian's Templates
+	// and Templater plugins, read from the vault's .obsidian settings.
+	// Default: false, template notes are never synced.
+	IncludeTemplates bool `yaml:"include_templates"`
+
+	// UndoRetention is how long the snapshots taken before each push, pull,
+	// or sync are kept for "sync undo", e.g. "72h". Default: 168h (7 days).
+	// Set to "0" to stop taking snapshots.
+	UndoRetention string `yaml:"undo_retention"`
+
+	// FileTimeout limits pushing or pulling each note, including its
+	// attachments, e.g. "5m". A note that runs out of time fails and is
+	// retried on the next run. Default: 10m. Set to "0" for no limit.
+	FileTimeout string `yaml:"file_timeout"`
+
+	// IgnoreTrivialChanges hashes notes in a canonical form for change
+	// detection, so rewrites that only reorder frontmatter keys or add or
+	// remove blank lines in frontmatter, as some plugins do, are not
+	// synced. Trailing whitespace is always ignored. Notes whose keys are
+	// unsorted are pushed once after this is turned on. Default: false.
+	IgnoreTrivialChanges bool `yaml:"ignore_trivial_changes"`
+
+	// Directions restrict which way notes under a path sync. The first
+	// matching policy wins; paths without one sync both ways.
+	Directions []DirectionPolicy `yaml:"directions"`
+
+	// Filenames controls how the titles of pages pulled from Notion become
+	// note filenames.
+	Filenames FilenamesConfig `yaml:"filenames"`
+
+	// PullLayout is the path of the notes of new pages pulled from the
+	// default database, such as "{{Area}}/{{title}}.md". Placeholders are
+	// {{title}}, {{year}} and {{month}} of creation, {{parent}} for the
+	// folders of the page's parent items, and {{Name}} for the value of
+	// the page's Name property. Default: "{{title}}.md", at the top of the
+	// vault.
+	PullLayout string `yaml:"pull_layout"`
+}
+
+// FilenamesConfig controls the filenames of notes created on pull.
+type FilenamesConfig struct {
+	// Unicode normalizes filenames: "nfc" (default), "nfd", or "none".
+	// NFC matches what most editors type; macOS tools may write NFD.
+	Unicode string `yaml:"unicode"`
+
+	// Emoji in titles: "keep" (default), "strip", or "transliterate"
+	// (replaced by their names, e.g. "rocket").
+	Emoji string `yaml:"emoji"`
+}
+
+// Modes for Config.Mode.
+const (
+	ModeSync   = "sync"
+	ModeMirror = "mirror"
+)
+
+// Sync directions for DirectionPolicy.
+const (
+	DirectionPush = "push"
+	DirectionPull = "pull"
+	DirectionBoth = "both"
+)
+
+// DirectionPolicy limits the notes matching a path to one sync direction.
+type DirectionPolicy struct {
+	// Path is a glob pattern such as "templates/*.md", or a folder such as
+	// "templates/" that covers everything below it.
+	Path string `yaml:"path"`
+
+	// Direction is "push" (Obsidian to Notion only), "pull" (Notion to
+	// Obsidian only), or "both".
+	Direction string `yaml:"direction"`
+}
+
+// matches reports whether the policy covers a vault-relative path.
+func (p DirectionPolicy) matches(path string) bool {
+	pattern := filepath.ToSlash(p.Path)
+	path = filepath.ToSlash(path)
+	if matched, _ := filepath.Match(pattern, path); matched {
+		return true
+	}
+	folder := strings.TrimSuffix(strings.TrimSuffix(pattern, "**"), "/")
+	if folder == "" || strings.ContainsAny(folder, "*?[") {
+		return false
+	}
+	return strings.HasPrefix(path, folder+"/")
+}
+
+// NoteSizeLimit returns the maximum note size in bytes, or 0 for no limit.
+func (s SyncConfig) NoteSizeLimit() int64 {
+	return sizeOrDefault(s.MaxNoteSize, DefaultMaxNoteSize)
+}
+
+// AttachmentSizeLimit returns the maximum attachment size in bytes, or 0
+// for no limit.
+func (s SyncConfig) AttachmentSizeLimit() int64 {
+	return sizeOrDefault(s.MaxAttachmentSize, DefaultMaxAttachmentSize)
+}
+
+// StreamThreshold returns the size in bytes above which notes are pushed
+// in chunks, or 0 to never stream.
+func (s SyncConfig) StreamThreshold() int64 {
+	return sizeOrDefault(s.StreamNoteSize, DefaultStreamNoteSize)
+}
+
+// UndoRetentionPeriod returns how long undo snapshots are kept, or 0 when
+// snapshots are disabled.
+func (s SyncConfig) UndoRetentionPeriod() time.Duration {
+	if s.UndoRetention == "" {
+		return DefaultUndoRetention
+	}
+	d, err := time.ParseDuration(s.UndoRetention)
+	if err != nil || d < 0 {
+		return DefaultUndoRetention
+	}
+	return d
+}
+
+// FileTimeoutPeriod returns how long pushing or pulling one note may
+// take, or 0 for no limit.
+func (s SyncConfig) FileTimeoutPeriod() time.Duration {
+	if s.FileTimeout == "" {
+		return DefaultFileTimeout
+	}
+	d, err := time.ParseDuration(s.FileTimeout)
+	if err != nil || d < 0 {
+		return DefaultFileTimeout
+	}
+	return d
+}
+
+// DeletionLimit returns how many pages a run may archive without
+// confirmation, or 0 for no limit.
+func (s SyncConfig) DeletionLimit() int {
+	switch {
+	case s.MaxDeletionsPerRun < 0:
+		return 0
+	case s.MaxDeletionsPerRun == 0:
+		return DefaultMaxDeletionsPerRun
+	default:
+		return s.MaxDeletionsPerRun
+	}
+}
+
+// sizeOrDefault parses a size setting, falling back to def when unset or
+// invalid. Validate rejects invalid sizes, so the fallback is a safety net.
+func sizeOrDefault(value string, def int64) int64 {
+	if value == "" {
+		return def
+	}
+	n, err := ParseSize(value)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// ParseSize parses a byte size such as "512KB", "5MB", "1.5GB", or "1024".
+// Units are powers of 1024 and case-insensitive.
+func ParseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		value  int64
+	}{
+		{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"B", 1},
+	} {
+		if strings.HasSuffix(str, unit.suffix) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, unit.suffix))
+			multiplier = unit.value
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(str, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// PullConfig holds formatting settings for pulled notes.
+type PullConfig struct {
+	// Wrap re-wraps long paragraph lines of pulled notes: "off" (default,
+	// one line per paragraph), a line width such as "80", or "preserve"
+	// (paragraphs whose text is unchanged keep the line breaks of the local
+	// note). Code, tables, and frontmatter are never wrapped.
+	Wrap string `yaml:"wrap"`
+
+	// BookmarkPreviews fetches the page each pulled bookmark links to and
+	// writes its title and description in a fenced "bookmark" block after
+	// the link, since the Notion API does not return bookmark previews.
+	// Push leaves the block out. Default: false.
+	BookmarkPreviews bool `yaml:"bookmark_previews"`
+}
+
+// WrapWidth returns the line width pulled paragraphs are wrapped to, or 0
+// when pull.wrap is not a width.
+func (p PullConfig) WrapWidth() int {
+	width, err := strconv.Atoi(p.Wrap)
+	if err != nil || width < 0 {
+		return 0
+	}
+	return width
 }
 
 // WatchConfig holds watch mode configuration.
@@ -119,6 +732,12 @@ type WatchConfig struct {
 	// Default: 5s. This prevents sync storms during rapid edits.
 	Debounce string `yaml:"debounce"`
 
+	// Settle is how long a changed note's size and modification time must
+	// stay the same before it is synced, so notes that iCloud or Obsidian
+	// Sync write in pieces are not pushed half-written. Default: 1s. Set to
+	// 0 to disable.
+	Settle string `yaml:"settle"`
+
 	// PollInterval is the interval for polling Notion for remote changes.
 	// Default: 5m. Set to 0 to disable polling.
 	PollInterval string `yaml:"poll_interval"`
@@ -130,6 +749,121 @@ type WatchConfig struct {
 	// LogFile is the path to the log file for daemon mode.
 	// Default: stdout
 	LogFile string `yaml:"log_file"`
+
+	// CoalesceOnResume syncs every change queued by 'watch pause' in one
+	// batch as soon as the watcher resumes. By default, queued notes wait
+	// out a fresh debounce from the resume, like new changes.
+	CoalesceOnResume bool `yaml:"coalesce_on_resume"`
+
+	// API serves a local HTTP API from the watch process, so an Obsidian
+	// plugin or scripts can sync a note immediately and show sync status.
+	API WatchAPIConfig `yaml:"api"`
+}
+
+// WatchAPIConfig holds the watch process's local HTTP API settings.
+type WatchAPIConfig struct {
+	// Listen is the loopback address to serve on, e.g. "127.0.0.1:27125".
+	// Empty disables the API.
+	Listen string `yaml:"listen"`
+
+	// Token must be sent as "Authorization: Bearer <token>" with every
+	// request. Required when Listen is set. Supports ${ENV_VAR} syntax.
+	Token string `yaml:"token"`
+}
+
+// NotifyConfig holds sync report notification settings.
+type NotifyConfig struct {
+	// When selects which syncs send a report: "always", "changes", or "failure".
+	// - always: Send a report after every sync.
+	// - changes: Send when anything was pushed or pulled, or on failure.
+	// - failure: Send only when a sync fails or leaves conflicts.
+	When string `yaml:"when"`
+
+	// Slack sends reports to a Slack incoming webhook.
+	Slack SlackConfig `yaml:"slack"`
+
+	// SMTP sends reports by email.
+	SMTP SMTPConfig `yaml:"smtp"`
+}
+
+// SlackConfig holds Slack webhook settings.
+type SlackConfig struct {
+	// WebhookURL is the incoming webhook URL. Supports ${ENV_VAR}.
+	WebhookURL string `yaml:"webhook_url"`
+}
+
+// SMTPConfig holds email delivery settings.
+type SMTPConfig struct {
+	// Host and Port of the SMTP server. Port defaults to 587.
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+
+	// Username and Password for PLAIN auth. Password supports ${ENV_VAR}.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+
+	// From is the sender address.
+	From string `yaml:"from"`
+
+	// To lists the recipient addresses.
+	To []string `yaml:"to"`
+}
+
+// Enabled reports whether any notification channel is configured.
+func (n NotifyConfig) Enabled() bool {
+	return n.Slack.WebhookURL != "" || n.SMTP.Host != ""
+}
+
+// PublishConfig holds the settings of the publish command.
+type PublishConfig struct {
+	// StatusProperty is the Notion property set to StatusValue when a note
+	// is published (default "Status"). It must be mapped to a frontmatter
+	// key in the property mappings; publish sets the key and pushes.
+	StatusProperty string `yaml:"status_property"`
+
+	// StatusValue is the option that marks a page published (default
+	// "Published").
+	StatusValue string `yaml:"status_value"`
+
+	// URLKey is the frontmatter key the page's public URL is recorded in,
+	// if the page is shared to the web (default "public_url").
+	URLKey string `yaml:"url_key"`
+
+	// Webhook, if set, is sent a JSON POST for each published note.
+	// Supports ${ENV_VAR}.
+	Webhook string `yaml:"webhook"`
+}
+
+// LinksConfig holds the settings for wiki-links.
+type LinksConfig struct {
+	// AutoCreateStubs creates a stub note, and its page, for each
+	// wiki-link of a pushed note to a note that does not exist, so the
+	// link becomes a page mention. Default: false, such links are
+	// pushed as transform.unresolved_links says.
+	AutoCreateStubs bool `yaml:"auto_create_stubs"`
+
+	// StubFolder is the vault folder stubs are created in, unless the
+	// link names a path. Default: the top of the vault.
+	StubFolder string `yaml:"stub_folder"`
+
+	// StubTemplate is a note in the vault whose content new stubs get,
+	// with {{title}}, {{date}}, and {{time}} filled in. Default: stubs
+	// are empty.
+	StubTemplate string `yaml:"stub_template"`
+}
+
+// TemplateConfig holds the settings for page templates.
+type TemplateConfig struct {
+	// Page is the ID or URL of a Notion page whose icon, cover, and
+	// blocks each new page gets before the note's content. Blocks the API
+	// cannot copy, such as databases, are left out. Default: none, pages
+	// get only the note's content.
+	Page string `yaml:"page"`
+
+	// Marker is the text of the template's paragraph the note's content
+	// replaces. Without such a paragraph, the content follows the
+	// template. Default: "{{content}}".
+	Marker string `yaml:"marker"`
 }
 
 // RateLimitConfig holds rate limiting settings.
@@ -140,9 +874,68 @@ type RateLimitConfig struct {
 	// BatchSize is the max blocks per API request.
 	BatchSize int `yaml:"batch_size"`
 
+	// PageSize is the number of results requested per page when listing
+	// database pages and search results. Large databases are read page by
+	// page until every result is fetched.
+	PageSize int `yaml:"page_size"`
+
 	// Workers is the number of parallel workers for processing.
 	// Default is 4. Set to 1 for sequential processing.
 	Workers int `yaml:"workers"`
+
+	// Buckets is how requests share the rate limit: "token" (default)
+	// paces each integration's requests together; "database" also gives
+	// each database a share of its integration's requests_per_second, so
+	// a busy database does not starve the others. Shares are the
+	// mappings' requests_per_second, or else equal; together, an
+	// integration's databases keep to its requests_per_second.
+	Buckets string `yaml:"buckets"`
+}
+
+// Rate limit buckets.
+const (
+	BucketsToken    = "token"
+	BucketsDatabase = "database"
+)
+
+// HTTPConfig holds the settings of the HTTP client used for Notion
+// requests, attachment uploads, and downloads.
+type HTTPConfig struct {
+	// Proxy is the URL of the proxy to send requests through, such as
+	// "http://proxy.example.com:3128". Empty uses the HTTPS_PROXY and
+	// NO_PROXY environment variables. Supports ${ENV_VAR} syntax, for
+	// proxy URLs with credentials.
+	Proxy string `yaml:"proxy"`
+
+	// CACert is a PEM file of certificate authorities to trust in
+	// addition to the system's, such as a proxy's that inspects TLS.
+	CACert string `yaml:"ca_cert"`
+
+	// Timeout limits each request, e.g. "60s". Default: no limit.
+	Timeout string `yaml:"timeout"`
+}
+
+// RequestTimeout returns the limit of each request, or 0 for none.
+func (h HTTPConfig) RequestTimeout() time.Duration {
+	d, err := time.ParseDuration(h.Timeout)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// AttachmentsConfig holds attachment upload settings. Attachments upload
+// in a queue of their own, so a batch of large images does not hold up
+// page operations.
+type AttachmentsConfig struct {
+	// Concurrency is how many attachments upload at once across all
+	// workers. Default: 2.
+	Concurrency int `yaml:"concurrency"`
+
+	// MaxRPS caps attachment upload requests per second, out of
+	// rate_limit.requests_per_second, leaving the rest to page operations.
+	// Default: 1.
+	MaxRPS float64 `yaml:"max_rps"`
 }
 
 // DefaultConfig returns a Config with sensible defaults.
@@ -152,22 +945,23 @@ func DefaultConfig() *Config {
 			Dataview:        "placeholder",
 			UnresolvedLinks: "placeholder",
 			Callouts: map[string]string{
-				"note":    "💡",
-				"warning": "⚠️",
-				"tip":     "💡",
-				"info":    "ℹ️",
-				"danger":  "🔴",
-				"example": "📝",
-				"quote":   "💬",
-				"success": "✅",
-				"failure": "❌",
-				"bug":     "🐛",
-				"question": "❓",
+				"note":     transformer.EmojiLightBulb,
+				"warning":  transformer.EmojiWarning,
+				"tip":      transformer.EmojiLightBulb,
+				"info":     transformer.EmojiInformation,
+				"danger":   transformer.EmojiRedCircle,
+				"example":  transformer.EmojiMemo,
+				"quote":    transformer.EmojiSpeechBalloon,
+				"success":  transformer.EmojiWhiteHeavyCheck,
+				"failure":  transformer.EmojiCross,
+				"bug":      transformer.EmojiBug,
+				"question": transformer.EmojiQuestion,
 			},
 		},
 		Sync: SyncConfig{
 			ConflictStrategy: "manual",
 			DeletionStrategy: "archive",
+			LinkRepair:       "batch",
 			Ignore: []string{
 				"templates/**",
 				"**/.excalidraw.md",
@@ -176,19 +970,42 @@ func DefaultConfig() *Config {
 		RateLimit: RateLimitConfig{
 			RequestsPerSecond: DefaultRequestsPerSecond,
 			BatchSize:         DefaultBatchSize,
+			PageSize:          DefaultPageSize,
 			Workers:           4,
 		},
+		Attachments: AttachmentsConfig{
+			Concurrency: DefaultAttachmentConcurrency,
+			MaxRPS:      DefaultAttachmentRequestsPerSecond,
+		},
 		Watch: WatchConfig{
 			Debounce:     "5s",
 			PollInterval: "5m",
 		},
+		Notify: NotifyConfig{
+			When: "always",
+		},
+		Publish: PublishConfig{
+			StatusProperty: "Status",
+			StatusValue:    "Published",
+			URLKey:         "public_url",
+		},
 	}
 }
 
 // Load loads configuration from a file or default locations.
 func Load(path string) (*Config, error) {
+	path, err := Find(path)
+	if err != nil {
+		return nil, err
+	}
+	return loadFromFile(path)
+}
+
+// Find returns the config file Load reads: path if it is given, otherwise
+// the first default location that exists.
+func Find(path string) (string, error) {
 	if path != "" {
-		return loadFromFile(path)
+		return path, nil
 	}
 
 	// Try default locations in order.
@@ -207,11 +1024,11 @@ func Load(path string) (*Config, error) {
 
 	for _, loc := range locations {
 		if _, err := os.Stat(loc); err == nil {
-			return loadFromFile(loc)
+			return loc, nil
 		}
 	}
 
-	return nil, fmt.Errorf("no configuration file found (tried: %s)", strings.Join(locations, ", "))
+	return "", fmt.Errorf("no configuration file found (tried: %s)", strings.Join(locations, ", "))
 }
 
 // loadFromFile loads configuration from a specific file.
@@ -220,7 +1037,11 @@ func loadFromFile(path string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read config file: %w", err)
 	}
+	return parseConfig(data)
+}
 
+// parseConfig loads configuration from the contents of a config file.
+func parseConfig(data []byte) (*Config, error) {
 	// Start with defaults.
 	cfg := DefaultConfig()
 
@@ -242,16 +1063,64 @@ func loadFromFile(path string) (*Config, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
+	if cfg.Sync.Root != "" {
+		if err := cfg.SetRoot(cfg.Sync.Root); err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+	}
 
 	return cfg, nil
 }
 
+// SetRoot scopes the config to a folder of the vault: Vault becomes the
+// folder, and VaultRoot keeps the whole vault. The folder is relative to
+// the whole vault, even if the config is already scoped; "" or "." is the
+// whole vault.
+func (c *Config) SetRoot(root string) error {
+	dir := filepath.Clean(filepath.FromSlash(strings.TrimSpace(root)))
+	if filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid sync root: %s (use a folder inside the vault)", root)
+	}
+
+	vaultRoot := c.VaultRoot()
+	scoped := filepath.Join(vaultRoot, dir)
+	if info, err := os.Stat(scoped); err != nil || !info.IsDir() {
+		return fmt.Errorf("sync root is not a folder of the vault: %s", root)
+	}
+
+	c.Sync.Root = filepath.ToSlash(dir)
+	if dir == "." {
+		c.Sync.Root = ""
+	}
+	c.Vault = scoped
+	c.vaultRoot = vaultRoot
+	return nil
+}
+
+// VaultRoot returns the whole vault, which holds the .obsidian settings,
+// when Vault is scoped to sync.root, and Vault otherwise.
+func (c *Config) VaultRoot() string {
+	if c.vaultRoot != "" {
+		return c.vaultRoot
+	}
+	return c.Vault
+}
+
 // expandEnvVars expands ${ENV_VAR} references in config values.
 func (c *Config) expandEnvVars() {
 	c.Notion.Token = expandEnv(c.Notion.Token)
 	c.Notion.DefaultDatabase = expandEnv(c.Notion.DefaultDatabase)
 	c.Notion.DefaultPage = expandEnv(c.Notion.DefaultPage)
+	c.Notion.StagingDatabase = expandEnv(c.Notion.StagingDatabase)
+	for name, token := range c.Notion.Credentials {
+		c.Notion.Credentials[name] = expandEnv(token)
+	}
 	c.Vault = expandEnv(c.Vault)
+	c.Notify.Slack.WebhookURL = expandEnv(c.Notify.Slack.WebhookURL)
+	c.Notify.SMTP.Password = expandEnv(c.Notify.SMTP.Password)
+	c.Watch.API.Token = expandEnv(c.Watch.API.Token)
+	c.HTTP.Proxy = expandEnv(c.HTTP.Proxy)
+	c.Publish.Webhook = expandEnv(c.Publish.Webhook)
 }
 
 // expandEnv expands ${VAR} or $VAR references.
@@ -284,6 +1153,18 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("at least one of notion.default_database, notion.default_page, or mappings is required")
 	}
 
+	for name, token := range c.Notion.Credentials {
+		if token == "" {
+			return fmt.Errorf("notion.credentials.%s is empty", name)
+		}
+	}
+
+	if c.Notion.APIVersion != "" {
+		if _, err := time.Parse("2006-01-02", c.Notion.APIVersion); err != nil {
+			return fmt.Errorf("invalid notion.api_version: %s (must be a date like 2022-06-28)", c.Notion.APIVersion)
+		}
+	}
+
 	// Validate conflict strategy if set.
 	if c.Sync.ConflictStrategy != "" {
 		validStrategies := map[string]bool{
@@ -294,6 +1175,27 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate direction policies.
+	for _, p := range c.Sync.Directions {
+		if p.Path == "" {
+			return fmt.Errorf("invalid sync.directions: path is required")
+		}
+		switch p.Direction {
+		case DirectionPush, DirectionPull, DirectionBoth:
+		default:
+			return fmt.Errorf("invalid sync.directions direction for %s: %q (must be push, pull, or both)", p.Path, p.Direction)
+		}
+	}
+
+	// Validate the mode. A mirror never archives or deletes pages.
+	switch c.Mode {
+	case "", ModeSync:
+	case ModeMirror:
+		c.Sync.DeletionStrategy = "ignore"
+	default:
+		return fmt.Errorf("invalid mode: %s (must be sync or mirror)", c.Mode)
+	}
+
 	// Validate deletion strategy if set.
 	if c.Sync.DeletionStrategy != "" {
 		validDeletionStrategies := map[string]bool{
@@ -303,6 +1205,84 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("invalid deletion_strategy: %s (must be archive, delete, or ignore)", c.Sync.DeletionStrategy)
 		}
 	}
+	if c.Sync.MaxDeletionsPerRun < -1 {
+		return fmt.Errorf("invalid sync.max_deletions_per_run: %d (must be positive, or -1 for no limit)", c.Sync.MaxDeletionsPerRun)
+	}
+
+	// Validate notification settings if set.
+	if c.Notify.When != "" {
+		validWhen := map[string]bool{"always": true, "changes": true, "failure": true}
+		if !validWhen[c.Notify.When] {
+			return fmt.Errorf("invalid notify.when: %s (must be always, changes, or failure)", c.Notify.When)
+		}
+	}
+	if c.Notify.SMTP.Host != "" {
+		if c.Notify.SMTP.From == "" {
+			return fmt.Errorf("notify.smtp.from is required when notify.smtp.host is set")
+		}
+		if len(c.Notify.SMTP.To) == 0 {
+			return fmt.Errorf("notify.smtp.to is required when notify.smtp.host is set")
+		}
+	}
+
+	// Validate heading split if set.
+	if c.Transform.SplitOn != "" && c.Transform.SplitOn != "h1" {
+		return fmt.Errorf("invalid split_on transform: %s (must be h1)", c.Transform.SplitOn)
+	}
+
+	// Validate link repair mode if set.
+	if c.Sync.LinkRepair != "" {
+		validLinkRepair := map[string]bool{"batch": true, "immediate": true, "off": true}
+		if !validLinkRepair[c.Sync.LinkRepair] {
+			return fmt.Errorf("invalid link_repair: %s (must be batch, immediate, or off)", c.Sync.LinkRepair)
+		}
+	}
+
+	// Validate size limits if set.
+	if c.Sync.MaxNoteSize != "" {
+		if _, err := ParseSize(c.Sync.MaxNoteSize); err != nil {
+			return fmt.Errorf("invalid max_note_size: %s (use a size like 5MB)", c.Sync.MaxNoteSize)
+		}
+	}
+	if c.Sync.MaxAttachmentSize != "" {
+		if _, err := ParseSize(c.Sync.MaxAttachmentSize); err != nil {
+			return fmt.Errorf("invalid max_attachment_size: %s (use a size like 20MB)", c.Sync.MaxAttachmentSize)
+		}
+	}
+	if c.Sync.StreamNoteSize != "" {
+		if _, err := ParseSize(c.Sync.StreamNoteSize); err != nil {
+			return fmt.Errorf("invalid stream_note_size: %s (use a size like 1MB)", c.Sync.StreamNoteSize)
+		}
+	}
+	if c.Sync.UndoRetention != "" {
+		if d, err := time.ParseDuration(c.Sync.UndoRetention); err != nil || d < 0 {
+			return fmt.Errorf("invalid undo_retention: %s (use a duration like 168h, or 0 to disable)", c.Sync.UndoRetention)
+		}
+	}
+	if c.Sync.FileTimeout != "" {
+		if d, err := time.ParseDuration(c.Sync.FileTimeout); err != nil || d < 0 {
+			return fmt.Errorf("invalid file_timeout: %s (use a duration like 5m, or 0 for no limit)", c.Sync.FileTimeout)
+		}
+	}
+
+	if c.Sync.Filenames.Unicode != "" {
+		validUnicode := map[string]bool{"nfc": true, "nfd": true, "none": true}
+		if !validUnicode[c.Sync.Filenames.Unicode] {
+			return fmt.Errorf("invalid filenames unicode: %s (must be nfc, nfd, or none)", c.Sync.Filenames.Unicode)
+		}
+	}
+
+	if c.Sync.Filenames.Emoji != "" {
+		validEmoji := map[string]bool{"keep": true, "strip": true, "transliterate": true}
+		if !validEmoji[c.Sync.Filenames.Emoji] {
+			return fmt.Errorf("invalid filenames emoji: %s (must be keep, strip, or transliterate)", c.Sync.Filenames.Emoji)
+		}
+	}
+	if layout := filepath.ToSlash(c.Sync.PullLayout); layout != "" {
+		if strings.HasPrefix(layout, "/") || filepath.IsAbs(c.Sync.PullLayout) || !strings.HasSuffix(layout, ".md") || slices.Contains(strings.Split(layout, "/"), "..") {
+			return fmt.Errorf("invalid sync.pull_layout: %s (must be a note path in the vault, like {{Area}}/{{title}}.md)", c.Sync.PullLayout)
+		}
+	}
 
 	// Validate transform settings if set.
 	if c.Transform.Dataview != "" {
@@ -312,6 +1292,115 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.Transform.HTML != "" {
+		validHTML := map[string]bool{"convert": true, "preserve": true, "strip": true}
+		if !validHTML[c.Transform.HTML] {
+			return fmt.Errorf("invalid html transform: %s (must be convert, preserve, or strip)", c.Transform.HTML)
+		}
+	}
+	if c.Transform.TextColors != "" {
+		validColors := map[string]bool{"html": true, "highlight": true, "strip": true}
+		if !validColors[c.Transform.TextColors] {
+			return fmt.Errorf("invalid text_colors transform: %s (must be html, highlight, or strip)", c.Transform.TextColors)
+		}
+	}
+
+	for name, userID := range c.Transform.Users {
+		if name == "" || strings.HasPrefix(name, "@") {
+			return fmt.Errorf("invalid transform.users name: %q (write the name without @)", name)
+		}
+		if userID == "" {
+			return fmt.Errorf("transform.users: no user ID for %s", name)
+		}
+	}
+
+	if c.Transform.DateMentions != "" {
+		validDateMentions := map[string]bool{"text": true, "marker": true, "iso": true}
+		if !validDateMentions[c.Transform.DateMentions] {
+			return fmt.Errorf("invalid date_mentions transform: %s (must be text, marker, or iso)", c.Transform.DateMentions)
+		}
+	}
+
+	if c.Transform.NestedTags != "" {
+		validNestedTags := map[string]bool{"keep": true, "expand": true, "flatten": true, "top": true}
+		if !validNestedTags[c.Transform.NestedTags] {
+			return fmt.Errorf("invalid nested_tags transform: %s (must be keep, expand, flatten, or top)", c.Transform.NestedTags)
+		}
+	}
+
+	if tc := c.Transform.Tags.Case; tc != "" && tc != "keep" && tc != "lower" {
+		return fmt.Errorf("invalid tags case: %s (must be keep or lower)", tc)
+	}
+	if ts := c.Transform.Tags.Spaces; ts != "" {
+		validSpaces := map[string]bool{"keep": true, "hyphen": true, "underscore": true, "remove": true}
+		if !validSpaces[ts] {
+			return fmt.Errorf("invalid tags spaces: %s (must be keep, hyphen, underscore, or remove)", ts)
+		}
+	}
+
+	if d := c.Transform.Dividers; d != "" && d != "---" && d != "***" && d != "___" {
+		return fmt.Errorf("invalid dividers transform: %s (must be ---, ***, or ___)", d)
+	}
+
+	if c.Transform.EmptyParagraphs != "" {
+		validEmpty := map[string]bool{"keep": true, "collapse": true}
+		if !validEmpty[c.Transform.EmptyParagraphs] {
+			return fmt.Errorf("invalid empty_paragraphs transform: %s (must be keep or collapse)", c.Transform.EmptyParagraphs)
+		}
+	}
+
+	for state, mapping := range c.Transform.TaskStates {
+		if utf8.RuneCountInString(state) != 1 || state == " " || strings.EqualFold(state, "x") || state == "]" {
+			return fmt.Errorf("invalid task_states key: %q (must be one character other than space, x, or ])", state)
+		}
+		if strings.TrimSpace(mapping) == "" {
+			return fmt.Errorf("invalid task_states value for %q: must be checked, unchecked, or badge text", state)
+		}
+	}
+
+	for callout, icon := range c.Transform.Callouts {
+		if err := transformer.ValidateIcon(icon); err != nil {
+			return fmt.Errorf("invalid transform.callouts value for %q: %w", callout, err)
+		}
+	}
+	for name, icon := range c.Transform.Icons {
+		if _, ok := transformer.DefaultIcons[name]; !ok {
+			return fmt.Errorf("invalid transform.icons key: %q (must be callout, dataview, image, map, or unsupported)", name)
+		}
+		if err := transformer.ValidateIcon(icon); err != nil {
+			return fmt.Errorf("invalid transform.icons value for %q: %w", name, err)
+		}
+	}
+
+	for plugin := range c.Transform.PluginBlocks {
+		if !transformer.IsPluginBlock(plugin) {
+			return fmt.Errorf("invalid transform.plugin_blocks key: %q (must be admonition, kanban, or leaflet)", plugin)
+		}
+	}
+
+	switch c.Pull.Wrap {
+	case "", "off", "preserve":
+	default:
+		if c.Pull.WrapWidth() < 20 {
+			return fmt.Errorf("invalid pull wrap: %s (use off, preserve, or a line width of at least 20)", c.Pull.Wrap)
+		}
+	}
+
+	if c.Transform.Dates.Created != "" && c.Transform.Dates.Created == c.Transform.Dates.Modified {
+		return fmt.Errorf("invalid dates transform: created and modified both use %q", c.Transform.Dates.Created)
+	}
+	if f := c.Transform.Dates.Format; f != "" && !validDateLayout(f) {
+		return fmt.Errorf("invalid dates format: %q (use a Go time layout with a year, month, and day, like 02.01.2006)", f)
+	}
+	if f := c.Transform.Dates.DateTimeFormat; f != "" && !validDateLayout(f) {
+		return fmt.Errorf("invalid dates datetime_format: %q (use a Go time layout like 02.01.2006 15:04)", f)
+	}
+	if tz := c.Transform.Dates.Timezone; tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return fmt.Errorf("invalid dates timezone: %s (use an IANA zone like Europe/Berlin)", tz)
+		}
+	}
+
 	if c.Transform.UnresolvedLinks != "" {
 		validUnresolved := map[string]bool{"placeholder": true, "text": true, "skip": true}
 		if !validUnresolved[c.Transform.UnresolvedLinks] {
@@ -323,6 +1412,19 @@ func (c *Config) Validate() error {
 	if err := validatePropertyMappings(c.Transform.PropertyMappings, "transform.property_mappings"); err != nil {
 		return err
 	}
+	if err := validatePropertyMappings(c.Properties.Renames(), "properties.map"); err != nil {
+		return err
+	}
+	if c.Properties.Unmapped != "" {
+		validUnmapped := map[string]bool{UnmappedPull: true, UnmappedPassthrough: true, UnmappedIgnore: true}
+		if !validUnmapped[c.Properties.Unmapped] {
+			return fmt.Errorf("invalid properties.unmapped: %s (must be pull, passthrough, or ignore)", c.Properties.Unmapped)
+		}
+	}
+
+	if err := validateWatchAPI(c.Watch.API); err != nil {
+		return err
+	}
 
 	// Validate rate limit settings.
 	if c.RateLimit.RequestsPerSecond < 0 {
@@ -334,6 +1436,77 @@ func (c *Config) Validate() error {
 	if c.RateLimit.BatchSize > MaxBatchSize {
 		return fmt.Errorf("rate_limit.batch_size must not exceed %d", MaxBatchSize)
 	}
+	if c.RateLimit.PageSize < 1 {
+		c.RateLimit.PageSize = DefaultPageSize
+	}
+	if c.RateLimit.PageSize > MaxPageSize {
+		return fmt.Errorf("rate_limit.page_size must not exceed %d", MaxPageSize)
+	}
+	if c.RateLimit.Buckets != "" && c.RateLimit.Buckets != BucketsToken && c.RateLimit.Buckets != BucketsDatabase {
+		return fmt.Errorf("invalid rate_limit.buckets: %s (must be token or database)", c.RateLimit.Buckets)
+	}
+	if err := c.validateBucketRates(); err != nil {
+		return err
+	}
+
+	// Validate HTTP settings.
+	if c.HTTP.Proxy != "" {
+		u, err := url.Parse(c.HTTP.Proxy)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			return fmt.Errorf("invalid http.proxy: %s (use a URL like http://proxy.example.com:3128)", redactURL(c.HTTP.Proxy))
+		}
+	}
+	if c.HTTP.Timeout != "" {
+		if d, err := time.ParseDuration(c.HTTP.Timeout); err != nil || d < 0 {
+			return fmt.Errorf("invalid http.timeout: %s (use a duration like 60s)", c.HTTP.Timeout)
+		}
+	}
+
+	// Validate publish settings.
+	if c.Publish.StatusProperty == "" {
+		c.Publish.StatusProperty = "Status"
+	}
+	if c.Publish.StatusValue == "" {
+		c.Publish.StatusValue = "Published"
+	}
+	if c.Publish.URLKey == "" {
+		c.Publish.URLKey = "public_url"
+	}
+	if c.Publish.Webhook != "" {
+		u, err := url.Parse(c.Publish.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid publish.webhook: %s (use an http or https URL)", redactURL(c.Publish.Webhook))
+		}
+	}
+
+	// Validate link settings.
+	for _, setting := range []struct{ name, value string }{
+		{"links.stub_folder", c.Links.StubFolder},
+		{"links.stub_template", c.Links.StubTemplate},
+	} {
+		dir := filepath.Clean(filepath.FromSlash(setting.value))
+		if filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid %s: %s (use a path inside the vault)", setting.name, setting.value)
+		}
+	}
+
+	if c.Template.Marker == "" {
+		c.Template.Marker = "{{content}}"
+	}
+
+	// Validate attachment upload settings.
+	if c.Attachments.Concurrency < 0 {
+		return fmt.Errorf("attachments.concurrency must be non-negative")
+	}
+	if c.Attachments.Concurrency == 0 {
+		c.Attachments.Concurrency = DefaultAttachmentConcurrency
+	}
+	if c.Attachments.MaxRPS < 0 {
+		return fmt.Errorf("attachments.max_rps must be non-negative")
+	}
+	if c.Attachments.MaxRPS == 0 {
+		c.Attachments.MaxRPS = DefaultAttachmentRequestsPerSecond
+	}
 
 	// Validate property mappings in folder mappings.
 	for i, mapping := range c.Mappings {
@@ -343,12 +1516,62 @@ func (c *Config) Validate() error {
 		if mapping.Database == "" {
 			return fmt.Errorf("mappings[%d].database is required", i)
 		}
+		if mapping.Credential != "" {
+			if _, ok := c.Notion.Credentials[mapping.Credential]; !ok {
+				return fmt.Errorf("mappings[%d].credential: unknown credential %q (define it under notion.credentials)", i, mapping.Credential)
+			}
+		}
 		prefix := fmt.Sprintf("mappings[%d].properties", i)
 		if err := validatePropertyMappings(mapping.Properties, prefix); err != nil {
 			return err
 		}
 	}
 
+	// Validate targets.
+	for name, target := range c.Targets {
+		if target.Database == "" {
+			return fmt.Errorf("targets.%s.database is required", name)
+		}
+		if err := validatePropertyMappings(target.Properties, "targets."+name+".properties"); err != nil {
+			return err
+		}
+	}
+
+	// Validate compositions.
+	for i, comp := range c.Compositions {
+		if comp.Path == "" {
+			return fmt.Errorf("compositions[%d].path is required", i)
+		}
+		if _, err := filepath.Match(comp.Path, ""); err != nil {
+			return fmt.Errorf("compositions[%d].path: %w", i, err)
+		}
+		if comp.Page == "" {
+			return fmt.Errorf("compositions[%d].page is required", i)
+		}
+		if comp.Order != "" && comp.Order != "date" && comp.Order != "name" {
+			return fmt.Errorf("invalid compositions[%d].order: %s (must be date or name)", i, comp.Order)
+		}
+	}
+
+	return nil
+}
+
+// validateWatchAPI checks that the watch API listens on a loopback address
+// and has a token. A disabled API is always valid.
+func validateWatchAPI(a WatchAPIConfig) error {
+	if a.Listen == "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(a.Listen)
+	if err != nil {
+		return fmt.Errorf("invalid watch.api.listen: %w", err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("invalid watch.api.listen: %s (must be a loopback address)", a.Listen)
+	}
+	if a.Token == "" {
+		return fmt.Errorf("watch.api.token is required when watch.api.listen is set")
+	}
 	return nil
 }
 
@@ -357,7 +1580,7 @@ func (c *Config) Validate() error {
 func validatePropertyMappings(mappings []PropertyMappingConfig, prefix string) error {
 	validTypes := map[string]bool{
 		"title": true, "rich_text": true, "number": true, "select": true,
-		"multi_select": true, "date": true, "checkbox": true, "url": true,
+		"status": true, "multi_select": true, "date": true, "checkbox": true, "url": true,
 		"email": true, "phone_number": true,
 	}
 
@@ -369,7 +1592,7 @@ func validatePropertyMappings(mappings []PropertyMappingConfig, prefix string) e
 			return fmt.Errorf("%s[%d].notion is required", prefix, i)
 		}
 		if prop.Type != "" && !validTypes[prop.Type] {
-			return fmt.Errorf("%s[%d].type is invalid: %s (valid types: title, rich_text, number, select, multi_select, date, checkbox, url, email, phone_number)", prefix, i, prop.Type)
+			return fmt.Errorf("%s[%d].type is invalid: %s (valid types: title, rich_text, number, select, status, multi_select, date, checkbox, url, email, phone_number)", prefix, i, prop.Type)
 		}
 	}
 	return nil
@@ -377,7 +1600,10 @@ func validatePropertyMappings(mappings []PropertyMappingConfig, prefix string) e
 
 // Save writes the configuration to a file.
 func (c *Config) Save(path string) error {
-	data, err := yaml.Marshal(c)
+	// A config scoped to sync.root is saved with the whole vault.
+	saved := *c
+	saved.Vault = c.VaultRoot()
+	data, err := yaml.Marshal(&saved)
 	if err != nil {
 		return fmt.Errorf("marshal config: %w", err)
 	}
@@ -406,6 +1632,47 @@ func (c *Config) GetMapping(path string) *FolderMapping {
 	return nil
 }
 
+// TemplateForPath returns the template page new pages for a path are
+// created from: its folder mapping's, or template.page.
+func (c *Config) TemplateForPath(path string) string {
+	if mapping := c.GetMapping(path); mapping != nil && mapping.Template != "" {
+		return mapping.Template
+	}
+	return c.Template.Page
+}
+
+// Mirror reports whether the vault is mirrored to Notion (mode: mirror).
+func (c *Config) Mirror() bool {
+	return c.Mode == ModeMirror
+}
+
+// DirectionForPath returns the sync direction allowed for a path:
+// DirectionPush, DirectionPull, or DirectionBoth. Every path of a mirror
+// is push-only.
+func (c *Config) DirectionForPath(path string) string {
+	if c.Mirror() {
+		return DirectionPush
+	}
+	for _, p := range c.Sync.Directions {
+		if p.matches(path) {
+			return p.Direction
+		}
+	}
+	return DirectionBoth
+}
+
+// GetComposition returns the composition rule that matches the given path,
+// or nil if the note syncs to its own page.
+func (c *Config) GetComposition(path string) *Composition {
+	for i := range c.Compositions {
+		matched, _ := filepath.Match(c.Compositions[i].Path, path)
+		if matched {
+			return &c.Compositions[i]
+		}
+	}
+	return nil
+}
+
 // GetDatabaseForPath returns the database ID for a given path.
 func (c *Config) GetDatabaseForPath(path string) string {
 	mapping := c.GetMapping(path)
@@ -415,6 +1682,152 @@ func (c *Config) GetDatabaseForPath(path string) string {
 	return c.Notion.DefaultDatabase
 }
 
+// TokenForCredential returns the token for a named credential.
+// An empty or unknown name falls back to notion.token.
+func (c *Config) TokenForCredential(name string) string {
+	if token, ok := c.Notion.Credentials[name]; ok && name != "" {
+		return token
+	}
+	return c.Notion.Token
+}
+
+// CredentialForPath returns the credential name bound to a path.
+// An empty name means the default notion.token.
+func (c *Config) CredentialForPath(path string) string {
+	mapping := c.GetMapping(path)
+	if mapping != nil {
+		return mapping.Credential
+	}
+	return ""
+}
+
+// TokenForPath returns the integration token for a given path.
+func (c *Config) TokenForPath(path string) string {
+	return c.TokenForCredential(c.CredentialForPath(path))
+}
+
+// TokenForDatabase returns the integration token for a database ID.
+// The credential is taken from the first mapping targeting the database.
+func (c *Config) TokenForDatabase(databaseID string) string {
+	for _, mapping := range c.Mappings {
+		if mapping.Database == databaseID {
+			return c.TokenForCredential(mapping.Credential)
+		}
+	}
+	return c.Notion.Token
+}
+
+// BucketForPath returns the rate limit bucket of a path: its database
+// with rate_limit.buckets set to database, or "" for its token's.
+func (c *Config) BucketForPath(path string) string {
+	if c.RateLimit.Buckets != BucketsDatabase {
+		return ""
+	}
+	return c.GetDatabaseForPath(path)
+}
+
+// BucketForDatabase returns the rate limit bucket of a database ID: the
+// database with rate_limit.buckets set to database, or "" for its token's.
+func (c *Config) BucketForDatabase(databaseID string) string {
+	if c.RateLimit.Buckets != BucketsDatabase {
+		return ""
+	}
+	return databaseID
+}
+
+// BucketRate returns the requests per second of a database's rate limit
+// bucket: the requests_per_second of its mapping or, without one, an
+// equal share of what the other databases of its integration leave. It
+// returns 0, its token's full rate, for the token's shared bucket.
+func (c *Config) BucketRate(bucket string) float64 {
+	if bucket == "" || c.RateLimit.Buckets != BucketsDatabase {
+		return 0
+	}
+	rates := c.databaseRates(c.TokenForDatabase(bucket))
+	if rate := rates[bucket]; rate > 0 {
+		return rate
+	}
+	left := c.RateLimit.RequestsPerSecond
+	shares := 0
+	if _, ok := rates[bucket]; !ok {
+		shares++ // A database the config does not name
+	}
+	for _, rate := range rates {
+		left -= rate
+		if rate == 0 {
+			shares++
+		}
+	}
+	if left <= 0 {
+		return 0
+	}
+	return left / float64(shares)
+}
+
+// databaseRates returns the requests_per_second of the databases of an
+// integration, 0 for those that share the rest. A database's rate is
+// taken from the first mapping targeting it, as its credential is.
+func (c *Config) databaseRates(token string) map[string]float64 {
+	rates := make(map[string]float64)
+	for _, mapping := range c.Mappings {
+		if mapping.Database == "" || c.TokenForDatabase(mapping.Database) != token {
+			continue
+		}
+		if _, ok := rates[mapping.Database]; !ok {
+			rates[mapping.Database] = mapping.RequestsPerSecond
+		}
+	}
+	if db := c.Notion.DefaultDatabase; db != "" && c.TokenForDatabase(db) == token {
+		if _, ok := rates[db]; !ok {
+			rates[db] = 0
+		}
+	}
+	return rates
+}
+
+// validateBucketRates checks that the databases of each integration get no
+// more than its rate_limit.requests_per_second together, and that every
+// database bucket gets some of it.
+func (c *Config) validateBucketRates() error {
+	for _, mapping := range c.Mappings {
+		if mapping.RequestsPerSecond < 0 {
+			return fmt.Errorf("mapping %s: requests_per_second must be non-negative", mapping.Path)
+		}
+		if mapping.RequestsPerSecond > 0 && c.RateLimit.Buckets != BucketsDatabase {
+			return fmt.Errorf("mapping %s: requests_per_second requires rate_limit.buckets: database", mapping.Path)
+		}
+	}
+	if c.RateLimit.Buckets != BucketsDatabase || c.RateLimit.RequestsPerSecond <= 0 {
+		return nil
+	}
+
+	checked := make(map[string]bool)
+	for _, mapping := range c.Mappings {
+		token := c.TokenForDatabase(mapping.Database)
+		if mapping.Database == "" || checked[token] {
+			continue
+		}
+		checked[token] = true
+		var total float64
+		shared := false
+		for _, rate := range c.databaseRates(token) {
+			total += rate
+			shared = shared || rate == 0
+		}
+		credential := mapping.Credential
+		if credential == "" {
+			credential = "notion.token"
+		}
+		if total > c.RateLimit.RequestsPerSecond {
+			return fmt.Errorf("the requests_per_second of the databases of %s add up to %g, over rate_limit.requests_per_second (%g)", credential, total, c.RateLimit.RequestsPerSecond)
+		}
+		if shared && total >= c.RateLimit.RequestsPerSecond {
+			return fmt.Errorf("the requests_per_second of the databases of %s leave no rate for its databases without one", credential)
+		}
+	}
+	return nil
+}
+
 // GetPropertyMappingsForPath returns the property mappings for a given path.
 // It merges global transform.property_mappings with folder-specific mappings.
 // Folder-specific mappings override global mappings for the same Obsidian key.
@@ -449,3 +1862,12 @@ func (c *Config) GetPropertyMappingsForPath(path string) []PropertyMappingConfig
 
 	return result
 }
+
+// redactURL hides the password of a URL, so errors do not show it.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "(unparsable URL)"
+	}
+	return u.Redacted()
+}
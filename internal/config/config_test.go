@@ -3,7 +3,11 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -17,6 +21,14 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("expected BatchSize=%d, got %d", DefaultBatchSize, cfg.RateLimit.BatchSize)
 	}
 
+	if cfg.RateLimit.PageSize != DefaultPageSize {
+		t.Errorf("expected PageSize=%d, got %d", DefaultPageSize, cfg.RateLimit.PageSize)
+	}
+
+	if cfg.Attachments.Concurrency != DefaultAttachmentConcurrency || cfg.Attachments.MaxRPS != DefaultAttachmentRequestsPerSecond {
+		t.Errorf("expected attachments concurrency=%d, max_rps=%f, got %+v", DefaultAttachmentConcurrency, DefaultAttachmentRequestsPerSecond, cfg.Attachments)
+	}
+
 	if cfg.Sync.ConflictStrategy != "manual" {
 		t.Errorf("expected ConflictStrategy=manual, got %s", cfg.Sync.ConflictStrategy)
 	}
@@ -269,6 +281,61 @@ func TestValidate(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			name: "invalid rate limit buckets",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+					Buckets:           "page",
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid rate_limit.buckets: page (must be token or database)",
+		},
+		{
+			name: "database rates over the token's rate",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token: "token123",
+				},
+				Mappings: []FolderMapping{
+					{Path: "work/*", Database: "workdb", RequestsPerSecond: 2},
+					{Path: "home/*", Database: "homedb", RequestsPerSecond: 2},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+					Buckets:           BucketsDatabase,
+				},
+			},
+			expectErr: true,
+			errMsg:    "the requests_per_second of the databases of notion.token add up to 4, over rate_limit.requests_per_second (3)",
+		},
+		{
+			name: "database rates without database buckets",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token: "token123",
+				},
+				Mappings: []FolderMapping{
+					{Path: "work/*", Database: "workdb", RequestsPerSecond: 1},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "mapping work/*: requests_per_second requires rate_limit.buckets: database",
+		},
 		{
 			name: "missing vault",
 			config: &Config{
@@ -333,6 +400,82 @@ func TestValidate(t *testing.T) {
 			expectErr: true,
 			errMsg:    "invalid conflict_strategy",
 		},
+		{
+			name: "invalid split_on transform",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Transform: TransformConfig{
+					SplitOn: "h2",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid split_on transform",
+		},
+		{
+			name: "composition without page",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Compositions: []Composition{
+					{Path: "meetings/*.md"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "compositions[0].page is required",
+		},
+		{
+			name: "invalid link repair mode",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Sync: SyncConfig{
+					LinkRepair: "sometimes",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid link_repair",
+		},
+		{
+			name: "smtp notify without recipients",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Notify: NotifyConfig{
+					SMTP: SMTPConfig{Host: "mail.example.com", From: "sync@example.com"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "notify.smtp.to is required",
+		},
 		{
 			name: "invalid dataview transform",
 			config: &Config{
@@ -353,7 +496,7 @@ func TestValidate(t *testing.T) {
 			errMsg:    "invalid dataview transform",
 		},
 		{
-			name: "invalid unresolved_links transform",
+			name: "invalid html transform",
 			config: &Config{
 				Vault: tmpVault,
 				Notion: NotionConfig{
@@ -361,7 +504,7 @@ func TestValidate(t *testing.T) {
 					DefaultDatabase: "db123",
 				},
 				Transform: TransformConfig{
-					UnresolvedLinks: "invalid",
+					HTML: "render",
 				},
 				RateLimit: RateLimitConfig{
 					RequestsPerSecond: 3,
@@ -369,49 +512,56 @@ func TestValidate(t *testing.T) {
 				},
 			},
 			expectErr: true,
-			errMsg:    "invalid unresolved_links transform",
+			errMsg:    "invalid html transform",
 		},
 		{
-			name: "negative rate limit",
+			name: "invalid nested_tags transform",
 			config: &Config{
 				Vault: tmpVault,
 				Notion: NotionConfig{
 					Token:           "token123",
 					DefaultDatabase: "db123",
 				},
+				Transform: TransformConfig{
+					NestedTags: "deep",
+				},
 				RateLimit: RateLimitConfig{
-					RequestsPerSecond: -1,
+					RequestsPerSecond: 3,
 					BatchSize:         100,
 				},
 			},
 			expectErr: true,
-			errMsg:    "rate_limit.requests_per_second must be non-negative",
+			errMsg:    "invalid nested_tags transform",
 		},
 		{
-			name: "batch size too large",
+			name: "invalid tags spaces",
 			config: &Config{
 				Vault: tmpVault,
 				Notion: NotionConfig{
 					Token:           "token123",
 					DefaultDatabase: "db123",
 				},
+				Transform: TransformConfig{
+					Tags: TagsConfig{Spaces: "dash"},
+				},
 				RateLimit: RateLimitConfig{
 					RequestsPerSecond: 3,
-					BatchSize:         200,
+					BatchSize:         100,
 				},
 			},
 			expectErr: true,
-			errMsg:    "rate_limit.batch_size must not exceed",
+			errMsg:    "invalid tags spaces",
 		},
 		{
-			name: "mapping missing path",
+			name: "invalid dividers transform",
 			config: &Config{
 				Vault: tmpVault,
 				Notion: NotionConfig{
-					Token: "token123",
+					Token:           "token123",
+					DefaultDatabase: "db123",
 				},
-				Mappings: []FolderMapping{
-					{Database: "db123"},
+				Transform: TransformConfig{
+					Dividers: "===",
 				},
 				RateLimit: RateLimitConfig{
 					RequestsPerSecond: 3,
@@ -419,17 +569,18 @@ func TestValidate(t *testing.T) {
 				},
 			},
 			expectErr: true,
-			errMsg:    "mappings[0].path is required",
+			errMsg:    "invalid dividers transform",
 		},
 		{
-			name: "mapping missing database",
+			name: "invalid empty_paragraphs transform",
 			config: &Config{
 				Vault: tmpVault,
 				Notion: NotionConfig{
-					Token: "token123",
+					Token:           "token123",
+					DefaultDatabase: "db123",
 				},
-				Mappings: []FolderMapping{
-					{Path: "work/*"},
+				Transform: TransformConfig{
+					EmptyParagraphs: "drop",
 				},
 				RateLimit: RateLimitConfig{
 					RequestsPerSecond: 3,
@@ -437,23 +588,18 @@ func TestValidate(t *testing.T) {
 				},
 			},
 			expectErr: true,
-			errMsg:    "mappings[0].database is required",
+			errMsg:    "invalid empty_paragraphs transform",
 		},
 		{
-			name: "property missing obsidian field",
+			name: "invalid task_states key",
 			config: &Config{
 				Vault: tmpVault,
 				Notion: NotionConfig{
-					Token: "token123",
+					Token:           "token123",
+					DefaultDatabase: "db123",
 				},
-				Mappings: []FolderMapping{
-					{
-						Path:     "work/*",
-						Database: "db123",
-						Properties: []PropertyMappingConfig{
-							{Notion: "Status", Type: "select"},
-						},
-					},
+				Transform: TransformConfig{
+					TaskStates: map[string]string{"x": "checked"},
 				},
 				RateLimit: RateLimitConfig{
 					RequestsPerSecond: 3,
@@ -461,47 +607,69 @@ func TestValidate(t *testing.T) {
 				},
 			},
 			expectErr: true,
-			errMsg:    "mappings[0].properties[0].obsidian is required",
+			errMsg:    "invalid task_states key",
 		},
 		{
-			name: "property missing notion field",
+			name: "invalid http proxy",
 			config: &Config{
 				Vault: tmpVault,
 				Notion: NotionConfig{
-					Token: "token123",
+					Token:           "token123",
+					DefaultDatabase: "db123",
 				},
-				Mappings: []FolderMapping{
-					{
-						Path:     "work/*",
-						Database: "db123",
-						Properties: []PropertyMappingConfig{
-							{Obsidian: "status", Type: "select"},
-						},
-					},
+				HTTP: HTTPConfig{Proxy: "proxy.example.com:3128"},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid http.proxy",
+		},
+		{
+			name: "invalid http timeout",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
 				},
+				HTTP: HTTPConfig{Timeout: "soon"},
 				RateLimit: RateLimitConfig{
 					RequestsPerSecond: 3,
 					BatchSize:         100,
 				},
 			},
 			expectErr: true,
-			errMsg:    "mappings[0].properties[0].notion is required",
+			errMsg:    "invalid http.timeout",
 		},
 		{
-			name: "property invalid type",
+			name: "invalid publish webhook",
 			config: &Config{
 				Vault: tmpVault,
 				Notion: NotionConfig{
-					Token: "token123",
+					Token:           "token123",
+					DefaultDatabase: "db123",
 				},
-				Mappings: []FolderMapping{
-					{
-						Path:     "work/*",
-						Database: "db123",
-						Properties: []PropertyMappingConfig{
-							{Obsidian: "status", Notion: "Status", Type: "invalid_type"},
-						},
-					},
+				Publish: PublishConfig{Webhook: "hooks.example.com/publish"},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid publish.webhook",
+		},
+		{
+			name: "invalid transform icons key",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Transform: TransformConfig{
+					Icons: map[string]string{"table": "\U0001F4CA"},
 				},
 				RateLimit: RateLimitConfig{
 					RequestsPerSecond: 3,
@@ -509,40 +677,802 @@ func TestValidate(t *testing.T) {
 				},
 			},
 			expectErr: true,
-			errMsg:    "mappings[0].properties[0].type is invalid",
+			errMsg:    "invalid transform.icons key",
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := tt.config.Validate()
-			if tt.expectErr {
-				if err == nil {
-					t.Errorf("expected error containing %q, got nil", tt.errMsg)
-				} else if tt.errMsg != "" && !contains(err.Error(), tt.errMsg) {
-					t.Errorf("expected error containing %q, got %q", tt.errMsg, err.Error())
-				}
-			} else {
-				if err != nil {
-					t.Errorf("unexpected error: %v", err)
-				}
-			}
-		})
-	}
-}
-
-func TestGetMapping(t *testing.T) {
-	cfg := &Config{
-		Mappings: []FolderMapping{
-			{Path: "work/*", Database: "workdb"},
-			{Path: "personal/*", Database: "personaldb"},
+		{
+			name: "invalid transform plugin_blocks key",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Transform: TransformConfig{
+					PluginBlocks: map[string]bool{"kanban": true, "excalidraw": false},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid transform.plugin_blocks key",
 		},
-	}
-
-	tests := []struct {
-		path         string
-		expectedDB   string
-		expectNil    bool
+		{
+			name: "stub folder outside vault",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Links: LinksConfig{AutoCreateStubs: true, StubFolder: "../stubs"},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid links.stub_folder",
+		},
+		{
+			name: "garbled callout icon",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Transform: TransformConfig{
+					Callouts: map[string]string{"chart": "\u00FC\u00F1\u00BA\u00D4\u220F\u00E8"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "looks garbled",
+		},
+		{
+			name: "invalid pull wrap",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Pull: PullConfig{
+					Wrap: "wide",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid pull wrap",
+		},
+		{
+			name: "invalid filenames unicode",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Sync: SyncConfig{
+					Filenames: FilenamesConfig{Unicode: "nfkc"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid filenames unicode",
+		},
+		{
+			name: "invalid filenames emoji",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Sync: SyncConfig{
+					Filenames: FilenamesConfig{Emoji: "drop"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid filenames emoji",
+		},
+		{
+			name: "pull layout outside the vault",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Sync: SyncConfig{
+					PullLayout: "../{{Area}}/{{title}}.md",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid sync.pull_layout",
+		},
+		{
+			name: "invalid dates format",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Transform: TransformConfig{
+					Dates: DatesConfig{Format: "DD.MM.YYYY"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid dates format",
+		},
+		{
+			name: "invalid dates timezone",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Transform: TransformConfig{
+					Dates: DatesConfig{Timezone: "Mars/Olympus"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid dates timezone",
+		},
+		{
+			name: "valid dates format",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Transform: TransformConfig{
+					Dates: DatesConfig{Format: "02.01.2006", DateTimeFormat: "02.01.2006 15:04", Timezone: "UTC"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "target without database",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Targets: map[string]TargetConfig{
+					"tasks": {Properties: []PropertyMappingConfig{{Obsidian: "status", Notion: "Status", Type: "select"}}},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "targets.tasks.database is required",
+		},
+		{
+			name: "invalid api_version",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+					APIVersion:      "latest",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid notion.api_version",
+		},
+		{
+			name: "invalid max_deletions_per_run",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Sync: SyncConfig{
+					MaxDeletionsPerRun: -5,
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid sync.max_deletions_per_run",
+		},
+		{
+			name: "watch api without token",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Watch: WatchConfig{
+					API: WatchAPIConfig{Listen: "127.0.0.1:27125"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "watch.api.token is required",
+		},
+		{
+			name: "watch api on non-loopback address",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Watch: WatchConfig{
+					API: WatchAPIConfig{Listen: "0.0.0.0:27125", Token: "secret"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "must be a loopback address",
+		},
+		{
+			name: "valid watch api",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Watch: WatchConfig{
+					API: WatchAPIConfig{Listen: "localhost:27125", Token: "secret"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid max_attachment_size",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Sync: SyncConfig{
+					MaxAttachmentSize: "huge",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid max_attachment_size",
+		},
+		{
+			name: "invalid undo_retention",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Sync: SyncConfig{
+					UndoRetention: "a week",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid undo_retention",
+		},
+		{
+			name: "invalid file_timeout",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Sync: SyncConfig{
+					FileTimeout: "-5m",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid file_timeout",
+		},
+		{
+			name: "dates transform with the same property twice",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Transform: TransformConfig{
+					Dates: DatesConfig{Created: "Date", Modified: "Date"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid dates transform",
+		},
+		{
+			name: "invalid sync direction",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Sync: SyncConfig{
+					Directions: []DirectionPolicy{{Path: "templates/", Direction: "sideways"}},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid sync.directions direction",
+		},
+		{
+			name: "properties.map without a notion name",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Properties: PropertiesConfig{
+					Map: map[string]PropertyRename{"status": {Name: ""}},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "properties.map",
+		},
+		{
+			name: "invalid properties.unmapped",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Properties: PropertiesConfig{Unmapped: "everything"},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid properties.unmapped",
+		},
+		{
+			name: "invalid text_colors transform",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Transform: TransformConfig{
+					TextColors: "rainbow",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid text_colors transform",
+		},
+		{
+			name: "user name with @",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Transform: TransformConfig{
+					Users: map[string]string{"@Ada": "user-1"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid transform.users name",
+		},
+		{
+			name: "user without ID",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Transform: TransformConfig{
+					Users: map[string]string{"Ada": ""},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "transform.users: no user ID for Ada",
+		},
+		{
+			name: "invalid date_mentions transform",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Transform: TransformConfig{
+					DateMentions: "calendar",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid date_mentions transform",
+		},
+		{
+			name: "invalid unresolved_links transform",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Transform: TransformConfig{
+					UnresolvedLinks: "invalid",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "invalid unresolved_links transform",
+		},
+		{
+			name: "negative rate limit",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: -1,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "rate_limit.requests_per_second must be non-negative",
+		},
+		{
+			name: "batch size too large",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         200,
+				},
+			},
+			expectErr: true,
+			errMsg:    "rate_limit.batch_size must not exceed",
+		},
+		{
+			name: "negative attachment concurrency",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Attachments: AttachmentsConfig{Concurrency: -1},
+			},
+			expectErr: true,
+			errMsg:    "attachments.concurrency must be non-negative",
+		},
+		{
+			name: "negative attachment rate limit",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				Attachments: AttachmentsConfig{MaxRPS: -1},
+			},
+			expectErr: true,
+			errMsg:    "attachments.max_rps must be non-negative",
+		},
+		{
+			name: "page size too large",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:           "token123",
+					DefaultDatabase: "db123",
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+					PageSize:          500,
+				},
+			},
+			expectErr: true,
+			errMsg:    "rate_limit.page_size must not exceed",
+		},
+		{
+			name: "mapping missing path",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token: "token123",
+				},
+				Mappings: []FolderMapping{
+					{Database: "db123"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "mappings[0].path is required",
+		},
+		{
+			name: "mapping missing database",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token: "token123",
+				},
+				Mappings: []FolderMapping{
+					{Path: "work/*"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "mappings[0].database is required",
+		},
+		{
+			name: "property missing obsidian field",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token: "token123",
+				},
+				Mappings: []FolderMapping{
+					{
+						Path:     "work/*",
+						Database: "db123",
+						Properties: []PropertyMappingConfig{
+							{Notion: "Status", Type: "select"},
+						},
+					},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "mappings[0].properties[0].obsidian is required",
+		},
+		{
+			name: "property missing notion field",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token: "token123",
+				},
+				Mappings: []FolderMapping{
+					{
+						Path:     "work/*",
+						Database: "db123",
+						Properties: []PropertyMappingConfig{
+							{Obsidian: "status", Type: "select"},
+						},
+					},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "mappings[0].properties[0].notion is required",
+		},
+		{
+			name: "property invalid type",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token: "token123",
+				},
+				Mappings: []FolderMapping{
+					{
+						Path:     "work/*",
+						Database: "db123",
+						Properties: []PropertyMappingConfig{
+							{Obsidian: "status", Notion: "Status", Type: "invalid_type"},
+						},
+					},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "mappings[0].properties[0].type is invalid",
+		},
+		{
+			name: "mapping with unknown credential",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token: "token123",
+				},
+				Mappings: []FolderMapping{
+					{Path: "work/*", Database: "db123", Credential: "work"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "mappings[0].credential: unknown credential",
+		},
+		{
+			name: "empty credential token",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:       "token123",
+					Credentials: map[string]string{"work": ""},
+				},
+				Mappings: []FolderMapping{
+					{Path: "work/*", Database: "db123", Credential: "work"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: true,
+			errMsg:    "notion.credentials.work is empty",
+		},
+		{
+			name: "mapping with known credential",
+			config: &Config{
+				Vault: tmpVault,
+				Notion: NotionConfig{
+					Token:       "token123",
+					Credentials: map[string]string{"work": "work_token"},
+				},
+				Mappings: []FolderMapping{
+					{Path: "work/*", Database: "db123", Credential: "work"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerSecond: 3,
+					BatchSize:         100,
+				},
+			},
+			expectErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error containing %q, got nil", tt.errMsg)
+				} else if tt.errMsg != "" && !contains(err.Error(), tt.errMsg) {
+					t.Errorf("expected error containing %q, got %q", tt.errMsg, err.Error())
+				}
+			} else {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetMapping(t *testing.T) {
+	cfg := &Config{
+		Mappings: []FolderMapping{
+			{Path: "work/*", Database: "workdb"},
+			{Path: "personal/*", Database: "personaldb"},
+		},
+	}
+
+	tests := []struct {
+		path         string
+		expectedDB   string
+		expectNil    bool
 	}{
 		{path: "work/notes.md", expectedDB: "workdb", expectNil: false},
 		{path: "personal/diary.md", expectedDB: "personaldb", expectNil: false},
@@ -567,6 +1497,203 @@ func TestGetMapping(t *testing.T) {
 	}
 }
 
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"1024", 1024, false},
+		{"512KB", 512 << 10, false},
+		{"5MB", 5 << 20, false},
+		{"1.5gb", 3 << 29, false},
+		{"20 MB", 20 << 20, false},
+		{"0", 0, false},
+		{"huge", 0, true},
+		{"-1MB", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	// Unset limits use the defaults; "0" disables them.
+	sync := SyncConfig{MaxNoteSize: "0"}
+	if sync.NoteSizeLimit() != 0 || sync.AttachmentSizeLimit() != DefaultMaxAttachmentSize {
+		t.Errorf("limits = %d, %d", sync.NoteSizeLimit(), sync.AttachmentSizeLimit())
+	}
+	if sync.StreamThreshold() != DefaultStreamNoteSize {
+		t.Errorf("StreamThreshold() = %d, want the default", sync.StreamThreshold())
+	}
+}
+
+func TestPullWrapWidth(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"off", 0},
+		{"preserve", 0},
+		{"80", 80},
+	}
+	for _, tt := range tests {
+		if got := (PullConfig{Wrap: tt.in}).WrapWidth(); got != tt.want {
+			t.Errorf("WrapWidth(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUndoRetentionPeriod(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", DefaultUndoRetention},
+		{"72h", 72 * time.Hour},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		sync := SyncConfig{UndoRetention: tt.in}
+		if got := sync.UndoRetentionPeriod(); got != tt.want {
+			t.Errorf("UndoRetentionPeriod(%q) = %s; want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFileTimeoutPeriod(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", DefaultFileTimeout},
+		{"90s", 90 * time.Second},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		sync := SyncConfig{FileTimeout: tt.in}
+		if got := sync.FileTimeoutPeriod(); got != tt.want {
+			t.Errorf("FileTimeoutPeriod(%q) = %s; want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGetComposition(t *testing.T) {
+	cfg := &Config{
+		Compositions: []Composition{
+			{Path: "project/meeting-notes/*.md", Page: "meetings"},
+		},
+	}
+
+	if comp := cfg.GetComposition("project/meeting-notes/2024-01-15.md"); comp == nil || comp.Page != "meetings" {
+		t.Errorf("GetComposition() = %+v, want page meetings", comp)
+	}
+	if comp := cfg.GetComposition("project/other.md"); comp != nil {
+		t.Errorf("GetComposition() = %+v, want nil", comp)
+	}
+}
+
+func TestPropertiesMap(t *testing.T) {
+	content := `
+map:
+  status: Workflow State
+  due:
+    name: Due Date
+    type: date
+unmapped: passthrough
+`
+	var props PropertiesConfig
+	if err := yaml.Unmarshal([]byte(content), &props); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if props.Unmapped != UnmappedPassthrough {
+		t.Errorf("Unmapped = %q, want %q", props.Unmapped, UnmappedPassthrough)
+	}
+
+	want := []PropertyMappingConfig{
+		{Obsidian: "due", Notion: "Due Date", Type: "date"},
+		{Obsidian: "status", Notion: "Workflow State"},
+	}
+	got := props.Renames()
+	if len(got) != len(want) {
+		t.Fatalf("Renames() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Renames()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	out, err := yaml.Marshal(props)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(out), "status: Workflow State") {
+		t.Errorf("Marshal() = %q, want the untyped rename as a plain name", out)
+	}
+}
+
+func TestDirectionForPath(t *testing.T) {
+	cfg := &Config{
+		Sync: SyncConfig{
+			Directions: []DirectionPolicy{
+				{Path: "templates/", Direction: DirectionPush},
+				{Path: "clippings/**", Direction: DirectionPull},
+				{Path: "journal/*.md", Direction: DirectionPull},
+			},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"templates/daily.md", DirectionPush},
+		{"templates/nested/weekly.md", DirectionPush},
+		{"clippings/web/article.md", DirectionPull},
+		{"journal/2024-01-01.md", DirectionPull},
+		{"journal/archive/2023.md", DirectionBoth},
+		{"templates.md", DirectionBoth},
+		{"notes/idea.md", DirectionBoth},
+	}
+	for _, tt := range tests {
+		if got := cfg.DirectionForPath(tt.path); got != tt.want {
+			t.Errorf("DirectionForPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMirrorMode(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Vault = t.TempDir()
+	cfg.Notion.Token = "token123"
+	cfg.Notion.DefaultDatabase = "db123"
+	cfg.Mode = ModeMirror
+	cfg.Sync.Directions = []DirectionPolicy{{Path: "clippings/**", Direction: DirectionPull}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	if !cfg.Mirror() {
+		t.Error("Mirror() = false")
+	}
+	if cfg.Sync.DeletionStrategy != "ignore" {
+		t.Errorf("DeletionStrategy = %q, want ignore", cfg.Sync.DeletionStrategy)
+	}
+	for _, path := range []string{"notes/idea.md", "clippings/web/article.md"} {
+		if got := cfg.DirectionForPath(path); got != DirectionPush {
+			t.Errorf("DirectionForPath(%q) = %q, want push", path, got)
+		}
+	}
+
+	cfg.Mode = "backup"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid mode") {
+		t.Errorf("Validate() with mode backup = %v, want invalid mode", err)
+	}
+}
+
 func TestGetDatabaseForPath(t *testing.T) {
 	cfg := &Config{
 		Notion: NotionConfig{
@@ -595,6 +1722,108 @@ func TestGetDatabaseForPath(t *testing.T) {
 	}
 }
 
+func TestTokenResolution(t *testing.T) {
+	cfg := &Config{
+		Notion: NotionConfig{
+			Token:           "default_token",
+			DefaultDatabase: "default_db",
+			Credentials: map[string]string{
+				"work": "work_token",
+			},
+		},
+		Mappings: []FolderMapping{
+			{Path: "work/*", Database: "workdb", Credential: "work"},
+			{Path: "journal/*", Database: "journaldb"},
+		},
+	}
+
+	pathTests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "work/notes.md", expected: "work_token"},
+		{path: "journal/today.md", expected: "default_token"},
+		{path: "other/file.md", expected: "default_token"},
+	}
+	for _, tt := range pathTests {
+		if got := cfg.TokenForPath(tt.path); got != tt.expected {
+			t.Errorf("TokenForPath(%q) = %q, expected %q", tt.path, got, tt.expected)
+		}
+	}
+
+	dbTests := []struct {
+		databaseID string
+		expected   string
+	}{
+		{databaseID: "workdb", expected: "work_token"},
+		{databaseID: "journaldb", expected: "default_token"},
+		{databaseID: "default_db", expected: "default_token"},
+	}
+	for _, tt := range dbTests {
+		if got := cfg.TokenForDatabase(tt.databaseID); got != tt.expected {
+			t.Errorf("TokenForDatabase(%q) = %q, expected %q", tt.databaseID, got, tt.expected)
+		}
+	}
+}
+
+func TestBucketResolution(t *testing.T) {
+	cfg := &Config{
+		Notion:   NotionConfig{Token: "default_token", DefaultDatabase: "default_db"},
+		Mappings: []FolderMapping{{Path: "work/*", Database: "workdb"}},
+	}
+	if got := cfg.BucketForPath("work/notes.md"); got != "" {
+		t.Errorf("BucketForPath() with token buckets = %q, want \"\"", got)
+	}
+
+	cfg.RateLimit.Buckets = BucketsDatabase
+	if got := cfg.BucketForPath("work/notes.md"); got != "workdb" {
+		t.Errorf("BucketForPath(work) = %q, want workdb", got)
+	}
+	if got := cfg.BucketForPath("other/file.md"); got != "default_db" {
+		t.Errorf("BucketForPath(other) = %q, want default_db", got)
+	}
+	if got := cfg.BucketForDatabase("workdb"); got != "workdb" {
+		t.Errorf("BucketForDatabase() = %q, want workdb", got)
+	}
+}
+
+func TestBucketRate(t *testing.T) {
+	cfg := &Config{
+		Notion: NotionConfig{
+			Token:           "default_token",
+			DefaultDatabase: "default_db",
+			Credentials:     map[string]string{"other": "other_token"},
+		},
+		Mappings: []FolderMapping{
+			{Path: "work/*", Database: "workdb", RequestsPerSecond: 1},
+			{Path: "home/*", Database: "homedb"},
+			{Path: "ext/*", Database: "extdb", Credential: "other"},
+		},
+		RateLimit: RateLimitConfig{RequestsPerSecond: 3, Buckets: BucketsDatabase},
+	}
+	tests := []struct {
+		bucket string
+		want   float64
+	}{
+		{"", 0},       // The token's shared bucket
+		{"workdb", 1}, // Its own rate
+		{"homedb", 1}, // Half of what workdb leaves, with default_db
+		{"default_db", 1},
+		{"extdb", 3}, // Alone on its token
+		{"unknown", 2.0 / 3},
+	}
+	for _, tt := range tests {
+		if got := cfg.BucketRate(tt.bucket); got != tt.want {
+			t.Errorf("BucketRate(%q) = %g, want %g", tt.bucket, got, tt.want)
+		}
+	}
+
+	cfg.RateLimit.Buckets = BucketsToken
+	if got := cfg.BucketRate("workdb"); got != 0 {
+		t.Errorf("BucketRate() with token buckets = %g, want 0", got)
+	}
+}
+
 func TestSaveAndLoad(t *testing.T) {
 	// Create a temporary directory for the vault.
 	tmpVault, err := os.MkdirTemp("", "test-vault")
@@ -662,6 +1891,83 @@ func TestSaveAndLoad(t *testing.T) {
 	}
 }
 
+func TestSetRoot(t *testing.T) {
+	vault := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(vault, "Areas", "Public"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vault, "note.md"), nil, 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cfg := &Config{Vault: vault}
+	if err := cfg.SetRoot("Areas/Public/"); err != nil {
+		t.Fatalf("SetRoot() error = %v", err)
+	}
+	if want := filepath.Join(vault, "Areas", "Public"); cfg.Vault != want {
+		t.Errorf("Vault = %q, expected %q", cfg.Vault, want)
+	}
+	if cfg.VaultRoot() != vault {
+		t.Errorf("VaultRoot() = %q, expected %q", cfg.VaultRoot(), vault)
+	}
+	if cfg.Sync.Root != "Areas/Public" {
+		t.Errorf("Sync.Root = %q, expected Areas/Public", cfg.Sync.Root)
+	}
+
+	// A new root is relative to the whole vault, not the current one.
+	if err := cfg.SetRoot("Areas"); err != nil {
+		t.Fatalf("SetRoot(Areas) error = %v", err)
+	}
+	if want := filepath.Join(vault, "Areas"); cfg.Vault != want {
+		t.Errorf("Vault = %q, expected %q", cfg.Vault, want)
+	}
+
+	for _, root := range []string{"../elsewhere", "Areas/../..", "/tmp", "Missing", "note.md"} {
+		if err := cfg.SetRoot(root); err == nil {
+			t.Errorf("SetRoot(%q) expected error", root)
+		}
+	}
+
+	if err := cfg.SetRoot("."); err != nil {
+		t.Fatalf("SetRoot(.) error = %v", err)
+	}
+	if cfg.Vault != vault || cfg.Sync.Root != "" {
+		t.Errorf("SetRoot(.) = %q (root %q), expected whole vault", cfg.Vault, cfg.Sync.Root)
+	}
+}
+
+func TestLoadSyncRoot(t *testing.T) {
+	vault := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(vault, "Public"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := "vault: " + vault + "\nnotion:\n  token: test_token\n  default_database: test_db\nsync:\n  root: Public\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if want := filepath.Join(vault, "Public"); cfg.Vault != want {
+		t.Errorf("Vault = %q, expected %q", cfg.Vault, want)
+	}
+
+	// The config is saved with the whole vault, so it loads the same.
+	if err := cfg.Save(configPath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	reloaded, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() after Save() error = %v", err)
+	}
+	if reloaded.Vault != cfg.Vault || reloaded.VaultRoot() != vault {
+		t.Errorf("reloaded Vault = %q (root %q), expected %q (root %q)", reloaded.Vault, reloaded.VaultRoot(), cfg.Vault, vault)
+	}
+}
+
 func TestLoadNoConfigFile(t *testing.T) {
 	// Save current working directory.
 	cwd, err := os.Getwd()
@@ -746,3 +2052,98 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestSetGetList(t *testing.T) {
+	vault := t.TempDir()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	original := "# My sync setup\nvault: " + vault + "\nnotion:\n  token: ${NOTION_TOKEN} # from the shell\n  default_database: db123\n"
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("NOTION_TOKEN", "secret")
+
+	if err := Set(path, "sync.conflict_strategy", "newer"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if backup, _ := os.ReadFile(path + ".bak"); string(backup) != original {
+		t.Errorf("backup = %q, want the file before the change", backup)
+	}
+	if err := Set(path, "transform.users.Ada", "user-1"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := Set(path, "sync.ignore", "[a, b]"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	for key, want := range map[string]string{
+		"notion.token":           "${NOTION_TOKEN}",
+		"sync.conflict_strategy": "newer",
+		"transform.users.Ada":    "user-1",
+		"sync.ignore":            "[a, b]",
+		"sync.ignore.1":          "b",
+		"transform.dataview":     "placeholder", // Default
+	} {
+		got, err := Get(path, key)
+		if err != nil {
+			t.Errorf("Get(%s) error = %v", key, err)
+		} else if got != want {
+			t.Errorf("Get(%s) = %q, want %q", key, got, want)
+		}
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "# My sync setup") || !strings.Contains(string(data), "# from the shell") {
+		t.Errorf("Set() dropped comments:\n%s", data)
+	}
+
+	settings, err := List(path, false)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var keys []string
+	for _, s := range settings {
+		keys = append(keys, s.Key)
+	}
+	want := []string{"vault", "notion.token", "notion.default_database", "sync.conflict_strategy", "sync.ignore.0", "sync.ignore.1", "transform.users.Ada"}
+	if strings.Join(keys, " ") != strings.Join(want, " ") {
+		t.Errorf("List() keys = %v, want %v", keys, want)
+	}
+
+	// Rejected changes leave the file alone.
+	before, _ := os.ReadFile(path)
+	for key, value := range map[string]string{
+		"sync.no_such_key":       "x",
+		"sync.conflict_strategy": "sometimes",
+		"rate_limit.batch_size":  "many",
+		"mappings.x":             "y",
+	} {
+		if err := Set(path, key, value); err == nil {
+			t.Errorf("Set(%s, %s) succeeded, want error", key, value)
+		}
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Errorf("rejected Set() changed the file:\n%s", after)
+	}
+	if _, err := Get(path, "notion.no_such_key"); err == nil {
+		t.Error("Get() of an unknown key succeeded, want error")
+	}
+}
+
+func TestTemplateForPath(t *testing.T) {
+	cfg := &Config{
+		Template: TemplateConfig{Page: "default-template"},
+		Mappings: []FolderMapping{
+			{Path: "meetings/*", Database: "db-1", Template: "meeting-template"},
+			{Path: "projects/*", Database: "db-2"},
+		},
+	}
+	for path, want := range map[string]string{
+		"meetings/standup.md": "meeting-template",
+		"projects/launch.md":  "default-template",
+		"inbox.md":            "default-template",
+	} {
+		if got := cfg.TemplateForPath(path); got != want {
+			t.Errorf("TemplateForPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
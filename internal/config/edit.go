@@ -0,0 +1,335 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Setting is one value of a configuration, addressed by its dot path such
+// as sync.root or mappings.0.database.
+type Setting struct {
+	Key   string
+	Value string
+}
+
+// Get returns the value of a key in the config file at path, or its
+// default, as YAML. Environment variables are not expanded.
+func Get(path, key string) (string, error) {
+	segments, typ, err := keyType(key)
+	if err != nil {
+		return "", err
+	}
+	doc, err := effectiveNode(path)
+	if err != nil {
+		return "", err
+	}
+	if n := lookupNode(doc, segments); n != nil {
+		return formatNode(n)
+	}
+
+	// Keys of maps and lists that are not set read as empty.
+	var zero yaml.Node
+	if err := zero.Encode(reflect.Zero(typ).Interface()); err != nil {
+		return "", fmt.Errorf("encode %s: %w", key, err)
+	}
+	return formatNode(&zero)
+}
+
+// List returns the settings of the config file at path in the order the
+// file lists them, or every setting including defaults if all is set.
+// Environment variables are not expanded.
+func List(path string, all bool) ([]Setting, error) {
+	var doc *yaml.Node
+	if all {
+		var err error
+		if doc, err = effectiveNode(path); err != nil {
+			return nil, err
+		}
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %w", err)
+		}
+		doc = &yaml.Node{}
+		if err := yaml.Unmarshal(data, doc); err != nil {
+			return nil, fmt.Errorf("parse config file: %w", err)
+		}
+	}
+
+	var settings []Setting
+	err := flattenNode(doc, "", &settings)
+	return settings, err
+}
+
+// Set changes a key in the config file at path to value, which is parsed
+// as YAML, keeping the file's comments. The result must be a valid
+// configuration; the previous file is kept as path.bak.
+func Set(path, key, value string) error {
+	segments, _, err := keyType(key)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse config file: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	newValue := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}
+	if len(parsed.Content) > 0 {
+		newValue = parsed.Content[0]
+	}
+	if err := setNode(doc.Content[0], segments, newValue); err != nil {
+		return fmt.Errorf("set %s: %w", key, err)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+
+	// The result must load as the file would.
+	if _, err := parseConfig(buf.Bytes()); err != nil {
+		return fmt.Errorf("set %s: %w", key, err)
+	}
+
+	if err := os.WriteFile(path+".bak", data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("back up config file: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	return nil
+}
+
+// effectiveNode returns the config file at path, over the defaults, as a
+// YAML node.
+func effectiveNode(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	cfg := DefaultConfig()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return nil, fmt.Errorf("marshal config: %w", err)
+	}
+	return &doc, nil
+}
+
+// keyType splits a dot-path key and returns the type of the setting it
+// names, or an error if Config has no such setting.
+func keyType(key string) ([]string, reflect.Type, error) {
+	segments := strings.Split(key, ".")
+	typ := reflect.TypeOf(Config{})
+	for _, seg := range segments {
+		if seg == "" {
+			return nil, nil, fmt.Errorf("invalid config key: %q", key)
+		}
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		switch typ.Kind() {
+		case reflect.Struct:
+			field, ok := yamlField(typ, seg)
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown config key: %s", key)
+			}
+			typ = field.Type
+		case reflect.Map:
+			typ = typ.Elem()
+		case reflect.Slice:
+			if _, err := strconv.Atoi(seg); err != nil {
+				return nil, nil, fmt.Errorf("invalid config key: %s (%s is a list; use an index)", key, seg)
+			}
+			typ = typ.Elem()
+		default:
+			return nil, nil, fmt.Errorf("unknown config key: %s", key)
+		}
+	}
+	return segments, typ, nil
+}
+
+// yamlField returns the field of a struct type with a yaml name.
+func yamlField(typ reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if tag == "" {
+			tag = strings.ToLower(field.Name)
+		}
+		if tag == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// lookupNode returns the node at a dot path, or nil if it is not set.
+func lookupNode(n *yaml.Node, segments []string) *yaml.Node {
+	if n.Kind == yaml.DocumentNode {
+		if len(n.Content) == 0 {
+			return nil
+		}
+		n = n.Content[0]
+	}
+	for _, seg := range segments {
+		n = childNode(n, seg)
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// childNode returns the value of a mapping key or list index, or nil.
+func childNode(n *yaml.Node, seg string) *yaml.Node {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == seg {
+				return n.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		if i, err := strconv.Atoi(seg); err == nil && i >= 0 && i < len(n.Content) {
+			return n.Content[i]
+		}
+	}
+	return nil
+}
+
+// setNode sets the value at a dot path below n, adding mappings for keys
+// that are not set yet.
+func setNode(n *yaml.Node, segments []string, value *yaml.Node) error {
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		switch n.Kind {
+		case yaml.MappingNode:
+			child := childNode(n, seg)
+			if child == nil {
+				child = &yaml.Node{Kind: yaml.MappingNode}
+				if last {
+					child = value
+				}
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: seg}, child)
+				n = child
+				continue
+			}
+			if last {
+				*child = *keepComments(child, value)
+			}
+			n = child
+		case yaml.SequenceNode:
+			child := childNode(n, seg)
+			if child == nil {
+				return fmt.Errorf("no list item %s", seg)
+			}
+			if last {
+				*child = *keepComments(child, value)
+			}
+			n = child
+		default:
+			if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+				*n = yaml.Node{Kind: yaml.MappingNode}
+				return setNode(n, segments[i:], value)
+			}
+			return fmt.Errorf("%s is not a mapping", strings.Join(segments[:i], "."))
+		}
+	}
+	return nil
+}
+
+// keepComments returns value with the comments of the node it replaces.
+func keepComments(old, value *yaml.Node) *yaml.Node {
+	replaced := *value
+	replaced.HeadComment = old.HeadComment
+	replaced.LineComment = old.LineComment
+	replaced.FootComment = old.FootComment
+	return &replaced
+}
+
+// flattenNode appends the scalar settings below n, and empty lists and
+// mappings, to settings.
+func flattenNode(n *yaml.Node, prefix string, settings *[]Setting) error {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch {
+	case n.Kind == yaml.DocumentNode:
+		for _, c := range n.Content {
+			if err := flattenNode(c, prefix, settings); err != nil {
+				return err
+			}
+		}
+	case n.Kind == yaml.MappingNode && len(n.Content) > 0:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if err := flattenNode(n.Content[i+1], join(n.Content[i].Value), settings); err != nil {
+				return err
+			}
+		}
+	case n.Kind == yaml.SequenceNode && len(n.Content) > 0:
+		for i, c := range n.Content {
+			if err := flattenNode(c, join(strconv.Itoa(i)), settings); err != nil {
+				return err
+			}
+		}
+	default:
+		value, err := formatNode(n)
+		if err != nil {
+			return err
+		}
+		*settings = append(*settings, Setting{Key: prefix, Value: value})
+	}
+	return nil
+}
+
+// formatNode writes a node as it is shown: a scalar as its value, and
+// anything else as flow-style YAML.
+func formatNode(n *yaml.Node) (string, error) {
+	if n.Kind == yaml.ScalarNode {
+		return n.Value, nil
+	}
+	flow := *n
+	flow.Style = yaml.FlowStyle
+	out, err := yaml.Marshal(&flow)
+	if err != nil {
+		return "", fmt.Errorf("marshal config: %w", err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
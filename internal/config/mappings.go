@@ -33,6 +33,8 @@ func toPropertyType(s string) transformer.PropertyType {
 		return transformer.PropertyTypeNumber
 	case "select":
 		return transformer.PropertyTypeSelect
+	case "status":
+		return transformer.PropertyTypeStatus
 	case "multi_select", "tags":
 		return transformer.PropertyTypeMultiSelect
 	case "date":
@@ -0,0 +1,210 @@
+// Package diff computes line-based differences between two texts and
+// formats them as unified diffs, optionally marking the words that changed
+// within lines.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the kind of a single edit.
+type Op int
+
+const (
+	// Equal marks a line present in both texts.
+	Equal Op = iota
+	// Delete marks a line only present in the old text.
+	Delete
+	// Insert marks a line only present in the new text.
+	Insert
+)
+
+// Edit is one line of a diff.
+type Edit struct {
+	Op   Op
+	Line string
+}
+
+// Hunk is a group of nearby edits with surrounding context. Line numbers
+// are 1-based; a hunk with no lines on one side reports the line before it.
+type Hunk struct {
+	FromLine, FromCount int
+	ToLine, ToCount     int
+	Edits               []Edit
+}
+
+// Lines returns the shortest edit script turning a into b, using Myers'
+// algorithm.
+func Lines(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	limit := n + m
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the recorded search frontiers from the end of both texts
+// back to the start, collecting the edits along the way.
+func backtrack(trace [][]int, a, b []string, offset int) []Edit {
+	x, y := len(a), len(b)
+	var edits []Edit
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, Edit{Op: Equal, Line: a[x-1]})
+			x--
+			y--
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			edits = append(edits, Edit{Op: Insert, Line: b[y-1]})
+		} else {
+			edits = append(edits, Edit{Op: Delete, Line: a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// Hunks groups edits into hunks with up to context unchanged lines around
+// each change. Changes separated by at most twice the context share a hunk.
+func Hunks(edits []Edit, context int) []Hunk {
+	// Line numbers consumed on each side before edit i.
+	fromPos := make([]int, len(edits)+1)
+	toPos := make([]int, len(edits)+1)
+	for i, e := range edits {
+		fromPos[i+1], toPos[i+1] = fromPos[i], toPos[i]
+		if e.Op != Insert {
+			fromPos[i+1]++
+		}
+		if e.Op != Delete {
+			toPos[i+1]++
+		}
+	}
+
+	var hunks []Hunk
+	for i := 0; i < len(edits); {
+		if edits[i].Op == Equal {
+			i++
+			continue
+		}
+
+		start := max(0, i-context)
+		end := i
+		for end < len(edits) {
+			if edits[end].Op != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].Op == Equal {
+				run++
+			}
+			if run == len(edits) || run-end > 2*context {
+				end = min(end+context, len(edits))
+				break
+			}
+			end = run
+		}
+
+		h := Hunk{
+			FromLine:  fromPos[start] + 1,
+			FromCount: fromPos[end] - fromPos[start],
+			ToLine:    toPos[start] + 1,
+			ToCount:   toPos[end] - toPos[start],
+			Edits:     edits[start:end],
+		}
+		if h.FromCount == 0 {
+			h.FromLine--
+		}
+		if h.ToCount == 0 {
+			h.ToLine--
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+	return hunks
+}
+
+// Unified returns a unified diff from a to b with the given number of
+// context lines, or an empty string when the texts are equal.
+func Unified(fromName, toName, a, b string, context int) string {
+	hunks := Hunks(Lines(SplitLines(a), SplitLines(b)), context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks {
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(h.FromLine, h.FromCount), hunkRange(h.ToLine, h.ToCount))
+		for _, e := range h.Edits {
+			switch e.Op {
+			case Equal:
+				out.WriteByte(' ')
+			case Delete:
+				out.WriteByte('-')
+			case Insert:
+				out.WriteByte('+')
+			}
+			out.WriteString(e.Line)
+			out.WriteByte('\n')
+		}
+	}
+	return out.String()
+}
+
+// hunkRange formats one side of a hunk header, omitting a count of one.
+func hunkRange(line, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+// SplitLines splits text into lines without their terminators. A final
+// newline does not start an extra empty line.
+func SplitLines(s string) []string {
+	s = strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
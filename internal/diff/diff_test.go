@@ -0,0 +1,134 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want string // one character per edit: ' ', '-', '+'
+	}{
+		{"both empty", nil, nil, ""},
+		{"equal", []string{"a", "b"}, []string{"a", "b"}, "  "},
+		{"insert into empty", nil, []string{"a", "b"}, "++"},
+		{"delete all", []string{"a", "b"}, nil, "--"},
+		{"replace middle", []string{"a", "b", "c"}, []string{"a", "x", "c"}, " -+ "},
+		{"append", []string{"a"}, []string{"a", "b"}, " +"},
+		{"prepend", []string{"b"}, []string{"a", "b"}, "+ "},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			edits := Lines(tc.a, tc.b)
+			var got strings.Builder
+			for _, e := range edits {
+				got.WriteByte(" -+"[e.Op])
+			}
+			if got.String() != tc.want {
+				t.Errorf("Lines(%v, %v) = %q; want %q", tc.a, tc.b, got.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestLines_Reconstructs(t *testing.T) {
+	a := SplitLines("one\ntwo\nthree\nfour\nfive\nsix\n")
+	b := SplitLines("zero\none\nthree\nfour\n4.5\nfive\nseven\n")
+
+	var from, to []string
+	for _, e := range Lines(a, b) {
+		if e.Op != Insert {
+			from = append(from, e.Line)
+		}
+		if e.Op != Delete {
+			to = append(to, e.Line)
+		}
+	}
+	if strings.Join(from, "\n") != strings.Join(a, "\n") {
+		t.Errorf("old side = %v; want %v", from, a)
+	}
+	if strings.Join(to, "\n") != strings.Join(b, "\n") {
+		t.Errorf("new side = %v; want %v", to, b)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := "# Title\n\nline 1\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\n"
+	b := "# Title\n\nline 1\nline two\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\nline 9\n"
+
+	got := Unified("local", "remote", a, b, 2)
+	want := "--- local\n+++ remote\n" +
+		"@@ -2,5 +2,5 @@\n" +
+		" \n line 1\n-line 2\n+line two\n line 3\n line 4\n" +
+		"@@ -9,2 +9,3 @@\n" +
+		" line 7\n line 8\n+line 9\n"
+	if got != want {
+		t.Errorf("Unified() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnified_MergesNearbyChanges(t *testing.T) {
+	a := "a\nb\nc\nd\ne\n"
+	b := "a\nB\nc\nD\ne\n"
+
+	got := Unified("a", "b", a, b, 1)
+	if n := strings.Count(got, "@@ -"); n != 1 {
+		t.Errorf("got %d hunks; want 1:\n%s", n, got)
+	}
+	if !strings.Contains(got, "@@ -1,5 +1,5 @@") {
+		t.Errorf("unexpected hunk header:\n%s", got)
+	}
+}
+
+func TestUnified_EmptySide(t *testing.T) {
+	got := Unified("a", "b", "", "new\n", 3)
+	if !strings.Contains(got, "@@ -0,0 +1 @@\n+new\n") {
+		t.Errorf("Unified() from empty =\n%s", got)
+	}
+}
+
+func TestUnified_Equal(t *testing.T) {
+	if got := Unified("a", "b", "same\n", "same", 3); got != "" {
+		t.Errorf("Unified() of equal texts = %q; want empty", got)
+	}
+}
+
+func TestInline(t *testing.T) {
+	tests := []struct {
+		name, a, b, want string
+	}{
+		{"equal", "same words", "same words", "same words"},
+		{"one word", "the quick fox", "the slow fox", "the [-quick-]{+slow+} fox"},
+		{"appended", "a line", "a line, longer", "a [-line-]{+line, longer+}"},
+		{"from empty", "", "new", "{+new+}"},
+		{"spacing", "a b", "a  b", "a[- -]{+  +}b"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Inline(tc.a, tc.b, PlainMarks); got != tc.want {
+				t.Errorf("Inline(%q, %q) = %q; want %q", tc.a, tc.b, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedWords(t *testing.T) {
+	a := "# Title\n\nA long paragraph with one word changed.\nRemoved line\n"
+	b := "# Title\n\nA long paragraph with a word changed.\n"
+
+	got := UnifiedWords("local", "remote", a, b, 1, PlainMarks)
+	want := "--- local\n+++ remote\n" +
+		"@@ -2,3 +2,2 @@\n" +
+		" \n" +
+		"~A long paragraph with [-one-]{+a+} word changed.\n" +
+		"-Removed line\n"
+	if got != want {
+		t.Errorf("UnifiedWords() =\n%s\nwant:\n%s", got, want)
+	}
+	if got := UnifiedWords("a", "b", "same\n", "same\n", 3, PlainMarks); got != "" {
+		t.Errorf("UnifiedWords() of equal texts = %q; want empty", got)
+	}
+}
@@ -0,0 +1,114 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Marks are the strings a word diff puts around deleted and inserted words.
+type Marks struct {
+	DeleteStart, DeleteEnd string
+	InsertStart, InsertEnd string
+}
+
+// PlainMarks mark words as git diff --word-diff=plain does.
+var PlainMarks = Marks{DeleteStart: "[-", DeleteEnd: "-]", InsertStart: "{+", InsertEnd: "+}"}
+
+// Words returns the shortest edit script turning line a into line b. Each
+// edit is a word or a run of whitespace.
+func Words(a, b string) []Edit {
+	return Lines(splitWords(a), splitWords(b))
+}
+
+// splitWords splits a line into words and the runs of whitespace between
+// them, which joined give the line back.
+func splitWords(s string) []string {
+	var words []string
+	start := 0
+	var space bool
+	for i, r := range s {
+		if i > start && unicode.IsSpace(r) != space {
+			words = append(words, s[start:i])
+			start = i
+		}
+		space = unicode.IsSpace(r)
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
+
+// Inline returns line b as a change from line a, with the words deleted
+// from a and inserted in b marked.
+func Inline(a, b string, marks Marks) string {
+	var out strings.Builder
+	edits := Words(a, b)
+	for i := 0; i < len(edits); {
+		op := edits[i].Op
+		j := i
+		for j < len(edits) && edits[j].Op == op {
+			j++
+		}
+		var run strings.Builder
+		for _, e := range edits[i:j] {
+			run.WriteString(e.Line)
+		}
+		switch op {
+		case Equal:
+			out.WriteString(run.String())
+		case Delete:
+			out.WriteString(marks.DeleteStart + run.String() + marks.DeleteEnd)
+		case Insert:
+			out.WriteString(marks.InsertStart + run.String() + marks.InsertEnd)
+		}
+		i = j
+	}
+	return out.String()
+}
+
+// UnifiedWords returns a diff from a to b like Unified, but a line
+// replaced by another is shown once, starting with ~, with the words that
+// changed marked. Lines only deleted or only inserted are shown as in
+// Unified.
+func UnifiedWords(fromName, toName, a, b string, context int, marks Marks) string {
+	hunks := Hunks(Lines(SplitLines(a), SplitLines(b)), context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks {
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(h.FromLine, h.FromCount), hunkRange(h.ToLine, h.ToCount))
+		for i := 0; i < len(h.Edits); {
+			if h.Edits[i].Op == Equal {
+				out.WriteString(" " + h.Edits[i].Line + "\n")
+				i++
+				continue
+			}
+
+			// Pair the lines of a change in order: the first deleted line
+			// with the first inserted one, and so on.
+			var deleted, inserted []string
+			for ; i < len(h.Edits) && h.Edits[i].Op == Delete; i++ {
+				deleted = append(deleted, h.Edits[i].Line)
+			}
+			for ; i < len(h.Edits) && h.Edits[i].Op == Insert; i++ {
+				inserted = append(inserted, h.Edits[i].Line)
+			}
+			paired := min(len(deleted), len(inserted))
+			for j := 0; j < paired; j++ {
+				out.WriteString("~" + Inline(deleted[j], inserted[j], marks) + "\n")
+			}
+			for _, line := range deleted[paired:] {
+				out.WriteString("-" + line + "\n")
+			}
+			for _, line := range inserted[paired:] {
+				out.WriteString("+" + line + "\n")
+			}
+		}
+	}
+	return out.String()
+}
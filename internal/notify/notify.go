@@ -0,0 +1,136 @@
+// Package notify sends sync reports to Slack or email so unattended syncs
+// surface their results.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxListed caps how many file names a report lists per section.
+const maxListed = 20
+
+// Failure records a file that could not be synced.
+type Failure struct {
+	Path string
+	Err  string
+}
+
+// Report summarizes a single sync run.
+type Report struct {
+	Command   string // Command that ran, e.g. "sync".
+	Vault     string
+	StartedAt time.Time
+	Duration  time.Duration
+	Pushed    int
+	Pulled    int
+	Conflicts []string  // Paths left in conflict.
+	Failures  []Failure // Files that failed to sync.
+	Err       error     // Set if the run aborted.
+	Details   string    // More text, appended to the report.
+}
+
+// Failed reports whether the run aborted, failed files, or left conflicts.
+func (r *Report) Failed() bool {
+	return r.Err != nil || len(r.Failures) > 0 || len(r.Conflicts) > 0
+}
+
+// Changed reports whether anything was pushed or pulled.
+func (r *Report) Changed() bool {
+	return r.Pushed > 0 || r.Pulled > 0
+}
+
+// Subject returns a one-line summary suitable for an email subject.
+func (r *Report) Subject() string {
+	status := "ok"
+	switch {
+	case r.Err != nil:
+		status = "FAILED"
+	case len(r.Failures) > 0:
+		status = fmt.Sprintf("%d failed", len(r.Failures))
+	case len(r.Conflicts) > 0:
+		status = fmt.Sprintf("%d conflict(s)", len(r.Conflicts))
+	}
+	return fmt.Sprintf("obsidian-notion %s: %s (pushed %d, pulled %d)", r.Command, status, r.Pushed, r.Pulled)
+}
+
+// Text renders the full plain-text report.
+func (r *Report) Text() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s\n\n", r.Subject())
+	fmt.Fprintf(&b, "Vault:    %s\n", r.Vault)
+	if !r.StartedAt.IsZero() {
+		fmt.Fprintf(&b, "Started:  %s\n", r.StartedAt.Format(time.RFC3339))
+	}
+	fmt.Fprintf(&b, "Duration: %s\n", r.Duration.Round(time.Second))
+	fmt.Fprintf(&b, "Pushed:   %d\n", r.Pushed)
+	fmt.Fprintf(&b, "Pulled:   %d\n", r.Pulled)
+
+	if r.Err != nil {
+		fmt.Fprintf(&b, "\nError: %v\n", r.Err)
+	}
+
+	if len(r.Conflicts) > 0 {
+		fmt.Fprintf(&b, "\nConflicts (%d):\n", len(r.Conflicts))
+		for i, path := range r.Conflicts {
+			if i == maxListed {
+				fmt.Fprintf(&b, "  ... and %d more\n", len(r.Conflicts)-maxListed)
+				break
+			}
+			fmt.Fprintf(&b, "  ! %s\n", path)
+		}
+	}
+
+	if len(r.Failures) > 0 {
+		fmt.Fprintf(&b, "\nFailures (%d):\n", len(r.Failures))
+		for i, f := range r.Failures {
+			if i == maxListed {
+				fmt.Fprintf(&b, "  ... and %d more\n", len(r.Failures)-maxListed)
+				break
+			}
+			fmt.Fprintf(&b, "  x %s: %s\n", f.Path, f.Err)
+		}
+	}
+
+	if r.Details != "" {
+		fmt.Fprintf(&b, "\n%s", r.Details)
+	}
+
+	return b.String()
+}
+
+// Notifier delivers sync reports.
+type Notifier interface {
+	Notify(ctx context.Context, r *Report) error
+}
+
+// Multi sends a report through several notifiers, attempting all of them.
+type Multi []Notifier
+
+// Notify implements Notifier.
+func (m Multi) Notify(ctx context.Context, r *Report) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ShouldSend reports whether a report should be sent for the given
+// notify.when setting ("always", "changes", or "failure").
+func ShouldSend(when string, r *Report) bool {
+	switch when {
+	case "failure":
+		return r.Failed()
+	case "changes":
+		return r.Changed() || r.Failed()
+	default:
+		return true
+	}
+}
@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+func TestReport_Text(t *testing.T) {
+	r := &Report{
+		Command:   "sync",
+		Vault:     "/vault",
+		Pushed:    3,
+		Pulled:    1,
+		Conflicts: []string{"notes/a.md"},
+		Failures:  []Failure{{Path: "notes/b.md", Err: "update page: 502"}},
+		Details:   "Mirror integrity report:",
+	}
+
+	if got := r.Subject(); got != "obsidian-notion sync: 1 failed (pushed 3, pulled 1)" {
+		t.Errorf("Subject() = %q", got)
+	}
+
+	text := r.Text()
+	for _, want := range []string{"Pushed:   3", "Pulled:   1", "! notes/a.md", "x notes/b.md: update page: 502", "\nMirror integrity report:"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestReport_TextTruncatesLists(t *testing.T) {
+	r := &Report{Command: "sync"}
+	for i := 0; i < maxListed+5; i++ {
+		r.Failures = append(r.Failures, Failure{Path: "f.md", Err: "boom"})
+	}
+	if !strings.Contains(r.Text(), "... and 5 more") {
+		t.Error("expected long failure list to be truncated")
+	}
+}
+
+func TestShouldSend(t *testing.T) {
+	quiet := &Report{}
+	changed := &Report{Pushed: 1}
+	failed := &Report{Err: errors.New("boom")}
+
+	tests := []struct {
+		when string
+		r    *Report
+		want bool
+	}{
+		{"always", quiet, true},
+		{"", quiet, true},
+		{"changes", quiet, false},
+		{"changes", changed, true},
+		{"changes", failed, true},
+		{"failure", changed, false},
+		{"failure", failed, true},
+	}
+
+	for _, tt := range tests {
+		if got := ShouldSend(tt.when, tt.r); got != tt.want {
+			t.Errorf("ShouldSend(%q, %+v) = %v; want %v", tt.when, tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestSlack_Notify(t *testing.T) {
+	var text string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+		text = msg["text"]
+	}))
+	defer server.Close()
+
+	err := NewSlack(server.URL).Notify(context.Background(), &Report{Command: "sync", Pushed: 2})
+	if err != nil {
+		t.Fatalf("Notify() error: %v", err)
+	}
+	if !strings.Contains(text, "pushed 2") {
+		t.Errorf("unexpected slack text: %q", text)
+	}
+}
+
+func TestSlack_NotifyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewSlack(server.URL).Notify(context.Background(), &Report{Command: "sync"})
+	if err == nil || !strings.Contains(err.Error(), "invalid_token") {
+		t.Errorf("expected webhook error, got %v", err)
+	}
+}
+
+func TestEmail_Notify(t *testing.T) {
+	e := NewEmail(SMTPSettings{
+		Host: "mail.example.com",
+		From: "sync@example.com",
+		To:   []string{"me@example.com"},
+	})
+
+	var gotAddr string
+	var gotMsg []byte
+	e.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr = addr
+		gotMsg = msg
+		if a != nil {
+			t.Error("expected no auth without a username")
+		}
+		return nil
+	}
+
+	if err := e.Notify(context.Background(), &Report{Command: "sync", Pulled: 4}); err != nil {
+		t.Fatalf("Notify() error: %v", err)
+	}
+	if gotAddr != "mail.example.com:587" {
+		t.Errorf("addr = %q; want default port 587", gotAddr)
+	}
+	msg := string(gotMsg)
+	if !strings.Contains(msg, "Subject: obsidian-notion sync: ok (pushed 0, pulled 4)\r\n") {
+		t.Errorf("missing subject header:\n%s", msg)
+	}
+	if !strings.Contains(msg, "To: me@example.com\r\n") {
+		t.Errorf("missing To header:\n%s", msg)
+	}
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Slack posts reports to a Slack incoming webhook.
+type Slack struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+// NewSlack creates a Slack notifier for an incoming webhook URL.
+func NewSlack(webhookURL string) *Slack {
+	return &Slack{
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Notify implements Notifier.
+func (s *Slack) Notify(ctx context.Context, r *Report) error {
+	body, err := json.Marshal(map[string]string{
+		"text": "```\n" + r.Text() + "```",
+	})
+	if err != nil {
+		return fmt.Errorf("marshal slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("post to slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("post to slack: status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
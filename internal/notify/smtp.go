@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSMTPPort is the mail submission port.
+const defaultSMTPPort = 587
+
+// SMTPSettings configures email delivery.
+type SMTPSettings struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Email sends reports as plain-text email.
+type Email struct {
+	settings SMTPSettings
+
+	// send is smtp.SendMail, replaceable in tests.
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmail creates an email notifier.
+func NewEmail(settings SMTPSettings) *Email {
+	if settings.Port == 0 {
+		settings.Port = defaultSMTPPort
+	}
+	return &Email{settings: settings, send: smtp.SendMail}
+}
+
+// Notify implements Notifier.
+func (e *Email) Notify(ctx context.Context, r *Report) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if e.settings.Username != "" {
+		auth = smtp.PlainAuth("", e.settings.Username, e.settings.Password, e.settings.Host)
+	}
+
+	addr := net.JoinHostPort(e.settings.Host, strconv.Itoa(e.settings.Port))
+	if err := e.send(addr, auth, e.settings.From, e.settings.To, e.message(r)); err != nil {
+		return fmt.Errorf("send email: %w", err)
+	}
+	return nil
+}
+
+// message builds the RFC 5322 message for a report.
+func (e *Email) message(r *Report) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", e.settings.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.settings.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", r.Subject())
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(r.Text(), "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -1,7 +1,10 @@
 package notion
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/jomei/notionapi"
@@ -22,7 +25,7 @@ func (c *Client) GetAllBlocks(ctx context.Context, pageID string) ([]notionapi.B
 			PageSize:    100,
 		})
 		if err != nil {
-			return nil, fmt.Errorf("get children: %w", err)
+			return nil, fmt.Errorf("get children: %w", classify(err))
 		}
 
 		allBlocks = append(allBlocks, resp.Results...)
@@ -58,14 +61,15 @@ func (c *Client) GetBlock(ctx context.Context, blockID string) (notionapi.Block,
 
 	block, err := c.api.Block.Get(ctx, notionapi.BlockID(blockID))
 	if err != nil {
-		return nil, fmt.Errorf("get block: %w", err)
+		return nil, fmt.Errorf("get block: %w", classify(err))
 	}
 
 	return block, nil
 }
 
-// AppendBlocks appends blocks to a parent (page or block).
-func (c *Client) AppendBlocks(ctx context.Context, parentID string, blocks []notionapi.Block) error {
+// AppendBlocks appends blocks to a parent (page or block) and returns the
+// IDs of the appended top-level blocks.
+func (c *Client) AppendBlocks(ctx context.Context, parentID string, blocks []notionapi.Block) ([]string, error) {
 	return c.appendBlocks(ctx, parentID, blocks)
 }
 
@@ -77,7 +81,7 @@ func (c *Client) DeleteBlock(ctx context.Context, blockID string) error {
 
 	_, err := c.api.Block.Delete(ctx, notionapi.BlockID(blockID))
 	if err != nil {
-		return fmt.Errorf("delete block: %w", err)
+		return fmt.Errorf("delete block: %w", classify(err))
 	}
 
 	return nil
@@ -98,12 +102,69 @@ func (c *Client) UpdateBlock(ctx context.Context, blockID string, block notionap
 
 	updatedBlock, err := c.api.Block.Update(ctx, notionapi.BlockID(blockID), req)
 	if err != nil {
-		return nil, fmt.Errorf("update block: %w", err)
+		return nil, fmt.Errorf("update block: %w", classify(err))
 	}
 
 	return updatedBlock, nil
 }
 
+// ErrNotPatchable is returned by PatchBlocks when blocks differ in more
+// than what a block update can change, so the page must be replaced.
+var ErrNotPatchable = errors.New("blocks cannot be patched in place")
+
+// PatchBlocks updates the top-level blocks of a page whose content changed
+// from before to after, leaving the others alone. ids are the Notion IDs of
+// the blocks, in the same order. Only a block's own content is updated, so
+// if the blocks differ in number, in type, or in their children, nothing is
+// sent and ErrNotPatchable is returned. It returns the number of blocks
+// updated.
+func (c *Client) PatchBlocks(ctx context.Context, ids []string, before, after []notionapi.Block) (int, error) {
+	if len(before) != len(after) || len(ids) != len(after) {
+		return 0, ErrNotPatchable
+	}
+
+	var changed []int
+	for i := range after {
+		same, err := sameJSON(before[i], after[i])
+		if err != nil {
+			return 0, err
+		}
+		if same {
+			continue
+		}
+		if ids[i] == "" || before[i].GetType() != after[i].GetType() {
+			return 0, ErrNotPatchable
+		}
+		if same, err := sameJSON(blockChildren(before[i]), blockChildren(after[i])); err != nil || !same {
+			return 0, ErrNotPatchable
+		}
+		if _, err := buildBlockUpdateRequest(after[i]); err != nil {
+			return 0, ErrNotPatchable
+		}
+		changed = append(changed, i)
+	}
+
+	for n, i := range changed {
+		if _, err := c.UpdateBlock(ctx, ids[i], after[i]); err != nil {
+			return n, err
+		}
+	}
+	return len(changed), nil
+}
+
+// sameJSON reports whether a and b encode to the same JSON.
+func sameJSON(a, b any) (bool, error) {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false, fmt.Errorf("encode block: %w", err)
+	}
+	y, err := json.Marshal(b)
+	if err != nil {
+		return false, fmt.Errorf("encode block: %w", err)
+	}
+	return bytes.Equal(x, y), nil
+}
+
 // buildBlockUpdateRequest creates a BlockUpdateRequest from a Block interface.
 // Each block type has specific fields that can be updated via the Notion API.
 func buildBlockUpdateRequest(block notionapi.Block) (*notionapi.BlockUpdateRequest, error) {
@@ -281,6 +342,35 @@ func extractBlockID(block notionapi.Block) string {
 	}
 }
 
+// blockChildren returns the children of a block that supports them, as
+// set by setBlockChildren.
+func blockChildren(block notionapi.Block) []notionapi.Block {
+	switch b := block.(type) {
+	case *notionapi.ParagraphBlock:
+		return b.Paragraph.Children
+	case *notionapi.BulletedListItemBlock:
+		return b.BulletedListItem.Children
+	case *notionapi.NumberedListItemBlock:
+		return b.NumberedListItem.Children
+	case *notionapi.ToDoBlock:
+		return b.ToDo.Children
+	case *notionapi.ToggleBlock:
+		return b.Toggle.Children
+	case *notionapi.QuoteBlock:
+		return b.Quote.Children
+	case *notionapi.CalloutBlock:
+		return b.Callout.Children
+	case *notionapi.ColumnListBlock:
+		return b.ColumnList.Children
+	case *notionapi.ColumnBlock:
+		return b.Column.Children
+	case *notionapi.SyncedBlock:
+		return b.SyncedBlock.Children
+	default:
+		return nil
+	}
+}
+
 // setBlockChildren sets children on a block that supports them.
 // Note: This modifies the block's Children field based on block type.
 func setBlockChildren(block notionapi.Block, children []notionapi.Block) notionapi.Block {
@@ -1,6 +1,11 @@
 package notion
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/jomei/notionapi"
@@ -858,3 +863,51 @@ func TestBuildBlockUpdateRequest_PreservesRichTextFormatting(t *testing.T) {
 		t.Error("expected third item to have italic annotation")
 	}
 }
+
+func TestPatchBlocks(t *testing.T) {
+	var updated []string
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		updated = append(updated, strings.TrimPrefix(r.URL.Path, "/v1/blocks/"))
+		_, _ = io.WriteString(w, `{"object":"block","id":"x","type":"paragraph","paragraph":{"rich_text":[]}}`)
+	})
+
+	paragraph := func(rt []notionapi.RichText) notionapi.Block {
+		return &notionapi.ParagraphBlock{
+			BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeParagraph},
+			Paragraph:  notionapi.Paragraph{RichText: rt},
+		}
+	}
+	mention := []notionapi.RichText{{
+		Type:    "mention",
+		Mention: &notionapi.Mention{Type: "page", Page: &notionapi.PageMention{ID: "page-2"}},
+	}}
+	before := []notionapi.Block{paragraph(testRichText("intro")), paragraph(testRichText("[[Later]]"))}
+	after := []notionapi.Block{paragraph(testRichText("intro")), paragraph(mention)}
+
+	n, err := client.PatchBlocks(context.Background(), []string{"block-1", "block-2"}, before, after)
+	if err != nil {
+		t.Fatalf("PatchBlocks() error: %v", err)
+	}
+	if n != 1 || len(updated) != 1 || updated[0] != "block-2" {
+		t.Errorf("PatchBlocks() = %d, updated %v; want only block-2", n, updated)
+	}
+
+	// A change below a block needs the page replaced.
+	updated = nil
+	item := func(child string) notionapi.Block {
+		return &notionapi.BulletedListItemBlock{
+			BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeBulletedListItem},
+			BulletedListItem: notionapi.ListItem{
+				RichText: testRichText("item"),
+				Children: []notionapi.Block{paragraph(testRichText(child))},
+			},
+		}
+	}
+	_, err = client.PatchBlocks(context.Background(), []string{"block-1"}, []notionapi.Block{item("[[Later]]")}, []notionapi.Block{item("Later")})
+	if !errors.Is(err, ErrNotPatchable) {
+		t.Errorf("PatchBlocks() error = %v, want ErrNotPatchable", err)
+	}
+	if len(updated) != 0 {
+		t.Errorf("PatchBlocks() sent %d updates for unpatchable blocks", len(updated))
+	}
+}
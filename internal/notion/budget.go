@@ -0,0 +1,148 @@
+package notion
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+// ErrBudgetExhausted is returned for API calls beyond a client's request
+// budget.
+var ErrBudgetExhausted = errors.New("request budget exhausted")
+
+// UploadRequests is the number of API calls a single-part file upload
+// makes: one to create the upload and one to send the file.
+const UploadRequests = 2
+
+// Budget caps the API calls of a run. Clients sharing a budget draw from
+// it together. Work can reserve its estimated calls before starting, so it
+// is not begun unless it can finish.
+type Budget struct {
+	mu       sync.Mutex
+	limit    int64
+	used     int64
+	reserved int64
+}
+
+// NewBudget returns a budget of limit API calls.
+func NewBudget(limit int64) *Budget {
+	return &Budget{limit: limit}
+}
+
+// WithBudget draws the client's API calls from a budget. Calls beyond it
+// fail with ErrBudgetExhausted.
+func WithBudget(b *Budget) ClientOption {
+	return func(c *Client) {
+		c.budget = b
+	}
+}
+
+// spend takes one call from the budget, reporting false if none is left.
+func (b *Budget) spend() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used >= b.limit {
+		return false
+	}
+	b.used++
+	return true
+}
+
+// Reserve sets aside n calls for work about to start and returns a
+// function that releases them when it is done. It reports false, reserving
+// nothing, if the calls already made and reserved leave fewer than n.
+func (b *Budget) Reserve(n int) (release func(), ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used+b.reserved+int64(n) > b.limit {
+		return nil, false
+	}
+	b.reserved += int64(n)
+	return func() {
+		b.mu.Lock()
+		b.reserved -= int64(n)
+		b.mu.Unlock()
+	}, true
+}
+
+// Used returns the calls made so far.
+func (b *Budget) Used() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// Limit returns the size of the budget.
+func (b *Budget) Limit() int64 {
+	return b.limit
+}
+
+// PageRequests estimates the API calls pushing a page makes: CreatePage
+// for a new page, or ReplacePage for an existing one. The existing page is
+// assumed to hold as many blocks as the new content, since its size is
+// only known by fetching it.
+func (c *Client) PageRequests(page *transformer.NotionPage, isNew bool) int {
+	// Creating the page, or updating its properties.
+	n := 1 + c.appendRequests(page.Children)
+	for _, section := range page.Sections {
+		n += 1 + c.appendRequests(section.Children)
+	}
+	if isNew {
+		return n
+	}
+
+	// Fetching the page and its blocks, then deleting each block,
+	// including the child pages of earlier sections.
+	existing := len(page.Children) + len(page.Sections)
+	n++
+	n += max(1, (existing+c.pageSize-1)/c.pageSize)
+	n += blocksWithChildren(page.Children)
+	n += existing
+	return n
+}
+
+// appendRequests returns the API calls appendBlocks makes for blocks:
+// one per batch, plus those appending the children of deeply nested
+// blocks to the created blocks.
+func (c *Client) appendRequests(blocks []notionapi.Block) int {
+	n := (len(blocks) + c.batchSize - 1) / c.batchSize
+	for _, block := range blocks {
+		if deferrable(block) {
+			n += c.appendRequests(blockChildren(block))
+		}
+	}
+	return n
+}
+
+// blocksWithChildren counts the blocks, at any depth, whose children are
+// fetched with a request of their own.
+func blocksWithChildren(blocks []notionapi.Block) int {
+	n := 0
+	for _, block := range blocks {
+		if table, ok := block.(*notionapi.TableBlock); ok && len(table.Table.Children) > 0 {
+			n++
+			continue
+		}
+		if children := blockChildren(block); len(children) > 0 {
+			n += 1 + blocksWithChildren(children)
+		}
+	}
+	return n
+}
+
+// CountBlocks returns the number of blocks, at any depth, including table
+// rows.
+func CountBlocks(blocks []notionapi.Block) int {
+	n := len(blocks)
+	for _, block := range blocks {
+		if table, ok := block.(*notionapi.TableBlock); ok {
+			n += len(table.Table.Children)
+			continue
+		}
+		n += CountBlocks(blockChildren(block))
+	}
+	return n
+}
@@ -0,0 +1,107 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+func TestBudget_Reserve(t *testing.T) {
+	b := NewBudget(10)
+
+	release, ok := b.Reserve(6)
+	if !ok {
+		t.Fatal("Reserve(6) of 10 should succeed")
+	}
+	if _, ok := b.Reserve(5); ok {
+		t.Error("Reserve(5) should fail with 6 of 10 reserved")
+	}
+	release()
+	if _, ok := b.Reserve(10); !ok {
+		t.Error("Reserve(10) should succeed after release")
+	}
+}
+
+func TestBudget_Spend(t *testing.T) {
+	b := NewBudget(2)
+	for i := 0; i < 2; i++ {
+		if !b.spend() {
+			t.Fatalf("spend() %d should succeed", i+1)
+		}
+	}
+	if b.spend() {
+		t.Error("spend() beyond the limit should fail")
+	}
+	if b.Used() != 2 {
+		t.Errorf("Used() = %d, want 2", b.Used())
+	}
+	if _, ok := b.Reserve(1); ok {
+		t.Error("Reserve(1) should fail once the budget is spent")
+	}
+}
+
+func TestWithBudget(t *testing.T) {
+	budget := NewBudget(1)
+	calls := 0
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"page","id":"page-1"}`))
+	}, WithBudget(budget))
+
+	ctx := context.Background()
+	if err := client.ArchivePage(ctx, "page-1"); err != nil {
+		t.Fatalf("first ArchivePage() error = %v", err)
+	}
+	err := client.ArchivePage(ctx, "page-1")
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("second ArchivePage() error = %v, want ErrBudgetExhausted", err)
+	}
+	if calls != 1 {
+		t.Errorf("server received %d requests, want 1", calls)
+	}
+}
+
+func TestPageRequests(t *testing.T) {
+	client := New("test-token", WithBatchSize(2))
+	para := func() notionapi.Block {
+		return &notionapi.ParagraphBlock{
+			BasicBlock: notionapi.BasicBlock{Object: "block", Type: notionapi.BlockTypeParagraph},
+		}
+	}
+	page := &transformer.NotionPage{
+		Children: []notionapi.Block{para(), para(), para()},
+	}
+
+	// One create, then two batches of children.
+	if got := client.PageRequests(page, true); got != 3 {
+		t.Errorf("PageRequests(new) = %d, want 3", got)
+	}
+	// Plus fetching the page, one page of blocks, and deleting three blocks.
+	if got := client.PageRequests(page, false); got != 8 {
+		t.Errorf("PageRequests(existing) = %d, want 8", got)
+	}
+}
+
+func TestCountBlocks(t *testing.T) {
+	blocks := []notionapi.Block{
+		&notionapi.BulletedListItemBlock{
+			BulletedListItem: notionapi.ListItem{
+				Children: []notionapi.Block{&notionapi.ParagraphBlock{}},
+			},
+		},
+		&notionapi.TableBlock{
+			Table: notionapi.Table{
+				Children: []notionapi.Block{&notionapi.TableRowBlock{}, &notionapi.TableRowBlock{}},
+			},
+		},
+	}
+	if got := CountBlocks(blocks); got != 5 {
+		t.Errorf("CountBlocks() = %d, want 5", got)
+	}
+}
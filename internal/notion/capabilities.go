@@ -0,0 +1,263 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jomei/notionapi"
+)
+
+// blockTypeVersions records the earliest Notion API version known to
+// accept each block type the transformer writes. Types not listed are
+// assumed to be supported by every version.
+var blockTypeVersions = map[notionapi.BlockType]string{
+	notionapi.BlockCallout:             "2021-08-16",
+	notionapi.BlockQuote:               "2021-08-16",
+	notionapi.BlockTypeCode:            "2021-08-16",
+	notionapi.BlockTypeEquation:        "2021-08-16",
+	notionapi.BlockTypeDivider:         "2021-08-16",
+	notionapi.BlockTypeImage:           "2021-08-16",
+	notionapi.BlockTypeVideo:           "2021-08-16",
+	notionapi.BlockTypeFile:            "2021-08-16",
+	notionapi.BlockTypePdf:             "2021-08-16",
+	notionapi.BlockTypeBookmark:        "2021-08-16",
+	notionapi.BlockTypeEmbed:           "2021-08-16",
+	notionapi.BlockTypeTableOfContents: "2021-08-16",
+	notionapi.BlockTypeBreadcrumb:      "2021-08-16",
+	notionapi.BlockTypeColumnList:      "2022-02-22",
+	notionapi.BlockTypeColumn:          "2022-02-22",
+	notionapi.BlockTypeLinkToPage:      "2022-02-22",
+	notionapi.BlockTypeSyncedBlock:     "2022-02-22",
+	notionapi.BlockTypeTableBlock:      "2022-02-22",
+	notionapi.BlockTypeTableRowBlock:   "2022-02-22",
+}
+
+// unsupportedBlockTypes returns the block types newer than an API version.
+// Versions are dates, so they compare as strings.
+func unsupportedBlockTypes(version string) map[notionapi.BlockType]bool {
+	unsupported := make(map[notionapi.BlockType]bool)
+	for blockType, since := range blockTypeVersions {
+		if version < since {
+			unsupported[blockType] = true
+		}
+	}
+	return unsupported
+}
+
+// Capabilities describes what the Notion API offers a client, for its API
+// version and integration token.
+type Capabilities struct {
+	// APIVersion is the Notion-Version the client sends.
+	APIVersion string
+
+	// Endpoints maps each probed endpoint to whether it is available.
+	Endpoints map[string]bool
+
+	// UnsupportedBlocks are the block types pushed as placeholders.
+	UnsupportedBlocks []notionapi.BlockType
+}
+
+// probeEndpoints are the endpoints Probe checks, with a harmless request
+// for each.
+var probeEndpoints = []struct {
+	name   string
+	method string
+	path   string
+	body   string
+}{
+	{"users", http.MethodGet, "/users/me", ""},
+	{"search", http.MethodPost, "/search", `{"page_size": 1}`},
+	{"file_uploads", http.MethodGet, "/file_uploads?page_size=1", ""},
+}
+
+// Probe checks which endpoints the API version and token can use. It fails
+// if the token is rejected or the API version is not accepted at all.
+func (c *Client) Probe(ctx context.Context) (*Capabilities, error) {
+	caps := &Capabilities{
+		APIVersion: c.version,
+		Endpoints:  make(map[string]bool),
+	}
+	for _, ep := range probeEndpoints {
+		available, err := c.probe(ctx, ep.method, ep.path, ep.body)
+		if err != nil {
+			return nil, fmt.Errorf("probe %s: %w", ep.name, err)
+		}
+		caps.Endpoints[ep.name] = available
+	}
+
+	c.capsMu.Lock()
+	for blockType := range c.unsupported {
+		caps.UnsupportedBlocks = append(caps.UnsupportedBlocks, blockType)
+	}
+	c.capsMu.Unlock()
+	sort.Slice(caps.UnsupportedBlocks, func(i, j int) bool {
+		return caps.UnsupportedBlocks[i] < caps.UnsupportedBlocks[j]
+	})
+	return caps, nil
+}
+
+// probe sends one request and reports whether the endpoint exists. Unknown
+// endpoints answer 404 or invalid_request_url; other refusals, such as a
+// missing capability of the integration, still mean it exists.
+func (c *Client) probe(ctx context.Context, method, path, body string) (bool, error) {
+	if err := c.wait(ctx); err != nil {
+		return false, fmt.Errorf("rate limit: %w", err)
+	}
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := c.newRequest(ctx, method, path, "application/json", reader)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var apiErr apiError
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(data, &apiErr)
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return true, nil
+	case resp.StatusCode == http.StatusNotFound || apiErr.Code == "invalid_request_url":
+		return false, nil
+	case resp.StatusCode == http.StatusUnauthorized:
+		return false, fmt.Errorf("unauthorized: check the integration token")
+	case apiErr.Code == "missing_version" || (resp.StatusCode == http.StatusBadRequest && strings.Contains(apiErr.Message, "Notion-Version")):
+		return false, fmt.Errorf("API version %s not accepted: %s", c.version, apiErr.Message)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return false, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	default:
+		return true, nil
+	}
+}
+
+// Degraded returns the number of blocks of each type pushed as
+// placeholders because the API version does not support them.
+func (c *Client) Degraded() map[notionapi.BlockType]int {
+	c.capsMu.Lock()
+	defer c.capsMu.Unlock()
+
+	degraded := make(map[notionapi.BlockType]int, len(c.degraded))
+	for blockType, n := range c.degraded {
+		degraded[blockType] = n
+	}
+	return degraded
+}
+
+// APIVersion returns the Notion-Version the client sends.
+func (c *Client) APIVersion() string {
+	return c.version
+}
+
+// degradeBlocks replaces blocks of unsupported types, at any depth, with
+// placeholder callouts naming the type.
+func (c *Client) degradeBlocks(blocks []notionapi.Block) []notionapi.Block {
+	c.capsMu.Lock()
+	defer c.capsMu.Unlock()
+	if len(c.unsupported) == 0 {
+		return blocks
+	}
+	return c.degradeLocked(blocks)
+}
+
+// degradeLocked implements degradeBlocks. The caller must hold capsMu.
+func (c *Client) degradeLocked(blocks []notionapi.Block) []notionapi.Block {
+	out := make([]notionapi.Block, len(blocks))
+	for i, block := range blocks {
+		blockType := block.GetType()
+		if c.unsupported[blockType] {
+			c.degraded[blockType]++
+			out[i] = placeholderBlock(blockType, c.version, c.placeholderIcon, !c.unsupported[notionapi.BlockCallout])
+			continue
+		}
+		if children := blockChildren(block); len(children) > 0 {
+			block = setBlockChildren(block, c.degradeLocked(children))
+		}
+		out[i] = block
+	}
+	return out
+}
+
+// rejectedTypeRegex matches the part of a validation error naming a block
+// type the API version does not know, such as
+// "body.children[2].table_of_contents should be not present".
+var rejectedTypeRegex = regexp.MustCompile(`\.([a-z_0-9]+) should be not present`)
+
+// markRejected records block types a validation error rejected, if they
+// appear in blocks, and reports whether any were new.
+func (c *Client) markRejected(err error, blocks []notionapi.Block) bool {
+	var apiErr *notionapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "validation_error" {
+		return false
+	}
+
+	present := make(map[notionapi.BlockType]bool)
+	var collect func([]notionapi.Block)
+	collect = func(blocks []notionapi.Block) {
+		for _, block := range blocks {
+			present[block.GetType()] = true
+			collect(blockChildren(block))
+		}
+	}
+	collect(blocks)
+
+	c.capsMu.Lock()
+	defer c.capsMu.Unlock()
+	marked := false
+	for _, m := range rejectedTypeRegex.FindAllStringSubmatch(apiErr.Message, -1) {
+		blockType := notionapi.BlockType(m[1])
+		if present[blockType] && !c.unsupported[blockType] {
+			c.unsupported[blockType] = true
+			marked = true
+		}
+	}
+	return marked
+}
+
+// placeholderBlock returns the block pushed in place of a block type the
+// API version does not support: a callout with the icon, or a paragraph
+// when callouts are not supported either.
+func placeholderBlock(blockType notionapi.BlockType, version, icon string, callout bool) notionapi.Block {
+	richText := []notionapi.RichText{{
+		Type: notionapi.ObjectTypeText,
+		Text: &notionapi.Text{
+			Content: fmt.Sprintf("Unsupported block type %s for Notion API version %s", blockType, version),
+		},
+	}}
+	if !callout {
+		return &notionapi.ParagraphBlock{
+			BasicBlock: notionapi.BasicBlock{
+				Object: notionapi.ObjectTypeBlock,
+				Type:   notionapi.BlockTypeParagraph,
+			},
+			Paragraph: notionapi.Paragraph{RichText: richText},
+		}
+	}
+
+	emoji := notionapi.Emoji(icon)
+	return &notionapi.CalloutBlock{
+		BasicBlock: notionapi.BasicBlock{
+			Object: notionapi.ObjectTypeBlock,
+			Type:   notionapi.BlockCallout,
+		},
+		Callout: notionapi.Callout{
+			RichText: richText,
+			Icon:     &notionapi.Icon{Type: "emoji", Emoji: &emoji},
+		},
+	}
+}
@@ -0,0 +1,155 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jomei/notionapi"
+)
+
+func TestUnsupportedBlockTypes(t *testing.T) {
+	if got := unsupportedBlockTypes(DefaultAPIVersion); len(got) != 0 {
+		t.Errorf("default version has unsupported block types: %v", got)
+	}
+
+	got := unsupportedBlockTypes("2021-05-13")
+	for _, blockType := range []notionapi.BlockType{notionapi.BlockCallout, notionapi.BlockTypeTableBlock} {
+		if !got[blockType] {
+			t.Errorf("%s should be unsupported by 2021-05-13", blockType)
+		}
+	}
+	if got[notionapi.BlockTypeParagraph] {
+		t.Error("paragraph should be supported by every version")
+	}
+}
+
+func TestProbe(t *testing.T) {
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get("Notion-Version"); v != "2025-01-01" {
+			t.Errorf("%s: Notion-Version = %q, want 2025-01-01", r.URL.Path, v)
+		}
+		switch r.URL.Path {
+		case "/v1/users/me", "/v1/search":
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"object":"error","status":400,"code":"invalid_request_url","message":"Invalid request URL."}`)
+		}
+	}, WithAPIVersion("2025-01-01"))
+
+	caps, err := client.Probe(context.Background())
+	if err != nil {
+		t.Fatalf("Probe() error: %v", err)
+	}
+	if caps.APIVersion != "2025-01-01" {
+		t.Errorf("APIVersion = %q", caps.APIVersion)
+	}
+	want := map[string]bool{"users": true, "search": true, "file_uploads": false}
+	for name, available := range want {
+		if caps.Endpoints[name] != available {
+			t.Errorf("Endpoints[%s] = %v, want %v", name, caps.Endpoints[name], available)
+		}
+	}
+}
+
+func TestProbe_Unauthorized(t *testing.T) {
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"object":"error","status":401,"code":"unauthorized","message":"API token is invalid."}`)
+	})
+
+	if _, err := client.Probe(context.Background()); err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("Probe() error = %v, want unauthorized", err)
+	}
+}
+
+func TestAppendBlocks_RejectedTypeBecomesPlaceholder(t *testing.T) {
+	var bodies []string
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if strings.Contains(string(data), `"type":"table_of_contents"`) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"object":"error","status":400,"code":"validation_error",`+
+				`"message":"body.children[1].table_of_contents should be not present, instead was `+"`{}`"+`."}`)
+			return
+		}
+		fmt.Fprint(w, `{"object":"list","results":[]}`)
+	}, WithPlaceholderIcon("\U0001F6A7"))
+
+	blocks := []notionapi.Block{
+		&notionapi.ParagraphBlock{
+			BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeParagraph},
+			Paragraph:  notionapi.Paragraph{RichText: []notionapi.RichText{}},
+		},
+		&notionapi.TableOfContentsBlock{BasicBlock: notionapi.BasicBlock{
+			Object: notionapi.ObjectTypeBlock,
+			Type:   notionapi.BlockTypeTableOfContents,
+		}},
+	}
+	if _, err := client.AppendBlocks(context.Background(), "page-1", blocks); err != nil {
+		t.Fatalf("AppendBlocks() error: %v", err)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("got %d requests, want a rejected one and a retry", len(bodies))
+	}
+	if !strings.Contains(bodies[1], "Unsupported block type table_of_contents") {
+		t.Errorf("retry does not contain a placeholder: %s", bodies[1])
+	}
+	if !strings.Contains(bodies[1], "\U0001F6A7") {
+		t.Errorf("placeholder does not have the configured icon: %s", bodies[1])
+	}
+	if got := client.Degraded()[notionapi.BlockTypeTableOfContents]; got != 1 {
+		t.Errorf("Degraded() = %d, want 1", got)
+	}
+
+	// Later pages skip the rejected type without another failed request.
+	bodies = nil
+	if _, err := client.AppendBlocks(context.Background(), "page-2", blocks); err != nil {
+		t.Fatalf("AppendBlocks() error: %v", err)
+	}
+	if len(bodies) != 1 {
+		t.Errorf("got %d requests for the second page, want 1", len(bodies))
+	}
+}
+
+func TestAppendBlocks_OldVersionDegradesNestedBlocks(t *testing.T) {
+	var request struct {
+		Children []map[string]json.RawMessage `json:"children"`
+	}
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		fmt.Fprint(w, `{"object":"list","results":[]}`)
+	}, WithAPIVersion("2021-05-13"))
+
+	emoji := notionapi.Emoji("💡")
+	item := &notionapi.BulletedListItemBlock{
+		BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeBulletedListItem},
+		BulletedListItem: notionapi.ListItem{
+			RichText: []notionapi.RichText{{Type: notionapi.ObjectTypeText, Text: &notionapi.Text{Content: "Item"}}},
+			Children: []notionapi.Block{&notionapi.CalloutBlock{
+				BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockCallout},
+				Callout:    notionapi.Callout{Icon: &notionapi.Icon{Type: "emoji", Emoji: &emoji}},
+			}},
+		},
+	}
+	if _, err := client.AppendBlocks(context.Background(), "page-1", []notionapi.Block{item}); err != nil {
+		t.Fatalf("AppendBlocks() error: %v", err)
+	}
+
+	if len(request.Children) != 1 {
+		t.Fatalf("sent %d blocks, want 1", len(request.Children))
+	}
+	listItem := string(request.Children[0]["bulleted_list_item"])
+	if strings.Contains(listItem, `"callout"`) || !strings.Contains(listItem, `"paragraph"`) {
+		t.Errorf("nested callout not replaced by a paragraph placeholder: %s", listItem)
+	}
+}
@@ -5,10 +5,16 @@ package notion
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jomei/notionapi"
 	"golang.org/x/time/rate"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
 )
 
 const (
@@ -17,6 +23,18 @@ const (
 
 	// DefaultBatchSize is the max blocks per append request.
 	DefaultBatchSize = 100
+
+	// DefaultPageSize is the number of results requested per page of a
+	// database query or search, the most Notion returns at once.
+	DefaultPageSize = 100
+
+	// apiBaseURL is the Notion REST endpoint used for requests the
+	// notionapi package does not cover, such as file uploads.
+	apiBaseURL = "https://api.notion.com/v1"
+
+	// DefaultAPIVersion is the Notion-Version every request is pinned to
+	// unless WithAPIVersion selects another.
+	DefaultAPIVersion = "2022-06-28"
 )
 
 // Client wraps the Notion API client with rate limiting and helper methods.
@@ -24,6 +42,60 @@ type Client struct {
 	api       *notionapi.Client
 	limiter   *rate.Limiter
 	batchSize int
+	pageSize  int
+	version   string
+
+	// uploadLimiter, if set, also paces file upload requests, so uploads
+	// take only part of the limiter's rate and leave the rest to pages.
+	uploadLimiter *rate.Limiter
+
+	// shared, if set, is the limiter of the client of the token's shared
+	// bucket, which the requests of a database's bucket also wait for, so
+	// a token's buckets together keep to its rate.
+	shared *rate.Limiter
+
+	// httpClient and baseURL serve direct REST requests.
+	httpClient *http.Client
+	baseURL    string
+
+	// requests and blocks count API calls and appended blocks.
+	requests atomic.Int64
+	blocks   atomic.Int64
+
+	// budget, if set, caps the API calls the client may make.
+	budget *Budget
+
+	// unsupported holds block types the API version rejects, which are
+	// pushed as placeholders; degraded counts the blocks replaced so far.
+	// Both are guarded by capsMu.
+	capsMu      sync.Mutex
+	unsupported map[notionapi.BlockType]bool
+	degraded    map[notionapi.BlockType]int
+
+	// placeholderIcon is the icon of the placeholders.
+	placeholderIcon string
+
+	// trace, if set, receives a line for every HTTP request.
+	trace io.Writer
+
+	// users caches workspace user lookups by ID, guarded by usersMu.
+	usersMu sync.Mutex
+	users   map[string]userLookup
+
+	// botID is the ID of the integration's bot user, once looked up,
+	// guarded by usersMu.
+	botID string
+}
+
+// Usage summarizes the API traffic of a client.
+type Usage struct {
+	Requests int64 // API calls made.
+	Blocks   int64 // Top-level blocks appended to pages.
+}
+
+// Add returns the sum of two usages.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{Requests: u.Requests + other.Requests, Blocks: u.Blocks + other.Blocks}
 }
 
 // ClientOption configures the Client.
@@ -36,6 +108,22 @@ func WithRateLimit(requestsPerSecond float64) ClientOption {
 	}
 }
 
+// withSharedLimiter also paces the client's requests by the limiter of
+// another client, the client of its token's shared bucket.
+func withSharedLimiter(l *rate.Limiter) ClientOption {
+	return func(c *Client) {
+		c.shared = l
+	}
+}
+
+// WithUploadRateLimit caps file upload requests at a rate within the
+// client's rate limit.
+func WithUploadRateLimit(requestsPerSecond float64) ClientOption {
+	return func(c *Client) {
+		c.uploadLimiter = rate.NewLimiter(rate.Limit(requestsPerSecond), 1)
+	}
+}
+
 // WithBatchSize sets a custom batch size for block operations.
 func WithBatchSize(size int) ClientOption {
 	return func(c *Client) {
@@ -43,24 +131,91 @@ func WithBatchSize(size int) ClientOption {
 	}
 }
 
+// WithPageSize sets the number of results requested per page when
+// listing database pages and search results.
+func WithPageSize(size int) ClientOption {
+	return func(c *Client) {
+		c.pageSize = size
+	}
+}
+
+// WithHTTPClient sends every request, including file uploads, through an
+// HTTP client, such as one configured with a proxy.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithAPIVersion pins requests to a Notion API version (the Notion-Version
+// header). Block types newer than the version are pushed as placeholders.
+func WithAPIVersion(version string) ClientOption {
+	return func(c *Client) {
+		c.version = version
+	}
+}
+
+// WithPlaceholderIcon sets the icon of the callouts pushed in place of
+// block types the API version does not support.
+func WithPlaceholderIcon(icon string) ClientOption {
+	return func(c *Client) {
+		c.placeholderIcon = icon
+	}
+}
+
+// ProgressFunc is called after each page of results is fetched with the
+// number of results fetched so far.
+type ProgressFunc func(fetched int)
+
 // New creates a new Notion API client with rate limiting.
 func New(token string, opts ...ClientOption) *Client {
 	c := &Client{
-		api:       notionapi.NewClient(notionapi.Token(token)),
 		limiter:   rate.NewLimiter(rate.Every(time.Second/DefaultRateLimit), 1),
 		batchSize: DefaultBatchSize,
+		pageSize:  DefaultPageSize,
+		version:   DefaultAPIVersion,
+
+		placeholderIcon: transformer.DefaultIcons[transformer.IconUnsupported],
+
+		httpClient: http.DefaultClient,
+		baseURL:    apiBaseURL,
 	}
 
 	for _, opt := range opts {
 		opt(c)
 	}
+	if c.trace != nil {
+		c.httpClient = traceHTTPClient(c.httpClient, c.trace)
+	}
 
+	c.api = notionapi.NewClient(notionapi.Token(token), notionapi.WithHTTPClient(c.httpClient), notionapi.WithVersion(c.version))
+	c.unsupported = unsupportedBlockTypes(c.version)
+	c.degraded = make(map[notionapi.BlockType]int)
 	return c
 }
 
-// wait blocks until the rate limiter allows a request.
+// wait blocks until the rate limiter allows a request. Every API call
+// goes through wait, so it also counts requests and draws them from the
+// budget.
 func (c *Client) wait(ctx context.Context) error {
-	return c.limiter.Wait(ctx)
+	if c.budget != nil && !c.budget.spend() {
+		return ErrBudgetExhausted
+	}
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	if c.shared != nil {
+		if err := c.shared.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	c.requests.Add(1)
+	return nil
+}
+
+// Usage returns the API traffic of the client so far.
+func (c *Client) Usage() Usage {
+	return Usage{Requests: c.requests.Load(), Blocks: c.blocks.Load()}
 }
 
 // GetDatabase retrieves a database by ID.
@@ -71,7 +226,7 @@ func (c *Client) GetDatabase(ctx context.Context, databaseID string) (*notionapi
 
 	db, err := c.api.Database.Get(ctx, notionapi.DatabaseID(databaseID))
 	if err != nil {
-		return nil, fmt.Errorf("get database: %w", err)
+		return nil, fmt.Errorf("get database: %w", classify(err))
 	}
 
 	return db, nil
@@ -89,12 +244,42 @@ func (c *Client) QueryDatabase(ctx context.Context, databaseID string, filter *n
 
 	resp, err := c.api.Database.Query(ctx, notionapi.DatabaseID(databaseID), filter)
 	if err != nil {
-		return nil, fmt.Errorf("query database: %w", err)
+		return nil, fmt.Errorf("query database: %w", classify(err))
 	}
 
 	return resp, nil
 }
 
+// QueryAllPages queries a database and follows pagination until every
+// matching page is fetched. The request's filter and sorts are kept; its
+// cursor and page size are managed here. progress, if not nil, is called
+// after each page of results.
+func (c *Client) QueryAllPages(ctx context.Context, databaseID string, query *notionapi.DatabaseQueryRequest, progress ProgressFunc) ([]notionapi.Page, error) {
+	req := notionapi.DatabaseQueryRequest{}
+	if query != nil {
+		req = *query
+	}
+	req.StartCursor = ""
+	req.PageSize = c.pageSize
+
+	var pages []notionapi.Page
+	for {
+		resp, err := c.QueryDatabase(ctx, databaseID, &req)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, resp.Results...)
+		if progress != nil {
+			progress(len(pages))
+		}
+
+		if !resp.HasMore || resp.NextCursor == "" {
+			return pages, nil
+		}
+		req.StartCursor = resp.NextCursor
+	}
+}
+
 // SearchPages searches for pages matching a query.
 func (c *Client) SearchPages(ctx context.Context, query string) (*notionapi.SearchResponse, error) {
 	if err := c.wait(ctx); err != nil {
@@ -109,12 +294,65 @@ func (c *Client) SearchPages(ctx context.Context, query string) (*notionapi.Sear
 		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("search pages: %w", err)
+		return nil, fmt.Errorf("search pages: %w", classify(err))
 	}
 
 	return resp, nil
 }
 
+// SearchAllPages searches for pages matching a query, most recently edited
+// first, following pagination until limit pages are found. Pages for which
+// keep returns false are skipped; a nil keep accepts every page. A limit of
+// 0 returns every match. progress, if not nil, is called after each page
+// of results with the number of results scanned.
+func (c *Client) SearchAllPages(ctx context.Context, query string, limit int, keep func(*notionapi.Page) bool, progress ProgressFunc) ([]*notionapi.Page, error) {
+	var pages []*notionapi.Page
+	var cursor notionapi.Cursor
+	var scanned int
+	for {
+		if err := c.wait(ctx); err != nil {
+			return nil, fmt.Errorf("rate limit: %w", err)
+		}
+
+		resp, err := c.api.Search.Do(ctx, &notionapi.SearchRequest{
+			Query: query,
+			Filter: notionapi.SearchFilter{
+				Property: "object",
+				Value:    "page",
+			},
+			Sort: &notionapi.SortObject{
+				Direction: notionapi.SortOrderDESC,
+				Timestamp: notionapi.TimestampLastEdited,
+			},
+			StartCursor: cursor,
+			PageSize:    c.pageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("search pages: %w", classify(err))
+		}
+		scanned += len(resp.Results)
+		if progress != nil {
+			progress(scanned)
+		}
+
+		for _, obj := range resp.Results {
+			page, ok := obj.(*notionapi.Page)
+			if !ok || (keep != nil && !keep(page)) {
+				continue
+			}
+			pages = append(pages, page)
+			if limit > 0 && len(pages) == limit {
+				return pages, nil
+			}
+		}
+
+		if !resp.HasMore || resp.NextCursor == "" {
+			return pages, nil
+		}
+		cursor = resp.NextCursor
+	}
+}
+
 // API returns the underlying notionapi.Client for advanced operations.
 func (c *Client) API() *notionapi.Client {
 	return c.api
@@ -2,9 +2,18 @@ package notion
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/jomei/notionapi"
 	"golang.org/x/time/rate"
 )
 
@@ -93,6 +102,28 @@ func TestWithBatchSize(t *testing.T) {
 	}
 }
 
+func TestWithHTTPClient(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		_, _ = io.WriteString(w, `{"object":"page","id":"page-1"}`)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	// API calls and direct REST requests both go through the client.
+	client := New("test-token", WithRateLimit(1000), WithHTTPClient(&http.Client{Transport: mockTransport{target}}))
+	if _, err := client.GetPage(context.Background(), "page-1"); err != nil {
+		t.Fatalf("GetPage() error: %v", err)
+	}
+	if err := client.MovePage(context.Background(), "page-1", "db-1"); err != nil {
+		t.Fatalf("MovePage() error: %v", err)
+	}
+	if want := []string{"/v1/pages/page-1", "/v1/pages/page-1/move"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("requests = %v, want %v", paths, want)
+	}
+}
+
 func TestWait(t *testing.T) {
 	// Create a client with a very high rate limit to avoid blocking.
 	client := New("test-token", WithRateLimit(1000))
@@ -148,6 +179,152 @@ func TestAPI(t *testing.T) {
 	}
 }
 
+// mockTransport sends the notionapi client's requests to a test server.
+type mockTransport struct {
+	target *url.URL
+}
+
+func (m mockTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = m.target.Scheme
+	r.URL.Host = m.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// newMockClient returns a client whose API calls are served by handler.
+func newMockClient(t *testing.T, handler http.HandlerFunc, opts ...ClientOption) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	client := New("test-token", append([]ClientOption{WithRateLimit(1000)}, opts...)...)
+	httpClient := &http.Client{Transport: mockTransport{target}}
+	client.api = notionapi.NewClient("test-token", notionapi.WithHTTPClient(httpClient), notionapi.WithVersion(client.version))
+	client.httpClient = httpClient
+	return client
+}
+
+// pagedResults serves total numbered pages in pages of the requested size,
+// recording each request body.
+func pagedResults(t *testing.T, total int, requests *[]map[string]any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		*requests = append(*requests, req)
+
+		start := 0
+		if cursor, ok := req["start_cursor"].(string); ok {
+			fmt.Sscanf(cursor, "cursor-%d", &start)
+		}
+		size := int(req["page_size"].(float64))
+		end := min(start+size, total)
+
+		results := make([]string, 0, end-start)
+		for i := start; i < end; i++ {
+			results = append(results, fmt.Sprintf(`{"object":"page","id":"page-%d"}`, i))
+		}
+		next := "null"
+		if end < total {
+			next = fmt.Sprintf(`"cursor-%d"`, end)
+		}
+		fmt.Fprintf(w, `{"object":"list","results":[%s],"has_more":%t,"next_cursor":%s}`,
+			strings.Join(results, ","), end < total, next)
+	}
+}
+
+func TestQueryAllPages(t *testing.T) {
+	var requests []map[string]any
+	var progress []int
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/databases/db-1/query" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		pagedResults(t, 250, &requests)(w, r)
+	})
+
+	pages, err := client.QueryAllPages(context.Background(), "db-1", nil, func(fetched int) {
+		progress = append(progress, fetched)
+	})
+	if err != nil {
+		t.Fatalf("QueryAllPages() error: %v", err)
+	}
+
+	if len(pages) != 250 {
+		t.Fatalf("got %d pages, want 250", len(pages))
+	}
+	for i, p := range pages {
+		if want := fmt.Sprintf("page-%d", i); string(p.ID) != want {
+			t.Fatalf("pages[%d].ID = %s, want %s", i, p.ID, want)
+		}
+	}
+	if want := []int{100, 200, 250}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+	if len(requests) != 3 || requests[0]["start_cursor"] != nil || requests[2]["start_cursor"] != "cursor-200" {
+		t.Errorf("requests = %v, want three following the cursor", requests)
+	}
+}
+
+func TestQueryAllPages_PageSize(t *testing.T) {
+	var requests []map[string]any
+	client := newMockClient(t, pagedResults(t, 25, &requests), WithPageSize(10))
+
+	pages, err := client.QueryAllPages(context.Background(), "db-1", nil, nil)
+	if err != nil {
+		t.Fatalf("QueryAllPages() error: %v", err)
+	}
+	if len(pages) != 25 || len(requests) != 3 {
+		t.Errorf("got %d pages in %d requests, want 25 in 3", len(pages), len(requests))
+	}
+	for _, req := range requests {
+		if req["page_size"] != float64(10) {
+			t.Errorf("page_size = %v, want 10", req["page_size"])
+		}
+	}
+}
+
+func TestSearchAllPages_Pagination(t *testing.T) {
+	var requests []map[string]any
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/search" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		pagedResults(t, 120, &requests)(w, r)
+	}, WithPageSize(50))
+
+	var scanned []int
+	keepEven := func(p *notionapi.Page) bool {
+		var n int
+		fmt.Sscanf(string(p.ID), "page-%d", &n)
+		return n%2 == 0
+	}
+	pages, err := client.SearchAllPages(context.Background(), "q", 0, keepEven, func(n int) {
+		scanned = append(scanned, n)
+	})
+	if err != nil {
+		t.Fatalf("SearchAllPages() error: %v", err)
+	}
+	if len(pages) != 60 {
+		t.Errorf("got %d pages, want 60", len(pages))
+	}
+	if want := []int{50, 100, 120}; !reflect.DeepEqual(scanned, want) {
+		t.Errorf("progress = %v, want %v", scanned, want)
+	}
+
+	// A limit stops paging early.
+	requests = nil
+	pages, err = client.SearchAllPages(context.Background(), "q", 30, keepEven, nil)
+	if err != nil {
+		t.Fatalf("SearchAllPages() error: %v", err)
+	}
+	if len(pages) != 30 || len(requests) != 2 {
+		t.Errorf("got %d pages in %d requests, want 30 in 2", len(pages), len(requests))
+	}
+}
+
 func TestDefaultConstants(t *testing.T) {
 	if DefaultRateLimit != 3 {
 		t.Errorf("DefaultRateLimit = %d, expected 3", DefaultRateLimit)
@@ -157,3 +334,150 @@ func TestDefaultConstants(t *testing.T) {
 		t.Errorf("DefaultBatchSize = %d, expected 100", DefaultBatchSize)
 	}
 }
+
+// staticResolver maps paths and databases to tokens for factory tests.
+type staticResolver struct {
+	paths     map[string]string
+	databases map[string]string
+}
+
+func (r staticResolver) TokenForPath(path string) string {
+	return r.paths[path]
+}
+
+func (r staticResolver) TokenForDatabase(databaseID string) string {
+	return r.databases[databaseID]
+}
+
+func TestFactory_ReusesClientPerToken(t *testing.T) {
+	resolver := staticResolver{
+		paths: map[string]string{
+			"work/a.md":     "work-token",
+			"work/b.md":     "work-token",
+			"personal/c.md": "personal-token",
+		},
+		databases: map[string]string{
+			"work-db": "work-token",
+		},
+	}
+	f := NewFactory(resolver, WithBatchSize(25))
+
+	workA := f.ForPath("work/a.md")
+	workB := f.ForPath("work/b.md")
+	personal := f.ForPath("personal/c.md")
+
+	if workA != workB {
+		t.Error("expected the same client for paths sharing a token")
+	}
+	if workA == personal {
+		t.Error("expected different clients for different tokens")
+	}
+	if f.ForDatabase("work-db") != workA {
+		t.Error("expected database lookup to share the work client")
+	}
+	if workA.batchSize != 25 {
+		t.Errorf("batchSize = %d, expected options to be applied", workA.batchSize)
+	}
+}
+
+// bucketResolver gives each database its own bucket.
+type bucketResolver struct {
+	staticResolver
+	buckets map[string]string
+	rates   map[string]float64
+}
+
+func (r bucketResolver) BucketForPath(path string) string {
+	return r.buckets[path]
+}
+
+func (r bucketResolver) BucketForDatabase(databaseID string) string {
+	return databaseID
+}
+
+func (r bucketResolver) BucketRate(bucket string) float64 {
+	return r.rates[bucket]
+}
+
+func TestFactory_Buckets(t *testing.T) {
+	resolver := bucketResolver{
+		staticResolver: staticResolver{
+			paths: map[string]string{
+				"tasks/a.md": "work-token",
+				"tasks/b.md": "work-token",
+				"notes/c.md": "work-token",
+			},
+			databases: map[string]string{
+				"tasks-db": "work-token",
+			},
+		},
+		buckets: map[string]string{
+			"tasks/a.md": "tasks-db",
+			"tasks/b.md": "tasks-db",
+			"notes/c.md": "notes-db",
+		},
+		rates: map[string]float64{"tasks-db": 600},
+	}
+	f := NewFactory(resolver, WithRateLimit(1000))
+
+	tasks := f.ForPath("tasks/a.md")
+	if f.ForPath("tasks/b.md") != tasks || f.ForDatabase("tasks-db") != tasks {
+		t.Error("expected the same client for a database's paths and the database")
+	}
+	notes := f.ForPath("notes/c.md")
+	if notes == tasks || notes.limiter == tasks.limiter {
+		t.Error("expected databases sharing a token to have their own clients and limiters")
+	}
+	shared := f.ForToken("work-token")
+	if shared == tasks {
+		t.Error("expected the token's shared client apart from the database buckets")
+	}
+	// The buckets keep to their rates and, together, to the token's.
+	if tasks.limiter.Limit() != 600 || notes.limiter.Limit() != 1000 {
+		t.Errorf("bucket rates = %v and %v, want 600 and the token's 1000", tasks.limiter.Limit(), notes.limiter.Limit())
+	}
+	if tasks.shared != shared.limiter || notes.shared != shared.limiter || shared.shared != nil {
+		t.Error("expected database buckets to wait for the token's shared limiter")
+	}
+
+	ctx := context.Background()
+	if err := tasks.wait(ctx); err != nil {
+		t.Fatalf("wait() error = %v", err)
+	}
+	if err := notes.wait(ctx); err != nil {
+		t.Fatalf("wait() error = %v", err)
+	}
+	if got := f.Usage(); got.Requests != 2 {
+		t.Errorf("factory usage = %+v, want the requests of every bucket", got)
+	}
+}
+
+func TestFactory_Usage(t *testing.T) {
+	resolver := staticResolver{
+		paths: map[string]string{
+			"work/a.md":     "work-token",
+			"personal/c.md": "personal-token",
+		},
+	}
+	f := NewFactory(resolver, WithRateLimit(1000))
+	ctx := context.Background()
+
+	work := f.ForPath("work/a.md")
+	personal := f.ForPath("personal/c.md")
+	for i := 0; i < 3; i++ {
+		if err := work.wait(ctx); err != nil {
+			t.Fatalf("wait() error = %v", err)
+		}
+	}
+	if err := personal.wait(ctx); err != nil {
+		t.Fatalf("wait() error = %v", err)
+	}
+	personal.blocks.Add(7)
+
+	if got := work.Usage(); got.Requests != 3 || got.Blocks != 0 {
+		t.Errorf("work usage = %+v", got)
+	}
+	if got := f.Usage(); got.Requests != 4 || got.Blocks != 7 {
+		t.Errorf("factory usage = %+v, want 4 requests and 7 blocks", got)
+	}
+}
@@ -0,0 +1,155 @@
+package notion
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/jomei/notionapi"
+)
+
+// The categories of failed Notion requests. Errors returned by the client
+// match one of them with errors.Is when Notion says why it refused.
+var (
+	// ErrNotFound means the page, block, or database does not exist or is
+	// not shared with the integration.
+	ErrNotFound = errors.New("not found in Notion")
+
+	// ErrUnauthorized means the token is invalid, or the integration
+	// lacks the capability or access the request needs.
+	ErrUnauthorized = errors.New("not authorized by Notion")
+
+	// ErrRateLimited means Notion kept refusing requests for being too
+	// frequent.
+	ErrRateLimited = errors.New("rate limited by Notion")
+
+	// ErrValidation means Notion rejected the content of the request.
+	ErrValidation = errors.New("rejected by Notion")
+
+	// ErrLocked means the page is locked against edits in Notion.
+	ErrLocked = errors.New("locked in Notion")
+)
+
+// Error is a request Notion refused. It matches its category, and the
+// error it was made from, with errors.Is and errors.As.
+type Error struct {
+	// Kind is ErrNotFound, ErrUnauthorized, ErrRateLimited, ErrValidation,
+	// ErrLocked, or nil for refusals outside the catalog, such as server errors.
+	Kind error
+
+	// Status and Code are the HTTP status and Notion error code.
+	Status int
+	Code   string
+
+	// Message is Notion's explanation.
+	Message string
+
+	// BlockPath locates the offending block of a validation error in the
+	// request, such as "children[2].paragraph.rich_text[0].text.content".
+	BlockPath string
+
+	err error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the category and the underlying error.
+func (e *Error) Unwrap() []error {
+	var errs []error
+	for _, err := range []error{e.Kind, e.err} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Category names the category of err for machine-readable output:
+// "not_found", "unauthorized", "rate_limited", "validation", "locked", or
+// "" for errors outside the catalog.
+func Category(err error) string {
+	switch {
+	case errors.Is(err, ErrLocked):
+		return "locked"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrValidation):
+		return "validation"
+	}
+	return ""
+}
+
+// blockPathRegex matches the request path a validation error names, such
+// as "body.children[2].paragraph.rich_text[0].text.content.length".
+var blockPathRegex = regexp.MustCompile(`body\.(children\[\d+\](?:\.[A-Za-z_]+|\[\d+\])*)`)
+
+// lockedRegex matches the explanation of a write refused because the page
+// is locked.
+var lockedRegex = regexp.MustCompile(`(?i)\b(page|block|database) is locked\b`)
+
+// classify returns the refusal of a notionapi request as an *Error, or err
+// unchanged if Notion did not refuse it.
+func classify(err error) error {
+	var rateErr *notionapi.RateLimitedError
+	if errors.As(err, &rateErr) {
+		return &Error{Kind: ErrRateLimited, Status: http.StatusTooManyRequests, Code: "rate_limited", Message: rateErr.Message, err: err}
+	}
+	var apiErr *notionapi.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	return newError(apiErr.Status, string(apiErr.Code), apiErr.Message, err)
+}
+
+// newError categorizes a refusal by its Notion error code, or its HTTP
+// status for codes the catalog does not know.
+func newError(status int, code, message string, err error) *Error {
+	e := &Error{Status: status, Code: code, Message: message, err: err}
+	switch {
+	case lockedRegex.MatchString(message):
+		e.Kind = ErrLocked
+	case code == "object_not_found" || status == http.StatusNotFound:
+		e.Kind = ErrNotFound
+	case code == "unauthorized" || code == "restricted_resource" ||
+		status == http.StatusUnauthorized || status == http.StatusForbidden:
+		e.Kind = ErrUnauthorized
+	case code == "rate_limited" || status == http.StatusTooManyRequests:
+		e.Kind = ErrRateLimited
+	case code == "validation_error" || code == "invalid_json" || code == "invalid_request" ||
+		status == http.StatusBadRequest:
+		e.Kind = ErrValidation
+		if m := blockPathRegex.FindStringSubmatch(message); m != nil {
+			e.BlockPath = m[1]
+		}
+	}
+	if e.err == nil {
+		e.err = errors.New(message)
+	}
+	return e
+}
+
+// blockIndexRegex matches the index of the top-level block in a block
+// path.
+var blockIndexRegex = regexp.MustCompile(`^children\[(\d+)\]`)
+
+// offsetBlockPath shifts the top-level block index of a validation error
+// by offset, the position of the request's first block in the page.
+func offsetBlockPath(err error, offset int) error {
+	var e *Error
+	if offset == 0 || !errors.As(err, &e) {
+		return err
+	}
+	if m := blockIndexRegex.FindStringSubmatch(e.BlockPath); m != nil {
+		index, _ := strconv.Atoi(m[1])
+		e.BlockPath = fmt.Sprintf("children[%d]%s", index+offset, e.BlockPath[len(m[0]):])
+	}
+	return err
+}
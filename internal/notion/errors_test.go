@@ -0,0 +1,83 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jomei/notionapi"
+)
+
+func TestErrorCatalog(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		kind     error
+		category string
+	}{
+		{"not found", http.StatusNotFound, `{"object":"error","status":404,"code":"object_not_found","message":"Could not find page with ID: page-1."}`, ErrNotFound, "not_found"},
+		{"unauthorized", http.StatusUnauthorized, `{"object":"error","status":401,"code":"unauthorized","message":"API token is invalid."}`, ErrUnauthorized, "unauthorized"},
+		{"restricted", http.StatusForbidden, `{"object":"error","status":403,"code":"restricted_resource","message":"Insufficient permissions."}`, ErrUnauthorized, "unauthorized"},
+		{"validation", http.StatusBadRequest, `{"object":"error","status":400,"code":"validation_error","message":"body failed validation."}`, ErrValidation, "validation"},
+		{"locked", http.StatusBadRequest, `{"object":"error","status":400,"code":"validation_error","message":"Page is locked."}`, ErrLocked, "locked"},
+		{"server error", http.StatusInternalServerError, `{"object":"error","status":500,"code":"internal_server_error","message":"Unexpected error."}`, nil, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			})
+			_, err := client.GetPage(context.Background(), "page-1")
+			if err == nil {
+				t.Fatal("GetPage() succeeded, want an error")
+			}
+			if tc.kind != nil && !errors.Is(err, tc.kind) {
+				t.Errorf("GetPage() error %v is not %v", err, tc.kind)
+			}
+			if got := Category(err); got != tc.category {
+				t.Errorf("Category() = %q, want %q", got, tc.category)
+			}
+			var apiErr *notionapi.Error
+			if !errors.As(err, &apiErr) {
+				t.Errorf("GetPage() error %v does not wrap the notionapi error", err)
+			}
+		})
+	}
+}
+
+func TestErrorBlockPath(t *testing.T) {
+	calls := 0
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			_, _ = w.Write([]byte(`{"object":"list","results":[{"object":"block","id":"block-1","type":"paragraph","paragraph":{"rich_text":[]}},{"object":"block","id":"block-2","type":"paragraph","paragraph":{"rich_text":[]}}]}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("{\"object\":\"error\",\"status\":400,\"code\":\"validation_error\",\"message\":\"body failed validation: body.children[0].paragraph.rich_text[0].text.content.length should be ≤ `2000`, instead was `2140`.\"}"))
+	}, WithBatchSize(2))
+
+	paragraph := func() notionapi.Block {
+		return &notionapi.ParagraphBlock{
+			BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeParagraph},
+			Paragraph:  notionapi.Paragraph{RichText: []notionapi.RichText{{Type: notionapi.ObjectTypeText, Text: &notionapi.Text{Content: "text"}}}},
+		}
+	}
+	_, err := client.AppendBlocks(context.Background(), "page-1", []notionapi.Block{paragraph(), paragraph(), paragraph()})
+
+	// The path counts blocks from the start of the page, not of the batch.
+	var e *Error
+	if !errors.As(err, &e) || !errors.Is(err, ErrValidation) {
+		t.Fatalf("AppendBlocks() error = %v, want a validation error", err)
+	}
+	if want := "children[2].paragraph.rich_text[0].text.content.length"; e.BlockPath != want {
+		t.Errorf("BlockPath = %q, want %q", e.BlockPath, want)
+	}
+	if !strings.Contains(err.Error(), "append batch 2-3") {
+		t.Errorf("error = %q, want the batch in the message", err)
+	}
+}
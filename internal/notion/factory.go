@@ -0,0 +1,146 @@
+package notion
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/jomei/notionapi"
+)
+
+// TokenResolver selects the integration token to use for an operation.
+// It allows different vault folders and databases to be served by
+// different Notion integrations.
+type TokenResolver interface {
+	// TokenForPath returns the token for an Obsidian path.
+	TokenForPath(path string) string
+
+	// TokenForDatabase returns the token for a Notion database ID.
+	TokenForDatabase(databaseID string) string
+}
+
+// BucketResolver is a TokenResolver that also splits an integration's
+// requests into rate limit buckets, such as one per database, so a busy
+// database does not starve the others.
+type BucketResolver interface {
+	TokenResolver
+
+	// BucketForPath returns the bucket for an Obsidian path, or "" for
+	// the one its token's requests share.
+	BucketForPath(path string) string
+
+	// BucketForDatabase returns the bucket for a Notion database ID, or
+	// "" for the one its token's requests share.
+	BucketForDatabase(databaseID string) string
+
+	// BucketRate returns the requests per second of a bucket, its share of
+	// its token's rate, or 0 for the token's full rate.
+	BucketRate(bucket string) float64
+}
+
+// Factory creates and caches one Client per integration token, or per
+// bucket if its resolver is a BucketResolver. Each client keeps its own
+// rate limiter, since Notion enforces rate limits per integration; the
+// clients of a token's buckets also wait for its shared bucket's limiter,
+// so together they keep to the token's rate. Factory is safe for
+// concurrent use.
+type Factory struct {
+	resolver TokenResolver
+	opts     []ClientOption
+
+	mu      sync.Mutex
+	clients map[bucketKey]*Client
+}
+
+// bucketKey identifies the client of a rate limit bucket.
+type bucketKey struct {
+	token, bucket string
+}
+
+// NewFactory creates a client factory. The options are applied to every
+// client the factory creates.
+func NewFactory(resolver TokenResolver, opts ...ClientOption) *Factory {
+	return &Factory{
+		resolver: resolver,
+		opts:     opts,
+		clients:  make(map[bucketKey]*Client),
+	}
+}
+
+// ForToken returns the client for a token's shared bucket, creating it on
+// first use.
+func (f *Factory) ForToken(token string) *Client {
+	return f.forBucket(bucketKey{token: token})
+}
+
+// ForPath returns the client for the integration bound to an Obsidian path.
+func (f *Factory) ForPath(path string) *Client {
+	key := bucketKey{token: f.resolver.TokenForPath(path)}
+	if buckets, ok := f.resolver.(BucketResolver); ok {
+		key.bucket = buckets.BucketForPath(path)
+	}
+	return f.forBucket(key)
+}
+
+// ForDatabase returns the client for the integration bound to a database.
+func (f *Factory) ForDatabase(databaseID string) *Client {
+	key := bucketKey{token: f.resolver.TokenForDatabase(databaseID)}
+	if buckets, ok := f.resolver.(BucketResolver); ok {
+		key.bucket = buckets.BucketForDatabase(databaseID)
+	}
+	return f.forBucket(key)
+}
+
+// forBucket returns the client for a bucket, creating it on first use.
+func (f *Factory) forBucket(key bucketKey) *Client {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.clientLocked(key)
+}
+
+// clientLocked returns the client for a bucket, creating it on first use.
+// A database's bucket is paced at its rate and by the limiter of its
+// token's shared bucket. f.mu must be held.
+func (f *Factory) clientLocked(key bucketKey) *Client {
+	if c, ok := f.clients[key]; ok {
+		return c
+	}
+
+	opts := f.opts
+	if key.bucket != "" {
+		shared := f.clientLocked(bucketKey{token: key.token})
+		opts = append(slices.Clip(opts), withSharedLimiter(shared.limiter))
+		if rate := f.resolver.(BucketResolver).BucketRate(key.bucket); rate > 0 {
+			opts = append(opts, WithRateLimit(rate))
+		}
+	}
+	c := New(key.token, opts...)
+	f.clients[key] = c
+	return c
+}
+
+// Usage returns the combined API traffic of every client created so far.
+func (f *Factory) Usage() Usage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var total Usage
+	for _, c := range f.clients {
+		total = total.Add(c.Usage())
+	}
+	return total
+}
+
+// Degraded returns the combined count of blocks, by type, every client
+// has pushed as placeholders for an unsupported block type.
+func (f *Factory) Degraded() map[notionapi.BlockType]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	total := make(map[notionapi.BlockType]int)
+	for _, c := range f.clients {
+		for blockType, n := range c.Degraded() {
+			total[blockType] += n
+		}
+	}
+	return total
+}
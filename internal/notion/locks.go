@@ -0,0 +1,82 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// LockedError is a write Notion refused because the page is locked. It
+// matches ErrLocked with errors.Is.
+type LockedError struct {
+	PageID string
+
+	// By is the name of the user who last edited the page, taken to be the
+	// one who locked it, or "" if it cannot be told.
+	By string
+
+	err error
+}
+
+func (e *LockedError) Error() string {
+	if e.By != "" {
+		return "page locked by " + e.By
+	}
+	return "page locked in Notion"
+}
+
+// Unwrap returns ErrLocked and the refusal of the write.
+func (e *LockedError) Unwrap() []error {
+	errs := []error{ErrLocked}
+	if e.err != nil {
+		errs = append(errs, e.err)
+	}
+	return errs
+}
+
+// pageLock is the lock state of a page, which notionapi does not decode.
+type pageLock struct {
+	IsLocked     bool `json:"is_locked"`
+	LastEditedBy struct {
+		ID string `json:"id"`
+	} `json:"last_edited_by"`
+}
+
+// CheckLock returns a *LockedError if a page is locked against edits in
+// Notion, and nil if it is not.
+func (c *Client) CheckLock(ctx context.Context, pageID string) error {
+	if err := c.wait(ctx); err != nil {
+		return fmt.Errorf("rate limit: %w", err)
+	}
+	var page pageLock
+	if err := c.send(ctx, http.MethodGet, "/pages/"+pageID, "application/json", nil, &page); err != nil {
+		return fmt.Errorf("get page: %w", err)
+	}
+	if !page.IsLocked {
+		return nil
+	}
+	locked := &LockedError{PageID: pageID}
+	if page.LastEditedBy.ID != "" {
+		locked.By, _ = c.UserName(ctx, page.LastEditedBy.ID)
+	}
+	return locked
+}
+
+// lockedError returns the refusal of a write to a page as a *LockedError
+// when the page is locked, so it is not mistaken for a problem with the
+// content or the token. Other errors are returned unchanged.
+func (c *Client) lockedError(ctx context.Context, pageID string, err error) error {
+	if !errors.Is(err, ErrLocked) && !errors.Is(err, ErrValidation) && !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+	var locked *LockedError
+	if lockErr := c.CheckLock(ctx, pageID); errors.As(lockErr, &locked) {
+		locked.err = err
+		return locked
+	}
+	if errors.Is(err, ErrLocked) {
+		return &LockedError{PageID: pageID, err: err}
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestUpdatePageTitle_Locked(t *testing.T) {
+	locked := true
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPatch:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"object":"error","status":403,"code":"restricted_resource","message":"Insufficient permissions for this endpoint."}`))
+		case r.URL.Path == "/v1/pages/page-1":
+			if locked {
+				_, _ = w.Write([]byte(`{"object":"page","id":"page-1","is_locked":true,"last_edited_by":{"object":"user","id":"user-1"}}`))
+			} else {
+				_, _ = w.Write([]byte(`{"object":"page","id":"page-1","is_locked":false}`))
+			}
+		case r.URL.Path == "/v1/users/user-1":
+			_, _ = w.Write([]byte(`{"object":"user","id":"user-1","type":"person","name":"Ada Lovelace"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	err := client.UpdatePageTitle(context.Background(), "page-1", "Title")
+	var lockErr *LockedError
+	if !errors.As(err, &lockErr) || !errors.Is(err, ErrLocked) {
+		t.Fatalf("UpdatePageTitle() error = %v, want a locked error", err)
+	}
+	if lockErr.By != "Ada Lovelace" || lockErr.Error() != "page locked by Ada Lovelace" {
+		t.Errorf("LockedError = %q (by %q)", lockErr.Error(), lockErr.By)
+	}
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("UpdatePageTitle() error %v does not keep the refusal", err)
+	}
+
+	// A refusal of an unlocked page is left as it is.
+	locked = false
+	err = client.UpdatePageTitle(context.Background(), "page-1", "Title")
+	if errors.Is(err, ErrLocked) || !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("UpdatePageTitle() of an unlocked page error = %v, want unauthorized", err)
+	}
+	if err := client.CheckLock(context.Background(), "page-1"); err != nil {
+		t.Errorf("CheckLock() of an unlocked page = %v", err)
+	}
+}
@@ -1,8 +1,12 @@
 package notion
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/jomei/notionapi"
@@ -31,17 +35,26 @@ func (c *Client) CreatePage(ctx context.Context, databaseID string, page *transf
 			DatabaseID: notionapi.DatabaseID(databaseID),
 		},
 		Properties: page.Properties,
+		Icon:       page.Icon,
+		Cover:      page.Cover,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("create page: %w", err)
+		return nil, fmt.Errorf("create page: %w", classify(err))
 	}
 
 	pageID := string(created.ID)
 
 	// Append blocks in batches.
-	if err := c.appendBlocks(ctx, pageID, page.Children); err != nil {
+	blockIDs, err := c.appendBlocks(ctx, pageID, page.Children)
+	if err != nil {
 		return &PageResult{PageID: pageID}, fmt.Errorf("append blocks: %w", err)
 	}
+	page.BlockIDs = blockIDs
+
+	// Create split sections as child pages.
+	if err := c.createSections(ctx, pageID, page.Sections); err != nil {
+		return &PageResult{PageID: pageID}, err
+	}
 
 	return &PageResult{
 		PageID:    pageID,
@@ -69,17 +82,26 @@ func (c *Client) CreatePageUnderPage(ctx context.Context, parentPageID string, p
 			PageID: notionapi.PageID(parentPageID),
 		},
 		Properties: props,
+		Icon:       page.Icon,
+		Cover:      page.Cover,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("create page: %w", err)
+		return nil, fmt.Errorf("create page: %w", classify(err))
 	}
 
 	pageID := string(created.ID)
 
 	// Append blocks in batches.
-	if err := c.appendBlocks(ctx, pageID, page.Children); err != nil {
+	blockIDs, err := c.appendBlocks(ctx, pageID, page.Children)
+	if err != nil {
 		return &PageResult{PageID: pageID}, fmt.Errorf("append blocks: %w", err)
 	}
+	page.BlockIDs = blockIDs
+
+	// Create split sections as child pages.
+	if err := c.createSections(ctx, pageID, page.Sections); err != nil {
+		return &PageResult{PageID: pageID}, err
+	}
 
 	return &PageResult{
 		PageID:    pageID,
@@ -91,10 +113,17 @@ func (c *Client) CreatePageUnderPage(ctx context.Context, parentPageID string, p
 
 // UpdatePage updates an existing page's properties and replaces all blocks.
 func (c *Client) UpdatePage(ctx context.Context, pageID string, page *transformer.NotionPage) error {
+	_, err := c.ReplacePage(ctx, pageID, page)
+	return err
+}
+
+// ReplacePage updates a page like UpdatePage and returns the page as it was
+// before, with the properties and blocks that were replaced.
+func (c *Client) ReplacePage(ctx context.Context, pageID string, page *transformer.NotionPage) (*transformer.NotionPage, error) {
 	// 1. Fetch existing page to determine parent type.
 	existingPage, err := c.GetPage(ctx, pageID)
 	if err != nil {
-		return fmt.Errorf("get existing page: %w", err)
+		return nil, fmt.Errorf("get existing page: %w", err)
 	}
 
 	// 2. Prepare properties - remap if page is under a parent page (not database).
@@ -110,27 +139,39 @@ func (c *Client) UpdatePage(ctx context.Context, pageID string, page *transforme
 
 	// 3. Update properties.
 	if err := c.wait(ctx); err != nil {
-		return fmt.Errorf("rate limit: %w", err)
+		return nil, fmt.Errorf("rate limit: %w", err)
 	}
 
 	_, err = c.api.Page.Update(ctx, notionapi.PageID(pageID), &notionapi.PageUpdateRequest{
 		Properties: props,
 	})
 	if err != nil {
-		return fmt.Errorf("update properties: %w", err)
+		return nil, fmt.Errorf("update properties: %w", c.lockedError(ctx, pageID, classify(err)))
 	}
 
 	// 4. Delete existing blocks.
-	if err := c.deleteAllBlocks(ctx, pageID); err != nil {
-		return fmt.Errorf("delete blocks: %w", err)
+	previous, err := c.deleteAllBlocks(ctx, pageID)
+	if err != nil {
+		return nil, fmt.Errorf("delete blocks: %w", err)
 	}
 
 	// 5. Append new blocks.
-	if err := c.appendBlocks(ctx, pageID, page.Children); err != nil {
-		return fmt.Errorf("append blocks: %w", err)
+	blockIDs, err := c.appendBlocks(ctx, pageID, page.Children)
+	if err != nil {
+		return nil, fmt.Errorf("append blocks: %w", err)
 	}
+	page.BlockIDs = blockIDs
 
-	return nil
+	// 6. Recreate split sections. Deleting the old blocks above also
+	// archived the previous section pages.
+	if err := c.createSections(ctx, pageID, page.Sections); err != nil {
+		return nil, err
+	}
+
+	return &transformer.NotionPage{
+		Properties: existingPage.Properties,
+		Children:   previous,
+	}, nil
 }
 
 // GetPage retrieves a page by ID.
@@ -141,7 +182,7 @@ func (c *Client) GetPage(ctx context.Context, pageID string) (*notionapi.Page, e
 
 	page, err := c.api.Page.Get(ctx, notionapi.PageID(pageID))
 	if err != nil {
-		return nil, fmt.Errorf("get page: %w", err)
+		return nil, fmt.Errorf("get page: %w", classify(err))
 	}
 
 	return page, nil
@@ -162,8 +203,10 @@ func (c *Client) FetchPage(ctx context.Context, pageID string) (*transformer.Not
 	}
 
 	return &transformer.NotionPage{
-		Properties: page.Properties,
-		Children:   blocks,
+		Properties:     page.Properties,
+		Children:       blocks,
+		CreatedTime:    page.CreatedTime,
+		LastEditedTime: page.LastEditedTime,
 	}, nil
 }
 
@@ -181,7 +224,7 @@ func (c *Client) ArchivePage(ctx context.Context, pageID string) error {
 		Archived:   true,
 	})
 	if err != nil {
-		return fmt.Errorf("archive page: %w", err)
+		return fmt.Errorf("archive page: %w", classify(err))
 	}
 
 	return nil
@@ -196,6 +239,23 @@ func (c *Client) DeletePage(ctx context.Context, pageID string) error {
 	return c.ArchivePage(ctx, pageID)
 }
 
+// RestorePage moves an archived page out of Notion's trash.
+func (c *Client) RestorePage(ctx context.Context, pageID string) error {
+	if err := c.wait(ctx); err != nil {
+		return fmt.Errorf("rate limit: %w", err)
+	}
+
+	_, err := c.api.Page.Update(ctx, notionapi.PageID(pageID), &notionapi.PageUpdateRequest{
+		Properties: notionapi.Properties{},
+		Archived:   false,
+	})
+	if err != nil {
+		return fmt.Errorf("restore page: %w", classify(err))
+	}
+
+	return nil
+}
+
 // UpdatePageTitle updates the title property of a page.
 func (c *Client) UpdatePageTitle(ctx context.Context, pageID string, title string) error {
 	if err := c.wait(ctx); err != nil {
@@ -222,48 +282,277 @@ func (c *Client) UpdatePageTitle(ctx context.Context, pageID string, title strin
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("update page title: %w", err)
+		return fmt.Errorf("update page title: %w", c.lockedError(ctx, pageID, classify(err)))
+	}
+
+	return nil
+}
+
+// UpdatePageProperties sets the given properties of a page, leaving its
+// other properties and its blocks alone.
+func (c *Client) UpdatePageProperties(ctx context.Context, pageID string, props notionapi.Properties) error {
+	if err := c.wait(ctx); err != nil {
+		return fmt.Errorf("rate limit: %w", err)
+	}
+
+	_, err := c.api.Page.Update(ctx, notionapi.PageID(pageID), &notionapi.PageUpdateRequest{
+		Properties: props,
+	})
+	if err != nil {
+		return fmt.Errorf("update page properties: %w", c.lockedError(ctx, pageID, classify(err)))
+	}
+
+	return nil
+}
+
+// MovePage moves a page into another database. The page keeps its ID,
+// properties, and content, so mentions of it stay valid.
+func (c *Client) MovePage(ctx context.Context, pageID, databaseID string) error {
+	body, err := json.Marshal(map[string]notionapi.Parent{
+		"parent": {
+			Type:       notionapi.ParentTypeDatabaseID,
+			DatabaseID: notionapi.DatabaseID(databaseID),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal move request: %w", err)
+	}
+
+	if err := c.do(ctx, "/pages/"+pageID+"/move", "application/json", bytes.NewReader(body), nil); err != nil {
+		return fmt.Errorf("move page: %w", err)
+	}
+
+	return nil
+}
+
+// createSections creates one child page per section under parentID and
+// records each new page ID in its section.
+func (c *Client) createSections(ctx context.Context, parentID string, sections []*transformer.PageSection) error {
+	for _, section := range sections {
+		result, err := c.CreatePageUnderPage(ctx, parentID, &transformer.NotionPage{
+			Properties: notionapi.Properties{
+				"title": notionapi.TitleProperty{
+					Title: []notionapi.RichText{{
+						Type: notionapi.ObjectTypeText,
+						Text: &notionapi.Text{Content: section.Title},
+					}},
+				},
+			},
+			Children: section.Children,
+		})
+		if err != nil {
+			return fmt.Errorf("create section %q: %w", section.Title, err)
+		}
+		section.PageID = result.PageID
+	}
+	return nil
+}
+
+// FetchSections loads the split sections of a fetched page, given the
+// sections recorded when it was pushed. Their child_page blocks are removed
+// from page.Children and their content is stored in page.Sections, in the
+// recorded order. Titles edited in Notion take precedence.
+func (c *Client) FetchSections(ctx context.Context, page *transformer.NotionPage, known []*transformer.PageSection) error {
+	titles := make(map[string]string, len(known))
+	for _, s := range known {
+		titles[normalizeID(s.PageID)] = s.Title
+	}
+
+	children := page.Children[:0]
+	for _, block := range page.Children {
+		if cp, ok := block.(*notionapi.ChildPageBlock); ok {
+			id := normalizeID(string(cp.ID))
+			if _, isSection := titles[id]; isSection {
+				if cp.ChildPage.Title != "" {
+					titles[id] = cp.ChildPage.Title
+				}
+				continue
+			}
+		}
+		children = append(children, block)
+	}
+	page.Children = children
+
+	for _, s := range known {
+		blocks, err := c.GetAllBlocks(ctx, s.PageID)
+		if err != nil {
+			return fmt.Errorf("fetch section %q: %w", s.Title, err)
+		}
+		page.Sections = append(page.Sections, &transformer.PageSection{
+			Title:    titles[normalizeID(s.PageID)],
+			PageID:   s.PageID,
+			Children: blocks,
+		})
 	}
 
 	return nil
 }
 
-// appendBlocks appends blocks to a page in batches.
-func (c *Client) appendBlocks(ctx context.Context, pageID string, blocks []notionapi.Block) error {
+// normalizeID strips dashes so IDs compare equal in either format.
+func normalizeID(id string) string {
+	return strings.ReplaceAll(id, "-", "")
+}
+
+// ParsePageID returns the page ID, in the dashed form the API uses, of a
+// page ID or a Notion page URL such as
+// https://www.notion.so/workspace/Title-<id> or a link opening the page in
+// a side peek (?p=<id>). It reports false if ref holds no page ID.
+func ParsePageID(ref string) (string, bool) {
+	candidate := strings.TrimSpace(ref)
+	if u, err := url.Parse(candidate); err == nil && u.Host != "" {
+		candidate = u.Path[strings.LastIndex(u.Path, "/")+1:]
+		if peek := u.Query().Get("p"); peek != "" {
+			candidate = peek
+		}
+	}
+
+	id := normalizeID(candidate)
+	if len(id) < 32 {
+		return "", false
+	}
+	id = strings.ToLower(id[len(id)-32:])
+	for _, c := range id {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f')) {
+			return "", false
+		}
+	}
+	return id[0:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:], true
+}
+
+// maxAppendDepth is how many levels of children the Notion API accepts
+// below the blocks of one append request.
+const maxAppendDepth = 2
+
+// appendBlocks appends blocks to a page in batches and returns the IDs of
+// the created blocks, in order. Blocks nested deeper than one request
+// accepts are appended without their children, which are then appended to
+// the created block.
+func (c *Client) appendBlocks(ctx context.Context, pageID string, blocks []notionapi.Block) ([]string, error) {
+	var ids []string
 	for i := 0; i < len(blocks); i += c.batchSize {
 		end := i + c.batchSize
 		if end > len(blocks) {
 			end = len(blocks)
 		}
-		batch := blocks[i:end]
+		batch := c.degradeBlocks(blocks[i:end])
+		deferred := deferDeepChildren(batch)
+		original := blocks[i:end]
+
+		var resp *notionapi.AppendBlockChildrenResponse
+		for {
+			if err := c.wait(ctx); err != nil {
+				restoreDeepChildren(original, deferred)
+				return nil, fmt.Errorf("rate limit: %w", err)
+			}
+
+			var err error
+			resp, err = c.api.Block.AppendChildren(ctx, notionapi.BlockID(pageID), &notionapi.AppendBlockChildrenRequest{
+				Children: batch,
+			})
+			if err == nil {
+				break
+			}
+			// A block type the API version rejects becomes a placeholder
+			// instead of failing the page.
+			if !c.markRejected(err, batch) {
+				restoreDeepChildren(original, deferred)
+				return nil, fmt.Errorf("append batch %d-%d: %w", i, end, offsetBlockPath(classify(err), i))
+			}
+			batch = c.degradeBlocks(batch)
+		}
+		restoreDeepChildren(original, deferred)
+		c.blocks.Add(int64(len(batch)))
+		for _, created := range resp.Results {
+			ids = append(ids, string(created.GetID()))
+		}
 
-		if err := c.wait(ctx); err != nil {
-			return fmt.Errorf("rate limit: %w", err)
+		for _, d := range deferred {
+			if batch[d.index] != original[d.index] {
+				continue // Replaced by a placeholder.
+			}
+			if d.index >= len(resp.Results) {
+				return nil, fmt.Errorf("append batch %d-%d: no block created for nested children", i, end)
+			}
+			if _, err := c.appendBlocks(ctx, getBlockID(resp.Results[d.index]), d.children); err != nil {
+				return nil, fmt.Errorf("append nested children: %w", err)
+			}
 		}
+	}
 
-		_, err := c.api.Block.AppendChildren(ctx, notionapi.BlockID(pageID), &notionapi.AppendBlockChildrenRequest{
-			Children: batch,
-		})
-		if err != nil {
-			return fmt.Errorf("append batch %d-%d: %w", i, end, err)
+	return ids, nil
+}
+
+// deferredChildren are children held back from an append request, to be
+// appended to the block at index once it exists.
+type deferredChildren struct {
+	index    int
+	children []notionapi.Block
+}
+
+// deferDeepChildren removes the children of blocks nested deeper than one
+// append request accepts and returns them. Tables and column lists must
+// be created with their children and are left alone.
+func deferDeepChildren(blocks []notionapi.Block) []deferredChildren {
+	var deferred []deferredChildren
+	for i, block := range blocks {
+		if deferrable(block) {
+			deferred = append(deferred, deferredChildren{index: i, children: blockChildren(block)})
+			setBlockChildren(block, nil)
 		}
 	}
+	return deferred
+}
 
-	return nil
+// deferrable reports whether a block is nested too deeply for one append
+// request and its children can be appended after it is created.
+func deferrable(block notionapi.Block) bool {
+	switch block.(type) {
+	case *notionapi.TableBlock, *notionapi.ColumnListBlock:
+		return false
+	}
+	return nestingDepth(block) > maxAppendDepth
 }
 
-// deleteAllBlocks deletes all children blocks of a page.
-func (c *Client) deleteAllBlocks(ctx context.Context, pageID string) error {
+// restoreDeepChildren puts deferred children back on their blocks, so the
+// caller's blocks are left as they were passed in.
+func restoreDeepChildren(blocks []notionapi.Block, deferred []deferredChildren) {
+	for _, d := range deferred {
+		setBlockChildren(blocks[d.index], d.children)
+	}
+}
+
+// nestingDepth returns how many levels of children are below a block.
+func nestingDepth(block notionapi.Block) int {
+	if table, ok := block.(*notionapi.TableBlock); ok && len(table.Table.Children) > 0 {
+		return 1
+	}
+	depth := 0
+	for _, child := range blockChildren(block) {
+		depth = max(depth, nestingDepth(child)+1)
+	}
+	return depth
+}
+
+// deleteAllBlocks deletes all children blocks of a page and returns them.
+// Databases nested in the page are kept, since their rows are notes of
+// their own.
+func (c *Client) deleteAllBlocks(ctx context.Context, pageID string) ([]notionapi.Block, error) {
 	// Get all block IDs first.
-	blocks, err := c.GetAllBlocks(ctx, pageID)
+	all, err := c.GetAllBlocks(ctx, pageID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Delete each block.
-	for _, block := range blocks {
+	var blocks []notionapi.Block
+	for _, block := range all {
+		if _, ok := block.(*notionapi.ChildDatabaseBlock); ok {
+			continue
+		}
+		blocks = append(blocks, block)
+
 		if err := c.wait(ctx); err != nil {
-			return fmt.Errorf("rate limit: %w", err)
+			return nil, fmt.Errorf("rate limit: %w", err)
 		}
 
 		// Get block ID from the block interface.
@@ -274,11 +563,11 @@ func (c *Client) deleteAllBlocks(ctx context.Context, pageID string) error {
 
 		_, err := c.api.Block.Delete(ctx, notionapi.BlockID(blockID))
 		if err != nil {
-			return fmt.Errorf("delete block %s: %w", blockID, err)
+			return nil, fmt.Errorf("delete block %s: %w", blockID, classify(err))
 		}
 	}
 
-	return nil
+	return blocks, nil
 }
 
 // getBlockID extracts the block ID from a notionapi.Block interface.
@@ -316,6 +605,27 @@ func remapTitlePropertyForPage(props notionapi.Properties) notionapi.Properties
 	return result
 }
 
+// PageTitle returns the plain text of a page's title property.
+func PageTitle(page *notionapi.Page) string {
+	for _, value := range page.Properties {
+		var title []notionapi.RichText
+		switch v := value.(type) {
+		case *notionapi.TitleProperty:
+			title = v.Title
+		case notionapi.TitleProperty:
+			title = v.Title
+		default:
+			continue
+		}
+		var sb strings.Builder
+		for _, rt := range title {
+			sb.WriteString(rt.PlainText)
+		}
+		return sb.String()
+	}
+	return ""
+}
+
 // PageMetadata contains lightweight page information for change detection.
 // This avoids fetching full page content when only timestamps are needed.
 type PageMetadata struct {
@@ -334,7 +644,7 @@ func (c *Client) GetPageMetadata(ctx context.Context, pageID string) (*PageMetad
 
 	page, err := c.api.Page.Get(ctx, notionapi.PageID(pageID))
 	if err != nil {
-		return nil, fmt.Errorf("get page metadata: %w", err)
+		return nil, fmt.Errorf("get page metadata: %w", classify(err))
 	}
 
 	return &PageMetadata{
@@ -1,10 +1,20 @@
 package notion
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
 )
 
 func TestPageResult(t *testing.T) {
@@ -244,3 +254,153 @@ func TestPageMetadata_ZeroTime(t *testing.T) {
 		t.Error("CreatedTime should be zero")
 	}
 }
+
+func TestMovePage(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		_, _ = io.WriteString(w, `{"object":"page","id":"page-1"}`)
+	}))
+	defer server.Close()
+
+	client := New("test-token", WithRateLimit(1000))
+	client.baseURL = server.URL
+
+	if err := client.MovePage(context.Background(), "page-1", "db-public"); err != nil {
+		t.Fatalf("MovePage() error: %v", err)
+	}
+	if gotPath != "/pages/page-1/move" {
+		t.Errorf("path = %q; want /pages/page-1/move", gotPath)
+	}
+	parent := gotBody["parent"]
+	if parent["type"] != "database_id" || parent["database_id"] != "db-public" {
+		t.Errorf("unexpected parent: %v", parent)
+	}
+}
+
+func TestAppendBlocks_DeepNesting(t *testing.T) {
+	type appendRequest struct {
+		parent string
+		body   string
+	}
+	var requests []appendRequest
+	created := 0
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Children []json.RawMessage `json:"children"`
+		}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		requests = append(requests, appendRequest{parent: strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/blocks/"), "/children"), body: string(data)})
+
+		var results []string
+		for range req.Children {
+			created++
+			results = append(results, fmt.Sprintf(`{"object":"block","id":"block-%d","type":"paragraph","paragraph":{"rich_text":[]}}`, created))
+		}
+		fmt.Fprintf(w, `{"object":"list","results":[%s]}`, strings.Join(results, ","))
+	})
+
+	item := func(text string, children ...notionapi.Block) notionapi.Block {
+		return &notionapi.BulletedListItemBlock{
+			BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeBulletedListItem},
+			BulletedListItem: notionapi.ListItem{
+				RichText: []notionapi.RichText{{Type: notionapi.ObjectTypeText, Text: &notionapi.Text{Content: text}}},
+				Children: children,
+			},
+		}
+	}
+	quote := &notionapi.QuoteBlock{
+		BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockQuote},
+		Quote: notionapi.Quote{
+			RichText: []notionapi.RichText{{Type: notionapi.ObjectTypeText, Text: &notionapi.Text{Content: "Quote"}}},
+			Children: []notionapi.Block{item("one", item("two", item("three")))},
+		},
+	}
+	shallow := item("shallow", item("nested"))
+
+	if _, err := client.AppendBlocks(context.Background(), "page-1", []notionapi.Block{shallow, quote}); err != nil {
+		t.Fatalf("AppendBlocks() error: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want the page blocks and the quote's children", len(requests))
+	}
+	if requests[0].parent != "page-1" || strings.Contains(requests[0].body, `"one"`) || !strings.Contains(requests[0].body, `"nested"`) {
+		t.Errorf("first request to %s = %s; want the quote without its children", requests[0].parent, requests[0].body)
+	}
+	if requests[1].parent != "block-2" || !strings.Contains(requests[1].body, `"three"`) {
+		t.Errorf("second request to %s = %s; want the quote's children under block-2", requests[1].parent, requests[1].body)
+	}
+	if len(quote.Quote.Children) != 1 {
+		t.Errorf("quote children were not restored: %v", quote.Quote.Children)
+	}
+}
+
+func TestCreatePage_BlockIDs(t *testing.T) {
+	created := 0
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/pages" {
+			fmt.Fprint(w, `{"object":"page","id":"page-1","properties":{}}`)
+			return
+		}
+		var req struct {
+			Children []json.RawMessage `json:"children"`
+		}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		var results []string
+		for range req.Children {
+			created++
+			results = append(results, fmt.Sprintf(`{"object":"block","id":"block-%d","type":"paragraph","paragraph":{"rich_text":[]}}`, created))
+		}
+		fmt.Fprintf(w, `{"object":"list","results":[%s]}`, strings.Join(results, ","))
+	}, WithBatchSize(2))
+
+	paragraph := func() notionapi.Block {
+		return &notionapi.ParagraphBlock{
+			BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeParagraph},
+		}
+	}
+	page := &transformer.NotionPage{Children: []notionapi.Block{paragraph(), paragraph(), paragraph()}}
+	if _, err := client.CreatePage(context.Background(), "db-1", page); err != nil {
+		t.Fatalf("CreatePage() error: %v", err)
+	}
+	want := []string{"block-1", "block-2", "block-3"}
+	if !reflect.DeepEqual(page.BlockIDs, want) {
+		t.Errorf("BlockIDs = %v, want %v", page.BlockIDs, want)
+	}
+}
+
+func TestParsePageID(t *testing.T) {
+	const want = "0123abcd-4567-89ab-cdef-0123456789ab"
+	tests := []struct {
+		ref  string
+		want string
+		ok   bool
+	}{
+		{"0123abcd456789abcdef0123456789ab", want, true},
+		{want, want, true},
+		{"0123ABCD456789ABCDEF0123456789AB", want, true},
+		{"https://www.notion.so/team/Meeting-Notes-0123abcd456789abcdef0123456789ab", want, true},
+		{"https://www.notion.so/0123abcd456789abcdef0123456789ab?pvs=4", want, true},
+		{"https://team.notion.site/Shared-Page-0123abcd456789abcdef0123456789ab#heading", want, true},
+		{"https://www.notion.so/team/ffffffffffffffffffffffffffffffff?v=1&p=0123abcd456789abcdef0123456789ab", want, true},
+		{"notion.so/Meeting-Notes-0123abcd456789abcdef0123456789ab", want, true},
+		{"Meeting Notes", "", false},
+		{"https://www.notion.so/team/Meeting-Notes", "", false},
+		{"0123abcd456789abcdef0123456789zz", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParsePageID(tt.ref)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParsePageID(%q) = %q, %v; want %q, %v", tt.ref, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+// Template is a page new pages are created from, read so that its icon,
+// cover, and blocks can be created again on another page.
+type Template struct {
+	Icon     *notionapi.Icon
+	Cover    *notionapi.Image
+	Children []notionapi.Block
+
+	// Skipped are the types of the template's blocks the API cannot
+	// create, such as databases, child pages, and files uploaded to
+	// Notion, which new pages go without.
+	Skipped []string
+}
+
+// FetchTemplate reads a template page.
+func (c *Client) FetchTemplate(ctx context.Context, pageID string) (*Template, error) {
+	page, err := c.GetPage(ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := c.GetAllBlocks(ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Template{}
+	if page.Icon != nil && page.Icon.Type != notionapi.FileTypeFile {
+		t.Icon = page.Icon
+	}
+	if page.Cover != nil {
+		if page.Cover.Type == notionapi.FileTypeFile {
+			t.Skipped = append(t.Skipped, "cover")
+		} else {
+			t.Cover = page.Cover
+		}
+	}
+	t.Children, err = c.templateBlocks(ctx, blocks, &t.Skipped)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// templateBlocks returns copies of fetched blocks without their IDs and
+// other read-only fields, leaving out blocks the API cannot create and
+// recording their types in skipped. Table rows, which GetAllBlocks does
+// not fetch, are fetched here.
+func (c *Client) templateBlocks(ctx context.Context, blocks []notionapi.Block, skipped *[]string) ([]notionapi.Block, error) {
+	var out []notionapi.Block
+	for _, block := range blocks {
+		if !creatable(block) {
+			*skipped = append(*skipped, string(block.GetType()))
+			continue
+		}
+		if table, ok := block.(*notionapi.TableBlock); ok && table.HasChildren {
+			rows, err := c.GetAllBlocks(ctx, string(table.ID))
+			if err != nil {
+				return nil, fmt.Errorf("get table rows: %w", err)
+			}
+			for _, row := range rows {
+				clearBlockFields(row)
+			}
+			table.Table.Children = rows
+		}
+		children, err := c.templateBlocks(ctx, blockChildren(block), skipped)
+		if err != nil {
+			return nil, err
+		}
+		if len(children) > 0 || len(blockChildren(block)) > 0 {
+			setBlockChildren(block, children)
+		}
+		clearBlockFields(block)
+		out = append(out, block)
+	}
+	return out, nil
+}
+
+// creatable reports whether the API can create a copy of a fetched block.
+func creatable(block notionapi.Block) bool {
+	switch b := block.(type) {
+	case *notionapi.ChildPageBlock, *notionapi.ChildDatabaseBlock, *notionapi.LinkPreviewBlock, *notionapi.UnsupportedBlock:
+		return false
+	case *notionapi.ImageBlock:
+		return b.Image.Type != notionapi.FileTypeFile
+	case *notionapi.FileBlock:
+		return b.File.Type != notionapi.FileTypeFile
+	case *notionapi.PdfBlock:
+		return b.Pdf.Type != notionapi.FileTypeFile
+	case *notionapi.VideoBlock:
+		return b.Video.Type != notionapi.FileTypeFile
+	case *notionapi.AudioBlock:
+		return b.Audio.Type != notionapi.FileTypeFile
+	case *notionapi.TableBlock:
+		return b.HasChildren || len(b.Table.Children) > 0
+	}
+	return true
+}
+
+// clearBlockFields resets the fields of a fetched block that a request
+// to create it must not carry, keeping its type.
+func clearBlockFields(block notionapi.Block) {
+	v := reflect.ValueOf(block)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	basic := v.Elem().FieldByName("BasicBlock")
+	if !basic.IsValid() || !basic.CanSet() {
+		return
+	}
+	basic.Set(reflect.ValueOf(notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: block.GetType()}))
+}
+
+// Apply puts a note's page in the template: the page gets the template's
+// icon and cover, and its blocks replace the template's top-level
+// paragraph whose text is marker, or follow the template's blocks if it
+// has none. It returns the template's blocks before and after the note's.
+// Each page gets its own copies of the template's blocks.
+func (t *Template) Apply(page *transformer.NotionPage, marker string) (before, after []notionapi.Block, err error) {
+	blocks, err := copyBlocks(t.Children)
+	if err != nil {
+		return nil, nil, fmt.Errorf("copy template: %w", err)
+	}
+	before = blocks
+	for i, block := range blocks {
+		if p, ok := block.(*notionapi.ParagraphBlock); ok && strings.TrimSpace(plainText(p.Paragraph.RichText)) == marker {
+			before, after = blocks[:i], blocks[i+1:]
+			break
+		}
+	}
+
+	children := make([]notionapi.Block, 0, len(before)+len(page.Children)+len(after))
+	children = append(children, before...)
+	children = append(children, page.Children...)
+	children = append(children, after...)
+	page.Children = children
+	if page.Icon == nil {
+		page.Icon = t.Icon
+	}
+	if page.Cover == nil {
+		page.Cover = t.Cover
+	}
+	return before, after, nil
+}
+
+// copyBlocks returns deep copies of blocks.
+func copyBlocks(blocks []notionapi.Block) ([]notionapi.Block, error) {
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return nil, err
+	}
+	var copies notionapi.Blocks
+	if err := json.Unmarshal(data, &copies); err != nil {
+		return nil, err
+	}
+	return copies, nil
+}
+
+// plainText returns the text of rich text without its formatting.
+func plainText(rts []notionapi.RichText) string {
+	var b strings.Builder
+	for _, rt := range rts {
+		b.WriteString(rt.PlainText)
+	}
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package notion
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+func TestFetchTemplate(t *testing.T) {
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/pages/tmpl":
+			_, _ = w.Write([]byte(`{"object":"page","id":"tmpl","icon":{"type":"emoji","emoji":"📋"},` +
+				`"cover":{"type":"file","file":{"url":"https://files.example.com/banner.png"}},"properties":{}}`))
+		case "/v1/blocks/tmpl/children":
+			_, _ = w.Write([]byte(`{"object":"list","has_more":false,"results":[` +
+				`{"object":"block","id":"b1","type":"heading_1","heading_1":{"rich_text":[{"type":"text","text":{"content":"Summary"},"plain_text":"Summary"}]}},` +
+				`{"object":"block","id":"b2","type":"paragraph","paragraph":{"rich_text":[{"type":"text","text":{"content":"{{content}}"},"plain_text":"{{content}}"}]}},` +
+				`{"object":"block","id":"b3","type":"child_database","child_database":{"title":"Tasks"}},` +
+				`{"object":"block","id":"b4","type":"image","image":{"type":"file","file":{"url":"https://files.example.com/a.png"}}},` +
+				`{"object":"block","id":"b5","type":"heading_1","heading_1":{"rich_text":[{"type":"text","text":{"content":"Follow-ups"},"plain_text":"Follow-ups"}]}}` +
+				`]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"object":"error","status":404,"code":"object_not_found","message":"not found"}`))
+		}
+	})
+
+	tmpl, err := client.FetchTemplate(context.Background(), "tmpl")
+	if err != nil {
+		t.Fatalf("FetchTemplate() error: %v", err)
+	}
+	if tmpl.Icon == nil || tmpl.Icon.Emoji == nil || *tmpl.Icon.Emoji != "📋" {
+		t.Errorf("Icon = %+v", tmpl.Icon)
+	}
+	if tmpl.Cover != nil {
+		t.Errorf("Cover = %+v, want none for a file uploaded to Notion", tmpl.Cover)
+	}
+	if want := []string{"cover", "child_database", "image"}; len(tmpl.Skipped) != len(want) || tmpl.Skipped[0] != want[0] || tmpl.Skipped[1] != want[1] || tmpl.Skipped[2] != want[2] {
+		t.Errorf("Skipped = %v, want %v", tmpl.Skipped, want)
+	}
+	if len(tmpl.Children) != 3 {
+		t.Fatalf("got %d blocks, want 3", len(tmpl.Children))
+	}
+	for _, block := range tmpl.Children {
+		if block.GetID() != "" {
+			t.Errorf("block %s kept its ID %s", block.GetType(), block.GetID())
+		}
+	}
+
+	page := &transformer.NotionPage{Children: []notionapi.Block{
+		&notionapi.ParagraphBlock{
+			BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeParagraph},
+			Paragraph:  notionapi.Paragraph{RichText: []notionapi.RichText{{PlainText: "Note."}}},
+		},
+	}}
+	before, after, err := tmpl.Apply(page, "{{content}}")
+	if err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+	if len(before) != 1 || len(after) != 1 || len(page.Children) != 3 {
+		t.Fatalf("Apply() = %d before, %d after, %d blocks; want 1, 1, 3", len(before), len(after), len(page.Children))
+	}
+	if p, ok := page.Children[1].(*notionapi.ParagraphBlock); !ok || plainText(p.Paragraph.RichText) != "Note." {
+		t.Errorf("note block = %#v, want the note's paragraph in place of the marker", page.Children[1])
+	}
+	if page.Icon != tmpl.Icon {
+		t.Errorf("page icon = %+v, want the template's", page.Icon)
+	}
+	if before[0] == tmpl.Children[0] {
+		t.Error("Apply() shares the template's blocks with the page")
+	}
+
+	// Without the marker, the note follows the template.
+	page = &transformer.NotionPage{}
+	before, after, _ = tmpl.Apply(page, "{{body}}")
+	if len(before) != 3 || len(after) != 0 {
+		t.Errorf("Apply() without marker = %d before, %d after; want 3, 0", len(before), len(after))
+	}
+}
@@ -0,0 +1,128 @@
+package notion
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithTrace logs every HTTP request the client makes to w: its method,
+// path, status, latency, and how many times it was retried after Notion
+// limited it. Tokens and signed URL parameters are redacted.
+func WithTrace(w io.Writer) ClientOption {
+	return func(c *Client) {
+		c.trace = w
+	}
+}
+
+// tracingTransport logs the requests it carries.
+type tracingTransport struct {
+	next http.RoundTripper
+
+	mu  sync.Mutex
+	out io.Writer
+
+	// limited counts the consecutive 429 responses to each request, by
+	// method and URL, to number the retries.
+	limited map[string]int
+}
+
+// traceHTTPClient returns a copy of client whose requests are logged to w.
+func traceHTTPClient(client *http.Client, w io.Writer) *http.Client {
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	traced := *client
+	traced.Transport = &tracingTransport{next: next, out: w, limited: make(map[string]int)}
+	return &traced
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.Method + " " + req.URL.String()
+	t.mu.Lock()
+	retry := t.limited[key]
+	t.mu.Unlock()
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	latency := time.Since(start).Round(time.Millisecond)
+
+	var status string
+	if err != nil {
+		status = traceError(err)
+	} else {
+		status = resp.Status
+	}
+	line := fmt.Sprintf("trace: %s %s %s %s", req.Method, redactURL(req.URL), status, latency)
+	if retry > 0 {
+		line += fmt.Sprintf(" retry=%d", retry)
+	}
+	if token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "); token != "" {
+		line += " token=" + redactToken(token)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		t.limited[key] = retry + 1
+	} else {
+		delete(t.limited, key)
+	}
+	fmt.Fprintln(t.out, line)
+	return resp, err
+}
+
+// redactToken shortens a token to its prefix and last four characters,
+// enough to tell integrations apart.
+func redactToken(token string) string {
+	if len(token) < 16 {
+		return "[redacted]"
+	}
+	prefix, _, ok := strings.Cut(token, "_")
+	if !ok || len(prefix) > 8 {
+		prefix = ""
+	} else {
+		prefix += "_"
+	}
+	return prefix + "..." + token[len(token)-4:]
+}
+
+// sensitiveParams are substrings of the names of query parameters whose
+// values are redacted, such as the signatures of file upload URLs.
+var sensitiveParams = []string{"token", "signature", "sig", "credential", "key", "secret"}
+
+// redactURL returns the host and path of a URL with the values of
+// sensitive query parameters redacted.
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	for name := range query {
+		lower := strings.ToLower(name)
+		for _, s := range sensitiveParams {
+			if strings.Contains(lower, s) {
+				query[name] = []string{"REDACTED"}
+				break
+			}
+		}
+	}
+	redacted := u.Host + u.Path
+	if len(query) > 0 {
+		redacted += "?" + query.Encode()
+	}
+	return redacted
+}
+
+// traceError describes a failed request without its URL, which is
+// already logged and may carry signatures.
+func traceError(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	return "error: " + err.Error()
+}
@@ -0,0 +1,63 @@
+package notion
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWithTrace(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"object":"error","status":429,"code":"rate_limited","message":"slow down"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"object":"page","id":"page-1","created_time":"2024-01-01T00:00:00Z","last_edited_time":"2024-01-01T00:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	var out strings.Builder
+	const token = "secret_abcdefghijklmnopqrstuvwxyz1234"
+	client := New(token,
+		WithRateLimit(1000),
+		WithTrace(&out),
+		WithHTTPClient(&http.Client{Transport: mockTransport{target}}),
+	)
+
+	if _, err := client.GetPage(context.Background(), "page-1"); err != nil {
+		t.Fatalf("GetPage() error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d trace lines, want 2:\n%s", len(lines), out.String())
+	}
+	for i, want := range []string{"429 Too Many Requests", "200 OK"} {
+		if !strings.HasPrefix(lines[i], "trace: GET api.notion.com/v1/pages/page-1 "+want) {
+			t.Errorf("line %d = %q, want GET of the page with %s", i, lines[i], want)
+		}
+	}
+	if strings.Contains(lines[0], "retry=") || !strings.Contains(lines[1], "retry=1") {
+		t.Errorf("retries not counted:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), token) || !strings.Contains(lines[0], "token=secret_...1234") {
+		t.Errorf("token not redacted:\n%s", out.String())
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	u, _ := url.Parse("https://files.example.com/upload/a.png?X-Amz-Signature=abc&X-Amz-Credential=def&part=2")
+	got := redactURL(u)
+	if strings.Contains(got, "abc") || strings.Contains(got, "def") || !strings.Contains(got, "part=2") {
+		t.Errorf("redactURL() = %q", got)
+	}
+}
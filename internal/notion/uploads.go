@@ -0,0 +1,243 @@
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+)
+
+// MaxSinglePartUploadSize is the largest file Notion accepts in a
+// single-part upload (20 MB). Larger files are sent in parts.
+const MaxSinglePartUploadSize = 20 * 1024 * 1024
+
+// UploadPartSize is the size of each part of a multi-part upload but the
+// last, within the 5 to 20 MB Notion allows.
+const UploadPartSize = 10 * 1024 * 1024
+
+// fileUpload is the file upload object returned by the Notion API.
+type fileUpload struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// PartialUpload is a multi-part upload in progress: its file upload ID,
+// and how many of its parts have been sent.
+type PartialUpload struct {
+	ID    string
+	Parts int
+	Sent  int
+}
+
+// UploadFile uploads a file with Notion's file upload API and returns the
+// file upload ID. The ID can be attached to any number of image or file
+// blocks, so identical content only needs to be uploaded once. Files
+// larger than MaxSinglePartUploadSize are sent in parts.
+func (c *Client) UploadFile(ctx context.Context, filename string, data []byte) (string, error) {
+	if len(data) > MaxSinglePartUploadSize {
+		return c.UploadFileParts(ctx, filename, data, nil, nil)
+	}
+
+	created, err := c.createUpload(ctx, filename, data, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := c.sendPart(ctx, created.ID, filename, data, 0); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// UploadFileParts uploads a file in parts of UploadPartSize and returns
+// the file upload ID. Given the PartialUpload of an interrupted call, it
+// sends only the parts that are left, if Notion still holds the upload
+// open; otherwise it starts over. progress, if not nil, is called after
+// each part is sent, so the caller can save the upload to resume it.
+func (c *Client) UploadFileParts(ctx context.Context, filename string, data []byte, resume *PartialUpload, progress func(PartialUpload)) (string, error) {
+	parts := (len(data) + UploadPartSize - 1) / UploadPartSize
+
+	upload := PartialUpload{Parts: parts}
+	if resume != nil && resume.Parts == parts && c.uploadPending(ctx, resume.ID) {
+		upload = *resume
+	} else {
+		created, err := c.createUpload(ctx, filename, data, &parts)
+		if err != nil {
+			return "", err
+		}
+		upload.ID = created.ID
+		if progress != nil {
+			progress(upload)
+		}
+	}
+
+	for upload.Sent < parts {
+		start := upload.Sent * UploadPartSize
+		end := min(start+UploadPartSize, len(data))
+		if err := c.sendPart(ctx, upload.ID, filename, data[start:end], upload.Sent+1); err != nil {
+			return "", err
+		}
+		upload.Sent++
+		if progress != nil {
+			progress(upload)
+		}
+	}
+
+	var completed fileUpload
+	if err := c.doUpload(ctx, http.MethodPost, "/file_uploads/"+upload.ID+"/complete", "application/json", nil, &completed); err != nil {
+		return "", fmt.Errorf("complete file upload: %w", err)
+	}
+	if completed.Status != "" && completed.Status != "uploaded" {
+		return "", fmt.Errorf("complete file upload: unexpected status %q", completed.Status)
+	}
+	return upload.ID, nil
+}
+
+// createUpload creates a file upload, in parts if parts is not nil.
+func (c *Client) createUpload(ctx context.Context, filename string, data []byte, parts *int) (*fileUpload, error) {
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+
+	req := map[string]any{
+		"filename":     filename,
+		"content_type": contentType,
+	}
+	if parts != nil {
+		req["mode"] = "multi_part"
+		req["number_of_parts"] = *parts
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal upload request: %w", err)
+	}
+
+	var created fileUpload
+	if err := c.doUpload(ctx, http.MethodPost, "/file_uploads", "application/json", bytes.NewReader(body), &created); err != nil {
+		return nil, fmt.Errorf("create file upload: %w", err)
+	}
+	return &created, nil
+}
+
+// sendPart sends the contents of a file upload, or one part of them when
+// part is not 0.
+func (c *Client) sendPart(ctx context.Context, id, filename string, data []byte, part int) error {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if part > 0 {
+		if err := mw.WriteField("part_number", strconv.Itoa(part)); err != nil {
+			return fmt.Errorf("write part number: %w", err)
+		}
+	}
+	formFile, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return fmt.Errorf("create form file: %w", err)
+	}
+	if _, err := formFile.Write(data); err != nil {
+		return fmt.Errorf("write form file: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return fmt.Errorf("close multipart writer: %w", err)
+	}
+
+	var sent fileUpload
+	if err := c.doUpload(ctx, http.MethodPost, "/file_uploads/"+id+"/send", mw.FormDataContentType(), &buf, &sent); err != nil {
+		return fmt.Errorf("send file upload: %w", err)
+	}
+	// Parts before the last leave a multi-part upload pending.
+	if sent.Status != "" && sent.Status != "uploaded" && (part == 0 || sent.Status != "pending") {
+		return fmt.Errorf("send file upload: unexpected status %q", sent.Status)
+	}
+	return nil
+}
+
+// uploadPending reports whether a file upload is still open for parts.
+// Uploads that are not completed expire after an hour.
+func (c *Client) uploadPending(ctx context.Context, id string) bool {
+	var upload fileUpload
+	err := c.doUpload(ctx, http.MethodGet, "/file_uploads/"+id, "application/json", nil, &upload)
+	return err == nil && upload.Status == "pending"
+}
+
+// do performs a rate-limited POST against the Notion REST API and decodes
+// the JSON response into out.
+func (c *Client) do(ctx context.Context, path, contentType string, body io.Reader, out any) error {
+	if err := c.wait(ctx); err != nil {
+		return fmt.Errorf("rate limit: %w", err)
+	}
+	return c.send(ctx, http.MethodPost, path, contentType, body, out)
+}
+
+// doUpload performs a file upload request, rate-limited by the upload
+// limiter as well as the client's own.
+func (c *Client) doUpload(ctx context.Context, method, path, contentType string, body io.Reader, out any) error {
+	if c.uploadLimiter != nil {
+		if err := c.uploadLimiter.Wait(ctx); err != nil {
+			return fmt.Errorf("rate limit: %w", err)
+		}
+	}
+	if err := c.wait(ctx); err != nil {
+		return fmt.Errorf("rate limit: %w", err)
+	}
+	return c.send(ctx, method, path, contentType, body, out)
+}
+
+// send performs a request against the Notion REST API and decodes the
+// JSON response into out.
+func (c *Client) send(ctx context.Context, method, path, contentType string, body io.Reader, out any) error {
+	req, err := c.newRequest(ctx, method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr apiError
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return newError(resp.StatusCode, apiErr.Code, fmt.Sprintf("%s (status %d, code %s)", apiErr.Message, resp.StatusCode, apiErr.Code), nil)
+		}
+		return newError(resp.StatusCode, "", fmt.Sprintf("unexpected status %d", resp.StatusCode), nil)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// newRequest builds an authenticated request against the Notion REST API,
+// pinned to the client's API version.
+func (c *Client) newRequest(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.api.Token.String())
+	req.Header.Set("Notion-Version", c.version)
+	req.Header.Set("Content-Type", contentType)
+	return req, nil
+}
+
+// apiError is the error body returned by the Notion API.
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
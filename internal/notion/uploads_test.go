@@ -0,0 +1,146 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUploadFile(t *testing.T) {
+	var sentBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if r.Header.Get("Notion-Version") == "" {
+			t.Error("missing Notion-Version header")
+		}
+
+		switch r.URL.Path {
+		case "/file_uploads":
+			var req map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req["filename"] != "cat.png" || req["content_type"] != "image/png" {
+				t.Errorf("unexpected create request: %v", req)
+			}
+			_, _ = io.WriteString(w, `{"id":"upload-123","status":"pending"}`)
+		case "/file_uploads/upload-123/send":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Fatalf("parse multipart: %v", err)
+			}
+			f, _, err := r.FormFile("file")
+			if err != nil {
+				t.Fatalf("form file: %v", err)
+			}
+			data, _ := io.ReadAll(f)
+			sentBody = string(data)
+			_, _ = io.WriteString(w, `{"id":"upload-123","status":"uploaded"}`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := New("test-token", WithRateLimit(1000))
+	client.baseURL = server.URL
+
+	id, err := client.UploadFile(context.Background(), "cat.png", []byte("meow"))
+	if err != nil {
+		t.Fatalf("UploadFile() error: %v", err)
+	}
+	if id != "upload-123" {
+		t.Errorf("id = %q, want upload-123", id)
+	}
+	if sentBody != "meow" {
+		t.Errorf("sent body = %q, want meow", sentBody)
+	}
+}
+
+func TestUploadFile_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"code":"validation_error","message":"bad file"}`)
+	}))
+	defer server.Close()
+
+	client := New("test-token", WithRateLimit(1000))
+	client.baseURL = server.URL
+
+	_, err := client.UploadFile(context.Background(), "cat.png", []byte("meow"))
+	if err == nil || !strings.Contains(err.Error(), "bad file") {
+		t.Errorf("expected API error message, got %v", err)
+	}
+}
+
+func TestUploadFileParts(t *testing.T) {
+	var (
+		created  map[string]any
+		parts    []string
+		statuses int
+		complete bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/file_uploads":
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			_, _ = io.WriteString(w, `{"id":"upload-new","status":"pending"}`)
+		case r.Method == http.MethodGet:
+			statuses++
+			_, _ = io.WriteString(w, `{"id":"upload-old","status":"pending"}`)
+		case strings.HasSuffix(r.URL.Path, "/send"):
+			if err := r.ParseMultipartForm(32 << 20); err != nil {
+				t.Fatalf("parse multipart: %v", err)
+			}
+			parts = append(parts, r.URL.Path+"#"+r.FormValue("part_number"))
+			_, _ = io.WriteString(w, `{"status":"pending"}`)
+		case strings.HasSuffix(r.URL.Path, "/complete"):
+			complete = true
+			_, _ = io.WriteString(w, `{"status":"uploaded"}`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := New("test-token", WithRateLimit(1000), WithUploadRateLimit(1000))
+	client.baseURL = server.URL
+	data := make([]byte, 2*UploadPartSize+1)
+
+	// A new upload sends every part, reporting each.
+	var saved []PartialUpload
+	id, err := client.UploadFile(context.Background(), "big.bin", data)
+	if err != nil || id != "upload-new" || !complete {
+		t.Fatalf("UploadFile() = %q, %v (complete %v); want upload-new", id, err, complete)
+	}
+	if created["mode"] != "multi_part" || created["number_of_parts"] != float64(3) {
+		t.Errorf("create request = %v, want 3 parts", created)
+	}
+	if len(parts) != 3 || parts[2] != "/file_uploads/upload-new/send#3" {
+		t.Errorf("sent parts %v, want 1 to 3", parts)
+	}
+
+	// A pending upload resumes after the parts already sent.
+	parts, created = nil, nil
+	resume := &PartialUpload{ID: "upload-old", Parts: 3, Sent: 2}
+	id, err = client.UploadFileParts(context.Background(), "big.bin", data, resume, func(p PartialUpload) {
+		saved = append(saved, p)
+	})
+	if err != nil || id != "upload-old" {
+		t.Fatalf("UploadFileParts() resumed = %q, %v; want upload-old", id, err)
+	}
+	if created != nil || statuses != 1 {
+		t.Errorf("resume created %v after %d status check(s), want none after 1", created, statuses)
+	}
+	if len(parts) != 1 || parts[0] != "/file_uploads/upload-old/send#3" {
+		t.Errorf("resumed parts %v, want only part 3", parts)
+	}
+	if len(saved) != 1 || saved[0].Sent != 3 {
+		t.Errorf("progress %v, want part 3 sent", saved)
+	}
+}
@@ -0,0 +1,92 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jomei/notionapi"
+)
+
+// userLookup is the cached result of looking up a workspace user.
+type userLookup struct {
+	name string
+	err  error
+}
+
+// UserName returns the display name of a workspace user. Each user is
+// looked up once per client; later calls, including for users whose
+// lookup failed, are answered from the cache.
+func (c *Client) UserName(ctx context.Context, userID string) (string, error) {
+	c.usersMu.Lock()
+	defer c.usersMu.Unlock()
+
+	if cached, ok := c.users[userID]; ok {
+		return cached.name, cached.err
+	}
+
+	var lookup userLookup
+	if err := c.wait(ctx); err != nil {
+		return "", fmt.Errorf("rate limit: %w", err)
+	}
+	user, err := c.api.User.Get(ctx, notionapi.UserID(userID))
+	if err != nil {
+		lookup.err = fmt.Errorf("get user: %w", classify(err))
+	} else {
+		lookup.name = user.Name
+	}
+
+	if c.users == nil {
+		c.users = make(map[string]userLookup)
+	}
+	c.users[userID] = lookup
+	return lookup.name, lookup.err
+}
+
+// Workspace returns the name of the workspace the integration token
+// belongs to.
+func (c *Client) Workspace(ctx context.Context) (string, error) {
+	if err := c.wait(ctx); err != nil {
+		return "", fmt.Errorf("rate limit: %w", err)
+	}
+	me, err := c.api.User.Me(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get bot user: %w", classify(err))
+	}
+	if me.Bot == nil {
+		return "", nil
+	}
+	return me.Bot.WorkspaceName, nil
+}
+
+// BotUserID returns the ID of the integration's bot user, the user the
+// integration's writes are recorded as. It is looked up once per client; a
+// failed lookup is tried again on the next call.
+func (c *Client) BotUserID(ctx context.Context) (string, error) {
+	c.usersMu.Lock()
+	defer c.usersMu.Unlock()
+
+	if c.botID != "" {
+		return c.botID, nil
+	}
+	if err := c.wait(ctx); err != nil {
+		return "", fmt.Errorf("rate limit: %w", err)
+	}
+	me, err := c.api.User.Me(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get bot user: %w", classify(err))
+	}
+	c.botID = me.ID.String()
+	return c.botID, nil
+}
+
+// EditedBySelf reports whether a page was last edited by the integration
+// itself. A page whose editor cannot be told counts as edited by someone
+// else.
+func (c *Client) EditedBySelf(ctx context.Context, page *notionapi.Page) bool {
+	editor := page.LastEditedBy.ID.String()
+	if editor == "" {
+		return false
+	}
+	botID, err := c.BotUserID(ctx)
+	return err == nil && normalizeID(botID) == normalizeID(editor)
+}
@@ -0,0 +1,61 @@
+package notion
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jomei/notionapi"
+)
+
+func TestUserName_Cached(t *testing.T) {
+	var requests int
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/v1/users/user-1":
+			_, _ = w.Write([]byte(`{"object":"user","id":"user-1","type":"person","name":"Ada Lovelace"}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"object":"error","status":403,"code":"restricted_resource","message":"no access"}`))
+		}
+	})
+
+	for range 2 {
+		name, err := client.UserName(context.Background(), "user-1")
+		if err != nil || name != "Ada Lovelace" {
+			t.Errorf("UserName() = %q, %v; want Ada Lovelace", name, err)
+		}
+		if _, err := client.UserName(context.Background(), "user-2"); err == nil {
+			t.Error("UserName() of an unreadable user: expected error")
+		}
+	}
+	if requests != 2 {
+		t.Errorf("made %d requests, want 2 (one per user)", requests)
+	}
+}
+
+func TestEditedBySelf(t *testing.T) {
+	var requests int
+	client := newMockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"object":"user","id":"0a1b2c3d-0000-4000-8000-000000000001","type":"bot","bot":{}}`))
+	})
+
+	page := func(editor string) *notionapi.Page {
+		return &notionapi.Page{LastEditedBy: notionapi.User{ID: notionapi.UserID(editor)}}
+	}
+	// IDs compare without hyphens.
+	if !client.EditedBySelf(context.Background(), page("0a1b2c3d000040008000000000000001")) {
+		t.Error("EditedBySelf() = false for the bot's edit")
+	}
+	if client.EditedBySelf(context.Background(), page("user-1")) {
+		t.Error("EditedBySelf() = true for a person's edit")
+	}
+	if client.EditedBySelf(context.Background(), page("")) {
+		t.Error("EditedBySelf() = true without an editor")
+	}
+	if requests != 1 {
+		t.Errorf("made %d requests, want 1", requests)
+	}
+}
@@ -0,0 +1,500 @@
+// Package notionexport reads the ZIP archives of Notion's workspace
+// export, as "Markdown & CSV" or as HTML, so a workspace can be imported
+// into a vault without the API.
+//
+// In an export, every page is a markdown file named after its title and
+// ID, such as "Roadmap 1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d.md", with its
+// subpages and files in a folder of the same name. A database is a CSV
+// file of its rows, whose pages are in the folder named after the CSV.
+// Large exports are split into ZIP archives inside the archive.
+//
+// HTML exports are laid out the same way, with a database being an HTML
+// page of its table instead of a CSV file. Their pages are read as Notion
+// blocks, so they keep what the markdown export leaves out, such as text
+// colors and mentions. Pages of a markdown export are converted from
+// Notion's markdown.
+package notionexport
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUnsafePath is returned for an archive with an entry whose name is
+// absolute or leads out of the archive with "..", which would be written
+// outside the vault.
+var ErrUnsafePath = errors.New("archive has a path outside the export")
+
+// Archive is an opened Notion export.
+type Archive struct {
+	// Pages are the exported pages, sorted by Path.
+	Pages []*Page
+
+	// Files are the exported attachments, such as images, sorted by Path.
+	Files []*File
+
+	// ExportedAt is when the export was made: the latest modification
+	// time of the archive's entries.
+	ExportedAt time.Time
+
+	// byExportPath finds pages and files by their path in the archive.
+	byExportPath map[string]string
+
+	closers []io.Closer
+	temps   []string
+}
+
+// Page is an exported page.
+type Page struct {
+	// ID is the page's Notion ID, hyphenated as the API returns it.
+	ID string
+
+	// Title is the page's title.
+	Title string
+
+	// Path is the page's path in the archive without the IDs Notion adds
+	// to names, such as "Projects/Roadmap.md". Names made the same by
+	// dropping the IDs are numbered.
+	Path string
+
+	// ExportPath is the page's path in the archive.
+	ExportPath string
+
+	// Database is the database the page is a row of, or nil.
+	Database *Database
+
+	// Properties are the row's properties, in the database's column
+	// order, as Notion exported them. Pages outside databases have none.
+	Properties []Property
+
+	// Body is the page's content: its markdown after the title and
+	// properties. It is empty for a page of an HTML export, whose content
+	// is read with Archive.Blocks.
+	Body string
+
+	// HTML reports whether the page is from an HTML export. Its Path has
+	// a ".md" extension, like those of a markdown export.
+	HTML bool
+
+	// doc is the parsed page of an HTML export.
+	doc *node
+}
+
+// Property is an exported property of a database row.
+type Property struct {
+	Name  string
+	Value string
+}
+
+// Database is an exported database.
+type Database struct {
+	// ID is the database's Notion ID, hyphenated.
+	ID string
+
+	// Title is the database's title.
+	Title string
+
+	// Columns are the names of the database's properties, the title
+	// first.
+	Columns []string
+}
+
+// File is an exported attachment.
+type File struct {
+	// Path is the file's path in the archive without the IDs of its
+	// folders, like Page.Path.
+	Path string
+
+	// ExportPath is the file's path in the archive.
+	ExportPath string
+
+	file *zip.File
+}
+
+// Open returns the contents of the file.
+func (f *File) Open() (io.ReadCloser, error) {
+	return f.file.Open()
+}
+
+// Close closes the archive and removes the temporary copies of its inner
+// archives.
+func (a *Archive) Close() error {
+	var errs []error
+	for _, c := range a.closers {
+		errs = append(errs, c.Close())
+	}
+	for _, temp := range a.temps {
+		errs = append(errs, os.Remove(temp))
+	}
+	return errors.Join(errs...)
+}
+
+// idRegex matches a name Notion exported with an ID, such as
+// "Roadmap 1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d.md", capturing the name, the
+// ID, and the extension.
+var idRegex = regexp.MustCompile(`^(.*?) ?([0-9a-f]{32})(\.[A-Za-z0-9]+)?$`)
+
+// exportIndex is the page of links to the top-level pages that HTML
+// exports of a workspace have, which is not a page of the workspace.
+const exportIndex = "index.html"
+
+// Open opens a Notion export archive. Inner archives, which Notion uses
+// to split large exports, are read as part of it.
+func Open(name string) (*Archive, error) {
+	a := &Archive{byExportPath: make(map[string]string)}
+	files, err := a.readZip(name, 0)
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	if err := a.load(files); err != nil {
+		a.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// readZip opens a ZIP archive and returns its files, with those of the
+// archives inside it.
+func (a *Archive) readZip(name string, depth int) ([]*zip.File, error) {
+	r, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path.Base(name), err)
+	}
+	a.closers = append(a.closers, r)
+
+	var files []*zip.File
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if strings.EqualFold(path.Ext(f.Name), ".zip") && depth == 0 {
+			inner, err := a.extract(f)
+			if err != nil {
+				return nil, err
+			}
+			innerFiles, err := a.readZip(inner, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, innerFiles...)
+			continue
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// extract copies an inner archive to a temporary file, since ZIP archives
+// are read at random.
+func (a *Archive) extract(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	temp, err := os.CreateTemp("", "notion-export-*.zip")
+	if err != nil {
+		return "", fmt.Errorf("extract %s: %w", f.Name, err)
+	}
+	a.temps = append(a.temps, temp.Name())
+	_, err = io.Copy(temp, rc)
+	if cerr := temp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("extract %s: %w", f.Name, err)
+	}
+	return temp.Name(), nil
+}
+
+// load reads the databases and pages of an archive's files.
+func (a *Archive) load(files []*zip.File) error {
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	names := newNamer()
+
+	// Databases come first, so their rows can be read with their columns.
+	// Newer exports have a "_all" CSV of every row next to the CSV of
+	// the default view. HTML exports have a page of the database's table
+	// instead, which is not imported as a page.
+	databases := make(map[string]*Database) // by export folder of the rows
+	docs := make(map[string]*node)          // HTML pages by export path
+	tables := make(map[string]bool)         // HTML pages that are databases
+	for _, f := range files {
+		name := f.Name
+		if !safeName(name) {
+			return fmt.Errorf("%w: %s", ErrUnsafePath, name)
+		}
+		if f.Modified.After(a.ExportedAt) {
+			a.ExportedAt = f.Modified
+		}
+		if name == exportIndex {
+			continue
+		}
+		switch strings.ToLower(path.Ext(name)) {
+		case ".html":
+			doc, err := readHTML(f)
+			if err != nil {
+				return err
+			}
+			docs[name] = doc
+			for _, c := range readCollections(doc, name) {
+				if !c.inline {
+					tables[name] = true
+				}
+				if c.folder == "" || databases[c.folder] != nil {
+					continue
+				}
+				db := &Database{Title: c.title, Columns: c.columns}
+				if m := idRegex.FindStringSubmatch(path.Base(c.folder)); m != nil {
+					db.Title = m[1]
+					db.ID = hyphenate(m[2])
+				}
+				databases[c.folder] = db
+			}
+			continue
+		case ".csv":
+		default:
+			continue
+		}
+		folder := strings.TrimSuffix(strings.TrimSuffix(name, path.Ext(name)), "_all")
+		if _, ok := databases[folder]; ok && !strings.HasSuffix(strings.TrimSuffix(name, path.Ext(name)), "_all") {
+			continue
+		}
+		db, err := readDatabase(f, path.Base(folder))
+		if err != nil {
+			return err
+		}
+		databases[folder] = db
+	}
+
+	for _, f := range files {
+		if f.Name == exportIndex {
+			continue
+		}
+		switch strings.ToLower(path.Ext(f.Name)) {
+		case ".md":
+			page, err := readPage(f, databases[path.Dir(f.Name)])
+			if err != nil {
+				return err
+			}
+			page.Path = names.strip(f.Name)
+			a.Pages = append(a.Pages, page)
+			a.byExportPath[f.Name] = page.Path
+		case ".html":
+			if tables[f.Name] {
+				continue
+			}
+			page := readHTMLFile(f.Name, docs[f.Name], databases[path.Dir(f.Name)])
+			stripped := names.strip(f.Name)
+			page.Path = strings.TrimSuffix(stripped, path.Ext(stripped)) + ".md"
+			a.Pages = append(a.Pages, page)
+			a.byExportPath[f.Name] = page.Path
+		case ".csv":
+		default:
+			file := &File{Path: names.strip(f.Name), ExportPath: f.Name, file: f}
+			a.Files = append(a.Files, file)
+			a.byExportPath[f.Name] = file.Path
+		}
+	}
+	sort.Slice(a.Pages, func(i, j int) bool { return a.Pages[i].Path < a.Pages[j].Path })
+	sort.Slice(a.Files, func(i, j int) bool { return a.Files[i].Path < a.Files[j].Path })
+	return nil
+}
+
+// readDatabase reads the columns of a database's CSV file.
+func readDatabase(f *zip.File, folder string) (*Database, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	r := csv.NewReader(bufio.NewReader(rc))
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read %s: %w", f.Name, err)
+	}
+	if len(header) > 0 {
+		// Notion writes a byte order mark before the header.
+		header[0] = strings.TrimPrefix(header[0], "\uFEFF")
+	}
+
+	db := &Database{Title: folder, Columns: header}
+	if m := idRegex.FindStringSubmatch(folder); m != nil {
+		db.Title = m[1]
+		db.ID = hyphenate(m[2])
+	}
+	return db, nil
+}
+
+// readPage reads an exported page: its title, its properties if it is a
+// row of db, and its body.
+func readPage(f *zip.File, db *Database) (*Page, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", f.Name, err)
+	}
+
+	page := &Page{ExportPath: f.Name, Database: db}
+	base := strings.TrimSuffix(path.Base(f.Name), path.Ext(f.Name))
+	page.Title = base
+	if m := idRegex.FindStringSubmatch(base); m != nil {
+		page.Title = m[1]
+		page.ID = hyphenate(m[2])
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "# ") {
+		page.Title = strings.TrimSpace(lines[0][2:])
+		lines = lines[1:]
+	}
+	lines = trimBlankLines(lines)
+
+	// A row's properties follow the title, one "Name: value" per line.
+	if db != nil {
+		columns := make(map[string]bool, len(db.Columns))
+		for _, c := range db.Columns {
+			columns[c] = true
+		}
+		for len(lines) > 0 {
+			name, value, ok := strings.Cut(lines[0], ": ")
+			if !ok || !columns[name] {
+				break
+			}
+			page.Properties = append(page.Properties, Property{Name: name, Value: value})
+			lines = lines[1:]
+		}
+		lines = trimBlankLines(lines)
+	}
+
+	page.Body = strings.Join(lines, "\n")
+	return page, nil
+}
+
+// readHTML reads and parses a page of an HTML export.
+func readHTML(f *zip.File) (*node, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	doc, err := parseHTML(bufio.NewReader(rc))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", f.Name, err)
+	}
+	return doc, nil
+}
+
+// readHTMLFile reads a page of an HTML export: its title, and its
+// properties if it is a row of db.
+func readHTMLFile(name string, doc *node, db *Database) *Page {
+	page := &Page{ExportPath: name, Database: db, HTML: true, doc: doc}
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	page.Title = base
+	if m := idRegex.FindStringSubmatch(base); m != nil {
+		page.Title = m[1]
+		page.ID = hyphenate(m[2])
+	}
+
+	h := readHTMLPage(doc)
+	if h.title != "" {
+		page.Title = h.title
+	}
+	if page.ID == "" {
+		page.ID = h.id
+	}
+	if db != nil {
+		page.Properties = h.properties
+	}
+	return page
+}
+
+// safeName reports whether the name of an archive entry is a relative path
+// that stays inside the archive. Backslashes count as separators, as some
+// tools write them.
+func safeName(name string) bool {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if path.IsAbs(name) || (len(name) > 1 && name[1] == ':') {
+		return false
+	}
+	for _, part := range strings.Split(path.Clean(name), "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// trimBlankLines drops the blank lines at the start of lines.
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	return lines
+}
+
+// hyphenate formats a 32-digit Notion ID as a UUID.
+func hyphenate(id string) string {
+	return id[0:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:32]
+}
+
+// namer strips the IDs from exported names, numbering names made the same
+// by it. A page and its folder, which share an ID, keep the same name.
+type namer struct {
+	// names maps a stripped folder and an ID to the name given the ID.
+	names map[string]string
+
+	// taken holds the names given in each stripped folder.
+	taken map[string]bool
+}
+
+func newNamer() *namer {
+	return &namer{names: make(map[string]string), taken: make(map[string]bool)}
+}
+
+// strip returns an export path without the IDs of its names.
+func (n *namer) strip(exportPath string) string {
+	parts := strings.Split(exportPath, "/")
+	stripped := make([]string, len(parts))
+	for i, part := range parts {
+		dir := strings.Join(stripped[:i], "/")
+		m := idRegex.FindStringSubmatch(part)
+		if m == nil {
+			stripped[i] = part
+			continue
+		}
+		key := dir + "\x00" + m[2]
+		name, ok := n.names[key]
+		if !ok {
+			name = m[1]
+			if name == "" {
+				name = "Untitled"
+			}
+			for k := 2; n.taken[strings.ToLower(dir+"/"+name)]; k++ {
+				name = fmt.Sprintf("%s (%d)", m[1], k)
+			}
+			n.names[key] = name
+			n.taken[strings.ToLower(dir+"/"+name)] = true
+		}
+		stripped[i] = name + m[3]
+	}
+	return strings.Join(stripped, "/")
+}
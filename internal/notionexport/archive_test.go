@@ -0,0 +1,317 @@
+package notionexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jomei/notionapi"
+)
+
+const (
+	roadmapID = "1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d"
+	tasksID   = "aaaabbbbccccddddeeeeffff00001111"
+	taskID    = "22223333444455556666777788889999"
+	otherID   = "fedcba9876543210fedcba9876543210"
+)
+
+// zipBytes returns a ZIP archive of files, by name.
+func zipBytes(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writeExport writes a ZIP archive of files and returns its path.
+func writeExport(t *testing.T, files map[string]string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "Export.zip")
+	if err := os.WriteFile(name, zipBytes(t, files), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+// testExport is a small export: a page with a database of tasks, an
+// image, and another page of the same name, split into two inner
+// archives.
+func testExport(t *testing.T) string {
+	t.Helper()
+	part1 := zipBytes(t, map[string]string{
+		"Roadmap " + roadmapID + ".md": "# Roadmap\n\n" +
+			"<aside>\n⚠️ Dates are estimates.\n\nAsk before moving them.\n</aside>\n\n" +
+			"See [Ship it](Roadmap%20" + roadmapID + "/Tasks%20" + tasksID + "/Ship%20it%20" + taskID + ".md) and [docs](https://example.com/a_b).\n\n" +
+			"![diagram.png](Roadmap%20" + roadmapID + "/diagram.png)\n\n" +
+			"```\n[not](Roadmap%20" + roadmapID + "/diagram.png)\n```\n",
+		"Roadmap " + roadmapID + "/diagram.png": "PNG",
+		"Roadmap " + roadmapID + "/Tasks " + tasksID + ".csv": "\uFEFFName,Status,Estimate,Done\n" +
+			"Ship it,In progress,3,No\n",
+	})
+	part2 := zipBytes(t, map[string]string{
+		"Roadmap " + roadmapID + "/Tasks " + tasksID + "/Ship it " + taskID + ".md": "# Ship it\n\n" +
+			"Status: In progress\nEstimate: 3\nDone: No\n\nBack to [Roadmap](../../Roadmap%20" + roadmapID + ".md).\n",
+		"Roadmap " + otherID + ".md": "# Roadmap\n\nAn older one.\n",
+	})
+	return writeExport(t, map[string]string{
+		"Export-Part-1.zip": string(part1),
+		"Export-Part-2.zip": string(part2),
+	})
+}
+
+func TestOpen(t *testing.T) {
+	a, err := Open(testExport(t))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer a.Close()
+
+	paths := map[string]*Page{}
+	for _, p := range a.Pages {
+		paths[p.Path] = p
+	}
+	if len(a.Pages) != 3 {
+		t.Fatalf("got %d pages, want 3: %v", len(a.Pages), paths)
+	}
+
+	// Of pages with the same name, the later by ID is numbered.
+	roadmap := paths["Roadmap.md"]
+	if roadmap == nil || roadmap.ID != "1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d" || roadmap.Title != "Roadmap" {
+		t.Errorf("Roadmap.md = %+v", roadmap)
+	}
+	if other := paths["Roadmap (2).md"]; other == nil || other.ID != "fedcba98-7654-3210-fedc-ba9876543210" {
+		t.Errorf("Roadmap (2).md = %+v", other)
+	}
+
+	task := paths["Roadmap/Tasks/Ship it.md"]
+	if task == nil {
+		t.Fatal("Roadmap/Tasks/Ship it.md not found")
+	}
+	if task.Database == nil || task.Database.Title != "Tasks" || task.Database.ID != "aaaabbbb-cccc-dddd-eeee-ffff00001111" {
+		t.Errorf("Database = %+v", task.Database)
+	}
+	if got := strings.Join(task.Database.Columns, ","); got != "Name,Status,Estimate,Done" {
+		t.Errorf("Columns = %q", got)
+	}
+	want := []Property{{"Status", "In progress"}, {"Estimate", "3"}, {"Done", "No"}}
+	if len(task.Properties) != len(want) {
+		t.Fatalf("Properties = %v, want %v", task.Properties, want)
+	}
+	for i, p := range want {
+		if task.Properties[i] != p {
+			t.Errorf("Properties[%d] = %v, want %v", i, task.Properties[i], p)
+		}
+	}
+	if !strings.HasPrefix(task.Body, "Back to") {
+		t.Errorf("Body = %q", task.Body)
+	}
+
+	if len(a.Files) != 1 || a.Files[0].Path != "Roadmap/diagram.png" {
+		t.Fatalf("Files = %+v", a.Files)
+	}
+	rc, err := a.Files[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	var buf bytes.Buffer
+	buf.ReadFrom(rc)
+	if buf.String() != "PNG" {
+		t.Errorf("file content = %q", buf.String())
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	a, err := Open(testExport(t))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer a.Close()
+
+	calloutType := func(icon string) string {
+		if icon == "⚠️" {
+			return "warning"
+		}
+		return "note"
+	}
+	var roadmap, task *Page
+	for _, p := range a.Pages {
+		switch p.Path {
+		case "Roadmap.md":
+			roadmap = p
+		case "Roadmap/Tasks/Ship it.md":
+			task = p
+		}
+	}
+
+	got := a.Markdown(roadmap, Options{Folder: "Notion", CalloutType: calloutType})
+	want := "> [!warning]\n> Dates are estimates.\n>\n> Ask before moving them.\n\n" +
+		"See [[Notion/Roadmap/Tasks/Ship it]] and [docs](https://example.com/a_b).\n\n" +
+		"![[Notion/Roadmap/diagram.png]]\n\n" +
+		"```\n[not](Roadmap%20" + roadmapID + "/diagram.png)\n```\n"
+	if got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := a.Markdown(task, Options{}); got != "Back to [[Roadmap]].\n" {
+		t.Errorf("Markdown() of row = %q", got)
+	}
+}
+
+// htmlExport is testExport as an HTML export, with the tasks database as
+// a page of its table.
+func htmlExport(t *testing.T) string {
+	t.Helper()
+	roadmap := "Roadmap%20" + roadmapID
+	shipIt := roadmap + "/Tasks%20" + tasksID + "/Ship%20it%20" + taskID + ".html"
+	tasksTable := `<table class="collection-content"><thead><tr><th>Name</th><th>Status</th><th>Tags</th><th>Shipped</th></tr></thead>` +
+		`<tbody><tr><td class="cell-title"><a href="Tasks%20` + tasksID + `/Ship%20it%20` + taskID + `.html">Ship it</a></td><td>Done</td><td></td><td></td></tr></tbody></table>`
+	return writeExport(t, map[string]string{
+		"index.html": `<html><body><a href="` + roadmap + `.html">Roadmap</a></body></html>`,
+		"Roadmap " + roadmapID + ".html": `<!DOCTYPE html><html><head><meta charset="utf-8"/><title>Roadmap</title><style>p { margin: 0; }</style></head><body>` +
+			`<article id="1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d" class="page sans"><header><h1 class="page-title">Roadmap</h1></header><div class="page-body">` +
+			`<figure class="callout" style="white-space:pre-wrap;display:flex"><div style="font-size:1.5em"><span class="icon">⚠️</span></div><div style="width:100%"><p>Dates are <mark class="highlight-red">estimates</mark>.</p></div></figure>` +
+			`<p>See <a href="` + shipIt + `">Ship it</a> and <a href="https://example.com/a_b">docs</a>, <strong>bold</strong> &amp; <em>it</em>.</p>` +
+			`<ul class="to-do-list"><li><div class="checkbox checkbox-on"></div> <span class="to-do-children-checked">Plan</span></li></ul>` +
+			`<ul class="toggle"><li><details open=""><summary>More</summary><p>Hidden</p></details></li></ul>` +
+			`<figure class="image"><a href="` + roadmap + `/diagram.png"><img style="width:100px" src="` + roadmap + `/diagram.png"/></a></figure>` +
+			`<pre class="code"><code class="language-Go">x := 1 &lt; 2</code></pre>` +
+			`<table class="simple-table"><thead class="simple-table-header"><tr><th>A</th><th>B</th></tr></thead><tbody><tr><td>1</td><td>2<br/>3</td></tr></tbody></table>` +
+			`<div class="collection-content"><h4 class="collection-title">Tasks</h4>` + strings.ReplaceAll(tasksTable, `href="Tasks`, `href="`+roadmap+`/Tasks`) + `</div>` +
+			`</div></article></body></html>`,
+		"Roadmap " + roadmapID + "/Tasks " + tasksID + ".html": `<html><body><article id="aaaabbbb-cccc-dddd-eeee-ffff00001111" class="page sans"><header><h1 class="page-title">Tasks</h1></header>` +
+			tasksTable + `</article></body></html>`,
+		"Roadmap " + roadmapID + "/Tasks " + tasksID + "/Ship it " + taskID + ".html": `<html><body><article id="22223333-4444-5555-6666-777788889999" class="page sans"><header><h1 class="page-title">Ship it</h1>` +
+			`<table class="properties"><tbody>` +
+			`<tr class="property-row"><th><span class="icon property-icon"><svg viewBox="0 0 16 16"></svg></span>Status</th><td><span class="selected-value select-value-color-green">Done</span></td></tr>` +
+			`<tr class="property-row"><th>Tags</th><td><span class="selected-value">a</span><span class="selected-value">b</span></td></tr>` +
+			`<tr class="property-row"><th>Shipped</th><td><div class="checkbox checkbox-on"></div></td></tr>` +
+			`</tbody></table></header><div class="page-body"><p>Back to <a href="../../Roadmap%20` + roadmapID + `.html">Roadmap</a>.</p></div></article></body></html>`,
+		"Roadmap " + roadmapID + "/diagram.png": "PNG",
+	})
+}
+
+func TestOpen_HTMLExport(t *testing.T) {
+	a, err := Open(htmlExport(t))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer a.Close()
+
+	// The index and the page of the database's table are not pages.
+	var paths []string
+	for _, p := range a.Pages {
+		paths = append(paths, p.Path)
+	}
+	if want := []string{"Roadmap.md", "Roadmap/Tasks/Ship it.md"}; !slices.Equal(paths, want) {
+		t.Fatalf("pages = %q, want %q", paths, want)
+	}
+	if len(a.Files) != 1 || a.Files[0].Path != "Roadmap/diagram.png" {
+		t.Errorf("files = %+v", a.Files)
+	}
+
+	roadmap, task := a.Pages[0], a.Pages[1]
+	if !roadmap.HTML || roadmap.ID != "1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d" || roadmap.Title != "Roadmap" || roadmap.Database != nil {
+		t.Errorf("roadmap = %+v", roadmap)
+	}
+	if task.Database == nil || task.Database.ID != "aaaabbbb-cccc-dddd-eeee-ffff00001111" || task.Database.Title != "Tasks" {
+		t.Fatalf("task database = %+v", task.Database)
+	}
+	if want := []string{"Name", "Status", "Tags", "Shipped"}; !slices.Equal(task.Database.Columns, want) {
+		t.Errorf("columns = %q, want %q", task.Database.Columns, want)
+	}
+	wantProps := []Property{{"Status", "Done"}, {"Tags", "a, b"}, {"Shipped", "Yes"}}
+	if !slices.Equal(task.Properties, wantProps) {
+		t.Errorf("properties = %+v, want %+v", task.Properties, wantProps)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	a, err := Open(htmlExport(t))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer a.Close()
+
+	blocks := a.Blocks(a.Pages[0], Options{Folder: "Notion"})
+	var types []notionapi.BlockType
+	for _, b := range blocks {
+		types = append(types, b.GetType())
+	}
+	wantTypes := []notionapi.BlockType{
+		notionapi.BlockCallout, notionapi.BlockTypeParagraph, notionapi.BlockTypeToDo,
+		notionapi.BlockTypeToggle, notionapi.BlockTypeImage, notionapi.BlockTypeCode,
+		notionapi.BlockTypeTableBlock,
+	}
+	if !slices.Equal(types, wantTypes) {
+		t.Fatalf("block types = %v, want %v", types, wantTypes)
+	}
+
+	callout := blocks[0].(*notionapi.CalloutBlock).Callout
+	if callout.Icon == nil || *callout.Icon.Emoji != "⚠️" || len(callout.RichText) != 3 || callout.RichText[1].Annotations.Color != "red" {
+		t.Errorf("callout = %+v", callout)
+	}
+
+	text := blocks[1].(*notionapi.ParagraphBlock).Paragraph.RichText
+	mention := text[1]
+	if mention.Mention == nil || mention.Mention.Page.ID != notionapi.ObjectID(hyphenate(taskID)) || mention.PlainText != "Ship it" {
+		t.Errorf("mention = %+v", mention)
+	}
+	if link := text[3]; link.Text.Link == nil || link.Text.Link.Url != "https://example.com/a_b" {
+		t.Errorf("link = %+v", link)
+	}
+	if bold := text[5]; !bold.Annotations.Bold || bold.PlainText != "bold" {
+		t.Errorf("bold = %+v", bold)
+	}
+
+	if todo := blocks[2].(*notionapi.ToDoBlock).ToDo; !todo.Checked || todo.RichText[0].PlainText != "Plan" {
+		t.Errorf("to-do = %+v", todo)
+	}
+	if toggle := blocks[3].(*notionapi.ToggleBlock).Toggle; toggle.RichText[0].PlainText != "More" || len(toggle.Children) != 1 {
+		t.Errorf("toggle = %+v", toggle)
+	}
+	if image := blocks[4].(*notionapi.ImageBlock).Image; image.File == nil || image.File.URL != "Notion/Roadmap/diagram.png" {
+		t.Errorf("image = %+v", image)
+	}
+	if code := blocks[5].(*notionapi.CodeBlock).Code; code.Language != "go" || code.RichText[0].PlainText != "x := 1 < 2" {
+		t.Errorf("code = %+v", code)
+	}
+	table := blocks[6].(*notionapi.TableBlock).Table
+	if !table.HasColumnHeader || table.TableWidth != 2 || len(table.Children) != 2 {
+		t.Errorf("table = %+v", table)
+	}
+
+	if blocks := a.Blocks(&Page{}, Options{}); blocks != nil {
+		t.Errorf("Blocks() of a markdown page = %v, want nil", blocks)
+	}
+}
+
+func TestOpen_UnsafePath(t *testing.T) {
+	for _, name := range []string{
+		"../../evil.md",
+		"Roadmap " + roadmapID + "/../../../.bashrc",
+		"/etc/evil.md",
+		`..\evil.png`,
+	} {
+		if _, err := Open(writeExport(t, map[string]string{name: "x"})); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("Open() of %q error = %v, want ErrUnsafePath", name, err)
+		}
+	}
+}
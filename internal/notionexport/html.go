@@ -0,0 +1,686 @@
+package notionexport
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/jomei/notionapi"
+)
+
+// node is an element or a run of text of an exported HTML page.
+type node struct {
+	tag      string // Lowercase; empty for text
+	attrs    map[string]string
+	text     string
+	children []*node
+}
+
+// parseHTML reads an exported HTML page into a tree. Notion writes
+// well-formed markup, so the decoder's HTML mode, which closes void
+// elements such as <br> and <img>, is enough to read it.
+func parseHTML(r io.Reader) (*node, error) {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	root := &node{tag: "#document"}
+	stack := []*node{root}
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{tag: strings.ToLower(t.Name.Local), attrs: make(map[string]string, len(t.Attr))}
+			for _, a := range t.Attr {
+				n.attrs[strings.ToLower(a.Name.Local)] = a.Value
+			}
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].tag == name {
+					stack = stack[:i]
+					break
+				}
+			}
+		case xml.CharData:
+			top.children = append(top.children, &node{text: string(t)})
+		}
+	}
+}
+
+// hasClass reports whether an element has a class.
+func (n *node) hasClass(class string) bool {
+	for _, c := range strings.Fields(n.attrs["class"]) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the first element below n, in document order, that match
+// reports true for, or nil.
+func (n *node) find(match func(*node) bool) *node {
+	for _, c := range n.children {
+		if c.tag == "" {
+			continue
+		}
+		if match(c) {
+			return c
+		}
+		if found := c.find(match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll returns the elements below n that match reports true for,
+// without looking inside those found.
+func (n *node) findAll(match func(*node) bool) []*node {
+	var found []*node
+	for _, c := range n.children {
+		if c.tag == "" {
+			continue
+		}
+		if match(c) {
+			found = append(found, c)
+			continue
+		}
+		found = append(found, c.findAll(match)...)
+	}
+	return found
+}
+
+// textContent returns the text of n and everything below it.
+func (n *node) textContent() string {
+	if n.tag == "" {
+		return n.text
+	}
+	var b strings.Builder
+	for _, c := range n.children {
+		if c.tag == "style" || c.tag == "script" {
+			continue
+		}
+		b.WriteString(c.textContent())
+	}
+	return b.String()
+}
+
+// byTag returns a matcher of elements with a tag and, if given, a class.
+func byTag(tag, class string) func(*node) bool {
+	return func(n *node) bool {
+		return n.tag == tag && (class == "" || n.hasClass(class))
+	}
+}
+
+// byClass returns a matcher of elements with a class.
+func byClass(class string) func(*node) bool {
+	return func(n *node) bool {
+		return n.hasClass(class)
+	}
+}
+
+// texAnnotation returns the TeX source KaTeX keeps in an equation's
+// MathML, or "".
+func texAnnotation(n *node) string {
+	a := n.find(func(c *node) bool {
+		return c.tag == "annotation" && c.attrs["encoding"] == "application/x-tex"
+	})
+	if a == nil {
+		return ""
+	}
+	return strings.TrimSpace(a.textContent())
+}
+
+// htmlPage is what is read from an exported HTML page.
+type htmlPage struct {
+	id         string
+	title      string
+	properties []Property
+	body       *node // The page's div.page-body, or nil
+}
+
+// readHTMLPage reads the title, properties, and body of an exported page.
+func readHTMLPage(doc *node) *htmlPage {
+	p := &htmlPage{}
+	article := doc.find(byTag("article", ""))
+	if article == nil {
+		article = doc
+	}
+	p.id = article.attrs["id"]
+	if h1 := article.find(byClass("page-title")); h1 != nil {
+		p.title = strings.TrimSpace(h1.textContent())
+	}
+	if table := article.find(byTag("table", "properties")); table != nil {
+		for _, row := range table.findAll(byTag("tr", "")) {
+			th := row.find(byTag("th", ""))
+			td := row.find(byTag("td", ""))
+			if th == nil || td == nil {
+				continue
+			}
+			p.properties = append(p.properties, Property{Name: strings.TrimSpace(th.textContent()), Value: propertyValue(td)})
+		}
+	}
+	p.body = article.find(byClass("page-body"))
+	return p
+}
+
+// propertyValue returns the value of an exported property cell as the
+// "Markdown & CSV" export writes it: options and relations separated by
+// commas, and checkboxes as Yes or No.
+func propertyValue(td *node) string {
+	if box := td.find(byClass("checkbox")); box != nil {
+		if box.hasClass("checkbox-on") {
+			return "Yes"
+		}
+		return "No"
+	}
+	items := td.findAll(func(n *node) bool {
+		return n.hasClass("selected-value") || n.tag == "a"
+	})
+	if len(items) > 1 {
+		values := make([]string, len(items))
+		for i, item := range items {
+			values[i] = strings.TrimSpace(item.textContent())
+		}
+		return strings.Join(values, ", ")
+	}
+	return strings.TrimSpace(td.textContent())
+}
+
+// collection is a database table in an exported HTML page.
+type collection struct {
+	title   string
+	columns []string
+	folder  string // The export folder of the rows, or "" if it has none
+	inline  bool   // The table is in the page's body, not the page itself
+}
+
+// readCollections returns the database tables of an exported page, whose
+// rows are linked from them.
+func readCollections(doc *node, exportPath string) []collection {
+	var found []collection
+	body := doc.find(byClass("page-body"))
+	for _, table := range doc.findAll(byTag("table", "collection-content")) {
+		c := collection{inline: body != nil && body.find(func(n *node) bool { return n == table }) != nil}
+		if head := table.find(byTag("thead", "")); head != nil {
+			for _, th := range head.findAll(byTag("th", "")) {
+				c.columns = append(c.columns, strings.TrimSpace(th.textContent()))
+			}
+		}
+		if a := table.find(func(n *node) bool { return n.tag == "a" && n.attrs["href"] != "" }); a != nil {
+			if target, ok := archiveTarget(exportPath, a.attrs["href"]); ok {
+				c.folder = path.Dir(target)
+			}
+		}
+		c.title = strings.TrimSuffix(path.Base(c.folder), path.Ext(c.folder))
+		found = append(found, c)
+	}
+	return found
+}
+
+// archiveTarget returns the archive path a relative link of a page points
+// to, or false for links out of the archive.
+func archiveTarget(exportPath, href string) (string, bool) {
+	if href == "" || strings.Contains(href, "://") || strings.HasPrefix(href, "mailto:") || strings.HasPrefix(href, "#") {
+		return "", false
+	}
+	href, _, _ = strings.Cut(href, "#")
+	unescaped, err := url.PathUnescape(href)
+	if err != nil {
+		return "", false
+	}
+	return path.Join(path.Dir(exportPath), unescaped), true
+}
+
+// Blocks returns the body of a page of an HTML export as Notion blocks, to
+// be converted to markdown as pulled pages are. Links to pages in the
+// archive become page mentions, and images and files in the archive point
+// to their paths in the vault, under opts.Folder. It returns nil for pages
+// of a "Markdown & CSV" export.
+func (a *Archive) Blocks(p *Page, opts Options) []notionapi.Block {
+	if p.doc == nil {
+		return nil
+	}
+	body := readHTMLPage(p.doc).body
+	if body == nil {
+		return nil
+	}
+	c := &htmlConverter{archive: a, page: p, folder: opts.Folder}
+	return c.blocks(body.children)
+}
+
+// htmlConverter converts the HTML of an exported page to Notion blocks.
+type htmlConverter struct {
+	archive *Archive
+	page    *Page
+	folder  string
+}
+
+// vaultPath returns the path in the vault of a page or file a link of the
+// page points to.
+func (c *htmlConverter) vaultPath(href string) (string, bool) {
+	target, ok := archiveTarget(c.page.ExportPath, href)
+	if !ok {
+		return "", false
+	}
+	stripped, ok := c.archive.byExportPath[target]
+	if !ok {
+		return "", false
+	}
+	return path.Join(c.folder, stripped), true
+}
+
+// blocks converts a run of nodes to blocks. Text and inline elements
+// between blocks become paragraphs.
+func (c *htmlConverter) blocks(nodes []*node) []notionapi.Block {
+	var out []notionapi.Block
+	var inline []*node
+	flush := func() {
+		if rt := c.richText(inline); len(rt) > 0 && strings.TrimSpace(plainText(rt)) != "" {
+			out = append(out, paragraph(rt))
+		}
+		inline = nil
+	}
+	for _, n := range nodes {
+		if n.tag == "" || inlineTags[n.tag] {
+			inline = append(inline, n)
+			continue
+		}
+		flush()
+		// Notion exports the children of a block after it, indented.
+		if n.tag == "div" && n.hasClass("indented") && len(out) > 0 {
+			if p, ok := out[len(out)-1].(*notionapi.ParagraphBlock); ok {
+				p.Paragraph.Children = append(p.Paragraph.Children, c.blocks(n.children)...)
+				continue
+			}
+		}
+		out = append(out, c.block(n)...)
+	}
+	flush()
+	return out
+}
+
+// inlineTags are the elements that are part of a block's text.
+var inlineTags = map[string]bool{
+	"a": true, "b": true, "strong": true, "i": true, "em": true, "u": true,
+	"s": true, "del": true, "code": true, "mark": true, "span": true,
+	"br": true, "time": true,
+}
+
+// block converts a block element to blocks.
+func (c *htmlConverter) block(n *node) []notionapi.Block {
+	switch n.tag {
+	case "p":
+		return []notionapi.Block{paragraph(c.richText(n.children))}
+	case "h1":
+		return []notionapi.Block{&notionapi.Heading1Block{BasicBlock: basic(notionapi.BlockTypeHeading1), Heading1: notionapi.Heading{RichText: c.richText(n.children)}}}
+	case "h2":
+		return []notionapi.Block{&notionapi.Heading2Block{BasicBlock: basic(notionapi.BlockTypeHeading2), Heading2: notionapi.Heading{RichText: c.richText(n.children)}}}
+	case "h3", "h4", "h5", "h6":
+		return []notionapi.Block{&notionapi.Heading3Block{BasicBlock: basic(notionapi.BlockTypeHeading3), Heading3: notionapi.Heading{RichText: c.richText(n.children)}}}
+	case "ul", "ol":
+		return c.list(n)
+	case "details":
+		return []notionapi.Block{c.toggle(n)}
+	case "blockquote":
+		text, children := c.split(n.children)
+		return []notionapi.Block{&notionapi.QuoteBlock{BasicBlock: basic(notionapi.BlockQuote), Quote: notionapi.Quote{RichText: text, Children: children}}}
+	case "pre":
+		return []notionapi.Block{c.code(n)}
+	case "hr":
+		return []notionapi.Block{&notionapi.DividerBlock{BasicBlock: basic(notionapi.BlockTypeDivider)}}
+	case "figure":
+		return c.figure(n)
+	case "img":
+		return c.image(n.attrs["src"], nil)
+	case "table":
+		if n.hasClass("simple-table") {
+			return []notionapi.Block{c.table(n)}
+		}
+		// Databases are imported as folders of notes, not as part of
+		// the page's note.
+		return nil
+	case "nav", "style", "script", "header":
+		return nil
+	}
+	if n.hasClass("collection-content") {
+		return nil
+	}
+	return c.blocks(n.children)
+}
+
+// split separates the text of a block from the blocks nested in it.
+func (c *htmlConverter) split(nodes []*node) ([]notionapi.RichText, notionapi.Blocks) {
+	i := 0
+	for i < len(nodes) && (nodes[i].tag == "" || inlineTags[nodes[i].tag]) {
+		i++
+	}
+	text := c.richText(nodes[:i])
+	children := c.blocks(nodes[i:])
+	// A block whose text is a paragraph of its own, as in callouts.
+	if len(text) == 0 && len(children) > 0 {
+		if p, ok := children[0].(*notionapi.ParagraphBlock); ok && len(p.Paragraph.Children) == 0 {
+			text, children = p.Paragraph.RichText, children[1:]
+		}
+	}
+	return text, children
+}
+
+// list converts a list to its items. Notion exports each item of a list
+// of to-dos or toggles with the list's class.
+func (c *htmlConverter) list(n *node) []notionapi.Block {
+	var out []notionapi.Block
+	for _, li := range n.children {
+		if li.tag != "li" {
+			continue
+		}
+		switch {
+		case n.hasClass("toggle"):
+			if details := li.find(byTag("details", "")); details != nil {
+				out = append(out, c.toggle(details))
+				continue
+			}
+		case n.hasClass("to-do-list"):
+			out = append(out, c.todo(li))
+			continue
+		}
+		text, children := c.split(li.children)
+		item := notionapi.ListItem{RichText: text, Children: children}
+		if n.tag == "ol" {
+			out = append(out, &notionapi.NumberedListItemBlock{BasicBlock: basic(notionapi.BlockTypeNumberedListItem), NumberedListItem: item})
+		} else {
+			out = append(out, &notionapi.BulletedListItemBlock{BasicBlock: basic(notionapi.BlockTypeBulletedListItem), BulletedListItem: item})
+		}
+	}
+	return out
+}
+
+// todo converts an item of a to-do list.
+func (c *htmlConverter) todo(li *node) notionapi.Block {
+	var checked bool
+	var rest []*node
+	for _, child := range li.children {
+		if child.tag == "div" && child.hasClass("checkbox") {
+			checked = child.hasClass("checkbox-on")
+			continue
+		}
+		rest = append(rest, child)
+	}
+	text, children := c.split(rest)
+	text = trimRichText(text)
+	return &notionapi.ToDoBlock{BasicBlock: basic(notionapi.BlockTypeToDo), ToDo: notionapi.ToDo{RichText: text, Checked: checked, Children: children}}
+}
+
+// toggle converts a <details> element.
+func (c *htmlConverter) toggle(details *node) notionapi.Block {
+	var text []notionapi.RichText
+	var rest []*node
+	for _, child := range details.children {
+		if child.tag == "summary" {
+			text = c.richText(child.children)
+			continue
+		}
+		rest = append(rest, child)
+	}
+	return &notionapi.ToggleBlock{BasicBlock: basic(notionapi.BlockTypeToggle), Toggle: notionapi.Toggle{RichText: text, Children: c.blocks(rest)}}
+}
+
+// code converts a code block, whose language is the class of its <code>.
+func (c *htmlConverter) code(pre *node) notionapi.Block {
+	language := "plain text"
+	source := pre
+	if code := pre.find(byTag("code", "")); code != nil {
+		source = code
+		if lang, ok := strings.CutPrefix(code.attrs["class"], "language-"); ok && lang != "" {
+			language = strings.ToLower(lang)
+		}
+	}
+	content := source.textContent()
+	return &notionapi.CodeBlock{BasicBlock: basic(notionapi.BlockTypeCode), Code: notionapi.Code{
+		RichText: []notionapi.RichText{textRun(content, nil, "")},
+		Language: language,
+	}}
+}
+
+// figure converts a <figure>: a callout, an equation, an image, a
+// bookmark, a link to a page, or a file.
+func (c *htmlConverter) figure(n *node) []notionapi.Block {
+	var caption []notionapi.RichText
+	if fc := n.find(byTag("figcaption", "")); fc != nil {
+		caption = c.richText(fc.children)
+	}
+	switch {
+	case n.hasClass("callout"):
+		return []notionapi.Block{c.callout(n)}
+	case n.hasClass("equation"):
+		return []notionapi.Block{&notionapi.EquationBlock{BasicBlock: basic(notionapi.BlockTypeEquation), Equation: notionapi.Equation{Expression: texAnnotation(n)}}}
+	case n.hasClass("image"):
+		if img := n.find(byTag("img", "")); img != nil {
+			return c.image(img.attrs["src"], caption)
+		}
+	case n.hasClass("link-to-page"):
+		if a := n.find(byTag("a", "")); a != nil {
+			return []notionapi.Block{paragraph(c.richText([]*node{a}))}
+		}
+	}
+	if a := n.find(byClass("bookmark")); a != nil && a.attrs["href"] != "" {
+		return []notionapi.Block{&notionapi.BookmarkBlock{BasicBlock: basic(notionapi.BlockTypeBookmark), Bookmark: notionapi.Bookmark{URL: a.attrs["href"], Caption: caption}}}
+	}
+	if a := n.find(byTag("a", "")); a != nil && a.attrs["href"] != "" {
+		return []notionapi.Block{c.file(a.attrs["href"], caption)}
+	}
+	return c.blocks(n.children)
+}
+
+// callout converts a callout, whose first <div> holds its icon.
+func (c *htmlConverter) callout(n *node) notionapi.Block {
+	block := &notionapi.CalloutBlock{BasicBlock: basic(notionapi.BlockCallout)}
+	var divs []*node
+	for _, child := range n.children {
+		if child.tag == "div" {
+			divs = append(divs, child)
+		}
+	}
+	content := n.children
+	if len(divs) >= 2 {
+		if icon := strings.TrimSpace(divs[0].textContent()); icon != "" {
+			emoji := notionapi.Emoji(icon)
+			block.Callout.Icon = &notionapi.Icon{Type: "emoji", Emoji: &emoji}
+		}
+		content = divs[1].children
+	}
+	block.Callout.RichText, block.Callout.Children = c.split(content)
+	return block
+}
+
+// image converts an image, which is in the archive or on the web.
+func (c *htmlConverter) image(src string, caption []notionapi.RichText) []notionapi.Block {
+	if src == "" {
+		return nil
+	}
+	image := notionapi.Image{Caption: caption}
+	if vaultPath, ok := c.vaultPath(src); ok {
+		image.Type = notionapi.FileTypeFile
+		image.File = &notionapi.FileObject{URL: vaultPath}
+	} else {
+		image.Type = notionapi.FileTypeExternal
+		image.External = &notionapi.FileObject{URL: src}
+	}
+	return []notionapi.Block{&notionapi.ImageBlock{BasicBlock: basic(notionapi.BlockTypeImage), Image: image}}
+}
+
+// file converts an attached file, a PDF or any other.
+func (c *htmlConverter) file(href string, caption []notionapi.RichText) notionapi.Block {
+	var file, external *notionapi.FileObject
+	fileType := notionapi.FileTypeExternal
+	if vaultPath, ok := c.vaultPath(href); ok {
+		file, fileType = &notionapi.FileObject{URL: vaultPath}, notionapi.FileTypeFile
+	} else {
+		external = &notionapi.FileObject{URL: href}
+	}
+	if strings.EqualFold(path.Ext(href), ".pdf") {
+		return &notionapi.PdfBlock{BasicBlock: basic(notionapi.BlockTypePdf), Pdf: notionapi.Pdf{Caption: caption, Type: fileType, File: file, External: external}}
+	}
+	return &notionapi.FileBlock{BasicBlock: basic(notionapi.BlockTypeFile), File: notionapi.BlockFile{Caption: caption, Type: fileType, File: file, External: external}}
+}
+
+// table converts a simple table. Its header row is in <thead>.
+func (c *htmlConverter) table(n *node) notionapi.Block {
+	block := &notionapi.TableBlock{BasicBlock: basic(notionapi.BlockTypeTableBlock)}
+	block.Table.HasColumnHeader = n.find(byTag("thead", "")) != nil
+	for _, tr := range n.findAll(byTag("tr", "")) {
+		var cells [][]notionapi.RichText
+		for _, cell := range tr.children {
+			if cell.tag == "td" || cell.tag == "th" {
+				cells = append(cells, c.richText(cell.children))
+			}
+		}
+		if len(cells) > block.Table.TableWidth {
+			block.Table.TableWidth = len(cells)
+		}
+		block.Table.Children = append(block.Table.Children, &notionapi.TableRowBlock{BasicBlock: basic(notionapi.BlockTypeTableRowBlock), TableRow: notionapi.TableRow{Cells: cells}})
+	}
+	return block
+}
+
+// richText converts inline nodes to rich text.
+func (c *htmlConverter) richText(nodes []*node) []notionapi.RichText {
+	var out []notionapi.RichText
+	for _, n := range nodes {
+		out = c.appendRichText(out, n, notionapi.Annotations{}, "")
+	}
+	return out
+}
+
+// appendRichText appends the rich text of a node, with the annotations and
+// link of the elements around it.
+func (c *htmlConverter) appendRichText(out []notionapi.RichText, n *node, ann notionapi.Annotations, link string) []notionapi.RichText {
+	if n.tag == "" {
+		if n.text == "" {
+			return out
+		}
+		return append(out, textRun(n.text, &ann, link))
+	}
+	switch n.tag {
+	case "br":
+		return append(out, textRun("\n", &ann, link))
+	case "strong", "b":
+		ann.Bold = true
+	case "em", "i":
+		ann.Italic = true
+	case "del", "s":
+		ann.Strikethrough = true
+	case "u":
+		ann.Underline = true
+	case "code":
+		ann.Code = true
+	case "img", "style", "script":
+		return out
+	case "a":
+		href := n.attrs["href"]
+		if target, ok := archiveTarget(c.page.ExportPath, href); ok {
+			if id, ok := pageID(target); ok && strings.EqualFold(path.Ext(target), ".html") {
+				return append(out, notionapi.RichText{
+					Type:      "mention",
+					Mention:   &notionapi.Mention{Type: notionapi.MentionTypePage, Page: &notionapi.PageMention{ID: notionapi.ObjectID(id)}},
+					PlainText: strings.TrimSpace(n.textContent()),
+				})
+			}
+			if vaultPath, ok := c.vaultPath(href); ok {
+				href = strings.ReplaceAll(vaultPath, " ", "%20")
+			}
+		}
+		link = href
+	}
+	if n.hasClass("notion-text-equation-token") {
+		expression := texAnnotation(n)
+		return append(out, notionapi.RichText{Type: "equation", Equation: &notionapi.Equation{Expression: expression}, PlainText: expression})
+	}
+	if strings.Contains(strings.ReplaceAll(n.attrs["style"], " ", ""), "border-bottom:0.05emsolid") {
+		ann.Underline = true
+	}
+	for _, class := range strings.Fields(n.attrs["class"]) {
+		if color, ok := strings.CutPrefix(class, "highlight-"); ok && color != "default" {
+			ann.Color = notionapi.Color(color)
+		}
+	}
+	for _, child := range n.children {
+		out = c.appendRichText(out, child, ann, link)
+	}
+	return out
+}
+
+// pageID returns the ID in the name of an exported page.
+func pageID(exportPath string) (string, bool) {
+	m := idRegex.FindStringSubmatch(path.Base(exportPath))
+	if m == nil {
+		return "", false
+	}
+	return hyphenate(m[2]), true
+}
+
+// textRun returns a run of text.
+func textRun(content string, ann *notionapi.Annotations, link string) notionapi.RichText {
+	rt := notionapi.RichText{
+		Type:        notionapi.ObjectTypeText,
+		Text:        &notionapi.Text{Content: content},
+		PlainText:   content,
+		Annotations: ann,
+	}
+	if ann != nil {
+		copied := *ann
+		rt.Annotations = &copied
+	}
+	if link != "" {
+		rt.Text.Link = &notionapi.Link{Url: link}
+	}
+	return rt
+}
+
+// trimRichText trims the space Notion puts between a to-do's checkbox and
+// its text.
+func trimRichText(rt []notionapi.RichText) []notionapi.RichText {
+	for len(rt) > 0 && rt[0].Text != nil {
+		rt[0].Text.Content = strings.TrimLeft(rt[0].Text.Content, " ")
+		rt[0].PlainText = rt[0].Text.Content
+		if rt[0].PlainText != "" {
+			break
+		}
+		rt = rt[1:]
+	}
+	return rt
+}
+
+// plainText returns the text of rich text.
+func plainText(rt []notionapi.RichText) string {
+	var b strings.Builder
+	for _, r := range rt {
+		b.WriteString(r.PlainText)
+	}
+	return b.String()
+}
+
+// paragraph returns a paragraph of rich text.
+func paragraph(rt []notionapi.RichText) *notionapi.ParagraphBlock {
+	return &notionapi.ParagraphBlock{BasicBlock: basic(notionapi.BlockTypeParagraph), Paragraph: notionapi.Paragraph{RichText: rt}}
+}
+
+// basic returns the common fields of a block of a type.
+func basic(blockType notionapi.BlockType) notionapi.BasicBlock {
+	return notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: blockType}
+}
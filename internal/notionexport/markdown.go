@@ -0,0 +1,139 @@
+package notionexport
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Options configure the conversion of exported pages to notes.
+type Options struct {
+	// Folder is the vault folder the archive is imported into. Links to
+	// pages and files in the archive point into it.
+	Folder string
+
+	// CalloutType returns the Obsidian callout type of a callout's emoji
+	// icon. Without it, every callout is a note.
+	CalloutType func(icon string) string
+}
+
+// linkRegex matches a markdown link or image with a target without
+// spaces, which Notion escapes in exported links.
+var linkRegex = regexp.MustCompile(`(!?)\[([^\]]*)\]\(([^)\s]+)\)`)
+
+// Markdown returns the body of an exported page as a note's markdown:
+// links to pages and files in the archive become wiki-links and embeds,
+// and callouts, which Notion exports as <aside> HTML, become Obsidian
+// callouts. Code blocks are left as they are.
+func (a *Archive) Markdown(p *Page, opts Options) string {
+	var out []string
+	var aside []string
+	inAside, inCode := false, false
+	for _, line := range strings.Split(p.Body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			inCode = !inCode
+		case inCode:
+		case trimmed == "<aside>":
+			inAside = true
+			aside = nil
+			continue
+		case inAside && trimmed == "</aside>":
+			inAside = false
+			out = append(out, callout(aside, opts)...)
+			continue
+		default:
+			line = a.rewriteLinks(p, line, opts)
+		}
+		if inAside {
+			aside = append(aside, line)
+			continue
+		}
+		out = append(out, line)
+	}
+	if inAside {
+		out = append(out, callout(aside, opts)...)
+	}
+	return strings.TrimRight(strings.Join(out, "\n"), "\n") + "\n"
+}
+
+// rewriteLinks turns the links of a line to pages and files in the
+// archive into wiki-links and embeds.
+func (a *Archive) rewriteLinks(p *Page, line string, opts Options) string {
+	return linkRegex.ReplaceAllStringFunc(line, func(link string) string {
+		m := linkRegex.FindStringSubmatch(link)
+		embed, text, target := m[1] == "!", m[2], m[3]
+		if strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") || strings.HasPrefix(target, "#") {
+			return link
+		}
+		unescaped, err := url.PathUnescape(target)
+		if err != nil {
+			return link
+		}
+		stripped, ok := a.byExportPath[path.Join(path.Dir(p.ExportPath), unescaped)]
+		if !ok {
+			return link
+		}
+
+		vaultPath := path.Join(opts.Folder, stripped)
+		if embed {
+			return "![[" + vaultPath + "]]"
+		}
+		if strings.EqualFold(path.Ext(vaultPath), ".md") {
+			vaultPath = strings.TrimSuffix(vaultPath, path.Ext(vaultPath))
+		}
+		if text == "" || text == path.Base(vaultPath) {
+			return "[[" + vaultPath + "]]"
+		}
+		return "[[" + vaultPath + "|" + text + "]]"
+	})
+}
+
+// callout converts the lines of an exported <aside> to an Obsidian
+// callout, typed by the emoji that starts it.
+func callout(lines []string, opts Options) []string {
+	lines = trimBlankLines(lines)
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	calloutType := "note"
+	if len(lines) > 0 {
+		icon, rest := leadingEmoji(lines[0])
+		if icon != "" {
+			if opts.CalloutType != nil {
+				calloutType = opts.CalloutType(icon)
+			}
+			lines[0] = rest
+			if strings.TrimSpace(rest) == "" {
+				lines = trimBlankLines(lines[1:])
+			}
+		}
+	}
+
+	out := []string{"> [!" + calloutType + "]"}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			out = append(out, ">")
+			continue
+		}
+		out = append(out, "> "+line)
+	}
+	return out
+}
+
+// leadingEmoji splits an emoji, the icon of an exported callout, from the
+// start of a line.
+func leadingEmoji(line string) (icon, rest string) {
+	line = strings.TrimSpace(line)
+	first, _ := utf8.DecodeRuneInString(line)
+	if first < 0x2000 || unicode.IsLetter(first) || unicode.IsDigit(first) {
+		return "", line
+	}
+	icon, rest, _ = strings.Cut(line, " ")
+	return icon, strings.TrimSpace(rest)
+}
@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+)
+
+// linkDefinitionRegex matches a single-line link reference definition,
+// "[label]: destination".
+var linkDefinitionRegex = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*\S`)
+
+// Chunker splits a note into chunks of about a given size for parsing one
+// at a time, so a very large note never has to be held as one AST.
+//
+// Chunks end only at a blank line followed by an unindented line, outside
+// frontmatter, code fences, math blocks, and %% comments, so no top-level
+// block is split. A block larger than the chunk size is kept whole. The
+// first chunk starts with the note's frontmatter, if any.
+type Chunker struct {
+	r           *bufio.Reader
+	size        int
+	definitions []byte
+
+	tracker   blockTracker
+	next      []byte // A line read ahead that starts the next chunk.
+	lastBlank bool
+	offset    int64
+	done      bool
+}
+
+// NewChunker creates a Chunker reading a note from r. The link reference
+// definitions, as returned by LinkDefinitions, are appended to every chunk
+// so reference links resolve wherever they are defined.
+func NewChunker(r io.Reader, size int, definitions []byte) *Chunker {
+	return &Chunker{
+		r:           bufio.NewReader(r),
+		size:        size,
+		definitions: definitions,
+		tracker:     blockTracker{atStart: true},
+	}
+}
+
+// Next returns the next chunk, or io.EOF after the last one.
+func (c *Chunker) Next() ([]byte, error) {
+	if c.done && c.next == nil {
+		return nil, io.EOF
+	}
+
+	var buf bytes.Buffer
+	if c.next != nil {
+		buf.Write(c.next)
+		c.next = nil
+	}
+	for !c.done {
+		line, err := c.r.ReadBytes('\n')
+		if err == io.EOF {
+			c.done = true
+		} else if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			break
+		}
+		c.offset += int64(len(line))
+
+		blank := len(bytes.TrimSpace(line)) == 0
+		boundary := buf.Len() >= c.size && c.lastBlank && !blank &&
+			!c.tracker.open() && line[0] != ' ' && line[0] != '\t'
+		c.tracker.update(line)
+		c.lastBlank = blank
+		if boundary {
+			c.next = line
+			break
+		}
+		buf.Write(line)
+	}
+
+	if buf.Len() == 0 {
+		return nil, io.EOF
+	}
+	if len(c.definitions) > 0 {
+		buf.WriteString("\n\n")
+		buf.Write(c.definitions)
+	}
+	return buf.Bytes(), nil
+}
+
+// Offset returns how many bytes of the note have been read so far.
+func (c *Chunker) Offset() int64 {
+	return c.offset
+}
+
+// LinkDefinitions returns the link reference definitions of a note, one
+// per line, skipping code fences, math blocks, and comments. Definitions
+// whose title continues on the next line are not recognized.
+func LinkDefinitions(r io.Reader) ([]byte, error) {
+	var defs bytes.Buffer
+	tracker := blockTracker{atStart: true}
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if !tracker.open() && linkDefinitionRegex.Match(line) {
+				defs.Write(bytes.TrimRight(line, "\r\n"))
+				defs.WriteByte('\n')
+			}
+			tracker.update(line)
+		}
+		if err == io.EOF {
+			return defs.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// blockTracker follows the multi-line constructs of a note line by line:
+// frontmatter, code fences, $$ math blocks, and %% comments.
+type blockTracker struct {
+	atStart     bool
+	frontmatter bool
+	fence       []byte // The opening fence, e.g. "```", while in a code block.
+	math        bool
+	comment     bool
+}
+
+// open reports whether the tracker is inside a multi-line construct.
+func (t *blockTracker) open() bool {
+	return t.frontmatter || t.fence != nil || t.math || t.comment
+}
+
+// update advances the tracker past a line.
+func (t *blockTracker) update(line []byte) {
+	trimmed := bytes.TrimSpace(line)
+	if t.atStart {
+		t.atStart = false
+		if string(trimmed) == frontmatterDelimiter {
+			t.frontmatter = true
+			return
+		}
+	}
+
+	switch {
+	case t.frontmatter:
+		t.frontmatter = string(trimmed) != frontmatterDelimiter
+	case t.fence != nil:
+		if bytes.HasPrefix(trimmed, t.fence) && len(bytes.Trim(trimmed, string(t.fence[:1]))) == 0 {
+			t.fence = nil
+		}
+	case t.math:
+		t.math = !bytes.HasSuffix(trimmed, []byte("$$"))
+	case t.comment:
+		t.comment = bytes.Count(trimmed, []byte("%%"))%2 == 0
+	default:
+		if fence := openingFence(trimmed); fence != nil {
+			t.fence = fence
+		} else if bytes.HasPrefix(trimmed, []byte("$$")) {
+			t.math = len(trimmed) == 2 || !bytes.HasSuffix(trimmed, []byte("$$"))
+		} else {
+			t.comment = bytes.Count(trimmed, []byte("%%"))%2 == 1
+		}
+	}
+}
+
+// openingFence returns the fence a line opens a code block with, or nil.
+func openingFence(trimmed []byte) []byte {
+	if len(trimmed) < 3 || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return nil
+	}
+	n := 0
+	for n < len(trimmed) && trimmed[n] == trimmed[0] {
+		n++
+	}
+	if n < 3 {
+		return nil
+	}
+	return bytes.Clone(trimmed[:n])
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"bytes"
 	"regexp"
 	"strings"
 )
@@ -54,6 +55,11 @@ type DataviewQuery struct {
 
 // ExtractDataviewQueries finds all dataview blocks and inline queries in content.
 func ExtractDataviewQueries(content []byte) []DataviewQuery {
+	// Every query is in backticks; most notes have none to scan for.
+	if !bytes.Contains(content, []byte("`")) {
+		return nil
+	}
+
 	var queries []DataviewQuery
 
 	// Find block queries (```dataview ... ```).
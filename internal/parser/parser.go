@@ -9,7 +9,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
@@ -106,15 +108,30 @@ type Embed struct {
 	Depth int
 }
 
-// New creates a new Parser with Obsidian extensions enabled.
-func New() *Parser {
-	md := goldmark.New(
+// markdown returns the goldmark instance every Parser shares. Setting one
+// up builds the parsers of all its extensions, which costs more than
+// parsing a typical note, and goldmark parsers are safe for concurrent use.
+var markdown = sync.OnceValue(func() goldmark.Markdown {
+	return goldmark.New(
 		goldmark.WithExtensions(
 			obsidian.NewObsidian(),
 			&wikilink.Extender{},
 		),
 	)
-	return &Parser{md: md}
+})
+
+// lineStartsPool holds the line offset slices of parsed notes, reused
+// across notes since large vaults parse thousands of them.
+var lineStartsPool = sync.Pool{
+	New: func() any {
+		starts := make([]int, 0, 256)
+		return &starts
+	},
+}
+
+// New creates a new Parser with Obsidian extensions enabled.
+func New() *Parser {
+	return &Parser{md: markdown()}
 }
 
 // Parse parses an Obsidian note from the given path and content.
@@ -127,7 +144,19 @@ func (p *Parser) Parse(path string, content []byte) (*ParsedNote, error) {
 	if err != nil {
 		return nil, err
 	}
+	return p.parseBody(path, frontmatter, body)
+}
 
+// ParseChunk parses a chunk of a note's body, as read by a Chunker. The
+// chunk has no frontmatter of its own: a leading "---" is a thematic
+// break, not the start of frontmatter.
+func (p *Parser) ParseChunk(path string, chunk []byte) (*ParsedNote, error) {
+	return p.parseBody(path, map[string]any{}, chunk)
+}
+
+// parseBody parses a note body and collects its links, tags, embeds, and
+// dataview queries.
+func (p *Parser) parseBody(path string, frontmatter map[string]any, body []byte) (*ParsedNote, error) {
 	// 2. Parse markdown body to AST.
 	reader := text.NewReader(body)
 	doc := p.md.Parser().Parse(reader)
@@ -137,7 +166,23 @@ func (p *Parser) Parse(path string, content []byte) (*ParsedNote, error) {
 	var tags []string
 	var embeds []Embed
 
-	err = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+	// Line numbers are looked up in the offsets of the body's lines,
+	// found when the first link needs one.
+	var starts *[]int
+	defer func() {
+		if starts != nil {
+			lineStartsPool.Put(starts)
+		}
+	}()
+	lineOf := func(node ast.Node) int {
+		if starts == nil {
+			starts = lineStartsPool.Get().(*[]int)
+			*starts = appendLineStarts((*starts)[:0], body)
+		}
+		return findNodeLine(node, *starts)
+	}
+
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		if !entering {
 			return ast.WalkContinue, nil
 		}
@@ -172,7 +217,7 @@ func (p *Parser) Parse(path string, content []byte) (*ParsedNote, error) {
 			line := 0
 			if node.Parent() != nil {
 				// Walk up to find a block-level parent with line info.
-				line = findNodeLine(node, body)
+				line = lineOf(node)
 			}
 
 			if node.Embed {
@@ -222,18 +267,10 @@ func (p *Parser) Parse(path string, content []byte) (*ParsedNote, error) {
 		return nil, err
 	}
 
-	// 4. Extract tags from frontmatter if present.
+	// 4. Extract tags from frontmatter if present. Nested tags keep their
+	// hierarchy ("project/alpha").
 	if fmTags, ok := frontmatter["tags"]; ok {
-		switch t := fmTags.(type) {
-		case []any:
-			for _, tag := range t {
-				if s, ok := tag.(string); ok {
-					tags = append(tags, s)
-				}
-			}
-		case []string:
-			tags = append(tags, t...)
-		}
+		tags = append(tags, FrontmatterTags(fmTags)...)
 	}
 
 	// 5. Extract dataview queries.
@@ -273,9 +310,10 @@ func extractWikilinkAlias(node *wikilink.Node, source []byte) string {
 	return alias.String()
 }
 
-// findNodeLine attempts to find the line number for a node.
+// findNodeLine attempts to find the line number for a node, given the
+// offsets at which the lines of its source start.
 // Returns 1-indexed line number, or 0 if unknown.
-func findNodeLine(n ast.Node, source []byte) int {
+func findNodeLine(n ast.Node, starts []int) int {
 	// Walk up to find a block node with line info.
 	// Inline nodes have Lines() but it panics - we must check Kind() first.
 	for node := n; node != nil; node = node.Parent() {
@@ -283,24 +321,24 @@ func findNodeLine(n ast.Node, source []byte) int {
 		if node.Kind().String() != "" && node.Type() == ast.TypeBlock {
 			lines := node.Lines()
 			if lines != nil && lines.Len() > 0 {
-				line := lines.At(0)
-				// Count newlines to determine line number.
-				return countNewlines(source[:line.Start]) + 1
+				// The line is the last one starting at or before the offset.
+				offset := lines.At(0).Start
+				return sort.Search(len(starts), func(i int) bool { return starts[i] > offset })
 			}
 		}
 	}
 	return 0
 }
 
-// countNewlines counts the number of newline characters in a byte slice.
-func countNewlines(b []byte) int {
-	count := 0
-	for _, c := range b {
+// appendLineStarts appends the offset of each line of source to starts.
+func appendLineStarts(starts []int, source []byte) []int {
+	starts = append(starts, 0)
+	for i, c := range source {
 		if c == '\n' {
-			count++
+			starts = append(starts, i+1)
 		}
 	}
-	return count
+	return starts
 }
 
 // isImageEmbed checks if an embed target is an image.
@@ -370,6 +408,30 @@ func parseEmbedDimensions(target string) (string, int, int) {
 	return cleanTarget, width, 0
 }
 
+// ParseImageSize parses an Obsidian image size suffix: a width ("800") or
+// width and height ("800x200"). It reports false for anything else, such
+// as an alias.
+func ParseImageSize(s string) (width, height int, ok bool) {
+	w, h, hasHeight := strings.Cut(s, "x")
+	if !isDigits(w) || (hasHeight && !isDigits(h)) {
+		return 0, 0, false
+	}
+	return parseInt(w), parseInt(h), true
+}
+
+// isDigits reports whether s is a non-empty run of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // parseInt converts a string to int, returning 0 on error.
 func parseInt(s string) int {
 	s = strings.TrimSpace(s)
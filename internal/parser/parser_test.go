@@ -1,6 +1,12 @@
 package parser
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -662,6 +668,27 @@ func TestParseEmbedDimensions(t *testing.T) {
 	}
 }
 
+func TestParseImageSize(t *testing.T) {
+	tests := []struct {
+		in            string
+		width, height int
+		ok            bool
+	}{
+		{"800", 800, 0, true},
+		{"800x200", 800, 200, true},
+		{"A banner", 0, 0, false},
+		{"800x", 0, 0, false},
+		{"x200", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		w, h, ok := ParseImageSize(tt.in)
+		if w != tt.width || h != tt.height || ok != tt.ok {
+			t.Errorf("ParseImageSize(%q) = %d, %d, %v; want %d, %d, %v", tt.in, w, h, ok, tt.width, tt.height, tt.ok)
+		}
+	}
+}
+
 // Nested embed tests.
 
 type mockEmbedResolver struct {
@@ -949,3 +976,182 @@ func TestMergeFrontmatter(t *testing.T) {
 		t.Errorf("date should be added, got %v", merged["date"])
 	}
 }
+
+func TestParse_NestedTags(t *testing.T) {
+	content := []byte(`---
+tags: "#area/work, project//alpha/"
+---
+
+Working on #project/alpha/backend today.
+`)
+
+	note, err := New().Parse("test.md", content)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	want := []string{"project/alpha/backend", "area/work", "project/alpha"}
+	if len(note.Tags) != len(want) {
+		t.Fatalf("Tags = %q, want %q", note.Tags, want)
+	}
+	for i := range want {
+		if note.Tags[i] != want[i] {
+			t.Errorf("Tags[%d] = %q, want %q", i, note.Tags[i], want[i])
+		}
+	}
+}
+
+func TestTagHierarchy(t *testing.T) {
+	got := TagHierarchy("project/alpha/backend")
+	want := []string{"project", "project/alpha", "project/alpha/backend"}
+	if len(got) != len(want) {
+		t.Fatalf("TagHierarchy() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("TagHierarchy()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestChunker(t *testing.T) {
+	note := "---\ntitle: Big\n\nmore: yaml\n---\n" +
+		"First paragraph.\n\n" +
+		"```\ncode\n\nnot a boundary\n```\n\n" +
+		"- item\n\n  continued item\n\n" +
+		"$$\nx\n\ny\n$$\n\n" +
+		"Last [ref].\n\n" +
+		"[ref]: https://example.com\n"
+
+	defs, err := LinkDefinitions(strings.NewReader(note))
+	if err != nil {
+		t.Fatalf("LinkDefinitions() error: %v", err)
+	}
+	if string(defs) != "[ref]: https://example.com\n" {
+		t.Errorf("LinkDefinitions() = %q", defs)
+	}
+
+	// With a tiny chunk size, every safe boundary ends a chunk.
+	c := NewChunker(strings.NewReader(note), 1, nil)
+	var chunks []string
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		chunks = append(chunks, string(chunk))
+	}
+	want := []string{
+		"---\ntitle: Big\n\nmore: yaml\n---\nFirst paragraph.\n\n",
+		"```\ncode\n\nnot a boundary\n```\n\n",
+		"- item\n\n  continued item\n\n",
+		"$$\nx\n\ny\n$$\n\n",
+		"Last [ref].\n\n",
+		"[ref]: https://example.com\n",
+	}
+	if !reflect.DeepEqual(chunks, want) {
+		t.Errorf("chunks = %q, want %q", chunks, want)
+	}
+	if c.Offset() != int64(len(note)) {
+		t.Errorf("Offset() = %d, want %d", c.Offset(), len(note))
+	}
+
+	// Definitions are appended to every chunk.
+	c = NewChunker(strings.NewReader("Last [ref].\n"), 1<<20, defs)
+	chunk, err := c.Next()
+	if err != nil || string(chunk) != "Last [ref].\n\n\n[ref]: https://example.com\n" {
+		t.Errorf("Next() = %q, %v", chunk, err)
+	}
+	if _, err := c.Next(); err != io.EOF {
+		t.Errorf("Next() after the last chunk = %v, want io.EOF", err)
+	}
+}
+
+func TestParseChunk(t *testing.T) {
+	p := New()
+
+	// A chunk starting with a thematic break has no frontmatter.
+	note, err := p.ParseChunk("big.md", []byte("---\n\nText with #tag and [[Link]].\n\n---\n"))
+	if err != nil {
+		t.Fatalf("ParseChunk() error: %v", err)
+	}
+	if len(note.Frontmatter) != 0 {
+		t.Errorf("Frontmatter = %v, want none", note.Frontmatter)
+	}
+	if !reflect.DeepEqual(note.Tags, []string{"tag"}) || len(note.WikiLinks) != 1 {
+		t.Errorf("Tags = %v, WikiLinks = %v", note.Tags, note.WikiLinks)
+	}
+}
+
+// benchmarkNote returns a note of about n sections with the wiki-links,
+// embeds, tags, and dataview queries typical of a vault.
+func benchmarkNote(i, n int) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "---\ntitle: Note %d\ntags: [project/alpha, reading]\n---\n", i)
+	for s := 0; s < n; s++ {
+		fmt.Fprintf(&b, "## Section %d\n\n", s)
+		fmt.Fprintf(&b, "Links to [[Note %d]] and [[Note %d#Section 1|the first section]], tagged #topic/%d.\n\n", i+1, i+2, s%7)
+		b.WriteString("- [ ] A task with `=this.file.name` inline\n- Another item with **bold** text\n\n")
+		if s%5 == 0 {
+			b.WriteString("![[diagram.png|300]]\n\n```dataview\nLIST FROM #reading\n```\n\n")
+		}
+		b.WriteString("> [!note] A callout\n> With a second line.\n\n")
+	}
+	return []byte(b.String())
+}
+
+// BenchmarkParseVault parses a vault of 500 notes from disk, as a push of
+// every note does.
+func BenchmarkParseVault(b *testing.B) {
+	vault := b.TempDir()
+	var paths []string
+	for i := 0; i < 500; i++ {
+		path := fmt.Sprintf("Note %d.md", i)
+		if err := os.WriteFile(filepath.Join(vault, path), benchmarkNote(i, 10), 0644); err != nil {
+			b.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		p := New()
+		for _, path := range paths {
+			if _, err := p.ParseFile(vault, path); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkParseLargeNote parses one note of 2,000 sections.
+func BenchmarkParseLargeNote(b *testing.B) {
+	content := benchmarkNote(0, 2000)
+	p := New()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(content)))
+	b.ResetTimer()
+	for range b.N {
+		if _, err := p.Parse("Large.md", content); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkNew creates a parser per note, as commands that parse one note
+// at a time do.
+func BenchmarkNew(b *testing.B) {
+	content := benchmarkNote(0, 1)
+
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := New().Parse("Note.md", content); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
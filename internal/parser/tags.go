@@ -0,0 +1,63 @@
+package parser
+
+import "strings"
+
+// normalizeTag trims a tag as written in frontmatter, where the leading #
+// is optional, and drops empty path segments ("a//b/" becomes "a/b").
+func normalizeTag(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	parts := strings.Split(tag, "/")
+	kept := parts[:0]
+	for _, p := range parts {
+		if p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, "/")
+}
+
+// FrontmatterTags reads a frontmatter tags value, given as a list or as a
+// single string, and returns its tags without a leading #.
+func FrontmatterTags(value any) []string {
+	var raw []string
+	switch v := value.(type) {
+	case []any:
+		for _, tag := range v {
+			if s, ok := tag.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	case []string:
+		raw = v
+	case string:
+		raw = splitFrontmatterTags(v)
+	}
+
+	var tags []string
+	for _, tag := range raw {
+		if tag = normalizeTag(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// splitFrontmatterTags splits a tags value given as a single string.
+// Obsidian accepts both comma- and space-separated lists.
+func splitFrontmatterTags(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+// TagHierarchy returns a nested tag and each of its ancestors, outermost
+// first: "project/alpha/backend" yields "project", "project/alpha", and
+// "project/alpha/backend".
+func TagHierarchy(tag string) []string {
+	parts := strings.Split(tag, "/")
+	hierarchy := make([]string, len(parts))
+	for i := range parts {
+		hierarchy[i] = strings.Join(parts[:i+1], "/")
+	}
+	return hierarchy
+}
@@ -0,0 +1,254 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Attachment records a local file that has been uploaded to Notion.
+type Attachment struct {
+	ContentHash  string    // SHA-256 of the file content.
+	Credential   string    // Credential name the upload belongs to ("" for the default token).
+	FileUploadID string    // Notion file upload ID, reusable across blocks.
+	OriginalPath string    // Vault-relative path of the first uploaded copy.
+	Size         int64     // File size in bytes.
+	UploadedAt   time.Time // When the file was uploaded.
+}
+
+// PartialUpload records a multi-part attachment upload that has not
+// finished yet.
+type PartialUpload struct {
+	ContentHash  string    // SHA-256 of the file content.
+	Credential   string    // Credential name the upload belongs to.
+	FileUploadID string    // Notion file upload ID.
+	Parts        int       // Number of parts the file is sent in.
+	Sent         int       // Parts sent so far.
+	StartedAt    time.Time // When the upload was created.
+}
+
+// AttachmentStore tracks uploaded attachments by content hash and the
+// notes that reference them.
+type AttachmentStore struct {
+	db *DB
+}
+
+// NewAttachmentStore creates a new AttachmentStore backed by the given database.
+func NewAttachmentStore(db *DB) *AttachmentStore {
+	return &AttachmentStore{db: db}
+}
+
+// Get returns the attachment with the given content hash uploaded through
+// the named credential, or nil if it has not been uploaded yet. File uploads
+// belong to the integration that created them, so each credential keeps
+// its own copy.
+func (s *AttachmentStore) Get(contentHash, credential string) (*Attachment, error) {
+	row := s.db.conn.QueryRow(`
+		SELECT content_hash, credential, file_upload_id, original_path, size, uploaded_at
+		FROM attachments
+		WHERE content_hash = ? AND credential = ?
+	`, contentHash, credential)
+
+	a, err := scanAttachment(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan attachment: %w", err)
+	}
+	return a, nil
+}
+
+// Record stores an uploaded attachment. An existing record for the same
+// content hash and credential is replaced.
+func (s *AttachmentStore) Record(a *Attachment) error {
+	_, err := s.db.conn.Exec(`
+		INSERT INTO attachments (content_hash, credential, file_upload_id, original_path, size, uploaded_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(content_hash, credential) DO UPDATE SET
+			file_upload_id = excluded.file_upload_id,
+			original_path = excluded.original_path,
+			size = excluded.size,
+			uploaded_at = excluded.uploaded_at
+	`, a.ContentHash, a.Credential, a.FileUploadID, a.OriginalPath, a.Size, nullTime(a.UploadedAt))
+	return err
+}
+
+// SetRefs replaces the set of attachments referenced by a note.
+func (s *AttachmentStore) SetRefs(notePath string, contentHashes []string) error {
+	tx, err := s.db.begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM attachment_refs WHERE note_path = ?`, notePath); err != nil {
+		return fmt.Errorf("clear refs: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO attachment_refs (note_path, content_hash)
+		VALUES (?, ?)
+		ON CONFLICT DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, hash := range contentHashes {
+		if _, err := stmt.Exec(notePath, hash); err != nil {
+			return fmt.Errorf("insert ref: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ClearRefs removes all attachment references from a note.
+func (s *AttachmentStore) ClearRefs(notePath string) error {
+	_, err := s.db.conn.Exec(`DELETE FROM attachment_refs WHERE note_path = ?`, notePath)
+	return err
+}
+
+// UpdateRefPath moves attachment references from an old note path to a new one.
+// Used when handling file renames.
+func (s *AttachmentStore) UpdateRefPath(oldPath, newPath string) error {
+	_, err := s.db.conn.Exec(`UPDATE attachment_refs SET note_path = ? WHERE note_path = ?`, newPath, oldPath)
+	return err
+}
+
+// RefCount returns the number of notes referencing an attachment.
+func (s *AttachmentStore) RefCount(contentHash string) (int, error) {
+	var count int
+	err := s.db.conn.QueryRow(`
+		SELECT COUNT(*) FROM attachment_refs WHERE content_hash = ?
+	`, contentHash).Scan(&count)
+	return count, err
+}
+
+// List returns all uploaded attachments ordered by original path.
+func (s *AttachmentStore) List() ([]*Attachment, error) {
+	return s.query(`
+		SELECT content_hash, credential, file_upload_id, original_path, size, uploaded_at
+		FROM attachments
+		ORDER BY original_path
+	`)
+}
+
+// ForNote returns the uploaded attachments a note references, ordered by
+// original path.
+func (s *AttachmentStore) ForNote(notePath string) ([]*Attachment, error) {
+	return s.query(`
+		SELECT a.content_hash, a.credential, a.file_upload_id, a.original_path, a.size, a.uploaded_at
+		FROM attachments a
+		JOIN attachment_refs r ON r.content_hash = a.content_hash
+		WHERE r.note_path = ?
+		ORDER BY a.original_path
+	`, notePath)
+}
+
+// Unused returns uploaded attachments that no note references anymore.
+func (s *AttachmentStore) Unused() ([]*Attachment, error) {
+	return s.query(`
+		SELECT a.content_hash, a.credential, a.file_upload_id, a.original_path, a.size, a.uploaded_at
+		FROM attachments a
+		WHERE NOT EXISTS (
+			SELECT 1 FROM attachment_refs r WHERE r.content_hash = a.content_hash
+		)
+		ORDER BY a.original_path
+	`)
+}
+
+// Delete removes an attachment record.
+func (s *AttachmentStore) Delete(contentHash, credential string) error {
+	_, err := s.db.conn.Exec(`DELETE FROM attachments WHERE content_hash = ? AND credential = ?`, contentHash, credential)
+	return err
+}
+
+// GetPartial returns the unfinished upload of the content with the given
+// hash through the named credential, or nil if there is none.
+func (s *AttachmentStore) GetPartial(contentHash, credential string) (*PartialUpload, error) {
+	p := &PartialUpload{ContentHash: contentHash, Credential: credential}
+	var startedAt sql.NullInt64
+	err := s.db.conn.QueryRow(`
+		SELECT file_upload_id, parts, sent, started_at
+		FROM attachment_uploads
+		WHERE content_hash = ? AND credential = ?
+	`, contentHash, credential).Scan(&p.FileUploadID, &p.Parts, &p.Sent, &startedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan partial upload: %w", err)
+	}
+	if startedAt.Valid {
+		p.StartedAt = time.Unix(startedAt.Int64, 0)
+	}
+	return p, nil
+}
+
+// SavePartial stores the progress of an unfinished upload, replacing any
+// earlier record for the same content and credential.
+func (s *AttachmentStore) SavePartial(p *PartialUpload) error {
+	_, err := s.db.conn.Exec(`
+		INSERT INTO attachment_uploads (content_hash, credential, file_upload_id, parts, sent, started_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(content_hash, credential) DO UPDATE SET
+			file_upload_id = excluded.file_upload_id,
+			parts = excluded.parts,
+			sent = excluded.sent,
+			started_at = excluded.started_at
+	`, p.ContentHash, p.Credential, p.FileUploadID, p.Parts, p.Sent, nullTime(p.StartedAt))
+	return err
+}
+
+// ClearPartial removes the record of an unfinished upload.
+func (s *AttachmentStore) ClearPartial(contentHash, credential string) error {
+	_, err := s.db.conn.Exec(`DELETE FROM attachment_uploads WHERE content_hash = ? AND credential = ?`, contentHash, credential)
+	return err
+}
+
+// query runs an attachment query and scans all rows.
+func (s *AttachmentStore) query(q string, args ...any) ([]*Attachment, error) {
+	rows, err := s.db.conn.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []*Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+
+	return attachments, rows.Err()
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanAttachment scans a single attachment row.
+func scanAttachment(row rowScanner) (*Attachment, error) {
+	a := &Attachment{}
+	var size, uploadedAt sql.NullInt64
+
+	if err := row.Scan(&a.ContentHash, &a.Credential, &a.FileUploadID, &a.OriginalPath, &size, &uploadedAt); err != nil {
+		return nil, err
+	}
+
+	if size.Valid {
+		a.Size = size.Int64
+	}
+	if uploadedAt.Valid {
+		a.UploadedAt = time.Unix(uploadedAt.Int64, 0)
+	}
+
+	return a, nil
+}
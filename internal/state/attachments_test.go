@@ -0,0 +1,172 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAttachmentStore_DedupAndUnused(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	store := NewAttachmentStore(db)
+
+	// Nothing uploaded yet.
+	a, err := store.Get("hash1", "")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if a != nil {
+		t.Fatal("expected no attachment before upload")
+	}
+
+	err = store.Record(&Attachment{
+		ContentHash:  "hash1",
+		FileUploadID: "upload-1",
+		OriginalPath: "images/a.png",
+		Size:         42,
+		UploadedAt:   time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	// Same content is found for the default credential only.
+	a, err = store.Get("hash1", "")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if a == nil || a.FileUploadID != "upload-1" || a.Size != 42 {
+		t.Fatalf("unexpected attachment: %+v", a)
+	}
+	if other, _ := store.Get("hash1", "work"); other != nil {
+		t.Error("uploads must not be shared across credentials")
+	}
+
+	// Two notes reference the same upload.
+	if err := store.SetRefs("one.md", []string{"hash1"}); err != nil {
+		t.Fatalf("set refs: %v", err)
+	}
+	if err := store.SetRefs("two.md", []string{"hash1"}); err != nil {
+		t.Fatalf("set refs: %v", err)
+	}
+	if n, _ := store.RefCount("hash1"); n != 2 {
+		t.Errorf("RefCount = %d, want 2", n)
+	}
+
+	unused, err := store.Unused()
+	if err != nil {
+		t.Fatalf("unused: %v", err)
+	}
+	if len(unused) != 0 {
+		t.Errorf("expected no unused attachments, got %d", len(unused))
+	}
+
+	// Rename one note, then drop both references.
+	if err := store.UpdateRefPath("one.md", "renamed.md"); err != nil {
+		t.Fatalf("update ref path: %v", err)
+	}
+	if err := store.ClearRefs("renamed.md"); err != nil {
+		t.Fatalf("clear refs: %v", err)
+	}
+	if err := store.SetRefs("two.md", nil); err != nil {
+		t.Fatalf("set refs: %v", err)
+	}
+
+	unused, err = store.Unused()
+	if err != nil {
+		t.Fatalf("unused: %v", err)
+	}
+	if len(unused) != 1 || unused[0].ContentHash != "hash1" {
+		t.Fatalf("expected hash1 to be unused, got %+v", unused)
+	}
+
+	if err := store.Delete("hash1", ""); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	all, err := store.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(all) != 0 {
+		t.Errorf("expected empty store after delete, got %d", len(all))
+	}
+}
+
+func TestAttachmentStore_ForNote(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	store := NewAttachmentStore(db)
+	for _, a := range []*Attachment{
+		{ContentHash: "hash1", FileUploadID: "upload-1", OriginalPath: "images/b.png"},
+		{ContentHash: "hash2", FileUploadID: "upload-2", OriginalPath: "images/a.png"},
+		{ContentHash: "hash3", FileUploadID: "upload-3", OriginalPath: "images/c.png"},
+	} {
+		if err := store.Record(a); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	if err := store.SetRefs("one.md", []string{"hash1", "hash2"}); err != nil {
+		t.Fatalf("set refs: %v", err)
+	}
+
+	got, err := store.ForNote("one.md")
+	if err != nil {
+		t.Fatalf("for note: %v", err)
+	}
+	if len(got) != 2 || got[0].OriginalPath != "images/a.png" || got[1].OriginalPath != "images/b.png" {
+		t.Errorf("ForNote() = %+v, want a.png and b.png", got)
+	}
+	if got, _ := store.ForNote("other.md"); len(got) != 0 {
+		t.Errorf("ForNote() of a note without refs = %+v", got)
+	}
+}
+
+func TestAttachmentStore_Partial(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	store := NewAttachmentStore(db)
+	if p, err := store.GetPartial("hash1", "work"); err != nil || p != nil {
+		t.Fatalf("GetPartial() before saving = %v, %v; want nil", p, err)
+	}
+
+	started := time.Unix(1700000000, 0)
+	for sent := 0; sent <= 2; sent++ {
+		if err := store.SavePartial(&PartialUpload{ContentHash: "hash1", Credential: "work", FileUploadID: "upload-1", Parts: 3, Sent: sent, StartedAt: started}); err != nil {
+			t.Fatalf("SavePartial() error: %v", err)
+		}
+	}
+	p, err := store.GetPartial("hash1", "work")
+	if err != nil || p == nil || p.FileUploadID != "upload-1" || p.Parts != 3 || p.Sent != 2 || !p.StartedAt.Equal(started) {
+		t.Fatalf("GetPartial() = %+v, %v; want upload-1 with 2 of 3 parts sent", p, err)
+	}
+	if p, _ := store.GetPartial("hash1", ""); p != nil {
+		t.Error("partial upload shared across credentials")
+	}
+
+	if err := store.ClearPartial("hash1", "work"); err != nil {
+		t.Fatalf("ClearPartial() error: %v", err)
+	}
+	if p, _ := store.GetPartial("hash1", "work"); p != nil {
+		t.Errorf("GetPartial() after clearing = %+v, want nil", p)
+	}
+}
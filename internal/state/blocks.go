@@ -0,0 +1,175 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// BlockMapping links a top-level markdown block of a note to the Notion
+// block it was last pushed as or pulled from.
+type BlockMapping struct {
+	Index         int    // Position of the block in the note
+	Hash          string // Hash of the block's markdown
+	NotionBlockID string
+	Heading       string // Text of a heading block, or ""
+	Marker        string // Markdown of a thematic break, such as "***", or ""
+}
+
+// HeadingRename is a heading whose text changed while its Notion block
+// stayed the same, which breaks links to the old text.
+type HeadingRename struct {
+	ID        int64
+	Path      string
+	Old       string
+	New       string
+	RenamedAt time.Time
+}
+
+// SetBlockMap replaces the recorded blocks of a note. Mappings are stored
+// by their Index. A heading block recorded before with other text is
+// recorded as a heading rename.
+func (db *DB) SetBlockMap(obsidianPath string, blocks []BlockMapping) error {
+	tx, err := db.begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	headings, err := blockHeadings(tx, obsidianPath)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for _, b := range blocks {
+		old := headings[b.NotionBlockID]
+		if old == "" || b.Heading == "" || old == b.Heading {
+			continue
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO heading_renames (obsidian_path, old_heading, new_heading, renamed_at)
+			VALUES (?, ?, ?, ?)
+		`, obsidianPath, old, b.Heading, now); err != nil {
+			return fmt.Errorf("record heading rename: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM block_map WHERE obsidian_path = ?`, obsidianPath); err != nil {
+		return fmt.Errorf("clear block map: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO block_map (obsidian_path, block_index, block_hash, notion_block_id, heading, marker)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, b := range blocks {
+		if _, err := stmt.Exec(obsidianPath, b.Index, b.Hash, b.NotionBlockID, b.Heading, b.Marker); err != nil {
+			return fmt.Errorf("insert block: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetBlockMap returns the recorded blocks of a note in document order.
+func (db *DB) GetBlockMap(obsidianPath string) ([]BlockMapping, error) {
+	rows, err := db.conn.Query(`
+		SELECT block_index, block_hash, notion_block_id, heading, marker FROM block_map
+		WHERE obsidian_path = ?
+		ORDER BY block_index
+	`, obsidianPath)
+	if err != nil {
+		return nil, fmt.Errorf("query block map: %w", err)
+	}
+	defer rows.Close()
+
+	var blocks []BlockMapping
+	for rows.Next() {
+		var b BlockMapping
+		if err := rows.Scan(&b.Index, &b.Hash, &b.NotionBlockID, &b.Heading, &b.Marker); err != nil {
+			return nil, fmt.Errorf("scan block: %w", err)
+		}
+		blocks = append(blocks, b)
+	}
+
+	return blocks, rows.Err()
+}
+
+// LookupBlock returns the note and mapping of a Notion block, or "" and nil
+// if the block is not recorded.
+func (db *DB) LookupBlock(notionBlockID string) (string, *BlockMapping, error) {
+	var path string
+	b := &BlockMapping{NotionBlockID: notionBlockID}
+	err := db.conn.QueryRow(`
+		SELECT obsidian_path, block_index, block_hash, heading, marker FROM block_map
+		WHERE notion_block_id = ?
+	`, notionBlockID).Scan(&path, &b.Index, &b.Hash, &b.Heading, &b.Marker)
+	if err == sql.ErrNoRows {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("query block map: %w", err)
+	}
+	return path, b, nil
+}
+
+// blockHeadings returns the recorded heading text of a note's blocks by
+// Notion block ID.
+func blockHeadings(q querier, obsidianPath string) (map[string]string, error) {
+	rows, err := q.Query(`
+		SELECT notion_block_id, heading FROM block_map
+		WHERE obsidian_path = ? AND heading != ''
+	`, obsidianPath)
+	if err != nil {
+		return nil, fmt.Errorf("query block map: %w", err)
+	}
+	defer rows.Close()
+
+	headings := make(map[string]string)
+	for rows.Next() {
+		var id, heading string
+		if err := rows.Scan(&id, &heading); err != nil {
+			return nil, fmt.Errorf("scan block: %w", err)
+		}
+		headings[id] = heading
+	}
+	return headings, rows.Err()
+}
+
+// HeadingRenames returns the recorded heading renames, oldest first.
+func (db *DB) HeadingRenames() ([]HeadingRename, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, obsidian_path, old_heading, new_heading, renamed_at FROM heading_renames
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query heading renames: %w", err)
+	}
+	defer rows.Close()
+
+	var renames []HeadingRename
+	for rows.Next() {
+		var r HeadingRename
+		var renamedAt int64
+		if err := rows.Scan(&r.ID, &r.Path, &r.Old, &r.New, &renamedAt); err != nil {
+			return nil, fmt.Errorf("scan heading rename: %w", err)
+		}
+		r.RenamedAt = time.Unix(renamedAt, 0)
+		renames = append(renames, r)
+	}
+	return renames, rows.Err()
+}
+
+// DeleteHeadingRename removes a heading rename once links to it are
+// repaired.
+func (db *DB) DeleteHeadingRename(id int64) error {
+	if _, err := db.conn.Exec(`DELETE FROM heading_renames WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete heading rename: %w", err)
+	}
+	return nil
+}
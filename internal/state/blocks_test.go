@@ -0,0 +1,100 @@
+package state
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDB_BlockMap(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	blocks := []BlockMapping{
+		{Index: 0, Hash: "h0", NotionBlockID: "block-0"},
+		{Index: 1, Hash: "h1", NotionBlockID: "block-1", Marker: "***"},
+	}
+	if err := db.SetBlockMap("note.md", blocks); err != nil {
+		t.Fatalf("SetBlockMap() error: %v", err)
+	}
+	got, err := db.GetBlockMap("note.md")
+	if err != nil {
+		t.Fatalf("GetBlockMap() error: %v", err)
+	}
+	if !reflect.DeepEqual(got, blocks) {
+		t.Errorf("GetBlockMap() = %+v, want %+v", got, blocks)
+	}
+
+	path, b, err := db.LookupBlock("block-1")
+	if err != nil || path != "note.md" || b == nil || b.Index != 1 || b.Hash != "h1" {
+		t.Errorf("LookupBlock(block-1) = %q, %+v, %v; want note.md at index 1", path, b, err)
+	}
+	if path, b, err := db.LookupBlock("unknown"); path != "" || b != nil || err != nil {
+		t.Errorf("LookupBlock(unknown) = %q, %+v, %v; want not found", path, b, err)
+	}
+
+	// Setting the map again replaces it.
+	if err := db.SetBlockMap("note.md", blocks[:1]); err != nil {
+		t.Fatalf("SetBlockMap() error: %v", err)
+	}
+	if got, _ := db.GetBlockMap("note.md"); len(got) != 1 {
+		t.Errorf("GetBlockMap() after replace = %+v, want 1 block", got)
+	}
+
+	// Renames carry the map along, and deleting the state clears it.
+	if err := db.UpdatePath("note.md", "renamed.md"); err != nil {
+		t.Fatalf("UpdatePath() error: %v", err)
+	}
+	if got, _ := db.GetBlockMap("renamed.md"); len(got) != 1 {
+		t.Errorf("GetBlockMap() after rename = %+v, want 1 block", got)
+	}
+	if err := db.DeleteState("renamed.md"); err != nil {
+		t.Fatalf("DeleteState() error: %v", err)
+	}
+	if got, _ := db.GetBlockMap("renamed.md"); len(got) != 0 {
+		t.Errorf("GetBlockMap() after delete = %+v, want none", got)
+	}
+}
+
+func TestDB_HeadingRenames(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	blocks := []BlockMapping{
+		{Index: 0, Hash: "h0", NotionBlockID: "block-0", Heading: "Agenda"},
+		{Index: 1, Hash: "h1", NotionBlockID: "block-1"},
+	}
+	if err := db.SetBlockMap("note.md", blocks); err != nil {
+		t.Fatalf("SetBlockMap() error: %v", err)
+	}
+	if renames, _ := db.HeadingRenames(); len(renames) != 0 {
+		t.Errorf("expected no renames for a new map, got %+v", renames)
+	}
+
+	// The same heading block with new text is a rename.
+	blocks[0].Heading = "Meeting agenda"
+	blocks[0].Hash = "h0b"
+	if err := db.SetBlockMap("note.md", blocks); err != nil {
+		t.Fatalf("SetBlockMap() error: %v", err)
+	}
+	renames, err := db.HeadingRenames()
+	if err != nil {
+		t.Fatalf("HeadingRenames() error: %v", err)
+	}
+	if len(renames) != 1 || renames[0].Path != "note.md" || renames[0].Old != "Agenda" || renames[0].New != "Meeting agenda" {
+		t.Fatalf("HeadingRenames() = %+v, want Agenda -> Meeting agenda", renames)
+	}
+
+	if err := db.DeleteHeadingRename(renames[0].ID); err != nil {
+		t.Fatalf("DeleteHeadingRename() error: %v", err)
+	}
+	if renames, _ := db.HeadingRenames(); len(renames) != 0 {
+		t.Errorf("expected rename deleted, got %+v", renames)
+	}
+}
@@ -46,12 +46,25 @@ type Change struct {
 	// This enables property-only updates in Notion without re-syncing content.
 	FrontmatterOnly bool
 	LocalHashes     ContentHashes // Full hash breakdown for local content
+
+	// Retitled indicates the change only edits the title frontmatter, so
+	// only the page title needs updating.
+	Retitled bool
 }
 
 // ChangeDetector detects changes between the local vault and sync state.
 type ChangeDetector struct {
 	db        *DB
 	vaultPath string
+
+	// exclude reports paths left out of change detection.
+	exclude func(path string) bool
+
+	// explain, if set, is told why files are not reported as changed.
+	explain func(path, reason string)
+
+	// hasher hashes notes to compare with their sync state.
+	hasher Hasher
 }
 
 // NewChangeDetector creates a new ChangeDetector.
@@ -62,6 +75,48 @@ func NewChangeDetector(db *DB, vaultPath string) *ChangeDetector {
 	}
 }
 
+// SetExclude sets a filter for paths left out of change detection.
+// Excluded notes are never reported, so notes synced before they were
+// excluded are not treated as deleted either.
+func (d *ChangeDetector) SetExclude(exclude func(path string) bool) {
+	d.exclude = exclude
+}
+
+// SetHasher sets how notes are hashed for comparison with the hashes
+// stored in their sync state.
+func (d *ChangeDetector) SetHasher(h Hasher) {
+	d.hasher = h
+}
+
+// excluded reports whether a path is left out of change detection.
+func (d *ChangeDetector) excluded(path string) bool {
+	return d.exclude != nil && d.exclude(path)
+}
+
+// SetExplain sets a function told why files are not reported as changed,
+// such as a file that was saved without changing its content, for
+// diagnosing why a note did not sync.
+func (d *ChangeDetector) SetExplain(explain func(path, reason string)) {
+	d.explain = explain
+}
+
+// explainf tells the explain function why a file is not reported.
+func (d *ChangeDetector) explainf(path, format string, args ...any) {
+	if d.explain != nil {
+		d.explain(path, fmt.Sprintf(format, args...))
+	}
+}
+
+// retitled reports whether a frontmatter-only change edits nothing but the
+// note's title, compared with the title it was last pushed with.
+func (d *ChangeDetector) retitled(path string, content []byte, state *SyncState) bool {
+	oldTitle, err := NewLinkRegistry(d.db).Title(path)
+	if err != nil {
+		return false
+	}
+	return d.hasher.IsTitleOnlyChange(content, oldTitle, state.FrontmatterHash)
+}
+
 // DetectChanges scans the vault and compares with stored sync state.
 func (d *ChangeDetector) DetectChanges(ctx context.Context) ([]Change, error) {
 	var changes []Change
@@ -102,9 +157,10 @@ func (d *ChangeDetector) DetectChanges(ctx context.Context) ([]Change, error) {
 			// New local file - compute hash for rename detection.
 			content, err := os.ReadFile(filepath.Join(d.vaultPath, path))
 			if err != nil {
+				d.explainf(path, "skipped: %v", err)
 				continue // Skip files we can't read.
 			}
-			localHashes := HashContent(content)
+			localHashes := d.hasher.HashContent(content)
 			newFiles[path] = fileWithHash{
 				info:   info,
 				hash:   localHashes.FullHash,
@@ -116,11 +172,15 @@ func (d *ChangeDetector) DetectChanges(ctx context.Context) ([]Change, error) {
 		// File exists in state - check for modifications.
 		content, err := os.ReadFile(filepath.Join(d.vaultPath, path))
 		if err != nil {
+			d.explainf(path, "skipped: %v", err)
 			continue // Skip files we can't read.
 		}
 
-		localHashes := HashContent(content)
+		localHashes := d.hasher.HashContent(content)
 		stateHashes := HashesFromState(state)
+		if !HasContentChanged(stateHashes, localHashes) && !info.ModTime().Equal(state.ObsidianMtime) {
+			d.explainf(path, "unchanged: saved since the last sync, but its content hash is the same")
+		}
 
 		// Check if content has changed using normalized comparison.
 		if HasContentChanged(stateHashes, localHashes) {
@@ -139,6 +199,9 @@ func (d *ChangeDetector) DetectChanges(ctx context.Context) ([]Change, error) {
 				FrontmatterOnly: frontmatterOnly,
 				LocalHashes:     localHashes,
 			}
+			if frontmatterOnly {
+				change.Retitled = d.retitled(path, content, state)
+			}
 
 			// Check if remote was also modified (conflict).
 			// Note: Full remote change detection is handled by RemoteChangeDetector.
@@ -158,7 +221,7 @@ func (d *ChangeDetector) DetectChanges(ctx context.Context) ([]Change, error) {
 
 	// 4. Collect deleted files (in state but not in vault).
 	for path, state := range stateMap {
-		if state.NotionPageID != "" && state.Status == "synced" {
+		if state.NotionPageID != "" && (state.Status == "synced" || state.Status == "degraded") && !d.excluded(path) {
 			deletedStates[path] = state
 		}
 	}
@@ -268,7 +331,7 @@ func (d *ChangeDetector) DetectRemoteChanges(ctx context.Context, getRemoteInfo
 				continue
 			}
 
-			localHashes := HashContent(localContent)
+			localHashes := d.hasher.HashContent(localContent)
 			stateHashes := HashesFromState(state)
 
 			if HasContentChanged(stateHashes, localHashes) {
@@ -319,6 +382,11 @@ func (d *ChangeDetector) scanVault() (map[string]fs.FileInfo, error) {
 				return err
 			}
 
+			if d.excluded(relPath) {
+				d.explainf(relPath, "excluded from change detection")
+				return nil
+			}
+
 			info, err := entry.Info()
 			if err != nil {
 				return err
@@ -336,19 +404,31 @@ func (d *ChangeDetector) scanVault() (map[string]fs.FileInfo, error) {
 // HashFile computes a normalized SHA-256 hash of a file.
 // Returns the FullHash which considers both frontmatter and body content.
 func HashFile(path string) (string, error) {
+	return Hasher{}.HashFile(path)
+}
+
+// HashFile computes a normalized hash of a file like the HashFile
+// function, with the hasher's settings.
+func (h Hasher) HashFile(path string) (string, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return "", err
 	}
-	return HashContent(content).FullHash, nil
+	return h.HashContent(content).FullHash, nil
 }
 
 // HashFileDetailed computes all hashes for a file.
 // Returns ContentHashes with separate frontmatter and body hashes.
 func HashFileDetailed(path string) (ContentHashes, error) {
+	return Hasher{}.HashFileDetailed(path)
+}
+
+// HashFileDetailed computes all hashes for a file like the
+// HashFileDetailed function, with the hasher's settings.
+func (h Hasher) HashFileDetailed(path string) (ContentHashes, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return ContentHashes{}, err
 	}
-	return HashContent(content), nil
+	return h.HashContent(content), nil
 }
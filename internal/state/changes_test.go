@@ -4,7 +4,9 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDetectRenames(t *testing.T) {
@@ -126,6 +128,149 @@ func TestDetectDeletions(t *testing.T) {
 	}
 }
 
+func TestDetectChanges_Exclude(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	// A template synced before it was excluded, since removed, and a new one.
+	err = db.SetState(&SyncState{
+		ObsidianPath: filepath.Join("Templates", "old.md"),
+		NotionPageID: "page-1",
+		ContentHash:  "somehash",
+		Status:       "synced",
+	})
+	if err != nil {
+		t.Fatalf("set state: %v", err)
+	}
+	for _, path := range []string{filepath.Join("Templates", "daily.md"), "note.md"} {
+		full := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(full, []byte("# {{title}}\n"), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+
+	detector := NewChangeDetector(db, tmpDir)
+	detector.SetExclude(func(path string) bool {
+		return filepath.Dir(path) == "Templates"
+	})
+	changes, err := detector.DetectChanges(context.Background())
+	if err != nil {
+		t.Fatalf("detect changes: %v", err)
+	}
+
+	if len(changes) != 1 || changes[0].Path != "note.md" || changes[0].Type != ChangeCreated {
+		t.Errorf("changes = %+v, want only note.md created", changes)
+	}
+}
+
+func TestDetectChanges_Explain(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	// A note saved since its sync without changing, and a template.
+	content := []byte("# Saved\n")
+	hashes := HashContent(content)
+	err = db.SetState(&SyncState{
+		ObsidianPath:    "saved.md",
+		NotionPageID:    "page-1",
+		ContentHash:     hashes.ContentHash,
+		FrontmatterHash: hashes.FrontmatterHash,
+		ObsidianMtime:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Status:          "synced",
+	})
+	if err != nil {
+		t.Fatalf("set state: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "saved.md"), content, 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "Templates"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "Templates", "daily.md"), content, 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	explained := make(map[string]string)
+	detector := NewChangeDetector(db, tmpDir)
+	detector.SetExclude(func(path string) bool {
+		return filepath.Dir(path) == "Templates"
+	})
+	detector.SetExplain(func(path, reason string) {
+		explained[path] = reason
+	})
+	changes, err := detector.DetectChanges(context.Background())
+	if err != nil {
+		t.Fatalf("detect changes: %v", err)
+	}
+
+	if len(changes) != 0 {
+		t.Errorf("changes = %+v, want none", changes)
+	}
+	if reason := explained["saved.md"]; !strings.HasPrefix(reason, "unchanged:") {
+		t.Errorf("saved.md explained as %q, want unchanged", reason)
+	}
+	if reason := explained[filepath.Join("Templates", "daily.md")]; !strings.HasPrefix(reason, "excluded") {
+		t.Errorf("template explained as %q, want excluded", reason)
+	}
+}
+
+func TestDetectChanges_Hasher(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	// A note synced with sorted keys, since rewritten with them reordered.
+	canonical := Hasher{CanonicalFrontmatter: true}
+	hashes := canonical.HashContent([]byte("---\nstatus: draft\ntags: [a]\n---\nBody\n"))
+	if err := os.WriteFile(filepath.Join(tmpDir, "note.md"), []byte("---\ntags: [a]\nstatus: draft\n---\nBody\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	err = db.SetState(&SyncState{
+		ObsidianPath:    "note.md",
+		NotionPageID:    "page-1",
+		ContentHash:     hashes.ContentHash,
+		FrontmatterHash: hashes.FrontmatterHash,
+		Status:          "synced",
+	})
+	if err != nil {
+		t.Fatalf("set state: %v", err)
+	}
+
+	detector := NewChangeDetector(db, tmpDir)
+	detector.SetHasher(canonical)
+	changes, err := detector.DetectChanges(context.Background())
+	if err != nil {
+		t.Fatalf("detect changes: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("changes with canonical frontmatter = %+v, want none", changes)
+	}
+
+	// The default hasher sees the reordered keys.
+	changes, err = NewChangeDetector(db, tmpDir).DetectChanges(context.Background())
+	if err != nil {
+		t.Fatalf("detect changes: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Type != ChangeModified {
+		t.Errorf("changes = %+v, want note.md modified", changes)
+	}
+}
+
 func TestDetectCreations(t *testing.T) {
 	// Create temporary directory for test vault.
 	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
@@ -309,6 +454,44 @@ This body stays the same.
 	if !change.FrontmatterOnly {
 		t.Error("expected FrontmatterOnly to be true for frontmatter-only change")
 	}
+	// The tags changed too, so it is not a retitle.
+	if change.Retitled {
+		t.Error("expected Retitled to be false when other keys changed")
+	}
+}
+
+func TestDetectRetitle(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	original := HashContent([]byte("---\ntitle: Old Title\ntags: [a]\n---\n\nBody.\n"))
+	if err := db.SetState(&SyncState{
+		ObsidianPath:    "note.md",
+		NotionPageID:    "page-1",
+		ContentHash:     original.ContentHash,
+		FrontmatterHash: original.FrontmatterHash,
+		Status:          "synced",
+	}); err != nil {
+		t.Fatalf("set state: %v", err)
+	}
+	if err := NewLinkRegistry(db).RegisterAlias("note.md", "Old Title", "title"); err != nil {
+		t.Fatalf("register alias: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "note.md"), []byte("---\ntitle: \"New Title\"\ntags: [a]\n---\n\nBody.\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	changes, err := NewChangeDetector(db, tmpDir).DetectChanges(context.Background())
+	if err != nil {
+		t.Fatalf("detect changes: %v", err)
+	}
+	if len(changes) != 1 || !changes[0].Retitled {
+		t.Errorf("changes = %+v, want one retitle", changes)
+	}
 }
 
 func TestDetectBodyOnlyModification(t *testing.T) {
@@ -0,0 +1,65 @@
+package state
+
+import "fmt"
+
+// CompositeMember records a note composed into a shared Notion page and
+// the Heading 1 text that starts its section.
+type CompositeMember struct {
+	ObsidianPath string
+	Heading      string
+}
+
+// SetCompositeMembers replaces the members of a composed page, in
+// section order.
+func (db *DB) SetCompositeMembers(notionPageID string, members []CompositeMember) error {
+	tx, err := db.begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM composite_members WHERE notion_page_id = ?`, notionPageID); err != nil {
+		return fmt.Errorf("clear members: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO composite_members (notion_page_id, position, obsidian_path, heading)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, m := range members {
+		if _, err := stmt.Exec(notionPageID, i, m.ObsidianPath, m.Heading); err != nil {
+			return fmt.Errorf("insert member: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetCompositeMembers returns the members of a composed page in section order.
+func (db *DB) GetCompositeMembers(notionPageID string) ([]CompositeMember, error) {
+	rows, err := db.conn.Query(`
+		SELECT obsidian_path, heading FROM composite_members
+		WHERE notion_page_id = ?
+		ORDER BY position
+	`, notionPageID)
+	if err != nil {
+		return nil, fmt.Errorf("query members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []CompositeMember
+	for rows.Next() {
+		var m CompositeMember
+		if err := rows.Scan(&m.ObsidianPath, &m.Heading); err != nil {
+			return nil, fmt.Errorf("scan member: %w", err)
+		}
+		members = append(members, m)
+	}
+
+	return members, rows.Err()
+}
@@ -0,0 +1,54 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDB_CompositeMembers(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	members := []CompositeMember{
+		{ObsidianPath: "meetings/2024-01-08.md", Heading: "2024-01-08 Kickoff"},
+		{ObsidianPath: "meetings/2024-01-15.md", Heading: "2024-01-15 Review"},
+	}
+	if err := db.SetCompositeMembers("page-1", members); err != nil {
+		t.Fatalf("SetCompositeMembers() error: %v", err)
+	}
+
+	got, err := db.GetCompositeMembers("page-1")
+	if err != nil {
+		t.Fatalf("GetCompositeMembers() error: %v", err)
+	}
+	if len(got) != 2 || got[0] != members[0] || got[1] != members[1] {
+		t.Errorf("GetCompositeMembers() = %+v, want %+v", got, members)
+	}
+
+	// Renames keep the member's position.
+	if err := db.UpdatePath("meetings/2024-01-08.md", "meetings/2024-01-08 kickoff.md"); err != nil {
+		t.Fatalf("UpdatePath() error: %v", err)
+	}
+	got, _ = db.GetCompositeMembers("page-1")
+	if len(got) != 2 || got[0].ObsidianPath != "meetings/2024-01-08 kickoff.md" {
+		t.Errorf("expected member to follow rename, got %+v", got)
+	}
+
+	// Replacing drops members that are gone.
+	if err := db.SetCompositeMembers("page-1", members[1:]); err != nil {
+		t.Fatalf("SetCompositeMembers() error: %v", err)
+	}
+	if got, _ := db.GetCompositeMembers("page-1"); len(got) != 1 {
+		t.Errorf("expected 1 member, got %d", len(got))
+	}
+}
@@ -30,6 +30,13 @@ func NewConflictTracker(db *DB) *ConflictTracker {
 
 // RecordConflict records a new conflict for a path.
 func (t *ConflictTracker) RecordConflict(info *ConflictInfo) error {
+	return t.db.InTx(func(tx *DB) error {
+		return NewConflictTracker(tx).recordConflict(info)
+	})
+}
+
+// recordConflict implements RecordConflict inside a transaction.
+func (t *ConflictTracker) recordConflict(info *ConflictInfo) error {
 	// Update sync state to conflict status.
 	state, err := t.db.GetState(info.Path)
 	if err != nil {
@@ -55,6 +62,13 @@ func (t *ConflictTracker) RecordConflict(info *ConflictInfo) error {
 
 // ResolveConflict marks a conflict as resolved.
 func (t *ConflictTracker) ResolveConflict(path string, resolution string, contentHash string) error {
+	return t.db.InTx(func(tx *DB) error {
+		return NewConflictTracker(tx).resolveConflict(path, resolution, contentHash)
+	})
+}
+
+// resolveConflict implements ResolveConflict inside a transaction.
+func (t *ConflictTracker) resolveConflict(path string, resolution string, contentHash string) error {
 	// Update sync state.
 	state, err := t.db.GetState(path)
 	if err != nil {
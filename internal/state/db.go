@@ -12,8 +12,9 @@ import (
 
 // DB wraps the SQLite database connection for sync state management.
 type DB struct {
-	conn *sql.DB
-	path string
+	conn  querier // The connection pool, or the transaction in InTx
+	sqlDB *sql.DB
+	path  string
 }
 
 // SyncState represents the sync state for a single note.
@@ -28,103 +29,46 @@ type SyncState struct {
 	NotionMtime     time.Time
 	LastSync        time.Time
 	SyncDirection   string
-	Status          string // "synced", "pending", "conflict", "error"
+	Status          string // "synced", "pending", "conflict", "error", "archived", "degraded", "blocked"
 }
 
-// Open opens or creates a sync state database at the given path.
+// Open opens or creates a sync state database at the given path, upgrading
+// its schema to the current version. An existing database is backed up
+// before it is migrated.
 func Open(path string) (*DB, error) {
-	conn, err := sql.Open("sqlite3", path+"?_journal_mode=WAL")
+	db, err := OpenUnmigrated(path)
 	if err != nil {
-		return nil, fmt.Errorf("open database: %w", err)
+		return nil, err
 	}
 
-	db := &DB{
-		conn: conn,
-		path: path,
+	if _, err := db.Migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate schema: %w", err)
 	}
 
-	if err := db.initSchema(); err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("init schema: %w", err)
+	return db, nil
+}
+
+// OpenUnmigrated opens a sync state database without upgrading its schema,
+// so pending migrations can be inspected first.
+func OpenUnmigrated(path string) (*DB, error) {
+	// Transactions take the write lock up front and wait for each other,
+	// so parallel workers don't fail with "database is locked".
+	conn, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
 	}
 
-	return db, nil
+	return &DB{
+		conn:  conn,
+		sqlDB: conn,
+		path:  path,
+	}, nil
 }
 
 // Close closes the database connection.
 func (db *DB) Close() error {
-	return db.conn.Close()
-}
-
-// initSchema creates the database schema if it doesn't exist.
-func (db *DB) initSchema() error {
-	schema := `
-	-- Core sync state
-	CREATE TABLE IF NOT EXISTS sync_state (
-		id INTEGER PRIMARY KEY,
-		obsidian_path TEXT UNIQUE NOT NULL,
-		notion_page_id TEXT,
-		notion_parent_id TEXT,
-		content_hash TEXT NOT NULL,
-		frontmatter_hash TEXT,
-		obsidian_mtime INTEGER,
-		notion_mtime INTEGER,
-		last_sync INTEGER,
-		sync_direction TEXT,
-		status TEXT DEFAULT 'pending'
-	);
-
-	-- Link resolution cache
-	CREATE TABLE IF NOT EXISTS links (
-		id INTEGER PRIMARY KEY,
-		source_path TEXT NOT NULL,
-		target_name TEXT NOT NULL,
-		target_path TEXT,
-		notion_page_id TEXT,
-		resolved INTEGER DEFAULT 0
-	);
-
-	-- Sync history for conflict resolution
-	CREATE TABLE IF NOT EXISTS sync_history (
-		id INTEGER PRIMARY KEY,
-		obsidian_path TEXT NOT NULL,
-		action TEXT NOT NULL,
-		timestamp INTEGER NOT NULL,
-		content_hash TEXT,
-		details TEXT
-	);
-
-	-- Configuration
-	CREATE TABLE IF NOT EXISTS config (
-		key TEXT PRIMARY KEY,
-		value TEXT
-	);
-
-	-- Page aliases for wiki-link resolution by title/alias
-	-- Maps frontmatter titles and aliases to obsidian paths
-	CREATE TABLE IF NOT EXISTS page_aliases (
-		obsidian_path TEXT NOT NULL,
-		alias_name TEXT NOT NULL,
-		alias_type TEXT DEFAULT 'title',
-		PRIMARY KEY (obsidian_path, alias_name)
-	);
-
-	-- Indexes
-	CREATE INDEX IF NOT EXISTS idx_sync_state_status ON sync_state(status);
-	CREATE INDEX IF NOT EXISTS idx_sync_state_notion_page ON sync_state(notion_page_id);
-	CREATE INDEX IF NOT EXISTS idx_links_source ON links(source_path);
-	CREATE INDEX IF NOT EXISTS idx_links_target ON links(target_name);
-	CREATE INDEX IF NOT EXISTS idx_history_path ON sync_history(obsidian_path);
-
-	-- Unique constraint to prevent duplicate links
-	CREATE UNIQUE INDEX IF NOT EXISTS idx_links_unique ON links(source_path, target_name);
-
-	-- Index for fast alias lookups by name
-	CREATE INDEX IF NOT EXISTS idx_aliases_name ON page_aliases(alias_name);
-	`
-
-	_, err := db.conn.Exec(schema)
-	return err
+	return db.sqlDB.Close()
 }
 
 // GetState retrieves the sync state for a given Obsidian path.
@@ -208,14 +152,66 @@ func (db *DB) SetState(state *SyncState) error {
 
 // DeleteState removes the sync state for a path.
 func (db *DB) DeleteState(path string) error {
-	_, err := db.conn.Exec(`DELETE FROM sync_state WHERE obsidian_path = ?`, path)
-	return err
+	return db.InTx(func(tx *DB) error {
+		if _, err := tx.conn.Exec(`DELETE FROM sync_state WHERE obsidian_path = ?`, path); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`DELETE FROM page_sections WHERE obsidian_path = ?`, path); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`DELETE FROM note_tags WHERE obsidian_path = ?`, path); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`DELETE FROM note_tasks WHERE obsidian_path = ?`, path); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`DELETE FROM heading_renames WHERE obsidian_path = ?`, path); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`DELETE FROM note_targets WHERE obsidian_path = ?`, path); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`DELETE FROM missing_mentions WHERE source_path = ?`, path); err != nil {
+			return err
+		}
+		_, err := tx.conn.Exec(`DELETE FROM block_map WHERE obsidian_path = ?`, path)
+		return err
+	})
 }
 
 // UpdatePath updates the obsidian_path for a sync state (used for renames).
 func (db *DB) UpdatePath(oldPath, newPath string) error {
-	_, err := db.conn.Exec(`UPDATE sync_state SET obsidian_path = ? WHERE obsidian_path = ?`, newPath, oldPath)
-	return err
+	return db.InTx(func(tx *DB) error {
+		if _, err := tx.conn.Exec(`UPDATE sync_state SET obsidian_path = ? WHERE obsidian_path = ?`, newPath, oldPath); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`UPDATE page_sections SET obsidian_path = ? WHERE obsidian_path = ?`, newPath, oldPath); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`UPDATE note_tags SET obsidian_path = ? WHERE obsidian_path = ?`, newPath, oldPath); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`UPDATE note_tasks SET obsidian_path = ? WHERE obsidian_path = ?`, newPath, oldPath); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`UPDATE heading_renames SET obsidian_path = ? WHERE obsidian_path = ?`, newPath, oldPath); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`UPDATE nested_databases SET parent_path = ? WHERE parent_path = ?`, newPath, oldPath); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`UPDATE composite_members SET obsidian_path = ? WHERE obsidian_path = ?`, newPath, oldPath); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`UPDATE note_targets SET obsidian_path = ? WHERE obsidian_path = ?`, newPath, oldPath); err != nil {
+			return err
+		}
+		if _, err := tx.conn.Exec(`UPDATE missing_mentions SET source_path = ? WHERE source_path = ?`, newPath, oldPath); err != nil {
+			return err
+		}
+		_, err := tx.conn.Exec(`UPDATE block_map SET obsidian_path = ? WHERE obsidian_path = ?`, newPath, oldPath)
+		return err
+	})
 }
 
 // ListStates returns all sync states matching the given status filter.
@@ -3,6 +3,7 @@ package state
 import (
 	"path/filepath"
 	"strings"
+	"time"
 	"unicode"
 )
 
@@ -23,7 +24,8 @@ type MatchResult struct {
 	PageID   string
 	Name     string
 	Score    MatchScore
-	Distance int // Levenshtein distance for fuzzy matches
+	Distance int       // Levenshtein distance for fuzzy matches
+	Modified time.Time // Last local change, used to rank otherwise equal matches
 }
 
 // FuzzyMatcher provides fuzzy string matching capabilities.
@@ -114,7 +116,8 @@ func (m *FuzzyMatcher) FindBestMatches(target string, candidates []MatchResult,
 	return matches
 }
 
-// sortMatches sorts matches by score (best first), then by distance.
+// sortMatches sorts matches by score (best first), then by distance and
+// recency.
 func sortMatches(matches []MatchResult) {
 	// Simple bubble sort for small lists.
 	for i := 0; i < len(matches); i++ {
@@ -133,7 +136,17 @@ func compareMatches(a, b MatchResult) int {
 		return int(b.Score) - int(a.Score)
 	}
 	// Lower distance is better.
-	return a.Distance - b.Distance
+	if a.Distance != b.Distance {
+		return a.Distance - b.Distance
+	}
+	// More recently modified notes are better.
+	switch {
+	case a.Modified.After(b.Modified):
+		return -1
+	case b.Modified.After(a.Modified):
+		return 1
+	}
+	return 0
 }
 
 // normalizeForMatch normalizes a string for fuzzy comparison.
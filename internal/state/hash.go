@@ -5,6 +5,8 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -18,6 +20,17 @@ var (
 	multipleBlankLines = regexp.MustCompile(`\n{3,}`)
 )
 
+// Hasher computes content hashes. The zero Hasher hashes like
+// HashContent.
+type Hasher struct {
+	// CanonicalFrontmatter makes hashes ignore the order of frontmatter
+	// keys and blank lines in frontmatter, so notes rewritten with
+	// reordered keys are not seen as changed (sync.ignore_trivial_changes).
+	// Hashes stored in state are compared with the ones computed later, so
+	// every hash of a vault should be computed with the same setting.
+	CanonicalFrontmatter bool
+}
+
 // ContentHashes holds both content and frontmatter hashes.
 type ContentHashes struct {
 	// ContentHash is the hash of the normalized body content (excluding frontmatter).
@@ -35,11 +48,17 @@ type ContentHashes struct {
 // - Collapsing multiple blank lines to at most 2
 // - Trimming leading/trailing whitespace from the file
 func HashContent(content []byte) ContentHashes {
+	return Hasher{}.HashContent(content)
+}
+
+// HashContent computes normalized content hashes like the HashContent
+// function, with the hasher's settings.
+func (h Hasher) HashContent(content []byte) ContentHashes {
 	// Split frontmatter and body.
 	frontmatter, body := splitFrontmatter(content)
 
 	// Normalize both parts.
-	normalizedFM := normalizeContent(frontmatter)
+	normalizedFM := normalizeFrontmatter(frontmatter, h.CanonicalFrontmatter)
 	normalizedBody := normalizeContent(body)
 
 	// Compute hashes.
@@ -135,6 +154,30 @@ func normalizeContent(content []byte) []byte {
 	return []byte(s)
 }
 
+// normalizeFrontmatter normalizes frontmatter like normalizeContent. If
+// canonical, blank lines are dropped and top-level keys, each with the
+// indented or list lines below it, are sorted.
+func normalizeFrontmatter(frontmatter []byte, canonical bool) []byte {
+	if !canonical || len(frontmatter) == 0 {
+		return normalizeContent(frontmatter)
+	}
+
+	var entries []string
+	for _, line := range strings.Split(strings.ReplaceAll(string(frontmatter), "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		switch {
+		case line == "":
+			continue
+		case len(entries) > 0 && strings.ContainsRune(" \t-", rune(line[0])):
+			entries[len(entries)-1] += "\n" + line
+		default:
+			entries = append(entries, line)
+		}
+	}
+	sort.Strings(entries)
+	return normalizeContent([]byte(strings.Join(entries, "\n")))
+}
+
 // computeHash computes SHA-256 hash and returns hex string.
 func computeHash(content []byte) string {
 	hash := sha256.Sum256(content)
@@ -160,6 +203,77 @@ func HasFrontmatterChanged(oldHashes, newHashes ContentHashes) bool {
 	return oldHashes.FrontmatterHash != newHashes.FrontmatterHash
 }
 
+// titleLine matches the title key of frontmatter and its value.
+var titleLine = regexp.MustCompile(`(?m)^title:[ \t]*(.*?)[ \t]*$`)
+
+// IsTitleOnlyChange reports whether the frontmatter of content differs
+// from the frontmatter hashed as frontmatterHash in its title key alone,
+// changed from oldTitle (or added, if oldTitle is empty) to a new title.
+func IsTitleOnlyChange(content []byte, oldTitle, frontmatterHash string) bool {
+	return Hasher{}.IsTitleOnlyChange(content, oldTitle, frontmatterHash)
+}
+
+// IsTitleOnlyChange is like the IsTitleOnlyChange function, for a
+// frontmatter hash computed with the hasher's settings.
+func (h Hasher) IsTitleOnlyChange(content []byte, oldTitle, frontmatterHash string) bool {
+	frontmatter, _ := splitFrontmatter(content)
+	loc := titleLine.FindSubmatchIndex(frontmatter)
+	if loc == nil {
+		return false
+	}
+	title := unquoteYAML(string(frontmatter[loc[2]:loc[3]]))
+	if title == "" || title == oldTitle {
+		return false
+	}
+
+	// Put the old title back in each way it may have been written.
+	var candidates [][]byte
+	if oldTitle == "" {
+		end := loc[1]
+		if end < len(frontmatter) {
+			end++ // The line's newline.
+		}
+		candidates = append(candidates, spliceBytes(frontmatter, loc[0], end, ""))
+	} else {
+		quoted := strings.ReplaceAll(oldTitle, "'", "''")
+		for _, line := range []string{"title: " + oldTitle, "title: " + strconv.Quote(oldTitle), "title: '" + quoted + "'"} {
+			candidates = append(candidates, spliceBytes(frontmatter, loc[0], loc[1], line))
+		}
+	}
+	for _, c := range candidates {
+		var hash string
+		if normalized := normalizeFrontmatter(c, h.CanonicalFrontmatter); len(normalized) > 0 {
+			hash = computeHash(normalized)
+		}
+		if hash == frontmatterHash {
+			return true
+		}
+	}
+	return false
+}
+
+// spliceBytes returns b with b[start:end] replaced by s.
+func spliceBytes(b []byte, start, end int, s string) []byte {
+	out := make([]byte, 0, len(b)-(end-start)+len(s))
+	out = append(out, b[:start]...)
+	out = append(out, s...)
+	return append(out, b[end:]...)
+}
+
+// unquoteYAML returns a YAML scalar written on one line without its quotes.
+func unquoteYAML(value string) string {
+	switch {
+	case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+		if s, err := strconv.Unquote(value); err == nil {
+			return s
+		}
+		return value[1 : len(value)-1]
+	case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+	return value
+}
+
 // HashesFromState creates ContentHashes from a SyncState.
 // Returns empty ContentHashes if state is nil.
 func HashesFromState(state *SyncState) ContentHashes {
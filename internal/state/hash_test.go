@@ -194,6 +194,24 @@ title: Same Title
 	}
 }
 
+func TestHashContent_CanonicalFrontmatter(t *testing.T) {
+	original := []byte("---\ntitle: Note\ntags:\n- a\n- b\naliases: [x]\n---\nBody\n")
+	reordered := []byte("---\naliases: [x]   \n\ntags:\n- a\n- b\ntitle: Note\n---\nBody\n")
+	edited := []byte("---\naliases: [x]\ntags:\n- b\n- a\ntitle: Note\n---\nBody\n")
+
+	if HashContent(original).FrontmatterHash == HashContent(reordered).FrontmatterHash {
+		t.Fatal("reordered frontmatter hashes the same without canonical frontmatter")
+	}
+
+	h := Hasher{CanonicalFrontmatter: true}
+	if HasContentChanged(h.HashContent(original), h.HashContent(reordered)) {
+		t.Error("reordered keys and blank lines seen as a change with canonical frontmatter")
+	}
+	if !HasContentChanged(h.HashContent(original), h.HashContent(edited)) {
+		t.Error("reordered list values not seen as a change with canonical frontmatter")
+	}
+}
+
 func TestHashContentRaw(t *testing.T) {
 	content := []byte("test content")
 	hash := HashContentRaw(content)
@@ -513,3 +531,31 @@ func TestHashContent_LargeFile(t *testing.T) {
 		t.Error("expected non-empty FullHash for large file")
 	}
 }
+
+func TestIsTitleOnlyChange(t *testing.T) {
+	stored := HashContent([]byte("---\ntitle: Old\nstatus: draft\n---\nBody\n")).FrontmatterHash
+	untitled := HashContent([]byte("---\nstatus: draft\n---\nBody\n")).FrontmatterHash
+
+	tests := []struct {
+		name     string
+		content  string
+		oldTitle string
+		hash     string
+		want     bool
+	}{
+		{"retitled", "---\ntitle: New\nstatus: draft\n---\nBody\n", "Old", stored, true},
+		{"quoted", "---\ntitle: 'It''s new'\nstatus: draft\n---\nBody\n", "Old", stored, true},
+		{"title added", "---\ntitle: New\nstatus: draft\n---\nBody\n", "", untitled, true},
+		{"other key changed", "---\ntitle: New\nstatus: done\n---\nBody\n", "Old", stored, false},
+		{"title removed", "---\nstatus: draft\n---\nBody\n", "Old", stored, false},
+		{"same title", "---\ntitle: Old\nstatus: draft\n---\nBody\n", "Old", stored, false},
+		{"unknown old title", "---\ntitle: New\nstatus: draft\n---\nBody\n", "Other", stored, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTitleOnlyChange([]byte(tt.content), tt.oldTitle, tt.hash); got != tt.want {
+				t.Errorf("IsTitleOnlyChange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 // LinkRegistry manages the mapping between Obsidian wiki-links and Notion page IDs.
@@ -26,7 +28,8 @@ type ResolveResult struct {
 
 // LinkSuggestion represents a possible match for an unresolved link.
 type LinkSuggestion struct {
-	Target      string // Original unresolved target
+	Target      string   // Original unresolved target
+	Sources     []string // Notes containing the link
 	Suggestions []MatchResult
 }
 
@@ -60,7 +63,7 @@ func (r *LinkRegistry) RegisterLink(sourcePath, targetName string) error {
 
 // RegisterLinks records multiple wiki-links from a source note.
 func (r *LinkRegistry) RegisterLinks(sourcePath string, targetNames []string) error {
-	tx, err := r.db.conn.Begin()
+	tx, err := r.db.begin()
 	if err != nil {
 		return fmt.Errorf("begin transaction: %w", err)
 	}
@@ -85,6 +88,21 @@ func (r *LinkRegistry) RegisterLinks(sourcePath string, targetNames []string) er
 	return tx.Commit()
 }
 
+// ReplaceLinks replaces the links from a source note in one transaction, so
+// a crash never leaves the note with its links cleared.
+func (r *LinkRegistry) ReplaceLinks(sourcePath string, targetNames []string) error {
+	return r.db.InTx(func(tx *DB) error {
+		registry := NewLinkRegistry(tx)
+		if err := registry.ClearLinksFrom(sourcePath); err != nil {
+			return fmt.Errorf("clear links: %w", err)
+		}
+		if len(targetNames) == 0 {
+			return nil
+		}
+		return registry.RegisterLinks(sourcePath, targetNames)
+	})
+}
+
 // ClearLinksFrom removes all links originating from a source path.
 func (r *LinkRegistry) ClearLinksFrom(sourcePath string) error {
 	_, err := r.db.conn.Exec(`DELETE FROM links WHERE source_path = ?`, sourcePath)
@@ -113,7 +131,7 @@ func (r *LinkRegistry) RegisterAlias(obsidianPath, aliasName, aliasType string)
 
 // RegisterAliases registers multiple aliases for a file path.
 func (r *LinkRegistry) RegisterAliases(obsidianPath string, aliases []string, aliasType string) error {
-	tx, err := r.db.conn.Begin()
+	tx, err := r.db.begin()
 	if err != nil {
 		return fmt.Errorf("begin transaction: %w", err)
 	}
@@ -148,6 +166,21 @@ func (r *LinkRegistry) ClearAliases(obsidianPath string) error {
 	return err
 }
 
+// Title returns the title alias registered for a file path, or "" if it
+// has none.
+func (r *LinkRegistry) Title(obsidianPath string) (string, error) {
+	var title string
+	err := r.db.conn.QueryRow(`
+		SELECT alias_name FROM page_aliases
+		WHERE obsidian_path = ? AND alias_type = 'title'
+		LIMIT 1
+	`, obsidianPath).Scan(&title)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return title, err
+}
+
 // UpdateAliasPath updates aliases when a file is renamed.
 func (r *LinkRegistry) UpdateAliasPath(oldPath, newPath string) error {
 	_, err := r.db.conn.Exec(`UPDATE page_aliases SET obsidian_path = ? WHERE obsidian_path = ?`, newPath, oldPath)
@@ -248,6 +281,22 @@ func (r *LinkRegistry) GetUnresolvedLinks() ([]*LinkEntry, error) {
 	return scanLinks(rows)
 }
 
+// AllLinks returns every registered link, ordered by source path and
+// target.
+func (r *LinkRegistry) AllLinks() ([]*LinkEntry, error) {
+	rows, err := r.db.conn.Query(`
+		SELECT id, source_path, target_name, target_path, notion_page_id, resolved
+		FROM links
+		ORDER BY source_path, target_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query links: %w", err)
+	}
+	defer rows.Close()
+
+	return scanLinks(rows)
+}
+
 // GetLinksFrom returns all links originating from a source path.
 func (r *LinkRegistry) GetLinksFrom(sourcePath string) ([]*LinkEntry, error) {
 	rows, err := r.db.conn.Query(`
@@ -281,6 +330,79 @@ func (r *LinkRegistry) GetBacklinks(targetPath string) ([]*LinkEntry, error) {
 	return scanLinks(rows)
 }
 
+// RefreshBacklinks re-resolves links that may point at a renamed note: links
+// whose target names the old or new path (by file name or full path, with
+// or without a heading or block anchor), and links previously resolved to
+// the old path. It returns the source paths whose link resolution changed,
+// which need to be pushed again so Notion reflects the new target.
+func (r *LinkRegistry) RefreshBacklinks(oldPath, newPath string) ([]string, error) {
+	var sources []string
+	err := r.db.InTx(func(tx *DB) error {
+		var err error
+		sources, err = NewLinkRegistry(tx).refreshBacklinks(oldPath, newPath)
+		return err
+	})
+	return sources, err
+}
+
+// refreshBacklinks implements RefreshBacklinks inside a transaction.
+func (r *LinkRegistry) refreshBacklinks(oldPath, newPath string) ([]string, error) {
+	candidates := make(map[string]bool)
+	for _, p := range []string{oldPath, newPath} {
+		noExt := strings.TrimSuffix(p, ".md")
+		candidates[noExt] = true
+		candidates[filepath.Base(noExt)] = true
+	}
+
+	rows, err := r.db.conn.Query(`
+		SELECT id, source_path, target_name, target_path, notion_page_id, resolved
+		FROM links
+		WHERE source_path != ?
+	`, newPath)
+	if err != nil {
+		return nil, fmt.Errorf("query backlinks: %w", err)
+	}
+	links, err := scanLinks(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]bool)
+	var sources []string
+	for _, link := range links {
+		page, _, _ := parseTarget(link.TargetName)
+		if !candidates[page] && link.TargetPath != oldPath {
+			continue
+		}
+
+		pageID, found := r.Resolve(page)
+		targetPath := ""
+		if found {
+			targetPath, _ = r.LookupPath(pageID)
+		}
+		if found == link.Resolved && pageID == link.NotionPageID && targetPath == link.TargetPath {
+			continue
+		}
+
+		_, err := r.db.conn.Exec(`
+			UPDATE links
+			SET target_path = ?, notion_page_id = ?, resolved = ?
+			WHERE id = ?
+		`, nullString(targetPath), nullString(pageID), found, link.ID)
+		if err != nil {
+			return sources, fmt.Errorf("update link: %w", err)
+		}
+
+		if !changed[link.SourcePath] {
+			changed[link.SourcePath] = true
+			sources = append(sources, link.SourcePath)
+		}
+	}
+
+	return sources, nil
+}
+
 // ResolveAll attempts to resolve all unresolved links.
 // Returns the number of newly resolved links.
 func (r *LinkRegistry) ResolveAll() (int, error) {
@@ -419,7 +541,7 @@ func (r *LinkRegistry) ResolveExtended(target string, enableFuzzy bool) *Resolve
 // getAllSyncedPaths returns all synced paths with their Notion page IDs.
 func (r *LinkRegistry) getAllSyncedPaths() ([]MatchResult, error) {
 	rows, err := r.db.conn.Query(`
-		SELECT obsidian_path, notion_page_id
+		SELECT obsidian_path, notion_page_id, COALESCE(obsidian_mtime, last_sync, 0)
 		FROM sync_state
 		WHERE notion_page_id IS NOT NULL AND notion_page_id != ''
 	`)
@@ -431,12 +553,14 @@ func (r *LinkRegistry) getAllSyncedPaths() ([]MatchResult, error) {
 	var results []MatchResult
 	for rows.Next() {
 		var path, pageID string
-		if err := rows.Scan(&path, &pageID); err != nil {
+		var modified int64
+		if err := rows.Scan(&path, &pageID, &modified); err != nil {
 			return nil, fmt.Errorf("scan: %w", err)
 		}
 		results = append(results, MatchResult{
-			Path:   path,
-			PageID: pageID,
+			Path:     path,
+			PageID:   pageID,
+			Modified: time.Unix(modified, 0),
 		})
 	}
 	return results, rows.Err()
@@ -444,6 +568,14 @@ func (r *LinkRegistry) getAllSyncedPaths() ([]MatchResult, error) {
 
 // GetSuggestionsForUnresolved returns fuzzy match suggestions for all unresolved links.
 func (r *LinkRegistry) GetSuggestionsForUnresolved(maxSuggestions int) ([]LinkSuggestion, error) {
+	return r.SuggestTargets(MatchFuzzy, maxSuggestions)
+}
+
+// SuggestTargets returns likely targets for each unresolved link target
+// with at least one match of minScore or better. Matches are ranked by
+// score, then edit distance, then how recently the note changed. Links to
+// the same target from several notes are reported once, sorted by target.
+func (r *LinkRegistry) SuggestTargets(minScore MatchScore, maxSuggestions int) ([]LinkSuggestion, error) {
 	unresolved, err := r.GetUnresolvedLinks()
 	if err != nil {
 		return nil, err
@@ -458,25 +590,64 @@ func (r *LinkRegistry) GetSuggestionsForUnresolved(maxSuggestions int) ([]LinkSu
 		return nil, nil
 	}
 
+	byTarget := make(map[string]int)
 	var suggestions []LinkSuggestion
 	for _, link := range unresolved {
-		page, _, _ := parseTarget(link.TargetName)
-		matches := r.fuzzy.FindBestMatches(page, candidates, maxSuggestions)
+		if i, ok := byTarget[link.TargetName]; ok {
+			if i >= 0 {
+				suggestions[i].Sources = append(suggestions[i].Sources, link.SourcePath)
+			}
+			continue
+		}
 
-		if len(matches) > 0 {
-			suggestions = append(suggestions, LinkSuggestion{
-				Target:      link.TargetName,
-				Suggestions: matches,
-			})
+		page, _, _ := parseTarget(link.TargetName)
+		matches := filterMatches(r.fuzzy.FindBestMatches(page, candidates, 0), minScore)
+		if len(matches) == 0 {
+			byTarget[link.TargetName] = -1
+			continue
+		}
+		if maxSuggestions > 0 && len(matches) > maxSuggestions {
+			matches = matches[:maxSuggestions]
 		}
+
+		byTarget[link.TargetName] = len(suggestions)
+		suggestions = append(suggestions, LinkSuggestion{
+			Target:      link.TargetName,
+			Sources:     []string{link.SourcePath},
+			Suggestions: matches,
+		})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].Target < suggestions[j].Target
+	})
+	for _, s := range suggestions {
+		sort.Strings(s.Sources)
 	}
 
 	return suggestions, nil
 }
 
+// filterMatches drops matches scoring below minScore. Matches are sorted
+// best first, so the result is a prefix of the input.
+func filterMatches(matches []MatchResult, minScore MatchScore) []MatchResult {
+	for i, m := range matches {
+		if m.Score < minScore {
+			return matches[:i]
+		}
+	}
+	return matches
+}
+
 // RepairLinks attempts to resolve unresolved links using fuzzy matching.
 // Returns the number of links repaired and any error.
 func (r *LinkRegistry) RepairLinks(dryRun bool) ([]RepairResult, error) {
+	return r.RepairLinksMinScore(MatchFuzzy, dryRun)
+}
+
+// RepairLinksMinScore resolves unresolved links to their best match, if it
+// scores minScore or better.
+func (r *LinkRegistry) RepairLinksMinScore(minScore MatchScore, dryRun bool) ([]RepairResult, error) {
 	unresolved, err := r.GetUnresolvedLinks()
 	if err != nil {
 		return nil, fmt.Errorf("get unresolved: %w", err)
@@ -492,11 +663,13 @@ func (r *LinkRegistry) RepairLinks(dryRun bool) ([]RepairResult, error) {
 		page, _, _ := parseTarget(link.TargetName)
 		matches := r.fuzzy.FindBestMatches(page, candidates, 1)
 
-		if len(matches) > 0 && matches[0].Score >= MatchFuzzy {
+		if len(matches) > 0 && matches[0].Score >= minScore {
 			match := matches[0]
 			result := RepairResult{
+				LinkID:      link.ID,
 				SourcePath:  link.SourcePath,
 				TargetName:  link.TargetName,
+				TargetPage:  page,
 				MatchedPath: match.Path,
 				MatchedID:   match.PageID,
 				Score:       match.Score,
@@ -528,8 +701,10 @@ func (r *LinkRegistry) RepairLinks(dryRun bool) ([]RepairResult, error) {
 
 // RepairResult describes the outcome of repairing a single link.
 type RepairResult struct {
+	LinkID      int64      // ID of the repaired link
 	SourcePath  string     // Path containing the link
 	TargetName  string     // Original target text
+	TargetPage  string     // Page part of the target, without heading or block
 	MatchedPath string     // Path of the matched page
 	MatchedID   string     // Notion page ID of the matched page
 	Score       MatchScore // Match quality
@@ -539,6 +714,20 @@ type RepairResult struct {
 	Error       string     // Error message if repair failed
 }
 
+// RetargetLink points a link at a new target after the link text in its
+// source note was rewritten.
+func (r *LinkRegistry) RetargetLink(id int64, targetName, targetPath, pageID string) error {
+	_, err := r.db.conn.Exec(`
+		UPDATE links
+		SET target_name = ?, target_path = ?, notion_page_id = ?, resolved = 1
+		WHERE id = ?
+	`, targetName, targetPath, pageID, id)
+	if err != nil {
+		return fmt.Errorf("update link: %w", err)
+	}
+	return nil
+}
+
 // ResolveAllWithFuzzy attempts to resolve all unresolved links, using fuzzy matching as fallback.
 // Returns counts of exact and fuzzy matches.
 func (r *LinkRegistry) ResolveAllWithFuzzy(enableFuzzy bool) (exact, fuzzy int, err error) {
@@ -643,3 +832,37 @@ func (r *LinkRegistry) GetStats() (*LinkStats, error) {
 
 	return stats, rows.Err()
 }
+
+// LinkCount is a note and the number of resolved links pointing at it.
+type LinkCount struct {
+	Path  string
+	Count int
+}
+
+// MostLinked returns up to limit synced notes with the most resolved
+// incoming links, most linked first.
+func (r *LinkRegistry) MostLinked(limit int) ([]LinkCount, error) {
+	rows, err := r.db.conn.Query(`
+		SELECT s.obsidian_path, COUNT(*) as cnt
+		FROM links l
+		JOIN sync_state s ON s.notion_page_id = l.notion_page_id
+		WHERE l.resolved = 1
+		GROUP BY s.obsidian_path
+		ORDER BY cnt DESC, s.obsidian_path
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query most linked: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []LinkCount
+	for rows.Next() {
+		var c LinkCount
+		if err := rows.Scan(&c.Path, &c.Count); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
@@ -3,7 +3,9 @@ package state
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLinkRegistry_RegisterAndResolve(t *testing.T) {
@@ -179,6 +181,35 @@ func TestLinkRegistry_ClearLinksFrom(t *testing.T) {
 	}
 }
 
+func TestLinkRegistry_AllLinks(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	registry := NewLinkRegistry(db)
+	if err := registry.RegisterLinks("b.md", []string{"A"}); err != nil {
+		t.Fatalf("register links: %v", err)
+	}
+	if err := registry.RegisterLinks("a.md", []string{"Missing", "B"}); err != nil {
+		t.Fatalf("register links: %v", err)
+	}
+
+	links, err := registry.AllLinks()
+	if err != nil {
+		t.Fatalf("all links: %v", err)
+	}
+	var got []string
+	for _, l := range links {
+		got = append(got, l.SourcePath+"->"+l.TargetName)
+	}
+	want := []string{"a.md->B", "a.md->Missing", "b.md->A"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("AllLinks() = %v, want %v", got, want)
+	}
+}
+
 func TestLinkRegistry_LookupPath(t *testing.T) {
 	// Create temporary directory for test database.
 	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
@@ -323,6 +354,67 @@ func TestLinkRegistry_GetBacklinks(t *testing.T) {
 	}
 }
 
+func TestLinkRegistry_RefreshBacklinks(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	registry := NewLinkRegistry(db)
+
+	// old.md is synced; a.md links to it, b.md links to its future name.
+	if err := db.SetState(&SyncState{ObsidianPath: "notes/old.md", NotionPageID: "page-1", Status: "synced"}); err != nil {
+		t.Fatalf("set state: %v", err)
+	}
+	_ = registry.RegisterLinks("a.md", []string{"old"})
+	_ = registry.RegisterLinks("b.md", []string{"new#Intro"})
+	_ = registry.RegisterLinks("c.md", []string{"unrelated"})
+	if _, err := registry.ResolveAll(); err != nil {
+		t.Fatalf("resolve all: %v", err)
+	}
+
+	// Rename old.md -> new.md.
+	if err := db.UpdatePath("notes/old.md", "notes/new.md"); err != nil {
+		t.Fatalf("update path: %v", err)
+	}
+
+	sources, err := registry.RefreshBacklinks("notes/old.md", "notes/new.md")
+	if err != nil {
+		t.Fatalf("RefreshBacklinks() error: %v", err)
+	}
+	if len(sources) != 2 || sources[0] != "a.md" || sources[1] != "b.md" {
+		t.Fatalf("expected [a.md b.md], got %v", sources)
+	}
+
+	// [[new#Intro]] now resolves to the renamed page.
+	links, _ := registry.GetLinksFrom("b.md")
+	if len(links) != 1 || !links[0].Resolved || links[0].NotionPageID != "page-1" || links[0].TargetPath != "notes/new.md" {
+		t.Errorf("unexpected link from b.md: %+v", links)
+	}
+
+	// [[old]] no longer matches anything.
+	links, _ = registry.GetLinksFrom("a.md")
+	if len(links) != 1 || links[0].Resolved || links[0].NotionPageID != "" {
+		t.Errorf("unexpected link from a.md: %+v", links)
+	}
+
+	// A second refresh has nothing left to change.
+	sources, err = registry.RefreshBacklinks("notes/old.md", "notes/new.md")
+	if err != nil {
+		t.Fatalf("RefreshBacklinks() error: %v", err)
+	}
+	if len(sources) != 0 {
+		t.Errorf("expected no changes on second refresh, got %v", sources)
+	}
+}
+
 func TestParseTarget(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -559,6 +651,80 @@ func TestLinkRegistry_RepairLinks(t *testing.T) {
 	}
 }
 
+func TestLinkRegistry_SuggestTargets(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	registry := NewLinkRegistry(db)
+
+	now := time.Now()
+	for _, note := range []struct {
+		path, pageID string
+		modified     time.Time
+	}{
+		{"Old/Meeting Notes A.md", "page-old", now.Add(-48 * time.Hour)},
+		{"New/Meeting Notes B.md", "page-new", now},
+		{"Meeting.md", "page-meeting", now.Add(-time.Hour)},
+	} {
+		if err := db.SetState(&SyncState{
+			ObsidianPath:  note.path,
+			NotionPageID:  note.pageID,
+			ObsidianMtime: note.modified,
+			Status:        "synced",
+		}); err != nil {
+			t.Fatalf("set state: %v", err)
+		}
+	}
+
+	for _, source := range []string{"b.md", "a.md"} {
+		if err := registry.RegisterLinks(source, []string{"Meeting Notes"}); err != nil {
+			t.Fatalf("register links: %v", err)
+		}
+	}
+	if err := registry.RegisterLinks("a.md", []string{"Meetng"}); err != nil {
+		t.Fatalf("register links: %v", err)
+	}
+
+	suggestions, err := registry.SuggestTargets(MatchFuzzy, 0)
+	if err != nil {
+		t.Fatalf("SuggestTargets() error: %v", err)
+	}
+	if len(suggestions) != 2 {
+		t.Fatalf("got %d suggestions; want 2", len(suggestions))
+	}
+
+	notes := suggestions[0]
+	if notes.Target != "Meeting Notes" || len(notes.Sources) != 2 || notes.Sources[0] != "a.md" {
+		t.Errorf("suggestion = %+v; want Meeting Notes from a.md and b.md", notes)
+	}
+	// Equal prefix matches rank the recently modified note first.
+	if len(notes.Suggestions) != 2 || notes.Suggestions[0].Path != "New/Meeting Notes B.md" {
+		t.Errorf("Meeting Notes suggestions = %+v; want the newer note first", notes.Suggestions)
+	}
+
+	if suggestions[1].Target != "Meetng" || suggestions[1].Suggestions[0].Path != "Meeting.md" {
+		t.Errorf("suggestion = %+v; want Meeting.md for Meetng", suggestions[1])
+	}
+
+	// Raising the minimum score drops the fuzzy-only target.
+	suggestions, err = registry.SuggestTargets(MatchPrefix, 1)
+	if err != nil {
+		t.Fatalf("SuggestTargets() error: %v", err)
+	}
+	if len(suggestions) != 1 || len(suggestions[0].Suggestions) != 1 {
+		t.Errorf("SuggestTargets(prefix, 1) = %+v; want one suggestion for Meeting Notes", suggestions)
+	}
+}
+
 func TestLinkRegistry_GetStats(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
 	if err != nil {
@@ -607,6 +773,48 @@ func TestLinkRegistry_GetStats(t *testing.T) {
 	}
 }
 
+func TestLinkRegistry_MostLinked(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	for path, pageID := range map[string]string{"hub.md": "page-hub", "leaf.md": "page-leaf"} {
+		if err := db.SetState(&SyncState{ObsidianPath: path, NotionPageID: pageID, ContentHash: "h", Status: "synced"}); err != nil {
+			t.Fatalf("set state: %v", err)
+		}
+	}
+
+	registry := NewLinkRegistry(db)
+	for _, source := range []string{"a.md", "b.md", "c.md"} {
+		if err := registry.RegisterLink(source, "hub"); err != nil {
+			t.Fatalf("register link: %v", err)
+		}
+	}
+	if err := registry.RegisterLinks("a.md", []string{"leaf", "missing"}); err != nil {
+		t.Fatalf("register links: %v", err)
+	}
+	if _, err := registry.ResolveAll(); err != nil {
+		t.Fatalf("resolve all: %v", err)
+	}
+
+	counts, err := registry.MostLinked(5)
+	if err != nil {
+		t.Fatalf("MostLinked() error: %v", err)
+	}
+	want := []LinkCount{{Path: "hub.md", Count: 3}, {Path: "leaf.md", Count: 1}}
+	if len(counts) != len(want) || counts[0] != want[0] || counts[1] != want[1] {
+		t.Errorf("MostLinked() = %+v, want %+v", counts, want)
+	}
+}
+
 // TestLinkRegistry_ResolveByTitle tests resolving wiki-links by frontmatter title
 // when the title differs from the filename. This is the bug from ANN-42.
 //
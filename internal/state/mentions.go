@@ -0,0 +1,71 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MissingMention is a page mentioned by pulled notes that is not synced
+// to the vault, so the mentions were pulled as links to notes that do not
+// exist.
+type MissingMention struct {
+	NotionPageID string
+	Sources      []string // Notes mentioning the page, sorted.
+}
+
+// SetMissingMentions replaces the pages a pulled note mentions that are
+// not synced to the vault.
+func (r *LinkRegistry) SetMissingMentions(sourcePath string, pageIDs []string) error {
+	return r.db.InTx(func(tx *DB) error {
+		if _, err := tx.conn.Exec(`DELETE FROM missing_mentions WHERE source_path = ?`, sourcePath); err != nil {
+			return fmt.Errorf("clear missing mentions: %w", err)
+		}
+		for _, id := range pageIDs {
+			_, err := tx.conn.Exec(`
+				INSERT INTO missing_mentions (source_path, notion_page_id)
+				VALUES (?, ?)
+				ON CONFLICT DO NOTHING
+			`, sourcePath, id)
+			if err != nil {
+				return fmt.Errorf("insert missing mention: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// MissingMentions returns the pages pulled notes mention that are still
+// not synced to the vault, sorted by page ID.
+func (r *LinkRegistry) MissingMentions() ([]*MissingMention, error) {
+	rows, err := r.db.conn.Query(`
+		SELECT notion_page_id, source_path FROM missing_mentions
+		WHERE notion_page_id NOT IN (
+			SELECT notion_page_id FROM sync_state WHERE notion_page_id IS NOT NULL
+		)
+		ORDER BY notion_page_id, source_path
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query missing mentions: %w", err)
+	}
+	defer rows.Close()
+
+	var mentions []*MissingMention
+	for rows.Next() {
+		var id, source string
+		if err := rows.Scan(&id, &source); err != nil {
+			return nil, fmt.Errorf("scan missing mention: %w", err)
+		}
+		if n := len(mentions); n == 0 || mentions[n-1].NotionPageID != id {
+			mentions = append(mentions, &MissingMention{NotionPageID: id})
+		}
+		last := mentions[len(mentions)-1]
+		last.Sources = append(last.Sources, source)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, m := range mentions {
+		sort.Strings(m.Sources)
+	}
+	return mentions, nil
+}
@@ -0,0 +1,60 @@
+package state
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLinkRegistry_MissingMentions(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	registry := NewLinkRegistry(db)
+
+	if err := registry.SetMissingMentions("b.md", []string{"page-1", "page-2"}); err != nil {
+		t.Fatalf("SetMissingMentions() error: %v", err)
+	}
+	if err := registry.SetMissingMentions("a.md", []string{"page-1", "page-1"}); err != nil {
+		t.Fatalf("SetMissingMentions() error: %v", err)
+	}
+
+	format := func() string {
+		t.Helper()
+		mentions, err := registry.MissingMentions()
+		if err != nil {
+			t.Fatalf("MissingMentions() error: %v", err)
+		}
+		var parts []string
+		for _, m := range mentions {
+			parts = append(parts, m.NotionPageID+"<-"+strings.Join(m.Sources, ","))
+		}
+		return strings.Join(parts, " ")
+	}
+	if got, want := format(), "page-1<-a.md,b.md page-2<-b.md"; got != want {
+		t.Errorf("MissingMentions() = %s, want %s", got, want)
+	}
+
+	// Pulling a mentioned page resolves it; pulling a note again replaces
+	// its mentions; renames and deletions follow the note.
+	if err := db.SetState(&SyncState{ObsidianPath: "Two.md", NotionPageID: "page-2", Status: "synced"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.SetMissingMentions("b.md", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdatePath("a.md", "c.md"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := format(), "page-1<-c.md"; got != want {
+		t.Errorf("MissingMentions() = %s, want %s", got, want)
+	}
+	if err := db.DeleteState("c.md"); err != nil {
+		t.Fatal(err)
+	}
+	if got := format(); got != "" {
+		t.Errorf("MissingMentions() after delete = %s, want none", got)
+	}
+}
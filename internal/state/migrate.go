@@ -0,0 +1,378 @@
+package state
+
+import (
+	"fmt"
+	"os"
+)
+
+// Migration upgrades the schema from the previous version to Version.
+type Migration struct {
+	Version     int
+	Description string
+	up          string
+}
+
+// migrations lists every schema change in order. Migration i upgrades the
+// schema to version i+1; released migrations must never be edited, only
+// appended to.
+var migrations = []Migration{
+	{Version: 1, Description: "initial schema", up: schemaV1},
+	{Version: 2, Description: "note targets", up: schemaV2},
+	{Version: 3, Description: "block map", up: schemaV3},
+	{Version: 4, Description: "partial attachment uploads", up: schemaV4},
+	{Version: 5, Description: "note task states", up: schemaV5},
+	{Version: 6, Description: "heading renames", up: schemaV6},
+	{Version: 7, Description: "nested databases", up: schemaV7},
+	{Version: 8, Description: "missing page mentions", up: schemaV8},
+	{Version: 9, Description: "divider markers", up: schemaV9},
+}
+
+// LatestSchemaVersion returns the schema version this build creates.
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+// SchemaVersion returns the schema version of the database. Databases
+// created before versioning report 0.
+func (db *DB) SchemaVersion() (int, error) {
+	var version int
+	if err := db.conn.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// PendingMigrations returns the migrations not yet applied to the database.
+func (db *DB) PendingMigrations() ([]Migration, error) {
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if version > len(migrations) {
+		return nil, fmt.Errorf("database schema version %d is newer than this build supports (%d); upgrade obsidian-notion", version, len(migrations))
+	}
+	return migrations[version:], nil
+}
+
+// Migrate applies pending migrations, each in its own transaction. A
+// database that already holds data is backed up first; the backup path is
+// returned, or "" if none was needed.
+func (db *DB) Migrate() (string, error) {
+	pending, err := db.PendingMigrations()
+	if err != nil || len(pending) == 0 {
+		return "", err
+	}
+
+	var backup string
+	empty, err := db.isEmpty()
+	if err != nil {
+		return "", err
+	}
+	if !empty {
+		backup, err = db.Backup(fmt.Sprintf("%s.v%d.bak", db.path, pending[0].Version-1))
+		if err != nil {
+			return "", fmt.Errorf("back up before migrating: %w", err)
+		}
+	}
+
+	for _, m := range pending {
+		err := db.InTx(func(tx *DB) error {
+			if _, err := tx.conn.Exec(m.up); err != nil {
+				return err
+			}
+			_, err := tx.conn.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, m.Version))
+			return err
+		})
+		if err != nil {
+			return backup, fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+	}
+
+	return backup, nil
+}
+
+// Backup writes a consistent copy of the database to path, replacing any
+// file already there, and returns path.
+func (db *DB) Backup(path string) (string, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("remove old backup: %w", err)
+	}
+	if _, err := db.conn.Exec(`VACUUM INTO ?`, path); err != nil {
+		return "", fmt.Errorf("write backup: %w", err)
+	}
+	return path, nil
+}
+
+// isEmpty reports whether the database has no tables yet.
+func (db *DB) isEmpty() (bool, error) {
+	var count int
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("count tables: %w", err)
+	}
+	return count == 0, nil
+}
+
+// schemaV1 is the schema as it was before versioning. Every statement is
+// idempotent, so it also adopts databases created by earlier builds.
+const schemaV1 = `
+
+	-- Core sync state
+	CREATE TABLE IF NOT EXISTS sync_state (
+		id INTEGER PRIMARY KEY,
+		obsidian_path TEXT UNIQUE NOT NULL,
+		notion_page_id TEXT,
+		notion_parent_id TEXT,
+		content_hash TEXT NOT NULL,
+		frontmatter_hash TEXT,
+		obsidian_mtime INTEGER,
+		notion_mtime INTEGER,
+		last_sync INTEGER,
+		sync_direction TEXT,
+		status TEXT DEFAULT 'pending'
+	);
+
+	-- Link resolution cache
+	CREATE TABLE IF NOT EXISTS links (
+		id INTEGER PRIMARY KEY,
+		source_path TEXT NOT NULL,
+		target_name TEXT NOT NULL,
+		target_path TEXT,
+		notion_page_id TEXT,
+		resolved INTEGER DEFAULT 0
+	);
+
+	-- Sync history for conflict resolution
+	CREATE TABLE IF NOT EXISTS sync_history (
+		id INTEGER PRIMARY KEY,
+		obsidian_path TEXT NOT NULL,
+		action TEXT NOT NULL,
+		timestamp INTEGER NOT NULL,
+		content_hash TEXT,
+		details TEXT
+	);
+
+	-- Configuration
+	CREATE TABLE IF NOT EXISTS config (
+		key TEXT PRIMARY KEY,
+		value TEXT
+	);
+
+	-- Page aliases for wiki-link resolution by title/alias
+	-- Maps frontmatter titles and aliases to obsidian paths
+	CREATE TABLE IF NOT EXISTS page_aliases (
+		obsidian_path TEXT NOT NULL,
+		alias_name TEXT NOT NULL,
+		alias_type TEXT DEFAULT 'title',
+		PRIMARY KEY (obsidian_path, alias_name)
+	);
+
+	-- Uploaded attachments, keyed by content hash so identical files
+	-- embedded in several notes are uploaded only once per integration
+	CREATE TABLE IF NOT EXISTS attachments (
+		content_hash TEXT NOT NULL,
+		credential TEXT NOT NULL DEFAULT '',
+		file_upload_id TEXT NOT NULL,
+		original_path TEXT NOT NULL,
+		size INTEGER,
+		uploaded_at INTEGER,
+		PRIMARY KEY (content_hash, credential)
+	);
+
+	-- Which notes reference which uploaded attachments
+	CREATE TABLE IF NOT EXISTS attachment_refs (
+		note_path TEXT NOT NULL,
+		content_hash TEXT NOT NULL,
+		PRIMARY KEY (note_path, content_hash)
+	);
+
+	-- Child pages a note was split into (transform.split_on), in order
+	CREATE TABLE IF NOT EXISTS page_sections (
+		obsidian_path TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		title TEXT NOT NULL,
+		notion_page_id TEXT NOT NULL,
+		PRIMARY KEY (obsidian_path, position)
+	);
+
+	-- Notes composed into a single page (compositions), in section order
+	CREATE TABLE IF NOT EXISTS composite_members (
+		notion_page_id TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		obsidian_path TEXT NOT NULL,
+		heading TEXT NOT NULL,
+		PRIMARY KEY (notion_page_id, position)
+	);
+
+	-- Notion multi-select values each note tag was pushed as, when nested
+	-- tags are flattened (transform.nested_tags), so pull can restore them
+	CREATE TABLE IF NOT EXISTS note_tags (
+		obsidian_path TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		tag TEXT NOT NULL,
+		notion_values TEXT NOT NULL,
+		PRIMARY KEY (obsidian_path, position)
+	);
+
+	-- One row per push, pull, or sync run, for the stats command
+	CREATE TABLE IF NOT EXISTS sync_runs (
+		id INTEGER PRIMARY KEY,
+		command TEXT NOT NULL,
+		started_at INTEGER NOT NULL,
+		duration_ms INTEGER,
+		pushed INTEGER DEFAULT 0,
+		pulled INTEGER DEFAULT 0,
+		failed INTEGER DEFAULT 0,
+		api_calls INTEGER DEFAULT 0,
+		blocks_pushed INTEGER DEFAULT 0
+	);
+
+	-- Push, pull, and sync runs that can be undone (sync undo), pruned
+	-- after sync.undo_retention
+	CREATE TABLE IF NOT EXISTS undo_runs (
+		id INTEGER PRIMARY KEY,
+		command TEXT NOT NULL,
+		started_at INTEGER NOT NULL
+	);
+
+	-- Notes as they were before an undoable run changed them: the local
+	-- file before a pull, the Notion page (as markdown) before an update,
+	-- and the sync state before either
+	CREATE TABLE IF NOT EXISTS undo_snapshots (
+		run_id INTEGER NOT NULL,
+		obsidian_path TEXT NOT NULL,
+		action TEXT NOT NULL,
+		content BLOB,
+		remote BLOB,
+		notion_page_id TEXT,
+		prior_status TEXT,
+		prior_parent_id TEXT,
+		prior_content_hash TEXT,
+		prior_frontmatter_hash TEXT,
+		prior_obsidian_mtime INTEGER,
+		prior_notion_mtime INTEGER,
+		prior_last_sync INTEGER,
+		prior_direction TEXT,
+		PRIMARY KEY (run_id, obsidian_path)
+	);
+
+	-- Indexes
+	CREATE INDEX IF NOT EXISTS idx_sync_state_status ON sync_state(status);
+	CREATE INDEX IF NOT EXISTS idx_sync_state_notion_page ON sync_state(notion_page_id);
+	CREATE INDEX IF NOT EXISTS idx_links_source ON links(source_path);
+	CREATE INDEX IF NOT EXISTS idx_links_target ON links(target_name);
+	CREATE INDEX IF NOT EXISTS idx_history_path ON sync_history(obsidian_path);
+
+	-- Unique constraint to prevent duplicate links
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_links_unique ON links(source_path, target_name);
+
+	-- Index for fast alias lookups by name
+	CREATE INDEX IF NOT EXISTS idx_aliases_name ON page_aliases(alias_name);
+
+	-- Index for finding the notes that reference an attachment
+	CREATE INDEX IF NOT EXISTS idx_attachment_refs_hash ON attachment_refs(content_hash);
+`
+
+// schemaV2 adds the pages notes publish to in other databases.
+const schemaV2 = `
+	-- Property-only pages a note publishes to in the databases of its
+	-- notion-targets, one per note and database
+	CREATE TABLE IF NOT EXISTS note_targets (
+		obsidian_path TEXT NOT NULL,
+		database_id TEXT NOT NULL,
+		target TEXT NOT NULL,
+		notion_page_id TEXT NOT NULL,
+		last_sync INTEGER,
+		PRIMARY KEY (obsidian_path, database_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_note_targets_page ON note_targets(notion_page_id);
+`
+
+// schemaV3 adds the Notion blocks of each note's markdown blocks.
+const schemaV3 = `
+	-- The Notion block each top-level markdown block of a note was last
+	-- pushed as or pulled from, in document order
+	CREATE TABLE IF NOT EXISTS block_map (
+		obsidian_path TEXT NOT NULL,
+		block_index INTEGER NOT NULL,
+		block_hash TEXT NOT NULL,
+		notion_block_id TEXT NOT NULL,
+		PRIMARY KEY (obsidian_path, block_index)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_block_map_block ON block_map(notion_block_id);
+`
+
+// schemaV4 adds multi-part attachment uploads in progress.
+const schemaV4 = `
+	-- Multi-part uploads of large attachments that were interrupted, so
+	-- the next push sends only the parts that are left
+	CREATE TABLE IF NOT EXISTS attachment_uploads (
+		content_hash TEXT NOT NULL,
+		credential TEXT NOT NULL DEFAULT '',
+		file_upload_id TEXT NOT NULL,
+		parts INTEGER NOT NULL,
+		sent INTEGER NOT NULL,
+		started_at INTEGER,
+		PRIMARY KEY (content_hash, credential)
+	);
+`
+
+// schemaV5 adds the alternative states of pushed tasks.
+const schemaV5 = `
+	-- Alternative task states ("- [>] task") pushed as checked or
+	-- unchecked to-dos (transform.task_states), so pull can restore them
+	CREATE TABLE IF NOT EXISTS note_tasks (
+		obsidian_path TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		text TEXT NOT NULL,
+		state TEXT NOT NULL,
+		PRIMARY KEY (obsidian_path, position)
+	);
+`
+
+// schemaV6 records the heading text of mapped blocks, and headings renamed
+// since, so links to them can be repaired.
+const schemaV6 = `
+	ALTER TABLE block_map ADD COLUMN heading TEXT NOT NULL DEFAULT '';
+
+	-- Headings whose text changed while their Notion block stayed the
+	-- same, until links repair --headings rewrites the links to them
+	CREATE TABLE IF NOT EXISTS heading_renames (
+		id INTEGER PRIMARY KEY,
+		obsidian_path TEXT NOT NULL,
+		old_heading TEXT NOT NULL,
+		new_heading TEXT NOT NULL,
+		renamed_at INTEGER NOT NULL
+	);
+`
+
+// schemaV7 adds the Notion databases found inside pulled pages.
+const schemaV7 = `
+	-- Databases nested in the page of a pulled note, whose rows are the
+	-- notes of a vault folder
+	CREATE TABLE IF NOT EXISTS nested_databases (
+		database_id TEXT PRIMARY KEY,
+		folder TEXT NOT NULL,
+		parent_path TEXT NOT NULL,
+		title TEXT NOT NULL
+	);
+`
+
+// schemaV8 adds the pages pulled notes mention that have no note.
+const schemaV8 = `
+	-- Pages mentioned by pulled notes that are not synced to the vault,
+	-- until the notes are pulled again without the mentions
+	CREATE TABLE IF NOT EXISTS missing_mentions (
+		source_path TEXT NOT NULL,
+		notion_page_id TEXT NOT NULL,
+		PRIMARY KEY (source_path, notion_page_id)
+	);
+`
+
+// schemaV9 records the markdown of thematic breaks in the block map, so
+// pull writes each divider as it was written.
+const schemaV9 = `
+	ALTER TABLE block_map ADD COLUMN marker TEXT NOT NULL DEFAULT '';
+`
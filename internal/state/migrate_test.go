@@ -0,0 +1,150 @@
+package state
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrations_Versions(t *testing.T) {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d; want %d", i, m.Version, i+1)
+		}
+		if m.Description == "" || m.up == "" {
+			t.Errorf("migration %d is missing a description or SQL", m.Version)
+		}
+	}
+}
+
+func TestOpen_MigratesNewDatabase(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	version, err := db.SchemaVersion()
+	if err != nil || version != LatestSchemaVersion() {
+		t.Errorf("SchemaVersion() = %d, %v; want %d", version, err, LatestSchemaVersion())
+	}
+
+	// A new database has nothing to back up.
+	matches, _ := filepath.Glob(dbPath + ".v*.bak")
+	if len(matches) != 0 {
+		t.Errorf("unexpected backups: %v", matches)
+	}
+}
+
+func TestMigrate_BacksUpExistingDatabase(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// A database from before versioning: tables exist, user_version is 0.
+	dbPath := filepath.Join(tmpDir, "test.db")
+	raw, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open raw db: %v", err)
+	}
+	if _, err := raw.Exec(`
+		CREATE TABLE sync_state (
+			id INTEGER PRIMARY KEY,
+			obsidian_path TEXT UNIQUE NOT NULL,
+			notion_page_id TEXT,
+			notion_parent_id TEXT,
+			content_hash TEXT NOT NULL,
+			frontmatter_hash TEXT,
+			obsidian_mtime INTEGER,
+			notion_mtime INTEGER,
+			last_sync INTEGER,
+			sync_direction TEXT,
+			status TEXT DEFAULT 'pending'
+		);
+		INSERT INTO sync_state (obsidian_path, notion_page_id, content_hash, status)
+		VALUES ('note.md', 'page-1', 'hash', 'synced');
+	`); err != nil {
+		t.Fatalf("create legacy schema: %v", err)
+	}
+	raw.Close()
+
+	db, err := OpenUnmigrated(dbPath)
+	if err != nil {
+		t.Fatalf("OpenUnmigrated() error: %v", err)
+	}
+	defer db.Close()
+
+	pending, err := db.PendingMigrations()
+	if err != nil || len(pending) != LatestSchemaVersion() {
+		t.Fatalf("PendingMigrations() = %d, %v; want all", len(pending), err)
+	}
+
+	backup, err := db.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate() error: %v", err)
+	}
+	if backup != dbPath+".v0.bak" {
+		t.Errorf("backup = %q; want %q", backup, dbPath+".v0.bak")
+	}
+
+	// The backup holds the data as it was.
+	bak, err := sql.Open("sqlite3", backup)
+	if err != nil {
+		t.Fatalf("open backup: %v", err)
+	}
+	defer bak.Close()
+	var pageID string
+	if err := bak.QueryRow(`SELECT notion_page_id FROM sync_state WHERE obsidian_path = 'note.md'`).Scan(&pageID); err != nil || pageID != "page-1" {
+		t.Errorf("backup row = %q, %v", pageID, err)
+	}
+
+	// The migrated database keeps its data and gains the new tables.
+	state, err := db.GetState("note.md")
+	if err != nil || state == nil || state.NotionPageID != "page-1" {
+		t.Errorf("GetState() = %+v, %v", state, err)
+	}
+	if err := db.SetConfig("key", "value"); err != nil {
+		t.Errorf("SetConfig() after migrate: %v", err)
+	}
+
+	if pending, _ := db.PendingMigrations(); len(pending) != 0 {
+		t.Errorf("pending after migrate = %d; want 0", len(pending))
+	}
+	if backup, err := db.Migrate(); backup != "" || err != nil {
+		t.Errorf("second Migrate() = %q, %v; want no-op", backup, err)
+	}
+}
+
+func TestOpen_RejectsNewerSchema(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	raw, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open raw db: %v", err)
+	}
+	if _, err := raw.Exec(`PRAGMA user_version = 999`); err != nil {
+		t.Fatalf("set version: %v", err)
+	}
+	raw.Close()
+
+	_, err = Open(dbPath)
+	if err == nil || !strings.Contains(err.Error(), "newer than this build") {
+		t.Errorf("Open() error = %v; want a newer schema error", err)
+	}
+}
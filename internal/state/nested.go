@@ -0,0 +1,53 @@
+package state
+
+import "fmt"
+
+// NestedDatabase is a Notion database found inside the page of a pulled
+// note, whose rows are synced as the notes of a vault folder.
+type NestedDatabase struct {
+	DatabaseID string
+	Folder     string
+	ParentPath string
+	Title      string
+}
+
+// AddNestedDatabase records a nested database unless it is recorded
+// already, so its folder does not move when the database is renamed. It
+// reports whether the database is new.
+func (db *DB) AddNestedDatabase(d NestedDatabase) (bool, error) {
+	result, err := db.conn.Exec(`
+		INSERT OR IGNORE INTO nested_databases (database_id, folder, parent_path, title)
+		VALUES (?, ?, ?, ?)
+	`, d.DatabaseID, d.Folder, d.ParentPath, d.Title)
+	if err != nil {
+		return false, fmt.Errorf("insert nested database: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("insert nested database: %w", err)
+	}
+	return n > 0, nil
+}
+
+// NestedDatabases returns the recorded nested databases by folder.
+func (db *DB) NestedDatabases() ([]NestedDatabase, error) {
+	rows, err := db.conn.Query(`
+		SELECT database_id, folder, parent_path, title FROM nested_databases
+		ORDER BY folder
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query nested databases: %w", err)
+	}
+	defer rows.Close()
+
+	var dbs []NestedDatabase
+	for rows.Next() {
+		var d NestedDatabase
+		if err := rows.Scan(&d.DatabaseID, &d.Folder, &d.ParentPath, &d.Title); err != nil {
+			return nil, fmt.Errorf("scan nested database: %w", err)
+		}
+		dbs = append(dbs, d)
+	}
+
+	return dbs, rows.Err()
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDB_NestedDatabases(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	tasks := NestedDatabase{DatabaseID: "db-1", Folder: "Projects/Tasks", ParentPath: "Projects.md", Title: "Tasks"}
+	added, err := db.AddNestedDatabase(tasks)
+	if err != nil || !added {
+		t.Fatalf("AddNestedDatabase() = %v, %v, want true", added, err)
+	}
+
+	// A database found again keeps its folder.
+	renamed := tasks
+	renamed.Folder, renamed.Title = "Projects/To do", "To do"
+	added, err = db.AddNestedDatabase(renamed)
+	if err != nil || added {
+		t.Fatalf("AddNestedDatabase() again = %v, %v, want false", added, err)
+	}
+
+	if err := db.UpdatePath("Projects.md", "Work.md"); err != nil {
+		t.Fatalf("UpdatePath() error: %v", err)
+	}
+	got, err := db.NestedDatabases()
+	if err != nil {
+		t.Fatalf("NestedDatabases() error: %v", err)
+	}
+	tasks.ParentPath = "Work.md"
+	if want := []NestedDatabase{tasks}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NestedDatabases() = %+v, want %+v", got, want)
+	}
+}
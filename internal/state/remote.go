@@ -162,7 +162,7 @@ func (d *RemoteChangeDetector) DetectAllChanges(ctx context.Context) ([]Change,
 				}
 
 				// Check if local matches stored state.
-				localHashes := HashContent(localContent)
+				localHashes := d.hasher.HashContent(localContent)
 				stateHashes := HashesFromState(state)
 
 				if HasContentChanged(stateHashes, localHashes) {
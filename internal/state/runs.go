@@ -0,0 +1,67 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Run records the outcome of a single push, pull, or sync.
+type Run struct {
+	Command      string
+	StartedAt    time.Time
+	Duration     time.Duration
+	Pushed       int
+	Pulled       int
+	Failed       int
+	APICalls     int64
+	BlocksPushed int64
+}
+
+// RecordRun stores a completed run.
+func (db *DB) RecordRun(run *Run) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO sync_runs (command, started_at, duration_ms, pushed, pulled, failed, api_calls, blocks_pushed)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.Command, run.StartedAt.Unix(), run.Duration.Milliseconds(),
+		run.Pushed, run.Pulled, run.Failed, run.APICalls, run.BlocksPushed)
+	if err != nil {
+		return fmt.Errorf("insert run: %w", err)
+	}
+	return nil
+}
+
+// LastRun returns the most recent run, or nil if none was recorded.
+func (db *DB) LastRun() (*Run, error) {
+	run := &Run{}
+	var startedAt, durationMs int64
+	err := db.conn.QueryRow(`
+		SELECT command, started_at, duration_ms, pushed, pulled, failed, api_calls, blocks_pushed
+		FROM sync_runs
+		ORDER BY started_at DESC, id DESC
+		LIMIT 1
+	`).Scan(&run.Command, &startedAt, &durationMs,
+		&run.Pushed, &run.Pulled, &run.Failed, &run.APICalls, &run.BlocksPushed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query last run: %w", err)
+	}
+	run.StartedAt = time.Unix(startedAt, 0)
+	run.Duration = time.Duration(durationMs) * time.Millisecond
+	return run, nil
+}
+
+// RunTotals returns the number of recorded runs and the blocks and API
+// calls summed across all of them.
+func (db *DB) RunTotals() (runs int, blocksPushed, apiCalls int64, err error) {
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(blocks_pushed), 0), COALESCE(SUM(api_calls), 0)
+		FROM sync_runs
+	`).Scan(&runs, &blocksPushed, &apiCalls)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("query run totals: %w", err)
+	}
+	return runs, blocksPushed, apiCalls, nil
+}
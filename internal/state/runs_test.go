@@ -0,0 +1,54 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDB_Runs(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	run, err := db.LastRun()
+	if err != nil || run != nil {
+		t.Fatalf("LastRun() on empty db = %+v, %v", run, err)
+	}
+
+	start := time.Unix(1700000000, 0)
+	runs := []*Run{
+		{Command: "push", StartedAt: start, Duration: 2 * time.Second, Pushed: 3, APICalls: 10, BlocksPushed: 40},
+		{Command: "sync", StartedAt: start.Add(time.Hour), Duration: 1500 * time.Millisecond, Pushed: 1, Pulled: 2, Failed: 1, APICalls: 7, BlocksPushed: 5},
+	}
+	for _, r := range runs {
+		if err := db.RecordRun(r); err != nil {
+			t.Fatalf("RecordRun() error: %v", err)
+		}
+	}
+
+	last, err := db.LastRun()
+	if err != nil {
+		t.Fatalf("LastRun() error: %v", err)
+	}
+	if *last != *runs[1] {
+		t.Errorf("LastRun() = %+v, want %+v", last, runs[1])
+	}
+
+	count, blocks, calls, err := db.RunTotals()
+	if err != nil {
+		t.Fatalf("RunTotals() error: %v", err)
+	}
+	if count != 2 || blocks != 45 || calls != 17 {
+		t.Errorf("RunTotals() = %d, %d, %d; want 2, 45, 17", count, blocks, calls)
+	}
+}
@@ -0,0 +1,64 @@
+package state
+
+import "fmt"
+
+// PageSection records a child page split off from a note.
+type PageSection struct {
+	Title        string
+	NotionPageID string
+}
+
+// SetSections replaces the recorded sections of a note. An empty slice
+// records that the note is not split.
+func (db *DB) SetSections(obsidianPath string, sections []PageSection) error {
+	tx, err := db.begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM page_sections WHERE obsidian_path = ?`, obsidianPath); err != nil {
+		return fmt.Errorf("clear sections: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO page_sections (obsidian_path, position, title, notion_page_id)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, s := range sections {
+		if _, err := stmt.Exec(obsidianPath, i, s.Title, s.NotionPageID); err != nil {
+			return fmt.Errorf("insert section: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetSections returns the recorded sections of a note in document order.
+func (db *DB) GetSections(obsidianPath string) ([]PageSection, error) {
+	rows, err := db.conn.Query(`
+		SELECT title, notion_page_id FROM page_sections
+		WHERE obsidian_path = ?
+		ORDER BY position
+	`, obsidianPath)
+	if err != nil {
+		return nil, fmt.Errorf("query sections: %w", err)
+	}
+	defer rows.Close()
+
+	var sections []PageSection
+	for rows.Next() {
+		var s PageSection
+		if err := rows.Scan(&s.Title, &s.NotionPageID); err != nil {
+			return nil, fmt.Errorf("scan section: %w", err)
+		}
+		sections = append(sections, s)
+	}
+
+	return sections, rows.Err()
+}
@@ -0,0 +1,53 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDB_Sections(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	sections := []PageSection{
+		{Title: "First", NotionPageID: "page-1"},
+		{Title: "Second", NotionPageID: "page-2"},
+	}
+	if err := db.SetSections("big.md", sections); err != nil {
+		t.Fatalf("SetSections() error: %v", err)
+	}
+
+	got, err := db.GetSections("big.md")
+	if err != nil {
+		t.Fatalf("GetSections() error: %v", err)
+	}
+	if len(got) != 2 || got[0] != sections[0] || got[1] != sections[1] {
+		t.Errorf("GetSections() = %+v, want %+v", got, sections)
+	}
+
+	// Renames carry the sections along.
+	if err := db.UpdatePath("big.md", "renamed.md"); err != nil {
+		t.Fatalf("UpdatePath() error: %v", err)
+	}
+	if got, _ := db.GetSections("renamed.md"); len(got) != 2 {
+		t.Errorf("expected sections to follow rename, got %d", len(got))
+	}
+
+	// Replacing with nil records that the note is no longer split.
+	if err := db.SetSections("renamed.md", nil); err != nil {
+		t.Fatalf("SetSections() error: %v", err)
+	}
+	if got, _ := db.GetSections("renamed.md"); len(got) != 0 {
+		t.Errorf("expected no sections, got %d", len(got))
+	}
+}
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NoteTag records the Notion multi-select values a note tag was pushed as.
+type NoteTag struct {
+	Tag    string
+	Values []string
+}
+
+// SetNoteTags replaces the recorded tags of a note. An empty slice records
+// that the note's tags were pushed unchanged.
+func (db *DB) SetNoteTags(obsidianPath string, tags []NoteTag) error {
+	tx, err := db.begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM note_tags WHERE obsidian_path = ?`, obsidianPath); err != nil {
+		return fmt.Errorf("clear tags: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO note_tags (obsidian_path, position, tag, notion_values)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	// Tags cannot contain newlines, so they separate the values.
+	for i, t := range tags {
+		if _, err := stmt.Exec(obsidianPath, i, t.Tag, strings.Join(t.Values, "\n")); err != nil {
+			return fmt.Errorf("insert tag: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetNoteTags returns the recorded tags of a note in their original order.
+func (db *DB) GetNoteTags(obsidianPath string) ([]NoteTag, error) {
+	rows, err := db.conn.Query(`
+		SELECT tag, notion_values FROM note_tags
+		WHERE obsidian_path = ?
+		ORDER BY position
+	`, obsidianPath)
+	if err != nil {
+		return nil, fmt.Errorf("query tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []NoteTag
+	for rows.Next() {
+		var t NoteTag
+		var values string
+		if err := rows.Scan(&t.Tag, &values); err != nil {
+			return nil, fmt.Errorf("scan tag: %w", err)
+		}
+		if values != "" {
+			t.Values = strings.Split(values, "\n")
+		}
+		tags = append(tags, t)
+	}
+
+	return tags, rows.Err()
+}
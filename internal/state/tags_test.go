@@ -0,0 +1,54 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDB_NoteTags(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	tags := []NoteTag{
+		{Tag: "project/alpha/backend", Values: []string{"project", "project/alpha", "project/alpha/backend"}},
+		{Tag: "inbox", Values: []string{"inbox"}},
+	}
+	if err := db.SetNoteTags("note.md", tags); err != nil {
+		t.Fatalf("SetNoteTags() error: %v", err)
+	}
+
+	got, err := db.GetNoteTags("note.md")
+	if err != nil {
+		t.Fatalf("GetNoteTags() error: %v", err)
+	}
+	if !reflect.DeepEqual(got, tags) {
+		t.Errorf("GetNoteTags() = %+v, want %+v", got, tags)
+	}
+
+	// Renames carry the tags along.
+	if err := db.UpdatePath("note.md", "renamed.md"); err != nil {
+		t.Fatalf("UpdatePath() error: %v", err)
+	}
+	if got, _ := db.GetNoteTags("renamed.md"); len(got) != 2 {
+		t.Errorf("expected tags to follow rename, got %+v", got)
+	}
+
+	// An empty slice clears them.
+	if err := db.SetNoteTags("renamed.md", nil); err != nil {
+		t.Fatalf("SetNoteTags() error: %v", err)
+	}
+	if got, _ := db.GetNoteTags("renamed.md"); len(got) != 0 {
+		t.Errorf("expected no tags, got %+v", got)
+	}
+}
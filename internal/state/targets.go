@@ -0,0 +1,82 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// NoteTarget is a property-only page a note publishes to in the database of
+// one of its notion-targets.
+type NoteTarget struct {
+	ObsidianPath string
+	DatabaseID   string
+	Target       string // Name of the target in the config
+	NotionPageID string
+	LastSync     time.Time
+}
+
+// SetNoteTarget records the page of a note in a target database, replacing
+// any earlier page of the note in that database.
+func (db *DB) SetNoteTarget(t *NoteTarget) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO note_targets (obsidian_path, database_id, target, notion_page_id, last_sync)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(obsidian_path, database_id) DO UPDATE SET
+			target = excluded.target,
+			notion_page_id = excluded.notion_page_id,
+			last_sync = excluded.last_sync
+	`, t.ObsidianPath, t.DatabaseID, t.Target, t.NotionPageID, nullTime(t.LastSync))
+	if err != nil {
+		return fmt.Errorf("set note target: %w", err)
+	}
+	return nil
+}
+
+// GetNoteTargets returns the target pages of a note.
+func (db *DB) GetNoteTargets(obsidianPath string) ([]*NoteTarget, error) {
+	rows, err := db.conn.Query(`
+		SELECT obsidian_path, database_id, target, notion_page_id, last_sync
+		FROM note_targets
+		WHERE obsidian_path = ?
+		ORDER BY target
+	`, obsidianPath)
+	if err != nil {
+		return nil, fmt.Errorf("query note targets: %w", err)
+	}
+	defer rows.Close()
+
+	var targets []*NoteTarget
+	for rows.Next() {
+		t := &NoteTarget{}
+		var lastSync sql.NullInt64
+		if err := rows.Scan(&t.ObsidianPath, &t.DatabaseID, &t.Target, &t.NotionPageID, &lastSync); err != nil {
+			return nil, fmt.Errorf("scan note target: %w", err)
+		}
+		if lastSync.Valid {
+			t.LastSync = time.Unix(lastSync.Int64, 0)
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, rows.Err()
+}
+
+// DeleteNoteTarget forgets the page of a note in a target database.
+func (db *DB) DeleteNoteTarget(obsidianPath, databaseID string) error {
+	_, err := db.conn.Exec(`DELETE FROM note_targets WHERE obsidian_path = ? AND database_id = ?`, obsidianPath, databaseID)
+	if err != nil {
+		return fmt.Errorf("delete note target: %w", err)
+	}
+	return nil
+}
+
+// IsTargetPage reports whether a Notion page is the target page of a note.
+func (db *DB) IsTargetPage(notionPageID string) (bool, error) {
+	var count int
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM note_targets WHERE notion_page_id = ?`, notionPageID).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("query note targets: %w", err)
+	}
+	return count > 0, nil
+}
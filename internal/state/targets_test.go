@@ -0,0 +1,63 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDB_NoteTargets(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	for _, target := range []*NoteTarget{
+		{ObsidianPath: "a.md", DatabaseID: "db-tasks", Target: "tasks", NotionPageID: "page-1", LastSync: now},
+		{ObsidianPath: "a.md", DatabaseID: "db-docs", Target: "docs", NotionPageID: "page-2", LastSync: now},
+		{ObsidianPath: "a.md", DatabaseID: "db-tasks", Target: "tasks", NotionPageID: "page-3", LastSync: now},
+	} {
+		if err := db.SetNoteTarget(target); err != nil {
+			t.Fatalf("SetNoteTarget() error: %v", err)
+		}
+	}
+
+	targets, err := db.GetNoteTargets("a.md")
+	if err != nil {
+		t.Fatalf("GetNoteTargets() error: %v", err)
+	}
+	if len(targets) != 2 || targets[0].Target != "docs" || targets[1].NotionPageID != "page-3" {
+		t.Fatalf("GetNoteTargets() = %+v, want docs and the replaced tasks page", targets)
+	}
+	if ok, _ := db.IsTargetPage("page-3"); !ok {
+		t.Error("IsTargetPage(page-3) = false, want true")
+	}
+	if ok, _ := db.IsTargetPage("page-1"); ok {
+		t.Error("IsTargetPage(page-1) = true for a replaced page")
+	}
+
+	// Renaming the note moves its targets.
+	if err := db.UpdatePath("a.md", "b.md"); err != nil {
+		t.Fatalf("UpdatePath() error: %v", err)
+	}
+	if targets, _ := db.GetNoteTargets("b.md"); len(targets) != 2 {
+		t.Errorf("targets after rename = %d, want 2", len(targets))
+	}
+
+	if err := db.DeleteNoteTarget("b.md", "db-docs"); err != nil {
+		t.Fatalf("DeleteNoteTarget() error: %v", err)
+	}
+	if targets, _ := db.GetNoteTargets("b.md"); len(targets) != 1 {
+		t.Errorf("targets after delete = %d, want 1", len(targets))
+	}
+
+	// Deleting the note's state forgets the rest.
+	if err := db.DeleteState("b.md"); err != nil {
+		t.Fatalf("DeleteState() error: %v", err)
+	}
+	if targets, _ := db.GetNoteTargets("b.md"); len(targets) != 0 {
+		t.Errorf("targets after DeleteState = %d, want 0", len(targets))
+	}
+}
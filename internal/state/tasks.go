@@ -0,0 +1,64 @@
+package state
+
+import "fmt"
+
+// NoteTask records the alternative state, the character in "- [>] task",
+// of a task pushed as a to-do.
+type NoteTask struct {
+	Text  string
+	State string
+}
+
+// SetNoteTasks replaces the recorded task states of a note.
+func (db *DB) SetNoteTasks(obsidianPath string, tasks []NoteTask) error {
+	tx, err := db.begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM note_tasks WHERE obsidian_path = ?`, obsidianPath); err != nil {
+		return fmt.Errorf("clear tasks: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO note_tasks (obsidian_path, position, text, state)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, t := range tasks {
+		if _, err := stmt.Exec(obsidianPath, i, t.Text, t.State); err != nil {
+			return fmt.Errorf("insert task: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetNoteTasks returns the recorded task states of a note in document order.
+func (db *DB) GetNoteTasks(obsidianPath string) ([]NoteTask, error) {
+	rows, err := db.conn.Query(`
+		SELECT text, state FROM note_tasks
+		WHERE obsidian_path = ?
+		ORDER BY position
+	`, obsidianPath)
+	if err != nil {
+		return nil, fmt.Errorf("query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []NoteTask
+	for rows.Next() {
+		var t NoteTask
+		if err := rows.Scan(&t.Text, &t.State); err != nil {
+			return nil, fmt.Errorf("scan task: %w", err)
+		}
+		tasks = append(tasks, t)
+	}
+
+	return tasks, rows.Err()
+}
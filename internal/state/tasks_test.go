@@ -0,0 +1,52 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDB_NoteTasks(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	tasks := []NoteTask{
+		{Text: "Call the bank", State: ">"},
+		{Text: "Draft the report", State: "/"},
+	}
+	if err := db.SetNoteTasks("note.md", tasks); err != nil {
+		t.Fatalf("SetNoteTasks() error: %v", err)
+	}
+
+	got, err := db.GetNoteTasks("note.md")
+	if err != nil {
+		t.Fatalf("GetNoteTasks() error: %v", err)
+	}
+	if !reflect.DeepEqual(got, tasks) {
+		t.Errorf("GetNoteTasks() = %+v, want %+v", got, tasks)
+	}
+
+	// Renames carry the tasks along, and forgetting the note drops them.
+	if err := db.UpdatePath("note.md", "renamed.md"); err != nil {
+		t.Fatalf("UpdatePath() error: %v", err)
+	}
+	if got, _ := db.GetNoteTasks("renamed.md"); len(got) != 2 {
+		t.Errorf("expected tasks to follow rename, got %+v", got)
+	}
+	if err := db.DeleteState("renamed.md"); err != nil {
+		t.Fatalf("DeleteState() error: %v", err)
+	}
+	if got, _ := db.GetNoteTasks("renamed.md"); len(got) != 0 {
+		t.Errorf("expected no tasks, got %+v", got)
+	}
+}
@@ -0,0 +1,112 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// History actions for deleted notes.
+const (
+	// HistoryTrashed means the note was deleted and its Notion page archived.
+	HistoryTrashed = "trashed"
+	// HistoryRestored means an archived page was restored to the vault.
+	HistoryRestored = "restored"
+)
+
+// TrashedPage is a Notion page archived because its note was deleted.
+type TrashedPage struct {
+	ID             int64
+	Path           string
+	NotionPageID   string
+	NotionParentID string
+	Strategy       string // Deletion strategy that removed the page
+	TrashedAt      time.Time
+}
+
+// trashDetails is the history details of a trashed page.
+type trashDetails struct {
+	NotionPageID   string `json:"notion_page_id"`
+	NotionParentID string `json:"notion_parent_id,omitempty"`
+	Strategy       string `json:"strategy"`
+}
+
+// RecordTrashed records that the Notion page of a deleted note was archived,
+// so it can be restored later.
+func (db *DB) RecordTrashed(path string, prior *SyncState, strategy string) error {
+	details, err := json.Marshal(trashDetails{
+		NotionPageID:   prior.NotionPageID,
+		NotionParentID: prior.NotionParentID,
+		Strategy:       strategy,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal details: %w", err)
+	}
+	_, err = db.conn.Exec(`
+		INSERT INTO sync_history (obsidian_path, action, timestamp, content_hash, details)
+		VALUES (?, ?, ?, ?, ?)
+	`, path, HistoryTrashed, time.Now().Unix(), nullString(prior.ContentHash), string(details))
+	if err != nil {
+		return fmt.Errorf("insert history: %w", err)
+	}
+	return nil
+}
+
+// RecordRestored records that a trashed page was restored, so it is no
+// longer listed by ListTrashed.
+func (db *DB) RecordRestored(path, notionPageID string) error {
+	details, err := json.Marshal(trashDetails{NotionPageID: notionPageID})
+	if err != nil {
+		return fmt.Errorf("marshal details: %w", err)
+	}
+	_, err = db.conn.Exec(`
+		INSERT INTO sync_history (obsidian_path, action, timestamp, details)
+		VALUES (?, ?, ?, ?)
+	`, path, HistoryRestored, time.Now().Unix(), string(details))
+	if err != nil {
+		return fmt.Errorf("insert history: %w", err)
+	}
+	return nil
+}
+
+// ListTrashed returns the pages trashed since a time and not restored
+// since, newest first.
+func (db *DB) ListTrashed(since time.Time) ([]*TrashedPage, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, obsidian_path, timestamp, details FROM sync_history h
+		WHERE action = ? AND timestamp >= ?
+		  AND NOT EXISTS (
+			SELECT 1 FROM sync_history r
+			WHERE r.obsidian_path = h.obsidian_path AND r.action = ? AND r.id > h.id
+		  )
+		ORDER BY timestamp DESC, id DESC
+	`, HistoryTrashed, since.Unix(), HistoryRestored)
+	if err != nil {
+		return nil, fmt.Errorf("query history: %w", err)
+	}
+	defer rows.Close()
+
+	var pages []*TrashedPage
+	for rows.Next() {
+		var (
+			p         TrashedPage
+			timestamp int64
+			details   *string
+		)
+		if err := rows.Scan(&p.ID, &p.Path, &timestamp, &details); err != nil {
+			return nil, fmt.Errorf("scan history: %w", err)
+		}
+		p.TrashedAt = time.Unix(timestamp, 0)
+		if details != nil {
+			var d trashDetails
+			if err := json.Unmarshal([]byte(*details), &d); err != nil {
+				return nil, fmt.Errorf("unmarshal details: %w", err)
+			}
+			p.NotionPageID = d.NotionPageID
+			p.NotionParentID = d.NotionParentID
+			p.Strategy = d.Strategy
+		}
+		pages = append(pages, &p)
+	}
+	return pages, rows.Err()
+}
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDB_Trash(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	for _, s := range []*SyncState{
+		{ObsidianPath: "a.md", NotionPageID: "page-a", NotionParentID: "db-1", ContentHash: "hash-a"},
+		{ObsidianPath: "b.md", NotionPageID: "page-b"},
+	} {
+		if err := db.RecordTrashed(s.ObsidianPath, s, "archive"); err != nil {
+			t.Fatalf("RecordTrashed(%s) error: %v", s.ObsidianPath, err)
+		}
+	}
+
+	trashed, err := db.ListTrashed(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ListTrashed() error: %v", err)
+	}
+	if len(trashed) != 2 {
+		t.Fatalf("ListTrashed() = %d pages, want 2", len(trashed))
+	}
+	byPath := make(map[string]*TrashedPage)
+	for _, p := range trashed {
+		byPath[p.Path] = p
+	}
+	if a := byPath["a.md"]; a == nil || a.NotionPageID != "page-a" || a.NotionParentID != "db-1" || a.Strategy != "archive" {
+		t.Errorf("trashed a.md = %+v", a)
+	}
+
+	// A restored page is no longer listed.
+	if err := db.RecordRestored("a.md", "page-a"); err != nil {
+		t.Fatalf("RecordRestored() error: %v", err)
+	}
+	trashed, err = db.ListTrashed(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ListTrashed() error: %v", err)
+	}
+	if len(trashed) != 1 || trashed[0].Path != "b.md" {
+		t.Errorf("ListTrashed() after restore = %+v, want only b.md", trashed)
+	}
+
+	// Deleting the note again trashes it again.
+	if err := db.RecordTrashed("a.md", &SyncState{NotionPageID: "page-a"}, "delete"); err != nil {
+		t.Fatalf("RecordTrashed() error: %v", err)
+	}
+	trashed, err = db.ListTrashed(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ListTrashed() error: %v", err)
+	}
+	if len(trashed) != 2 {
+		t.Errorf("ListTrashed() after deleting again = %d pages, want 2", len(trashed))
+	}
+
+	// Pages trashed before the cutoff are left out.
+	trashed, err = db.ListTrashed(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ListTrashed() error: %v", err)
+	}
+	if len(trashed) != 0 {
+		t.Errorf("ListTrashed(future) = %d pages, want 0", len(trashed))
+	}
+}
@@ -0,0 +1,64 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// querier is the part of *sql.DB and *sql.Tx the state methods use, so the
+// same methods work inside and outside a transaction.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	Prepare(query string) (*sql.Stmt, error)
+}
+
+// InTx runs fn in a transaction, committing it if fn returns nil and
+// rolling it back otherwise. Registries and stores created from the DB
+// passed to fn write inside the transaction. Calls made inside fn join it
+// rather than starting their own. The DB passed to fn must not be closed.
+func (db *DB) InTx(fn func(tx *DB) error) error {
+	if _, ok := db.conn.(*sql.Tx); ok {
+		return fn(db)
+	}
+
+	tx, err := db.sqlDB.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&DB{conn: tx, sqlDB: db.sqlDB, path: db.path}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// stateTx is a transaction used by a single state method. Inside InTx it
+// joins the outer transaction, leaving the commit to InTx.
+type stateTx struct {
+	querier
+	commit   func() error
+	rollback func() error
+}
+
+// Commit commits the transaction, unless it belongs to an outer InTx.
+func (t *stateTx) Commit() error { return t.commit() }
+
+// Rollback rolls the transaction back, unless it belongs to an outer InTx.
+func (t *stateTx) Rollback() error { return t.rollback() }
+
+// begin starts a transaction for a method that writes several rows.
+func (db *DB) begin() (*stateTx, error) {
+	if outer, ok := db.conn.(*sql.Tx); ok {
+		noop := func() error { return nil }
+		return &stateTx{querier: outer, commit: noop, rollback: noop}, nil
+	}
+
+	tx, err := db.sqlDB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &stateTx{querier: tx, commit: tx.Commit, rollback: tx.Rollback}, nil
+}
@@ -0,0 +1,61 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDB_InTx(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	// A failing transaction leaves nothing behind, including writes made
+	// by methods that use their own transaction.
+	errBoom := errors.New("boom")
+	err = db.InTx(func(tx *DB) error {
+		if err := tx.SetState(&SyncState{ObsidianPath: "a.md", Status: "synced"}); err != nil {
+			return err
+		}
+		if err := NewLinkRegistry(tx).RegisterLinks("a.md", []string{"B", "C"}); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("InTx() error = %v; want boom", err)
+	}
+	if s, _ := db.GetState("a.md"); s != nil {
+		t.Errorf("state after rollback = %+v; want nil", s)
+	}
+	if links, _ := NewLinkRegistry(db).GetLinksFrom("a.md"); len(links) != 0 {
+		t.Errorf("links after rollback = %d; want 0", len(links))
+	}
+
+	// A successful one commits everything.
+	err = db.InTx(func(tx *DB) error {
+		if err := tx.SetState(&SyncState{ObsidianPath: "a.md", Status: "synced"}); err != nil {
+			return err
+		}
+		return NewLinkRegistry(tx).ReplaceLinks("a.md", []string{"B"})
+	})
+	if err != nil {
+		t.Fatalf("InTx() error: %v", err)
+	}
+	if s, _ := db.GetState("a.md"); s == nil {
+		t.Error("state not committed")
+	}
+	if links, _ := NewLinkRegistry(db).GetLinksFrom("a.md"); len(links) != 1 {
+		t.Errorf("links = %d; want 1", len(links))
+	}
+}
@@ -0,0 +1,165 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Snapshot actions, describing what a run did to a note.
+const (
+	// SnapshotPulled means the run wrote the local file from Notion.
+	SnapshotPulled = "pulled"
+	// SnapshotUpdated means the run replaced the content of a Notion page.
+	SnapshotUpdated = "updated"
+	// SnapshotCreated means the run created a Notion page.
+	SnapshotCreated = "created"
+)
+
+// Snapshot records a note as it was before a run changed it.
+type Snapshot struct {
+	Path         string
+	Action       string
+	Content      []byte     // Local file before a pull; nil if it did not exist
+	Remote       []byte     // Notion page as markdown before an update
+	NotionPageID string     // Page the run updated or created
+	Prior        *SyncState // Sync state before the run; nil if untracked
+}
+
+// UndoRun is a run together with the snapshots taken during it.
+type UndoRun struct {
+	ID        int64
+	Command   string
+	StartedAt time.Time
+	Snapshots []*Snapshot
+}
+
+// BeginUndoRun starts recording snapshots for a run and returns its ID.
+func (db *DB) BeginUndoRun(command string, started time.Time) (int64, error) {
+	result, err := db.conn.Exec(`
+		INSERT INTO undo_runs (command, started_at) VALUES (?, ?)
+	`, command, started.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("insert undo run: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// SaveSnapshot stores a snapshot for a run. Only the first snapshot of a
+// path is kept, since later ones no longer describe the state before the run.
+func (db *DB) SaveSnapshot(runID int64, s *Snapshot) error {
+	prior := s.Prior
+	if prior == nil {
+		prior = &SyncState{}
+	}
+	_, err := db.conn.Exec(`
+		INSERT OR IGNORE INTO undo_snapshots (
+			run_id, obsidian_path, action, content, remote, notion_page_id,
+			prior_status, prior_parent_id, prior_content_hash, prior_frontmatter_hash,
+			prior_obsidian_mtime, prior_notion_mtime, prior_last_sync, prior_direction
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		runID, s.Path, s.Action, s.Content, s.Remote, nullString(s.NotionPageID),
+		nullString(prior.Status), nullString(prior.NotionParentID),
+		nullString(prior.ContentHash), nullString(prior.FrontmatterHash),
+		nullTime(prior.ObsidianMtime), nullTime(prior.NotionMtime),
+		nullTime(prior.LastSync), nullString(prior.SyncDirection),
+	)
+	if err != nil {
+		return fmt.Errorf("insert snapshot: %w", err)
+	}
+	return nil
+}
+
+// LastUndoRun returns the most recent run that recorded snapshots, or nil
+// if there is none.
+func (db *DB) LastUndoRun() (*UndoRun, error) {
+	run := &UndoRun{}
+	var startedAt int64
+	err := db.conn.QueryRow(`
+		SELECT id, command, started_at FROM undo_runs r
+		WHERE EXISTS (SELECT 1 FROM undo_snapshots s WHERE s.run_id = r.id)
+		ORDER BY started_at DESC, id DESC
+		LIMIT 1
+	`).Scan(&run.ID, &run.Command, &startedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query undo run: %w", err)
+	}
+	run.StartedAt = time.Unix(startedAt, 0)
+
+	rows, err := db.conn.Query(`
+		SELECT obsidian_path, action, content, remote, notion_page_id,
+		       prior_status, prior_parent_id, prior_content_hash, prior_frontmatter_hash,
+		       prior_obsidian_mtime, prior_notion_mtime, prior_last_sync, prior_direction
+		FROM undo_snapshots
+		WHERE run_id = ?
+		ORDER BY obsidian_path
+	`, run.ID)
+	if err != nil {
+		return nil, fmt.Errorf("query snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s := &Snapshot{}
+		var pageID, status, parentID, contentHash, frontmatterHash, direction sql.NullString
+		var obsidianMtime, notionMtime, lastSync sql.NullInt64
+		if err := rows.Scan(&s.Path, &s.Action, &s.Content, &s.Remote, &pageID,
+			&status, &parentID, &contentHash, &frontmatterHash,
+			&obsidianMtime, &notionMtime, &lastSync, &direction); err != nil {
+			return nil, fmt.Errorf("scan snapshot: %w", err)
+		}
+		s.NotionPageID = pageID.String
+		if status.Valid {
+			s.Prior = &SyncState{
+				ObsidianPath:    s.Path,
+				NotionPageID:    s.NotionPageID,
+				NotionParentID:  parentID.String,
+				ContentHash:     contentHash.String,
+				FrontmatterHash: frontmatterHash.String,
+				SyncDirection:   direction.String,
+				Status:          status.String,
+			}
+			if obsidianMtime.Valid {
+				s.Prior.ObsidianMtime = time.Unix(obsidianMtime.Int64, 0)
+			}
+			if notionMtime.Valid {
+				s.Prior.NotionMtime = time.Unix(notionMtime.Int64, 0)
+			}
+			if lastSync.Valid {
+				s.Prior.LastSync = time.Unix(lastSync.Int64, 0)
+			}
+		}
+		run.Snapshots = append(run.Snapshots, s)
+	}
+	return run, rows.Err()
+}
+
+// DeleteUndoRun removes a run and its snapshots.
+func (db *DB) DeleteUndoRun(id int64) error {
+	if _, err := db.conn.Exec(`DELETE FROM undo_snapshots WHERE run_id = ?`, id); err != nil {
+		return fmt.Errorf("delete snapshots: %w", err)
+	}
+	if _, err := db.conn.Exec(`DELETE FROM undo_runs WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete undo run: %w", err)
+	}
+	return nil
+}
+
+// PruneUndoRuns removes runs started before the given time, with their
+// snapshots.
+func (db *DB) PruneUndoRuns(before time.Time) error {
+	if _, err := db.conn.Exec(`
+		DELETE FROM undo_snapshots
+		WHERE run_id IN (SELECT id FROM undo_runs WHERE started_at < ?)
+	`, before.Unix()); err != nil {
+		return fmt.Errorf("prune snapshots: %w", err)
+	}
+	if _, err := db.conn.Exec(`DELETE FROM undo_runs WHERE started_at < ?`, before.Unix()); err != nil {
+		return fmt.Errorf("prune undo runs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDB_UndoRuns(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "obsidian-test-*")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	if run, err := db.LastUndoRun(); err != nil || run != nil {
+		t.Fatalf("LastUndoRun() on empty db = %+v, %v", run, err)
+	}
+
+	start := time.Unix(1700000000, 0)
+	first, err := db.BeginUndoRun("push", start)
+	if err != nil {
+		t.Fatalf("BeginUndoRun() error: %v", err)
+	}
+	if err := db.SaveSnapshot(first, &Snapshot{Path: "a.md", Action: SnapshotCreated, NotionPageID: "page-a"}); err != nil {
+		t.Fatalf("SaveSnapshot() error: %v", err)
+	}
+
+	second, err := db.BeginUndoRun("sync", start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("BeginUndoRun() error: %v", err)
+	}
+	prior := &SyncState{
+		ObsidianPath: "b.md",
+		NotionPageID: "page-b",
+		ContentHash:  "hash-b",
+		NotionMtime:  start,
+		LastSync:     start,
+		Status:       "synced",
+	}
+	snapshots := []*Snapshot{
+		{Path: "b.md", Action: SnapshotPulled, Content: []byte("# Before\n"), NotionPageID: "page-b", Prior: prior},
+		{Path: "c.md", Action: SnapshotPulled, Content: []byte{}, NotionPageID: "page-c"},
+		{Path: "d.md", Action: SnapshotPulled, NotionPageID: "page-d"},
+		{Path: "e.md", Action: SnapshotUpdated, Remote: []byte("# Remote\n"), NotionPageID: "page-e"},
+		// A later snapshot of the same path is ignored.
+		{Path: "b.md", Action: SnapshotPulled, Content: []byte("# After\n")},
+	}
+	for _, s := range snapshots {
+		if err := db.SaveSnapshot(second, s); err != nil {
+			t.Fatalf("SaveSnapshot(%s) error: %v", s.Path, err)
+		}
+	}
+
+	// A run without snapshots is never the one undone.
+	if _, err := db.BeginUndoRun("pull", start.Add(2*time.Hour)); err != nil {
+		t.Fatalf("BeginUndoRun() error: %v", err)
+	}
+
+	run, err := db.LastUndoRun()
+	if err != nil {
+		t.Fatalf("LastUndoRun() error: %v", err)
+	}
+	if run == nil || run.ID != second || run.Command != "sync" || !run.StartedAt.Equal(start.Add(time.Hour)) {
+		t.Fatalf("LastUndoRun() = %+v; want the sync run", run)
+	}
+	if len(run.Snapshots) != 4 {
+		t.Fatalf("got %d snapshots; want 4", len(run.Snapshots))
+	}
+
+	b, c, d, e := run.Snapshots[0], run.Snapshots[1], run.Snapshots[2], run.Snapshots[3]
+	if string(b.Content) != "# Before\n" {
+		t.Errorf("b.md content = %q; want the first snapshot", b.Content)
+	}
+	if b.Prior == nil || b.Prior.ContentHash != "hash-b" || b.Prior.Status != "synced" || !b.Prior.NotionMtime.Equal(start) {
+		t.Errorf("b.md prior state = %+v", b.Prior)
+	}
+	if c.Content == nil || len(c.Content) != 0 {
+		t.Errorf("c.md content = %#v; want an empty file", c.Content)
+	}
+	if d.Content != nil || d.Prior != nil {
+		t.Errorf("d.md = %+v; want no content and no prior state", d)
+	}
+	if e.Action != SnapshotUpdated || string(e.Remote) != "# Remote\n" || e.NotionPageID != "page-e" {
+		t.Errorf("e.md = %+v", e)
+	}
+
+	// Undoing the last run exposes the one before it.
+	if err := db.DeleteUndoRun(second); err != nil {
+		t.Fatalf("DeleteUndoRun() error: %v", err)
+	}
+	run, err = db.LastUndoRun()
+	if err != nil || run == nil || run.ID != first {
+		t.Fatalf("LastUndoRun() after delete = %+v, %v; want the push run", run, err)
+	}
+
+	if err := db.PruneUndoRuns(start.Add(time.Minute)); err != nil {
+		t.Fatalf("PruneUndoRuns() error: %v", err)
+	}
+	if run, err := db.LastUndoRun(); err != nil || run != nil {
+		t.Errorf("LastUndoRun() after prune = %+v, %v; want nil", run, err)
+	}
+}
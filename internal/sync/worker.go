@@ -3,7 +3,10 @@ package sync
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 )
 
 // WorkerPool manages a pool of workers for parallel task execution.
@@ -76,14 +79,14 @@ func Process[T any, R any](ctx context.Context, pool *WorkerPool, inputs []T, fn
 
 	// Send inputs to workers.
 	go func() {
+		defer close(inputCh)
 		for i, input := range inputs {
 			select {
 			case <-ctx.Done():
-				break
+				return
 			case inputCh <- indexedInput{index: i, input: input}:
 			}
 		}
-		close(inputCh)
 	}()
 
 	// Wait for workers to finish and close result channel.
@@ -98,10 +101,13 @@ func Process[T any, R any](ctx context.Context, pool *WorkerPool, inputs []T, fn
 		results[i].Input = inputs[i]
 	}
 
+	reached := make([]bool, len(inputs))
 	for result := range resultCh {
 		results[result.index].Result = result.result
 		results[result.index].Err = result.err
+		reached[result.index] = true
 	}
+	markUnreached(ctx, results, reached)
 
 	return results
 }
@@ -159,14 +165,14 @@ func ProcessWithProgress[T any, R any](
 
 	// Send inputs to workers.
 	go func() {
+		defer close(inputCh)
 		for i, input := range inputs {
 			select {
 			case <-ctx.Done():
-				break
+				return
 			case inputCh <- indexedInput{index: i, input: input}:
 			}
 		}
-		close(inputCh)
 	}()
 
 	// Wait for workers to finish and close result channel.
@@ -183,14 +189,45 @@ func ProcessWithProgress[T any, R any](
 
 	completed := 0
 	total := len(inputs)
+	reached := make([]bool, len(inputs))
 	for result := range resultCh {
 		results[result.index].Result = result.result
 		results[result.index].Err = result.err
+		reached[result.index] = true
 		completed++
 		if progress != nil {
 			progress(completed, total)
 		}
 	}
+	markUnreached(ctx, results, reached)
 
 	return results
 }
+
+// markUnreached gives the tasks a cancelled context kept from running the
+// context's error, so they are not mistaken for tasks that succeeded.
+func markUnreached[T any, R any](ctx context.Context, results []Task[T, R], reached []bool) {
+	for i := range results {
+		if !reached[i] {
+			results[i].Err = ctx.Err()
+		}
+	}
+}
+
+// WithTimeout returns fn limited to timeout per task, or fn itself if
+// timeout is 0. A task that runs out of time fails with an error saying
+// so, wrapping context.DeadlineExceeded.
+func WithTimeout[T any, R any](timeout time.Duration, fn func(context.Context, T) (R, error)) func(context.Context, T) (R, error) {
+	if timeout <= 0 {
+		return fn
+	}
+	return func(ctx context.Context, input T) (R, error) {
+		taskCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		result, err := fn(taskCtx, input)
+		if err != nil && ctx.Err() == nil && errors.Is(taskCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+		return result, err
+	}
+}
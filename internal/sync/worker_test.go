@@ -357,3 +357,53 @@ func TestProcess_StructInputsAndOutputs(t *testing.T) {
 		}
 	}
 }
+
+func TestProcess_UnreachedTasksFail(t *testing.T) {
+	pool := NewWorkerPool(1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	results := Process(ctx, pool, []int{1, 2, 3}, func(ctx context.Context, n int) (int, error) {
+		cancel()
+		return n, nil
+	})
+
+	if results[0].Err != nil {
+		t.Errorf("first task: unexpected error %v", results[0].Err)
+	}
+	for _, r := range results[1:] {
+		if r.Err == nil && r.Result == 0 {
+			t.Errorf("task %d: not run but has no error", r.Input)
+		}
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	slow := func(ctx context.Context, n int) (int, error) {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(time.Second):
+			return n, nil
+		}
+	}
+
+	_, err := WithTimeout(10*time.Millisecond, slow)(context.Background(), 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if got := err.Error(); got != "timed out after 10ms: context deadline exceeded" {
+		t.Errorf("error = %q", got)
+	}
+
+	// A cancelled run is not reported as a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = WithTimeout(time.Minute, slow)(ctx, 1)
+	if err != context.Canceled {
+		t.Errorf("expected the cancellation itself, got %v", err)
+	}
+
+	if n, err := WithTimeout(0, func(ctx context.Context, n int) (int, error) { return n, nil })(context.Background(), 7); err != nil || n != 7 {
+		t.Errorf("no timeout: got %d, %v", n, err)
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/jomei/notionapi"
 	"github.com/yuin/goldmark/ast"
 	extast "github.com/yuin/goldmark/extension/ast"
+	"go.abhg.dev/goldmark/wikilink"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
 )
 
 // transformHeading converts a goldmark heading to a Notion heading block.
@@ -70,10 +73,13 @@ func (t *Transformer) tryImageBlock(p *ast.Paragraph, source []byte) notionapi.B
 	var imageNode *ast.Image
 	childCount := 0
 
+	var embedNode *wikilink.Node
 	for child := p.FirstChild(); child != nil; child = child.NextSibling() {
 		childCount++
 		if img, ok := child.(*ast.Image); ok {
 			imageNode = img
+		} else if wl, ok := child.(*wikilink.Node); ok && wl.Embed && isImageFile(string(wl.Target)) {
+			embedNode = wl
 		} else if txt, ok := child.(*ast.Text); ok {
 			// Allow whitespace-only text nodes.
 			content := strings.TrimSpace(string(txt.Segment.Value(source)))
@@ -84,7 +90,21 @@ func (t *Transformer) tryImageBlock(p *ast.Paragraph, source []byte) notionapi.B
 		}
 	}
 
-	if imageNode == nil || childCount != 1 {
+	if childCount != 1 {
+		return nil
+	}
+
+	// Image embeds (![[image.png]]) only become image blocks once uploaded;
+	// otherwise they stay inline placeholders in the paragraph.
+	if embedNode != nil {
+		target := string(embedNode.Target)
+		if uploadID, ok := t.resolveAttachment(target); ok {
+			return newFileUploadImageBlock(uploadID, embedSizeCaption(target, extractWikilinkAliasFromNode(embedNode, source)))
+		}
+		return nil
+	}
+
+	if imageNode == nil {
 		return nil
 	}
 
@@ -93,16 +113,13 @@ func (t *Transformer) tryImageBlock(p *ast.Paragraph, source []byte) notionapi.B
 
 // transformImage converts an ast.Image to a Notion image block.
 func (t *Transformer) transformImage(img *ast.Image, source []byte) notionapi.Block {
-	url := string(img.Destination)
-	alt := string(img.Text(source))
+	return t.imageBlock(string(img.Destination), string(img.Text(source)))
+}
 
-	// Check if this is a local file path.
-	if isLocalPath(url) {
-		// For local images without upload support, create a placeholder callout.
-		return t.createImagePlaceholder(url, alt, "local")
-	}
+// imageBlock creates an image block for a URL or local path. Local images
+// that were not uploaded become placeholder callouts.
+func (t *Transformer) imageBlock(url, alt string) notionapi.Block {
 
-	// External image - create an ImageBlock.
 	var caption []notionapi.RichText
 	if alt != "" {
 		caption = []notionapi.RichText{
@@ -113,6 +130,17 @@ func (t *Transformer) transformImage(img *ast.Image, source []byte) notionapi.Bl
 		}
 	}
 
+	// Check if this is a local file path.
+	if isLocalPath(url) {
+		if uploadID, ok := t.resolveAttachment(url); ok {
+			return newFileUploadImageBlock(uploadID, caption)
+		}
+		// For local images that were not uploaded, create a placeholder callout.
+		return t.createImagePlaceholder(url, alt, "local")
+	}
+
+	// External image - create an ImageBlock.
+
 	return &notionapi.ImageBlock{
 		BasicBlock: notionapi.BasicBlock{
 			Object: notionapi.ObjectTypeBlock,
@@ -128,6 +156,64 @@ func (t *Transformer) transformImage(img *ast.Image, source []byte) notionapi.Bl
 	}
 }
 
+// embedSizeCaption returns the caption recording the display size of an
+// image embed (![[banner.png|800x200]]). Notion's API cannot size images,
+// so the size is kept in the caption as "banner.png|800x200", which pull
+// turns back into the embed. Embeds without a size get no caption.
+func embedSizeCaption(target, alias string) []notionapi.RichText {
+	if _, _, ok := parser.ParseImageSize(alias); !ok {
+		return nil
+	}
+	return []notionapi.RichText{
+		{
+			Type: notionapi.ObjectTypeText,
+			Text: &notionapi.Text{Content: target + "|" + alias},
+		},
+	}
+}
+
+// resolveAttachment looks up an uploaded attachment for a local reference.
+func (t *Transformer) resolveAttachment(ref string) (string, bool) {
+	if t.attachmentResolver == nil {
+		return "", false
+	}
+	return t.attachmentResolver.ResolveAttachment(ref)
+}
+
+// FileUploadImageBlock is an image block that references a Notion file upload.
+// notionapi.Image has no representation for the file_upload source type.
+type FileUploadImageBlock struct {
+	notionapi.BasicBlock
+	Image FileUploadImage `json:"image"`
+}
+
+// FileUploadImage is the image payload of a FileUploadImageBlock.
+type FileUploadImage struct {
+	Type       string               `json:"type"`
+	FileUpload FileUploadRef        `json:"file_upload"`
+	Caption    []notionapi.RichText `json:"caption,omitempty"`
+}
+
+// FileUploadRef identifies an uploaded file by ID.
+type FileUploadRef struct {
+	ID string `json:"id"`
+}
+
+// newFileUploadImageBlock creates an image block for an uploaded file.
+func newFileUploadImageBlock(uploadID string, caption []notionapi.RichText) notionapi.Block {
+	return &FileUploadImageBlock{
+		BasicBlock: notionapi.BasicBlock{
+			Object: notionapi.ObjectTypeBlock,
+			Type:   notionapi.BlockTypeImage,
+		},
+		Image: FileUploadImage{
+			Type:       "file_upload",
+			FileUpload: FileUploadRef{ID: uploadID},
+			Caption:    caption,
+		},
+	}
+}
+
 // isLocalPath checks if a URL is a local file path.
 func isLocalPath(url string) bool {
 	// Data URLs are inline embedded content, not local files.
@@ -182,7 +268,7 @@ func (t *Transformer) createImagePlaceholder(path, alt, reason string) notionapi
 		},
 	}
 
-	emoji := notionapi.Emoji("🖼️")
+	emoji := t.icon(IconImage)
 	return &notionapi.CalloutBlock{
 		BasicBlock: notionapi.BasicBlock{
 			Object: notionapi.ObjectTypeBlock,
@@ -210,6 +296,9 @@ func (t *Transformer) transformListItem(li *ast.ListItem, source []byte) notiona
 	if isTaskItem(li) {
 		return t.transformTaskItem(li, source)
 	}
+	if state, marker, ok := t.altTaskState(li, source); ok {
+		return t.transformAltTaskItem(li, source, state, marker)
+	}
 
 	// Check if ordered or unordered.
 	if list.IsOrdered() {
@@ -378,6 +467,7 @@ func (t *Transformer) transformQuote(bq *ast.Blockquote, source []byte) notionap
 		},
 		Quote: notionapi.Quote{
 			RichText: richText,
+			Children: t.transformBlockquoteChildren(bq, source),
 		},
 	}
 }
@@ -398,13 +488,14 @@ func (t *Transformer) tryCallout(bq *ast.Blockquote, source []byte) notionapi.Bl
 	}
 
 	calloutType := strings.ToLower(matches[1])
-	// matches[2] is the fold indicator (+ or -), we ignore it for Notion
-	title := strings.TrimSpace(matches[3])
+	// matches[2] is the fold indicator (+ or -), we ignore it for Notion.
+	// The title is the rest of the line, links and formatting included.
+	title := t.transformCalloutTitle(bq, source, len(matches[0])-len(matches[3]))
 
 	// Get icon for this callout type.
 	icon := t.config.CalloutIcons[calloutType]
 	if icon == "" {
-		icon = "💡" // Default icon
+		icon = string(t.icon(IconCallout))
 	}
 
 	// Get remaining content, skipping the first line (callout marker).
@@ -414,14 +505,8 @@ func (t *Transformer) tryCallout(bq *ast.Blockquote, source []byte) notionapi.Bl
 	var richText []notionapi.RichText
 
 	// Add title with bold formatting if present.
-	if title != "" {
-		richText = append(richText, notionapi.RichText{
-			Type: notionapi.ObjectTypeText,
-			Text: &notionapi.Text{Content: title},
-			Annotations: &notionapi.Annotations{
-				Bold: true,
-			},
-		})
+	if len(title) > 0 {
+		richText = append(richText, title...)
 		// Add newline separator if there's content after the title.
 		if len(content) > 0 {
 			richText = append(richText, notionapi.RichText{
@@ -445,6 +530,7 @@ func (t *Transformer) tryCallout(bq *ast.Blockquote, source []byte) notionapi.Bl
 				Type:  "emoji",
 				Emoji: &emoji,
 			},
+			Children: t.transformBlockquoteChildren(bq, source),
 		},
 	}
 }
@@ -620,8 +706,7 @@ func (t *Transformer) transformDataviewBlock(cb *ast.FencedCodeBlock, source []b
 		},
 	}
 
-	// Use info icon for dataview placeholders.
-	emoji := notionapi.Emoji("📊")
+	emoji := t.icon(IconDataview)
 
 	return &notionapi.CalloutBlock{
 		BasicBlock: notionapi.BasicBlock{
@@ -641,17 +726,18 @@ func (t *Transformer) transformDataviewBlock(cb *ast.FencedCodeBlock, source []b
 
 // Helper functions
 
-// extractNestedChildren extracts nested list items from a list item's children.
-// In goldmark's AST, a list item can have nested lists as children (after the TextBlock/Paragraph).
-// This function finds those nested lists and recursively transforms their items into Notion blocks.
+// extractNestedChildren extracts the child blocks of a list item.
+// In goldmark's AST, a list item's first TextBlock/Paragraph is its own text; nested lists,
+// further paragraphs, code blocks, and quotes after it become the item's children in Notion.
 func (t *Transformer) extractNestedChildren(li *ast.ListItem, source []byte) notionapi.Blocks {
 	var children notionapi.Blocks
 
-	// Walk through children of the list item looking for nested lists.
+	seenContent := false
 	for child := li.FirstChild(); child != nil; child = child.NextSibling() {
-		if nestedList, ok := child.(*ast.List); ok {
+		switch c := child.(type) {
+		case *ast.List:
 			// Transform each item in the nested list.
-			for item := nestedList.FirstChild(); item != nil; item = item.NextSibling() {
+			for item := c.FirstChild(); item != nil; item = item.NextSibling() {
 				if nestedItem, ok := item.(*ast.ListItem); ok {
 					block := t.transformListItem(nestedItem, source)
 					if block != nil {
@@ -659,6 +745,16 @@ func (t *Transformer) extractNestedChildren(li *ast.ListItem, source []byte) not
 					}
 				}
 			}
+			continue
+		case *ast.TextBlock, *ast.Paragraph:
+			if !seenContent {
+				// The item's own text, see transformListItemContent.
+				seenContent = true
+				continue
+			}
+		}
+		if block, _ := t.transformNode(child, source); block != nil {
+			children = append(children, block)
 		}
 	}
 
@@ -717,57 +813,95 @@ func (t *Transformer) transformListItemContent(li *ast.ListItem, source []byte)
 	return []notionapi.RichText{}
 }
 
-// transformBlockquoteContent extracts content from a blockquote.
+// transformBlockquoteContent extracts the rich text of a blockquote: the
+// paragraphs before any other content.
 func (t *Transformer) transformBlockquoteContent(bq *ast.Blockquote, source []byte) []notionapi.RichText {
 	var result []notionapi.RichText
 
 	for child := bq.FirstChild(); child != nil; child = child.NextSibling() {
-		if p, ok := child.(*ast.Paragraph); ok {
-			result = append(result, t.transformInlineContent(p, source)...)
+		p, ok := child.(*ast.Paragraph)
+		if !ok {
+			break
 		}
+		result = append(result, t.transformInlineContent(p, source)...)
 	}
 
 	return result
 }
 
 // transformCalloutContent extracts content from a callout blockquote,
-// skipping the first line which contains the callout marker.
+// skipping the first line which contains the callout marker. Like
+// transformBlockquoteContent, it stops at the first non-paragraph.
 func (t *Transformer) transformCalloutContent(bq *ast.Blockquote, source []byte) []notionapi.RichText {
 	var result []notionapi.RichText
 	isFirst := true
 
 	for child := bq.FirstChild(); child != nil; child = child.NextSibling() {
-		if p, ok := child.(*ast.Paragraph); ok {
-			if isFirst {
-				// Skip content before the first newline in the first paragraph.
-				// The callout marker is on the first line.
-				isFirst = false
-				result = append(result, t.transformCalloutParagraph(p, source)...)
-			} else {
-				result = append(result, t.transformInlineContent(p, source)...)
-			}
+		p, ok := child.(*ast.Paragraph)
+		if !ok {
+			break
+		}
+		if isFirst {
+			// Skip content before the first newline in the first paragraph.
+			// The callout marker is on the first line.
+			isFirst = false
+			result = append(result, t.transformCalloutParagraph(p, source)...)
+		} else {
+			result = append(result, t.transformInlineContent(p, source)...)
 		}
 	}
 
 	return result
 }
 
-// transformCalloutParagraph transforms a paragraph, skipping the first line.
+// transformBlockquoteChildren converts the content of a quote or callout
+// from its first non-paragraph on, such as lists and code, to child blocks.
+// Nodes are converted as they would be at the top level of the note.
+func (t *Transformer) transformBlockquoteChildren(bq *ast.Blockquote, source []byte) notionapi.Blocks {
+	child := bq.FirstChild()
+	for child != nil {
+		if _, ok := child.(*ast.Paragraph); !ok {
+			break
+		}
+		child = child.NextSibling()
+	}
+
+	var children notionapi.Blocks
+	for ; child != nil; child = child.NextSibling() {
+		_ = ast.Walk(child, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+			if t.consumed[n] {
+				return ast.WalkSkipChildren, nil
+			}
+			block, skipChildren := t.transformNode(n, source)
+			if block != nil {
+				children = append(children, block)
+			}
+			if skipChildren {
+				return ast.WalkSkipChildren, nil
+			}
+			return ast.WalkContinue, nil
+		})
+	}
+	return children
+}
+
+// transformCalloutParagraph transforms a paragraph, skipping the first
+// line, which holds the callout marker and title.
 func (t *Transformer) transformCalloutParagraph(p *ast.Paragraph, source []byte) []notionapi.RichText {
 	var result []notionapi.RichText
 	skippedFirstLine := false
 
 	for child := p.FirstChild(); child != nil; child = child.NextSibling() {
 		if !skippedFirstLine {
-			// Skip until we hit a soft/hard line break or run out of text nodes.
-			if txt, ok := child.(*ast.Text); ok {
-				if txt.SoftLineBreak() || txt.HardLineBreak() {
-					skippedFirstLine = true
-					continue
-				}
-				// Skip this text node as it's part of the first line.
-				continue
+			// Skip until we hit a soft/hard line break; links and
+			// emphasis before it are part of the title.
+			if txt, ok := child.(*ast.Text); ok && (txt.SoftLineBreak() || txt.HardLineBreak()) {
+				skippedFirstLine = true
 			}
+			continue
 		}
 
 		// Process remaining content.
@@ -777,6 +911,78 @@ func (t *Transformer) transformCalloutParagraph(p *ast.Paragraph, source []byte)
 	return result
 }
 
+// transformCalloutTitle transforms the title of a callout: the first line
+// of its first paragraph after the marker, which is skip bytes long. The
+// title is bold and keeps its links, mentions, and other formatting.
+func (t *Transformer) transformCalloutTitle(bq *ast.Blockquote, source []byte, skip int) []notionapi.RichText {
+	p, ok := bq.FirstChild().(*ast.Paragraph)
+	if !ok {
+		return nil
+	}
+
+	var title []notionapi.RichText
+	for child := p.FirstChild(); child != nil; child = child.NextSibling() {
+		txt, isText := child.(*ast.Text)
+		lineBreak := isText && (txt.SoftLineBreak() || txt.HardLineBreak())
+		switch {
+		case isText && skip > 0:
+			// The marker may span several text nodes, such as "[" and "!note]".
+			value := txt.Segment.Value(source)
+			if len(value) > skip {
+				title = append(title, notionapi.RichText{
+					Type: notionapi.ObjectTypeText,
+					Text: &notionapi.Text{Content: string(value[skip:])},
+				})
+			}
+			skip = max(skip-len(value), 0)
+		case isText:
+			title = append(title, notionapi.RichText{
+				Type: notionapi.ObjectTypeText,
+				Text: &notionapi.Text{Content: string(txt.Segment.Value(source))},
+			})
+		default:
+			title = append(title, t.transformInline(child, source, nil)...)
+		}
+		if lineBreak {
+			break
+		}
+	}
+
+	title = trimRichText(mergePlainRuns(title))
+	for i := range title {
+		annotations := copyAnnotations(title[i].Annotations)
+		annotations.Bold = true
+		title[i].Annotations = annotations
+	}
+	return t.expandRuns(title)
+}
+
+// trimRichText trims spaces from the start of the first run and the end of
+// the last, dropping runs left empty.
+func trimRichText(richText []notionapi.RichText) []notionapi.RichText {
+	for len(richText) > 0 && richText[0].Type == notionapi.ObjectTypeText && richText[0].Text != nil {
+		content := strings.TrimLeft(richText[0].Text.Content, " \t")
+		if content != "" {
+			richText[0].Text = &notionapi.Text{Content: content, Link: richText[0].Text.Link}
+			break
+		}
+		richText = richText[1:]
+	}
+	for len(richText) > 0 {
+		last := &richText[len(richText)-1]
+		if last.Type != notionapi.ObjectTypeText || last.Text == nil {
+			break
+		}
+		content := strings.TrimRight(last.Text.Content, " \t")
+		if content != "" {
+			last.Text = &notionapi.Text{Content: content, Link: last.Text.Link}
+			break
+		}
+		richText = richText[:len(richText)-1]
+	}
+	return richText
+}
+
 // getBlockquoteFirstLine extracts the first line of text from a blockquote.
 // It concatenates all text segments until a line break is encountered.
 func getBlockquoteFirstLine(bq *ast.Blockquote, source []byte) string {
@@ -0,0 +1,157 @@
+package transformer
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jomei/notionapi"
+	"github.com/yuin/goldmark/ast"
+)
+
+// BookmarkMarker annotates a paragraph holding only a link so it is pushed
+// as a Notion bookmark: "[Caption](https://example.com) {bookmark}".
+const BookmarkMarker = "{bookmark}"
+
+// bookmarkMetaLanguage is the language of the fenced block pull writes
+// after a bookmark with its preview. Push leaves the block out, since
+// Notion renders its own preview.
+const bookmarkMetaLanguage = "bookmark"
+
+// bookmarkDescriptionMaxLength is how much of a bookmarked page's
+// description is written into its preview block.
+const bookmarkDescriptionMaxLength = 200
+
+// BookmarkPreview is what a bookmarked page says about itself.
+type BookmarkPreview struct {
+	Title       string
+	Description string
+}
+
+// BookmarkPreviewer fetches the preview of bookmarked pages, which the
+// Notion API does not return.
+type BookmarkPreviewer interface {
+	// BookmarkPreview returns the preview of the page at url, or false if
+	// it has none or could not be fetched.
+	BookmarkPreview(url string) (BookmarkPreview, bool)
+}
+
+// SetBookmarkPreviewer sets the previewer used to write a preview block
+// after pulled bookmarks. Without one, bookmarks are written as links.
+func (t *ReverseTransformer) SetBookmarkPreviewer(p BookmarkPreviewer) {
+	t.bookmarkPreviewer = p
+}
+
+// bookmarkLink returns the URL and link text of a paragraph holding only a
+// link annotated with BookmarkMarker.
+func bookmarkLink(n ast.Node, source []byte) (url, text string, ok bool) {
+	p, isParagraph := n.(*ast.Paragraph)
+	if !isParagraph {
+		return "", "", false
+	}
+
+	var rest strings.Builder
+	for child := p.FirstChild(); child != nil; child = child.NextSibling() {
+		switch c := child.(type) {
+		case *ast.Link:
+			if url != "" {
+				return "", "", false
+			}
+			url, text = string(c.Destination), string(c.Text(source))
+		case *ast.AutoLink:
+			if url != "" {
+				return "", "", false
+			}
+			url = string(c.URL(source))
+		case *ast.Text:
+			rest.Write(c.Segment.Value(source))
+		default:
+			return "", "", false
+		}
+	}
+	if url == "" || strings.TrimSpace(rest.String()) != BookmarkMarker {
+		return "", "", false
+	}
+	return url, text, true
+}
+
+// tryBookmarkBlock returns a bookmark block for a paragraph holding only a
+// link annotated with BookmarkMarker, or nil. Link text other than the URL
+// becomes the bookmark's caption.
+func (t *Transformer) tryBookmarkBlock(p *ast.Paragraph, source []byte) notionapi.Block {
+	url, text, ok := bookmarkLink(p, source)
+	if !ok {
+		return nil
+	}
+
+	var caption []notionapi.RichText
+	if text != "" && text != url {
+		caption = []notionapi.RichText{{
+			Type: notionapi.ObjectTypeText,
+			Text: &notionapi.Text{Content: text},
+		}}
+	}
+	return &notionapi.BookmarkBlock{
+		BasicBlock: notionapi.BasicBlock{
+			Object: notionapi.ObjectTypeBlock,
+			Type:   notionapi.BlockTypeBookmark,
+		},
+		Bookmark: notionapi.Bookmark{
+			URL:     url,
+			Caption: caption,
+		},
+	}
+}
+
+// isBookmarkPreview reports whether a fenced code block is the preview
+// block pull wrote after a bookmark.
+func isBookmarkPreview(cb *ast.FencedCodeBlock, source []byte) bool {
+	if string(cb.Language(source)) != bookmarkMetaLanguage {
+		return false
+	}
+	_, _, ok := bookmarkLink(cb.PreviousSibling(), source)
+	return ok
+}
+
+// bookmarkToMarkdown writes a bookmark as a link annotated with
+// BookmarkMarker, its caption as the link text. With a previewer, a short
+// fenced block of the page's title and description follows.
+func (t *ReverseTransformer) bookmarkToMarkdown(b *notionapi.BookmarkBlock, indent string) string {
+	url := b.Bookmark.URL
+	if url == "" {
+		return ""
+	}
+
+	link := "<" + url + ">"
+	if caption := t.richTextToMarkdown(b.Bookmark.Caption); caption != "" {
+		link = fmt.Sprintf("[%s](%s)", caption, url)
+	}
+	result := fmt.Sprintf("%s%s %s\n\n", indent, link, BookmarkMarker)
+
+	if t.bookmarkPreviewer == nil {
+		return result
+	}
+	preview, ok := t.bookmarkPreviewer.BookmarkPreview(url)
+	title, description := oneLine(preview.Title), oneLine(preview.Description)
+	if !ok || (title == "" && description == "") {
+		return result
+	}
+	if utf8.RuneCountInString(description) > bookmarkDescriptionMaxLength {
+		description = string([]rune(description)[:bookmarkDescriptionMaxLength-1]) + "…"
+	}
+
+	result += indent + "```" + bookmarkMetaLanguage + "\n"
+	if title != "" {
+		result += fmt.Sprintf("%stitle: %s\n", indent, title)
+	}
+	if description != "" {
+		result += fmt.Sprintf("%sdescription: %s\n", indent, description)
+	}
+	return result + indent + "```\n\n"
+}
+
+// oneLine collapses runs of whitespace, newlines included, to single
+// spaces.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,44 @@
+package transformer
+
+import (
+	"testing"
+
+	"github.com/jomei/notionapi"
+)
+
+// fakePreviewer returns a fixed preview for every URL.
+type fakePreviewer BookmarkPreview
+
+func (p fakePreviewer) BookmarkPreview(url string) (BookmarkPreview, bool) {
+	return BookmarkPreview(p), p != fakePreviewer{}
+}
+
+func TestBookmarkToMarkdown(t *testing.T) {
+	bookmark := &notionapi.BookmarkBlock{Bookmark: notionapi.Bookmark{
+		URL:     "https://example.com",
+		Caption: []notionapi.RichText{{PlainText: "Example"}},
+	}}
+
+	tests := []struct {
+		name      string
+		previewer BookmarkPreviewer
+		want      string
+	}{
+		{"no previewer", nil, "[Example](https://example.com) {bookmark}\n\n"},
+		{"no preview", fakePreviewer{}, "[Example](https://example.com) {bookmark}\n\n"},
+		{
+			"preview",
+			fakePreviewer{Title: "Example\nDomain", Description: "For   examples."},
+			"[Example](https://example.com) {bookmark}\n\n```bookmark\ntitle: Example Domain\ndescription: For examples.\n```\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := NewReverse(nil, nil)
+			rt.SetBookmarkPreviewer(tt.previewer)
+			if got := rt.bookmarkToMarkdown(bookmark, ""); got != tt.want {
+				t.Errorf("bookmarkToMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
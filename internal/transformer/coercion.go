@@ -0,0 +1,136 @@
+package transformer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PropertyError is a frontmatter value that does not fit the type of the
+// Notion property it is mapped to, such as "priority: high" for a number
+// property.
+type PropertyError struct {
+	// Key is the frontmatter key, and Property the Notion property.
+	Key      string
+	Property string
+
+	// Value is the frontmatter value.
+	Value any
+
+	// Type is the property's type.
+	Type PropertyType
+
+	// Suggestion says how to fix the value or the mapping.
+	Suggestion string
+}
+
+func (e *PropertyError) Error() string {
+	value := fmt.Sprintf("%v", e.Value)
+	if s, ok := e.Value.(string); ok {
+		value = strconv.Quote(s)
+	}
+	return fmt.Sprintf("%s: %s is not a valid %s for property %q (%s)", e.Key, value, e.Type, e.Property, e.Suggestion)
+}
+
+// PropertyErrors are all the property errors of a note. Transform returns
+// them together so each can be reported.
+type PropertyErrors []*PropertyError
+
+func (e PropertyErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// checkboxWords are the strings a checkbox property accepts.
+var checkboxWords = map[string]bool{"true": true, "false": true, "yes": true, "no": true, "1": true, "0": true}
+
+// checkValue returns an error if a frontmatter value cannot be stored in a
+// property of type propType. Empty values always fit.
+func (m *PropertyMapper) checkValue(mapping PropertyMapping, value any, propType PropertyType) *PropertyError {
+	if value == nil {
+		return nil
+	}
+	key := mapping.ObsidianKey
+	fail := func(suggestion string) *PropertyError {
+		return &PropertyError{Key: key, Property: mapping.NotionName, Value: value, Type: propType, Suggestion: suggestion}
+	}
+
+	switch propType {
+	case PropertyTypeNumber:
+		switch v := value.(type) {
+		case int, int64, float64:
+			return nil
+		case string:
+			if _, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil || strings.TrimSpace(v) == "" {
+				return nil
+			}
+		}
+		return fail(fmt.Sprintf("write a number, e.g. %s: 3, or map %s to a select property", key, key))
+
+	case PropertyTypeDate:
+		switch v := value.(type) {
+		case time.Time:
+			return nil
+		case string:
+			if _, ok := m.dates.parse(v); ok || strings.TrimSpace(v) == "" {
+				return nil
+			}
+			if _, err := parseDate(v); err == nil {
+				return nil
+			}
+		}
+		return fail(fmt.Sprintf("write a date, e.g. %s: %s", key, m.dates.example()))
+
+	case PropertyTypeCheckbox:
+		switch v := value.(type) {
+		case bool, int:
+			return nil
+		case string:
+			if v == "" || checkboxWords[strings.ToLower(v)] {
+				return nil
+			}
+		}
+		return fail(fmt.Sprintf("write true or false, e.g. %s: true", key))
+
+	case PropertyTypeSelect, PropertyTypeStatus:
+		switch value.(type) {
+		case []any, []string:
+			return fail(fmt.Sprintf("keep one value, or map %s to a multi_select property", key))
+		}
+		if strings.Contains(toString(value), ",") {
+			return fail("remove the commas, which Notion does not allow in options")
+		}
+
+	case PropertyTypeMultiSelect:
+		var items []string
+		switch v := value.(type) {
+		case []string:
+			items = v
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					items = append(items, s)
+				}
+			}
+		}
+		for _, item := range items {
+			if strings.Contains(item, ",") {
+				return fail("remove the commas, which Notion does not allow in options")
+			}
+		}
+	}
+	return nil
+}
+
+// example returns a date written the way frontmatter dates are read.
+func (f dateFormat) example() string {
+	layout := f.date
+	if layout == "" {
+		layout = "2006-01-02"
+	}
+	return time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC).Format(layout)
+}
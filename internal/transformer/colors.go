@@ -0,0 +1,124 @@
+package transformer
+
+import (
+	"strings"
+
+	"github.com/jomei/notionapi"
+)
+
+// Text color modes for Config.TextColors.
+const (
+	// TextColorsHTML writes colored text as <span> tags with inline styles.
+	TextColorsHTML = "html"
+	// TextColorsHighlight writes every background color as an Obsidian
+	// ==highlight== and drops text colors.
+	TextColorsHighlight = "highlight"
+	// TextColorsStrip drops all colors except the yellow background used
+	// for highlights.
+	TextColorsStrip = "strip"
+)
+
+// textColorNames are the Notion colors, which are also CSS color names.
+var textColorNames = map[string]bool{
+	"gray":   true,
+	"brown":  true,
+	"orange": true,
+	"yellow": true,
+	"green":  true,
+	"blue":   true,
+	"purple": true,
+	"pink":   true,
+	"red":    true,
+}
+
+// colorSpan wraps text in a <span> styled with a Notion color. Text with
+// the default color is returned unchanged.
+func colorSpan(text string, color notionapi.Color) string {
+	name, background := strings.CutSuffix(string(color), "_background")
+	if !textColorNames[name] {
+		return text
+	}
+	property := "color"
+	if background {
+		property = "background-color"
+	}
+	return `<span style="` + property + ": " + name + `">` + text + "</span>"
+}
+
+// htmlTagColor returns the Notion color set by a <span style="..."> or
+// <font color="..."> tag. A background color wins over a text color, since
+// Notion keeps only one.
+func htmlTagColor(tag htmlTag) (notionapi.Color, bool) {
+	if tag.name == "font" {
+		return cssColor(tag.attrs["color"], false)
+	}
+
+	var color notionapi.Color
+	var found bool
+	for _, decl := range strings.Split(tag.attrs["style"], ";") {
+		property, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(property)) {
+		case "color":
+			if c, ok := cssColor(value, false); ok && !found {
+				color, found = c, true
+			}
+		case "background", "background-color":
+			if c, ok := cssColor(value, true); ok {
+				return c, true
+			}
+		}
+	}
+	return color, found
+}
+
+// cssColor converts a CSS color name to the matching Notion color.
+func cssColor(value string, background bool) (notionapi.Color, bool) {
+	name := strings.ToLower(strings.TrimSpace(value))
+	if name == "grey" {
+		name = "gray"
+	}
+	if !textColorNames[name] {
+		return "", false
+	}
+	if background {
+		name += "_background"
+	}
+	return notionapi.Color(name), true
+}
+
+// placeholderColor reports whether a run has the color push gives the
+// placeholders it writes: red for unresolved [[links]], and a gray, blue,
+// or purple background for image, embed, and dataview placeholders. Such
+// colors are not the author's, so they are not pulled.
+func placeholderColor(rt notionapi.RichText) bool {
+	text := rt.PlainText
+	switch rt.Annotations.Color {
+	case notionapi.ColorRed:
+		return strings.HasPrefix(text, "[[") && strings.HasSuffix(text, "]]")
+	case notionapi.ColorGrayBackground, notionapi.ColorBlueBackground:
+		return strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]")
+	case notionapi.ColorPurpleBackground:
+		return rt.Annotations.Code && strings.HasPrefix(text, "[dv: ")
+	}
+	return false
+}
+
+// colorText renders a Notion text color on pull, per Config.TextColors.
+// The yellow background is always an Obsidian highlight and is handled by
+// the caller.
+func (t *ReverseTransformer) colorText(text string, color notionapi.Color) string {
+	switch t.config.TextColors {
+	case TextColorsStrip:
+		return text
+	case TextColorsHighlight:
+		if strings.HasSuffix(string(color), "_background") && color != notionapi.ColorDefaultBackground {
+			return "==" + text + "=="
+		}
+		return text
+	default:
+		return colorSpan(text, color)
+	}
+}
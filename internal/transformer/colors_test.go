@@ -0,0 +1,93 @@
+package transformer
+
+import (
+	"testing"
+
+	"github.com/jomei/notionapi"
+)
+
+func TestRichTextToMarkdown_TextColors(t *testing.T) {
+	text := func(content string, color notionapi.Color) notionapi.RichText {
+		return notionapi.RichText{
+			Type:        notionapi.ObjectTypeText,
+			PlainText:   content,
+			Annotations: &notionapi.Annotations{Color: color},
+		}
+	}
+	richText := []notionapi.RichText{
+		text("red", notionapi.ColorRed),
+		text(" ", notionapi.ColorDefault),
+		text("blue bg", notionapi.ColorBlueBackground),
+		text(" ", notionapi.ColorDefault),
+		text("marked", notionapi.ColorYellowBackground),
+	}
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"", `<span style="color: red">red</span> <span style="background-color: blue">blue bg</span> ==marked==`},
+		{TextColorsHighlight, "red ==blue bg== ==marked=="},
+		{TextColorsStrip, "red blue bg ==marked=="},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.TextColors = tt.mode
+		if got := NewReverse(nil, cfg).richTextToMarkdown(richText); got != tt.want {
+			t.Errorf("mode %q: got %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestRichTextToMarkdown_PlaceholderColors(t *testing.T) {
+	text := func(content string, color notionapi.Color, code bool) notionapi.RichText {
+		return notionapi.RichText{
+			Type:        notionapi.ObjectTypeText,
+			PlainText:   content,
+			Annotations: &notionapi.Annotations{Color: color, Code: code},
+		}
+	}
+	richText := []notionapi.RichText{
+		text("[[Missing]]", notionapi.ColorRed, false),
+		text(" ", notionapi.ColorDefault, false),
+		text("[🖼️ a.png]", notionapi.ColorGrayBackground, false),
+		text(" ", notionapi.ColorDefault, false),
+		text("[📄 b.pdf]", notionapi.ColorBlueBackground, false),
+		text(" ", notionapi.ColorDefault, false),
+		text("[dv: this.x]", notionapi.ColorPurpleBackground, true),
+		text(" ", notionapi.ColorDefault, false),
+		text("[[red by hand", notionapi.ColorRed, false),
+	}
+	want := "[[Missing]] [🖼️ a.png] [📄 b.pdf] `[dv: this.x]` " + `<span style="color: red">[[red by hand</span>`
+	if got := NewReverse(nil, DefaultConfig()).richTextToMarkdown(richText); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTransform_ColoredSpans(t *testing.T) {
+	markdown := `Some <span style="color: red">red</span>, <font color="grey">gray</font>, ` +
+		`<span style="color: blue; background-color: green">green bg</span> and <span class="x">plain</span> text.` + "\n"
+	page := transformHTMLNote(t, markdown, "")
+
+	para, ok := page.Children[0].(*notionapi.ParagraphBlock)
+	if !ok {
+		t.Fatalf("expected paragraph, got %T", page.Children[0])
+	}
+	colors := map[string]notionapi.Color{}
+	for _, rt := range para.Paragraph.RichText {
+		colors[rt.Text.Content] = rt.Annotations.Color
+	}
+	want := map[string]notionapi.Color{
+		"red":      notionapi.ColorRed,
+		"gray":     notionapi.ColorGray,
+		"green bg": notionapi.ColorGreenBackground,
+	}
+	for content, color := range want {
+		if colors[content] != color {
+			t.Errorf("%q color = %q, want %q", content, colors[content], color)
+		}
+	}
+	if got := richTextContent(para.Paragraph.RichText); got != `Some red, gray, green bg and <span class="x">plain</span> text.` {
+		t.Errorf("content = %q; want uncolored spans kept as text", got)
+	}
+}
@@ -0,0 +1,151 @@
+package transformer
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+)
+
+// Options for Config.DateMentions.
+const (
+	// DateMentionsText writes date mentions as Notion shows them, e.g.
+	// "March 3, 2024".
+	DateMentionsText = "text"
+	// DateMentionsMarker writes {{date:2024-03-03}} markers, which are
+	// pushed back as date mentions.
+	DateMentionsMarker = "marker"
+	// DateMentionsISO writes ISO dates as plain text.
+	DateMentionsISO = "iso"
+)
+
+// isoDatePattern matches an ISO date, optionally with a time and offset,
+// as date markers write them.
+const isoDatePattern = `\d{4}-\d{2}-\d{2}(?:T\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:\d{2}))?`
+
+// dateMarkerRegex matches a date marker, {{date:START}} or
+// {{date:START/END}} for a range.
+var dateMarkerRegex = regexp.MustCompile(`\{\{date:(` + isoDatePattern + `)(?:/(` + isoDatePattern + `))?\}\}`)
+
+// mentionDate writes the start or end of a date mention in ISO form: a
+// date alone for midnight UTC, which Notion returns for dates without a
+// time, and RFC 3339 otherwise.
+func mentionDate(d *notionapi.Date) string {
+	t := time.Time(*d)
+	if t.Location() == time.UTC && t.Equal(t.Truncate(24*time.Hour)) {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
+
+// dateMentionToMarkdown writes a pulled date mention per
+// Config.DateMentions. Reminders set on a date are not returned by the
+// API and are lost.
+func (t *ReverseTransformer) dateMentionToMarkdown(date *notionapi.DateObject, plainText string) string {
+	if date.Start == nil {
+		return plainText
+	}
+	iso := mentionDate(date.Start)
+	if date.End != nil {
+		iso += "/" + mentionDate(date.End)
+	}
+
+	switch t.config.DateMentions {
+	case DateMentionsMarker:
+		return "{{date:" + iso + "}}"
+	case DateMentionsISO:
+		return iso
+	}
+	return plainText
+}
+
+// expandDateMarkers converts {{date:...}} markers in plain text runs to
+// date mentions. Code and links are left alone.
+func expandDateMarkers(richText []notionapi.RichText) []notionapi.RichText {
+	if !hasDateMarker(richText) {
+		return richText
+	}
+	var result []notionapi.RichText
+	for _, rt := range mergePlainRuns(richText) {
+		if !isPlainRun(rt) {
+			result = append(result, rt)
+			continue
+		}
+		content := rt.Text.Content
+		start := 0
+		for _, m := range dateMarkerRegex.FindAllStringSubmatchIndex(content, -1) {
+			var end string
+			if m[4] >= 0 {
+				end = content[m[4]:m[5]]
+			}
+			date, ok := markerDate(content[m[2]:m[3]], end)
+			if !ok {
+				continue
+			}
+			if m[0] > start {
+				result = append(result, plainRun(content[start:m[0]], rt.Annotations))
+			}
+			result = append(result, notionapi.RichText{
+				Type:        "mention",
+				Mention:     &notionapi.Mention{Type: "date", Date: date},
+				Annotations: copyAnnotations(rt.Annotations),
+				PlainText:   content[m[0]:m[1]],
+			})
+			start = m[1]
+		}
+		if start < len(content) {
+			result = append(result, plainRun(content[start:], rt.Annotations))
+		}
+	}
+	return result
+}
+
+// hasDateMarker reports whether the text runs might hold a date marker.
+// Markers may be split across runs, so the runs' text is checked as a
+// whole.
+func hasDateMarker(richText []notionapi.RichText) bool {
+	var text strings.Builder
+	for _, rt := range richText {
+		if rt.Text != nil {
+			text.WriteString(rt.Text.Content)
+		}
+	}
+	return strings.Contains(text.String(), "{{date:")
+}
+
+// markerDate parses the start and end, if any, of a date marker.
+func markerDate(start, end string) (*notionapi.DateObject, bool) {
+	s, ok := parseISODate(start)
+	if !ok {
+		return nil, false
+	}
+	date := &notionapi.DateObject{Start: &s}
+	if end != "" {
+		e, ok := parseISODate(end)
+		if !ok {
+			return nil, false
+		}
+		date.End = &e
+	}
+	return date, true
+}
+
+// parseISODate parses a date as written by mentionDate.
+func parseISODate(s string) (notionapi.Date, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return notionapi.Date(t), true
+		}
+	}
+	return notionapi.Date{}, false
+}
+
+// plainRun returns a text run with a copy of annotations.
+func plainRun(content string, annotations *notionapi.Annotations) notionapi.RichText {
+	return notionapi.RichText{
+		Type:        notionapi.ObjectTypeText,
+		Text:        &notionapi.Text{Content: content},
+		Annotations: copyAnnotations(annotations),
+	}
+}
@@ -0,0 +1,102 @@
+package transformer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+func TestReverse_DateMentions(t *testing.T) {
+	date := func(start, end time.Time, plain string) notionapi.RichText {
+		d := &notionapi.DateObject{Start: (*notionapi.Date)(&start)}
+		if !end.IsZero() {
+			d.End = (*notionapi.Date)(&end)
+		}
+		return notionapi.RichText{
+			Type:      "mention",
+			Mention:   &notionapi.Mention{Type: "date", Date: d},
+			PlainText: plain,
+		}
+	}
+	day := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+	later := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	meeting := time.Date(2024, 3, 3, 9, 30, 0, 0, time.FixedZone("", 2*60*60))
+
+	tests := []struct {
+		name string
+		mode string
+		rt   notionapi.RichText
+		want string
+	}{
+		{"text", "", date(day, time.Time{}, "March 3, 2024"), "March 3, 2024"},
+		{"marker", DateMentionsMarker, date(day, time.Time{}, "March 3, 2024"), "{{date:2024-03-03}}"},
+		{"marker range", DateMentionsMarker, date(day, later, "March 3, 2024 → March 5, 2024"), "{{date:2024-03-03/2024-03-05}}"},
+		{"marker time", DateMentionsMarker, date(meeting, time.Time{}, "March 3, 2024 9:30 AM"), "{{date:2024-03-03T09:30:00+02:00}}"},
+		{"iso", DateMentionsISO, date(day, time.Time{}, "March 3, 2024"), "2024-03-03"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.DateMentions = tt.mode
+			rt := NewReverse(nil, cfg)
+			if got := rt.richTextToMarkdown([]notionapi.RichText{tt.rt}); got != tt.want {
+				t.Errorf("richTextToMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransform_DateMarkers(t *testing.T) {
+	note, err := parser.New().Parse("note.md", []byte("Due {{date:2024-03-03}}, meet {{date:2024-03-03T09:30:00+02:00/2024-03-03T10:00:00+02:00}}, not {{date:YYYY}} or `{{date:2024-03-03}}`.\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	page, err := New(nil, DefaultConfig()).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	var dates []string
+	var text string
+	for _, rt := range page.Children[0].(*notionapi.ParagraphBlock).Paragraph.RichText {
+		if rt.Mention != nil {
+			d := rt.Mention.Date
+			iso := mentionDate(d.Start)
+			if d.End != nil {
+				iso += "/" + mentionDate(d.End)
+			}
+			dates = append(dates, iso)
+			continue
+		}
+		text += rt.Text.Content
+	}
+	if len(dates) != 2 || dates[0] != "2024-03-03" || dates[1] != "2024-03-03T09:30:00+02:00/2024-03-03T10:00:00+02:00" {
+		t.Errorf("mentioned dates = %v", dates)
+	}
+	if want := "Due , meet , not {{date:YYYY}} or {{date:2024-03-03}}."; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+}
+
+func TestExpandDateMarkers_SplitRuns(t *testing.T) {
+	richText := []notionapi.RichText{
+		plainRun("Due {", nil),
+		plainRun("{date:2024-", nil),
+		plainRun("03-03}} now", nil),
+	}
+	got := expandDateMarkers(richText)
+	if len(got) != 3 || got[1].Mention == nil || mentionDate(got[1].Mention.Date.Start) != "2024-03-03" {
+		t.Fatalf("expandDateMarkers() = %+v, want the split marker as a date mention", got)
+	}
+	if got[0].Text.Content != "Due " || got[2].Text.Content != " now" {
+		t.Errorf("text around the mention = %q, %q", got[0].Text.Content, got[2].Text.Content)
+	}
+
+	plain := []notionapi.RichText{plainRun("{", nil), plainRun("{x}}", nil)}
+	if got := expandDateMarkers(plain); len(got) != 2 {
+		t.Errorf("expandDateMarkers() without a marker merged runs: %+v", got)
+	}
+}
@@ -0,0 +1,193 @@
+package transformer
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+)
+
+// Frontmatter keys holding a note's creation and modification dates.
+const (
+	CreatedKey = "created"
+	UpdatedKey = "updated"
+)
+
+// noteDateLayout is how pulled dates are written to frontmatter: local
+// time without a zone, the format of Obsidian's date & time properties.
+const noteDateLayout = "2006-01-02T15:04:05"
+
+// noteDateLayouts are the frontmatter date formats read on push, most
+// precise first. Dates without a zone are local time.
+var noteDateLayouts = []string{
+	time.RFC3339,
+	noteDateLayout,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// dateFormat reads and writes frontmatter dates in the layouts of
+// Config.DateLayout and Config.DateTimeLayout.
+type dateFormat struct {
+	date     string
+	dateTime string
+	loc      *time.Location
+}
+
+// newDateFormat returns the date format of a transformer config.
+func newDateFormat(cfg *Config) dateFormat {
+	return dateFormat{date: cfg.DateLayout, dateTime: cfg.DateTimeLayout, loc: cfg.DateLocation}
+}
+
+// configured reports whether any date setting differs from the defaults.
+func (f dateFormat) configured() bool {
+	return f.date != "" || f.dateTime != "" || f.loc != nil
+}
+
+// location returns the zone of times written without one.
+func (f dateFormat) location() *time.Location {
+	if f.loc == nil {
+		return time.Local
+	}
+	return f.loc
+}
+
+// parse parses s with the configured layouts. Dates without a time are
+// midnight UTC, the form Notion returns them in; times are in the
+// configured zone unless s has its own.
+func (f dateFormat) parse(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if f.dateTime != "" {
+		if t, err := time.ParseInLocation(f.dateTime, s, f.location()); err == nil {
+			return t, true
+		}
+	}
+	if f.date != "" {
+		if t, err := time.Parse(f.date, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatProperty writes the start of a pulled Notion date property. Without
+// date settings it is kept in RFC 3339. Otherwise dates at midnight UTC,
+// which Notion returns for dates without a time, use the date layout, and
+// other times are shown in the configured zone with the datetime layout.
+func (f dateFormat) formatProperty(d *notionapi.Date) string {
+	if !f.configured() {
+		return d.String()
+	}
+	t := time.Time(*d)
+	if t.Location() == time.UTC && t.Equal(t.Truncate(24*time.Hour)) {
+		layout := f.date
+		if layout == "" {
+			layout = "2006-01-02"
+		}
+		return t.Format(layout)
+	}
+	return f.formatTime(t)
+}
+
+// formatTime writes a time to frontmatter in the configured zone and
+// datetime layout.
+func (f dateFormat) formatTime(t time.Time) string {
+	layout := f.dateTime
+	if layout == "" {
+		layout = noteDateLayout
+	}
+	return t.In(f.location()).Format(layout)
+}
+
+// setNoteDates sets the configured created and modified date properties
+// from the frontmatter, falling back to the file's timestamps.
+func (t *Transformer) setNoteDates(props notionapi.Properties, frontmatter map[string]any) {
+	set := func(name string, value any, fallback time.Time) {
+		if name == "" {
+			return
+		}
+		date := noteDate(value, newDateFormat(t.config))
+		if date.IsZero() {
+			date = fallback
+		}
+		if date.IsZero() {
+			return
+		}
+		start := notionapi.Date(date)
+		props[name] = notionapi.DateProperty{
+			Date: &notionapi.DateObject{Start: &start},
+		}
+	}
+
+	set(t.config.CreatedProperty, frontmatter[CreatedKey], t.config.FileCreated)
+	set(t.config.ModifiedProperty, frontmatter[UpdatedKey], t.config.FileModified)
+}
+
+// noteDate parses a frontmatter date, returning the zero time if the value
+// is missing or not a date. The configured layouts are tried first, all in
+// the configured zone.
+func noteDate(value any, f dateFormat) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case string:
+		s := strings.TrimSpace(v)
+		layouts := noteDateLayouts
+		if f.date != "" || f.dateTime != "" {
+			layouts = append([]string{f.dateTime, f.date}, noteDateLayouts...)
+		}
+		for _, layout := range layouts {
+			if layout == "" {
+				continue
+			}
+			if date, err := time.ParseInLocation(layout, s, f.location()); err == nil {
+				return date
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// setPulledDates writes a page's creation and last edit times into the
+// frontmatter. The created property wins over the page's own creation
+// time, which for a migrated note is when it was first pushed.
+func (t *ReverseTransformer) setPulledDates(frontmatter map[string]any, page *NotionPage) {
+	if name := t.config.CreatedProperty; name != "" {
+		created := propertyDate(page.Properties[name])
+		if created.IsZero() {
+			created = page.CreatedTime
+		}
+		delete(frontmatter, strings.ToLower(name))
+		if !created.IsZero() {
+			frontmatter[CreatedKey] = newDateFormat(t.config).formatTime(created)
+		}
+	}
+
+	if name := t.config.ModifiedProperty; name != "" {
+		modified := page.LastEditedTime
+		if modified.IsZero() {
+			modified = propertyDate(page.Properties[name])
+		}
+		delete(frontmatter, strings.ToLower(name))
+		if !modified.IsZero() {
+			frontmatter[UpdatedKey] = newDateFormat(t.config).formatTime(modified)
+		}
+	}
+}
+
+// propertyDate returns the start of a date property, or the zero time.
+func propertyDate(prop notionapi.Property) time.Time {
+	var date *notionapi.DateObject
+	switch p := prop.(type) {
+	case *notionapi.DateProperty:
+		date = p.Date
+	case notionapi.DateProperty:
+		date = p.Date
+	}
+	if date == nil || date.Start == nil {
+		return time.Time{}
+	}
+	return time.Time(*date.Start)
+}
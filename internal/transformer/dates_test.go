@@ -0,0 +1,161 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+func TestNoteDate(t *testing.T) {
+	local := func(s string) time.Time {
+		d, _ := time.ParseInLocation(noteDateLayout, s, time.Local)
+		return d
+	}
+	tests := []struct {
+		value any
+		want  time.Time
+	}{
+		{"2024-03-01T09:30:00", local("2024-03-01T09:30:00")},
+		{"2024-03-01 09:30", local("2024-03-01T09:30:00")},
+		{"2024-03-01", local("2024-03-01T00:00:00")},
+		{"2024-03-01T09:30:00Z", time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)},
+		{time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"last week", time.Time{}},
+		{nil, time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := noteDate(tt.value, dateFormat{}); !got.Equal(tt.want) {
+			t.Errorf("noteDate(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTransform_NoteDates(t *testing.T) {
+	content := "---\ncreated: 2020-05-04T08:00:00Z\n---\n\nBody\n"
+	note, err := parser.New().Parse("test.md", []byte(content))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	fileModified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cfg := DefaultConfig()
+	cfg.CreatedProperty = "Created"
+	cfg.ModifiedProperty = "Last Edited"
+	cfg.FileCreated = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg.FileModified = fileModified
+
+	page, err := New(nil, cfg).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	// The frontmatter date wins over the file's creation time.
+	if got := propertyDate(page.Properties["Created"]); !got.Equal(time.Date(2020, 5, 4, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Created = %v", got)
+	}
+	if got := propertyDate(page.Properties["Last Edited"]); !got.Equal(fileModified) {
+		t.Errorf("Last Edited = %v, want the file time", got)
+	}
+
+	// Without configured properties nothing is added.
+	page, err = New(nil, DefaultConfig()).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	if _, ok := page.Properties["Created"]; ok {
+		t.Error("Created set without dates configured")
+	}
+}
+
+func TestNotionToMarkdown_PulledDates(t *testing.T) {
+	original := notionapi.Date(time.Date(2020, 5, 4, 8, 0, 0, 0, time.UTC))
+	edited := time.Date(2024, 6, 7, 10, 11, 12, 0, time.UTC)
+	page := &NotionPage{
+		Properties: notionapi.Properties{
+			"Created":     &notionapi.DateProperty{Date: &notionapi.DateObject{Start: &original}},
+			"Last Edited": &notionapi.DateProperty{Date: &notionapi.DateObject{Start: &original}},
+		},
+		CreatedTime:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		LastEditedTime: edited,
+	}
+
+	cfg := DefaultConfig()
+	cfg.CreatedProperty = "Created"
+	cfg.ModifiedProperty = "Last Edited"
+	md, err := NewReverse(nil, cfg).NotionToMarkdown(page)
+	if err != nil {
+		t.Fatalf("NotionToMarkdown() error: %v", err)
+	}
+
+	out := string(md)
+	wantCreated := "created: " + time.Time(original).Local().Format(noteDateLayout)
+	wantUpdated := "updated: " + edited.Local().Format(noteDateLayout)
+	if !strings.Contains(out, wantCreated) || !strings.Contains(out, wantUpdated) {
+		t.Errorf("expected %q and %q, got:\n%s", wantCreated, wantUpdated, out)
+	}
+	if strings.Contains(out, "last edited:") {
+		t.Errorf("date property duplicated under its own name:\n%s", out)
+	}
+
+	// Without a created property value, Notion's creation time is used.
+	delete(page.Properties, "Created")
+	md, _ = NewReverse(nil, cfg).NotionToMarkdown(page)
+	if want := "created: " + page.CreatedTime.Local().Format(noteDateLayout); !strings.Contains(string(md), want) {
+		t.Errorf("expected %q, got:\n%s", want, md)
+	}
+}
+
+func TestDateFormat(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	f := dateFormat{date: "02.01.2006", dateTime: "02.01.2006 15:04", loc: berlin}
+
+	date, ok := f.parse("15.01.2024")
+	if !ok || !date.Equal(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("parse(date) = %v, %v; want midnight UTC", date, ok)
+	}
+	dateTime, ok := f.parse("15.01.2024 09:30")
+	if !ok || !dateTime.Equal(time.Date(2024, 1, 15, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("parse(datetime) = %v, %v; want 08:30 UTC", dateTime, ok)
+	}
+	if _, ok := f.parse("2024-01-15"); ok {
+		t.Error("parse() accepted a date outside the configured layouts")
+	}
+
+	start := notionapi.Date(date)
+	if got := f.formatProperty(&start); got != "15.01.2024" {
+		t.Errorf("formatProperty(date) = %q, want 15.01.2024", got)
+	}
+	start = notionapi.Date(dateTime.UTC())
+	if got := f.formatProperty(&start); got != "15.01.2024 09:30" {
+		t.Errorf("formatProperty(datetime) = %q, want 15.01.2024 09:30", got)
+	}
+	if got := (dateFormat{}).formatProperty(&start); got != "2024-01-15T08:30:00Z" {
+		t.Errorf("formatProperty() without settings = %q, want RFC 3339", got)
+	}
+
+	if got := noteDate("15.01.2024 09:30", f); !got.Equal(dateTime) {
+		t.Errorf("noteDate() = %v, want %v", got, dateTime)
+	}
+}
+
+func TestPropertyMapper_DateFormat(t *testing.T) {
+	mapper := newConfigPropertyMapper(&Config{
+		PropertyMappings: []PropertyMapping{{ObsidianKey: "due", NotionName: "Due Date", NotionType: PropertyTypeDate}},
+		DateLayout:       "02.01.2006",
+	})
+
+	props := mapper.ToNotionProperties(map[string]any{"due": "15.01.2024"}, nil)
+	if got := propertyDate(props["Due Date"]); !got.Equal(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Due Date = %v, want 2024-01-15", got)
+	}
+	if got := mapper.ToFrontmatter(props)["due"]; got != "15.01.2024" {
+		t.Errorf("pulled due = %v, want 15.01.2024", got)
+	}
+}
@@ -0,0 +1,121 @@
+package transformer
+
+import (
+	"strings"
+
+	"github.com/jomei/notionapi"
+	"github.com/yuin/goldmark/ast"
+)
+
+// defaultDividerMarker is the markdown dividers are pulled as unless
+// Config.DividerMarker or a recorded marker says otherwise.
+const defaultDividerMarker = "---"
+
+// dividerMarker returns the markdown a top-level thematic break was
+// written as, such as "***" or "- - -", or "" if it cannot be found.
+// Goldmark keeps no source position for thematic breaks, so the marker is
+// looked for between the blocks around it: the first rule line after the
+// nearest earlier block with a position, skipping one for each thematic
+// break in between.
+func dividerMarker(n ast.Node, source []byte) string {
+	if _, topLevel := n.Parent().(*ast.Document); !topLevel {
+		return ""
+	}
+
+	from, skip := 0, 0
+	for p := n.PreviousSibling(); p != nil; p = p.PreviousSibling() {
+		if _, ok := p.(*ast.ThematicBreak); ok {
+			skip++
+			continue
+		}
+		if _, end := sourceRange(p); end >= 0 {
+			from = end
+			// The underline of a setext heading follows its text.
+			if h, ok := p.(*ast.Heading); ok && !isATXHeading(h, source) {
+				from = skipLine(source, skipLine(source, from))
+			}
+			break
+		}
+	}
+	to := len(source)
+	for next := n.NextSibling(); next != nil; next = next.NextSibling() {
+		if start, _ := sourceRange(next); start >= 0 {
+			to = start
+			break
+		}
+	}
+	if from > to {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(source[from:to]), "\n") {
+		if !isRuleLine(line) {
+			continue
+		}
+		if skip == 0 {
+			return strings.TrimSpace(line)
+		}
+		skip--
+	}
+	return ""
+}
+
+// isATXHeading reports whether a heading is written with leading #s
+// rather than underlined.
+func isATXHeading(h *ast.Heading, source []byte) bool {
+	start, _ := sourceRange(h)
+	if start < 0 {
+		return true
+	}
+	lineStart := strings.LastIndexByte(string(source[:start]), '\n') + 1
+	return strings.HasPrefix(strings.TrimSpace(string(source[lineStart:start])), "#")
+}
+
+// skipLine returns the offset of the line after the one at offset i.
+func skipLine(source []byte, i int) int {
+	if nl := strings.IndexByte(string(source[i:]), '\n'); nl >= 0 {
+		return i + nl + 1
+	}
+	return len(source)
+}
+
+// isRuleLine reports whether a line is a thematic break: three or more
+// of the same -, *, or _ character, optionally separated by spaces.
+func isRuleLine(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" || !strings.ContainsRune("-*_", rune(line[0])) {
+		return false
+	}
+	count := 0
+	for _, r := range line {
+		switch {
+		case r == rune(line[0]):
+			count++
+		case r != ' ' && r != '\t':
+			return false
+		}
+	}
+	return count >= 3
+}
+
+// pulledDivider returns the markdown a divider block is pulled as: the
+// marker recorded for it, or the configured one.
+func (t *ReverseTransformer) pulledDivider(block notionapi.Block) string {
+	if marker := t.dividers[block]; marker != "" {
+		return marker
+	}
+	if t.config.DividerMarker != "" {
+		return t.config.DividerMarker
+	}
+	return defaultDividerMarker
+}
+
+// guardLeadingRule rewrites a rule of dashes on the first line of a note
+// without frontmatter as ***, since a first line of --- opens frontmatter.
+func guardLeadingRule(body string) string {
+	first, _, _ := strings.Cut(body, "\n")
+	if !isRuleLine(first) || strings.Trim(strings.TrimSpace(first), "-") != "" {
+		return body
+	}
+	return "***" + body[len(first):]
+}
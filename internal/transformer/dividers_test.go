@@ -0,0 +1,74 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+func TestTransform_DividerMarkers(t *testing.T) {
+	content := "Intro\n\n***\n\nSetext\n------\n\n___\n- - -\n\nEnd\n\n---\n"
+	note, err := parser.New().Parse("test.md", []byte(content))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	page, err := New(nil, nil).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	var got []string
+	for _, block := range page.Children {
+		if _, ok := block.(*notionapi.DividerBlock); ok {
+			got = append(got, page.Dividers[block])
+		}
+	}
+	want := []string{"***", "___", "- - -", "---"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("divider markers = %q, want %q", got, want)
+	}
+
+	// Pull writes each divider as it was written.
+	md, err := NewReverse(nil, nil).NotionToMarkdown(page)
+	if err != nil {
+		t.Fatalf("NotionToMarkdown() error: %v", err)
+	}
+	for _, marker := range want {
+		if !strings.Contains(string(md), "\n"+marker+"\n") {
+			t.Errorf("marker %q not restored:\n%s", marker, md)
+		}
+	}
+}
+
+func TestNotionToMarkdown_DividerDefaults(t *testing.T) {
+	divider := func() notionapi.Block {
+		return &notionapi.DividerBlock{BasicBlock: notionapi.BasicBlock{Type: notionapi.BlockTypeDivider}}
+	}
+	paragraph := &notionapi.ParagraphBlock{
+		BasicBlock: notionapi.BasicBlock{Type: notionapi.BlockTypeParagraph},
+		Paragraph:  notionapi.Paragraph{RichText: []notionapi.RichText{{Type: notionapi.ObjectTypeText, Text: &notionapi.Text{Content: "Text"}, PlainText: "Text"}}},
+	}
+
+	// A leading divider would open frontmatter as ---.
+	page := &NotionPage{Children: []notionapi.Block{divider(), paragraph, divider()}}
+	md, err := NewReverse(nil, nil).NotionToMarkdown(page)
+	if err != nil {
+		t.Fatalf("NotionToMarkdown() error: %v", err)
+	}
+	if want := "***\n\nText\n\n---\n\n"; string(md) != want {
+		t.Errorf("NotionToMarkdown() = %q, want %q", md, want)
+	}
+
+	cfg := DefaultConfig()
+	cfg.DividerMarker = "___"
+	md, err = NewReverse(nil, cfg).NotionToMarkdown(page)
+	if err != nil {
+		t.Fatalf("NotionToMarkdown() error: %v", err)
+	}
+	if want := "___\n\nText\n\n___\n\n"; string(md) != want {
+		t.Errorf("NotionToMarkdown() with ___ = %q, want %q", md, want)
+	}
+}
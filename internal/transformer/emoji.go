@@ -0,0 +1,87 @@
+package transformer
+
+import (
+	_ "embed"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed emoji.txt
+var emojiTable string
+
+var (
+	emojiOnce     sync.Once
+	emojiByCode   map[string]string
+	shortcodeRepl *strings.Replacer
+	emojiRepl     *strings.Replacer
+)
+
+// loadEmoji parses the embedded emoji table and builds the replacers used
+// in both directions.
+func loadEmoji() {
+	emojiByCode = make(map[string]string)
+	codeByEmoji := make(map[string]string)
+
+	for _, line := range strings.Split(emojiTable, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		code, emoji, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		emojiByCode[code] = emoji
+		if _, exists := codeByEmoji[emoji]; !exists {
+			codeByEmoji[emoji] = code
+		}
+	}
+
+	pairs := make([]string, 0, 2*len(emojiByCode))
+	for code, emoji := range emojiByCode {
+		pairs = append(pairs, ":"+code+":", emoji)
+	}
+	shortcodeRepl = strings.NewReplacer(pairs...)
+
+	// The replacer tries candidates in argument order, so longer sequences
+	// (flags, keycaps, variation selectors) must come before their prefixes.
+	emojis := make([]string, 0, len(codeByEmoji))
+	for emoji := range codeByEmoji {
+		emojis = append(emojis, emoji)
+	}
+	sort.Slice(emojis, func(i, j int) bool {
+		if len(emojis[i]) != len(emojis[j]) {
+			return len(emojis[i]) > len(emojis[j])
+		}
+		return emojis[i] < emojis[j]
+	})
+	pairs = make([]string, 0, 2*len(emojis))
+	for _, emoji := range emojis {
+		pairs = append(pairs, emoji, ":"+codeByEmoji[emoji]+":")
+	}
+	emojiRepl = strings.NewReplacer(pairs...)
+}
+
+// LookupEmoji returns the emoji for a shortcode name (without colons).
+func LookupEmoji(shortcode string) (string, bool) {
+	emojiOnce.Do(loadEmoji)
+	emoji, ok := emojiByCode[shortcode]
+	return emoji, ok
+}
+
+// ExpandShortcodes replaces known :shortcode: sequences with their emoji.
+// Unknown shortcodes are left as written.
+func ExpandShortcodes(s string) string {
+	if !strings.Contains(s, ":") {
+		return s
+	}
+	emojiOnce.Do(loadEmoji)
+	return shortcodeRepl.Replace(s)
+}
+
+// CollapseEmoji replaces known emoji with their :shortcode: form.
+func CollapseEmoji(s string) string {
+	emojiOnce.Do(loadEmoji)
+	return emojiRepl.Replace(s)
+}
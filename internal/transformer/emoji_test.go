@@ -0,0 +1,114 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+func TestEmojiTable_Valid(t *testing.T) {
+	for _, line := range strings.Split(emojiTable, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		code, emoji, ok := strings.Cut(line, " ")
+		if !ok || code == "" || emoji == "" {
+			t.Errorf("malformed line %q", line)
+			continue
+		}
+		// Keycap emoji are the only ones starting with an ASCII character.
+		isKeycap := strings.HasSuffix(emoji, "\u20e3")
+		if !utf8.ValidString(emoji) || (emoji[0] < utf8.RuneSelf && !isKeycap) {
+			t.Errorf("%s: %q is not an emoji", code, emoji)
+		}
+	}
+
+	if emoji, ok := LookupEmoji("smile"); !ok || emoji != "\U0001F604" {
+		t.Errorf("LookupEmoji(smile) = %q, %v", emoji, ok)
+	}
+}
+
+func TestExpandShortcodes(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Ship it :rocket:", "Ship it \U0001F680"},
+		{":+1::tada:", "\U0001F44D\U0001F389"},
+		{"Unknown :not_an_emoji: stays", "Unknown :not_an_emoji: stays"},
+		{"Time 10:30:00", "Time 10:30:00"},
+	}
+	for _, tt := range tests {
+		if got := ExpandShortcodes(tt.in); got != tt.want {
+			t.Errorf("ExpandShortcodes(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCollapseEmoji(t *testing.T) {
+	// Aliases collapse to the first shortcode in the table.
+	if got := CollapseEmoji("Nice \U0001F44D"); got != "Nice :+1:" {
+		t.Errorf("CollapseEmoji() = %q", got)
+	}
+	// Variation selectors are part of the match.
+	if got := CollapseEmoji("I ❤️ Go"); got != "I :heart: Go" {
+		t.Errorf("CollapseEmoji() = %q", got)
+	}
+}
+
+func TestTransform_EmojiShortcodes(t *testing.T) {
+	note, err := parser.New().Parse("test.md", []byte("Done :white_check_mark: and *hot :fire:*\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.EmojiShortcodes = true
+	page, err := New(nil, cfg).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	p, ok := page.Children[0].(*notionapi.ParagraphBlock)
+	if !ok {
+		t.Fatalf("expected paragraph, got %T", page.Children[0])
+	}
+	var text strings.Builder
+	for _, rt := range p.Paragraph.RichText {
+		text.WriteString(rt.Text.Content)
+	}
+	if got := text.String(); got != "Done ✅ and hot \U0001F525" || len(p.Paragraph.RichText) != 2 {
+		t.Errorf("rich text = %q in %d run(s), want 2", got, len(p.Paragraph.RichText))
+	}
+
+	// Disabled by default.
+	page, _ = New(nil, nil).Transform(note)
+	p = page.Children[0].(*notionapi.ParagraphBlock)
+	text.Reset()
+	for _, rt := range p.Paragraph.RichText {
+		text.WriteString(rt.Text.Content)
+	}
+	if !strings.Contains(text.String(), ":white_check_mark:") {
+		t.Errorf("expected shortcodes to be kept by default, got %q", text.String())
+	}
+}
+
+func TestRichTextToMarkdown_EmojiReverse(t *testing.T) {
+	richText := []notionapi.RichText{
+		{PlainText: "Launch \U0001F680 "},
+		{PlainText: "\U0001F680", Annotations: &notionapi.Annotations{Code: true}},
+	}
+
+	if got := NewReverse(nil, nil).richTextToMarkdown(richText); got != "Launch \U0001F680 `\U0001F680`" {
+		t.Errorf("default = %q; emoji should be kept", got)
+	}
+
+	cfg := DefaultConfig()
+	cfg.EmojiReverse = true
+	if got := NewReverse(nil, cfg).richTextToMarkdown(richText); got != "Launch :rocket: `\U0001F680`" {
+		t.Errorf("reverse = %q; code spans should be kept", got)
+	}
+}
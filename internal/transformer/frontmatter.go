@@ -0,0 +1,178 @@
+package transformer
+
+import (
+	"bytes"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeFrontmatter writes pulled frontmatter as YAML, so that pulling an
+// unchanged page rewrites the note's frontmatter byte for byte. Keys of
+// the local note (Config.FrontmatterSource) keep their order, and values
+// it already has keep how they were written; other keys follow, sorted.
+// New values are quoted only where YAML needs it, and lists are written
+// in flow style unless the local list was not.
+func (t *ReverseTransformer) writeFrontmatter(buf *bytes.Buffer, frontmatter map[string]any) {
+	keys, values := localFrontmatter(t.config.FrontmatterSource)
+	keys = slices.DeleteFunc(keys, func(key string) bool {
+		_, ok := frontmatter[key]
+		return !ok
+	})
+	var added []string
+	for key := range frontmatter {
+		if _, ok := values[key]; !ok {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+
+	buf.WriteString("---\n")
+	for _, key := range append(keys, added...) {
+		keyNode, valueNode := frontmatterNodes(key, frontmatter[key])
+		if valueNode == nil {
+			continue
+		}
+		if local, ok := values[key]; ok {
+			keyNode = local[0]
+			valueNode = keepStyle(local[1], valueNode)
+		}
+		entry, err := encodeYAML(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{keyNode, valueNode}})
+		if err != nil {
+			continue
+		}
+		buf.WriteString(entry)
+	}
+	buf.WriteString("---\n\n")
+}
+
+// localFrontmatter returns the keys of a note's frontmatter, in order, and
+// the key and value nodes of each. A note without valid frontmatter has
+// none.
+func localFrontmatter(content []byte) ([]string, map[string][2]*yaml.Node) {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	rest, ok := strings.CutPrefix(text, "---\n")
+	if !ok {
+		return nil, nil
+	}
+	end := strings.Index(rest, "\n---\n")
+	if end == -1 {
+		if !strings.HasSuffix(rest, "\n---") {
+			return nil, nil
+		}
+		end = len(rest) - len("\n---")
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(rest[:end]), &doc); err != nil || len(doc.Content) == 0 {
+		return nil, nil
+	}
+	mapping := doc.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	var keys []string
+	values := make(map[string][2]*yaml.Node)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key := mapping.Content[i].Value
+		if _, dup := values[key]; dup {
+			continue
+		}
+		keys = append(keys, key)
+		values[key] = [2]*yaml.Node{mapping.Content[i], mapping.Content[i+1]}
+	}
+	return keys, values
+}
+
+// frontmatterNodes returns the YAML nodes of a pulled frontmatter entry,
+// or a nil value node if it cannot be written.
+func frontmatterNodes(key string, value any) (keyNode, valueNode *yaml.Node) {
+	keyNode = stringNode(key)
+	switch v := value.(type) {
+	case metaEntry:
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(v), &doc); err != nil || len(doc.Content) == 0 ||
+			doc.Content[0].Kind != yaml.MappingNode || len(doc.Content[0].Content) != 2 {
+			return keyNode, nil
+		}
+		return doc.Content[0].Content[0], doc.Content[0].Content[1]
+	case string:
+		return keyNode, stringNode(v)
+	case []string:
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, s := range v {
+			seq.Content = append(seq.Content, stringNode(s))
+		}
+		return keyNode, seq
+	}
+	valueNode = new(yaml.Node)
+	if err := valueNode.Encode(value); err != nil {
+		return keyNode, nil
+	}
+	if valueNode.Kind == yaml.SequenceNode {
+		valueNode.Style = yaml.FlowStyle
+	}
+	return keyNode, valueNode
+}
+
+// stringNode returns the node of a string, which the encoder quotes only
+// if it would read back as something else. Dates are left unquoted, as
+// Obsidian writes them.
+func stringNode(s string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+	var resolved yaml.Node
+	if yaml.Unmarshal([]byte(s), &resolved) == nil && len(resolved.Content) == 1 &&
+		resolved.Content[0].Kind == yaml.ScalarNode && resolved.Content[0].Tag == "!!timestamp" &&
+		resolved.Content[0].Value == s {
+		n.Tag = "!!timestamp"
+	}
+	return n
+}
+
+// keepStyle returns the local value node if it holds the same value as
+// the pulled one, which keeps its quoting, list style, and comments.
+// Otherwise the pulled node is returned in the local node's style.
+func keepStyle(local, pulled *yaml.Node) *yaml.Node {
+	if sameValue(local, pulled) {
+		return local
+	}
+	switch {
+	case local.Kind == yaml.SequenceNode && pulled.Kind == yaml.SequenceNode:
+		pulled.Style = local.Style &^ yaml.TaggedStyle
+	case local.Kind == yaml.ScalarNode && pulled.Kind == yaml.ScalarNode && pulled.Tag == "!!str":
+		pulled.Style = local.Style & (yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle)
+	}
+	return pulled
+}
+
+// sameValue reports whether two nodes hold the same value, however they
+// are written.
+func sameValue(a, b *yaml.Node) bool {
+	if a.Kind != b.Kind || len(a.Content) != len(b.Content) {
+		return false
+	}
+	if a.Kind == yaml.ScalarNode {
+		if a.Value == b.Value {
+			return true
+		}
+		var av, bv any
+		if a.Decode(&av) != nil || b.Decode(&bv) != nil {
+			return false
+		}
+		if an, ok := av.(int); ok {
+			av = float64(an)
+		}
+		if bn, ok := bv.(int); ok {
+			bv = float64(bn)
+		}
+		return av == bv
+	}
+	for i := range a.Content {
+		if !sameValue(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package transformer
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/jomei/notionapi"
+	"github.com/yuin/goldmark/ast"
+)
+
+// BlockHandler converts the content of a fenced code block to a Notion
+// block. It returns false to decline the block, in which case the built-in
+// handling for the language is used.
+type BlockHandler func(lang, content string) (notionapi.Block, bool)
+
+// InlineHandler converts an inline code span to rich text. It receives the
+// span's content with the registered prefix removed and the annotations of
+// the surrounding text. It returns false to decline the span, in which case
+// the span is rendered as inline code.
+type InlineHandler func(content string, annotations *notionapi.Annotations) ([]notionapi.RichText, bool)
+
+var (
+	handlersMu     sync.RWMutex
+	blockHandlers  = make(map[string]BlockHandler)
+	inlineHandlers []inlineHandler
+)
+
+// inlineHandler is a registered InlineHandler with its code span prefix.
+type inlineHandler struct {
+	prefix  string
+	handler InlineHandler
+}
+
+// RegisterBlockHandler registers a handler for fenced code blocks in a
+// language, such as "chart" for ```chart blocks. Languages are matched
+// case-insensitively. Registered handlers run before the built-in handling,
+// so they can also override languages like "dataview" or "math".
+// Registering a nil handler removes the handler for the language.
+//
+// Handlers are shared by every Transformer and are typically registered
+// from an init function.
+func RegisterBlockHandler(lang string, h BlockHandler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	lang = strings.ToLower(lang)
+	if h == nil {
+		delete(blockHandlers, lang)
+		return
+	}
+	blockHandlers[lang] = h
+}
+
+// RegisterInlineHandler registers a handler for inline code spans starting
+// with prefix, such as "chart:" for `chart:...` spans. When several
+// prefixes match, the longest wins. Registered handlers run before the
+// built-in handling, including inline Dataview queries (`=expr`).
+// Registering a nil handler removes the handler for the prefix.
+func RegisterInlineHandler(prefix string, h InlineHandler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	for i, ih := range inlineHandlers {
+		if ih.prefix == prefix {
+			inlineHandlers = append(inlineHandlers[:i], inlineHandlers[i+1:]...)
+			break
+		}
+	}
+	if h == nil || prefix == "" {
+		return
+	}
+	inlineHandlers = append(inlineHandlers, inlineHandler{prefix: prefix, handler: h})
+}
+
+// customBlock runs the registered handler for a fenced code block's
+// language, if any.
+func customBlock(cb *ast.FencedCodeBlock, source []byte) (notionapi.Block, bool) {
+	lang := string(cb.Language(source))
+	handlersMu.RLock()
+	h, ok := blockHandlers[strings.ToLower(lang)]
+	handlersMu.RUnlock()
+	if !ok || lang == "" {
+		return nil, false
+	}
+	return h(lang, fencedCodeContent(cb, source))
+}
+
+// fencedCodeContent returns the content of a fenced code block without its
+// trailing newline.
+func fencedCodeContent(cb *ast.FencedCodeBlock, source []byte) string {
+	var content strings.Builder
+	lines := cb.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		content.Write(line.Value(source))
+	}
+	return strings.TrimSuffix(content.String(), "\n")
+}
+
+// customInline runs the registered handler with the longest prefix matching
+// an inline code span, if any.
+func customInline(content string, annotations *notionapi.Annotations) ([]notionapi.RichText, bool) {
+	handlersMu.RLock()
+	var best *inlineHandler
+	for i, ih := range inlineHandlers {
+		if strings.HasPrefix(content, ih.prefix) && (best == nil || len(ih.prefix) > len(best.prefix)) {
+			best = &inlineHandlers[i]
+		}
+	}
+	var h inlineHandler
+	if best != nil {
+		h = *best
+	}
+	handlersMu.RUnlock()
+
+	if h.handler == nil {
+		return nil, false
+	}
+	return h.handler(strings.TrimPrefix(content, h.prefix), copyAnnotations(annotations))
+}
@@ -0,0 +1,103 @@
+package transformer
+
+import (
+	"testing"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+func TestRegisterBlockHandler(t *testing.T) {
+	var gotLang, gotContent string
+	RegisterBlockHandler("Chart", func(lang, content string) (notionapi.Block, bool) {
+		gotLang, gotContent = lang, content
+		return &notionapi.EmbedBlock{
+			BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeEmbed},
+			Embed:      notionapi.Embed{URL: "https://charts.example.com/" + content},
+		}, true
+	})
+	// Declining hands the block back to the built-in handling.
+	RegisterBlockHandler("dataview", func(lang, content string) (notionapi.Block, bool) {
+		return nil, false
+	})
+	defer RegisterBlockHandler("chart", nil)
+	defer RegisterBlockHandler("dataview", nil)
+
+	content := "```chart\nbar\n```\n\n```dataview\nLIST\n```\n\n```go\nx := 1\n```\n"
+	note, err := parser.New().Parse("test.md", []byte(content))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	page, err := New(nil, nil).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	if gotLang != "chart" || gotContent != "bar" {
+		t.Errorf("handler called with (%q, %q); want (\"chart\", \"bar\")", gotLang, gotContent)
+	}
+	if len(page.Children) != 3 {
+		t.Fatalf("got %d blocks; want 3", len(page.Children))
+	}
+	embed, ok := page.Children[0].(*notionapi.EmbedBlock)
+	if !ok || embed.Embed.URL != "https://charts.example.com/bar" {
+		t.Errorf("chart block = %#v; want the handler's embed", page.Children[0])
+	}
+	if page.Children[1].GetType() == notionapi.BlockTypeEmbed {
+		t.Errorf("dataview block = %#v; want built-in handling", page.Children[1])
+	}
+	if code, ok := page.Children[2].(*notionapi.CodeBlock); !ok || code.Code.Language != "go" {
+		t.Errorf("go block = %#v; want a code block", page.Children[2])
+	}
+}
+
+func TestRegisterInlineHandler(t *testing.T) {
+	RegisterInlineHandler("kb:", func(content string, annotations *notionapi.Annotations) ([]notionapi.RichText, bool) {
+		annotations.Bold = true
+		return []notionapi.RichText{{
+			Type:        notionapi.ObjectTypeText,
+			Text:        &notionapi.Text{Content: "[" + content + "]"},
+			Annotations: annotations,
+		}}, true
+	})
+	RegisterInlineHandler("kb:skip", func(content string, annotations *notionapi.Annotations) ([]notionapi.RichText, bool) {
+		return nil, false
+	})
+	defer RegisterInlineHandler("kb:", nil)
+	defer RegisterInlineHandler("kb:skip", nil)
+
+	tests := []struct {
+		input string
+		want  string
+		bold  bool
+		code  bool
+	}{
+		{"Press `kb:Ctrl+S` now", "[Ctrl+S]", true, false},
+		{"Press `kb:skip this` now", "kb:skip this", false, true},
+		{"Press `plain` now", "plain", false, true},
+	}
+	for _, tt := range tests {
+		note, err := parser.New().Parse("test.md", []byte(tt.input+"\n"))
+		if err != nil {
+			t.Fatalf("Parse() error: %v", err)
+		}
+		page, err := New(nil, nil).Transform(note)
+		if err != nil {
+			t.Fatalf("Transform() error: %v", err)
+		}
+		para := page.Children[0].(*notionapi.ParagraphBlock)
+		var found bool
+		for _, rt := range para.Paragraph.RichText {
+			if rt.Text.Content == tt.want {
+				found = true
+				if rt.Annotations.Bold != tt.bold || rt.Annotations.Code != tt.code {
+					t.Errorf("%q: annotations = %+v", tt.input, rt.Annotations)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%q: no rich text %q in %+v", tt.input, tt.want, para.Paragraph.RichText)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package transformer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jomei/notionapi"
+	"github.com/yuin/goldmark/ast"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+// HTMLPassthroughCaption is the caption that marks a code block holding raw
+// HTML preserved from a note. Pull writes such blocks back as plain HTML.
+const HTMLPassthroughCaption = "obsidian-notion:html"
+
+var (
+	// htmlTagPattern matches a single opening, closing, or self-closing tag.
+	htmlTagPattern = regexp.MustCompile(`^<\s*(/?)\s*([a-zA-Z][a-zA-Z0-9]*)((?:\s[^>]*?)?)\s*(/?)>$`)
+
+	// htmlAttrPattern matches a tag attribute with a quoted or bare value.
+	htmlAttrPattern = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+	// detailsPattern splits a <details> block into its summary, body, and
+	// closing tag (absent when the body continues in later blocks).
+	detailsPattern = regexp.MustCompile(`(?is)^\s*<details[^>]*>\s*(?:<summary[^>]*>(.*?)</summary>)?(.*?)(</details>)?\s*$`)
+)
+
+// htmlTag is a parsed HTML tag.
+type htmlTag struct {
+	name    string
+	closing bool
+	attrs   map[string]string
+}
+
+// parseHTMLTag parses a string holding exactly one HTML tag.
+func parseHTMLTag(s string) (htmlTag, bool) {
+	m := htmlTagPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return htmlTag{}, false
+	}
+	tag := htmlTag{
+		name:    strings.ToLower(m[2]),
+		closing: m[1] == "/",
+		attrs:   make(map[string]string),
+	}
+	for _, a := range htmlAttrPattern.FindAllStringSubmatch(m[3], -1) {
+		tag.attrs[strings.ToLower(a[1])] = a[2] + a[3] + a[4]
+	}
+	return tag, true
+}
+
+// htmlMode returns the configured HTML handling mode.
+func (t *Transformer) htmlMode() string {
+	if t.config.HTMLHandling == "" {
+		return "convert"
+	}
+	return t.config.HTMLHandling
+}
+
+// transformHTMLBlock converts a block of raw HTML. In convert mode, <img>
+// becomes an image block and <details> a toggle; anything else is kept as
+// a marked code block so pull can restore it.
+func (t *Transformer) transformHTMLBlock(node *ast.HTMLBlock, source []byte) notionapi.Block {
+	raw := htmlBlockText(node, source)
+
+	switch t.htmlMode() {
+	case "strip":
+		return nil
+	case "preserve":
+		return htmlCodeBlock(raw)
+	}
+
+	trimmed := strings.TrimSpace(raw)
+	if tag, ok := parseHTMLTag(trimmed); ok && !tag.closing {
+		switch tag.name {
+		case "img":
+			if src := tag.attrs["src"]; src != "" {
+				return t.imageBlock(src, tag.attrs["alt"])
+			}
+		case "hr":
+			return t.transformDivider()
+		case "br":
+			return nil
+		}
+	}
+
+	if m := detailsPattern.FindStringSubmatch(trimmed); m != nil {
+		children := t.transformFragment(m[2])
+		if m[3] == "" {
+			children = append(children, t.consumeDetailsBody(node, source)...)
+		}
+		return &notionapi.ToggleBlock{
+			BasicBlock: notionapi.BasicBlock{
+				Object: notionapi.ObjectTypeBlock,
+				Type:   notionapi.BlockTypeToggle,
+			},
+			Toggle: notionapi.Toggle{
+				RichText: t.fragmentRichText(m[1]),
+				Children: children,
+			},
+		}
+	}
+
+	return htmlCodeBlock(raw)
+}
+
+// consumeDetailsBody transforms the blocks following an unclosed <details>
+// up to the HTML block that closes it. Blank lines end an HTML block, so
+// the body of a multi-paragraph <details> arrives as separate siblings.
+func (t *Transformer) consumeDetailsBody(open *ast.HTMLBlock, source []byte) []notionapi.Block {
+	var children []notionapi.Block
+	for sib := open.NextSibling(); sib != nil; sib = sib.NextSibling() {
+		t.consume(sib)
+		if hb, ok := sib.(*ast.HTMLBlock); ok {
+			raw := htmlBlockText(hb, source)
+			if idx := strings.Index(strings.ToLower(raw), "</details>"); idx >= 0 {
+				children = append(children, t.transformFragment(raw[:idx])...)
+				break
+			}
+		}
+		children = append(children, t.transformSubtree(sib, source)...)
+	}
+	return children
+}
+
+// consume marks a node as already transformed.
+func (t *Transformer) consume(n ast.Node) {
+	if t.consumed == nil {
+		t.consumed = make(map[ast.Node]bool)
+	}
+	t.consumed[n] = true
+}
+
+// transformSubtree converts a node and its descendants to blocks.
+func (t *Transformer) transformSubtree(root ast.Node, source []byte) []notionapi.Block {
+	var blocks []notionapi.Block
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if t.consumed[n] && n != root {
+			return ast.WalkSkipChildren, nil
+		}
+		block, skipChildren := t.transformNode(n, source)
+		if block != nil {
+			blocks = append(blocks, block)
+		}
+		if skipChildren {
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return blocks
+}
+
+// transformFragment parses a markdown fragment embedded in HTML and
+// converts it to blocks.
+func (t *Transformer) transformFragment(markdown string) []notionapi.Block {
+	if strings.TrimSpace(markdown) == "" {
+		return nil
+	}
+	note, err := parser.New().Parse("", []byte(markdown))
+	if err != nil || note.AST == nil {
+		return []notionapi.Block{htmlCodeBlock(markdown)}
+	}
+	return t.transformSubtree(note.AST, note.Source)
+}
+
+// fragmentRichText converts an inline markdown fragment, such as a
+// <summary>, to rich text.
+func (t *Transformer) fragmentRichText(markdown string) []notionapi.RichText {
+	markdown = strings.TrimSpace(markdown)
+	if markdown == "" {
+		return []notionapi.RichText{{
+			Type: notionapi.ObjectTypeText,
+			Text: &notionapi.Text{Content: "Details"},
+		}}
+	}
+	note, err := parser.New().Parse("", []byte(markdown))
+	if err == nil && note.AST != nil {
+		if p, ok := note.AST.FirstChild().(*ast.Paragraph); ok {
+			return t.transformInlineContent(p, note.Source)
+		}
+	}
+	return []notionapi.RichText{{
+		Type: notionapi.ObjectTypeText,
+		Text: &notionapi.Text{Content: markdown},
+	}}
+}
+
+// htmlBlockText returns the raw source of an HTML block.
+func htmlBlockText(node *ast.HTMLBlock, source []byte) string {
+	var b strings.Builder
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		b.Write(seg.Value(source))
+	}
+	if node.HasClosure() {
+		b.Write(node.ClosureLine.Value(source))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// htmlCodeBlock wraps raw HTML in a code block marked for round-trip.
+func htmlCodeBlock(raw string) notionapi.Block {
+	return &notionapi.CodeBlock{
+		BasicBlock: notionapi.BasicBlock{
+			Object: notionapi.ObjectTypeBlock,
+			Type:   notionapi.BlockTypeCode,
+		},
+		Code: notionapi.Code{
+			Language: "html",
+			RichText: splitCodeContent(raw, notionRichTextMaxLength),
+			Caption: []notionapi.RichText{{
+				Type: notionapi.ObjectTypeText,
+				Text: &notionapi.Text{Content: HTMLPassthroughCaption},
+			}},
+		},
+	}
+}
+
+// inlineHTML tracks formatting opened by inline HTML tags. Goldmark reports
+// <b>text</b> as separate raw HTML nodes around the text, so the state is
+// carried across siblings.
+type inlineHTML struct {
+	base  *notionapi.Annotations
+	stack []inlineFormat
+}
+
+// inlineFormat is a formatting tag that is currently open.
+type inlineFormat struct {
+	name        string
+	annotations *notionapi.Annotations
+}
+
+// newInlineHTML starts tracking inline HTML below the given annotations.
+func newInlineHTML(base *notionapi.Annotations) *inlineHTML {
+	if base == nil {
+		base = &notionapi.Annotations{}
+	}
+	return &inlineHTML{base: base}
+}
+
+// current returns the annotations for the next sibling.
+func (h *inlineHTML) current() *notionapi.Annotations {
+	if len(h.stack) == 0 {
+		return h.base
+	}
+	return h.stack[len(h.stack)-1].annotations
+}
+
+// isOpen reports whether a formatting tag is open.
+func (h *inlineHTML) isOpen(name string) bool {
+	for _, f := range h.stack {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// transformInlineHTML handles a raw inline HTML node. It returns the rich
+// text to emit and false when the node should be rendered as text instead.
+func (t *Transformer) transformInlineHTML(h *inlineHTML, raw *ast.RawHTML, source []byte) ([]notionapi.RichText, bool) {
+	switch t.htmlMode() {
+	case "strip":
+		return nil, true
+	case "preserve":
+		return nil, false
+	}
+
+	var content strings.Builder
+	for i := 0; i < raw.Segments.Len(); i++ {
+		seg := raw.Segments.At(i)
+		content.Write(seg.Value(source))
+	}
+	tag, ok := parseHTMLTag(content.String())
+	if !ok {
+		return nil, false
+	}
+
+	switch tag.name {
+	case "br":
+		return []notionapi.RichText{{
+			Type:        notionapi.ObjectTypeText,
+			Text:        &notionapi.Text{Content: "\n"},
+			Annotations: copyAnnotations(h.current()),
+		}}, true
+
+	case "img":
+		if tag.closing || tag.attrs["src"] == "" {
+			return nil, false
+		}
+		src := tag.attrs["src"]
+		label := tag.attrs["alt"]
+		if label == "" {
+			label = src
+		}
+		return []notionapi.RichText{{
+			Type: notionapi.ObjectTypeText,
+			Text: &notionapi.Text{
+				Content: label,
+				Link:    &notionapi.Link{Url: src},
+			},
+			Annotations: copyAnnotations(h.current()),
+		}}, true
+	}
+
+	annotations := copyAnnotations(h.current())
+	switch tag.name {
+	case "b", "strong":
+		annotations.Bold = true
+	case "i", "em":
+		annotations.Italic = true
+	case "u", "ins":
+		annotations.Underline = true
+	case "s", "del", "strike":
+		annotations.Strikethrough = true
+	case "code", "kbd":
+		annotations.Code = true
+	case "mark":
+		annotations.Color = notionapi.ColorYellowBackground
+	case "span", "font":
+		// Only colored spans are formatting; keep any other span as text.
+		if tag.closing {
+			if !h.isOpen(tag.name) {
+				return nil, false
+			}
+		} else if color, ok := htmlTagColor(tag); ok {
+			annotations.Color = color
+		} else {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+
+	if !tag.closing {
+		h.stack = append(h.stack, inlineFormat{name: tag.name, annotations: annotations})
+		return nil, true
+	}
+	for i := len(h.stack) - 1; i >= 0; i-- {
+		if h.stack[i].name == tag.name {
+			h.stack = h.stack[:i]
+			break
+		}
+	}
+	return nil, true
+}
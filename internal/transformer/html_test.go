@@ -0,0 +1,161 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+func transformHTMLNote(t *testing.T, markdown, mode string) *NotionPage {
+	t.Helper()
+	note, err := parser.New().Parse("test.md", []byte(markdown))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	cfg := DefaultConfig()
+	cfg.HTMLHandling = mode
+	page, err := New(nil, cfg).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	return page
+}
+
+func richTextContent(rts []notionapi.RichText) string {
+	var b strings.Builder
+	for _, rt := range rts {
+		b.WriteString(rt.Text.Content)
+	}
+	return b.String()
+}
+
+func TestTransform_HTMLImage(t *testing.T) {
+	page := transformHTMLNote(t, `<img src="https://example.com/cat.png" alt="A cat">`+"\n", "")
+
+	if len(page.Children) != 1 {
+		t.Fatalf("expected 1 block, got %d", len(page.Children))
+	}
+	img, ok := page.Children[0].(*notionapi.ImageBlock)
+	if !ok {
+		t.Fatalf("expected image block, got %T", page.Children[0])
+	}
+	if img.Image.External == nil || img.Image.External.URL != "https://example.com/cat.png" {
+		t.Errorf("image URL = %+v", img.Image.External)
+	}
+}
+
+func TestTransform_HTMLDetails(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		children int
+	}{
+		{
+			name:     "single block",
+			markdown: "<details><summary>More</summary>Hidden text</details>\n",
+			children: 1,
+		},
+		{
+			name:     "multiple blocks",
+			markdown: "<details>\n<summary>More</summary>\n\nFirst paragraph.\n\n- item\n\n</details>\n\nAfter.\n",
+			children: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := transformHTMLNote(t, tt.markdown, "")
+
+			toggle, ok := page.Children[0].(*notionapi.ToggleBlock)
+			if !ok {
+				t.Fatalf("expected toggle block, got %T", page.Children[0])
+			}
+			if got := richTextContent(toggle.Toggle.RichText); got != "More" {
+				t.Errorf("summary = %q, want %q", got, "More")
+			}
+			if len(toggle.Toggle.Children) != tt.children {
+				t.Errorf("toggle has %d children, want %d", len(toggle.Toggle.Children), tt.children)
+			}
+		})
+	}
+
+	// Blocks inside the toggle are not repeated at the top level.
+	page := transformHTMLNote(t, tests[1].markdown, "")
+	if len(page.Children) != 2 {
+		t.Errorf("expected toggle and trailing paragraph, got %d blocks", len(page.Children))
+	}
+}
+
+func TestTransform_HTMLInline(t *testing.T) {
+	page := transformHTMLNote(t, "Some <b>bold</b> and <i>italic</i> text<br>next line\n", "")
+
+	p, ok := page.Children[0].(*notionapi.ParagraphBlock)
+	if !ok {
+		t.Fatalf("expected paragraph, got %T", page.Children[0])
+	}
+	if got := richTextContent(p.Paragraph.RichText); got != "Some bold and italic text\nnext line" {
+		t.Errorf("content = %q", got)
+	}
+	for _, rt := range p.Paragraph.RichText {
+		switch rt.Text.Content {
+		case "bold":
+			if rt.Annotations == nil || !rt.Annotations.Bold {
+				t.Errorf("expected %q to be bold", rt.Text.Content)
+			}
+		case "italic":
+			if rt.Annotations == nil || !rt.Annotations.Italic {
+				t.Errorf("expected %q to be italic", rt.Text.Content)
+			}
+		case " and ":
+			if rt.Annotations != nil && (rt.Annotations.Bold || rt.Annotations.Italic) {
+				t.Errorf("expected %q to be plain", rt.Text.Content)
+			}
+		}
+	}
+}
+
+func TestTransform_HTMLPassthroughRoundTrip(t *testing.T) {
+	table := "<table>\n<tr><td>a</td></tr>\n</table>"
+	page := transformHTMLNote(t, table+"\n", "")
+
+	code, ok := page.Children[0].(*notionapi.CodeBlock)
+	if !ok {
+		t.Fatalf("expected code block, got %T", page.Children[0])
+	}
+	if code.Code.Language != "html" || richTextContent(code.Code.Caption) != HTMLPassthroughCaption {
+		t.Errorf("code block not marked as HTML passthrough: %+v", code.Code)
+	}
+
+	// Notion returns plain text on read.
+	code.Code.RichText[0].PlainText = code.Code.RichText[0].Text.Content
+	code.Code.Caption[0].PlainText = HTMLPassthroughCaption
+	got := NewReverse(nil, nil).blockToMarkdown(code, 0)
+	if got != table+"\n\n" {
+		t.Errorf("round trip = %q, want %q", got, table+"\n\n")
+	}
+}
+
+func TestTransform_HTMLModes(t *testing.T) {
+	markdown := `<img src="https://example.com/cat.png">` + "\n\nSome <b>bold</b> text\n"
+
+	page := transformHTMLNote(t, markdown, "preserve")
+	if _, ok := page.Children[0].(*notionapi.CodeBlock); !ok {
+		t.Errorf("preserve: expected code block, got %T", page.Children[0])
+	}
+	p := page.Children[1].(*notionapi.ParagraphBlock)
+	if got := richTextContent(p.Paragraph.RichText); got != "Some <b>bold</b> text" {
+		t.Errorf("preserve: content = %q", got)
+	}
+
+	page = transformHTMLNote(t, markdown, "strip")
+	if len(page.Children) != 1 {
+		t.Fatalf("strip: expected 1 block, got %d", len(page.Children))
+	}
+	p = page.Children[0].(*notionapi.ParagraphBlock)
+	if got := richTextContent(p.Paragraph.RichText); got != "Some bold text" {
+		t.Errorf("strip: content = %q", got)
+	}
+}
@@ -0,0 +1,93 @@
+package transformer
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/jomei/notionapi"
+)
+
+// The emoji written as Notion icons. They are spelled as code points, which
+// no editor or file encoding can turn into mojibake such as "üìä".
+const (
+	EmojiBarChart        = "\U0001F4CA"       // 📊
+	EmojiBug             = "\U0001F41B"       // 🐛
+	EmojiClipboard       = "\U0001F4CB"       // 📋
+	EmojiCross           = "\u274C"           // ❌
+	EmojiExclamation     = "\u2757"           // ❗
+	EmojiFramedPicture   = "\U0001F5BC\uFE0F" // 🖼️
+	EmojiInformation     = "\u2139\uFE0F"     // ℹ️
+	EmojiLightBulb       = "\U0001F4A1"       // 💡
+	EmojiMemo            = "\U0001F4DD"       // 📝
+	EmojiOpenBook        = "\U0001F4D6"       // 📖
+	EmojiQuestion        = "\u2753"           // ❓
+	EmojiRedCircle       = "\U0001F534"       // 🔴
+	EmojiSpeechBalloon   = "\U0001F4AC"       // 💬
+	EmojiWarning         = "\u26A0\uFE0F"     // ⚠️
+	EmojiWhiteHeavyCheck = "\u2705"           // ✅
+	EmojiWorldMap        = "\U0001F5FA\uFE0F" // 🗺️
+)
+
+// The names of the icons of generated blocks, the keys of Config.Icons.
+const (
+	// IconCallout is the icon of callouts whose type has no icon.
+	IconCallout = "callout"
+
+	// IconDataview is the icon of dataview query placeholders.
+	IconDataview = "dataview"
+
+	// IconImage is the icon of placeholders for images that could not be
+	// embedded.
+	IconImage = "image"
+
+	// IconMap is the icon of Leaflet map placeholders.
+	IconMap = "map"
+
+	// IconUnsupported is the icon of placeholders for blocks the Notion
+	// API version does not support.
+	IconUnsupported = "unsupported"
+)
+
+// DefaultIcons maps the names of the icons of generated blocks to their
+// default emoji.
+var DefaultIcons = map[string]string{
+	IconCallout:     EmojiLightBulb,
+	IconDataview:    EmojiBarChart,
+	IconImage:       EmojiFramedPicture,
+	IconMap:         EmojiWorldMap,
+	IconUnsupported: EmojiWarning,
+}
+
+// ValidateIcon returns an error unless s can be used as an icon: a
+// non-empty, valid UTF-8 string without the Latin letters that emoji
+// decoded with the wrong encoding turn into.
+func ValidateIcon(s string) error {
+	if s == "" {
+		return fmt.Errorf("icon is empty")
+	}
+	if !utf8.ValidString(s) {
+		return fmt.Errorf("icon %q is not valid UTF-8", s)
+	}
+	for _, r := range s {
+		switch {
+		case r == utf8.RuneError:
+			return fmt.Errorf("icon %q contains a replacement character", s)
+		case r == '\u00A9' || r == '\u00AE':
+			// The copyright and registered signs are emoji.
+		case r < 0x80 && (r < '0' || r > '9') && r != '#' && r != '*':
+			return fmt.Errorf("icon %q is text, not an emoji", s)
+		case r >= 0x80 && r < 0x300:
+			return fmt.Errorf("icon %q looks garbled; save the config file as UTF-8", s)
+		}
+	}
+	return nil
+}
+
+// icon returns the icon of generated blocks named name, as configured or
+// by default.
+func (t *Transformer) icon(name string) notionapi.Emoji {
+	if icon := t.config.Icons[name]; icon != "" {
+		return notionapi.Emoji(icon)
+	}
+	return notionapi.Emoji(DefaultIcons[name])
+}
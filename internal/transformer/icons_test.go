@@ -0,0 +1,126 @@
+package transformer
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+func TestEmojiCodePoints(t *testing.T) {
+	tests := []struct {
+		name  string
+		emoji string
+		want  []rune
+	}{
+		{"bar chart", EmojiBarChart, []rune{0x1F4CA}},
+		{"bug", EmojiBug, []rune{0x1F41B}},
+		{"clipboard", EmojiClipboard, []rune{0x1F4CB}},
+		{"cross", EmojiCross, []rune{0x274C}},
+		{"exclamation", EmojiExclamation, []rune{0x2757}},
+		{"framed picture", EmojiFramedPicture, []rune{0x1F5BC, 0xFE0F}},
+		{"information", EmojiInformation, []rune{0x2139, 0xFE0F}},
+		{"light bulb", EmojiLightBulb, []rune{0x1F4A1}},
+		{"memo", EmojiMemo, []rune{0x1F4DD}},
+		{"open book", EmojiOpenBook, []rune{0x1F4D6}},
+		{"question", EmojiQuestion, []rune{0x2753}},
+		{"red circle", EmojiRedCircle, []rune{0x1F534}},
+		{"speech balloon", EmojiSpeechBalloon, []rune{0x1F4AC}},
+		{"warning", EmojiWarning, []rune{0x26A0, 0xFE0F}},
+		{"white heavy check", EmojiWhiteHeavyCheck, []rune{0x2705}},
+		{"world map", EmojiWorldMap, []rune{0x1F5FA, 0xFE0F}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !utf8.ValidString(tt.emoji) {
+				t.Fatalf("%q is not valid UTF-8", tt.emoji)
+			}
+			if got := []rune(tt.emoji); !slices.Equal(got, tt.want) {
+				t.Errorf("code points = %U, want %U", got, tt.want)
+			}
+			if err := ValidateIcon(tt.emoji); err != nil {
+				t.Errorf("ValidateIcon() error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDefaultIconsAreValid(t *testing.T) {
+	icons := map[string]string{}
+	for name, icon := range DefaultIcons {
+		icons["icons."+name] = icon
+	}
+	for callout, icon := range DefaultConfig().CalloutIcons {
+		icons["callout."+callout] = icon
+	}
+	for name, icon := range icons {
+		if err := ValidateIcon(icon); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestValidateIcon(t *testing.T) {
+	tests := []struct {
+		icon    string
+		wantErr string
+	}{
+		{icon: "\U0001F680"},
+		{icon: "\U0001F1F3\U0001F1F4"}, // Flag.
+		{icon: "1\uFE0F\u20E3"},        // Keycap.
+		{icon: "\u00A9\uFE0F"},
+		{icon: "", wantErr: "empty"},
+		{icon: "\xf0\x9f", wantErr: "not valid UTF-8"},
+		{icon: "\uFFFD", wantErr: "replacement character"},
+		{icon: "idea", wantErr: "not an emoji"},
+		{icon: "üñºÔ∏è", wantErr: "looks garbled"}, // 🖼️ decoded as Mac Roman.
+		{icon: "ðŸ“Š", wantErr: "looks garbled"},   // 📊 decoded as Windows-1252.
+	}
+	for _, tt := range tests {
+		err := ValidateIcon(tt.icon)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("ValidateIcon(%q) error: %v", tt.icon, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("ValidateIcon(%q) error = %v, want %q", tt.icon, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTransform_IconOverrides(t *testing.T) {
+	content := "> [!unknown] Note\n> body\n\n```dataview\nLIST\n```\n\n![diagram](diagram.png)\n"
+	note, err := parser.New().Parse("test.md", []byte(content))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	icons := func(cfg *Config) []string {
+		page, err := New(nil, cfg).Transform(note)
+		if err != nil {
+			t.Fatalf("Transform() error: %v", err)
+		}
+		var got []string
+		for _, block := range page.Children {
+			if callout, ok := block.(*notionapi.CalloutBlock); ok {
+				got = append(got, string(*callout.Callout.Icon.Emoji))
+			}
+		}
+		return got
+	}
+
+	want := []string{EmojiLightBulb, EmojiBarChart, EmojiFramedPicture}
+	if got := icons(DefaultConfig()); !slices.Equal(got, want) {
+		t.Errorf("default icons = %q, want %q", got, want)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Icons = map[string]string{IconCallout: "\U0001F4CC", IconDataview: "\U0001F4C8", IconImage: "\U0001F4F7"}
+	want = []string{"\U0001F4CC", "\U0001F4C8", "\U0001F4F7"}
+	if got := icons(cfg); !slices.Equal(got, want) {
+		t.Errorf("overridden icons = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,144 @@
+package transformer
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/jomei/notionapi"
+	"gopkg.in/yaml.v3"
+)
+
+// FrontmatterMetaCaption is the caption that marks the code block holding
+// frontmatter keys that have no Notion property, such as cssclasses, so
+// they are restored on pull.
+const FrontmatterMetaCaption = "obsidian-notion:frontmatter"
+
+// unpushedKeys returns the frontmatter keys, sorted, that are not pushed as
+// Notion properties: keys without a mapping, unless they are passed
+// through. The note's targets come from the config on pull and are left out.
+func (m *PropertyMapper) unpushedKeys(frontmatter map[string]any) []string {
+	mapped := make(map[string]bool, len(m.mappings))
+	for _, mapping := range m.mappings {
+		mapped[mapping.ObsidianKey] = true
+	}
+
+	var keys []string
+	for key := range frontmatter {
+		if mapped[key] || key == TargetsKey {
+			continue
+		}
+		if m.unmapped == UnmappedPassthrough && !reservedKeys[key] {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// frontmatterMetaBlock returns a code block holding the note's unpushed
+// frontmatter keys as YAML, or nil if every key is pushed.
+func (t *Transformer) frontmatterMetaBlock(frontmatter map[string]any) notionapi.Block {
+	keys := t.propertyMapper.unpushedKeys(frontmatter)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range keys {
+		var value yaml.Node
+		if err := value.Encode(frontmatter[key]); err != nil {
+			continue
+		}
+		trimMidnight(&value)
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &value)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	content, err := encodeYAML(doc)
+	if err != nil {
+		return nil
+	}
+
+	return &notionapi.CodeBlock{
+		BasicBlock: notionapi.BasicBlock{
+			Object: notionapi.ObjectTypeBlock,
+			Type:   notionapi.BlockTypeCode,
+		},
+		Code: notionapi.Code{
+			Language: "yaml",
+			RichText: splitCodeContent(strings.TrimSuffix(content, "\n"), notionRichTextMaxLength),
+			Caption: []notionapi.RichText{{
+				Type: notionapi.ObjectTypeText,
+				Text: &notionapi.Text{Content: FrontmatterMetaCaption},
+			}},
+		},
+	}
+}
+
+// trimMidnight writes timestamps at midnight UTC, which YAML decodes bare
+// dates to, back as dates.
+func trimMidnight(n *yaml.Node) {
+	if n.Tag == "!!timestamp" {
+		n.Value = strings.TrimSuffix(n.Value, "T00:00:00Z")
+	}
+	for _, c := range n.Content {
+		trimMidnight(c)
+	}
+}
+
+// encodeYAML encodes a node with two-space indentation, as Obsidian
+// writes frontmatter.
+func encodeYAML(n *yaml.Node) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// metaEntry is a frontmatter key restored from a frontmatter code block,
+// already written as YAML ("key: value\n").
+type metaEntry string
+
+// isFrontmatterMeta reports whether a block is a frontmatter code block.
+func (t *ReverseTransformer) isFrontmatterMeta(block notionapi.Block) bool {
+	b, ok := block.(*notionapi.CodeBlock)
+	return ok && t.richTextToPlainText(b.Code.Caption) == FrontmatterMetaCaption
+}
+
+// frontmatterMeta returns the entries of the frontmatter code blocks among
+// a page's top-level blocks, keyed by frontmatter key. Blocks that are not
+// valid YAML mappings are ignored.
+func (t *ReverseTransformer) frontmatterMeta(blocks []notionapi.Block) map[string]metaEntry {
+	entries := make(map[string]metaEntry)
+	for _, block := range blocks {
+		if !t.isFrontmatterMeta(block) {
+			continue
+		}
+		var doc yaml.Node
+		content := t.richTextToPlainText(block.(*notionapi.CodeBlock).Code.RichText)
+		if err := yaml.Unmarshal([]byte(content), &doc); err != nil || len(doc.Content) == 0 {
+			continue
+		}
+		mapping := doc.Content[0]
+		if mapping.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			entry, err := encodeYAML(&yaml.Node{Kind: yaml.MappingNode, Content: mapping.Content[i : i+2]})
+			if err != nil {
+				continue
+			}
+			entries[mapping.Content[i].Value] = metaEntry(entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,307 @@
+package transformer
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jomei/notionapi"
+	"github.com/yuin/goldmark/ast"
+)
+
+// The Obsidian plugins whose fenced code blocks are converted to Notion
+// equivalents, the keys of Config.PluginBlocks.
+const (
+	// PluginAdmonition converts ```ad-<type> blocks to callouts.
+	PluginAdmonition = "admonition"
+
+	// PluginKanban converts ```kanban boards to tables with a column per
+	// lane.
+	PluginKanban = "kanban"
+
+	// PluginLeaflet converts ```leaflet maps to callouts linking their
+	// coordinates on OpenStreetMap.
+	PluginLeaflet = "leaflet"
+)
+
+// pluginLanguages match the languages of the fenced code blocks of each
+// plugin, by plugin name.
+var pluginLanguages = map[string]func(lang string) bool{
+	PluginAdmonition: func(lang string) bool { return strings.HasPrefix(lang, "ad-") && len(lang) > len("ad-") },
+	PluginKanban:     func(lang string) bool { return lang == "kanban" },
+	PluginLeaflet:    func(lang string) bool { return lang == "leaflet" },
+}
+
+// IsPluginBlock reports whether name is a plugin whose blocks are
+// converted.
+func IsPluginBlock(name string) bool {
+	_, ok := pluginLanguages[name]
+	return ok
+}
+
+// pluginBlock converts a fenced code block of an Obsidian plugin, unless
+// the plugin is turned off in Config.PluginBlocks. A block the plugin's
+// converter declines is pushed as code.
+func (t *Transformer) pluginBlock(cb *ast.FencedCodeBlock, source []byte) (notionapi.Block, bool) {
+	lang := strings.ToLower(string(cb.Language(source)))
+	for name, match := range pluginLanguages {
+		if !match(lang) {
+			continue
+		}
+		if enabled, ok := t.config.PluginBlocks[name]; ok && !enabled {
+			return nil, false
+		}
+		var block notionapi.Block
+		content := fencedCodeContent(cb, source)
+		switch name {
+		case PluginAdmonition:
+			block = t.admonitionBlock(lang, content)
+		case PluginKanban:
+			block = t.kanbanBlock(content)
+		case PluginLeaflet:
+			block = t.leafletBlock(content)
+		}
+		return block, block != nil
+	}
+	return nil, false
+}
+
+// admonitionParamRegex matches a parameter line at the top of an
+// admonition, such as "title: Read first".
+var admonitionParamRegex = regexp.MustCompile(`^(title|collapse|icon|color):\s*(.*)$`)
+
+// admonitionBlock converts an Admonition plugin block to a callout with the
+// icon of the matching callout type. The block's title, or the capitalized
+// type, is the callout's text, and its body the callout's children.
+func (t *Transformer) admonitionBlock(lang, content string) notionapi.Block {
+	calloutType := strings.TrimPrefix(lang, "ad-")
+	title := strings.ToUpper(calloutType[:1]) + calloutType[1:]
+
+	lines := strings.Split(content, "\n")
+	for len(lines) > 0 {
+		m := admonitionParamRegex.FindStringSubmatch(strings.TrimSpace(lines[0]))
+		if m == nil {
+			break
+		}
+		if m[1] == "title" {
+			title = m[2]
+		}
+		lines = lines[1:]
+	}
+
+	icon := t.config.CalloutIcons[calloutType]
+	if icon == "" {
+		icon = string(t.icon(IconCallout))
+	}
+	emoji := notionapi.Emoji(icon)
+
+	var richText []notionapi.RichText
+	if strings.TrimSpace(title) != "" {
+		richText = t.fragmentRichText(title)
+		for i := range richText {
+			annotations := copyAnnotations(richText[i].Annotations)
+			annotations.Bold = true
+			richText[i].Annotations = annotations
+		}
+	}
+
+	return &notionapi.CalloutBlock{
+		BasicBlock: notionapi.BasicBlock{
+			Object: notionapi.ObjectTypeBlock,
+			Type:   "callout",
+		},
+		Callout: notionapi.Callout{
+			RichText: richText,
+			Icon: &notionapi.Icon{
+				Type:  "emoji",
+				Emoji: &emoji,
+			},
+			Children: t.transformFragment(strings.Join(lines, "\n")),
+		},
+	}
+}
+
+// kanbanCardRegex matches a card of a Kanban board: a list item with an
+// optional checkbox.
+var kanbanCardRegex = regexp.MustCompile(`^[-*+]\s+(?:\[([ xX])\]\s*)?(.*)$`)
+
+// kanbanLane is a lane of a Kanban board.
+type kanbanLane struct {
+	title string
+	cards []string
+}
+
+// kanbanBlock converts a Kanban plugin board to a table with a column per
+// lane, headed by the lane's title. Completed cards are marked with a check
+// mark. Boards without lanes are declined.
+func (t *Transformer) kanbanBlock(content string) notionapi.Block {
+	var lanes []*kanbanLane
+	inSettings := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inSettings:
+			inSettings = !strings.HasSuffix(trimmed, "%%")
+		case strings.HasPrefix(trimmed, "%%"):
+			// The board settings, kept in a comment.
+			inSettings = len(trimmed) == 2 || !strings.HasSuffix(trimmed, "%%")
+		case strings.HasPrefix(line, "## "):
+			lanes = append(lanes, &kanbanLane{title: strings.TrimSpace(line[3:])})
+		case len(lanes) == 0 || trimmed == "":
+			// Text before the first lane, and blank lines.
+		case kanbanCardRegex.MatchString(line):
+			m := kanbanCardRegex.FindStringSubmatch(line)
+			card := m[2]
+			if m[1] == "x" || m[1] == "X" {
+				card = EmojiWhiteHeavyCheck + " " + card
+			}
+			lane := lanes[len(lanes)-1]
+			lane.cards = append(lane.cards, card)
+		case line != trimmed:
+			// An indented line continues the last card.
+			lane := lanes[len(lanes)-1]
+			if n := len(lane.cards); n > 0 {
+				lane.cards[n-1] += "\n" + trimmed
+			}
+		}
+	}
+	if len(lanes) == 0 {
+		return nil
+	}
+
+	depth := 0
+	header := notionapi.TableRow{}
+	for _, lane := range lanes {
+		header.Cells = append(header.Cells, t.fragmentRichText(lane.title))
+		depth = max(depth, len(lane.cards))
+	}
+	rows := []notionapi.TableRow{header}
+	for i := 0; i < depth; i++ {
+		row := notionapi.TableRow{}
+		for _, lane := range lanes {
+			cell := []notionapi.RichText{}
+			if i < len(lane.cards) {
+				cell = t.fragmentRichText(lane.cards[i])
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		rows = append(rows, row)
+	}
+
+	return &notionapi.TableBlock{
+		BasicBlock: notionapi.BasicBlock{
+			Object: notionapi.ObjectTypeBlock,
+			Type:   notionapi.BlockTypeTableBlock,
+		},
+		Table: notionapi.Table{
+			TableWidth:      len(lanes),
+			HasColumnHeader: true,
+			Children:        buildTableRowBlocks(rows),
+		},
+	}
+}
+
+// leafletBlock converts a Leaflet plugin map to a callout titled with the
+// map's id, linking its center and markers on OpenStreetMap.
+func (t *Transformer) leafletBlock(content string) notionapi.Block {
+	var id, lat, long string
+	var markers []string
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "id":
+			id = value
+		case "lat":
+			lat = value
+		case "long":
+			long = value
+		case "marker":
+			markers = append(markers, value)
+		}
+	}
+
+	title := "Map"
+	if id != "" {
+		title += ": " + id
+	}
+	richText := []notionapi.RichText{{
+		Type:        notionapi.ObjectTypeText,
+		Text:        &notionapi.Text{Content: title},
+		Annotations: &notionapi.Annotations{Bold: true},
+	}}
+	if link, ok := mapLink(lat, long); ok {
+		richText = append(richText, notionapi.RichText{
+			Type: notionapi.ObjectTypeText,
+			Text: &notionapi.Text{Content: "\n"},
+		}, link)
+	}
+
+	// Markers are "type, lat, long, link".
+	var children notionapi.Blocks
+	for _, marker := range markers {
+		fields := strings.SplitN(marker, ",", 4)
+		if len(fields) < 3 {
+			continue
+		}
+		link, ok := mapLink(strings.TrimSpace(fields[1]), strings.TrimSpace(fields[2]))
+		if !ok {
+			continue
+		}
+		text := []notionapi.RichText{link}
+		if len(fields) == 4 && strings.TrimSpace(fields[3]) != "" {
+			text = append(text, notionapi.RichText{
+				Type: notionapi.ObjectTypeText,
+				Text: &notionapi.Text{Content: " "},
+			})
+			text = append(text, t.fragmentRichText(fields[3])...)
+		}
+		children = append(children, &notionapi.BulletedListItemBlock{
+			BasicBlock: notionapi.BasicBlock{
+				Object: notionapi.ObjectTypeBlock,
+				Type:   notionapi.BlockTypeBulletedListItem,
+			},
+			BulletedListItem: notionapi.ListItem{RichText: text},
+		})
+	}
+
+	emoji := t.icon(IconMap)
+	return &notionapi.CalloutBlock{
+		BasicBlock: notionapi.BasicBlock{
+			Object: notionapi.ObjectTypeBlock,
+			Type:   "callout",
+		},
+		Callout: notionapi.Callout{
+			RichText: richText,
+			Icon: &notionapi.Icon{
+				Type:  "emoji",
+				Emoji: &emoji,
+			},
+			Children: children,
+		},
+	}
+}
+
+// mapLink returns "lat, long" linked to the point on OpenStreetMap, or
+// false if the coordinates are not numbers.
+func mapLink(lat, long string) (notionapi.RichText, bool) {
+	if _, err := strconv.ParseFloat(lat, 64); err != nil {
+		return notionapi.RichText{}, false
+	}
+	if _, err := strconv.ParseFloat(long, 64); err != nil {
+		return notionapi.RichText{}, false
+	}
+	query := url.Values{"mlat": {lat}, "mlon": {long}}
+	return notionapi.RichText{
+		Type: notionapi.ObjectTypeText,
+		Text: &notionapi.Text{
+			Content: fmt.Sprintf("%s, %s", lat, long),
+			Link:    &notionapi.Link{Url: "https://www.openstreetmap.org/?" + query.Encode()},
+		},
+	}, true
+}
@@ -0,0 +1,154 @@
+package transformer
+
+import (
+	"testing"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+// transformPluginNote transforms a note and returns its blocks.
+func transformPluginNote(t *testing.T, cfg *Config, content string) []notionapi.Block {
+	t.Helper()
+	note, err := parser.New().Parse("test.md", []byte(content))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	page, err := New(nil, cfg).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	return page.Children
+}
+
+func TestPluginBlocks_Admonition(t *testing.T) {
+	content := "```ad-warning\ntitle: Read **first**\ncollapse: open\nDo not feed the gremlins.\n\n- after midnight\n```\n"
+	blocks := transformPluginNote(t, DefaultConfig(), content)
+	if len(blocks) != 1 {
+		t.Fatalf("got %d blocks, want 1", len(blocks))
+	}
+	callout, ok := blocks[0].(*notionapi.CalloutBlock)
+	if !ok {
+		t.Fatalf("block is %T, want *notionapi.CalloutBlock", blocks[0])
+	}
+	if got := string(*callout.Callout.Icon.Emoji); got != EmojiWarning {
+		t.Errorf("icon = %q, want %q", got, EmojiWarning)
+	}
+	if got := plainText(callout.Callout.RichText); got != "Read first" {
+		t.Errorf("title = %q, want %q", got, "Read first")
+	}
+	for _, rt := range callout.Callout.RichText {
+		if rt.Annotations == nil || !rt.Annotations.Bold {
+			t.Errorf("title run %q is not bold", rt.PlainText)
+		}
+	}
+	children := callout.Callout.Children
+	if len(children) != 2 {
+		t.Fatalf("got %d children, want 2", len(children))
+	}
+	if _, ok := children[0].(*notionapi.ParagraphBlock); !ok {
+		t.Errorf("children[0] is %T, want *notionapi.ParagraphBlock", children[0])
+	}
+	if _, ok := children[1].(*notionapi.BulletedListItemBlock); !ok {
+		t.Errorf("children[1] is %T, want *notionapi.BulletedListItemBlock", children[1])
+	}
+
+	// Without a title, the type is the title.
+	blocks = transformPluginNote(t, DefaultConfig(), "```ad-tip\nBody\n```\n")
+	callout = blocks[0].(*notionapi.CalloutBlock)
+	if got := plainText(callout.Callout.RichText); got != "Tip" {
+		t.Errorf("default title = %q, want Tip", got)
+	}
+}
+
+func TestPluginBlocks_Kanban(t *testing.T) {
+	content := "```kanban\n## Todo\n\n- [ ] Write [[Plan]]\n- [ ] Review\n\n## Done\n\n**Complete**\n- [x] Kickoff\n\n%% kanban:settings\n{\"kanban-plugin\":\"basic\"}\n%%\n```\n"
+	blocks := transformPluginNote(t, DefaultConfig(), content)
+	if len(blocks) != 1 {
+		t.Fatalf("got %d blocks, want 1", len(blocks))
+	}
+	table, ok := blocks[0].(*notionapi.TableBlock)
+	if !ok {
+		t.Fatalf("block is %T, want *notionapi.TableBlock", blocks[0])
+	}
+	if table.Table.TableWidth != 2 || !table.Table.HasColumnHeader {
+		t.Errorf("width %d, column header %v; want 2, true", table.Table.TableWidth, table.Table.HasColumnHeader)
+	}
+
+	// Unresolved links keep their brackets.
+	want := [][]string{
+		{"Todo", "Done"},
+		{"Write [[Plan]]", EmojiWhiteHeavyCheck + " Kickoff"},
+		{"Review", ""},
+	}
+	rows := table.Table.Children
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, row := range rows {
+		cells := row.(*notionapi.TableRowBlock).TableRow.Cells
+		for j, cell := range cells {
+			if got := plainText(cell); got != want[i][j] {
+				t.Errorf("cell [%d][%d] = %q, want %q", i, j, got, want[i][j])
+			}
+		}
+	}
+
+	// A board without lanes stays code.
+	blocks = transformPluginNote(t, DefaultConfig(), "```kanban\n- [ ] loose card\n```\n")
+	if _, ok := blocks[0].(*notionapi.CodeBlock); !ok {
+		t.Errorf("board without lanes is %T, want *notionapi.CodeBlock", blocks[0])
+	}
+}
+
+func TestPluginBlocks_Leaflet(t *testing.T) {
+	content := "```leaflet\nid: trip\nlat: 48.8566\nlong: 2.3522\nheight: 400px\nmarker: default, 48.8584, 2.2945, [[Eiffel Tower]]\nmarker: default, not, numbers\n```\n"
+	blocks := transformPluginNote(t, DefaultConfig(), content)
+	callout, ok := blocks[0].(*notionapi.CalloutBlock)
+	if !ok {
+		t.Fatalf("block is %T, want *notionapi.CalloutBlock", blocks[0])
+	}
+	if got := string(*callout.Callout.Icon.Emoji); got != EmojiWorldMap {
+		t.Errorf("icon = %q, want %q", got, EmojiWorldMap)
+	}
+	if got := plainText(callout.Callout.RichText); got != "Map: trip\n48.8566, 2.3522" {
+		t.Errorf("text = %q", got)
+	}
+	link := callout.Callout.RichText[2].Text.Link
+	if link == nil || link.Url != "https://www.openstreetmap.org/?mlat=48.8566&mlon=2.3522" {
+		t.Errorf("center link = %+v", link)
+	}
+
+	if len(callout.Callout.Children) != 1 {
+		t.Fatalf("got %d markers, want 1", len(callout.Callout.Children))
+	}
+	marker := callout.Callout.Children[0].(*notionapi.BulletedListItemBlock)
+	if got := plainText(marker.BulletedListItem.RichText); got != "48.8584, 2.2945 [[Eiffel Tower]]" {
+		t.Errorf("marker = %q", got)
+	}
+}
+
+func TestPluginBlocks_Disabled(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PluginBlocks = map[string]bool{PluginKanban: false, PluginLeaflet: true}
+	blocks := transformPluginNote(t, cfg, "```kanban\n## Todo\n- [ ] card\n```\n\n```leaflet\nid: map\n```\n")
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(blocks))
+	}
+	if code, ok := blocks[0].(*notionapi.CodeBlock); !ok || code.Code.Language != "kanban" {
+		t.Errorf("disabled kanban block is %T, want a kanban code block", blocks[0])
+	}
+	if _, ok := blocks[1].(*notionapi.CalloutBlock); !ok {
+		t.Errorf("enabled leaflet block is %T, want *notionapi.CalloutBlock", blocks[1])
+	}
+
+	for _, name := range []string{PluginAdmonition, PluginKanban, PluginLeaflet} {
+		if !IsPluginBlock(name) {
+			t.Errorf("IsPluginBlock(%q) = false", name)
+		}
+	}
+	if IsPluginBlock("excalidraw") {
+		t.Error(`IsPluginBlock("excalidraw") = true`)
+	}
+}
@@ -2,6 +2,7 @@ package transformer
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -17,6 +18,7 @@ const (
 	PropertyTypeRichText    PropertyType = "rich_text"
 	PropertyTypeNumber      PropertyType = "number"
 	PropertyTypeSelect      PropertyType = "select"
+	PropertyTypeStatus      PropertyType = "status"
 	PropertyTypeMultiSelect PropertyType = "multi_select"
 	PropertyTypeDate        PropertyType = "date"
 	PropertyTypeCheckbox    PropertyType = "checkbox"
@@ -62,9 +64,52 @@ var BuiltInMappings = []PropertyMapping{
 	{ObsidianKey: "author", NotionName: "Author", NotionType: PropertyTypeRichText},
 }
 
+// Unmapped property modes for Config.UnmappedProperties.
+const (
+	// UnmappedPull adds unmapped Notion properties to the frontmatter on
+	// pull. Unmapped frontmatter keys are not pushed.
+	UnmappedPull = "pull"
+	// UnmappedPassthrough also pushes unmapped frontmatter keys as Notion
+	// properties of the same name.
+	UnmappedPassthrough = "passthrough"
+	// UnmappedIgnore syncs mapped properties only.
+	UnmappedIgnore = "ignore"
+)
+
+// TargetsKey is the frontmatter key naming the databases a note publishes
+// to (the config's targets).
+const TargetsKey = "notion-targets"
+
+// ConflictKey is the frontmatter key naming the conflict strategy of a
+// note, which overrides the one sync and watch run with.
+const ConflictKey = "notion-conflict"
+
+// reservedKeys are frontmatter keys Obsidian and this tool use, which are
+// never passed through to Notion unless they are mapped.
+var reservedKeys = map[string]bool{
+	"title":      true,
+	"tags":       true,
+	"aliases":    true,
+	"cssclasses": true,
+	TargetsKey:   true,
+	ConflictKey:  true,
+}
+
 // PropertyMapper handles conversion between frontmatter and Notion properties.
 type PropertyMapper struct {
 	mappings []PropertyMapping
+
+	// nestedTags is the Config.NestedTags mode applied to tags.
+	nestedTags string
+
+	// tagRules is the Config.TagRules applied to tags.
+	tagRules TagRules
+
+	// unmapped is the Config.UnmappedProperties mode.
+	unmapped string
+
+	// dates reads and writes date values.
+	dates dateFormat
 }
 
 // NewPropertyMapper creates a new PropertyMapper with the given mappings.
@@ -76,9 +121,65 @@ func NewPropertyMapper(mappings []PropertyMapping) *PropertyMapper {
 	return &PropertyMapper{mappings: mappings}
 }
 
-// ToNotionProperties converts frontmatter to Notion properties.
+// newConfigPropertyMapper creates the PropertyMapper for a transformer
+// config: its property mappings (or DefaultMappings) with PropertyRenames
+// applied on top.
+func newConfigPropertyMapper(cfg *Config) *PropertyMapper {
+	mappings := DefaultMappings
+	if len(cfg.PropertyMappings) > 0 {
+		mappings = cfg.PropertyMappings
+	}
+	if len(cfg.PropertyRenames) > 0 {
+		mappings = applyRenames(mappings, cfg.PropertyRenames)
+	}
+
+	m := NewPropertyMapper(mappings)
+	m.nestedTags = cfg.NestedTags
+	m.tagRules = cfg.TagRules
+	m.unmapped = cfg.UnmappedProperties
+	m.dates = newDateFormat(cfg)
+	return m
+}
+
+// applyRenames returns mappings with each rename replacing the Notion name
+// of the mapping for the same frontmatter key, and its type when the rename
+// has one. Renames of unmapped keys are added as new mappings.
+func applyRenames(mappings, renames []PropertyMapping) []PropertyMapping {
+	result := make([]PropertyMapping, len(mappings), len(mappings)+len(renames))
+	copy(result, mappings)
+
+	for _, r := range renames {
+		found := false
+		for i := range result {
+			if result[i].ObsidianKey != r.ObsidianKey {
+				continue
+			}
+			result[i].NotionName = r.NotionName
+			if r.NotionType != "" {
+				result[i].NotionType = r.NotionType
+			}
+			found = true
+		}
+		if !found {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// ToNotionProperties converts frontmatter to Notion properties. Values
+// that do not fit their property types are left out.
 func (m *PropertyMapper) ToNotionProperties(frontmatter map[string]any, tags []string) notionapi.Properties {
+	props, _ := m.ConvertProperties(frontmatter, tags)
+	return props
+}
+
+// ConvertProperties converts frontmatter to Notion properties like
+// ToNotionProperties, and also returns PropertyErrors for the values left
+// out because they do not fit their property types.
+func (m *PropertyMapper) ConvertProperties(frontmatter map[string]any, tags []string) (notionapi.Properties, error) {
 	props := make(notionapi.Properties)
+	var errs PropertyErrors
 
 	// Process each mapping.
 	for _, mapping := range m.mappings {
@@ -92,19 +193,91 @@ func (m *PropertyMapper) ToNotionProperties(frontmatter map[string]any, tags []s
 			}
 		}
 
+		if mapping.ObsidianKey == "tags" {
+			value, _ = pushedTags(frontmatter, tags, m.tagRules, m.nestedTags)
+		}
+
 		// Apply transformation if defined.
 		if mapping.Transform != nil {
 			value = mapping.Transform(value)
 		}
 
 		// Convert to Notion property.
-		prop := m.convertToProperty(value, mapping.NotionType)
+		propType := mapping.NotionType
+		if propType == "" {
+			propType = m.inferPropertyType(value)
+		}
+		if err := m.checkValue(mapping, value, propType); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		prop := m.convertToProperty(value, propType)
 		if prop != nil {
 			props[mapping.NotionName] = prop
 		}
 	}
 
-	return props
+	if m.unmapped == UnmappedPassthrough {
+		m.passthrough(frontmatter, props)
+	}
+
+	if len(errs) > 0 {
+		return props, errs
+	}
+	return props, nil
+}
+
+// passthrough adds unmapped frontmatter keys to props under their own
+// names, with types inferred from their values.
+func (m *PropertyMapper) passthrough(frontmatter map[string]any, props notionapi.Properties) {
+	mapped := make(map[string]bool, len(m.mappings))
+	for _, mapping := range m.mappings {
+		mapped[mapping.ObsidianKey] = true
+	}
+
+	keys := make([]string, 0, len(frontmatter))
+	for key := range frontmatter {
+		if !mapped[key] && !reservedKeys[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, exists := props[key]; exists {
+			continue
+		}
+		value := frontmatter[key]
+		if value == nil {
+			continue
+		}
+		if prop := m.convertToProperty(value, m.inferPropertyType(value)); prop != nil {
+			props[key] = prop
+		}
+	}
+}
+
+// inferPropertyType picks a Notion property type for a frontmatter value
+// that has no configured type.
+func (m *PropertyMapper) inferPropertyType(value any) PropertyType {
+	switch v := value.(type) {
+	case bool:
+		return PropertyTypeCheckbox
+	case int, int64, float64:
+		return PropertyTypeNumber
+	case time.Time:
+		return PropertyTypeDate
+	case []any, []string:
+		return PropertyTypeMultiSelect
+	case string:
+		if _, ok := m.dates.parse(v); ok {
+			return PropertyTypeDate
+		}
+		if _, err := parseDate(v); err == nil {
+			return PropertyTypeDate
+		}
+	}
+	return PropertyTypeRichText
 }
 
 // ToFrontmatter converts Notion properties to frontmatter.
@@ -128,6 +301,10 @@ func (m *PropertyMapper) ToFrontmatter(props notionapi.Properties) map[string]an
 		processed[mapping.NotionName] = true
 	}
 
+	if m.unmapped == UnmappedIgnore {
+		return frontmatter
+	}
+
 	// Second pass: add unmapped properties with lowercase names.
 	for name, prop := range props {
 		if processed[name] {
@@ -164,6 +341,10 @@ func (m *PropertyMapper) extractPropertyValueAuto(prop notionapi.Property) any {
 		if p.Select.Name != "" {
 			return p.Select.Name
 		}
+	case *notionapi.StatusProperty:
+		if p.Status.Name != "" {
+			return p.Status.Name
+		}
 	case *notionapi.MultiSelectProperty:
 		var values []string
 		for _, opt := range p.MultiSelect {
@@ -174,7 +355,7 @@ func (m *PropertyMapper) extractPropertyValueAuto(prop notionapi.Property) any {
 		}
 	case *notionapi.DateProperty:
 		if p.Date != nil && p.Date.Start != nil {
-			return p.Date.Start.String()
+			return m.dates.formatProperty(p.Date.Start)
 		}
 	case *notionapi.CheckboxProperty:
 		return p.Checkbox
@@ -218,6 +399,10 @@ func (m *PropertyMapper) extractPropertyValueAuto(prop notionapi.Property) any {
 		if p.Select.Name != "" {
 			return p.Select.Name
 		}
+	case notionapi.StatusProperty:
+		if p.Status.Name != "" {
+			return p.Status.Name
+		}
 	case notionapi.MultiSelectProperty:
 		var values []string
 		for _, opt := range p.MultiSelect {
@@ -228,7 +413,7 @@ func (m *PropertyMapper) extractPropertyValueAuto(prop notionapi.Property) any {
 		}
 	case notionapi.DateProperty:
 		if p.Date != nil && p.Date.Start != nil {
-			return p.Date.Start.String()
+			return m.dates.formatProperty(p.Date.Start)
 		}
 	case notionapi.CheckboxProperty:
 		return p.Checkbox
@@ -260,6 +445,8 @@ func (m *PropertyMapper) convertToProperty(value any, propType PropertyType) not
 		return m.toNumberProperty(value)
 	case PropertyTypeSelect:
 		return m.toSelectProperty(value)
+	case PropertyTypeStatus:
+		return m.toStatusProperty(value)
 	case PropertyTypeMultiSelect:
 		return m.toMultiSelectProperty(value)
 	case PropertyTypeDate:
@@ -308,8 +495,10 @@ func (m *PropertyMapper) toNumberProperty(value any) notionapi.Property {
 		num = v
 	case int:
 		num = float64(v)
+	case int64:
+		num = float64(v)
 	case string:
-		num, _ = strconv.ParseFloat(v, 64)
+		num, _ = strconv.ParseFloat(strings.TrimSpace(v), 64)
 	}
 	return notionapi.NumberProperty{Number: num}
 }
@@ -321,6 +510,12 @@ func (m *PropertyMapper) toSelectProperty(value any) notionapi.Property {
 	}
 }
 
+func (m *PropertyMapper) toStatusProperty(value any) notionapi.Property {
+	return notionapi.StatusProperty{
+		Status: notionapi.Status{Name: toString(value)},
+	}
+}
+
 func (m *PropertyMapper) toMultiSelectProperty(value any) notionapi.Property {
 	var options []notionapi.Option
 
@@ -360,10 +555,13 @@ func (m *PropertyMapper) toDateProperty(value any) notionapi.Property {
 		dateStr = fmt.Sprintf("%v", v)
 	}
 
-	// Parse the date.
-	date, err := parseDate(dateStr)
-	if err != nil {
-		return nil
+	// Parse the date, trying the configured layouts first.
+	date, ok := m.dates.parse(dateStr)
+	if !ok {
+		var err error
+		if date, err = parseDate(dateStr); err != nil {
+			return nil
+		}
 	}
 
 	notionDate := notionapi.Date(date)
@@ -421,6 +619,8 @@ func (m *PropertyMapper) extractPropertyValue(prop notionapi.Property, propType
 		return p.Number
 	case *notionapi.SelectProperty:
 		return p.Select.Name
+	case *notionapi.StatusProperty:
+		return p.Status.Name
 	case *notionapi.MultiSelectProperty:
 		var values []string
 		for _, opt := range p.MultiSelect {
@@ -429,7 +629,7 @@ func (m *PropertyMapper) extractPropertyValue(prop notionapi.Property, propType
 		return values
 	case *notionapi.DateProperty:
 		if p.Date != nil && p.Date.Start != nil {
-			return p.Date.Start.String()
+			return m.dates.formatProperty(p.Date.Start)
 		}
 	case *notionapi.CheckboxProperty:
 		return p.Checkbox
@@ -465,6 +665,8 @@ func (m *PropertyMapper) extractPropertyValue(prop notionapi.Property, propType
 		return p.Number
 	case notionapi.SelectProperty:
 		return p.Select.Name
+	case notionapi.StatusProperty:
+		return p.Status.Name
 	case notionapi.MultiSelectProperty:
 		var values []string
 		for _, opt := range p.MultiSelect {
@@ -473,7 +675,7 @@ func (m *PropertyMapper) extractPropertyValue(prop notionapi.Property, propType
 		return values
 	case notionapi.DateProperty:
 		if p.Date != nil && p.Date.Start != nil {
-			return p.Date.Start.String()
+			return m.dates.formatProperty(p.Date.Start)
 		}
 	case notionapi.CheckboxProperty:
 		return p.Checkbox
@@ -545,6 +747,8 @@ func PropertyTypeFromString(s string) PropertyType {
 		return PropertyTypeNumber
 	case "select":
 		return PropertyTypeSelect
+	case "status":
+		return PropertyTypeStatus
 	case "multi_select":
 		return PropertyTypeMultiSelect
 	case "date":
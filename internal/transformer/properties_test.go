@@ -1,6 +1,8 @@
 package transformer
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -112,6 +114,30 @@ func TestToNotionProperties_SelectProperty(t *testing.T) {
 	}
 }
 
+func TestToNotionProperties_StatusProperty(t *testing.T) {
+	mappings := []PropertyMapping{
+		{ObsidianKey: "status", NotionName: "Status", NotionType: PropertyTypeStatus},
+	}
+	pm := NewPropertyMapper(mappings)
+
+	props := pm.ToNotionProperties(map[string]any{"status": "Published"}, nil)
+
+	statusProp, ok := props["Status"].(notionapi.StatusProperty)
+	if !ok {
+		t.Fatal("expected Status to be StatusProperty")
+	}
+	if statusProp.Status.Name != "Published" {
+		t.Errorf("expected status 'Published', got '%s'", statusProp.Status.Name)
+	}
+
+	pulled := pm.ToFrontmatter(notionapi.Properties{
+		"Status": &notionapi.StatusProperty{Status: notionapi.Status{Name: "Draft"}},
+	})
+	if pulled["status"] != "Draft" {
+		t.Errorf("expected pulled status 'Draft', got %v", pulled["status"])
+	}
+}
+
 func TestToNotionProperties_DateProperty(t *testing.T) {
 	mappings := []PropertyMapping{
 		{ObsidianKey: "due", NotionName: "Due Date", NotionType: PropertyTypeDate},
@@ -414,6 +440,89 @@ func TestToFrontmatter_AllPropertyTypes(t *testing.T) {
 	}
 }
 
+func TestPropertyRenames(t *testing.T) {
+	pm := newConfigPropertyMapper(&Config{
+		PropertyRenames: []PropertyMapping{
+			{ObsidianKey: "title", NotionName: "Title"},
+			{ObsidianKey: "status", NotionName: "Workflow State", NotionType: PropertyTypeSelect},
+			{ObsidianKey: "estimate", NotionName: "Points"},
+		},
+	})
+
+	props := pm.ToNotionProperties(map[string]any{
+		"title":    "Note",
+		"status":   "Doing",
+		"estimate": 3,
+		"owner":    "ada",
+	}, nil)
+
+	if _, ok := props["Title"].(notionapi.TitleProperty); !ok {
+		t.Errorf("Title = %#v; want the title renamed with its type kept", props["Title"])
+	}
+	if _, ok := props["Name"]; ok {
+		t.Error("Name set; want it replaced by the rename")
+	}
+	if sel, ok := props["Workflow State"].(notionapi.SelectProperty); !ok || sel.Select.Name != "Doing" {
+		t.Errorf("Workflow State = %#v; want select Doing", props["Workflow State"])
+	}
+	if num, ok := props["Points"].(notionapi.NumberProperty); !ok || num.Number != 3 {
+		t.Errorf("Points = %#v; want an inferred number 3", props["Points"])
+	}
+	if _, ok := props["owner"]; ok {
+		t.Error("unmapped key pushed without passthrough")
+	}
+
+	frontmatter := pm.ToFrontmatter(notionapi.Properties{
+		"Workflow State": &notionapi.SelectProperty{Select: notionapi.Option{Name: "Done"}},
+		"Points":         &notionapi.NumberProperty{Number: 5},
+	})
+	if frontmatter["status"] != "Done" || frontmatter["estimate"] != float64(5) {
+		t.Errorf("ToFrontmatter() = %v; want status and estimate renamed back", frontmatter)
+	}
+}
+
+func TestUnmappedProperties(t *testing.T) {
+	frontmatter := map[string]any{
+		"title":   "Note",
+		"aliases": []any{"n"},
+		"owner":   "ada",
+		"done":    true,
+		"due":     "2024-03-01",
+		"labels":  []any{"a", "b"},
+	}
+
+	pm := newConfigPropertyMapper(&Config{UnmappedProperties: UnmappedPassthrough})
+	props := pm.ToNotionProperties(frontmatter, nil)
+	if _, ok := props["owner"].(notionapi.RichTextProperty); !ok {
+		t.Errorf("owner = %#v; want rich text", props["owner"])
+	}
+	if _, ok := props["done"].(notionapi.CheckboxProperty); !ok {
+		t.Errorf("done = %#v; want a checkbox", props["done"])
+	}
+	if _, ok := props["due"].(notionapi.DateProperty); !ok {
+		t.Errorf("due = %#v; want a date", props["due"])
+	}
+	if _, ok := props["labels"].(notionapi.MultiSelectProperty); !ok {
+		t.Errorf("labels = %#v; want a multi-select", props["labels"])
+	}
+	if _, ok := props["aliases"]; ok {
+		t.Error("aliases pushed; want reserved keys skipped")
+	}
+
+	pulled := notionapi.Properties{
+		"Name":   &notionapi.TitleProperty{Title: []notionapi.RichText{{PlainText: "Note"}}},
+		"Status": &notionapi.SelectProperty{Select: notionapi.Option{Name: "Done"}},
+	}
+	ignore := newConfigPropertyMapper(&Config{UnmappedProperties: UnmappedIgnore})
+	got := ignore.ToFrontmatter(pulled)
+	if _, ok := got["status"]; ok {
+		t.Errorf("ToFrontmatter() = %v; want unmapped properties ignored", got)
+	}
+	if got["title"] != "Note" {
+		t.Errorf("title = %v; want mapped properties kept", got["title"])
+	}
+}
+
 func TestPropertyMappingFromConfig(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -464,6 +573,7 @@ func TestPropertyTypeFromString(t *testing.T) {
 		{"rich_text", PropertyTypeRichText},
 		{"number", PropertyTypeNumber},
 		{"select", PropertyTypeSelect},
+		{"status", PropertyTypeStatus},
 		{"multi_select", PropertyTypeMultiSelect},
 		{"date", PropertyTypeDate},
 		{"checkbox", PropertyTypeCheckbox},
@@ -508,6 +618,53 @@ func TestToNotionProperties_SkipsMissingFrontmatter(t *testing.T) {
 	}
 }
 
+func TestConvertProperties_CoercionErrors(t *testing.T) {
+	pm := NewPropertyMapper([]PropertyMapping{
+		{ObsidianKey: "title", NotionName: "Name", NotionType: PropertyTypeTitle},
+		{ObsidianKey: "priority", NotionName: "Priority", NotionType: PropertyTypeNumber},
+		{ObsidianKey: "effort", NotionName: "Effort", NotionType: PropertyTypeNumber},
+		{ObsidianKey: "due", NotionName: "Due", NotionType: PropertyTypeDate},
+		{ObsidianKey: "done", NotionName: "Done", NotionType: PropertyTypeCheckbox},
+		{ObsidianKey: "status", NotionName: "Status", NotionType: PropertyTypeSelect},
+		{ObsidianKey: "empty", NotionName: "Empty", NotionType: PropertyTypeDate},
+	})
+	props, err := pm.ConvertProperties(map[string]any{
+		"title":    "Plan",
+		"priority": "high",
+		"effort":   " 2.5",
+		"due":      "next week",
+		"done":     "maybe",
+		"status":   []any{"open", "blocked"},
+		"empty":    nil,
+	}, nil)
+
+	var errs PropertyErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ConvertProperties() error = %v, want PropertyErrors", err)
+	}
+	var keys []string
+	for _, e := range errs {
+		keys = append(keys, e.Key)
+	}
+	if got := strings.Join(keys, " "); got != "priority due done status" {
+		t.Errorf("errors for %q, want priority due done status", got)
+	}
+	if want := `priority: "high" is not a valid number for property "Priority" (write a number, e.g. priority: 3, or map priority to a select property)`; errs[0].Error() != want {
+		t.Errorf("Error() = %q, want %q", errs[0].Error(), want)
+	}
+
+	// The values that fit are still converted.
+	if _, ok := props["Name"]; !ok {
+		t.Error("title property missing")
+	}
+	if n, ok := props["Effort"].(notionapi.NumberProperty); !ok || n.Number != 2.5 {
+		t.Errorf("Effort = %v, want 2.5", props["Effort"])
+	}
+	if _, ok := props["Priority"]; ok {
+		t.Error("Priority converted despite its error")
+	}
+}
+
 func TestDateParsing(t *testing.T) {
 	tests := []struct {
 		name    string
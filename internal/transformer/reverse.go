@@ -3,10 +3,15 @@ package transformer
 import (
 	"bytes"
 	"fmt"
-	"sort"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
 )
 
 // PathLookup resolves Notion page IDs back to Obsidian paths.
@@ -15,11 +20,29 @@ type PathLookup interface {
 	LookupPath(notionPageID string) (obsidianPath string, found bool)
 }
 
+// AttachmentSaver saves files hosted by Notion into the vault.
+type AttachmentSaver interface {
+	// SaveAttachment downloads a Notion-hosted file and returns the embed
+	// target of the saved copy. name is the file name to save it under.
+	// Returns empty string and false if the file was not saved.
+	SaveAttachment(url, name string) (target string, saved bool)
+}
+
 // ReverseTransformer converts Notion pages back to Obsidian-flavored markdown.
 type ReverseTransformer struct {
-	pathLookup     PathLookup
-	config         *Config
-	propertyMapper *PropertyMapper
+	pathLookup        PathLookup
+	attachmentSaver   AttachmentSaver
+	bookmarkPreviewer BookmarkPreviewer
+	userNamer         UserNamer
+	config            *Config
+	propertyMapper    *PropertyMapper
+
+	// tasks are the recorded task states of the page being converted that
+	// are not restored yet.
+	tasks []TaskState
+
+	// dividers are the recorded markers of the page's dividers.
+	dividers map[notionapi.Block]string
 }
 
 // NewReverse creates a new ReverseTransformer.
@@ -28,30 +51,25 @@ func NewReverse(lookup PathLookup, cfg *Config) *ReverseTransformer {
 		cfg = DefaultConfig()
 	}
 
-	// Create property mapper from config or use defaults.
-	var mappings []PropertyMapping
-	if len(cfg.PropertyMappings) > 0 {
-		mappings = cfg.PropertyMappings
-	}
+	propertyMapper := newConfigPropertyMapper(cfg)
 
 	return &ReverseTransformer{
 		pathLookup:     lookup,
 		config:         cfg,
-		propertyMapper: NewPropertyMapper(mappings),
+		propertyMapper: propertyMapper,
 	}
 }
 
+// SetAttachmentSaver sets the saver used to download files hosted by
+// Notion. Without one, they are linked by their temporary URLs.
+func (t *ReverseTransformer) SetAttachmentSaver(s AttachmentSaver) {
+	t.attachmentSaver = s
+}
+
 // Transform converts Notion blocks to Obsidian-flavored markdown.
 // This is the main entry point for block-level conversion.
 func (rt *ReverseTransformer) Transform(blocks []notionapi.Block) (string, error) {
-	var buf bytes.Buffer
-
-	for _, block := range blocks {
-		md := rt.blockToMarkdown(block, 0)
-		buf.WriteString(md)
-	}
-
-	return buf.String(), nil
+	return rt.transformChildren(blocks, 0), nil
 }
 
 // TransformRichText converts Notion rich text to markdown string.
@@ -66,27 +84,48 @@ func (t *ReverseTransformer) NotionToMarkdown(page *NotionPage) ([]byte, error)
 
 	// 1. Convert properties to frontmatter.
 	frontmatter := t.propertiesToFrontmatter(page.Properties)
-	if len(frontmatter) > 0 {
-		buf.WriteString("---\n")
-		// Sort keys for deterministic output.
-		keys := make([]string, 0, len(frontmatter))
-		for key := range frontmatter {
-			keys = append(keys, key)
-		}
-		sort.Strings(keys)
-		for _, key := range keys {
-			buf.WriteString(fmt.Sprintf("%s: %v\n", key, frontmatter[key]))
+	if values, ok := frontmatter["tags"].([]string); ok && (nestsTags(t.config.NestedTags) || len(page.Tags) > 0) {
+		frontmatter["tags"] = RestoreTags(values, page.Tags, t.config.NestedTags)
+	}
+	t.setPulledDates(frontmatter, page)
+	if len(t.config.NoteTargets) > 0 {
+		frontmatter[TargetsKey] = t.config.NoteTargets
+	}
+	for key, entry := range t.frontmatterMeta(page.Children) {
+		if _, exists := frontmatter[key]; !exists {
+			frontmatter[key] = entry
 		}
-		buf.WriteString("---\n\n")
+	}
+	if len(frontmatter) > 0 {
+		t.writeFrontmatter(&buf, frontmatter)
 	}
 
 	// 2. Convert blocks to markdown.
-	for _, block := range page.Children {
-		md := t.blockToMarkdown(block, 0)
-		buf.WriteString(md)
+	t.tasks = slices.Clone(page.Tasks)
+	t.dividers = page.Dividers
+	body := t.transformChildren(page.Children, 0)
+	if len(frontmatter) == 0 {
+		body = guardLeadingRule(body)
 	}
+	buf.WriteString(body)
 
-	return buf.Bytes(), nil
+	// 3. Reassemble sections split into child pages under their headings.
+	for _, section := range page.Sections {
+		if endsBareLine(body) {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("# " + section.Title + "\n\n")
+		body = t.transformChildren(section.Children, 0)
+		buf.WriteString(body)
+	}
+
+	return []byte(t.wrapParagraphs(buf.String())), nil
+}
+
+// BlockMarkdown converts a single top-level block, with its children, to
+// markdown.
+func (t *ReverseTransformer) BlockMarkdown(block notionapi.Block) string {
+	return t.blockToMarkdown(block, 0)
 }
 
 // blockToMarkdown converts a Notion block to markdown with proper indentation.
@@ -106,6 +145,10 @@ func (t *ReverseTransformer) blockToMarkdown(block notionapi.Block, depth int) s
 	case *notionapi.ParagraphBlock:
 		text := t.richTextToMarkdown(b.Paragraph.RichText)
 		if text == "" {
+			// An empty paragraph is one blank line, unless collapsed.
+			if t.config.EmptyParagraphs == "collapse" {
+				return ""
+			}
 			return "\n"
 		}
 		result := indent + text + "\n\n"
@@ -117,14 +160,14 @@ func (t *ReverseTransformer) blockToMarkdown(block notionapi.Block, depth int) s
 		text := t.richTextToMarkdown(b.BulletedListItem.RichText)
 		result := indent + "- " + text + "\n"
 		// Handle nested children.
-		result += t.transformChildren(b.BulletedListItem.Children, depth+1)
+		result += t.itemChildren(b.BulletedListItem.Children, indent+"  ")
 		return result
 
 	case *notionapi.NumberedListItemBlock:
 		text := t.richTextToMarkdown(b.NumberedListItem.RichText)
 		result := indent + "1. " + text + "\n"
 		// Handle nested children.
-		result += t.transformChildren(b.NumberedListItem.Children, depth+1)
+		result += t.itemChildren(b.NumberedListItem.Children, indent+"   ")
 		return result
 
 	case *notionapi.ToDoBlock:
@@ -133,9 +176,12 @@ func (t *ReverseTransformer) blockToMarkdown(block notionapi.Block, depth int) s
 			checkbox = "[x]"
 		}
 		text := t.richTextToMarkdown(b.ToDo.RichText)
+		if state, rest, ok := t.restoreTaskState(b.ToDo, text); ok {
+			checkbox, text = "["+state+"]", rest
+		}
 		result := indent + "- " + checkbox + " " + text + "\n"
-		// Handle nested children.
-		result += t.transformChildren(b.ToDo.Children, depth+1)
+		// Handle nested children, such as sub-tasks.
+		result += t.itemChildren(b.ToDo.Children, indent+"  ")
 		return result
 
 	case *notionapi.QuoteBlock:
@@ -146,14 +192,7 @@ func (t *ReverseTransformer) blockToMarkdown(block notionapi.Block, depth int) s
 			result.WriteString(indent + "> " + line + "\n")
 		}
 		// Handle nested children.
-		childMd := t.transformChildren(b.Quote.Children, depth)
-		if childMd != "" {
-			// Prefix each line with > for quote context.
-			childLines := strings.Split(strings.TrimSuffix(childMd, "\n"), "\n")
-			for _, line := range childLines {
-				result.WriteString(indent + "> " + line + "\n")
-			}
-		}
+		result.WriteString(quoteChildren(indent, t.transformChildren(b.Quote.Children, depth)))
 		result.WriteString("\n")
 		return result.String()
 
@@ -174,17 +213,19 @@ func (t *ReverseTransformer) blockToMarkdown(block notionapi.Block, depth int) s
 			}
 		}
 		// Handle nested children.
-		childMd := t.transformChildren(b.Callout.Children, depth)
-		if childMd != "" {
-			childLines := strings.Split(strings.TrimSuffix(childMd, "\n"), "\n")
-			for _, line := range childLines {
-				result.WriteString(indent + "> " + line + "\n")
-			}
-		}
+		result.WriteString(quoteChildren(indent, t.transformChildren(b.Callout.Children, depth)))
 		result.WriteString("\n")
 		return result.String()
 
 	case *notionapi.CodeBlock:
+		// Frontmatter kept on push is restored with the page's properties.
+		if t.isFrontmatterMeta(b) {
+			return ""
+		}
+		// HTML preserved on push is written back as-is.
+		if b.Code.Language == "html" && t.richTextToPlainText(b.Code.Caption) == HTMLPassthroughCaption {
+			return indent + t.richTextToPlainText(b.Code.RichText) + "\n\n"
+		}
 		lang := b.Code.Language
 		if lang == "plain text" {
 			lang = ""
@@ -193,7 +234,7 @@ func (t *ReverseTransformer) blockToMarkdown(block notionapi.Block, depth int) s
 		return fmt.Sprintf("%s```%s\n%s\n```\n\n", indent, lang, code)
 
 	case *notionapi.DividerBlock:
-		return indent + "---\n\n"
+		return indent + t.pulledDivider(block) + "\n\n"
 
 	case *notionapi.EquationBlock:
 		return fmt.Sprintf("%s$$\n%s\n$$\n\n", indent, b.Equation.Expression)
@@ -206,11 +247,42 @@ func (t *ReverseTransformer) blockToMarkdown(block notionapi.Block, depth int) s
 			url = b.Image.External.URL
 		}
 		caption := t.richTextToMarkdown(b.Image.Caption)
+		if b.Image.File != nil {
+			// Uploaded embeds with a display size carry it in the caption.
+			embed, sized := sizedEmbed(t.richTextToPlainText(b.Image.Caption))
+			name := hostedFileName(url)
+			if sized {
+				name, _, _ = strings.Cut(embed, "|")
+			}
+			if target, ok := t.saveAttachment(url, name); ok {
+				switch {
+				case sized:
+					_, size, _ := strings.Cut(embed, "|")
+					return fmt.Sprintf("%s![[%s|%s]]\n\n", indent, target, size)
+				case caption != "":
+					return fmt.Sprintf("%s![%s](%s)\n\n", indent, caption, escapeLinkTarget(target))
+				}
+				return fmt.Sprintf("%s![[%s]]\n\n", indent, target)
+			}
+			if sized {
+				return fmt.Sprintf("%s![[%s]]\n\n", indent, embed)
+			}
+		}
 		if caption != "" {
 			return fmt.Sprintf("%s![%s](%s)\n\n", indent, caption, url)
 		}
 		return fmt.Sprintf("%s![](%s)\n\n", indent, url)
 
+	case *FileUploadImageBlock:
+		// A block built for push renders as the Notion-hosted image it
+		// becomes, named by its upload ID until it is read back.
+		return t.blockToMarkdown(&notionapi.ImageBlock{
+			Image: notionapi.Image{
+				Caption: b.Image.Caption,
+				File:    &notionapi.FileObject{URL: b.Image.FileUpload.ID},
+			},
+		}, depth)
+
 	case *notionapi.TableBlock:
 		return t.tableToMarkdown(b, depth)
 
@@ -222,19 +294,11 @@ func (t *ReverseTransformer) blockToMarkdown(block notionapi.Block, depth int) s
 		text := t.richTextToMarkdown(b.Toggle.RichText)
 		result := fmt.Sprintf("%s- %s\n", indent, text)
 		// Handle nested children.
-		result += t.transformChildren(b.Toggle.Children, depth+1)
+		result += t.itemChildren(b.Toggle.Children, indent+"  ")
 		return result
 
 	case *notionapi.BookmarkBlock:
-		url := ""
-		if b.Bookmark.URL != "" {
-			url = b.Bookmark.URL
-		}
-		caption := t.richTextToMarkdown(b.Bookmark.Caption)
-		if caption != "" {
-			return fmt.Sprintf("%s[%s](%s)\n\n", indent, caption, url)
-		}
-		return fmt.Sprintf("%s<%s>\n\n", indent, url)
+		return t.bookmarkToMarkdown(b, indent)
 
 	case *notionapi.EmbedBlock:
 		return fmt.Sprintf("%s<%s>\n\n", indent, b.Embed.URL)
@@ -256,6 +320,14 @@ func (t *ReverseTransformer) blockToMarkdown(block notionapi.Block, depth int) s
 			url = b.File.External.URL
 		}
 		caption := t.richTextToMarkdown(b.File.Caption)
+		if b.File.File != nil {
+			if target, ok := t.saveAttachment(url, hostedFileName(url)); ok {
+				url = escapeLinkTarget(target)
+				if caption == "" {
+					return fmt.Sprintf("%s![[%s]]\n\n", indent, target)
+				}
+			}
+		}
 		if caption != "" {
 			return fmt.Sprintf("%s[%s](%s)\n\n", indent, caption, url)
 		}
@@ -268,23 +340,101 @@ func (t *ReverseTransformer) blockToMarkdown(block notionapi.Block, depth int) s
 		} else if b.Pdf.External != nil {
 			url = b.Pdf.External.URL
 		}
+		if b.Pdf.File != nil {
+			if target, ok := t.saveAttachment(url, hostedFileName(url)); ok {
+				return fmt.Sprintf("%s![[%s]]\n\n", indent, target)
+			}
+		}
 		return fmt.Sprintf("%s[PDF](%s)\n\n", indent, url)
 
+	case *notionapi.ChildDatabaseBlock:
+		// Nested databases are pulled as folders of notes, not as part of
+		// the page's note.
+		return ""
+
 	default:
 		// Unknown block type, return HTML comment for transparency.
 		return fmt.Sprintf("%s<!-- Unsupported Notion block type: %T -->\n\n", indent, block)
 	}
 }
 
-// transformChildren recursively transforms child blocks.
+// transformChildren recursively transforms child blocks. A block that is
+// not a list item gets a blank line after a list item, so it is not read
+// as a continuation of the item.
 func (t *ReverseTransformer) transformChildren(children []notionapi.Block, depth int) string {
 	if len(children) == 0 {
 		return ""
 	}
 
 	var result strings.Builder
+	var last notionapi.Block
 	for _, child := range children {
-		result.WriteString(t.blockToMarkdown(child, depth))
+		md := t.blockToMarkdown(child, depth)
+		if md == "" {
+			continue
+		}
+		if last != nil && isListItem(last) && !isListItem(child) && !strings.HasPrefix(md, "\n") {
+			result.WriteString("\n")
+		}
+		result.WriteString(md)
+		last = child
+	}
+	return result.String()
+}
+
+// itemChildren converts the children of a list item or task and indents
+// them to the item's content column, so they nest under it. Children that
+// are not list items start after a blank line, keeping them out of the
+// item's own text.
+func (t *ReverseTransformer) itemChildren(children []notionapi.Block, indent string) string {
+	md := t.transformChildren(children, 0)
+	if md == "" {
+		return ""
+	}
+	if !isListItem(children[0]) && !strings.HasPrefix(md, "\n") {
+		md = "\n" + md
+	}
+
+	var result strings.Builder
+	for _, line := range strings.SplitAfter(md, "\n") {
+		if strings.TrimSpace(line) != "" {
+			result.WriteString(indent)
+		}
+		result.WriteString(line)
+	}
+	return result.String()
+}
+
+// isListItem reports whether a block is written as a list item line.
+func isListItem(block notionapi.Block) bool {
+	switch block.(type) {
+	case *notionapi.BulletedListItemBlock, *notionapi.NumberedListItemBlock, *notionapi.ToDoBlock, *notionapi.ToggleBlock:
+		return true
+	}
+	return false
+}
+
+// endsBareLine reports whether markdown ends in a line not followed by a
+// blank line.
+func endsBareLine(md string) bool {
+	return strings.HasSuffix(md, "\n") && !strings.HasSuffix(md, "\n\n")
+}
+
+// quoteChildren prefixes the markdown of a quote's or callout's children
+// with > so they stay inside it. Blank lines between children become bare
+// > lines, and trailing ones are dropped.
+func quoteChildren(indent, childMd string) string {
+	childMd = strings.TrimRight(childMd, "\n")
+	if childMd == "" {
+		return ""
+	}
+	var result strings.Builder
+	for _, line := range strings.Split(childMd, "\n") {
+		if line == "" {
+			result.WriteString(indent + ">\n")
+		} else {
+			result.WriteString(indent + "> " + line + "\n")
+		}
 	}
 	return result.String()
 }
@@ -372,16 +522,29 @@ func (t *ReverseTransformer) richTextToMarkdown(richText []notionapi.RichText) s
 			}
 			// Handle date mentions.
 			if rt.Mention.Type == "date" && rt.Mention.Date != nil {
-				result.WriteString(rt.PlainText)
+				result.WriteString(t.dateMentionToMarkdown(rt.Mention.Date, rt.PlainText))
 				continue
 			}
 			// Handle user mentions.
 			if rt.Mention.Type == "user" && rt.Mention.User != nil {
-				result.WriteString("@" + rt.PlainText)
+				result.WriteString("@" + t.userMentionName(rt.Mention.User, rt.PlainText))
+				continue
+			}
+		}
+
+		// Handle links to headings of synced pages (convert back to
+		// [[note#heading]] wiki-links).
+		if rt.Text != nil && rt.Text.Link != nil {
+			if link, ok := t.headingWikiLink(rt.Text.Link.Url, rt.PlainText); ok {
+				result.WriteString(link)
 				continue
 			}
 		}
 
+		if t.config.EmojiReverse && (rt.Annotations == nil || !rt.Annotations.Code) {
+			text = CollapseEmoji(text)
+		}
+
 		// Apply annotations in the correct order.
 		// Order matters: innermost first, then outer wrappers.
 		if rt.Annotations != nil {
@@ -394,6 +557,9 @@ func (t *ReverseTransformer) richTextToMarkdown(richText []notionapi.RichText) s
 			if rt.Annotations.Code {
 				text = "`" + text + "`"
 			}
+			if rt.Annotations.Color != notionapi.ColorYellowBackground && !placeholderColor(rt) {
+				text = t.colorText(text, rt.Annotations.Color)
+			}
 			if rt.Annotations.Strikethrough {
 				text = "~~" + text + "~~"
 			}
@@ -416,17 +582,123 @@ func (t *ReverseTransformer) richTextToMarkdown(richText []notionapi.RichText) s
 		result.WriteString(text)
 	}
 
-	return result.String()
+	return escapeLinkDefinitions(result.String())
+}
+
+// headingWikiLink converts a Notion link to a heading of a synced page
+// into a wiki-link. The heading is recovered from the "Note > Heading"
+// text push writes for it, or from the anchor when the text is an alias.
+func (t *ReverseTransformer) headingWikiLink(rawURL, text string) (string, bool) {
+	if t.pathLookup == nil {
+		return "", false
+	}
+	pageID, slug, ok := parseNotionHeadingURL(rawURL)
+	if !ok {
+		return "", false
+	}
+	path, found := t.pathLookup.LookupPath(pageID)
+	if !found {
+		return "", false
+	}
+	var shown string
+	if i := strings.LastIndex(text, " > "); i != -1 {
+		shown = text[i+3:]
+	}
+	heading := headingFromSlug(slug, shown)
+
+	link := path + "#" + heading
+	if text != "" && (shown == "" || NotionSlug(shown) != slug) {
+		link += "|" + text
+	}
+	return "[[" + link + "]]", true
+}
+
+// saveAttachment saves a Notion-hosted file with the attachment saver, if
+// one is set.
+func (t *ReverseTransformer) saveAttachment(url, name string) (string, bool) {
+	if t.attachmentSaver == nil || url == "" || name == "" {
+		return "", false
+	}
+	return t.attachmentSaver.SaveAttachment(url, name)
+}
+
+// hostedFileName returns the file name in a Notion-hosted file URL, which
+// ends in the name the file was uploaded with.
+func hostedFileName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// escapeLinkTarget escapes spaces in a markdown link destination.
+func escapeLinkTarget(target string) string {
+	return strings.ReplaceAll(target, " ", "%20")
+}
+
+// linkDefinitionRE matches a line that markdown would read as a link
+// reference definition ("[label]: destination").
+var linkDefinitionRE = regexp.MustCompile(`(?m)^( {0,3})\[([^\]\n]+)\]:`)
+
+// escapeLinkDefinitions escapes text that would otherwise become a link
+// reference definition. Pulled links are always written inline, so such a
+// line is text typed in Notion; unescaped, it would vanish from the note
+// and capture any [label] elsewhere in it.
+func escapeLinkDefinitions(text string) string {
+	return linkDefinitionRE.ReplaceAllString(text, `$1\[$2]:`)
+}
+
+// sizedEmbed reports whether an image caption records a sized embed, as
+// written by push for ![[banner.png|800x200]], and returns the embed.
+func sizedEmbed(caption string) (string, bool) {
+	target, size, ok := strings.Cut(caption, "|")
+	if !ok || !isImageFile(target) || strings.ContainsAny(target, "[]") {
+		return "", false
+	}
+	if _, _, ok := parser.ParseImageSize(size); !ok {
+		return "", false
+	}
+	return caption, true
+}
+
+// primaryCalloutTypes are Obsidian's main callout types, which win over
+// their aliases when several types share an icon.
+var primaryCalloutTypes = []string{
+	"note", "abstract", "info", "todo", "tip", "success", "question",
+	"warning", "failure", "danger", "bug", "example", "quote",
+}
+
+// CalloutType returns the Obsidian callout type of a Notion callout's
+// icon, as callouts are pulled.
+func (t *ReverseTransformer) CalloutType(icon string) string {
+	return t.iconToCalloutType(icon)
 }
 
 // iconToCalloutType maps Notion icons back to Obsidian callout types.
+// When several types share the icon, primary types are preferred, then
+// the alphabetically first type, so the result is stable.
 func (t *ReverseTransformer) iconToCalloutType(icon string) string {
+	for _, calloutType := range primaryCalloutTypes {
+		if t.config.CalloutIcons[calloutType] == icon {
+			return calloutType
+		}
+	}
+
 	// Reverse lookup in callout icons map.
+	var match string
 	for calloutType, calloutIcon := range t.config.CalloutIcons {
-		if calloutIcon == icon {
-			return calloutType
+		if calloutIcon == icon && (match == "" || calloutType < match) {
+			match = calloutType
 		}
 	}
+	if match != "" {
+		return match
+	}
 	// Default to "note" if icon not found.
 	return "note"
 }
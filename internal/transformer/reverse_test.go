@@ -1,10 +1,13 @@
 package transformer
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
 )
 
 // mockPathLookup is a test double for path lookup.
@@ -233,6 +236,65 @@ func TestTransform_ToDo(t *testing.T) {
 	}
 }
 
+func TestTransform_ToDoChildren(t *testing.T) {
+	rt := NewReverse(nil, nil)
+
+	text := func(s string) []notionapi.RichText {
+		return []notionapi.RichText{{PlainText: s}}
+	}
+	blocks := []notionapi.Block{
+		&notionapi.ToDoBlock{
+			ToDo: notionapi.ToDo{
+				RichText: text("Parent"),
+				Children: notionapi.Blocks{
+					&notionapi.ToDoBlock{ToDo: notionapi.ToDo{RichText: text("Sub-task"), Checked: true}},
+					&notionapi.ParagraphBlock{Paragraph: notionapi.Paragraph{RichText: text("Notes")}},
+					&notionapi.CodeBlock{Code: notionapi.Code{RichText: text("make"), Language: "bash"}},
+				},
+			},
+		},
+		&notionapi.NumberedListItemBlock{
+			NumberedListItem: notionapi.ListItem{
+				RichText: text("Step"),
+				Children: notionapi.Blocks{
+					&notionapi.ToDoBlock{ToDo: notionapi.ToDo{RichText: text("Task")}},
+				},
+			},
+		},
+	}
+
+	result, err := rt.Transform(blocks)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	expected := "- [ ] Parent\n  - [x] Sub-task\n\n  Notes\n\n  ```bash\n  make\n  ```\n\n1. Step\n   - [ ] Task\n"
+	if result != expected {
+		t.Errorf("Transform() = %q, want %q", result, expected)
+	}
+
+	// Pushing the pulled tasks gives back the same hierarchy.
+	note, err := parser.New().Parse("test.md", []byte(result))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	page, err := New(nil, nil).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	if len(page.Children) != 2 {
+		t.Fatalf("round trip gave %d blocks, want 2", len(page.Children))
+	}
+	parent, ok := page.Children[0].(*notionapi.ToDoBlock)
+	if !ok || len(parent.ToDo.Children) != 3 {
+		t.Fatalf("round trip parent = %#v, want a to_do with 3 children", page.Children[0])
+	}
+	step, ok := page.Children[1].(*notionapi.NumberedListItemBlock)
+	if !ok || len(step.NumberedListItem.Children) != 1 {
+		t.Errorf("round trip step = %#v, want a numbered item with 1 child", page.Children[1])
+	}
+}
+
 func TestTransform_Quote(t *testing.T) {
 	rt := NewReverse(nil, nil)
 
@@ -663,6 +725,27 @@ func TestTransformRichText_Link(t *testing.T) {
 	}
 }
 
+func TestTransformRichText_LinkDefinitionEscaped(t *testing.T) {
+	rt := NewReverse(nil, nil)
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"[docs]: https://example.com", `\[docs]: https://example.com`},
+		{"intro\n  [ref]: other", "intro\n  \\[ref]: other"},
+		{"see [docs]: not at line start", "see [docs]: not at line start"},
+		{"[[Note]]: a wiki-link", "[[Note]]: a wiki-link"},
+		{"[docs] without a colon", "[docs] without a colon"},
+	}
+	for _, tt := range tests {
+		richText := []notionapi.RichText{{PlainText: tt.text, Text: &notionapi.Text{Content: tt.text}}}
+		if got := rt.TransformRichText(richText); got != tt.want {
+			t.Errorf("TransformRichText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
 func TestTransformRichText_PageMention_Resolved(t *testing.T) {
 	lookup := &mockPathLookup{
 		paths: map[string]string{
@@ -1039,7 +1122,7 @@ func TestTransform_Bookmark(t *testing.T) {
 					},
 				},
 			},
-			expected: "[Example Site](https://example.com)\n\n",
+			expected: "[Example Site](https://example.com) {bookmark}\n\n",
 		},
 		{
 			name: "without caption",
@@ -1048,7 +1131,7 @@ func TestTransform_Bookmark(t *testing.T) {
 					URL: "https://example.com",
 				},
 			},
-			expected: "<https://example.com>\n\n",
+			expected: "<https://example.com> {bookmark}\n\n",
 		},
 	}
 
@@ -1201,3 +1284,270 @@ func TestTransform_UnknownBlockTypeWithIndent(t *testing.T) {
 		t.Errorf("Expected unsupported block comment, got %q", result)
 	}
 }
+
+func TestNotionToMarkdown_ReassemblesSections(t *testing.T) {
+	rt := NewReverse(nil, nil)
+	paragraph := func(text string) notionapi.Block {
+		return &notionapi.ParagraphBlock{
+			Paragraph: notionapi.Paragraph{
+				RichText: []notionapi.RichText{{PlainText: text}},
+			},
+		}
+	}
+
+	page := &NotionPage{
+		Children: []notionapi.Block{paragraph("Intro.")},
+		Sections: []*PageSection{
+			{Title: "First", Children: []notionapi.Block{paragraph("Body one.")}},
+			{Title: "Second", Children: []notionapi.Block{paragraph("Body two.")}},
+		},
+	}
+
+	md, err := rt.NotionToMarkdown(page)
+	if err != nil {
+		t.Fatalf("NotionToMarkdown() error: %v", err)
+	}
+
+	want := "Intro.\n\n# First\n\nBody one.\n\n# Second\n\nBody two.\n\n"
+	if string(md) != want {
+		t.Errorf("NotionToMarkdown() =\n%q\nwant\n%q", md, want)
+	}
+}
+
+func TestBlockToMarkdown_SizedEmbed(t *testing.T) {
+	rt := NewReverse(nil, nil)
+	image := func(file bool, caption string) *notionapi.ImageBlock {
+		b := &notionapi.ImageBlock{
+			BasicBlock: notionapi.BasicBlock{Type: notionapi.BlockTypeImage},
+			Image: notionapi.Image{
+				Caption: []notionapi.RichText{{PlainText: caption, Text: &notionapi.Text{Content: caption}}},
+			},
+		}
+		if file {
+			b.Image.File = &notionapi.FileObject{URL: "https://files.example.com/banner.png"}
+		} else {
+			b.Image.External = &notionapi.FileObject{URL: "https://example.com/banner.png"}
+		}
+		return b
+	}
+
+	tests := []struct {
+		name  string
+		block *notionapi.ImageBlock
+		want  string
+	}{
+		{"sized upload", image(true, "banner.png|800x200"), "![[banner.png|800x200]]\n\n"},
+		{"width only", image(true, "banner.png|800"), "![[banner.png|800]]\n\n"},
+		{"plain caption", image(true, "A banner"), "![A banner](https://files.example.com/banner.png)\n\n"},
+		{"external image", image(false, "banner.png|800"), "![banner.png|800](https://example.com/banner.png)\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rt.blockToMarkdown(tt.block, 0); got != tt.want {
+				t.Errorf("blockToMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// mockAttachmentSaver records the files it is asked to save.
+type mockAttachmentSaver struct {
+	saved map[string]string // url -> name
+}
+
+func (m *mockAttachmentSaver) SaveAttachment(url, name string) (string, bool) {
+	if strings.Contains(url, "fail") {
+		return "", false
+	}
+	if m.saved == nil {
+		m.saved = make(map[string]string)
+	}
+	m.saved[url] = name
+	return "assets/" + name, true
+}
+
+func TestBlockToMarkdown_SavedAttachments(t *testing.T) {
+	saver := &mockAttachmentSaver{}
+	rt := NewReverse(nil, nil)
+	rt.SetAttachmentSaver(saver)
+
+	caption := func(text string) []notionapi.RichText {
+		if text == "" {
+			return nil
+		}
+		return []notionapi.RichText{{PlainText: text, Text: &notionapi.Text{Content: text}}}
+	}
+	image := func(url, text string) notionapi.Block {
+		return &notionapi.ImageBlock{
+			BasicBlock: notionapi.BasicBlock{Type: notionapi.BlockTypeImage},
+			Image:      notionapi.Image{File: &notionapi.FileObject{URL: url}, Caption: caption(text)},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		block notionapi.Block
+		want  string
+	}{
+		{"image", image("https://files.example.com/a/photo.png?X-Amz=1", ""), "![[assets/photo.png]]\n\n"},
+		{"sized image", image("https://files.example.com/a/upload.png", "banner.png|800x200"), "![[assets/banner.png|800x200]]\n\n"},
+		{"captioned image", image("https://files.example.com/a/my%20photo.png", "A photo"), "![A photo](assets/my%20photo.png)\n\n"},
+		{"download failed", image("https://files.example.com/fail/x.png", ""), "![](https://files.example.com/fail/x.png)\n\n"},
+		{"pushed upload", newFileUploadImageBlock("upload-1", caption("banner.png|800x200")), "![[assets/banner.png|800x200]]\n\n"},
+		{"external image", &notionapi.ImageBlock{
+			BasicBlock: notionapi.BasicBlock{Type: notionapi.BlockTypeImage},
+			Image:      notionapi.Image{External: &notionapi.FileObject{URL: "https://example.com/x.png"}},
+		}, "![](https://example.com/x.png)\n\n"},
+		{"pdf", &notionapi.PdfBlock{
+			BasicBlock: notionapi.BasicBlock{Type: notionapi.BlockTypePdf},
+			Pdf:        notionapi.Pdf{File: &notionapi.FileObject{URL: "https://files.example.com/a/report.pdf"}},
+		}, "![[assets/report.pdf]]\n\n"},
+		{"file with caption", &notionapi.FileBlock{
+			BasicBlock: notionapi.BasicBlock{Type: notionapi.BlockTypeFile},
+			File:       notionapi.BlockFile{File: &notionapi.FileObject{URL: "https://files.example.com/a/data.csv"}, Caption: caption("Data")},
+		}, "[Data](assets/data.csv)\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rt.blockToMarkdown(tt.block, 0); got != tt.want {
+				t.Errorf("blockToMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if name := saver.saved["https://files.example.com/a/upload.png"]; name != "banner.png" {
+		t.Errorf("sized image saved as %q, want the embed name banner.png", name)
+	}
+	if name := saver.saved["https://files.example.com/a/my%20photo.png"]; name != "my photo.png" {
+		t.Errorf("captioned image saved as %q, want the unescaped URL name", name)
+	}
+}
+
+func TestNotionToMarkdown_KeepsNoteTargets(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.NoteTargets = []string{"docs", "tasks"}
+	md, err := NewReverse(nil, cfg).NotionToMarkdown(&NotionPage{})
+	if err != nil {
+		t.Fatalf("NotionToMarkdown() error: %v", err)
+	}
+	if want := "notion-targets: [docs, tasks]\n"; !strings.Contains(string(md), want) {
+		t.Errorf("expected %q, got:\n%s", want, md)
+	}
+
+	// Pushing the pulled note finds the same targets.
+	note, err := parser.New().Parse("a.md", md)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if got := note.Frontmatter[TargetsKey]; !reflect.DeepEqual(got, []any{"docs", "tasks"}) {
+		t.Errorf("parsed notion-targets = %#v", got)
+	}
+}
+
+func TestNotionToMarkdown_RestoresUnmappedFrontmatter(t *testing.T) {
+	note, err := parser.New().Parse("a.md", []byte("---\ntitle: Plan\ncssclasses:\n  - wide\npublished: 2024-01-02\nstatus: draft\n---\n\nBody.\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	page, err := New(nil, nil).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	if len(page.Children) != 2 {
+		t.Fatalf("got %d blocks, want the body and the frontmatter block", len(page.Children))
+	}
+	code, ok := page.Children[1].(*notionapi.CodeBlock)
+	if !ok {
+		t.Fatalf("last block = %T, want a code block", page.Children[1])
+	}
+	if got, want := plainText(code.Code.RichText), "cssclasses:\n  - wide\npublished: 2024-01-02\nstatus: draft"; got != want {
+		t.Errorf("frontmatter block = %q, want %q", got, want)
+	}
+
+	// A property of the same name wins over the kept key.
+	pulled := &NotionPage{
+		Properties: notionapi.Properties{
+			"Name":   &notionapi.TitleProperty{Title: []notionapi.RichText{{PlainText: "Plan"}}},
+			"Status": &notionapi.RichTextProperty{RichText: []notionapi.RichText{{PlainText: "done"}}},
+		},
+		Children: []notionapi.Block{
+			&notionapi.ParagraphBlock{Paragraph: notionapi.Paragraph{RichText: []notionapi.RichText{{PlainText: "Body."}}}},
+			&notionapi.CodeBlock{Code: notionapi.Code{
+				Language: "yaml",
+				RichText: []notionapi.RichText{{PlainText: plainText(code.Code.RichText)}},
+				Caption:  []notionapi.RichText{{PlainText: FrontmatterMetaCaption}},
+			}},
+		},
+	}
+	md, err := NewReverse(nil, nil).NotionToMarkdown(pulled)
+	if err != nil {
+		t.Fatalf("NotionToMarkdown() error: %v", err)
+	}
+	want := "---\ncssclasses:\n  - wide\npublished: 2024-01-02\nstatus: done\ntitle: Plan\n---\n\nBody.\n\n"
+	if string(md) != want {
+		t.Errorf("NotionToMarkdown() = %q, want %q", md, want)
+	}
+
+	// Passed-through keys are properties, not kept in the block.
+	cfg := DefaultConfig()
+	cfg.UnmappedProperties = UnmappedPassthrough
+	page, err = New(nil, cfg).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	code = page.Children[len(page.Children)-1].(*notionapi.CodeBlock)
+	if got := plainText(code.Code.RichText); got != "cssclasses:\n  - wide" {
+		t.Errorf("passthrough frontmatter block = %q", got)
+	}
+}
+
+func TestNotionToMarkdown_KeepsFrontmatterLayout(t *testing.T) {
+	local := "---\n" +
+		"title: \"Plan\"\n" +
+		"tags:\n  - alpha\n  - beta\n" +
+		"status: 'draft' # set by hand\n" +
+		"estimate: 3.0\n" +
+		"---\n\nBody.\n"
+	cfg := DefaultConfig()
+	cfg.FrontmatterSource = []byte(local)
+	pulled := &NotionPage{
+		Properties: notionapi.Properties{
+			"Name":     &notionapi.TitleProperty{Title: []notionapi.RichText{{PlainText: "Plan"}}},
+			"Tags":     &notionapi.MultiSelectProperty{MultiSelect: []notionapi.Option{{Name: "alpha"}, {Name: "gamma"}}},
+			"Status":   &notionapi.RichTextProperty{RichText: []notionapi.RichText{{PlainText: "done"}}},
+			"Estimate": &notionapi.NumberProperty{Number: 3},
+			"Owner":    &notionapi.RichTextProperty{RichText: []notionapi.RichText{{PlainText: "true"}}},
+			"Due":      &notionapi.RichTextProperty{RichText: []notionapi.RichText{{PlainText: "2024-01-02"}}},
+		},
+		Children: []notionapi.Block{
+			&notionapi.ParagraphBlock{Paragraph: notionapi.Paragraph{RichText: []notionapi.RichText{{PlainText: "Body."}}}},
+		},
+	}
+	md, err := NewReverse(nil, cfg).NotionToMarkdown(pulled)
+	if err != nil {
+		t.Fatalf("NotionToMarkdown() error: %v", err)
+	}
+	// Local keys keep their order and style; new keys follow, sorted and
+	// quoted only where needed.
+	want := "---\n" +
+		"title: \"Plan\"\n" +
+		"tags:\n  - alpha\n  - gamma\n" +
+		"status: 'done'\n" +
+		"estimate: 3.0\n" +
+		"due: 2024-01-02\n" +
+		"owner: \"true\"\n" +
+		"---\n\nBody.\n\n"
+	if string(md) != want {
+		t.Errorf("NotionToMarkdown() = %q, want %q", md, want)
+	}
+
+	// Pulling again into the written note changes nothing.
+	cfg.FrontmatterSource = md
+	again, err := NewReverse(nil, cfg).NotionToMarkdown(pulled)
+	if err != nil {
+		t.Fatalf("NotionToMarkdown() error: %v", err)
+	}
+	if string(again) != string(md) {
+		t.Errorf("second pull = %q, want %q", again, md)
+	}
+}
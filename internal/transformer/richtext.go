@@ -8,19 +8,87 @@ import (
 	"github.com/yuin/goldmark/ast"
 	extast "github.com/yuin/goldmark/extension/ast"
 	"go.abhg.dev/goldmark/wikilink"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
 )
 
 // transformInlineContent converts all inline children of a node to rich text.
 func (t *Transformer) transformInlineContent(n ast.Node, source []byte) []notionapi.RichText {
 	var result []notionapi.RichText
 
+	html := newInlineHTML(nil)
 	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
-		result = append(result, t.transformInline(child, source, nil)...)
+		if raw, ok := child.(*ast.RawHTML); ok {
+			if rt, handled := t.transformInlineHTML(html, raw, source); handled {
+				result = append(result, rt...)
+				continue
+			}
+		}
+		result = append(result, t.transformInline(child, source, html.current())...)
+	}
+
+	return t.expandRuns(result)
+}
+
+// expandRuns expands the emoji shortcodes, user mentions, and date markers
+// in the text runs of a block's rich text.
+func (t *Transformer) expandRuns(result []notionapi.RichText) []notionapi.RichText {
+	if t.config.EmojiShortcodes {
+		result = expandEmojiRuns(result)
+	}
+	if len(t.config.UserMentions) > 0 {
+		result = t.expandUserMentions(result)
 	}
+	return expandDateMarkers(result)
+}
 
+// expandEmojiRuns converts :shortcode: sequences in plain text runs to emoji.
+// Code and links are left alone.
+func expandEmojiRuns(richText []notionapi.RichText) []notionapi.RichText {
+	result := mergePlainRuns(richText)
+	for i := range result {
+		if isPlainRun(result[i]) {
+			result[i].Text = &notionapi.Text{Content: ExpandShortcodes(result[i].Text.Content)}
+		}
+	}
 	return result
 }
 
+// isPlainRun reports whether a rich text run is text outside code and
+// links.
+func isPlainRun(rt notionapi.RichText) bool {
+	return rt.Type == notionapi.ObjectTypeText && rt.Text != nil && rt.Text.Link == nil &&
+		(rt.Annotations == nil || !rt.Annotations.Code)
+}
+
+// mergePlainRuns joins adjacent plain text runs with the same formatting.
+// The parser splits text at characters like "_", so runs are merged to see
+// whole shortcodes and names.
+func mergePlainRuns(richText []notionapi.RichText) []notionapi.RichText {
+	var result []notionapi.RichText
+	for _, rt := range richText {
+		if n := len(result); n > 0 && isPlainRun(rt) && isPlainRun(result[n-1]) && sameAnnotations(rt.Annotations, result[n-1].Annotations) {
+			prev := &result[n-1]
+			prev.Text = &notionapi.Text{Content: prev.Text.Content + rt.Text.Content}
+			continue
+		}
+		result = append(result, rt)
+	}
+	return result
+}
+
+// sameAnnotations reports whether two annotations format text identically.
+func sameAnnotations(a, b *notionapi.Annotations) bool {
+	var za, zb notionapi.Annotations
+	if a != nil {
+		za = *a
+	}
+	if b != nil {
+		zb = *b
+	}
+	return za == zb
+}
+
 // transformInline converts a single inline node to rich text.
 // Annotations are inherited from parent formatting contexts.
 func (t *Transformer) transformInline(n ast.Node, source []byte, inherited *notionapi.Annotations) []notionapi.RichText {
@@ -55,6 +123,9 @@ func (t *Transformer) transformInline(n ast.Node, source []byte, inherited *noti
 
 	case *ast.CodeSpan:
 		content := string(node.Text(source))
+		if rt, ok := customInline(content, inherited); ok {
+			return rt
+		}
 		// Check for inline dataview query: `=expression`
 		if strings.HasPrefix(content, "=") {
 			return t.transformInlineDataview(content[1:], inherited)
@@ -70,27 +141,30 @@ func (t *Transformer) transformInline(n ast.Node, source []byte, inherited *noti
 		}
 
 	case *ast.Link:
-		// Get link text.
-		var textContent string
-		for child := node.FirstChild(); child != nil; child = child.NextSibling() {
-			if t, ok := child.(*ast.Text); ok {
-				textContent += string(t.Segment.Value(source))
+		// Inline and reference links ([text][ref], [ref]) look the same
+		// here: the parser has already resolved the reference definition.
+		// The link text keeps its formatting, with the URL on every run.
+		url := string(node.Destination)
+		var result []notionapi.RichText
+		for _, rt := range t.transformInlineChildren(node, source, inherited) {
+			if rt.Type == notionapi.ObjectTypeText && rt.Text != nil && rt.Text.Link == nil {
+				rt.Text = &notionapi.Text{Content: rt.Text.Content, Link: &notionapi.Link{Url: url}}
 			}
+			result = append(result, rt)
 		}
-		if textContent == "" {
-			textContent = string(node.Destination)
-		}
-
-		return []notionapi.RichText{
-			{
-				Type: notionapi.ObjectTypeText,
-				Text: &notionapi.Text{
-					Content: textContent,
-					Link:    &notionapi.Link{Url: string(node.Destination)},
+		if len(result) == 0 {
+			result = []notionapi.RichText{
+				{
+					Type: notionapi.ObjectTypeText,
+					Text: &notionapi.Text{
+						Content: url,
+						Link:    &notionapi.Link{Url: url},
+					},
+					Annotations: copyAnnotations(inherited),
 				},
-				Annotations: copyAnnotations(inherited),
-			},
+			}
 		}
+		return result
 
 	case *ast.AutoLink:
 		url := string(node.URL(source))
@@ -152,6 +226,15 @@ func (t *Transformer) transformInline(n ast.Node, source []byte, inherited *noti
 			return t.transformWikiLinkEmbed(target, alias, inherited)
 		}
 
+		// Heading link: [[target#heading]]. Block references (#^id) have no
+		// Notion anchor and link to the page itself.
+		if heading := string(node.Fragment); heading != "" && !strings.HasPrefix(heading, "^") {
+			if alias == target+"#"+heading {
+				alias = ""
+			}
+			return t.transformHeadingLink(target, heading, alias, inherited)
+		}
+
 		// Regular wiki-link: [[target]] or [[target|alias]]
 		return t.transformWikiLink(target, alias, inherited)
 
@@ -200,8 +283,15 @@ func (t *Transformer) transformText(text *ast.Text, source []byte, annotations *
 func (t *Transformer) transformInlineChildren(n ast.Node, source []byte, annotations *notionapi.Annotations) []notionapi.RichText {
 	var result []notionapi.RichText
 
+	html := newInlineHTML(annotations)
 	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
-		result = append(result, t.transformInline(child, source, annotations)...)
+		if raw, ok := child.(*ast.RawHTML); ok {
+			if rt, handled := t.transformInlineHTML(html, raw, source); handled {
+				result = append(result, rt...)
+				continue
+			}
+		}
+		result = append(result, t.transformInline(child, source, html.current())...)
 	}
 
 	return result
@@ -215,7 +305,16 @@ var highlightRegex = regexp.MustCompile(`==([^=]+)==`)
 func (t *Transformer) transformInlineChildrenWithHighlight(n ast.Node, source []byte, annotations *notionapi.Annotations) []notionapi.RichText {
 	var result []notionapi.RichText
 
+	html := newInlineHTML(annotations)
 	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		annotations := html.current()
+		if raw, ok := child.(*ast.RawHTML); ok {
+			if rt, handled := t.transformInlineHTML(html, raw, source); handled {
+				result = append(result, rt...)
+				continue
+			}
+		}
+
 		// For text nodes, check for highlight patterns.
 		if txt, ok := child.(*ast.Text); ok {
 			content := string(txt.Segment.Value(source))
@@ -356,6 +455,31 @@ func (t *Transformer) transformWikiLink(target, alias string, annotations *notio
 	}
 }
 
+// transformHeadingLink converts a wiki-link to a heading into a link to
+// the heading's anchor on the target page, shown the way Obsidian shows
+// it. Unresolved heading links fall back to the unresolved link style.
+func (t *Transformer) transformHeadingLink(target, heading, alias string, annotations *notionapi.Annotations) []notionapi.RichText {
+	if t.linkResolver != nil {
+		if pageID, found := t.linkResolver.Resolve(target); found {
+			display := alias
+			if display == "" {
+				display = target + " > " + heading
+			}
+			return []notionapi.RichText{
+				{
+					Type: notionapi.ObjectTypeText,
+					Text: &notionapi.Text{
+						Content: display,
+						Link:    &notionapi.Link{Url: notionHeadingURL(pageID, heading)},
+					},
+					Annotations: copyAnnotations(annotations),
+				},
+			}
+		}
+	}
+	return t.transformWikiLink(target+"#"+heading, alias, annotations)
+}
+
 // copyAnnotations creates a copy of annotations to avoid mutation.
 func copyAnnotations(a *notionapi.Annotations) *notionapi.Annotations {
 	if a == nil {
@@ -425,11 +549,9 @@ func isImageFile(filename string) bool {
 // Since we can't create block-level images in inline context, we create a placeholder.
 func (t *Transformer) transformWikiLinkImage(target, alias string, annotations *notionapi.Annotations) []notionapi.RichText {
 	displayText := target
-	if alias != "" {
-		// Alias might contain dimensions like "300x200", ignore for display.
-		if !strings.Contains(alias, "x") && !strings.ContainsAny(alias, "0123456789") {
-			displayText = alias
-		}
+	// The alias might be a size like "300x200" rather than a display name.
+	if _, _, isSize := parser.ParseImageSize(alias); alias != "" && !isSize {
+		displayText = alias
 	}
 
 	// Create an inline image placeholder.
@@ -0,0 +1,107 @@
+package transformer
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// notionPageURL is the base of the page URLs push writes for heading links.
+const notionPageURL = "https://www.notion.so/"
+
+// obsidianLinkUnsafe are the characters Obsidian will not keep in the
+// heading part of a wiki-link. It replaces them with spaces when it
+// completes a link to a heading that contains them.
+const obsidianLinkUnsafe = "#|^:%[]"
+
+// ObsidianSlug returns the form of a heading Obsidian uses after the # in
+// a wiki-link: characters that would end or break the link become spaces
+// and runs of whitespace collapse to one space.
+func ObsidianSlug(heading string) string {
+	mapped := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(obsidianLinkUnsafe, r) {
+			return ' '
+		}
+		return r
+	}, heading)
+	return strings.Join(strings.Fields(mapped), " ")
+}
+
+// NotionSlug returns the anchor used for a heading in Notion link URLs:
+// lowercase letters and digits separated by single hyphens. Punctuation is
+// dropped, so a heading and its ObsidianSlug share the same NotionSlug.
+func NotionSlug(heading string) string {
+	var sb strings.Builder
+	pendingHyphen := false
+	for _, r := range heading {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if pendingHyphen && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			pendingHyphen = false
+			sb.WriteRune(unicode.ToLower(r))
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			pendingHyphen = true
+		}
+	}
+	return sb.String()
+}
+
+// headingFromSlug recovers the Obsidian heading for a Notion anchor. The
+// candidates are headings the anchor may have been made from; the first
+// whose NotionSlug matches wins. Otherwise the slug's words are used as
+// the heading, which Obsidian still resolves when they match the original
+// heading up to case and punctuation.
+func headingFromSlug(slug string, candidates ...string) string {
+	for _, c := range candidates {
+		if c != "" && NotionSlug(c) == slug {
+			return ObsidianSlug(c)
+		}
+	}
+	return strings.ReplaceAll(slug, "-", " ")
+}
+
+// notionHeadingURL returns the Notion URL linking to a heading of a page.
+func notionHeadingURL(pageID, heading string) string {
+	return notionPageURL + strings.ReplaceAll(pageID, "-", "") + "#" + NotionSlug(heading)
+}
+
+// parseNotionHeadingURL extracts the page ID and heading anchor from a
+// Notion page URL such as https://www.notion.so/Title-<id>#anchor. The
+// page ID is returned in the dashed form the API uses. Anchors that are
+// block IDs are not heading slugs and are rejected.
+func parseNotionHeadingURL(rawURL string) (pageID, slug string, ok bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Fragment == "" {
+		return "", "", false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host != "notion.so" && host != "www.notion.so" && !strings.HasSuffix(host, ".notion.site") {
+		return "", "", false
+	}
+	if isHexID(strings.ReplaceAll(u.Fragment, "-", "")) {
+		return "", "", false
+	}
+
+	segment := u.Path[strings.LastIndex(u.Path, "/")+1:]
+	if len(segment) < 32 || !isHexID(segment[len(segment)-32:]) {
+		return "", "", false
+	}
+	id := strings.ToLower(segment[len(segment)-32:])
+	pageID = id[0:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:]
+	return pageID, u.Fragment, true
+}
+
+// isHexID reports whether s is a 32-character hexadecimal Notion ID.
+func isHexID(s string) bool {
+	if len(s) != 32 {
+		return false
+	}
+	for _, c := range s {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package transformer
+
+import (
+	"testing"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+func TestSlugs(t *testing.T) {
+	tests := []struct {
+		heading  string
+		obsidian string
+		notion   string
+	}{
+		{"Getting Started", "Getting Started", "getting-started"},
+		{"Step 1: Install", "Step 1 Install", "step-1-install"},
+		{"What's new?", "What's new?", "whats-new"},
+		{"A | B ^ C", "A B C", "a-b-c"},
+		{"  snake_case -- words  ", "snake_case -- words", "snake-case-words"},
+		{"Café Übersicht", "Café Übersicht", "café-übersicht"},
+		{"[[Link]] #tag", "Link tag", "link-tag"},
+	}
+	for _, tt := range tests {
+		if got := ObsidianSlug(tt.heading); got != tt.obsidian {
+			t.Errorf("ObsidianSlug(%q) = %q, want %q", tt.heading, got, tt.obsidian)
+		}
+		if got := NotionSlug(tt.heading); got != tt.notion {
+			t.Errorf("NotionSlug(%q) = %q, want %q", tt.heading, got, tt.notion)
+		}
+		// Obsidian's form of a heading must anchor the same Notion heading.
+		if got := NotionSlug(ObsidianSlug(tt.heading)); got != tt.notion {
+			t.Errorf("NotionSlug(ObsidianSlug(%q)) = %q, want %q", tt.heading, got, tt.notion)
+		}
+		if got := headingFromSlug(tt.notion, tt.heading); got != tt.obsidian {
+			t.Errorf("headingFromSlug(%q, %q) = %q, want %q", tt.notion, tt.heading, got, tt.obsidian)
+		}
+	}
+}
+
+func TestParseNotionHeadingURL(t *testing.T) {
+	tests := []struct {
+		url    string
+		pageID string
+		slug   string
+		ok     bool
+	}{
+		{"https://www.notion.so/0123456789abcdef0123456789abcdef#setup", "01234567-89ab-cdef-0123-456789abcdef", "setup", true},
+		{"https://notion.so/ws/My-Page-0123456789ABCDEF0123456789ABCDEF#a-b", "01234567-89ab-cdef-0123-456789abcdef", "a-b", true},
+		{"https://team.notion.site/0123456789abcdef0123456789abcdef#x", "01234567-89ab-cdef-0123-456789abcdef", "x", true},
+		{"https://www.notion.so/0123456789abcdef0123456789abcdef", "", "", false},
+		{"https://www.notion.so/0123456789abcdef0123456789abcdef#fedcba9876543210fedcba9876543210", "", "", false},
+		{"https://example.com/0123456789abcdef0123456789abcdef#setup", "", "", false},
+		{"https://www.notion.so/not-a-page#setup", "", "", false},
+	}
+	for _, tt := range tests {
+		pageID, slug, ok := parseNotionHeadingURL(tt.url)
+		if pageID != tt.pageID || slug != tt.slug || ok != tt.ok {
+			t.Errorf("parseNotionHeadingURL(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.url, pageID, slug, ok, tt.pageID, tt.slug, tt.ok)
+		}
+	}
+}
+
+func TestHeadingLinks_RoundTrip(t *testing.T) {
+	const pageID = "01234567-89ab-cdef-0123-456789abcdef"
+	resolver := &mockLinkResolver{links: map[string]string{"Other": pageID}}
+	lookup := &mockPathLookup{paths: map[string]string{pageID: "Other.md"}}
+
+	tests := []struct {
+		markdown string
+		url      string
+		want     string
+	}{
+		{
+			markdown: "See [[Other#Getting Started]]\n",
+			url:      "https://www.notion.so/0123456789abcdef0123456789abcdef#getting-started",
+			want:     "See [[Other.md#Getting Started]]",
+		},
+		{
+			markdown: "See [[Other#Step 1 Install|the install step]]\n",
+			url:      "https://www.notion.so/0123456789abcdef0123456789abcdef#step-1-install",
+			want:     "See [[Other.md#step 1 install|the install step]]",
+		},
+	}
+	for _, tt := range tests {
+		note, err := parser.New().Parse("test.md", []byte(tt.markdown))
+		if err != nil {
+			t.Fatalf("Parse() error: %v", err)
+		}
+		page, err := New(resolver, nil).Transform(note)
+		if err != nil {
+			t.Fatalf("Transform() error: %v", err)
+		}
+		para, ok := page.Children[0].(*notionapi.ParagraphBlock)
+		if !ok {
+			t.Fatalf("expected paragraph, got %T", page.Children[0])
+		}
+		richText := para.Paragraph.RichText
+		link := richText[len(richText)-1]
+		if link.Text == nil || link.Text.Link == nil || link.Text.Link.Url != tt.url {
+			t.Fatalf("%q: heading link = %+v, want URL %q", tt.markdown, link.Text, tt.url)
+		}
+
+		// Notion fills in the plain text of each rich text object.
+		for i := range richText {
+			richText[i].PlainText = richText[i].Text.Content
+		}
+		if got := NewReverse(lookup, nil).richTextToMarkdown(richText); got != tt.want {
+			t.Errorf("round trip of %q = %q, want %q", tt.markdown, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package transformer
+
+import (
+	"strings"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+// TagSource records the multi-select values a note tag was pushed as.
+type TagSource struct {
+	Tag    string
+	Values []string
+}
+
+// nestsTags reports whether a Config.NestedTags mode rewrites tags.
+func nestsTags(mode string) bool {
+	return mode != "" && mode != "keep"
+}
+
+// nestedTagValues returns the multi-select values for a tag.
+func nestedTagValues(tag, mode string) []string {
+	switch mode {
+	case "expand":
+		return parser.TagHierarchy(tag)
+	case "flatten":
+		return []string{strings.ReplaceAll(tag, "/", "-")}
+	case "top":
+		top, _, _ := strings.Cut(tag, "/")
+		return []string{top}
+	}
+	return []string{tag}
+}
+
+// nestTags converts tags to multi-select values, dropping duplicates.
+func nestTags(tags []string, mode string) []string {
+	var values []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		for _, v := range nestedTagValues(tag, mode) {
+			if !seen[v] {
+				seen[v] = true
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// TagRules are the rules tags are normalized by before they are pushed.
+type TagRules struct {
+	// Case is "keep" (default) or "lower".
+	Case string
+
+	// Spaces is how spaces in tags are written: "keep" (default),
+	// "hyphen", "underscore", or "remove".
+	Spaces string
+
+	// Inline adds the tags in the body to the frontmatter tags, when the
+	// note has both.
+	Inline bool
+}
+
+// rewrites reports whether the rules change the tags pushed.
+func (r TagRules) rewrites() bool {
+	return (r.Case != "" && r.Case != "keep") || (r.Spaces != "" && r.Spaces != "keep") || r.Inline
+}
+
+// normalize applies the rules to a tag. Commas are dropped, since Notion
+// does not allow them in multi-select values.
+func (r TagRules) normalize(tag string) string {
+	tag = strings.ReplaceAll(tag, ",", "")
+	if r.Case == "lower" {
+		tag = strings.ToLower(tag)
+	}
+	switch r.Spaces {
+	case "hyphen":
+		tag = strings.Join(strings.Fields(tag), "-")
+	case "underscore":
+		tag = strings.Join(strings.Fields(tag), "_")
+	case "remove":
+		tag = strings.Join(strings.Fields(tag), "")
+	}
+	return strings.TrimSpace(tag)
+}
+
+// pushedTags returns the multi-select values pushed for a note's tags:
+// the frontmatter tags if present, otherwise the tags found in the body,
+// normalized by rules and converted by the nested tags mode. Tags that
+// differ only in case are pushed once, as first written.
+//
+// It also records how each tag was converted, or nil when neither the
+// rules nor the mode change them, since there is nothing to restore. Body
+// tags added by rules.Inline are recorded without a tag, so pull does not
+// copy them into the frontmatter.
+func pushedTags(frontmatter map[string]any, bodyTags []string, rules TagRules, mode string) ([]string, []TagSource) {
+	tags := bodyTags
+	if value, ok := frontmatter["tags"]; ok {
+		tags = tagList(value)
+	}
+	own := len(tags)
+	if _, ok := frontmatter["tags"]; ok && rules.Inline {
+		tags = append(tags, bodyTags...)
+	}
+
+	var values []string
+	var sources []TagSource
+	seenTag := make(map[string]bool)
+	seenValue := make(map[string]bool)
+	for i, tag := range tags {
+		normalized := rules.normalize(tag)
+		key := strings.ToLower(normalized)
+		if normalized == "" || seenTag[key] {
+			continue
+		}
+		seenTag[key] = true
+
+		src := TagSource{Tag: tag, Values: nestedTagValues(normalized, mode)}
+		if i >= own {
+			src.Tag = ""
+		}
+		sources = append(sources, src)
+		for _, v := range src.Values {
+			if !seenValue[v] {
+				seenValue[v] = true
+				values = append(values, v)
+			}
+		}
+	}
+
+	if !nestsTags(mode) && !rules.rewrites() {
+		sources = nil
+	}
+	return values, sources
+}
+
+// tagList reads a frontmatter tags value, given as a list or as a comma-
+// or space-separated string.
+func tagList(value any) []string {
+	return parser.FrontmatterTags(value)
+}
+
+// RestoreTags converts multi-select values read from Notion back to note
+// tags. A recorded tag is restored when all the values it was pushed as
+// are still present; one recorded without a tag covers its values but is
+// not restored. Values added in Notion are kept as they are, except
+// that in "expand" mode an ancestor of another value is dropped.
+func RestoreTags(values []string, sources []TagSource, mode string) []string {
+	present := make(map[string]bool, len(values))
+	for _, v := range values {
+		present[v] = true
+	}
+
+	var tags []string
+	seen := make(map[string]bool)
+	covered := make(map[string]bool)
+	for _, src := range sources {
+		restored := len(src.Values) > 0
+		for _, v := range src.Values {
+			restored = restored && present[v]
+		}
+		if !restored {
+			continue
+		}
+		for _, v := range src.Values {
+			covered[v] = true
+		}
+		if src.Tag != "" && !seen[src.Tag] {
+			seen[src.Tag] = true
+			tags = append(tags, src.Tag)
+		}
+	}
+
+	for _, v := range values {
+		if covered[v] || seen[v] {
+			continue
+		}
+		if mode == "expand" && hasDescendantTag(v, values) {
+			continue
+		}
+		seen[v] = true
+		tags = append(tags, v)
+	}
+	return tags
+}
+
+// hasDescendantTag reports whether any value is nested below tag.
+func hasDescendantTag(tag string, values []string) bool {
+	for _, v := range values {
+		if strings.HasPrefix(v, tag+"/") {
+			return true
+		}
+	}
+	return false
+}
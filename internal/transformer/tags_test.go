@@ -0,0 +1,135 @@
+package transformer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+func TestNestTags(t *testing.T) {
+	tags := []string{"project/alpha/backend", "project/beta", "inbox"}
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{"keep", []string{"project/alpha/backend", "project/beta", "inbox"}},
+		{"expand", []string{"project", "project/alpha", "project/alpha/backend", "project/beta", "inbox"}},
+		{"flatten", []string{"project-alpha-backend", "project-beta", "inbox"}},
+		{"top", []string{"project", "inbox"}},
+	}
+	for _, tt := range tests {
+		if got := nestTags(tags, tt.mode); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("nestTags(%s) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestRestoreTags(t *testing.T) {
+	tags := []string{"project/alpha/backend", "project/beta", "inbox"}
+
+	frontmatter := map[string]any{"tags": tags}
+	for _, mode := range []string{"expand", "flatten", "top"} {
+		_, sources := pushedTags(frontmatter, nil, TagRules{}, mode)
+		got := RestoreTags(nestTags(tags, mode), sources, mode)
+		if !reflect.DeepEqual(got, tags) {
+			t.Errorf("%s: round trip = %q, want %q", mode, got, tags)
+		}
+	}
+
+	// Values added in Notion are kept; removed values drop their tags.
+	_, sources := pushedTags(frontmatter, nil, TagRules{}, "top")
+	if got := RestoreTags([]string{"inbox", "new"}, sources, "top"); !reflect.DeepEqual(got, []string{"inbox", "new"}) {
+		t.Errorf("top with edits = %q", got)
+	}
+
+	// Without a record, expand collapses ancestors into the deepest tag.
+	values := []string{"area", "area/work", "misc"}
+	if got := RestoreTags(values, nil, "expand"); !reflect.DeepEqual(got, []string{"area/work", "misc"}) {
+		t.Errorf("expand without record = %q", got)
+	}
+}
+
+func TestPushedTags(t *testing.T) {
+	tests := []struct {
+		name        string
+		frontmatter map[string]any
+		body        []string
+		rules       TagRules
+		want        []string
+	}{
+		{"string with spaces", map[string]any{"tags": "#alpha beta, gamma"}, nil, TagRules{}, []string{"alpha", "beta", "gamma"}},
+		{"list with #", map[string]any{"tags": []any{"#alpha", "beta"}}, nil, TagRules{}, []string{"alpha", "beta"}},
+		{"body tags without frontmatter", map[string]any{}, []string{"alpha"}, TagRules{}, []string{"alpha"}},
+		{"body tags ignored", map[string]any{"tags": "alpha"}, []string{"alpha", "beta"}, TagRules{}, []string{"alpha"}},
+		{"case dedupe", map[string]any{"tags": []any{"Alpha", "alpha"}}, nil, TagRules{}, []string{"Alpha"}},
+		{"lower case", map[string]any{"tags": []any{"Alpha", "Beta"}}, nil, TagRules{Case: "lower"}, []string{"alpha", "beta"}},
+		{"hyphen spaces", map[string]any{"tags": []any{"My Tag", "a,b"}}, nil, TagRules{Spaces: "hyphen"}, []string{"My-Tag", "ab"}},
+		{"inline deduped", map[string]any{"tags": "Alpha"}, []string{"alpha", "beta", "Alpha"}, TagRules{Inline: true}, []string{"Alpha", "beta"}},
+	}
+	for _, tt := range tests {
+		got, _ := pushedTags(tt.frontmatter, tt.body, tt.rules, "keep")
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: pushedTags() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRestoreTags_Rules(t *testing.T) {
+	frontmatter := map[string]any{"tags": []any{"My Tag", "Project/Alpha"}}
+	rules := TagRules{Case: "lower", Spaces: "hyphen", Inline: true}
+	values, sources := pushedTags(frontmatter, []string{"inbox"}, rules, "keep")
+	if want := []string{"my-tag", "project/alpha", "inbox"}; !reflect.DeepEqual(values, want) {
+		t.Fatalf("values = %q, want %q", values, want)
+	}
+
+	// Tags are restored as written; the body tag stays out of the
+	// frontmatter.
+	got := RestoreTags(append(values, "new"), sources, "keep")
+	if want := []string{"My Tag", "Project/Alpha", "new"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RestoreTags() = %q, want %q", got, want)
+	}
+}
+
+func TestTransform_NestedTagsRoundTrip(t *testing.T) {
+	content := "---\ntags:\n  - project/alpha/backend\n  - project/beta\n---\n\nBody\n"
+	note, err := parser.New().Parse("test.md", []byte(content))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.NestedTags = "flatten"
+	page, err := New(nil, cfg).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	prop, ok := page.Properties["Tags"].(notionapi.MultiSelectProperty)
+	if !ok {
+		t.Fatalf("expected multi-select Tags, got %T", page.Properties["Tags"])
+	}
+	var names []string
+	for _, opt := range prop.MultiSelect {
+		names = append(names, opt.Name)
+	}
+	if want := []string{"project-alpha-backend", "project-beta"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("multi-select = %q, want %q", names, want)
+	}
+
+	// Notion returns pointer properties on read.
+	pulled := &NotionPage{
+		Properties: notionapi.Properties{"Tags": &prop},
+		Tags:       page.Tags,
+	}
+	md, err := NewReverse(nil, cfg).NotionToMarkdown(pulled)
+	if err != nil {
+		t.Fatalf("NotionToMarkdown() error: %v", err)
+	}
+	if !strings.Contains(string(md), "tags: [project/alpha/backend, project/beta]") {
+		t.Errorf("expected original tags restored, got:\n%s", md)
+	}
+}
@@ -0,0 +1,159 @@
+package transformer
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jomei/notionapi"
+	"github.com/yuin/goldmark/ast"
+)
+
+// Task state mappings of Config.TaskStates other than badges.
+const (
+	taskChecked   = "checked"
+	taskUnchecked = "unchecked"
+)
+
+// TaskState records the alternative state, the character in "- [>] task",
+// of a task pushed as a checked or unchecked to-do.
+type TaskState struct {
+	// Text is the task's text, as returned by taskText.
+	Text  string
+	State string
+}
+
+// isBadge reports whether a Config.TaskStates mapping is a badge.
+func isBadge(mapping string) bool {
+	return mapping != taskChecked && mapping != taskUnchecked
+}
+
+// altTaskState returns the alternative state of a list item whose first
+// line starts with a state mapped in Config.TaskStates, such as "[>] ", and
+// the length of that marker. Goldmark only parses " ", "x", and "X" as task
+// checkboxes, so these items are plain list items.
+func (t *Transformer) altTaskState(li *ast.ListItem, source []byte) (string, int, bool) {
+	if len(t.config.TaskStates) == 0 {
+		return "", 0, false
+	}
+	first := li.FirstChild()
+	switch first.(type) {
+	case *ast.TextBlock, *ast.Paragraph:
+	default:
+		return "", 0, false
+	}
+	if first.Lines().Len() == 0 {
+		return "", 0, false
+	}
+	segment := first.Lines().At(0)
+	line := segment.Value(source)
+	if len(line) < 3 || line[0] != '[' {
+		return "", 0, false
+	}
+	r, size := utf8.DecodeRune(line[1:])
+	end := 1 + size
+	if r == utf8.RuneError || end >= len(line) || line[end] != ']' {
+		return "", 0, false
+	}
+	state := string(r)
+	if _, ok := t.config.TaskStates[state]; !ok {
+		return "", 0, false
+	}
+
+	marker := end + 1
+	if marker < len(line) && line[marker] != ' ' && line[marker] != '\t' {
+		return "", 0, false
+	}
+	for marker < len(line) && (line[marker] == ' ' || line[marker] == '\t') {
+		marker++
+	}
+	return state, marker, true
+}
+
+// transformAltTaskItem creates a to-do block for a task with an alternative
+// state, whose marker is the first marker bytes of its text. Checked and
+// unchecked states are recorded for pull; badges are put before the text.
+func (t *Transformer) transformAltTaskItem(li *ast.ListItem, source []byte, state string, marker int) notionapi.Block {
+	richText := skipRichText(t.transformListItemContent(li, source), marker)
+	mapping := t.config.TaskStates[state]
+	if isBadge(mapping) {
+		badge := notionapi.RichText{
+			Type: notionapi.ObjectTypeText,
+			Text: &notionapi.Text{Content: mapping + " "},
+		}
+		richText = mergePlainRuns(append([]notionapi.RichText{badge}, richText...))
+	} else {
+		t.tasks = append(t.tasks, TaskState{Text: taskText(richText), State: state})
+	}
+
+	return &notionapi.ToDoBlock{
+		BasicBlock: notionapi.BasicBlock{
+			Object: notionapi.ObjectTypeBlock,
+			Type:   notionapi.BlockTypeToDo,
+		},
+		ToDo: notionapi.ToDo{
+			RichText: richText,
+			Checked:  mapping == taskChecked,
+			Children: t.extractNestedChildren(li, source),
+		},
+	}
+}
+
+// skipRichText drops the first n bytes of text from the start of rich text.
+func skipRichText(richText []notionapi.RichText, n int) []notionapi.RichText {
+	for n > 0 && len(richText) > 0 && richText[0].Type == notionapi.ObjectTypeText && richText[0].Text != nil {
+		content := richText[0].Text.Content
+		if len(content) > n {
+			richText[0].Text = &notionapi.Text{Content: content[n:], Link: richText[0].Text.Link}
+			break
+		}
+		n -= len(content)
+		richText = richText[1:]
+	}
+	return richText
+}
+
+// taskText returns the text of a to-do's text runs, which is the same for a
+// pushed to-do and the one fetched back.
+func taskText(richText []notionapi.RichText) string {
+	var text strings.Builder
+	for _, rt := range richText {
+		if rt.Type == notionapi.ObjectTypeText && rt.Text != nil {
+			text.WriteString(rt.Text.Content)
+		}
+	}
+	return text.String()
+}
+
+// restoreTaskState returns the alternative state a to-do was pushed from,
+// and its markdown text without the state's badge. A recorded state is
+// restored once, for the first to-do with its text and checked state.
+func (t *ReverseTransformer) restoreTaskState(todo notionapi.ToDo, text string) (string, string, bool) {
+	if len(t.config.TaskStates) == 0 {
+		return "", text, false
+	}
+
+	key := taskText(todo.RichText)
+	for i, task := range t.tasks {
+		mapping, ok := t.config.TaskStates[task.State]
+		if ok && !isBadge(mapping) && task.Text == key && (mapping == taskChecked) == todo.Checked {
+			t.tasks = slices.Delete(t.tasks, i, i+1)
+			return task.State, text, true
+		}
+	}
+
+	if todo.Checked {
+		return "", text, false
+	}
+	for _, state := range slices.Sorted(maps.Keys(t.config.TaskStates)) {
+		mapping := t.config.TaskStates[state]
+		if !isBadge(mapping) {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(text, mapping+" "); ok {
+			return state, rest, true
+		}
+	}
+	return "", text, false
+}
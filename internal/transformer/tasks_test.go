@@ -0,0 +1,85 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+func TestTransform_TaskStatesRoundTrip(t *testing.T) {
+	content := "- [ ] open\n- [-] cancelled **now**\n- [>] deferred\n- [/] in progress\n- [?] unmapped\n- [x] done\n"
+	note, err := parser.New().Parse("test.md", []byte(content))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.TaskStates = map[string]string{"-": "checked", ">": "unchecked", "/": "🚧"}
+	page, err := New(nil, cfg).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	if len(page.Children) != 6 {
+		t.Fatalf("expected 6 blocks, got %d", len(page.Children))
+	}
+	wants := []struct {
+		text    string
+		checked bool
+	}{
+		{"open", false},
+		{"cancelled now", true},
+		{"deferred", false},
+		{"🚧 in progress", false},
+	}
+	for i, want := range wants {
+		todo, ok := page.Children[i].(*notionapi.ToDoBlock)
+		if !ok {
+			t.Fatalf("block %d: expected to-do, got %T", i, page.Children[i])
+		}
+		if got := taskText(todo.ToDo.RichText); got != want.text || todo.ToDo.Checked != want.checked {
+			t.Errorf("block %d = %q (checked %v), want %q (checked %v)", i, got, todo.ToDo.Checked, want.text, want.checked)
+		}
+	}
+
+	// Notion fills in the plain text of fetched blocks.
+	for _, block := range page.Children {
+		var richText []notionapi.RichText
+		switch b := block.(type) {
+		case *notionapi.ToDoBlock:
+			richText = b.ToDo.RichText
+		case *notionapi.BulletedListItemBlock:
+			richText = b.BulletedListItem.RichText
+		}
+		for i := range richText {
+			richText[i].PlainText = richText[i].Text.Content
+		}
+	}
+	if _, ok := page.Children[4].(*notionapi.BulletedListItemBlock); !ok {
+		t.Errorf("expected unmapped state to stay a list item, got %T", page.Children[4])
+	}
+	if len(page.Tasks) != 2 || page.Tasks[0].State != "-" || page.Tasks[1].State != ">" {
+		t.Errorf("unexpected recorded tasks: %+v", page.Tasks)
+	}
+
+	md, err := NewReverse(nil, cfg).NotionToMarkdown(&NotionPage{Children: page.Children, Tasks: page.Tasks})
+	if err != nil {
+		t.Fatalf("NotionToMarkdown() error: %v", err)
+	}
+	if got := strings.TrimSpace(string(md)); got != strings.TrimSpace(content) {
+		t.Errorf("round trip =\n%s\nwant\n%s", got, content)
+	}
+
+	// Without the record, or once checked in Notion, states are not restored.
+	todo := page.Children[2].(*notionapi.ToDoBlock)
+	todo.ToDo.Checked = true
+	md, _ = NewReverse(nil, cfg).NotionToMarkdown(&NotionPage{Children: page.Children})
+	for _, want := range []string{"- [x] cancelled **now**", "- [x] deferred", "- [/] in progress"} {
+		if !strings.Contains(string(md), want) {
+			t.Errorf("expected %q in:\n%s", want, md)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package transformer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/yuin/goldmark/ast"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+// templateSyntaxRegex matches placeholders of Obsidian's core Templates
+// plugin ({{date}}, {{time:HH:mm}}, {{title}}) and Templater commands
+// (<% tp.date.now() %>, <%* ... %>), which are only meaningful before a
+// template is inserted.
+var templateSyntaxRegex = regexp.MustCompile(`\{\{\s*(?:date|time|title)(?::[^{}\n]*)?\s*\}\}|<%[-_*+~]?[^%]*?[-_]?%>`)
+
+// UnexpandedTemplates returns the template placeholders left in a note,
+// in the order they appear. Frontmatter values are checked first. Code is
+// skipped, so notes documenting template syntax are not reported.
+func UnexpandedTemplates(note *parser.ParsedNote) []string {
+	var found []string
+
+	keys := make([]string, 0, len(note.Frontmatter))
+	for key := range note.Frontmatter {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		found = append(found, templatePlaceholders(fmt.Sprint(note.Frontmatter[key]))...)
+	}
+
+	source := note.Source
+	if note.AST != nil {
+		source = withoutCode(note.AST, note.Source)
+	}
+	return append(found, templatePlaceholders(string(source))...)
+}
+
+// templatePlaceholders returns the template placeholders in text. Date
+// markers, {{date:2024-03-03}}, are dates pulled from Notion, not
+// placeholders.
+func templatePlaceholders(text string) []string {
+	var found []string
+	for _, m := range templateSyntaxRegex.FindAllString(text, -1) {
+		if !dateMarkerRegex.MatchString(m) {
+			found = append(found, m)
+		}
+	}
+	return found
+}
+
+// withoutCode returns a copy of source with code blocks and code spans
+// blanked out.
+func withoutCode(doc ast.Node, source []byte) []byte {
+	out := append([]byte(nil), source...)
+	blank := func(start, stop int) {
+		for i := start; i < stop && i < len(out); i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			lines := node.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				seg := lines.At(i)
+				blank(seg.Start, seg.Stop)
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeSpan:
+			for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+				if text, ok := c.(*ast.Text); ok {
+					blank(text.Segment.Start, text.Segment.Stop)
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return out
+}
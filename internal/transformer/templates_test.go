@@ -0,0 +1,51 @@
+package transformer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+func TestUnexpandedTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+	}{
+		{
+			name:     "expanded note",
+			markdown: "---\ncreated: 2024-01-05\n---\n# Daily 2024-01-05\n\nNothing {left} here.\n",
+		},
+		{
+			name:     "core placeholders",
+			markdown: "---\ncreated: \"{{date}}\"\n---\n# {{title}}\n\nAt {{time:HH:mm}}.\n",
+			want:     []string{"{{date}}", "{{title}}", "{{time:HH:mm}}"},
+		},
+		{
+			name:     "templater commands",
+			markdown: "# <% tp.file.title %>\n\n<%* tR += \"x\" -%>\n",
+			want:     []string{"<% tp.file.title %>", `<%* tR += "x" -%>`},
+		},
+		{
+			name:     "date markers are skipped",
+			markdown: "Due {{date:2024-03-03}} until {{date:2024-03-03/2024-03-05}}, not {{date:YYYY}}.\n",
+			want:     []string{"{{date:YYYY}}"},
+		},
+		{
+			name:     "code is skipped",
+			markdown: "Use `{{date}}` or:\n\n```\n<% tp.date.now() %>\n```\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			note, err := parser.New().Parse("test.md", []byte(tt.markdown))
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			if got := UnexpandedTemplates(note); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnexpandedTemplates() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,8 +6,10 @@
 package transformer
 
 import (
+	"bytes"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/jomei/notionapi"
 	"github.com/yuin/goldmark/ast"
@@ -23,11 +25,30 @@ type LinkResolver interface {
 	Resolve(target string) (notionPageID string, found bool)
 }
 
+// AttachmentResolver maps local attachment references to uploaded Notion files.
+type AttachmentResolver interface {
+	// ResolveAttachment returns the Notion file upload ID for a local
+	// attachment reference (a markdown image path or embed target).
+	// Returns empty string and false if the attachment is not uploaded.
+	ResolveAttachment(ref string) (fileUploadID string, found bool)
+}
+
 // Transformer converts Obsidian parsed notes to Notion page structures.
 type Transformer struct {
-	linkResolver   LinkResolver
-	config         *Config
-	propertyMapper *PropertyMapper
+	linkResolver       LinkResolver
+	attachmentResolver AttachmentResolver
+	config             *Config
+	propertyMapper     *PropertyMapper
+
+	// consumed holds nodes already transformed as part of an earlier
+	// sibling, such as the body of an HTML <details> block.
+	consumed map[ast.Node]bool
+
+	// tasks are the alternative task states of the note being transformed.
+	tasks []TaskState
+
+	// dividers are the markers of the note's dividers (NotionPage.Dividers).
+	dividers map[notionapi.Block]string
 }
 
 // Config holds transformer configuration options.
@@ -39,16 +60,138 @@ type Config struct {
 	// CalloutIcons maps Obsidian callout types to Notion icons.
 	CalloutIcons map[string]string
 
+	// Icons overrides the icons of generated blocks, by the names in
+	// DefaultIcons.
+	Icons map[string]string
+
 	// DataviewHandling determines how to handle dataview queries.
 	// Options: "snapshot" (static content), "placeholder" (info block)
 	DataviewHandling string
 
+	// PluginBlocks turns the conversion of the fenced blocks of Obsidian
+	// plugins, such as ```kanban boards, on or off by plugin name (see
+	// PluginKanban and the like). Plugins not listed are converted; blocks
+	// of plugins turned off are pushed as code blocks.
+	PluginBlocks map[string]bool
+
 	// FlattenHeadings flattens H4-H6 to H3 (Notion only supports H1-H3).
 	FlattenHeadings bool
 
 	// PropertyMappings defines how frontmatter fields map to Notion properties.
 	// If nil or empty, uses DefaultMappings.
 	PropertyMappings []PropertyMapping
+
+	// PropertyRenames rename frontmatter keys to Notion properties on top
+	// of PropertyMappings (or DefaultMappings). A rename with an empty
+	// NotionType keeps the type of the mapping it replaces, or infers one
+	// from the value.
+	PropertyRenames []PropertyMapping
+
+	// UnmappedProperties determines how keys and properties without a
+	// mapping are synced. Options: "pull" (default: Notion properties are
+	// added to the frontmatter), "passthrough" (frontmatter keys are also
+	// pushed), "ignore" (mapped properties only)
+	UnmappedProperties string
+
+	// SplitOn splits a note into child pages at top-level headings.
+	// Options: "" (no splitting), "h1" (one child page per H1 section)
+	SplitOn string
+
+	// EmojiShortcodes converts :shortcode: text to emoji on push.
+	EmojiShortcodes bool
+
+	// EmojiReverse converts emoji back to :shortcode: text on pull.
+	EmojiReverse bool
+
+	// UserMentions maps display names to Notion user IDs. "@Name" in a
+	// note becomes a mention of the user on push, and mentions of the user
+	// are written as "@Name" on pull.
+	UserMentions map[string]string
+
+	// DateMentions determines how date mentions are written on pull.
+	// Options: "text" (default: as Notion shows them), "marker"
+	// ({{date:2024-03-03}}, pushed back as date mentions), "iso" (plain
+	// ISO dates). Markers are converted on push whatever the option.
+	DateMentions string
+
+	// HTMLHandling determines how raw HTML is handled.
+	// Options: "convert" (default: common tags become Notion formatting,
+	// the rest is preserved), "preserve" (all HTML kept as marked code
+	// blocks or text), "strip" (HTML is dropped)
+	HTMLHandling string
+
+	// TextColors determines how Notion text and background colors are
+	// written on pull. Options: "html" (default: <span> tags with inline
+	// styles), "highlight" (background colors become ==highlights==, text
+	// colors are dropped), "strip" (colors are dropped). The yellow
+	// background is always a highlight. Colored spans are converted back
+	// on push unless HTMLHandling is "preserve".
+	TextColors string
+
+	// NestedTags determines how nested tags ("project/alpha") map to
+	// multi-select values. Options: "keep" (default, one value per tag),
+	// "expand" (the tag and each ancestor), "flatten" ("project-alpha"),
+	// "top" (top-level segment only)
+	NestedTags string
+
+	// TagRules normalizes tags before they are pushed (see TagRules).
+	TagRules TagRules
+
+	// DividerMarker is the markdown pull writes for dividers without a
+	// recorded marker: "---" (default), "***", or "___". A note's first
+	// line is never written as ---, which would open frontmatter.
+	DividerMarker string
+
+	// EmptyParagraphs determines how blank lines map to empty paragraph
+	// blocks. Options: "collapse" (default: extra blank lines are not
+	// pushed; set explicitly, empty paragraphs are also dropped on pull,
+	// which otherwise pulls each as a blank line), "keep" (each blank line
+	// beyond the one separating two top-level blocks becomes an empty
+	// paragraph on push, and each empty paragraph a blank line on pull)
+	EmptyParagraphs string
+
+	// TaskStates maps alternative task states, the character in
+	// "- [>] task", to "checked" or "unchecked" to-dos, or to a badge: text
+	// put before the task in an unchecked to-do. Pull restores the states.
+	// Unmapped states are left as list items.
+	TaskStates map[string]string
+
+	// CreatedProperty and ModifiedProperty name Notion date properties set
+	// from the note's "created" and "updated" frontmatter, or FileCreated
+	// and FileModified when the frontmatter has none. On pull, the page's
+	// creation and last edit times are written back to those keys.
+	CreatedProperty  string
+	ModifiedProperty string
+
+	// FileCreated and FileModified are the note file's timestamps. A zero
+	// time leaves the property unset.
+	FileCreated  time.Time
+	FileModified time.Time
+
+	// DateLayout and DateTimeLayout are Go time layouts of frontmatter
+	// dates without and with a time, e.g. "02.01.2006". They are tried
+	// before the built-in formats on push and used to write dates on pull.
+	// DateLocation is the zone of frontmatter times without one; nil means
+	// local time. Unless one of them is set, pulled date properties are
+	// written in RFC 3339.
+	DateLayout     string
+	DateTimeLayout string
+	DateLocation   *time.Location
+	// NoteTargets are the notion-targets of the local note, kept in the
+	// frontmatter on pull since Notion does not store them.
+	NoteTargets []string
+
+	// WrapWidth wraps single-line paragraphs longer than this many
+	// characters on pull. 0 leaves them on one line.
+	WrapWidth int
+
+	// WrapSource is the local note, whose line breaks are kept on pull for
+	// paragraphs with unchanged text. nil keeps none.
+	WrapSource []byte
+
+	// FrontmatterSource is the local note, whose frontmatter key order and
+	// value styles are kept on pull. nil writes keys sorted.
+	FrontmatterSource []byte
 }
 
 // NotionPage represents a page ready to be created in Notion.
@@ -58,6 +201,51 @@ type NotionPage struct {
 
 	// Children are the content blocks.
 	Children []notionapi.Block
+
+	// Icon and Cover, if set, are given to the page when it is created.
+	Icon  *notionapi.Icon
+	Cover *notionapi.Image
+
+	// BlockIDs are the Notion IDs of Children, in order, set once the page
+	// is pushed. Fetched blocks carry their own IDs.
+	BlockIDs []string
+
+	// Sections are parts of the note split off into child pages
+	// (see Config.SplitOn), in document order after Children.
+	Sections []*PageSection
+
+	// Tags records the multi-select values each note tag was pushed as
+	// when Config.NestedTags or Config.TagRules rewrite them. Pull uses it
+	// to restore the original tags.
+	Tags []TagSource
+
+	// Tasks records the alternative states of tasks pushed as checked or
+	// unchecked to-dos (see Config.TaskStates), in document order. Pull
+	// uses it to restore them.
+	Tasks []TaskState
+
+	// Dividers records the markdown of top-level dividers by block: as the
+	// thematic breaks were written on push, and as recorded in the block
+	// map on pull. Dividers without a marker are pulled as
+	// Config.DividerMarker.
+	Dividers map[notionapi.Block]string
+
+	// CreatedTime and LastEditedTime are the page's timestamps in Notion,
+	// set for fetched pages.
+	CreatedTime    time.Time
+	LastEditedTime time.Time
+}
+
+// PageSection is a heading-delimited part of a note stored as a child page.
+type PageSection struct {
+	// Title is the heading text, used as the child page title.
+	Title string
+
+	// PageID is the Notion page ID, set once the child page exists.
+	PageID string
+
+	// Children are the content blocks below the heading.
+	Children []notionapi.Block
 }
 
 // New creates a new Transformer with the given link resolver and config.
@@ -66,50 +254,52 @@ func New(resolver LinkResolver, cfg *Config) *Transformer {
 		cfg = DefaultConfig()
 	}
 
-	// Create property mapper from config or use defaults.
-	var mappings []PropertyMapping
-	if len(cfg.PropertyMappings) > 0 {
-		mappings = cfg.PropertyMappings
-	}
+	propertyMapper := newConfigPropertyMapper(cfg)
 
 	return &Transformer{
 		linkResolver:   resolver,
 		config:         cfg,
-		propertyMapper: NewPropertyMapper(mappings),
+		propertyMapper: propertyMapper,
 	}
 }
 
+// SetAttachmentResolver sets the resolver used to embed uploaded local
+// attachments. Without one, local images become placeholder callouts.
+func (t *Transformer) SetAttachmentResolver(r AttachmentResolver) {
+	t.attachmentResolver = r
+}
+
 // DefaultConfig returns the default transformer configuration.
 func DefaultConfig() *Config {
 	return &Config{
 		UnresolvedLinkStyle: "placeholder",
 		CalloutIcons: map[string]string{
-			"note":      "💡",
-			"abstract":  "📋",
-			"summary":   "📋",
-			"info":      "ℹ️",
-			"todo":      "📝",
-			"tip":       "💡",
-			"hint":      "💡",
-			"important": "❗",
-			"success":   "✅",
-			"check":     "✅",
-			"done":      "✅",
-			"question":  "❓",
-			"help":      "❓",
-			"faq":       "❓",
-			"warning":   "⚠️",
-			"caution":   "⚠️",
-			"attention": "⚠️",
-			"failure":   "❌",
-			"fail":      "❌",
-			"missing":   "❌",
-			"danger":    "🔴",
-			"error":     "🔴",
-			"bug":       "🐛",
-			"example":   "📖",
-			"quote":     "💬",
-			"cite":      "💬",
+			"note":      EmojiLightBulb,
+			"abstract":  EmojiClipboard,
+			"summary":   EmojiClipboard,
+			"info":      EmojiInformation,
+			"todo":      EmojiMemo,
+			"tip":       EmojiLightBulb,
+			"hint":      EmojiLightBulb,
+			"important": EmojiExclamation,
+			"success":   EmojiWhiteHeavyCheck,
+			"check":     EmojiWhiteHeavyCheck,
+			"done":      EmojiWhiteHeavyCheck,
+			"question":  EmojiQuestion,
+			"help":      EmojiQuestion,
+			"faq":       EmojiQuestion,
+			"warning":   EmojiWarning,
+			"caution":   EmojiWarning,
+			"attention": EmojiWarning,
+			"failure":   EmojiCross,
+			"fail":      EmojiCross,
+			"missing":   EmojiCross,
+			"danger":    EmojiRedCircle,
+			"error":     EmojiRedCircle,
+			"bug":       EmojiBug,
+			"example":   EmojiOpenBook,
+			"quote":     EmojiSpeechBalloon,
+			"cite":      EmojiSpeechBalloon,
 		},
 		DataviewHandling: "placeholder",
 		FlattenHeadings:  true,
@@ -118,20 +308,67 @@ func DefaultConfig() *Config {
 
 // Transform converts an Obsidian parsed note to a Notion page structure.
 func (t *Transformer) Transform(note *parser.ParsedNote) (*NotionPage, error) {
+	properties, err := t.transformProperties(note.Frontmatter, note.Tags)
+	if err != nil {
+		return nil, err
+	}
+	_, tags := pushedTags(note.Frontmatter, note.Tags, t.config.TagRules, t.config.NestedTags)
 	page := &NotionPage{
-		Properties: t.transformProperties(note.Frontmatter, note.Tags),
+		Properties: properties,
 		Children:   []notionapi.Block{},
+		Tags:       tags,
 	}
+	t.setNoteDates(page.Properties, note.Frontmatter)
+
+	// Blocks go to the page until the first split heading, then to the
+	// current section.
+	var section *PageSection
+	t.consumed = nil
+	t.tasks = nil
+	t.dividers = nil
+	addBlock := func(block notionapi.Block) {
+		if section != nil {
+			section.Children = append(section.Children, block)
+		} else {
+			page.Children = append(page.Children, block)
+		}
+	}
+
+	// prevTop is the last top-level block, for finding the blank lines
+	// before the next one.
+	var prevTop ast.Node
 
 	// Walk AST and build Notion blocks.
-	err := ast.Walk(note.AST, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+	err = ast.Walk(note.AST, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		if !entering {
 			return ast.WalkContinue, nil
 		}
 
+		if t.consumed[n] {
+			return ast.WalkSkipChildren, nil
+		}
+
+		if _, topLevel := n.Parent().(*ast.Document); topLevel {
+			if !t.isSplitHeading(n) {
+				for i := t.extraBlankLines(prevTop, n, note.Source); i > 0; i-- {
+					addBlock(emptyParagraph())
+				}
+			}
+			prevTop = n
+		}
+
+		if t.isSplitHeading(n) {
+			section = &PageSection{
+				Title:    plainText(t.transformInlineContent(n, note.Source)),
+				Children: []notionapi.Block{},
+			}
+			page.Sections = append(page.Sections, section)
+			return ast.WalkSkipChildren, nil
+		}
+
 		block, skipChildren := t.transformNode(n, note.Source)
 		if block != nil {
-			page.Children = append(page.Children, block)
+			addBlock(block)
 		}
 		if skipChildren {
 			return ast.WalkSkipChildren, nil
@@ -142,9 +379,122 @@ func (t *Transformer) Transform(note *parser.ParsedNote) (*NotionPage, error) {
 		return nil, err
 	}
 
+	// Keys without a Notion property are kept in a marked code block.
+	if block := t.frontmatterMetaBlock(note.Frontmatter); block != nil {
+		page.Children = append(page.Children, block)
+	}
+	page.Tasks = t.tasks
+	page.Dividers = t.dividers
+
 	return page, nil
 }
 
+// isSplitHeading reports whether a node starts a new section under
+// Config.SplitOn. Only top-level headings split a note.
+func (t *Transformer) isSplitHeading(n ast.Node) bool {
+	if t.config.SplitOn != "h1" {
+		return false
+	}
+	h, ok := n.(*ast.Heading)
+	if !ok || h.Level != 1 {
+		return false
+	}
+	_, topLevel := n.Parent().(*ast.Document)
+	return topLevel
+}
+
+// extraBlankLines returns the number of blank lines between two top-level
+// blocks beyond the one that separates them, or 0 unless Config.EmptyParagraphs
+// is "keep". Blocks without a source position, such as thematic breaks,
+// have no extra blank lines.
+func (t *Transformer) extraBlankLines(prev, next ast.Node, source []byte) int {
+	if prev == nil || t.config.EmptyParagraphs != "keep" {
+		return 0
+	}
+	_, end := sourceRange(prev)
+	start, _ := sourceRange(next)
+	if end < 0 || start < 0 || end > start {
+		return 0
+	}
+
+	// Count whole lines only: from the line after prev ends to the line
+	// next starts on.
+	if end > 0 && source[end-1] != '\n' {
+		nl := bytes.IndexByte(source[end:start], '\n')
+		if nl < 0 {
+			return 0
+		}
+		end += nl + 1
+	}
+	start = bytes.LastIndexByte(source[:start], '\n') + 1
+	if end > start {
+		return 0
+	}
+	lines := strings.Split(strings.TrimSuffix(string(source[end:start]), "\n"), "\n")
+
+	blank := 0
+	for i := len(lines) - 1; i >= 0 && strings.TrimSpace(lines[i]) == ""; i-- {
+		blank++
+	}
+	return max(blank-1, 0)
+}
+
+// sourceRange returns the first and last source offsets covered by the
+// lines and text of n, or -1 when n has none.
+func sourceRange(n ast.Node) (start, stop int) {
+	start, stop = -1, -1
+	extend := func(segStart, segStop int) {
+		if start < 0 || segStart < start {
+			start = segStart
+		}
+		if segStop > stop {
+			stop = segStop
+		}
+	}
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if c.Type() == ast.TypeBlock {
+			lines := c.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				seg := lines.At(i)
+				extend(seg.Start, seg.Stop)
+			}
+		} else if text, ok := c.(*ast.Text); ok {
+			extend(text.Segment.Start, text.Segment.Stop)
+		}
+		return ast.WalkContinue, nil
+	})
+	return start, stop
+}
+
+// emptyParagraph returns a paragraph block with no text.
+func emptyParagraph() notionapi.Block {
+	return &notionapi.ParagraphBlock{
+		BasicBlock: notionapi.BasicBlock{
+			Object: notionapi.ObjectTypeBlock,
+			Type:   notionapi.BlockTypeParagraph,
+		},
+		Paragraph: notionapi.Paragraph{
+			RichText: []notionapi.RichText{},
+		},
+	}
+}
+
+// plainText concatenates the text content of rich text segments.
+func plainText(richText []notionapi.RichText) string {
+	var b strings.Builder
+	for _, rt := range richText {
+		if rt.Text != nil {
+			b.WriteString(rt.Text.Content)
+		} else {
+			b.WriteString(rt.PlainText)
+		}
+	}
+	return b.String()
+}
+
 // transformNode converts a goldmark AST node to a Notion block.
 // Returns the block and whether to skip children (already processed).
 func (t *Transformer) transformNode(n ast.Node, source []byte) (notionapi.Block, bool) {
@@ -165,6 +515,9 @@ func (t *Transformer) transformNode(n ast.Node, source []byte) (notionapi.Block,
 		if imageBlock := t.tryImageBlock(node, source); imageBlock != nil {
 			return imageBlock, true
 		}
+		if bookmark := t.tryBookmarkBlock(node, source); bookmark != nil {
+			return bookmark, true
+		}
 		return t.transformParagraph(node, source), true
 
 	case *ast.List:
@@ -178,6 +531,15 @@ func (t *Transformer) transformNode(n ast.Node, source []byte) (notionapi.Block,
 	case *ast.FencedCodeBlock:
 		// Check for special code block types.
 		lang := string(node.Language(source))
+		if isBookmarkPreview(node, source) {
+			return nil, true
+		}
+		if block, ok := customBlock(node, source); ok {
+			return block, true
+		}
+		if block, ok := t.pluginBlock(node, source); ok {
+			return block, true
+		}
 		if lang == "math" || lang == "latex" {
 			return t.transformMathCodeBlock(node, source), true
 		}
@@ -195,7 +557,17 @@ func (t *Transformer) transformNode(n ast.Node, source []byte) (notionapi.Block,
 		return t.transformQuote(node, source), true
 
 	case *ast.ThematicBreak:
-		return t.transformDivider(), true
+		block := t.transformDivider()
+		if marker := dividerMarker(node, source); marker != "" {
+			if t.dividers == nil {
+				t.dividers = make(map[notionapi.Block]string)
+			}
+			t.dividers[block] = marker
+		}
+		return block, true
+
+	case *ast.HTMLBlock:
+		return t.transformHTMLBlock(node, source), true
 
 	case *ast.CodeSpan, *ast.Text, *ast.Emphasis, *ast.Link, *ast.Image:
 		// Inline elements are handled at the paragraph/heading level.
@@ -208,8 +580,10 @@ func (t *Transformer) transformNode(n ast.Node, source []byte) (notionapi.Block,
 }
 
 // transformProperties converts frontmatter and tags to Notion properties.
-func (t *Transformer) transformProperties(frontmatter map[string]any, tags []string) notionapi.Properties {
-	return t.propertyMapper.ToNotionProperties(frontmatter, tags)
+// Values that do not fit their property types are returned as
+// PropertyErrors.
+func (t *Transformer) transformProperties(frontmatter map[string]any, tags []string) (notionapi.Properties, error) {
+	return t.propertyMapper.ConvertProperties(frontmatter, tags)
 }
 
 // transformExtensionNode handles goldmark extension node types.
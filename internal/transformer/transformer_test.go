@@ -2,6 +2,7 @@ package transformer
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -206,6 +207,59 @@ func TestTransformParagraph_InlineFormatting(t *testing.T) {
 	}
 }
 
+func TestTransformParagraph_ReferenceLinks(t *testing.T) {
+	p := parser.New()
+	tr := New(nil, nil)
+
+	content := []byte("See [the **docs**][docs], [Docs], and [site][].\n\n" +
+		"[docs]: https://example.com/docs \"Docs\"\n" +
+		"[site]: <https://example.com>\n")
+
+	note, err := p.Parse("test.md", content)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	page, err := tr.Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	// The definitions produce no blocks of their own.
+	if len(page.Children) != 1 {
+		t.Fatalf("got %d blocks, want only the paragraph", len(page.Children))
+	}
+	para, ok := page.Children[0].(*notionapi.ParagraphBlock)
+	if !ok {
+		t.Fatalf("block is not ParagraphBlock, got %T", page.Children[0])
+	}
+
+	type run struct {
+		text, url string
+		bold      bool
+	}
+	var got []run
+	for _, rt := range para.Paragraph.RichText {
+		r := run{text: rt.Text.Content, bold: rt.Annotations != nil && rt.Annotations.Bold}
+		if rt.Text.Link != nil {
+			r.url = rt.Text.Link.Url
+		}
+		got = append(got, r)
+	}
+	want := []run{
+		{text: "See "},
+		{text: "the ", url: "https://example.com/docs"},
+		{text: "docs", url: "https://example.com/docs", bold: true},
+		{text: ", "},
+		{text: "Docs", url: "https://example.com/docs"},
+		{text: ", and "},
+		{text: "site", url: "https://example.com"},
+		{text: "."},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rich text = %+v, want %+v", got, want)
+	}
+}
+
 func TestTransformList_Bulleted(t *testing.T) {
 	p := parser.New()
 	tr := New(nil, nil)
@@ -424,6 +478,107 @@ func TestTransformQuote(t *testing.T) {
 	}
 }
 
+func TestTransformCalloutTitleLinks(t *testing.T) {
+	p := parser.New()
+	tr := New(&mockLinkResolver{links: map[string]string{"Runbook": "page-1"}}, nil)
+
+	content := []byte("> [!warning]- See [[Runbook]] and [docs](https://example.com) *now*\n> Body with [[Runbook]].\n")
+	note, err := p.Parse("test.md", content)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	page, err := tr.Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	callout, ok := page.Children[0].(*notionapi.CalloutBlock)
+	if !ok {
+		t.Fatalf("first block = %T, want callout", page.Children[0])
+	}
+
+	rt := callout.Callout.RichText
+	if len(rt) < 6 {
+		t.Fatalf("callout rich text = %+v", rt)
+	}
+	if rt[0].Text == nil || rt[0].Text.Content != "See " {
+		t.Errorf("title starts with %+v, want the marker dropped", rt[0])
+	}
+	if rt[1].Mention == nil || rt[1].Mention.Page == nil || rt[1].Mention.Page.ID != "page-1" || !rt[1].Annotations.Bold {
+		t.Errorf("title link = %+v, want a bold page mention", rt[1])
+	}
+	if rt[3].Text == nil || rt[3].Text.Link == nil || rt[3].Text.Link.Url != "https://example.com" || !rt[3].Annotations.Bold {
+		t.Errorf("title markdown link = %+v, want a bold link", rt[3])
+	}
+	if rt[5].Text == nil || rt[5].Text.Content != "now" || !rt[5].Annotations.Italic || !rt[5].Annotations.Bold {
+		t.Errorf("title emphasis = %+v, want bold italic", rt[5])
+	}
+
+	// The title's link is not repeated in the body.
+	mentions := 0
+	for _, r := range rt {
+		if r.Mention != nil {
+			mentions++
+		}
+	}
+	if mentions != 2 {
+		t.Errorf("got %d mentions, want one in the title and one in the body", mentions)
+	}
+	if got := plainText(rt); got != "See Runbook and docs now\nBody with Runbook." {
+		t.Errorf("callout text = %q", got)
+	}
+}
+
+func TestTransformQuoteAndCalloutChildren(t *testing.T) {
+	p := parser.New()
+	tr := New(nil, nil)
+
+	content := []byte("> [!tip] Steps\n> Do this:\n>\n> - one\n> - two\n>\n> ```go\n> x := 1\n> ```\n\n> Quoted\n>\n> 1. first\n>\n> After\n")
+
+	note, err := p.Parse("test.md", content)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	page, err := tr.Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	if len(page.Children) != 2 {
+		t.Fatalf("got %d blocks, want a callout and a quote", len(page.Children))
+	}
+
+	types := func(blocks notionapi.Blocks) []notionapi.BlockType {
+		var out []notionapi.BlockType
+		for _, b := range blocks {
+			out = append(out, b.GetType())
+		}
+		return out
+	}
+
+	callout, ok := page.Children[0].(*notionapi.CalloutBlock)
+	if !ok {
+		t.Fatalf("first block = %T, want callout", page.Children[0])
+	}
+	if got := plainText(callout.Callout.RichText); got != "Steps\nDo this:" {
+		t.Errorf("callout text = %q", got)
+	}
+	want := []notionapi.BlockType{notionapi.BlockTypeBulletedListItem, notionapi.BlockTypeBulletedListItem, notionapi.BlockTypeCode}
+	if got := types(callout.Callout.Children); !reflect.DeepEqual(got, want) {
+		t.Errorf("callout children = %v, want %v", got, want)
+	}
+
+	quote, ok := page.Children[1].(*notionapi.QuoteBlock)
+	if !ok {
+		t.Fatalf("second block = %T, want quote", page.Children[1])
+	}
+	if got := plainText(quote.Quote.RichText); got != "Quoted" {
+		t.Errorf("quote text = %q", got)
+	}
+	want = []notionapi.BlockType{notionapi.BlockTypeNumberedListItem, notionapi.BlockTypeParagraph}
+	if got := types(quote.Quote.Children); !reflect.DeepEqual(got, want) {
+		t.Errorf("quote children = %v, want %v", got, want)
+	}
+}
+
 func TestTransformDivider(t *testing.T) {
 	p := parser.New()
 	tr := New(nil, nil)
@@ -1915,3 +2070,268 @@ func TestSplitCodeContent(t *testing.T) {
 		})
 	}
 }
+
+// mockAttachmentResolver is a test double for attachment resolution.
+type mockAttachmentResolver struct {
+	uploads map[string]string
+}
+
+func (m *mockAttachmentResolver) ResolveAttachment(ref string) (string, bool) {
+	id, ok := m.uploads[ref]
+	return id, ok
+}
+
+func TestTransformImage_UploadedAttachment(t *testing.T) {
+	p := parser.New()
+	tr := New(nil, nil)
+	tr.SetAttachmentResolver(&mockAttachmentResolver{uploads: map[string]string{
+		"./images/screenshot.png": "upload-1",
+		"diagram.png":             "upload-2",
+	}})
+
+	content := []byte("![Screenshot](./images/screenshot.png)\n\n![[diagram.png]]\n\n![[missing.png]]\n")
+
+	note, err := p.Parse("test.md", content)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	page, err := tr.Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	if len(page.Children) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(page.Children))
+	}
+
+	first, ok := page.Children[0].(*FileUploadImageBlock)
+	if !ok {
+		t.Fatalf("block 0 = %T, want *FileUploadImageBlock", page.Children[0])
+	}
+	if first.Image.FileUpload.ID != "upload-1" || first.Image.Type != "file_upload" {
+		t.Errorf("unexpected image payload: %+v", first.Image)
+	}
+	if len(first.Image.Caption) != 1 || first.Image.Caption[0].Text.Content != "Screenshot" {
+		t.Error("alt text should become the caption")
+	}
+
+	second, ok := page.Children[1].(*FileUploadImageBlock)
+	if !ok {
+		t.Fatalf("block 1 = %T, want *FileUploadImageBlock", page.Children[1])
+	}
+	if second.Image.FileUpload.ID != "upload-2" {
+		t.Errorf("embed upload ID = %q, want upload-2", second.Image.FileUpload.ID)
+	}
+
+	// Unresolved embeds keep the inline placeholder.
+	if _, ok := page.Children[2].(*notionapi.ParagraphBlock); !ok {
+		t.Errorf("block 2 = %T, want *notionapi.ParagraphBlock", page.Children[2])
+	}
+}
+
+func TestTransformImage_EmbedSize(t *testing.T) {
+	tr := New(nil, nil)
+	tr.SetAttachmentResolver(&mockAttachmentResolver{uploads: map[string]string{
+		"banner.png": "upload-1",
+	}})
+
+	note, err := parser.New().Parse("test.md", []byte("![[banner.png|800x200]]\n\n![[banner.png|A banner]]\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	page, err := tr.Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	sized, ok := page.Children[0].(*FileUploadImageBlock)
+	if !ok {
+		t.Fatalf("block 0 = %T, want *FileUploadImageBlock", page.Children[0])
+	}
+	if len(sized.Image.Caption) != 1 || sized.Image.Caption[0].Text.Content != "banner.png|800x200" {
+		t.Errorf("caption = %+v, want the size recorded", sized.Image.Caption)
+	}
+
+	// An alias is not a size.
+	aliased := page.Children[1].(*FileUploadImageBlock)
+	if len(aliased.Image.Caption) != 0 {
+		t.Errorf("caption = %+v, want none", aliased.Image.Caption)
+	}
+}
+
+func TestTransform_SplitOnH1(t *testing.T) {
+	p := parser.New()
+	cfg := DefaultConfig()
+	cfg.SplitOn = "h1"
+	tr := New(nil, cfg)
+
+	content := []byte("Intro paragraph.\n\n# First\n\nBody one.\n\n## Detail\n\n# Second\n\n- item\n")
+
+	note, err := p.Parse("test.md", content)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	page, err := tr.Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	if len(page.Children) != 1 {
+		t.Errorf("expected 1 intro block, got %d", len(page.Children))
+	}
+	if len(page.Sections) != 2 {
+		t.Fatalf("expected 2 sections, got %d", len(page.Sections))
+	}
+
+	first := page.Sections[0]
+	if first.Title != "First" {
+		t.Errorf("section 0 title = %q, want First", first.Title)
+	}
+	if len(first.Children) != 2 {
+		t.Fatalf("section 0 has %d blocks, want 2", len(first.Children))
+	}
+	if _, ok := first.Children[1].(*notionapi.Heading2Block); !ok {
+		t.Errorf("H2 should stay inside its section, got %T", first.Children[1])
+	}
+
+	if page.Sections[1].Title != "Second" || len(page.Sections[1].Children) != 1 {
+		t.Errorf("unexpected second section: %+v", page.Sections[1])
+	}
+}
+
+func TestTransform_NoSplitByDefault(t *testing.T) {
+	p := parser.New()
+	tr := New(nil, nil)
+
+	note, err := p.Parse("test.md", []byte("# First\n\nBody.\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	page, err := tr.Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	if len(page.Sections) != 0 {
+		t.Errorf("expected no sections, got %d", len(page.Sections))
+	}
+	if len(page.Children) != 2 {
+		t.Errorf("expected 2 blocks, got %d", len(page.Children))
+	}
+}
+
+func TestTransform_EmptyParagraphs(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		mode     string
+		want     []string // block types, "empty" for an empty paragraph
+	}{
+		{
+			name:     "single blank line",
+			markdown: "One\n\nTwo\n",
+			want:     []string{"paragraph", "paragraph"},
+		},
+		{
+			name:     "extra blank lines collapsed by default",
+			markdown: "One\n\n\n\nTwo\n",
+			want:     []string{"paragraph", "paragraph"},
+		},
+		{
+			name:     "extra blank lines kept",
+			markdown: "One\n\n\n\nTwo\n",
+			mode:     "keep",
+			want:     []string{"paragraph", "empty", "empty", "paragraph"},
+		},
+		{
+			name:     "extra blank lines collapsed",
+			markdown: "One\n\n\n\nTwo\n",
+			mode:     "collapse",
+			want:     []string{"paragraph", "paragraph"},
+		},
+		{
+			name:     "after heading and code",
+			markdown: "# Title\n\n\nText\n\n```go\nx := 1\n\n\n```\n\n\nEnd\n",
+			mode:     "keep",
+			want:     []string{"heading_1", "empty", "paragraph", "code", "empty", "paragraph"},
+		},
+		{
+			name:     "blank lines inside lists are not blocks",
+			markdown: "- a\n\n\n- b\n\nText\n",
+			mode:     "keep",
+			want:     []string{"bulleted_list_item", "bulleted_list_item", "paragraph"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			note, err := parser.New().Parse("test.md", []byte(tt.markdown))
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			cfg := DefaultConfig()
+			cfg.EmptyParagraphs = tt.mode
+			page, err := New(nil, cfg).Transform(note)
+			if err != nil {
+				t.Fatalf("Transform() error: %v", err)
+			}
+
+			var got []string
+			for _, block := range page.Children {
+				kind := string(block.GetType())
+				if p, ok := block.(*notionapi.ParagraphBlock); ok && len(p.Paragraph.RichText) == 0 {
+					kind = "empty"
+				}
+				got = append(got, kind)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blocks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReverse_EmptyParagraphs(t *testing.T) {
+	blocks := []notionapi.Block{
+		&notionapi.ParagraphBlock{Paragraph: notionapi.Paragraph{RichText: []notionapi.RichText{{PlainText: "One"}}}},
+		&notionapi.ParagraphBlock{Paragraph: notionapi.Paragraph{RichText: []notionapi.RichText{}}},
+		&notionapi.ParagraphBlock{Paragraph: notionapi.Paragraph{RichText: []notionapi.RichText{{PlainText: "Two"}}}},
+	}
+
+	// By default an empty paragraph still pulls as a blank line.
+	kept, err := NewReverse(nil, nil).Transform(blocks)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	if kept != "One\n\n\nTwo\n\n" {
+		t.Errorf("keep: got %q", kept)
+	}
+
+	cfg := DefaultConfig()
+	cfg.EmptyParagraphs = "collapse"
+	collapsed, err := NewReverse(nil, cfg).Transform(blocks)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	if collapsed != "One\n\nTwo\n\n" {
+		t.Errorf("collapse: got %q", collapsed)
+	}
+
+	// Kept blank lines push back as the same empty paragraphs.
+	note, err := parser.New().Parse("test.md", []byte(kept))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	keep := DefaultConfig()
+	keep.EmptyParagraphs = "keep"
+	page, err := New(nil, keep).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	if len(page.Children) != len(blocks) {
+		t.Errorf("round trip gave %d blocks, want %d", len(page.Children), len(blocks))
+	}
+}
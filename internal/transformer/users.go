@@ -0,0 +1,142 @@
+package transformer
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jomei/notionapi"
+)
+
+// UserNamer looks up the display names of Notion workspace users.
+type UserNamer interface {
+	// UserName returns the name of a user, or false if it is not known.
+	UserName(userID string) (name string, found bool)
+}
+
+// SetUserNamer sets the lookup used to name pulled user mentions that
+// Config.UserMentions does not cover. Without one, they are named by the
+// text Notion gives them.
+func (t *ReverseTransformer) SetUserNamer(n UserNamer) {
+	t.userNamer = n
+}
+
+// userMentionName returns the name a pulled user mention is written with
+// after "@": its name in Config.UserMentions, then the looked-up name, the
+// name or text Notion sent with it, and last its ID.
+func (t *ReverseTransformer) userMentionName(user *notionapi.User, plainText string) string {
+	id := string(user.ID)
+	for name, userID := range t.config.UserMentions {
+		if sameNotionID(userID, id) {
+			return name
+		}
+	}
+	if t.userNamer != nil {
+		if name, ok := t.userNamer.UserName(id); ok && name != "" {
+			return name
+		}
+	}
+	if user.Name != "" {
+		return user.Name
+	}
+	if name := strings.TrimPrefix(strings.TrimSpace(plainText), "@"); name != "" {
+		return name
+	}
+	return id
+}
+
+// sameNotionID reports whether two Notion IDs are equal, with or without
+// dashes.
+func sameNotionID(a, b string) bool {
+	return strings.EqualFold(strings.ReplaceAll(a, "-", ""), strings.ReplaceAll(b, "-", ""))
+}
+
+// expandUserMentions converts "@Name" in plain text runs to mentions of
+// the users named in Config.UserMentions. Longer names are matched first,
+// and a name must not continue into a word, so "@Ada" does not match
+// "@Adam". Code and links are left alone.
+func (t *Transformer) expandUserMentions(richText []notionapi.RichText) []notionapi.RichText {
+	names := make([]string, 0, len(t.config.UserMentions))
+	for name := range t.config.UserMentions {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	var result []notionapi.RichText
+	for _, rt := range mergePlainRuns(richText) {
+		if !isPlainRun(rt) {
+			result = append(result, rt)
+			continue
+		}
+		result = append(result, t.splitUserMentions(rt, names)...)
+	}
+	return result
+}
+
+// splitUserMentions splits a plain text run at the user mentions in it.
+func (t *Transformer) splitUserMentions(rt notionapi.RichText, names []string) []notionapi.RichText {
+	content := rt.Text.Content
+	var result []notionapi.RichText
+	start := 0
+	for i := 0; i < len(content); i++ {
+		if content[i] != '@' {
+			continue
+		}
+		if before, _ := utf8.DecodeLastRuneInString(content[:i]); i > 0 && isWordRune(before) {
+			continue // An e-mail address or a word with an "@".
+		}
+		name := matchUserName(content[i+1:], names)
+		if name == "" {
+			continue
+		}
+		if i > start {
+			result = append(result, plainRun(content[start:i], rt.Annotations))
+		}
+		result = append(result, notionapi.RichText{
+			Type: "mention",
+			Mention: &notionapi.Mention{
+				Type: "user",
+				User: &notionapi.User{Object: "user", ID: notionapi.UserID(t.config.UserMentions[name])},
+			},
+			Annotations: copyAnnotations(rt.Annotations),
+			PlainText:   "@" + name,
+		})
+		start = i + 1 + len(name)
+		i = start - 1
+	}
+	if start == 0 {
+		return []notionapi.RichText{rt}
+	}
+	if start < len(content) {
+		result = append(result, plainRun(content[start:], rt.Annotations))
+	}
+	return result
+}
+
+// matchUserName returns the first of names that text starts with as a
+// whole word, or "".
+func matchUserName(text string, names []string) string {
+	for _, name := range names {
+		if !strings.HasPrefix(text, name) {
+			continue
+		}
+		if next, _ := utf8.DecodeRuneInString(text[len(name):]); len(text) == len(name) || !isWordRune(next) {
+			return name
+		}
+	}
+	return ""
+}
+
+// isWordRune reports whether r is part of a word: a letter, digit, or
+// underscore.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
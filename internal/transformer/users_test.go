@@ -0,0 +1,80 @@
+package transformer
+
+import (
+	"testing"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+)
+
+// mockUserNamer names users from a map.
+type mockUserNamer map[string]string
+
+func (m mockUserNamer) UserName(userID string) (string, bool) {
+	name, ok := m[userID]
+	return name, ok
+}
+
+func TestTransform_UserMentions(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.UserMentions = map[string]string{"Ada": "user-1", "Ada Lovelace": "user-2"}
+	note, err := parser.New().Parse("note.md", []byte("Ask @Ada Lovelace and @Ada, not @Adam, ada@example.com or `@Ada`.\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	page, err := New(nil, cfg).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	var mentions []string
+	var text string
+	for _, rt := range page.Children[0].(*notionapi.ParagraphBlock).Paragraph.RichText {
+		if rt.Mention != nil {
+			mentions = append(mentions, string(rt.Mention.User.ID))
+			text += rt.PlainText
+			continue
+		}
+		text += rt.Text.Content
+	}
+	if len(mentions) != 2 || mentions[0] != "user-2" || mentions[1] != "user-1" {
+		t.Errorf("mentioned users = %v, want [user-2 user-1]", mentions)
+	}
+	if want := "Ask @Ada Lovelace and @Ada, not @Adam, ada@example.com or @Ada."; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+}
+
+func TestReverse_UserMentions(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.UserMentions = map[string]string{"Ada": "9b2f0000-0000-0000-0000-000000000001"}
+	rt := NewReverse(nil, cfg)
+	rt.SetUserNamer(mockUserNamer{"user-2": "Grace Hopper"})
+
+	mention := func(id, name, plain string) notionapi.RichText {
+		return notionapi.RichText{
+			Type:      "mention",
+			Mention:   &notionapi.Mention{Type: "user", User: &notionapi.User{ID: notionapi.UserID(id), Name: name}},
+			PlainText: plain,
+		}
+	}
+	tests := []struct {
+		name string
+		rt   notionapi.RichText
+		want string
+	}{
+		{"configured", mention("9b2f0000000000000000000000000001", "", "@Anonymous"), "@Ada"},
+		{"looked up", mention("user-2", "", ""), "@Grace Hopper"},
+		{"sent name", mention("user-3", "Alan Turing", "@Alan Turing"), "@Alan Turing"},
+		{"plain text", mention("user-4", "", "@Edsger"), "@Edsger"},
+		{"id only", mention("user-5", "", ""), "@user-5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rt.richTextToMarkdown([]notionapi.RichText{tt.rt}); got != tt.want {
+				t.Errorf("richTextToMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
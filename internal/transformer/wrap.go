@@ -0,0 +1,240 @@
+package transformer
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// wrapProtectedRegex matches inline spans that are never broken across
+// lines: code spans, wiki-links, links and images, inline math, and HTML
+// tags.
+var wrapProtectedRegex = regexp.MustCompile("`+[^`]*`+|!?\\[\\[[^\\]]*\\]\\]|!?\\[[^\\]]*\\]\\([^)]*\\)|\\$[^$\\s][^$]*\\$|<[^>]*>")
+
+// orderedMarkerRegex matches a word that would start an ordered list item
+// at the beginning of a line.
+var orderedMarkerRegex = regexp.MustCompile(`^\d+[.)]$`)
+
+// wrapParagraphs applies Config.WrapSource and Config.WrapWidth to pulled
+// markdown. Only paragraph lines are changed; code, math, tables, lists,
+// quotes, and frontmatter are left as they are.
+func (t *ReverseTransformer) wrapParagraphs(md string) string {
+	if t.config.WrapWidth <= 0 && t.config.WrapSource == nil {
+		return md
+	}
+
+	var local map[string][]string
+	if t.config.WrapSource != nil {
+		local = localParagraphs(string(t.config.WrapSource))
+	}
+
+	lines := strings.Split(md, "\n")
+	para := paragraphLines(lines)
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		if !para[i] {
+			out = append(out, lines[i])
+			continue
+		}
+		// Only single-line paragraphs, as Notion paragraphs are pulled,
+		// are rewrapped.
+		single := (i+1 == len(lines) || !para[i+1]) && (i == 0 || !para[i-1])
+		if !single {
+			out = append(out, lines[i])
+			continue
+		}
+		if kept, ok := local[lines[i]]; ok {
+			out = append(out, kept...)
+			continue
+		}
+		if t.config.WrapWidth > 0 {
+			out = append(out, wrapLine(lines[i], t.config.WrapWidth)...)
+			continue
+		}
+		out = append(out, lines[i])
+	}
+	return strings.Join(out, "\n")
+}
+
+// localParagraphs returns the wrapped paragraphs of a local note keyed by
+// their text joined onto one line.
+func localParagraphs(md string) map[string][]string {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	para := paragraphLines(lines)
+	paragraphs := make(map[string][]string)
+	for i := 0; i < len(lines); {
+		if !para[i] {
+			i++
+			continue
+		}
+		start := i
+		for i < len(lines) && para[i] {
+			i++
+		}
+		if i-start < 2 {
+			continue
+		}
+		run := lines[start:i]
+		words := make([]string, len(run))
+		for j, line := range run {
+			words[j] = strings.TrimRight(line, " ")
+		}
+		// Trailing spaces are hard breaks, which pull differently.
+		if strings.Join(words, "\n") != strings.Join(run, "\n") {
+			continue
+		}
+		paragraphs[strings.Join(words, " ")] = run
+	}
+	return paragraphs
+}
+
+// paragraphLines reports which lines of a note are paragraph text: the
+// unindented lines of runs that start after a blank line, outside
+// frontmatter, code fences, math blocks, and %% comments, that do not
+// start another kind of block.
+func paragraphLines(lines []string) []bool {
+	para := make([]bool, len(lines))
+	var (
+		frontmatter = len(lines) > 0 && strings.TrimSpace(lines[0]) == frontmatterFence
+		fence       string
+		math        bool
+		comment     bool
+		afterBlank  = true
+	)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case frontmatter:
+			frontmatter = i == 0 || trimmed != frontmatterFence
+			afterBlank = false
+			continue
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		case math:
+			math = !strings.HasSuffix(trimmed, "$$")
+			continue
+		case comment:
+			comment = strings.Count(trimmed, "%%")%2 == 0
+			continue
+		}
+
+		if f := codeFence(trimmed); f != "" {
+			fence = f
+			afterBlank = false
+			continue
+		}
+		if strings.HasPrefix(trimmed, "$$") {
+			math = len(trimmed) == 2 || !strings.HasSuffix(trimmed, "$$")
+			afterBlank = false
+			continue
+		}
+		if strings.Count(trimmed, "%%")%2 == 1 {
+			comment = true
+			afterBlank = false
+			continue
+		}
+
+		if trimmed == "" {
+			afterBlank = true
+			continue
+		}
+		para[i] = (afterBlank || (i > 0 && para[i-1])) && isParagraphStart(line)
+		afterBlank = false
+	}
+	return para
+}
+
+// frontmatterFence delimits frontmatter.
+const frontmatterFence = "---"
+
+// codeFence returns the fence a line opens a code block with, or "".
+func codeFence(trimmed string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(trimmed) - len(strings.TrimLeft(trimmed, c))
+		if n >= 3 {
+			return strings.Repeat(c, n)
+		}
+	}
+	return ""
+}
+
+// isParagraphStart reports whether a line is plain paragraph text rather
+// than the start of a heading, list item, quote, table, HTML block, link
+// definition, or other block.
+func isParagraphStart(line string) bool {
+	if line == "" || line[0] == ' ' || line[0] == '\t' {
+		return false
+	}
+	word, _, _ := strings.Cut(line, " ")
+	return !startsBlock(word)
+}
+
+// startsBlock reports whether a word at the start of a line would begin a
+// block other than a paragraph, or end a paragraph as a setext underline.
+func startsBlock(word string) bool {
+	switch {
+	case word == "":
+		return false
+	case strings.Trim(word, "#") == "" && len(word) <= 6:
+		return true // ATX heading
+	case strings.ContainsRune(">|<", rune(word[0])):
+		return true // Quote, table, or HTML block
+	case strings.ContainsRune("-*+_=", rune(word[0])) && strings.Trim(word, word[:1]) == "":
+		return true // List marker, thematic break, or setext underline
+	case strings.HasPrefix(word, "```"), strings.HasPrefix(word, "~~~"), strings.HasPrefix(word, "$$"):
+		return true
+	case word[0] == '[' && strings.HasSuffix(word, "]:"):
+		return true // Link or footnote definition
+	}
+	return orderedMarkerRegex.MatchString(word)
+}
+
+// wrapLine breaks a paragraph line at spaces into lines of at most width
+// characters where possible. Protected spans are not broken, and no line
+// is made to start a new block or to end in a hard break.
+func wrapLine(line string, width int) []string {
+	text := strings.TrimRight(line, " ")
+	trailing := line[len(text):]
+	if utf8.RuneCountInString(text) <= width {
+		return []string{line}
+	}
+
+	protected := make([]bool, len(text))
+	for _, span := range wrapProtectedRegex.FindAllStringIndex(text, -1) {
+		for i := span[0]; i < span[1]; i++ {
+			protected[i] = true
+		}
+	}
+
+	// Split at the spaces that can become line breaks.
+	var words []string
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] != ' ' || protected[i] {
+			continue
+		}
+		word, next := text[start:i], text[i+1:]
+		nextWord, _, _ := strings.Cut(next, " ")
+		if word == "" || nextWord == "" || strings.HasSuffix(word, " ") || strings.HasSuffix(word, "\\") || startsBlock(nextWord) {
+			continue
+		}
+		words = append(words, word)
+		start = i + 1
+	}
+	words = append(words, text[start:])
+
+	var lines []string
+	current := words[0]
+	for _, word := range words[1:] {
+		if utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > width {
+			lines = append(lines, current)
+			current = word
+			continue
+		}
+		current += " " + word
+	}
+	return append(lines, current+trailing)
+}
@@ -0,0 +1,61 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrapLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"short", "Fits.", []string{"Fits."}},
+		{"wraps", "one two three four five six", []string{"one two", "three", "four", "five six"}},
+		{"long word", "supercalifragilistic word", []string{"supercalifragilistic", "word"}},
+		{"code span kept", "see `a b c d` here", []string{"see", "`a b c d`", "here"}},
+		{"wiki-link kept", "see [[My Note]] now", []string{"see", "[[My Note]]", "now"}},
+		{"no list marker", "ab cd - ef gh", []string{"ab cd -", "ef gh"}},
+		{"no heading", "ab cd # ef", []string{"ab cd #", "ef"}},
+		{"no ordered marker", "abc de 1. ef", []string{"abc", "de 1. ef"}},
+		{"no hard break", `ab\ cd ef`, []string{`ab\ cd`, "ef"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapLine(tt.line, 8)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("wrapLine(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapParagraphs(t *testing.T) {
+	md := "---\ntitle: a very long title that is not wrapped\n---\n\n" +
+		"# A heading that is far too long to fit\n\n" +
+		"A paragraph that is far too long to fit.\n\n" +
+		"```\ncode that is far too long to fit on a line\n```\n\n" +
+		"| a table row that is far too long to fit |\n| --- |\n\n" +
+		"- a list item that is far too long to fit\n"
+
+	rt := NewReverse(nil, &Config{WrapWidth: 20})
+	want := "---\ntitle: a very long title that is not wrapped\n---\n\n" +
+		"# A heading that is far too long to fit\n\n" +
+		"A paragraph that is\nfar too long to fit.\n\n" +
+		"```\ncode that is far too long to fit on a line\n```\n\n" +
+		"| a table row that is far too long to fit |\n| --- |\n\n" +
+		"- a list item that is far too long to fit\n"
+	if got := rt.wrapParagraphs(md); got != want {
+		t.Errorf("wrapParagraphs() =\n%s\nwant:\n%s", got, want)
+	}
+
+	// Without a width, the local note's wrapping is kept for unchanged
+	// paragraphs only.
+	local := "Kept as\nwrapped locally.\n\nChanged\nparagraph.\n"
+	rt = NewReverse(nil, &Config{WrapSource: []byte(local)})
+	got := rt.wrapParagraphs("Kept as wrapped locally.\n\nChanged paragraph, edited in Notion.\n")
+	if want := "Kept as\nwrapped locally.\n\nChanged paragraph, edited in Notion.\n"; got != want {
+		t.Errorf("wrapParagraphs() with source = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package vault
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// appConfig is the part of .obsidian/app.json the scanner reads.
+type appConfig struct {
+	AttachmentFolderPath string `json:"attachmentFolderPath"`
+}
+
+// readAttachmentFolder returns the attachment folder configured in the
+// vault's .obsidian/app.json, or "" if the file or setting is missing.
+func readAttachmentFolder(root string) string {
+	data, err := os.ReadFile(filepath.Join(root, ".obsidian", "app.json"))
+	if err != nil {
+		return ""
+	}
+	var cfg appConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return ""
+	}
+	return cfg.AttachmentFolderPath
+}
+
+// SetAttachmentFolder overrides the attachment folder read from
+// .obsidian/app.json. It uses the same syntax as Obsidian's setting.
+func (s *Scanner) SetAttachmentFolder(folder string) {
+	s.attachmentFolder = folder
+}
+
+// AttachmentDir returns the vault-relative folder new attachments of a note
+// are stored in, following Obsidian's "Default location for new
+// attachments": "/" (or unset) is the vault root, "./" the note's folder,
+// "./name" a subfolder next to the note, and anything else a vault folder.
+// The vault root is returned as ".".
+func (s *Scanner) AttachmentDir(fromNote string) string {
+	folder := strings.TrimSpace(filepath.FromSlash(s.attachmentFolder))
+	switch {
+	case folder == "" || folder == string(filepath.Separator):
+		return "."
+	case folder == "." || strings.HasPrefix(folder, "."+string(filepath.Separator)):
+		return filepath.Join(filepath.Dir(fromNote), folder)
+	}
+
+	dir := filepath.Clean(strings.TrimPrefix(folder, string(filepath.Separator)))
+	if dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		return "."
+	}
+	return dir
+}
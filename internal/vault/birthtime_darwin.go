@@ -0,0 +1,17 @@
+//go:build darwin
+
+package vault
+
+import (
+	"syscall"
+	"time"
+)
+
+// birthTime reads the creation time APFS and HFS+ record for every file.
+func birthTime(path string) time.Time {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return time.Time{}
+	}
+	return time.Unix(st.Birthtimespec.Unix())
+}
@@ -0,0 +1,22 @@
+//go:build linux
+
+package vault
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// birthTime reads the creation time with statx, which most current
+// filesystems (ext4, btrfs, xfs) fill in.
+func birthTime(path string) time.Time {
+	var stx unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, path, 0, unix.STATX_BTIME, &stx); err != nil {
+		return time.Time{}
+	}
+	if stx.Mask&unix.STATX_BTIME == 0 {
+		return time.Time{}
+	}
+	return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec))
+}
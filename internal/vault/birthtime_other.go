@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !windows
+
+package vault
+
+import "time"
+
+// birthTime reports no creation time on platforms without a portable way
+// to read it.
+func birthTime(path string) time.Time {
+	return time.Time{}
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package vault
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// birthTime reads the creation time NTFS records for every file.
+func birthTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(0, data.CreationTime.Nanoseconds())
+}
@@ -0,0 +1,17 @@
+package vault
+
+import (
+	"os"
+	"time"
+)
+
+// FileTimes returns when the file at path was created and last modified.
+// The creation time is zero where the platform or filesystem does not
+// record it.
+func FileTimes(path string) (created, modified time.Time, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return birthTime(path), info.ModTime(), nil
+}
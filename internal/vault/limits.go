@@ -0,0 +1,66 @@
+package vault
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// binarySniffLen is how much of a note is inspected for binary content.
+const binarySniffLen = 8000
+
+// SkipError reports a file left out of a sync because it is larger than the
+// configured limit or, for notes, binary.
+type SkipError struct {
+	Path   string
+	Size   int64
+	Limit  int64 // Set when the file is over the size limit.
+	Binary bool
+}
+
+func (e *SkipError) Error() string {
+	if e.Binary {
+		return fmt.Sprintf("%s: binary content", e.Path)
+	}
+	return fmt.Sprintf("%s: %d bytes exceeds the %d byte limit", e.Path, e.Size, e.Limit)
+}
+
+// CheckSize returns a *SkipError if the file at relPath is larger than
+// limit bytes. A limit of 0 disables the check.
+func (s *Scanner) CheckSize(relPath string, limit int64) error {
+	info, err := os.Stat(filepath.Join(s.root, relPath))
+	if err != nil {
+		return err
+	}
+	if limit > 0 && info.Size() > limit {
+		return &SkipError{Path: relPath, Size: info.Size(), Limit: limit}
+	}
+	return nil
+}
+
+// CheckNote returns a *SkipError if the note at relPath is larger than
+// limit bytes or is not text, such as a file misnamed with a .md extension.
+func (s *Scanner) CheckNote(relPath string, limit int64) error {
+	if err := s.CheckSize(relPath, limit); err != nil {
+		return err
+	}
+
+	f, err := os.Open(filepath.Join(s.root, relPath))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	head := make([]byte, binarySniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	// Text files never contain NUL bytes; this is the same test git uses.
+	if bytes.IndexByte(head[:n], 0) >= 0 {
+		return &SkipError{Path: relPath, Binary: true}
+	}
+	return nil
+}
@@ -13,6 +13,13 @@ import (
 type Scanner struct {
 	root    string
 	ignore  []string
+
+	// attachmentFolder is Obsidian's attachment folder setting.
+	attachmentFolder string
+
+	// templateFolders are the Templates and Templater plugin folders,
+	// which are never scanned.
+	templateFolders []string
 }
 
 // File represents a markdown file in the vault.
@@ -30,11 +37,51 @@ type File struct {
 // NewScanner creates a new vault Scanner.
 func NewScanner(root string, ignore []string) *Scanner {
 	return &Scanner{
-		root:   root,
-		ignore: ignore,
+		root:             root,
+		ignore:           ignore,
+		attachmentFolder: readAttachmentFolder(root),
+		templateFolders:  readTemplateFolders(root),
+	}
+}
+
+// SetVaultRoot reads the .obsidian settings from the whole vault when the
+// scanner walks only a folder of it. Attachment and template folders are
+// made relative to that folder; attachments kept outside it go to the
+// folder itself, and templates outside it are never scanned anyway.
+func (s *Scanner) SetVaultRoot(vaultRoot string) {
+	dir, err := filepath.Rel(vaultRoot, s.root)
+	if err != nil || dir == "." {
+		return
+	}
+
+	s.attachmentFolder = "/"
+	switch folder := readAttachmentFolder(vaultRoot); {
+	case folder == "." || strings.HasPrefix(folder, "./"):
+		s.attachmentFolder = folder
+	default:
+		if rel, ok := insideFolder(cleanFolder(folder), dir); ok {
+			s.attachmentFolder = rel
+		}
+	}
+
+	s.templateFolders = nil
+	for _, folder := range readTemplateFolders(vaultRoot) {
+		if rel, ok := insideFolder(folder, dir); ok {
+			s.templateFolders = append(s.templateFolders, rel)
+		}
 	}
 }
 
+// insideFolder returns a vault-relative path relative to dir, if it is
+// below dir.
+func insideFolder(path, dir string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if path == "" || err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
 // Scan walks the vault and returns all markdown files.
 func (s *Scanner) Scan(ctx context.Context) ([]File, error) {
 	var files []File
@@ -215,13 +262,70 @@ func (s *Scanner) Exists(relPath string) bool {
 	return err == nil
 }
 
+// FindAttachment locates a file referenced from a note, the way Obsidian
+// resolves embeds: relative to the note, then in the note's attachment
+// folder, then relative to the vault root, then by file name anywhere in
+// the vault (shortest path wins).
+// Returns the vault-relative path, or "" if no file matches.
+func (s *Scanner) FindAttachment(ref, fromNote string) (string, error) {
+	ref = filepath.FromSlash(strings.TrimPrefix(ref, "file://"))
+
+	candidates := []string{
+		filepath.Join(filepath.Dir(fromNote), ref),
+		filepath.Join(s.AttachmentDir(fromNote), ref),
+		filepath.Clean(ref),
+	}
+	for _, c := range candidates {
+		if strings.HasPrefix(c, "..") {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(s.root, c)); err == nil && !info.IsDir() {
+			return c, nil
+		}
+	}
+
+	// Fall back to a vault-wide search by file name.
+	name := filepath.Base(ref)
+	var found string
+	err := filepath.WalkDir(s.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != s.root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Name() != name {
+			return nil
+		}
+		relPath, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		if found == "" || len(relPath) < len(found) {
+			found = relPath
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return found, nil
+}
+
 // Root returns the vault root path.
 func (s *Scanner) Root() string {
 	return s.root
 }
 
-// shouldIgnore checks if a path matches any ignore pattern.
+// shouldIgnore checks if a path matches any ignore pattern or is a template.
 func (s *Scanner) shouldIgnore(path string) bool {
+	if s.IsTemplate(path) {
+		return true
+	}
 	for _, pattern := range s.ignore {
 		matched, _ := filepath.Match(pattern, path)
 		if matched {
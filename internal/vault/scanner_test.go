@@ -4,6 +4,8 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
 	"time"
 )
@@ -496,3 +498,265 @@ func TestScanner_ListDirectories_ContextCancellation(t *testing.T) {
 		t.Error("expected context cancellation error")
 	}
 }
+
+func TestScanner_FindAttachment(t *testing.T) {
+	vaultPath := setupTestVault(t)
+	defer os.RemoveAll(vaultPath)
+
+	files := map[string]string{
+		"notes/local.png":         "local",
+		"attachments/shared.png":  "shared",
+		"attachments/deep/x.png":  "deep",
+		"other/deeper/more/x.png": "deeper",
+		".trash/missing-here.png": "hidden",
+	}
+	for path, content := range files {
+		full := filepath.Join(vaultPath, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	scanner := NewScanner(vaultPath, nil)
+
+	tests := []struct {
+		name     string
+		ref      string
+		fromNote string
+		expected string
+	}{
+		{"relative to note", "local.png", "notes/note1.md", filepath.Join("notes", "local.png")},
+		{"relative to root", "attachments/shared.png", "notes/note1.md", filepath.Join("attachments", "shared.png")},
+		{"by name anywhere", "shared.png", "notes/note1.md", filepath.Join("attachments", "shared.png")},
+		{"shortest path wins", "x.png", "root.md", filepath.Join("attachments", "deep", "x.png")},
+		{"hidden dirs skipped", "missing-here.png", "root.md", ""},
+		{"not found", "nope.png", "root.md", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scanner.FindAttachment(tt.ref, tt.fromNote)
+			if err != nil {
+				t.Fatalf("FindAttachment() error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("FindAttachment(%q, %q) = %q, want %q", tt.ref, tt.fromNote, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestScanner_CheckNote(t *testing.T) {
+	vaultPath := setupTestVault(t)
+	defer os.RemoveAll(vaultPath)
+
+	if err := os.WriteFile(filepath.Join(vaultPath, "binary.md"), []byte("PK\x03\x04\x00\x00"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	scanner := NewScanner(vaultPath, nil)
+
+	if err := scanner.CheckNote("root.md", 1024); err != nil {
+		t.Errorf("CheckNote(root.md) = %v, want nil", err)
+	}
+
+	err := scanner.CheckNote("root.md", 4)
+	skip, ok := err.(*SkipError)
+	if !ok || skip.Limit != 4 || skip.Size != int64(len("# Root note")) {
+		t.Errorf("CheckNote over limit = %v, want size SkipError", err)
+	}
+
+	// No limit still detects binary content.
+	err = scanner.CheckNote("binary.md", 0)
+	if skip, ok := err.(*SkipError); !ok || !skip.Binary {
+		t.Errorf("CheckNote(binary.md) = %v, want binary SkipError", err)
+	}
+
+	if err := scanner.CheckSize("binary.md", 0); err != nil {
+		t.Errorf("CheckSize without limit = %v, want nil", err)
+	}
+}
+
+func TestScanner_AttachmentDir(t *testing.T) {
+	tests := []struct {
+		folder   string
+		fromNote string
+		expected string
+	}{
+		{"", "notes/note.md", "."},
+		{"/", "notes/note.md", "."},
+		{"./", "notes/note.md", "notes"},
+		{"./assets", "notes/note.md", filepath.Join("notes", "assets")},
+		{"./assets", "root.md", "assets"},
+		{"Files/Attachments", "notes/note.md", filepath.Join("Files", "Attachments")},
+		{"/Files", "notes/note.md", "Files"},
+		{"../outside", "notes/note.md", "."},
+	}
+
+	scanner := NewScanner(t.TempDir(), nil)
+	for _, tt := range tests {
+		scanner.SetAttachmentFolder(tt.folder)
+		if got := scanner.AttachmentDir(tt.fromNote); got != tt.expected {
+			t.Errorf("AttachmentDir(%q) with folder %q = %q, want %q", tt.fromNote, tt.folder, got, tt.expected)
+		}
+	}
+}
+
+func TestScanner_AttachmentFolderFromAppJSON(t *testing.T) {
+	vaultPath := setupTestVault(t)
+	defer os.RemoveAll(vaultPath)
+
+	files := map[string]string{
+		".obsidian/app.json": `{"attachmentFolderPath": "assets", "alwaysUpdateLinks": true}`,
+		"assets/x.png":       "configured",
+		"x/x.png":            "elsewhere",
+	}
+	for path, content := range files {
+		full := filepath.Join(vaultPath, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	scanner := NewScanner(vaultPath, nil)
+	if got := scanner.AttachmentDir("notes/note1.md"); got != "assets" {
+		t.Errorf("AttachmentDir() = %q, want assets", got)
+	}
+
+	// The attachment folder is checked before searching the vault by name.
+	got, err := scanner.FindAttachment("x.png", "notes/note1.md")
+	if err != nil {
+		t.Fatalf("FindAttachment() error: %v", err)
+	}
+	if got != filepath.Join("assets", "x.png") {
+		t.Errorf("FindAttachment() = %q, want assets/x.png", got)
+	}
+}
+
+func TestScanner_TemplateFolders(t *testing.T) {
+	vaultPath := t.TempDir()
+	files := map[string]string{
+		".obsidian/templates.json":                       `{"folder": "Templates"}`,
+		".obsidian/plugins/templater-obsidian/data.json": `{"templates_folder": "/Meta/Templater/", "trigger_on_file_creation": true}`,
+		"Templates/daily.md":                             "# {{date}}",
+		"Meta/Templater/meeting.md":                      "<% tp.file.title %>",
+		"Meta/notes.md":                                  "kept",
+		"TemplatesArchive/old.md":                        "kept",
+		"note.md":                                        "kept",
+	}
+	for path, content := range files {
+		full := filepath.Join(vaultPath, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	scanner := NewScanner(vaultPath, nil)
+	want := []string{"Templates", filepath.Join("Meta", "Templater")}
+	if got := scanner.TemplateFolders(); !reflect.DeepEqual(got, want) {
+		t.Errorf("TemplateFolders() = %v, want %v", got, want)
+	}
+
+	scanned, err := scanner.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	var paths []string
+	for _, f := range scanned {
+		paths = append(paths, f.Path)
+	}
+	sort.Strings(paths)
+	wantPaths := []string{filepath.Join("Meta", "notes.md"), filepath.Join("TemplatesArchive", "old.md"), "note.md"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("Scan() = %v, want %v", paths, wantPaths)
+	}
+
+	// Clearing the folders includes templates again.
+	scanner.SetTemplateFolders(nil)
+	if scanner.IsTemplate(filepath.Join("Templates", "daily.md")) {
+		t.Error("IsTemplate() = true after SetTemplateFolders(nil)")
+	}
+}
+
+func TestScanner_SetVaultRoot(t *testing.T) {
+	vaultPath := t.TempDir()
+	files := map[string]string{
+		".obsidian/app.json":       `{"attachmentFolderPath": "Public/assets"}`,
+		".obsidian/templates.json": `{"folder": "Public/Templates"}`,
+		"Public/Templates/daily.md": "# {{date}}",
+		"Public/note.md":           "kept",
+		"Private/note.md":          "out of scope",
+	}
+	for path, content := range files {
+		full := filepath.Join(vaultPath, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	scanner := NewScanner(filepath.Join(vaultPath, "Public"), nil)
+	scanner.SetVaultRoot(vaultPath)
+	if got := scanner.AttachmentDir("note.md"); got != "assets" {
+		t.Errorf("AttachmentDir() = %q, want assets", got)
+	}
+	if want := []string{"Templates"}; !reflect.DeepEqual(scanner.TemplateFolders(), want) {
+		t.Errorf("TemplateFolders() = %v, want %v", scanner.TemplateFolders(), want)
+	}
+
+	scanned, err := scanner.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if len(scanned) != 1 || scanned[0].Path != "note.md" {
+		t.Errorf("Scan() = %v, want only note.md", scanned)
+	}
+
+	// Settings that point outside the scanned folder fall back to its root.
+	scanner = NewScanner(filepath.Join(vaultPath, "Private"), nil)
+	scanner.SetVaultRoot(vaultPath)
+	if got := scanner.AttachmentDir("note.md"); got != "." {
+		t.Errorf("AttachmentDir() outside scope = %q, want vault root", got)
+	}
+	if got := scanner.TemplateFolders(); len(got) != 0 {
+		t.Errorf("TemplateFolders() outside scope = %v, want none", got)
+	}
+}
+
+func TestFileTimes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.md")
+	if err := os.WriteFile(path, []byte("# Note\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	created, modified, err := FileTimes(path)
+	if err != nil {
+		t.Fatalf("FileTimes() error: %v", err)
+	}
+	if !modified.Equal(mtime) {
+		t.Errorf("modified = %v, want %v", modified, mtime)
+	}
+	// Creation time is optional, but never later than now.
+	if created.After(time.Now()) {
+		t.Errorf("created = %v is in the future", created)
+	}
+
+	if _, _, err := FileTimes(filepath.Join(t.TempDir(), "missing.md")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// templatesConfig is the part of .obsidian/templates.json, the core
+// Templates plugin's settings, the scanner reads.
+type templatesConfig struct {
+	Folder string `json:"folder"`
+}
+
+// templaterConfig is the part of the Templater plugin's data.json the
+// scanner reads.
+type templaterConfig struct {
+	TemplatesFolder string `json:"templates_folder"`
+}
+
+// readTemplateFolders returns the template folders configured for the core
+// Templates plugin and the Templater plugin, without duplicates. Missing
+// files and settings are skipped.
+func readTemplateFolders(root string) []string {
+	var core templatesConfig
+	readJSON(filepath.Join(root, ".obsidian", "templates.json"), &core)
+	var templater templaterConfig
+	readJSON(filepath.Join(root, ".obsidian", "plugins", "templater-obsidian", "data.json"), &templater)
+
+	var folders []string
+	for _, folder := range []string{core.Folder, templater.TemplatesFolder} {
+		if folder = cleanFolder(folder); folder != "" && !slices.Contains(folders, folder) {
+			folders = append(folders, folder)
+		}
+	}
+	return folders
+}
+
+// readJSON decodes a JSON file into v, leaving v unchanged if the file is
+// missing or invalid.
+func readJSON(path string, v any) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, v)
+}
+
+// cleanFolder converts a folder setting to a clean vault-relative path.
+// The vault root and paths outside the vault return "", since they cannot
+// be a template folder.
+func cleanFolder(folder string) string {
+	folder = strings.TrimSpace(filepath.FromSlash(folder))
+	folder = strings.TrimPrefix(folder, string(filepath.Separator))
+	if folder == "" {
+		return ""
+	}
+	folder = filepath.Clean(folder)
+	if folder == "." || folder == ".." || strings.HasPrefix(folder, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return folder
+}
+
+// SetTemplateFolders overrides the template folders read from the Obsidian
+// config. Passing none includes templates in scans.
+func (s *Scanner) SetTemplateFolders(folders []string) {
+	s.templateFolders = nil
+	for _, folder := range folders {
+		if folder = cleanFolder(folder); folder != "" {
+			s.templateFolders = append(s.templateFolders, folder)
+		}
+	}
+}
+
+// TemplateFolders returns the vault-relative template folders excluded
+// from scans.
+func (s *Scanner) TemplateFolders() []string {
+	return s.templateFolders
+}
+
+// IsTemplate reports whether relPath is inside a template folder.
+func (s *Scanner) IsTemplate(relPath string) bool {
+	relPath = filepath.Clean(relPath)
+	for _, folder := range s.templateFolders {
+		if relPath == folder || strings.HasPrefix(relPath, folder+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,386 @@
+// Package obsidiannotion is the public API for embedding Obsidian-Notion
+// sync in other tools.
+//
+// It exposes the conversion pipeline used by the obsidian-notion CLI:
+// Parse reads Obsidian markdown, Transform converts a parsed note to a
+// Notion page, and ReverseTransform converts a Notion page back to
+// markdown. A Syncer pushes and pulls single notes while keeping the same
+// state database the CLI uses, so both can work on one vault.
+//
+// The interfaces callers implement are versioned. LinkResolverV1 and
+// PathLookupV1 will not change; incompatible changes get a new V2 interface,
+// and the unversioned aliases keep pointing at the version they name today
+// until the next major release. Note, Page, and TransformConfig belong to
+// this package and are converted to the converter's own types at its
+// boundary, so they only gain fields.
+package obsidiannotion
+
+import (
+	"time"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+// Note is a parsed Obsidian note. Transform converts the note as Parse read
+// it; parse the changed markdown again to convert a changed note.
+type Note struct {
+	// Path is the note's vault-relative path.
+	Path string
+
+	// Frontmatter is the note's YAML frontmatter.
+	Frontmatter map[string]any
+
+	// Tags are the note's tags, from its frontmatter and body, without
+	// the leading #.
+	Tags []string
+
+	// WikiLinks are the note's [[wiki-links]], in order.
+	WikiLinks []WikiLink
+
+	// Embeds are the targets of the note's ![[embeds]], in order.
+	Embeds []string
+
+	// Content is the note's markdown.
+	Content []byte
+
+	parsed *parser.ParsedNote
+}
+
+// WikiLink is a [[wiki-link]] in a note.
+type WikiLink struct {
+	// Target is the linked note's name or path.
+	Target string
+
+	// Alias is the link's display text, after |.
+	Alias string
+
+	// Heading and Block are the heading (after #) or block (after ^) the
+	// link points to, if any.
+	Heading string
+	Block   string
+
+	// Line is the link's line in the note, from 1.
+	Line int
+}
+
+// Page is a Notion page: its properties, content blocks, and the child
+// pages a note was split into.
+type Page struct {
+	// Properties are the page's properties, such as its title and tags.
+	Properties notionapi.Properties
+
+	// Children are the page's content blocks.
+	Children []notionapi.Block
+
+	// Icon and Cover, if set, are given to the page when it is created.
+	Icon  *notionapi.Icon
+	Cover *notionapi.Image
+
+	// Sections are the parts of a note split off into child pages (see
+	// TransformConfig.SplitOn), in order after Children.
+	Sections []Section
+
+	// CreatedTime and LastEditedTime are the page's timestamps in Notion,
+	// set for fetched pages.
+	CreatedTime    time.Time
+	LastEditedTime time.Time
+
+	// page keeps what the converter records about a pushed note for a
+	// later pull, such as the tags it was pushed as.
+	page *transformer.NotionPage
+}
+
+// Section is a part of a note below a heading, stored as a child page.
+type Section struct {
+	// Title is the heading's text, the child page's title.
+	Title string
+
+	// PageID is the child page's ID, once it exists.
+	PageID string
+
+	// Children are the section's content blocks.
+	Children []notionapi.Block
+}
+
+// TransformConfig controls how notes are converted to and from Notion. The
+// fields are those of the CLI's transform settings of the same names.
+// A nil config uses DefaultTransformConfig.
+type TransformConfig struct {
+	// UnresolvedLinkStyle is how unresolved wiki-links are pushed:
+	// "placeholder" (red text), "text", or "skip".
+	UnresolvedLinkStyle string
+
+	// CalloutIcons maps Obsidian callout types to Notion callout icons.
+	CalloutIcons map[string]string
+
+	// DataviewHandling is how dataview queries are pushed: "snapshot" or
+	// "placeholder".
+	DataviewHandling string
+
+	// FlattenHeadings pushes H4-H6 as H3, the smallest Notion heading.
+	FlattenHeadings bool
+
+	// PropertyMappings map frontmatter keys to Notion properties. Without
+	// any, the CLI's default mappings are used.
+	PropertyMappings []PropertyMapping
+
+	// UnmappedProperties is how keys and properties without a mapping are
+	// synced: "pull" (the default), "passthrough", or "ignore".
+	UnmappedProperties string
+
+	// SplitOn splits notes into child pages at headings: "" (never) or
+	// "h1".
+	SplitOn string
+
+	// EmojiShortcodes pushes :shortcode: text as emoji, and EmojiReverse
+	// pulls emoji as shortcodes.
+	EmojiShortcodes bool
+	EmojiReverse    bool
+
+	// UserMentions maps display names to Notion user IDs, for "@Name"
+	// mentions.
+	UserMentions map[string]string
+
+	// DateMentions is how date mentions are pulled: "text" (the default),
+	// "marker", or "iso".
+	DateMentions string
+
+	// HTMLHandling is how raw HTML is pushed: "convert" (the default),
+	// "preserve", or "strip".
+	HTMLHandling string
+
+	// TextColors is how colored text is pulled: "html" (the default),
+	// "highlight", or "strip".
+	TextColors string
+
+	// NestedTags is how nested tags are pushed: "keep" (the default),
+	// "expand", "flatten", or "top".
+	NestedTags string
+
+	// DividerMarker is the markdown dividers are pulled as: "---" (the
+	// default), "***", or "___".
+	DividerMarker string
+
+	// EmptyParagraphs is how blank lines map to empty paragraphs:
+	// "collapse" (the default) or "keep".
+	EmptyParagraphs string
+
+	// TaskStates maps alternative task states, as in "- [>] task", to
+	// "checked" or "unchecked" to-dos, or to a badge.
+	TaskStates map[string]string
+
+	// CreatedProperty and ModifiedProperty name the Notion date properties
+	// the note's creation and modification dates are synced with.
+	CreatedProperty  string
+	ModifiedProperty string
+
+	// WrapWidth wraps pulled paragraphs longer than this many characters.
+	// 0 leaves them on one line.
+	WrapWidth int
+}
+
+// PropertyMapping maps a frontmatter key to a Notion property.
+type PropertyMapping struct {
+	// ObsidianKey is the frontmatter key.
+	ObsidianKey string
+
+	// NotionName is the Notion property's name.
+	NotionName string
+
+	// NotionType is the Notion property's type, such as "select" or
+	// "multi_select".
+	NotionType string
+}
+
+// LinkResolverV1 resolves Obsidian wiki-link targets to Notion page IDs
+// when converting notes to Notion.
+type LinkResolverV1 interface {
+	// Resolve returns the Notion page ID for a wiki-link target, such as
+	// "Note" or "folder/Note".
+	Resolve(target string) (pageID string, found bool)
+}
+
+// PathLookupV1 resolves Notion page IDs to Obsidian paths when converting
+// pages back to markdown.
+type PathLookupV1 interface {
+	// LookupPath returns the vault-relative path of the note synced with a
+	// Notion page.
+	LookupPath(pageID string) (path string, found bool)
+}
+
+// LinkResolver is the current version of the link resolver interface.
+type LinkResolver = LinkResolverV1
+
+// PathLookup is the current version of the path lookup interface.
+type PathLookup = PathLookupV1
+
+// DefaultTransformConfig returns the conversion settings the CLI uses
+// without a config file.
+func DefaultTransformConfig() *TransformConfig {
+	d := transformer.DefaultConfig()
+	return &TransformConfig{
+		UnresolvedLinkStyle: d.UnresolvedLinkStyle,
+		CalloutIcons:        d.CalloutIcons,
+		DataviewHandling:    d.DataviewHandling,
+		FlattenHeadings:     d.FlattenHeadings,
+	}
+}
+
+// Parse parses the markdown content of an Obsidian note. path is the
+// note's vault-relative path, used for its title and in errors.
+func Parse(path string, content []byte) (*Note, error) {
+	parsed, err := parser.New().Parse(path, content)
+	if err != nil {
+		return nil, err
+	}
+	return newNote(parsed), nil
+}
+
+// Transform converts a parsed note to a Notion page. resolver may be nil,
+// in which case every wiki-link is treated as unresolved.
+func Transform(note *Note, resolver LinkResolver, cfg *TransformConfig) (*Page, error) {
+	parsed := note.parsed
+	if parsed == nil {
+		var err error
+		if parsed, err = parser.New().Parse(note.Path, note.Content); err != nil {
+			return nil, err
+		}
+	}
+	var r transformer.LinkResolver
+	if resolver != nil {
+		r = resolver
+	}
+	page, err := transformer.New(r, cfg.internal()).Transform(parsed)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(page), nil
+}
+
+// ReverseTransform converts a Notion page to Obsidian markdown, including
+// frontmatter. lookup may be nil, in which case links to other pages become
+// wiki-links to the page titles.
+func ReverseTransform(page *Page, lookup PathLookup, cfg *TransformConfig) ([]byte, error) {
+	var l transformer.PathLookup
+	if lookup != nil {
+		l = lookup
+	}
+	return transformer.NewReverse(l, cfg.internal()).NotionToMarkdown(page.internal())
+}
+
+// BlockHandler converts the content of a fenced code block to a Notion
+// block. It returns false to decline the block, in which case the built-in
+// handling for the language is used.
+type BlockHandler func(lang, content string) (notionapi.Block, bool)
+
+// InlineHandler converts an inline code span to rich text. It receives the
+// span's content with the registered prefix removed and the annotations of
+// the surrounding text. It returns false to decline the span, in which case
+// the span is rendered as inline code.
+type InlineHandler func(content string, annotations *notionapi.Annotations) ([]notionapi.RichText, bool)
+
+// RegisterBlockHandler registers a handler for fenced code blocks in a
+// language. It runs before the built-in handling; see BlockHandler.
+func RegisterBlockHandler(lang string, h BlockHandler) {
+	transformer.RegisterBlockHandler(lang, transformer.BlockHandler(h))
+}
+
+// RegisterInlineHandler registers a handler for inline code spans starting
+// with prefix. It runs before the built-in handling; see InlineHandler.
+func RegisterInlineHandler(prefix string, h InlineHandler) {
+	transformer.RegisterInlineHandler(prefix, transformer.InlineHandler(h))
+}
+
+// newNote returns the Note of a parsed note.
+func newNote(parsed *parser.ParsedNote) *Note {
+	note := &Note{
+		Path:        parsed.Path,
+		Frontmatter: parsed.Frontmatter,
+		Tags:        parsed.Tags,
+		Content:     parsed.Source,
+		parsed:      parsed,
+	}
+	for _, l := range parsed.WikiLinks {
+		note.WikiLinks = append(note.WikiLinks, WikiLink{Target: l.Target, Alias: l.Alias, Heading: l.Heading, Block: l.Block, Line: l.Line})
+	}
+	for _, e := range parsed.Embeds {
+		note.Embeds = append(note.Embeds, e.Target)
+	}
+	return note
+}
+
+// newPage returns the Page of a converted or fetched page.
+func newPage(page *transformer.NotionPage) *Page {
+	p := &Page{
+		Properties:     page.Properties,
+		Children:       page.Children,
+		Icon:           page.Icon,
+		Cover:          page.Cover,
+		CreatedTime:    page.CreatedTime,
+		LastEditedTime: page.LastEditedTime,
+		page:           page,
+	}
+	for _, sec := range page.Sections {
+		p.Sections = append(p.Sections, Section{Title: sec.Title, PageID: sec.PageID, Children: sec.Children})
+	}
+	return p
+}
+
+// internal returns the converter's page of p, with what it recorded when
+// the page was converted.
+func (p *Page) internal() *transformer.NotionPage {
+	page := &transformer.NotionPage{}
+	if p.page != nil {
+		*page = *p.page
+	}
+	page.Properties = p.Properties
+	page.Children = p.Children
+	page.Icon = p.Icon
+	page.Cover = p.Cover
+	page.CreatedTime = p.CreatedTime
+	page.LastEditedTime = p.LastEditedTime
+	page.Sections = nil
+	for _, sec := range p.Sections {
+		page.Sections = append(page.Sections, &transformer.PageSection{Title: sec.Title, PageID: sec.PageID, Children: sec.Children})
+	}
+	return page
+}
+
+// internal returns the converter's config of c, or nil for its defaults.
+func (c *TransformConfig) internal() *transformer.Config {
+	if c == nil {
+		return nil
+	}
+	cfg := &transformer.Config{
+		UnresolvedLinkStyle: c.UnresolvedLinkStyle,
+		CalloutIcons:        c.CalloutIcons,
+		DataviewHandling:    c.DataviewHandling,
+		FlattenHeadings:     c.FlattenHeadings,
+		UnmappedProperties:  c.UnmappedProperties,
+		SplitOn:             c.SplitOn,
+		EmojiShortcodes:     c.EmojiShortcodes,
+		EmojiReverse:        c.EmojiReverse,
+		UserMentions:        c.UserMentions,
+		DateMentions:        c.DateMentions,
+		HTMLHandling:        c.HTMLHandling,
+		TextColors:          c.TextColors,
+		NestedTags:          c.NestedTags,
+		DividerMarker:       c.DividerMarker,
+		EmptyParagraphs:     c.EmptyParagraphs,
+		TaskStates:          c.TaskStates,
+		CreatedProperty:     c.CreatedProperty,
+		ModifiedProperty:    c.ModifiedProperty,
+		WrapWidth:           c.WrapWidth,
+	}
+	for _, m := range c.PropertyMappings {
+		cfg.PropertyMappings = append(cfg.PropertyMappings, transformer.PropertyMapping{
+			ObsidianKey: m.ObsidianKey,
+			NotionName:  m.NotionName,
+			NotionType:  transformer.PropertyType(m.NotionType),
+		})
+	}
+	return cfg
+}
@@ -0,0 +1,145 @@
+package obsidiannotion
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jomei/notionapi"
+)
+
+// mapResolver resolves links from a map, standing in for a caller's own
+// LinkResolverV1 and PathLookupV1 implementations.
+type mapResolver map[string]string
+
+func (m mapResolver) Resolve(target string) (string, bool) {
+	id, ok := m[target]
+	return id, ok
+}
+
+func (m mapResolver) LookupPath(pageID string) (string, bool) {
+	for target, id := range m {
+		if id == pageID {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+func TestRoundTrip(t *testing.T) {
+	content := "---\ntitle: Example\n---\n\n# Heading\n\nSee [[Other]] and **bold** text.\n"
+	note, err := Parse("Example.md", []byte(content))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if len(note.WikiLinks) != 1 || note.WikiLinks[0].Target != "Other" {
+		t.Fatalf("WikiLinks = %+v; want [[Other]]", note.WikiLinks)
+	}
+
+	links := mapResolver{"Other": "0123456789abcdef0123456789abcdef"}
+	page, err := Transform(note, links, nil)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	var mentioned bool
+	for _, block := range page.Children {
+		// Notion fills in the plain text of rich text it returns, which is
+		// what the reverse transform reads.
+		var richText []notionapi.RichText
+		switch b := block.(type) {
+		case *notionapi.Heading1Block:
+			richText = b.Heading1.RichText
+		case *notionapi.ParagraphBlock:
+			richText = b.Paragraph.RichText
+		}
+		for i := range richText {
+			if richText[i].Text != nil {
+				richText[i].PlainText = richText[i].Text.Content
+			}
+			if richText[i].Mention != nil && richText[i].Mention.Page != nil {
+				mentioned = true
+			}
+		}
+	}
+	if !mentioned {
+		t.Errorf("Transform() did not link [[Other]] to its page: %+v", page.Children)
+	}
+
+	markdown, err := ReverseTransform(page, links, DefaultTransformConfig())
+	if err != nil {
+		t.Fatalf("ReverseTransform() error: %v", err)
+	}
+	for _, want := range []string{"# Heading", "[[Other]]", "**bold**"} {
+		if !strings.Contains(string(markdown), want) {
+			t.Errorf("ReverseTransform() = %q; missing %q", markdown, want)
+		}
+	}
+}
+
+func TestTransform_NilResolver(t *testing.T) {
+	note, err := Parse("a.md", []byte("Link to [[Missing]].\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if _, err := Transform(note, nil, nil); err != nil {
+		t.Fatalf("Transform() with nil resolver error: %v", err)
+	}
+}
+
+func TestNewSyncer(t *testing.T) {
+	vault := t.TempDir()
+
+	if _, err := NewSyncer(vault, ""); err == nil {
+		t.Error("NewSyncer() without a token succeeded")
+	}
+	if _, err := NewSyncer(filepath.Join(vault, "missing"), "token"); err == nil {
+		t.Error("NewSyncer() with a missing vault succeeded")
+	}
+
+	s, err := NewSyncer(vault, "token", WithDatabase("db"))
+	if err != nil {
+		t.Fatalf("NewSyncer() error: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := os.Stat(filepath.Join(vault, StateFile)); err != nil {
+		t.Errorf("state database not created in the vault: %v", err)
+	}
+	if err := s.Pull(context.Background(), "unsynced.md"); err == nil {
+		t.Error("Pull() of an unsynced note succeeded")
+	}
+}
+
+func TestTransform_Config(t *testing.T) {
+	// A note built by the caller, not by Parse, is parsed from its content.
+	note := &Note{Path: "Split.md", Content: []byte("Intro.\n\n# One\n\nFirst.\n\n# Two\n\nSecond.\n")}
+	cfg := DefaultTransformConfig()
+	cfg.SplitOn = "h1"
+	page, err := Transform(note, nil, cfg)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+	if len(page.Sections) != 2 || page.Sections[0].Title != "One" || page.Sections[1].Title != "Two" {
+		t.Errorf("Sections = %+v; want One and Two", page.Sections)
+	}
+
+	// The sections are pulled back under their headings.
+	for _, sec := range page.Sections {
+		for _, block := range sec.Children {
+			if p, ok := block.(*notionapi.ParagraphBlock); ok {
+				for i := range p.Paragraph.RichText {
+					p.Paragraph.RichText[i].PlainText = p.Paragraph.RichText[i].Text.Content
+				}
+			}
+		}
+	}
+	markdown, err := ReverseTransform(page, nil, cfg)
+	if err != nil {
+		t.Fatalf("ReverseTransform() error: %v", err)
+	}
+	if !strings.Contains(string(markdown), "# Two\n\nSecond.") {
+		t.Errorf("ReverseTransform() = %q; missing the second section", markdown)
+	}
+}
@@ -0,0 +1,312 @@
+package obsidiannotion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/notion"
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+	"github.com/adamancini/obsidian-notion-sync/internal/state"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+// StateFile is the name of the state database the CLI keeps in the vault.
+const StateFile = ".obsidian-notion.db"
+
+// Option configures a Syncer.
+type Option func(*Syncer)
+
+// WithDatabase sets the Notion database new pages are created in.
+func WithDatabase(databaseID string) Option {
+	return func(s *Syncer) {
+		s.databaseID = databaseID
+	}
+}
+
+// WithParentPage sets the Notion page new pages are created under, when no
+// database is set.
+func WithParentPage(pageID string) Option {
+	return func(s *Syncer) {
+		s.parentPageID = pageID
+	}
+}
+
+// WithStatePath sets the state database path. It defaults to StateFile in
+// the vault, shared with the CLI.
+func WithStatePath(path string) Option {
+	return func(s *Syncer) {
+		s.statePath = path
+	}
+}
+
+// WithTransformConfig sets the conversion settings used for every note.
+func WithTransformConfig(cfg *TransformConfig) Option {
+	return func(s *Syncer) {
+		s.transformCfg = cfg
+	}
+}
+
+// WithRateLimit sets the maximum Notion API requests per second.
+func WithRateLimit(requestsPerSecond float64) Option {
+	return func(s *Syncer) {
+		s.clientOpts = append(s.clientOpts, notion.WithRateLimit(requestsPerSecond))
+	}
+}
+
+// WithIgnoreTrivialChanges hashes notes so that reordering their
+// frontmatter keys does not change them, as the CLI does with
+// sync.ignore_trivial_changes. Use the same setting as the CLI on vaults
+// both sync.
+func WithIgnoreTrivialChanges(ignore bool) Option {
+	return func(s *Syncer) {
+		s.hasher.CanonicalFrontmatter = ignore
+	}
+}
+
+// Syncer pushes and pulls the notes of one vault. Wiki-links resolve
+// through the state database, so links between notes pushed by the Syncer
+// or the CLI become Notion page links.
+//
+// Attachments are not uploaded or downloaded; use the CLI for vaults that
+// embed local files.
+type Syncer struct {
+	vault        string
+	databaseID   string
+	parentPageID string
+	statePath    string
+	transformCfg *TransformConfig
+	clientOpts   []notion.ClientOption
+	hasher       state.Hasher
+
+	client *notion.Client
+	db     *state.DB
+	links  *state.LinkRegistry
+}
+
+// PushResult describes a pushed note.
+type PushResult struct {
+	// PageID is the ID of the note's Notion page.
+	PageID string
+
+	// Created reports whether the page was created by the push.
+	Created bool
+}
+
+// NewSyncer creates a Syncer for the vault at vaultPath using a Notion
+// integration token. Close the Syncer when done.
+func NewSyncer(vaultPath, token string, opts ...Option) (*Syncer, error) {
+	if token == "" {
+		return nil, errors.New("notion token is required")
+	}
+	info, err := os.Stat(vaultPath)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("vault: %s is not a directory", vaultPath)
+	}
+
+	s := &Syncer{vault: vaultPath}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.statePath == "" {
+		s.statePath = filepath.Join(vaultPath, StateFile)
+	}
+
+	db, err := state.Open(s.statePath)
+	if err != nil {
+		return nil, fmt.Errorf("open state: %w", err)
+	}
+	s.db = db
+	s.links = state.NewLinkRegistry(db)
+	s.client = notion.New(token, s.clientOpts...)
+	return s, nil
+}
+
+// Close closes the state database.
+func (s *Syncer) Close() error {
+	return s.db.Close()
+}
+
+// LinkResolver returns the resolver the Syncer uses for wiki-links.
+func (s *Syncer) LinkResolver() LinkResolver {
+	return s.links
+}
+
+// PathLookup returns the lookup the Syncer uses for links to other pages.
+func (s *Syncer) PathLookup() PathLookup {
+	return s.links
+}
+
+// Push converts the note at a vault-relative path and creates or updates
+// its Notion page.
+func (s *Syncer) Push(ctx context.Context, path string) (*PushResult, error) {
+	fullPath := filepath.Join(s.vault, path)
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("stat file: %w", err)
+	}
+
+	note, err := parser.New().Parse(path, content)
+	if err != nil {
+		return nil, fmt.Errorf("parse markdown: %w", err)
+	}
+	targets := make([]string, len(note.WikiLinks))
+	for i, link := range note.WikiLinks {
+		targets[i] = link.Target
+	}
+	if err := s.links.ReplaceLinks(path, targets); err != nil {
+		return nil, fmt.Errorf("register links: %w", err)
+	}
+
+	page, err := transformer.New(s.links, s.transformCfg.internal()).Transform(note)
+	if err != nil {
+		return nil, fmt.Errorf("transform to Notion: %w", err)
+	}
+
+	prior, err := s.db.GetState(path)
+	if err != nil {
+		return nil, fmt.Errorf("get state: %w", err)
+	}
+
+	result := &PushResult{}
+	if prior == nil || prior.NotionPageID == "" {
+		var created *notion.PageResult
+		switch {
+		case s.databaseID != "":
+			created, err = s.client.CreatePage(ctx, s.databaseID, page)
+		case s.parentPageID != "":
+			created, err = s.client.CreatePageUnderPage(ctx, s.parentPageID, page)
+		default:
+			return nil, errors.New("no database or parent page to create the page in")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("create page: %w", err)
+		}
+		result.PageID = created.PageID
+		result.Created = true
+	} else {
+		if err := s.client.UpdatePage(ctx, prior.NotionPageID, page); err != nil {
+			return nil, fmt.Errorf("update page: %w", err)
+		}
+		result.PageID = prior.NotionPageID
+	}
+
+	hashes, err := s.hasher.HashFileDetailed(fullPath)
+	if err != nil {
+		hashes = state.ContentHashes{}
+	}
+	now := time.Now()
+	if err := s.db.SetState(&state.SyncState{
+		ObsidianPath:    path,
+		NotionPageID:    result.PageID,
+		ObsidianMtime:   info.ModTime(),
+		NotionMtime:     now,
+		ContentHash:     hashes.ContentHash,
+		FrontmatterHash: hashes.FrontmatterHash,
+		LastSync:        now,
+		SyncDirection:   "push",
+		Status:          "synced",
+	}); err != nil {
+		return nil, fmt.Errorf("update state: %w", err)
+	}
+	if err := s.recordPage(path, page); err != nil {
+		return nil, fmt.Errorf("record page state: %w", err)
+	}
+	return result, nil
+}
+
+// Pull fetches the Notion page synced with the note at a vault-relative
+// path and overwrites the note with it.
+func (s *Syncer) Pull(ctx context.Context, path string) error {
+	prior, err := s.db.GetState(path)
+	if err != nil {
+		return fmt.Errorf("get state: %w", err)
+	}
+	if prior == nil || prior.NotionPageID == "" {
+		return fmt.Errorf("%s is not synced with a Notion page", path)
+	}
+
+	page, err := s.client.FetchPage(ctx, prior.NotionPageID)
+	if err != nil {
+		return fmt.Errorf("fetch page: %w", err)
+	}
+	tags, err := s.db.GetNoteTags(path)
+	if err != nil {
+		return fmt.Errorf("get tags: %w", err)
+	}
+	for _, t := range tags {
+		page.Tags = append(page.Tags, transformer.TagSource{Tag: t.Tag, Values: t.Values})
+	}
+	sections, err := s.db.GetSections(path)
+	if err != nil {
+		return fmt.Errorf("get sections: %w", err)
+	}
+	if len(sections) > 0 {
+		known := make([]*transformer.PageSection, len(sections))
+		for i, sec := range sections {
+			known[i] = &transformer.PageSection{Title: sec.Title, PageID: sec.NotionPageID}
+		}
+		if err := s.client.FetchSections(ctx, page, known); err != nil {
+			return fmt.Errorf("fetch sections: %w", err)
+		}
+	}
+
+	markdown, err := transformer.NewReverse(s.links, s.transformCfg.internal()).NotionToMarkdown(page)
+	if err != nil {
+		return fmt.Errorf("transform to markdown: %w", err)
+	}
+
+	fullPath := filepath.Join(s.vault, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	if err := os.WriteFile(fullPath, markdown, 0644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	hashes, err := s.hasher.HashFileDetailed(fullPath)
+	if err != nil {
+		hashes = state.ContentHashes{}
+	}
+	if info, err := os.Stat(fullPath); err == nil {
+		prior.ObsidianMtime = info.ModTime()
+	}
+	prior.ContentHash = hashes.ContentHash
+	prior.FrontmatterHash = hashes.FrontmatterHash
+	prior.NotionMtime = page.LastEditedTime
+	prior.LastSync = time.Now()
+	prior.SyncDirection = "pull"
+	prior.Status = "synced"
+	if err := s.db.SetState(prior); err != nil {
+		return fmt.Errorf("update state: %w", err)
+	}
+	return nil
+}
+
+// recordPage stores the child pages a note was split into and the tags it
+// was pushed as, so a later pull can reassemble the note.
+func (s *Syncer) recordPage(path string, page *transformer.NotionPage) error {
+	sections := make([]state.PageSection, len(page.Sections))
+	for i, sec := range page.Sections {
+		sections[i] = state.PageSection{Title: sec.Title, NotionPageID: sec.PageID}
+	}
+	if err := s.db.SetSections(path, sections); err != nil {
+		return err
+	}
+
+	tags := make([]state.NoteTag, len(page.Tags))
+	for i, t := range page.Tags {
+		tags[i] = state.NoteTag{Tag: t.Tag, Values: t.Values}
+	}
+	return s.db.SetNoteTags(path, tags)
+}
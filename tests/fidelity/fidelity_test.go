@@ -0,0 +1,183 @@
+// Package fidelity checks conversion fidelity against a golden corpus.
+//
+// Each directory under testdata is a case holding input.md, the Obsidian
+// note; expected_blocks.json, the blocks push sends to Notion; and
+// expected_roundtrip.md, the note pull writes back from those blocks. The
+// test runs offline: the blocks are passed through JSON the way the Notion
+// API returns them, then converted back.
+//
+// Run with -update to regenerate the golden files after a deliberate
+// change, and review the diff before committing it.
+package fidelity
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jomei/notionapi"
+
+	"github.com/adamancini/obsidian-notion-sync/internal/parser"
+	"github.com/adamancini/obsidian-notion-sync/internal/transformer"
+)
+
+var update = flag.Bool("update", false, "regenerate golden files")
+
+// Golden file names within a case directory.
+const (
+	inputFile     = "input.md"
+	blocksFile    = "expected_blocks.json"
+	roundtripFile = "expected_roundtrip.md"
+)
+
+func TestFidelity(t *testing.T) {
+	cases, err := filepath.Glob(filepath.Join("testdata", "*", inputFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("no fidelity cases found in testdata")
+	}
+
+	for _, input := range cases {
+		dir := filepath.Dir(input)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			runCase(t, dir)
+		})
+	}
+}
+
+// runCase converts a case's input to Notion and back and compares both
+// results with the golden files.
+func runCase(t *testing.T, dir string) {
+	source, err := os.ReadFile(filepath.Join(dir, inputFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	note, err := parser.New().Parse(inputFile, source)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	page, err := transformer.New(nil, nil).Transform(note)
+	if err != nil {
+		t.Fatalf("Transform() error: %v", err)
+	}
+
+	blocks, err := json.MarshalIndent(page.Children, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal blocks: %v", err)
+	}
+	blocks = append(blocks, '\n')
+	compareGolden(t, filepath.Join(dir, blocksFile), blocks)
+
+	fetched, err := asFetched(page)
+	if err != nil {
+		t.Fatalf("simulate fetch: %v", err)
+	}
+	markdown, err := transformer.NewReverse(nil, nil).NotionToMarkdown(fetched)
+	if err != nil {
+		t.Fatalf("NotionToMarkdown() error: %v", err)
+	}
+	compareGolden(t, filepath.Join(dir, roundtripFile), markdown)
+}
+
+// compareGolden compares got with a golden file, or rewrites the file
+// with -update.
+func compareGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the converted output (run with -update to accept it)\n--- got ---\n%s\n--- want ---\n%s",
+			filepath.Base(path), got, want)
+	}
+}
+
+// asFetched returns the page as pull would see it after push: properties
+// and blocks are encoded to JSON and decoded again, and each text object
+// gets the plain text Notion fills in.
+func asFetched(page *transformer.NotionPage) (*transformer.NotionPage, error) {
+	fetched := &transformer.NotionPage{}
+	if err := reencode(page.Properties, &fetched.Properties); err != nil {
+		return nil, err
+	}
+	var children notionapi.Blocks
+	if err := reencode(page.Children, &children); err != nil {
+		return nil, err
+	}
+	fetched.Children = children
+	return fetched, nil
+}
+
+// reencode marshals in, fills in what the Notion API adds to responses,
+// and unmarshals into out.
+func reencode(in, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	fillPlainText(raw)
+	fillPropertyTypes(raw)
+	if data, err = json.Marshal(raw); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// fillPlainText sets plain_text on text objects that lack it to their
+// content, as the Notion API does in responses.
+func fillPlainText(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		if text, ok := v["text"].(map[string]any); ok && v["type"] == "text" {
+			if _, set := v["plain_text"]; !set {
+				v["plain_text"] = text["content"]
+			}
+		}
+		for _, child := range v {
+			fillPlainText(child)
+		}
+	case []any:
+		for _, child := range v {
+			fillPlainText(child)
+		}
+	}
+}
+
+// fillPropertyTypes sets the type of each page property, which requests
+// leave implied by the property's only value key.
+func fillPropertyTypes(v any) {
+	props, ok := v.(map[string]any)
+	if !ok {
+		return
+	}
+	for _, p := range props {
+		prop, ok := p.(map[string]any)
+		if !ok || prop["type"] != nil {
+			continue
+		}
+		var typ string
+		for key := range prop {
+			if key != "id" {
+				typ = key
+			}
+		}
+		prop["type"] = typ
+	}
+}